@@ -399,9 +399,10 @@ func SetRecoveryBootSystemAndMode(dev Device, systemLabel, mode string) error {
 }
 
 // UpdateManagedBootConfigs updates managed boot config assets if those are
-// present for the ubuntu-boot bootloader. Returns true when an update was
-// carried out.
-func UpdateManagedBootConfigs(dev Device, gadgetSnapOrDir string) (updated bool, err error) {
+// present for the ubuntu-boot bootloader. The kernel command line arguments
+// set through the system configuration, if any, must be given. Returns true
+// when an update was carried out.
+func UpdateManagedBootConfigs(dev Device, gadgetSnapOrDir string, syscmdline *SystemCommandLine) (updated bool, err error) {
 	if !dev.HasModeenv() {
 		// only UC20 devices use managed boot config
 		return false, nil
@@ -409,10 +410,10 @@ func UpdateManagedBootConfigs(dev Device, gadgetSnapOrDir string) (updated bool,
 	if !dev.RunMode() {
 		return false, fmt.Errorf("internal error: boot config can only be updated in run mode")
 	}
-	return updateManagedBootConfigForBootloader(dev, ModeRun, gadgetSnapOrDir)
+	return updateManagedBootConfigForBootloader(dev, ModeRun, gadgetSnapOrDir, syscmdline)
 }
 
-func updateManagedBootConfigForBootloader(dev Device, mode, gadgetSnapOrDir string) (updated bool, err error) {
+func updateManagedBootConfigForBootloader(dev Device, mode, gadgetSnapOrDir string, syscmdline *SystemCommandLine) (updated bool, err error) {
 	if mode != ModeRun {
 		return false, fmt.Errorf("internal error: updating boot config of recovery bootloader is not supported yet")
 	}
@@ -430,7 +431,7 @@ func updateManagedBootConfigForBootloader(dev Device, mode, gadgetSnapOrDir stri
 		return false, err
 	}
 	// boot config update can lead to a change of kernel command line
-	_, err = observeCommandLineUpdate(dev.Model(), commandLineUpdateReasonSnapd, gadgetSnapOrDir)
+	_, err = observeCommandLineUpdate(dev.Model(), commandLineUpdateReasonSnapd, gadgetSnapOrDir, syscmdline)
 	if err != nil {
 		return false, err
 	}
@@ -438,10 +439,27 @@ func updateManagedBootConfigForBootloader(dev Device, mode, gadgetSnapOrDir stri
 }
 
 // UpdateCommandLineForGadgetComponent handles the update of a gadget that
-// contributes to the kernel command line of the run system. Returns true when a
-// change in command line has been observed and a reboot is needed. The reboot,
-// if needed, should be requested at the the earliest possible occasion.
-func UpdateCommandLineForGadgetComponent(dev Device, gadgetSnapOrDir string) (needsReboot bool, err error) {
+// contributes to the kernel command line of the run system. The kernel
+// command line arguments set through the system configuration, if any, must
+// be given. Returns true when a change in command line has been observed and
+// a reboot is needed. The reboot, if needed, should be requested at the the
+// earliest possible occasion.
+func UpdateCommandLineForGadgetComponent(dev Device, gadgetSnapOrDir string, syscmdline *SystemCommandLine) (needsReboot bool, err error) {
+	return updateCommandLine(dev, commandLineUpdateReasonGadget, gadgetSnapOrDir, syscmdline)
+}
+
+// UpdateCommandLineForSystemOption handles a change of the kernel command
+// line arguments of the run system set through the system configuration,
+// given along with the current gadget. The encryption keys are resealed for
+// the new command line before the bootloader is updated. Returns true when a
+// change in command line has been observed and a reboot is needed. The
+// reboot, if needed, should be requested at the the earliest possible
+// occasion.
+func UpdateCommandLineForSystemOption(dev Device, gadgetSnapOrDir string, syscmdline *SystemCommandLine) (needsReboot bool, err error) {
+	return updateCommandLine(dev, commandLineUpdateReasonSystemOption, gadgetSnapOrDir, syscmdline)
+}
+
+func updateCommandLine(dev Device, reason commandLineUpdateReason, gadgetSnapOrDir string, syscmdline *SystemCommandLine) (needsReboot bool, err error) {
 	if !dev.HasModeenv() {
 		// only UC20 devices are supported
 		return false, fmt.Errorf("internal error: command line component cannot be updated on non UC20 devices")
@@ -459,8 +477,9 @@ func UpdateCommandLineForGadgetComponent(dev Device, gadgetSnapOrDir string) (ne
 		}
 		return false, err
 	}
-	// gadget update can lead to a change of kernel command line
-	cmdlineChange, err := observeCommandLineUpdate(dev.Model(), commandLineUpdateReasonGadget, gadgetSnapOrDir)
+	// gadget or system configuration update can lead to a change of
+	// kernel command line
+	cmdlineChange, err := observeCommandLineUpdate(dev.Model(), reason, gadgetSnapOrDir, syscmdline)
 	if err != nil {
 		return false, err
 	}
@@ -469,7 +488,7 @@ func UpdateCommandLineForGadgetComponent(dev Device, gadgetSnapOrDir string) (ne
 	}
 	// update the bootloader environment, maybe clearing the relevant
 	// variables
-	cmdlineVars, err := bootVarsForTrustedCommandLineFromGadget(gadgetSnapOrDir)
+	cmdlineVars, err := bootVarsForTrustedCommandLineFromGadget(gadgetSnapOrDir, syscmdline)
 	if err != nil {
 		return false, fmt.Errorf("cannot prepare bootloader variables for kernel command line: %v", err)
 	}
@@ -3170,7 +3170,7 @@ func (s *bootConfigSuite) TestBootConfigUpdateHappyNoKeysNoReseal(c *C) {
 	})
 	defer restore()
 
-	updated, err := boot.UpdateManagedBootConfigs(coreDev, s.gadgetSnap)
+	updated, err := boot.UpdateManagedBootConfigs(coreDev, s.gadgetSnap, nil)
 	c.Assert(err, IsNil)
 	c.Check(updated, Equals, false)
 	c.Check(s.bootloader.UpdateCalls, Equals, 1)
@@ -3226,7 +3226,7 @@ func (s *bootConfigSuite) TestBootConfigUpdateHappyWithReseal(c *C) {
 	})
 	defer restore()
 
-	updated, err := boot.UpdateManagedBootConfigs(coreDev, s.gadgetSnap)
+	updated, err := boot.UpdateManagedBootConfigs(coreDev, s.gadgetSnap, nil)
 	c.Assert(err, IsNil)
 	c.Check(updated, Equals, false)
 	c.Check(s.bootloader.UpdateCalls, Equals, 1)
@@ -3265,7 +3265,7 @@ func (s *bootConfigSuite) TestBootConfigUpdateHappyNoChange(c *C) {
 	})
 	defer restore()
 
-	updated, err := boot.UpdateManagedBootConfigs(coreDev, s.gadgetSnap)
+	updated, err := boot.UpdateManagedBootConfigs(coreDev, s.gadgetSnap, nil)
 	c.Assert(err, IsNil)
 	c.Check(updated, Equals, false)
 	c.Check(s.bootloader.UpdateCalls, Equals, 1)
@@ -3279,7 +3279,7 @@ func (s *bootConfigSuite) TestBootConfigUpdateHappyNoChange(c *C) {
 func (s *bootConfigSuite) TestBootConfigUpdateNonUC20DoesNothing(c *C) {
 	nonUC20coreDev := boottest.MockDevice("pc-kernel")
 	c.Assert(nonUC20coreDev.HasModeenv(), Equals, false)
-	updated, err := boot.UpdateManagedBootConfigs(nonUC20coreDev, s.gadgetSnap)
+	updated, err := boot.UpdateManagedBootConfigs(nonUC20coreDev, s.gadgetSnap, nil)
 	c.Assert(err, IsNil)
 	c.Check(updated, Equals, false)
 	c.Check(s.bootloader.UpdateCalls, Equals, 0)
@@ -3288,7 +3288,7 @@ func (s *bootConfigSuite) TestBootConfigUpdateNonUC20DoesNothing(c *C) {
 func (s *bootConfigSuite) TestBootConfigUpdateBadModeErr(c *C) {
 	uc20Dev := boottest.MockUC20Device("recover", nil)
 	c.Assert(uc20Dev.HasModeenv(), Equals, true)
-	updated, err := boot.UpdateManagedBootConfigs(uc20Dev, s.gadgetSnap)
+	updated, err := boot.UpdateManagedBootConfigs(uc20Dev, s.gadgetSnap, nil)
 	c.Assert(err, ErrorMatches, "internal error: boot config can only be updated in run mode")
 	c.Check(updated, Equals, false)
 	c.Check(s.bootloader.UpdateCalls, Equals, 0)
@@ -3308,7 +3308,7 @@ func (s *bootConfigSuite) TestBootConfigUpdateFailErr(c *C) {
 
 	s.bootloader.UpdateErr = errors.New("update fail")
 
-	updated, err := boot.UpdateManagedBootConfigs(coreDev, s.gadgetSnap)
+	updated, err := boot.UpdateManagedBootConfigs(coreDev, s.gadgetSnap, nil)
 	c.Assert(err, ErrorMatches, "update fail")
 	c.Check(updated, Equals, false)
 	c.Check(s.bootloader.UpdateCalls, Equals, 1)
@@ -3325,7 +3325,7 @@ func (s *bootConfigSuite) TestBootConfigUpdateCmdlineMismatchErr(c *C) {
 
 	s.mockCmdline(c, "snapd_recovery_mode=run unexpected cmdline")
 
-	updated, err := boot.UpdateManagedBootConfigs(coreDev, s.gadgetSnap)
+	updated, err := boot.UpdateManagedBootConfigs(coreDev, s.gadgetSnap, nil)
 	c.Assert(err, ErrorMatches, `internal error: current kernel command lines is unset`)
 	c.Check(updated, Equals, false)
 	c.Check(s.bootloader.UpdateCalls, Equals, 0)
@@ -3344,7 +3344,7 @@ func (s *bootConfigSuite) TestBootConfigUpdateNotManagedErr(c *C) {
 	}
 	c.Assert(m.WriteTo(""), IsNil)
 
-	updated, err := boot.UpdateManagedBootConfigs(coreDev, s.gadgetSnap)
+	updated, err := boot.UpdateManagedBootConfigs(coreDev, s.gadgetSnap, nil)
 	c.Assert(err, IsNil)
 	c.Check(updated, Equals, false)
 	c.Check(s.bootloader.UpdateCalls, Equals, 0)
@@ -3362,7 +3362,7 @@ func (s *bootConfigSuite) TestBootConfigUpdateBootloaderFindErr(c *C) {
 	}
 	c.Assert(m.WriteTo(""), IsNil)
 
-	updated, err := boot.UpdateManagedBootConfigs(coreDev, s.gadgetSnap)
+	updated, err := boot.UpdateManagedBootConfigs(coreDev, s.gadgetSnap, nil)
 	c.Assert(err, ErrorMatches, "internal error: cannot find trusted assets bootloader under .*: mocked find error")
 	c.Check(updated, Equals, false)
 	c.Check(s.bootloader.UpdateCalls, Equals, 0)
@@ -3422,7 +3422,7 @@ func (s *bootConfigSuite) TestBootConfigUpdateWithGadgetAndReseal(c *C) {
 	})
 	defer restore()
 
-	updated, err := boot.UpdateManagedBootConfigs(coreDev, gadgetSnap)
+	updated, err := boot.UpdateManagedBootConfigs(coreDev, gadgetSnap, nil)
 	c.Assert(err, IsNil)
 	c.Check(updated, Equals, false)
 	c.Check(s.bootloader.UpdateCalls, Equals, 1)
@@ -3470,7 +3470,7 @@ func (s *bootConfigSuite) TestBootConfigUpdateWithGadgetFullAndReseal(c *C) {
 	})
 	defer restore()
 
-	updated, err := boot.UpdateManagedBootConfigs(coreDev, gadgetSnap)
+	updated, err := boot.UpdateManagedBootConfigs(coreDev, gadgetSnap, nil)
 	c.Assert(err, IsNil)
 	c.Check(updated, Equals, true)
 	c.Check(s.bootloader.UpdateCalls, Equals, 1)
@@ -3574,7 +3574,7 @@ func (s *bootKernelCommandLineSuite) TestCommandLineUpdateNonUC20(c *C) {
 		{"cmdline.extra", "foo"},
 	})
 
-	reboot, err := boot.UpdateCommandLineForGadgetComponent(nonUC20dev, sf)
+	reboot, err := boot.UpdateCommandLineForGadgetComponent(nonUC20dev, sf, nil)
 	c.Assert(err, ErrorMatches, "internal error: command line component cannot be updated on non UC20 devices")
 	c.Assert(reboot, Equals, false)
 }
@@ -3590,7 +3590,7 @@ func (s *bootKernelCommandLineSuite) TestCommandLineUpdateUC20NotManagedBootload
 	bl.SetErr = fmt.Errorf("unexpected call")
 	s.forceBootloader(bl)
 
-	reboot, err := boot.UpdateCommandLineForGadgetComponent(s.uc20dev, sf)
+	reboot, err := boot.UpdateCommandLineForGadgetComponent(s.uc20dev, sf, nil)
 	c.Assert(err, IsNil)
 	c.Assert(reboot, Equals, false)
 	c.Check(bl.SetBootVarsCalls, Equals, 0)
@@ -3606,7 +3606,7 @@ func (s *bootKernelCommandLineSuite) TestCommandLineUpdateUC20ArgsAdded(c *C) {
 	s.modeenvWithEncryption.CurrentKernelCommandLines = []string{"snapd_recovery_mode=run static mocked panic=-1"}
 	c.Assert(s.modeenvWithEncryption.WriteTo(""), IsNil)
 
-	reboot, err := boot.UpdateCommandLineForGadgetComponent(s.uc20dev, sf)
+	reboot, err := boot.UpdateCommandLineForGadgetComponent(s.uc20dev, sf, nil)
 	c.Assert(err, IsNil)
 	c.Assert(reboot, Equals, true)
 
@@ -3635,6 +3635,74 @@ func (s *bootKernelCommandLineSuite) TestCommandLineUpdateUC20ArgsAdded(c *C) {
 	})
 }
 
+func (s *bootKernelCommandLineSuite) TestCommandLineUpdateUC20SystemOption(c *C) {
+	s.stampSealedKeys(c, dirs.GlobalRootDir)
+
+	sf := snaptest.MakeTestSnapWithFiles(c, gadgetSnapYaml, [][]string{
+		{"cmdline.extra", "args from gadget"},
+	})
+
+	s.modeenvWithEncryption.CurrentKernelCommandLines = []string{"snapd_recovery_mode=run static mocked panic=-1 args from gadget"}
+	c.Assert(s.modeenvWithEncryption.WriteTo(""), IsNil)
+	err := s.bootloader.SetBootVars(map[string]string{
+		"snapd_extra_cmdline_args": "args from gadget",
+	})
+	c.Assert(err, IsNil)
+	s.bootloader.SetBootVarsCalls = 0
+
+	syscmdline := &boot.SystemCommandLine{Append: "isolcpus=1"}
+	reboot, err := boot.UpdateCommandLineForSystemOption(s.uc20dev, sf, syscmdline)
+	c.Assert(err, IsNil)
+	c.Assert(reboot, Equals, true)
+
+	// reseal happened before the bootloader was updated
+	c.Check(s.resealCalls, Equals, 1)
+	c.Check(s.resealCommandLines, DeepEquals, [][]string{{
+		"snapd_recovery_mode=run static mocked panic=-1 args from gadget",
+		"snapd_recovery_mode=run static mocked panic=-1 args from gadget isolcpus=1",
+	}})
+
+	newM, err := boot.ReadModeenv("")
+	c.Assert(err, IsNil)
+	c.Check(newM.CurrentKernelCommandLines, DeepEquals, boot.BootCommandLines{
+		"snapd_recovery_mode=run static mocked panic=-1 args from gadget",
+		"snapd_recovery_mode=run static mocked panic=-1 args from gadget isolcpus=1",
+	})
+
+	c.Check(s.bootloader.SetBootVarsCalls, Equals, 1)
+	args, err := s.bootloader.GetBootVars("snapd_extra_cmdline_args", "snapd_full_cmdline_args")
+	c.Assert(err, IsNil)
+	c.Check(args, DeepEquals, map[string]string{
+		"snapd_extra_cmdline_args": "args from gadget isolcpus=1",
+		"snapd_full_cmdline_args":  "",
+	})
+
+	// rebooted with the new command line
+	newM.CurrentKernelCommandLines = boot.BootCommandLines{
+		"snapd_recovery_mode=run static mocked panic=-1 args from gadget isolcpus=1",
+	}
+	c.Assert(newM.Write(), IsNil)
+
+	// the same arguments again are a no-op
+	reboot, err = boot.UpdateCommandLineForSystemOption(s.uc20dev, sf, syscmdline)
+	c.Assert(err, IsNil)
+	c.Check(reboot, Equals, false)
+	c.Check(s.resealCalls, Equals, 1)
+	c.Check(s.bootloader.SetBootVarsCalls, Equals, 1)
+
+	// the full override replaces the gadget arguments
+	reboot, err = boot.UpdateCommandLineForSystemOption(s.uc20dev, sf, &boot.SystemCommandLine{Full: "full args"})
+	c.Assert(err, IsNil)
+	c.Check(reboot, Equals, true)
+	c.Check(s.resealCalls, Equals, 2)
+	args, err = s.bootloader.GetBootVars("snapd_extra_cmdline_args", "snapd_full_cmdline_args")
+	c.Assert(err, IsNil)
+	c.Check(args, DeepEquals, map[string]string{
+		"snapd_extra_cmdline_args": "",
+		"snapd_full_cmdline_args":  "full args",
+	})
+}
+
 func (s *bootKernelCommandLineSuite) TestCommandLineUpdateUC20ArgsSwitch(c *C) {
 	s.stampSealedKeys(c, dirs.GlobalRootDir)
 
@@ -3652,7 +3720,7 @@ func (s *bootKernelCommandLineSuite) TestCommandLineUpdateUC20ArgsSwitch(c *C) {
 	c.Assert(err, IsNil)
 	s.bootloader.SetBootVarsCalls = 0
 
-	reboot, err := boot.UpdateCommandLineForGadgetComponent(s.uc20dev, sf)
+	reboot, err := boot.UpdateCommandLineForGadgetComponent(s.uc20dev, sf, nil)
 	c.Assert(err, IsNil)
 	c.Assert(reboot, Equals, false)
 
@@ -3678,7 +3746,7 @@ func (s *bootKernelCommandLineSuite) TestCommandLineUpdateUC20ArgsSwitch(c *C) {
 		{"cmdline.extra", "changed"},
 	})
 
-	reboot, err = boot.UpdateCommandLineForGadgetComponent(s.uc20dev, sfChanged)
+	reboot, err = boot.UpdateCommandLineForGadgetComponent(s.uc20dev, sfChanged, nil)
 	c.Assert(err, IsNil)
 	c.Assert(reboot, Equals, true)
 
@@ -3725,7 +3793,7 @@ func (s *bootKernelCommandLineSuite) TestCommandLineUpdateUC20UnencryptedArgsRem
 	c.Assert(err, IsNil)
 	s.bootloader.SetBootVarsCalls = 0
 
-	reboot, err := boot.UpdateCommandLineForGadgetComponent(s.uc20dev, sf)
+	reboot, err := boot.UpdateCommandLineForGadgetComponent(s.uc20dev, sf, nil)
 	c.Assert(err, IsNil)
 	c.Assert(reboot, Equals, true)
 
@@ -3765,7 +3833,7 @@ func (s *bootKernelCommandLineSuite) TestCommandLineUpdateUC20SetError(c *C) {
 
 	s.bootloader.SetErr = fmt.Errorf("set fails")
 
-	reboot, err := boot.UpdateCommandLineForGadgetComponent(s.uc20dev, sf)
+	reboot, err := boot.UpdateCommandLineForGadgetComponent(s.uc20dev, sf, nil)
 	c.Assert(err, ErrorMatches, "cannot set run system kernel command line arguments: set fails")
 	c.Assert(reboot, Equals, false)
 	// set boot vars was called and failed
@@ -3803,7 +3871,7 @@ func (s *bootKernelCommandLineSuite) TestCommandLineUpdateWithResealError(c *C)
 	})
 	defer restore()
 
-	reboot, err := boot.UpdateCommandLineForGadgetComponent(s.uc20dev, gadgetSnap)
+	reboot, err := boot.UpdateCommandLineForGadgetComponent(s.uc20dev, gadgetSnap, nil)
 	c.Assert(err, ErrorMatches, "cannot reseal the encryption key: reseal fails")
 	c.Check(reboot, Equals, false)
 	c.Check(s.bootloader.SetBootVarsCalls, Equals, 0)
@@ -3835,7 +3903,7 @@ func (s *bootKernelCommandLineSuite) TestCommandLineUpdateUC20TransitionFullExtr
 	sf := snaptest.MakeTestSnapWithFiles(c, gadgetSnapYaml, [][]string{
 		{"cmdline.extra", "extra args"},
 	})
-	reboot, err := boot.UpdateCommandLineForGadgetComponent(s.uc20dev, sf)
+	reboot, err := boot.UpdateCommandLineForGadgetComponent(s.uc20dev, sf, nil)
 	c.Assert(err, IsNil)
 	c.Assert(reboot, Equals, true)
 	c.Check(s.resealCalls, Equals, 1)
@@ -3868,7 +3936,7 @@ func (s *bootKernelCommandLineSuite) TestCommandLineUpdateUC20TransitionFullExtr
 	sfFull := snaptest.MakeTestSnapWithFiles(c, gadgetSnapYaml, [][]string{
 		{"cmdline.full", "full args"},
 	})
-	reboot, err = boot.UpdateCommandLineForGadgetComponent(s.uc20dev, sfFull)
+	reboot, err = boot.UpdateCommandLineForGadgetComponent(s.uc20dev, sfFull, nil)
 	c.Assert(err, IsNil)
 	c.Assert(reboot, Equals, true)
 	c.Check(s.resealCalls, Equals, 2)
@@ -3901,7 +3969,7 @@ func (s *bootKernelCommandLineSuite) TestCommandLineUpdateUC20TransitionFullExtr
 
 	// transition back to no arguments from the gadget
 	sfNone := snaptest.MakeTestSnapWithFiles(c, gadgetSnapYaml, nil)
-	reboot, err = boot.UpdateCommandLineForGadgetComponent(s.uc20dev, sfNone)
+	reboot, err = boot.UpdateCommandLineForGadgetComponent(s.uc20dev, sfNone, nil)
 	c.Assert(err, IsNil)
 	c.Assert(reboot, Equals, true)
 	c.Check(s.resealCalls, Equals, 3)
@@ -3975,7 +4043,7 @@ func (s *bootKernelCommandLineSuite) TestCommandLineUpdateUC20OverSpuriousReboot
 	// let's panic on reseal first
 	resealPanic = true
 	c.Assert(func() {
-		boot.UpdateCommandLineForGadgetComponent(s.uc20dev, sf)
+		boot.UpdateCommandLineForGadgetComponent(s.uc20dev, sf, nil)
 	}, PanicMatches, "reseal panic")
 	c.Check(s.resealCalls, Equals, 1)
 	c.Check(s.resealCommandLines, DeepEquals, [][]string{{
@@ -4012,7 +4080,7 @@ func (s *bootKernelCommandLineSuite) TestCommandLineUpdateUC20OverSpuriousReboot
 	resealPanic = false
 	// but panic in set
 	c.Assert(func() {
-		boot.UpdateCommandLineForGadgetComponent(s.uc20dev, sf)
+		boot.UpdateCommandLineForGadgetComponent(s.uc20dev, sf, nil)
 	}, PanicMatches, "mocked reboot panic in SetBootVars")
 	c.Check(s.resealCalls, Equals, 1)
 	c.Check(s.resealCommandLines, DeepEquals, [][]string{{
@@ -4044,7 +4112,7 @@ func (s *bootKernelCommandLineSuite) TestCommandLineUpdateUC20OverSpuriousReboot
 	s.resealCalls = 0
 	s.resealCommandLines = nil
 	restoreBootloaderNoPanic()
-	reboot, err := boot.UpdateCommandLineForGadgetComponent(s.uc20dev, sf)
+	reboot, err := boot.UpdateCommandLineForGadgetComponent(s.uc20dev, sf, nil)
 	c.Assert(err, IsNil)
 	c.Check(reboot, Equals, true)
 	c.Check(s.resealCalls, Equals, 1)
@@ -4096,7 +4164,7 @@ func (s *bootKernelCommandLineSuite) TestCommandLineUpdateUC20OverSpuriousReboot
 		panic("mocked reboot panic after SetBootVars")
 	}
 	c.Assert(func() {
-		boot.UpdateCommandLineForGadgetComponent(s.uc20dev, sf)
+		boot.UpdateCommandLineForGadgetComponent(s.uc20dev, sf, nil)
 	}, PanicMatches, "mocked reboot panic after SetBootVars")
 	c.Check(s.resealCalls, Equals, 1)
 	c.Check(s.resealCommandLines, DeepEquals, [][]string{{
@@ -4140,7 +4208,7 @@ func (s *bootKernelCommandLineSuite) TestCommandLineUpdateUC20OverSpuriousReboot
 
 	// try again, as if the task handler gets to run again
 	s.resealCalls = 0
-	reboot, err := boot.UpdateCommandLineForGadgetComponent(s.uc20dev, sf)
+	reboot, err := boot.UpdateCommandLineForGadgetComponent(s.uc20dev, sf, nil)
 	c.Assert(err, IsNil)
 	// nothing changed now, we already booted with the new command line
 	c.Check(reboot, Equals, false)
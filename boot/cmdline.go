@@ -99,34 +99,83 @@ func getBootloaderManagingItsAssets(where string, opts *bootloader.Options) (boo
 	return mbl, nil
 }
 
-// bootVarsForTrustedCommandLineFromGadget returns a set of boot variables that
-// carry the command line arguments requested by the gadget. This is only useful
-// if snapd is managing the boot config.
-func bootVarsForTrustedCommandLineFromGadget(gadgetDirOrSnapPath string) (map[string]string, error) {
-	extraOrFull, full, err := gadget.KernelCommandLineFromGadget(gadgetDirOrSnapPath)
-	if err != nil {
-		if err == gadget.ErrNoKernelCommandline {
-			// nothing set by the gadget, but we could have had
-			// arguments before, so make sure those are cleared now
-			clear := map[string]string{
-				"snapd_extra_cmdline_args": "",
-				"snapd_full_cmdline_args":  "",
+// CommandLineIsManaged returns true when the kernel command line of the run
+// system is managed by snapd, that is when the bootloader of the run system
+// manages its boot assets.
+func CommandLineIsManaged() (bool, error) {
+	opts := &bootloader.Options{
+		Role: bootloader.RoleRunMode,
+	}
+	if _, err := getBootloaderManagingItsAssets("", opts); err != nil {
+		if err == errBootConfigNotManaged {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// SystemCommandLine carries the kernel command line arguments of the run
+// system that are set through the system configuration rather than by the
+// gadget.
+type SystemCommandLine struct {
+	// Append is appended to the arguments of the run system.
+	Append string
+	// Full, when set, replaces both the built-in static arguments of the
+	// bootloader and the arguments requested by the gadget.
+	Full string
+}
+
+// extraAndFullCommandLineArgs returns the extra or full arguments of the
+// kernel command line, as requested by the gadget and the system
+// configuration. At most one of them is set.
+func extraAndFullCommandLineArgs(gadgetDirOrSnapPath string, syscmdline *SystemCommandLine) (extra, full string, err error) {
+	if gadgetDirOrSnapPath != "" {
+		extraOrFull, isFull, err := gadget.KernelCommandLineFromGadget(gadgetDirOrSnapPath)
+		if err != nil && err != gadget.ErrNoKernelCommandline {
+			return "", "", fmt.Errorf("cannot use kernel command line from gadget: %v", err)
+		}
+		if err == nil {
+			// gadget provides some part of the kernel command line
+			if isFull {
+				full = extraOrFull
+			} else {
+				extra = extraOrFull
 			}
-			return clear, nil
 		}
-		return nil, fmt.Errorf("cannot use kernel command line from gadget: %v", err)
 	}
-	// gadget has the kernel command line
-	args := map[string]string{
-		"snapd_extra_cmdline_args": "",
-		"snapd_full_cmdline_args":  "",
+	if syscmdline == nil {
+		return extra, full, nil
 	}
-	if full {
-		args["snapd_full_cmdline_args"] = extraOrFull
-	} else {
-		args["snapd_extra_cmdline_args"] = extraOrFull
+	if syscmdline.Full != "" {
+		extra, full = "", syscmdline.Full
+	}
+	if syscmdline.Append != "" {
+		if full != "" {
+			full = full + " " + syscmdline.Append
+		} else if extra != "" {
+			extra = extra + " " + syscmdline.Append
+		} else {
+			extra = syscmdline.Append
+		}
+	}
+	return extra, full, nil
+}
+
+// bootVarsForTrustedCommandLineFromGadget returns a set of boot variables that
+// carry the command line arguments requested by the gadget and the system
+// configuration. This is only useful if snapd is managing the boot config.
+func bootVarsForTrustedCommandLineFromGadget(gadgetDirOrSnapPath string, syscmdline *SystemCommandLine) (map[string]string, error) {
+	extra, full, err := extraAndFullCommandLineArgs(gadgetDirOrSnapPath, syscmdline)
+	if err != nil {
+		return nil, err
 	}
-	return args, nil
+	// the arguments that are not set now could have been set before, so
+	// make sure those are cleared
+	return map[string]string{
+		"snapd_extra_cmdline_args": extra,
+		"snapd_full_cmdline_args":  full,
+	}, nil
 }
 
 const (
@@ -134,7 +183,7 @@ const (
 	candidateEdition
 )
 
-func composeCommandLine(currentOrCandidate int, mode, system, gadgetDirOrSnapPath string) (string, error) {
+func composeCommandLine(currentOrCandidate int, mode, system, gadgetDirOrSnapPath string, syscmdline *SystemCommandLine) (string, error) {
 	if mode != ModeRun && mode != ModeRecover {
 		return "", fmt.Errorf("internal error: unsupported command line mode %q", mode)
 	}
@@ -159,6 +208,8 @@ func composeCommandLine(currentOrCandidate int, mode, system, gadgetDirOrSnapPat
 			ModeArg:   "snapd_recovery_mode=recover",
 			SystemArg: fmt.Sprintf("snapd_recovery_system=%v", system),
 		}
+		// the system configuration applies to the run system only
+		syscmdline = nil
 	}
 	mbl, err := getBootloaderManagingItsAssets(bootloaderRootDir, opts)
 	if err != nil {
@@ -167,19 +218,9 @@ func composeCommandLine(currentOrCandidate int, mode, system, gadgetDirOrSnapPat
 		}
 		return "", err
 	}
-	if gadgetDirOrSnapPath != "" {
-		extraOrFull, full, err := gadget.KernelCommandLineFromGadget(gadgetDirOrSnapPath)
-		if err != nil && err != gadget.ErrNoKernelCommandline {
-			return "", fmt.Errorf("cannot use kernel command line from gadget: %v", err)
-		}
-		if err == nil {
-			// gadget provides some part of the kernel command line
-			if full {
-				components.FullArgs = extraOrFull
-			} else {
-				components.ExtraArgs = extraOrFull
-			}
-		}
+	components.ExtraArgs, components.FullArgs, err = extraAndFullCommandLineArgs(gadgetDirOrSnapPath, syscmdline)
+	if err != nil {
+		return "", err
 	}
 	if currentOrCandidate == currentEdition {
 		return mbl.CommandLine(components)
@@ -194,26 +235,28 @@ func ComposeRecoveryCommandLine(model *asserts.Model, system, gadgetDirOrSnapPat
 	if model.Grade() == asserts.ModelGradeUnset {
 		return "", nil
 	}
-	return composeCommandLine(currentEdition, ModeRecover, system, gadgetDirOrSnapPath)
+	return composeCommandLine(currentEdition, ModeRecover, system, gadgetDirOrSnapPath, nil)
 }
 
 // ComposeCommandLine composes the kernel command line used when booting the
-// system in run mode.
-func ComposeCommandLine(model *asserts.Model, gadgetDirOrSnapPath string) (string, error) {
+// system in run mode, including the arguments set through the system
+// configuration if any.
+func ComposeCommandLine(model *asserts.Model, gadgetDirOrSnapPath string, syscmdline *SystemCommandLine) (string, error) {
 	if model.Grade() == asserts.ModelGradeUnset {
 		return "", nil
 	}
-	return composeCommandLine(currentEdition, ModeRun, "", gadgetDirOrSnapPath)
+	return composeCommandLine(currentEdition, ModeRun, "", gadgetDirOrSnapPath, syscmdline)
 }
 
 // ComposeCandidateCommandLine composes the kernel command line used when
 // booting the system in run mode with the current built-in edition of managed
-// boot assets.
-func ComposeCandidateCommandLine(model *asserts.Model, gadgetDirOrSnapPath string) (string, error) {
+// boot assets, including the arguments set through the system configuration
+// if any.
+func ComposeCandidateCommandLine(model *asserts.Model, gadgetDirOrSnapPath string, syscmdline *SystemCommandLine) (string, error) {
 	if model.Grade() == asserts.ModelGradeUnset {
 		return "", nil
 	}
-	return composeCommandLine(candidateEdition, ModeRun, "", gadgetDirOrSnapPath)
+	return composeCommandLine(candidateEdition, ModeRun, "", gadgetDirOrSnapPath, syscmdline)
 }
 
 // ComposeCandidateRecoveryCommandLine composes the kernel command line used
@@ -223,7 +266,7 @@ func ComposeCandidateRecoveryCommandLine(model *asserts.Model, system, gadgetDir
 	if model.Grade() == asserts.ModelGradeUnset {
 		return "", nil
 	}
-	return composeCommandLine(candidateEdition, ModeRecover, system, gadgetDirOrSnapPath)
+	return composeCommandLine(candidateEdition, ModeRecover, system, gadgetDirOrSnapPath, nil)
 }
 
 // observeSuccessfulCommandLine observes a successful boot with a command line
@@ -283,7 +326,7 @@ func observeSuccessfulCommandLineUpdate(m *Modeenv) (*Modeenv, error) {
 func observeSuccessfulCommandLineCompatBoot(model *asserts.Model, m *Modeenv) (*Modeenv, error) {
 	// since this is a compatibility scenario, the kernel command line
 	// arguments would not have come from the gadget before either
-	cmdlineExpected, err := ComposeCommandLine(model, "")
+	cmdlineExpected, err := ComposeCommandLine(model, "", nil)
 	if err != nil {
 		return nil, err
 	}
@@ -313,13 +356,15 @@ type commandLineUpdateReason int
 const (
 	commandLineUpdateReasonSnapd commandLineUpdateReason = iota
 	commandLineUpdateReasonGadget
+	commandLineUpdateReasonSystemOption
 )
 
 // observeCommandLineUpdate observes a pending kernel command line change caused
-// by an update of boot config or the gadget snap. When needed, the modeenv is
-// updated with a candidate command line and the encryption keys are resealed.
-// This helper should be called right before updating the managed boot config.
-func observeCommandLineUpdate(model *asserts.Model, reason commandLineUpdateReason, gadgetSnapOrDir string) (updated bool, err error) {
+// by an update of boot config, the gadget snap or the system configuration.
+// When needed, the modeenv is updated with a candidate command line and the
+// encryption keys are resealed. This helper should be called right before
+// updating the managed boot config.
+func observeCommandLineUpdate(model *asserts.Model, reason commandLineUpdateReason, gadgetSnapOrDir string, syscmdline *SystemCommandLine) (updated bool, err error) {
 	// TODO:UC20: consider updating a recovery system command line
 
 	m, err := loadModeenv()
@@ -338,10 +383,10 @@ func observeCommandLineUpdate(model *asserts.Model, reason commandLineUpdateReas
 	switch reason {
 	case commandLineUpdateReasonSnapd:
 		// pending boot config update
-		candidateCmdline, err = ComposeCandidateCommandLine(model, gadgetSnapOrDir)
-	case commandLineUpdateReasonGadget:
-		// pending gadget update
-		candidateCmdline, err = ComposeCommandLine(model, gadgetSnapOrDir)
+		candidateCmdline, err = ComposeCandidateCommandLine(model, gadgetSnapOrDir, syscmdline)
+	case commandLineUpdateReasonGadget, commandLineUpdateReasonSystemOption:
+		// pending gadget or system configuration update
+		candidateCmdline, err = ComposeCommandLine(model, gadgetSnapOrDir, syscmdline)
 	}
 	if err != nil {
 		return false, err
@@ -376,7 +421,7 @@ func kernelCommandLinesForResealWithFallback(modeenv *Modeenv) (cmdlines []strin
 	// there would be no kernel command lines arguments coming from the
 	// gadget either
 	gadgetDir := ""
-	cmdline, err := composeCommandLine(currentEdition, ModeRun, "", gadgetDir)
+	cmdline, err := composeCommandLine(currentEdition, ModeRun, "", gadgetDir, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -20,6 +20,7 @@
 package boot_test
 
 import (
+	"errors"
 	"io/ioutil"
 	"os"
 	"path/filepath"
@@ -126,7 +127,7 @@ func (s *kernelCommandLineSuite) TestComposeCommandLineNotManagedHappy(c *C) {
 	c.Assert(err, IsNil)
 	c.Assert(cmdline, Equals, "")
 
-	cmdline, err = boot.ComposeCommandLine(model, "")
+	cmdline, err = boot.ComposeCommandLine(model, "", nil)
 	c.Assert(err, IsNil)
 	c.Assert(cmdline, Equals, "")
 
@@ -137,11 +138,32 @@ func (s *kernelCommandLineSuite) TestComposeCommandLineNotManagedHappy(c *C) {
 	c.Assert(err, IsNil)
 	c.Assert(cmdline, Equals, "snapd_recovery_mode=recover snapd_recovery_system=20200314")
 
-	cmdline, err = boot.ComposeCommandLine(model, "")
+	cmdline, err = boot.ComposeCommandLine(model, "", nil)
 	c.Assert(err, IsNil)
 	c.Assert(cmdline, Equals, "snapd_recovery_mode=run")
 }
 
+func (s *kernelCommandLineSuite) TestCommandLineIsManaged(c *C) {
+	bl := bootloadertest.Mock("btloader", c.MkDir())
+	bootloader.Force(bl)
+	defer bootloader.Force(nil)
+
+	managed, err := boot.CommandLineIsManaged()
+	c.Assert(err, IsNil)
+	c.Check(managed, Equals, false)
+
+	bootloader.Force(bl.WithTrustedAssets())
+
+	managed, err = boot.CommandLineIsManaged()
+	c.Assert(err, IsNil)
+	c.Check(managed, Equals, true)
+
+	bootloader.ForceError(errors.New("mocked error"))
+
+	_, err = boot.CommandLineIsManaged()
+	c.Check(err, ErrorMatches, `internal error: cannot find trusted assets bootloader under "": mocked error`)
+}
+
 func (s *kernelCommandLineSuite) TestComposeCommandLineNotUC20(c *C) {
 	model := boottest.MakeMockModel()
 
@@ -152,7 +174,7 @@ func (s *kernelCommandLineSuite) TestComposeCommandLineNotUC20(c *C) {
 	c.Assert(err, IsNil)
 	c.Check(cmdline, Equals, "")
 
-	cmdline, err = boot.ComposeCommandLine(model, "")
+	cmdline, err = boot.ComposeCommandLine(model, "", nil)
 	c.Assert(err, IsNil)
 	c.Check(cmdline, Equals, "")
 }
@@ -169,14 +191,14 @@ func (s *kernelCommandLineSuite) TestComposeCommandLineManagedHappy(c *C) {
 	cmdline, err := boot.ComposeRecoveryCommandLine(model, "20200314", "")
 	c.Assert(err, IsNil)
 	c.Assert(cmdline, Equals, "snapd_recovery_mode=recover snapd_recovery_system=20200314 panic=-1")
-	cmdline, err = boot.ComposeCommandLine(model, "")
+	cmdline, err = boot.ComposeCommandLine(model, "", nil)
 	c.Assert(err, IsNil)
 	c.Assert(cmdline, Equals, "snapd_recovery_mode=run panic=-1")
 
 	cmdline, err = boot.ComposeRecoveryCommandLine(model, "20200314", "")
 	c.Assert(err, IsNil)
 	c.Assert(cmdline, Equals, "snapd_recovery_mode=recover snapd_recovery_system=20200314 panic=-1")
-	cmdline, err = boot.ComposeCommandLine(model, "")
+	cmdline, err = boot.ComposeCommandLine(model, "", nil)
 	c.Assert(err, IsNil)
 	c.Assert(cmdline, Equals, "snapd_recovery_mode=run panic=-1")
 }
@@ -191,7 +213,7 @@ func (s *kernelCommandLineSuite) TestComposeCandidateCommandLineManagedHappy(c *
 	tbl.StaticCommandLine = "panic=-1"
 	tbl.CandidateStaticCommandLine = "candidate panic=0"
 
-	cmdline, err := boot.ComposeCandidateCommandLine(model, "")
+	cmdline, err := boot.ComposeCandidateCommandLine(model, "", nil)
 	c.Assert(err, IsNil)
 	c.Assert(cmdline, Equals, "snapd_recovery_mode=run candidate panic=0")
 }
@@ -233,6 +255,7 @@ func (s *kernelCommandLineSuite) TestComposeCommandLineWithGadget(c *C) {
 	for _, tc := range []struct {
 		which          string
 		files          [][]string
+		syscmdline     *boot.SystemCommandLine
 		expCommandLine string
 		errMsg         string
 	}{{
@@ -265,6 +288,35 @@ func (s *kernelCommandLineSuite) TestComposeCommandLineWithGadget(c *C) {
 			{"cmdline.extra", `bad-quote="`},
 		},
 		errMsg: `cannot use kernel command line from gadget: invalid kernel command line in cmdline.extra: unbalanced quoting`,
+	}, {
+		which:          "current",
+		syscmdline:     &boot.SystemCommandLine{Append: "isolcpus=1"},
+		expCommandLine: "snapd_recovery_mode=run panic=-1 isolcpus=1",
+	}, {
+		which: "current",
+		files: [][]string{
+			{"cmdline.extra", "cmdline extra"},
+		},
+		syscmdline:     &boot.SystemCommandLine{Append: "isolcpus=1"},
+		expCommandLine: "snapd_recovery_mode=run panic=-1 cmdline extra isolcpus=1",
+	}, {
+		which: "candidate",
+		files: [][]string{
+			{"cmdline.full", "cmdline full"},
+		},
+		syscmdline:     &boot.SystemCommandLine{Append: "isolcpus=1"},
+		expCommandLine: "snapd_recovery_mode=run cmdline full isolcpus=1",
+	}, {
+		which: "current",
+		files: [][]string{
+			{"cmdline.extra", "cmdline extra"},
+		},
+		syscmdline:     &boot.SystemCommandLine{Full: "system full", Append: "isolcpus=1"},
+		expCommandLine: "snapd_recovery_mode=run system full isolcpus=1",
+	}, {
+		which:          "candidate",
+		syscmdline:     &boot.SystemCommandLine{Full: "system full"},
+		expCommandLine: "snapd_recovery_mode=run system full",
 	}} {
 		sf := snaptest.MakeTestSnapWithFiles(c, gadgetSnapYaml, append([][]string{
 			{"meta/snap.yaml", gadgetSnapYaml},
@@ -273,9 +325,9 @@ func (s *kernelCommandLineSuite) TestComposeCommandLineWithGadget(c *C) {
 		var err error
 		switch tc.which {
 		case "current":
-			cmdline, err = boot.ComposeCommandLine(model, sf)
+			cmdline, err = boot.ComposeCommandLine(model, sf, tc.syscmdline)
 		case "candidate":
-			cmdline, err = boot.ComposeCandidateCommandLine(model, sf)
+			cmdline, err = boot.ComposeCandidateCommandLine(model, sf, tc.syscmdline)
 		default:
 			c.Fatalf("unexpected command line type")
 		}
@@ -361,6 +413,7 @@ func (s *kernelCommandLineSuite) TestBootVarsForGadgetCommandLine(c *C) {
 	for _, tc := range []struct {
 		errMsg       string
 		files        [][]string
+		syscmdline   *boot.SystemCommandLine
 		expectedVars map[string]string
 	}{{
 		files: [][]string{
@@ -398,11 +451,45 @@ func (s *kernelCommandLineSuite) TestBootVarsForGadgetCommandLine(c *C) {
 			"snapd_extra_cmdline_args": "",
 			"snapd_full_cmdline_args":  "",
 		},
+	}, {
+		files: [][]string{
+			{"cmdline.extra", "foo bar baz"},
+		},
+		syscmdline: &boot.SystemCommandLine{Append: "isolcpus=1"},
+		expectedVars: map[string]string{
+			"snapd_extra_cmdline_args": "foo bar baz isolcpus=1",
+			"snapd_full_cmdline_args":  "",
+		},
+	}, {
+		files: [][]string{
+			{"cmdline.full", "full foo bar baz"},
+		},
+		syscmdline: &boot.SystemCommandLine{Append: "isolcpus=1"},
+		expectedVars: map[string]string{
+			"snapd_extra_cmdline_args": "",
+			"snapd_full_cmdline_args":  "full foo bar baz isolcpus=1",
+		},
+	}, {
+		files: [][]string{
+			{"cmdline.extra", "foo bar baz"},
+		},
+		syscmdline: &boot.SystemCommandLine{Full: "system full"},
+		expectedVars: map[string]string{
+			"snapd_extra_cmdline_args": "",
+			"snapd_full_cmdline_args":  "system full",
+		},
+	}, {
+		files:      [][]string{},
+		syscmdline: &boot.SystemCommandLine{Append: "isolcpus=1"},
+		expectedVars: map[string]string{
+			"snapd_extra_cmdline_args": "isolcpus=1",
+			"snapd_full_cmdline_args":  "",
+		},
 	}} {
 		sf := snaptest.MakeTestSnapWithFiles(c, gadgetSnapYaml, append([][]string{
 			{"meta/snap.yaml", gadgetSnapYaml},
 		}, tc.files...))
-		vars, err := boot.BootVarsForTrustedCommandLineFromGadget(sf)
+		vars, err := boot.BootVarsForTrustedCommandLineFromGadget(sf, tc.syscmdline)
 		if tc.errMsg == "" {
 			c.Assert(err, IsNil)
 			c.Assert(vars, DeepEquals, tc.expectedVars)
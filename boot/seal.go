@@ -566,7 +566,7 @@ func recoveryBootChainsForSystems(systems []string, trbl bootloader.TrustedAsset
 			}
 
 			// get the command line
-			cmdline, err := composeCommandLine(currentEdition, ModeRecover, system, seedGadget.Path)
+			cmdline, err := composeCommandLine(currentEdition, ModeRecover, system, seedGadget.Path, nil)
 			if err != nil {
 				return fmt.Errorf("cannot obtain recovery kernel command line: %v", err)
 			}
@@ -42,6 +42,17 @@ type AppActivator struct {
 	Type    string
 	Active  bool
 	Enabled bool
+
+	// Schedule is the schedule of a timer, as given in snap.yaml.
+	Schedule string `json:"schedule,omitempty"`
+	// NextRun and LastRun are the next and last times a timer
+	// elapses, and LastResult is the result of the service run when
+	// it last elapsed. They are zero if unknown.
+	NextRun    time.Time `json:"next-run,omitempty"`
+	LastRun    time.Time `json:"last-run,omitempty"`
+	LastResult string    `json:"last-result,omitempty"`
+	// Listen are the addresses a socket listens on.
+	Listen []string `json:"listen,omitempty"`
 }
 
 // AppInfo describes a single snap application.
@@ -55,8 +66,35 @@ type AppInfo struct {
 	Active      bool             `json:"active,omitempty"`
 	CommonID    string           `json:"common-id,omitempty"`
 	Activators  []AppActivator   `json:"activators,omitempty"`
+
+	// UserStatus is the status of a user daemon in each of the user
+	// sessions, if it was asked for.
+	UserStatus []AppUserStatus `json:"user-status,omitempty"`
+}
+
+// AppUserStatus is the status of a user daemon in the session of a
+// single user.
+type AppUserStatus struct {
+	Uid     int    `json:"uid"`
+	User    string `json:"user,omitempty"`
+	Enabled bool   `json:"enabled,omitempty"`
+	Active  bool   `json:"active,omitempty"`
 }
 
+// UserSelection selects the users whose sessions user daemons are
+// inspected or controlled in.
+type UserSelection string
+
+const (
+	// UserSelectionNone only considers the system units.
+	UserSelectionNone UserSelection = ""
+	// UserSelectionSelf selects the session of the calling user.
+	UserSelectionSelf UserSelection = "self"
+	// UserSelectionAll selects the sessions of all the logged in users.
+	// It is only allowed to root and to authenticated users.
+	UserSelectionAll UserSelection = "all"
+)
+
 // IsService returns true if the application is a background daemon.
 func (a *AppInfo) IsService() bool {
 	if a == nil {
@@ -74,6 +112,10 @@ type AppOptions struct {
 	// If Service is true, only return apps that are services
 	// (app.IsService() is true); otherwise, return all.
 	Service bool
+	// Users, if set, asks for the status of the user daemons in
+	// the sessions of the selected users; only user daemons are
+	// returned then.
+	Users UserSelection
 }
 
 // Apps returns information about all matching apps. Each name can be
@@ -87,6 +129,9 @@ func (client *Client) Apps(names []string, opts AppOptions) ([]*AppInfo, error)
 	if opts.Service {
 		q.Add("select", "service")
 	}
+	if opts.Users != UserSelectionNone {
+		q.Add("users", string(opts.Users))
+	}
 
 	var appInfos []*AppInfo
 	_, err := client.doSync("GET", "/v2/apps", q, nil, nil, &appInfos)
@@ -98,6 +143,13 @@ func (client *Client) Apps(names []string, opts AppOptions) ([]*AppInfo, error)
 type LogOptions struct {
 	N      int  // The maximum number of log lines to retrieve initially. If <0, no limit.
 	Follow bool // Whether to continue returning new lines as they appear
+
+	Since    time.Time // If not zero, only return lines logged at or after this time
+	Until    time.Time // If not zero, only return lines logged at or before this time
+	Priority string    // If not empty, the lowest priority of the lines, as a syslog level name or number
+	Boot     string    // If not empty, only return lines of the "current" or "previous" boot
+	Grep     string    // If not empty, a PCRE2 regular expression the messages must match
+	Fields   bool      // Whether to return all the journal fields of the lines
 }
 
 // A Log holds the information of a single syslog entry
@@ -106,6 +158,9 @@ type Log struct {
 	Message   string    `json:"message"`   // The log message itself
 	SID       string    `json:"sid"`       // The syslog identifier
 	PID       string    `json:"pid"`       // The process identifier
+
+	// Fields has all the journal fields of the entry, if asked for.
+	Fields map[string]string `json:"fields,omitempty"`
 }
 
 func (l Log) String() string {
@@ -122,6 +177,24 @@ func (client *Client) Logs(names []string, opts LogOptions) (<-chan Log, error)
 	if opts.Follow {
 		query.Set("follow", strconv.FormatBool(opts.Follow))
 	}
+	if !opts.Since.IsZero() {
+		query.Set("since", opts.Since.Format(time.RFC3339Nano))
+	}
+	if !opts.Until.IsZero() {
+		query.Set("until", opts.Until.Format(time.RFC3339Nano))
+	}
+	if opts.Priority != "" {
+		query.Set("priority", opts.Priority)
+	}
+	if opts.Boot != "" {
+		query.Set("boot", opts.Boot)
+	}
+	if opts.Grep != "" {
+		query.Set("grep", opts.Grep)
+	}
+	if opts.Fields {
+		query.Set("fields", strconv.FormatBool(opts.Fields))
+	}
 
 	rsp, err := client.raw(context.Background(), "GET", "/v2/logs", query, nil, nil)
 	if err != nil {
@@ -174,8 +247,9 @@ func (client *Client) Logs(names []string, opts LogOptions) (<-chan Log, error)
 var ErrNoNames = errors.New(`"names" must not be empty`)
 
 type appInstruction struct {
-	Action string   `json:"action"`
-	Names  []string `json:"names"`
+	Action string        `json:"action"`
+	Names  []string      `json:"names"`
+	Users  UserSelection `json:"users,omitempty"`
 	StartOptions
 	StopOptions
 	RestartOptions
@@ -186,6 +260,9 @@ type StartOptions struct {
 	// Enable, as well as starting, the listed services. A
 	// disabled service does not start on boot.
 	Enable bool `json:"enable,omitempty"`
+	// Users, if set, only starts the user daemons in the sessions
+	// of the selected users.
+	Users UserSelection `json:"-"`
 }
 
 // Start services.
@@ -201,6 +278,7 @@ func (client *Client) Start(names []string, opts StartOptions) (changeID string,
 	buf, err := json.Marshal(appInstruction{
 		Action:       "start",
 		Names:        names,
+		Users:        opts.Users,
 		StartOptions: opts,
 	})
 	if err != nil {
@@ -214,6 +292,9 @@ type StopOptions struct {
 	// Disable, as well as stopping, the listed services. A
 	// service that is not disabled starts on boot.
 	Disable bool `json:"disable,omitempty"`
+	// Users, if set, only stops the user daemons in the sessions
+	// of the selected users.
+	Users UserSelection `json:"-"`
 }
 
 // Stop services.
@@ -229,6 +310,7 @@ func (client *Client) Stop(names []string, opts StopOptions) (changeID string, e
 	buf, err := json.Marshal(appInstruction{
 		Action:      "stop",
 		Names:       names,
+		Users:       opts.Users,
 		StopOptions: opts,
 	})
 	if err != nil {
@@ -242,6 +324,9 @@ type RestartOptions struct {
 	// Reload the services, if possible (i.e. if the App has a
 	// ReloadCommand, invoque it), instead of restarting.
 	Reload bool `json:"reload,omitempty"`
+	// Users, if set, only restarts the user daemons in the sessions
+	// of the selected users.
+	Users UserSelection `json:"-"`
 }
 
 // Restart services.
@@ -258,6 +343,7 @@ func (client *Client) Restart(names []string, opts RestartOptions) (changeID str
 	buf, err := json.Marshal(appInstruction{
 		Action:         "restart",
 		Names:          names,
+		Users:          opts.Users,
 		RestartOptions: opts,
 	})
 	if err != nil {
@@ -22,8 +22,10 @@ package client_test
 import (
 	"encoding/json"
 	"fmt"
+	"net/url"
 	"strconv"
 	"strings"
+	"time"
 
 	"gopkg.in/check.v1"
 
@@ -103,6 +105,25 @@ func (cs *clientSuite) TestClientAppCommonID(c *check.C) {
 	}
 }
 
+func (cs *clientSuite) TestClientAppsUsers(c *check.C) {
+	cs.rsp = `{"type": "sync", "result": [{"snap": "foo", "name": "svc", "daemon": "simple", "daemon-scope": "user", "user-status": [{"uid": 1000, "user": "jane", "enabled": true, "active": true}, {"uid": 1001}]}]}`
+	actual, err := cs.cli.Apps([]string{"foo"}, client.AppOptions{Service: true, Users: client.UserSelectionAll})
+	c.Assert(err, check.IsNil)
+	query := cs.req.URL.Query()
+	c.Check(query, check.HasLen, 3)
+	c.Check(query.Get("users"), check.Equals, "all")
+	c.Check(actual, check.DeepEquals, []*client.AppInfo{{
+		Snap:        "foo",
+		Name:        "svc",
+		Daemon:      "simple",
+		DaemonScope: "user",
+		UserStatus: []client.AppUserStatus{
+			{Uid: 1000, User: "jane", Enabled: true, Active: true},
+			{Uid: 1001},
+		},
+	}})
+}
+
 func testClientLogs(cs *clientSuite, c *check.C) ([]client.Log, error) {
 	ch, err := cs.cli.Logs([]string{"foo", "bar"}, client.LogOptions{N: -1, Follow: false})
 	c.Check(cs.req.URL.Path, check.Equals, "/v2/logs")
@@ -209,6 +230,42 @@ func (cs *clientSuite) TestClientLogsOpts(c *check.C) {
 	}
 }
 
+func (cs *clientSuite) TestClientLogsFilterOpts(c *check.C) {
+	ch, err := cs.cli.Logs([]string{"foo"}, client.LogOptions{
+		N:        10,
+		Since:    time.Date(2021, 3, 1, 10, 0, 0, 0, time.UTC),
+		Until:    time.Date(2021, 3, 2, 10, 0, 0, 500, time.UTC),
+		Priority: "warning",
+		Boot:     "previous",
+		Grep:     "crash",
+		Fields:   true,
+	})
+	c.Assert(err, check.IsNil)
+	for range ch {
+	}
+	c.Check(cs.req.URL.Query(), check.DeepEquals, url.Values{
+		"names":    {"foo"},
+		"n":        {"10"},
+		"since":    {"2021-03-01T10:00:00Z"},
+		"until":    {"2021-03-02T10:00:00.0000005Z"},
+		"priority": {"warning"},
+		"boot":     {"previous"},
+		"grep":     {"crash"},
+		"fields":   {"true"},
+	})
+}
+
+func (cs *clientSuite) TestClientLogsFields(c *check.C) {
+	cs.rsp = "\x1e" + `{"message":"hello","fields":{"MESSAGE":"hello","_PID":"42"}}
+`
+	logs, err := testClientLogs(cs, c)
+	c.Assert(err, check.IsNil)
+	c.Check(logs, check.DeepEquals, []client.Log{{
+		Message: "hello",
+		Fields:  map[string]string{"MESSAGE": "hello", "_PID": "42"},
+	}})
+}
+
 func (cs *clientSuite) TestClientLogsNotFound(c *check.C) {
 	cs.rsp = `{"type":"error","status-code":404,"status":"Not Found","result":{"message":"snap \"foo\" not found","kind":"snap-not-found","value":"foo"}}`
 	cs.status = 404
@@ -402,3 +459,29 @@ func (cs *clientSuite) TestClientServiceRestart(c *check.C) {
 		}
 	}
 }
+
+func (cs *clientSuite) TestClientServiceControlUsers(c *check.C) {
+	cs.status = 202
+	cs.rsp = `{"type": "async", "status-code": 202, "change": "24"}`
+
+	for _, action := range []string{"start", "stop", "restart"} {
+		var err error
+		switch action {
+		case "start":
+			_, err = cs.cli.Start([]string{"foo"}, client.StartOptions{Users: client.UserSelectionSelf})
+		case "stop":
+			_, err = cs.cli.Stop([]string{"foo"}, client.StopOptions{Users: client.UserSelectionSelf})
+		case "restart":
+			_, err = cs.cli.Restart([]string{"foo"}, client.RestartOptions{Users: client.UserSelectionSelf})
+		}
+		c.Assert(err, check.IsNil)
+
+		var reqOp map[string]interface{}
+		c.Assert(json.NewDecoder(cs.req.Body).Decode(&reqOp), check.IsNil)
+		c.Check(reqOp, check.DeepEquals, map[string]interface{}{
+			"action": action,
+			"names":  []interface{}{"foo"},
+			"users":  "self",
+		})
+	}
+}
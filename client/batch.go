@@ -0,0 +1,68 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// BatchOperation is a single operation of a batch. The action is one
+// of install, refresh, remove, revert, enable, disable and switch,
+// which operate on Snap, connect and disconnect, which operate on Plug
+// and Slot, or set, which applies Values to the configuration of Snap.
+type BatchOperation struct {
+	Action string `json:"action"`
+
+	Snap        string `json:"snap,omitempty"`
+	Channel     string `json:"channel,omitempty"`
+	Revision    string `json:"revision,omitempty"`
+	CohortKey   string `json:"cohort-key,omitempty"`
+	LeaveCohort bool   `json:"leave-cohort,omitempty"`
+	DevMode     bool   `json:"devmode,omitempty"`
+	JailMode    bool   `json:"jailmode,omitempty"`
+	Classic     bool   `json:"classic,omitempty"`
+	Purge       bool   `json:"purge,omitempty"`
+
+	Plug *PlugRef `json:"plug,omitempty"`
+	Slot *SlotRef `json:"slot,omitempty"`
+
+	Values map[string]interface{} `json:"values,omitempty"`
+}
+
+type batchData struct {
+	Operations []*BatchOperation `json:"operations"`
+}
+
+// Batch performs the given operations as a single change, in order,
+// each operation starting once the previous one is done.
+func (client *Client) Batch(ops []*BatchOperation) (changeID string, err error) {
+	data, err := json.Marshal(&batchData{Operations: ops})
+	if err != nil {
+		return "", fmt.Errorf("cannot marshal batch: %v", err)
+	}
+
+	headers := map[string]string{
+		"Content-Type": "application/json",
+	}
+
+	return client.doAsync("POST", "/v2/batch", nil, headers, bytes.NewBuffer(data))
+}
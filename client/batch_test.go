@@ -0,0 +1,64 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package client_test
+
+import (
+	"encoding/json"
+
+	"gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/client"
+)
+
+func (cs *clientSuite) TestClientBatch(c *check.C) {
+	cs.status = 202
+	cs.rsp = `{
+		"type": "async",
+		"status-code": 202,
+		"result": {},
+		"change": "42"
+	}`
+	id, err := cs.cli.Batch([]*client.BatchOperation{
+		{Action: "install", Snap: "foo", Channel: "beta"},
+		{Action: "switch", Snap: "bar", CohortKey: "some-cohort"},
+		{Action: "connect", Plug: &client.PlugRef{Snap: "foo", Name: "plug"}, Slot: &client.SlotRef{Snap: "bar", Name: "slot"}},
+		{Action: "set", Snap: "bar", Values: map[string]interface{}{"key": "value"}},
+	})
+	c.Assert(err, check.IsNil)
+	c.Check(id, check.Equals, "42")
+	c.Check(cs.req.Method, check.Equals, "POST")
+	c.Check(cs.req.URL.Path, check.Equals, "/v2/batch")
+	c.Check(cs.req.Header.Get("Content-Type"), check.Equals, "application/json")
+
+	var body map[string]interface{}
+	c.Assert(json.NewDecoder(cs.req.Body).Decode(&body), check.IsNil)
+	c.Check(body, check.DeepEquals, map[string]interface{}{
+		"operations": []interface{}{
+			map[string]interface{}{"action": "install", "snap": "foo", "channel": "beta"},
+			map[string]interface{}{"action": "switch", "snap": "bar", "cohort-key": "some-cohort"},
+			map[string]interface{}{
+				"action": "connect",
+				"plug":   map[string]interface{}{"snap": "foo", "plug": "plug"},
+				"slot":   map[string]interface{}{"snap": "bar", "slot": "slot"},
+			},
+			map[string]interface{}{"action": "set", "snap": "bar", "values": map[string]interface{}{"key": "value"}},
+		},
+	})
+}
@@ -24,6 +24,9 @@ import (
 	"encoding/json"
 	"net/url"
 	"strings"
+	"time"
+
+	"github.com/snapcore/snapd/snap/configschema"
 )
 
 // SetConf requests a snap to apply the provided patch to the configuration.
@@ -50,3 +53,90 @@ func (client *Client) Conf(snapName string, keys []string) (configuration map[st
 
 	return configuration, nil
 }
+
+// ConfSchema asks for the configuration schema declared by a snap.
+func (client *Client) ConfSchema(snapName string) (*configschema.Schema, error) {
+	query := url.Values{}
+	query.Set("schema", "true")
+
+	var schema configschema.Schema
+	if _, err := client.doSync("GET", "/v2/snaps/"+snapName+"/conf", query, nil, nil, &schema); err != nil {
+		return nil, err
+	}
+
+	return &schema, nil
+}
+
+// ConfHistoryEntry describes the configuration of a snap as it was after
+// a change of it was applied.
+type ConfHistoryEntry struct {
+	Revision int `json:"revision"`
+	// Time is zero for the configuration that predates the history.
+	Time time.Time `json:"time,omitempty"`
+	// Initiator is the username or email of the authenticated user, or
+	// "uid:<uid>" of the local user, that requested the change. It is
+	// "snapctl" or "snapd" for changes done by the snap or by snapd.
+	Initiator string                 `json:"initiator,omitempty"`
+	ChangeID  string                 `json:"change-id,omitempty"`
+	Config    map[string]interface{} `json:"config,omitempty"`
+}
+
+// ConfHistory asks for the recorded configuration changes of a snap,
+// oldest first.
+func (client *Client) ConfHistory(snapName string) ([]*ConfHistoryEntry, error) {
+	query := url.Values{}
+	query.Set("history", "true")
+
+	var history []*ConfHistoryEntry
+	if _, err := client.doSync("GET", "/v2/snaps/"+snapName+"/conf", query, nil, nil, &history); err != nil {
+		return nil, err
+	}
+
+	return history, nil
+}
+
+// RevertConf requests a snap to restore the configuration recorded in the
+// given revision of its configuration history, or the previous one if
+// revision is 0.
+func (client *Client) RevertConf(snapName string, revision int) (changeID string, err error) {
+	b, err := json.Marshal(map[string]interface{}{
+		"action":   "revert",
+		"revision": revision,
+	})
+	if err != nil {
+		return "", err
+	}
+	return client.doAsync("POST", "/v2/snaps/"+snapName+"/conf", nil, nil, bytes.NewReader(b))
+}
+
+// ExportConf asks for the whole configuration of the given snaps, keyed by
+// snap name. If no snap is given the configuration of all the snaps that
+// have some and of the system is returned. The options snapd keeps in the
+// system configuration for its own use are left out.
+//
+// Note that the configuration may include json.Numbers.
+func (client *Client) ExportConf(snapNames []string) (map[string]map[string]interface{}, error) {
+	query := url.Values{}
+	if len(snapNames) > 0 {
+		query.Set("snaps", strings.Join(snapNames, ","))
+	}
+
+	var confs map[string]map[string]interface{}
+	if _, err := client.doSync("GET", "/v2/conf", query, nil, nil, &confs); err != nil {
+		return nil, err
+	}
+
+	return confs, nil
+}
+
+// ReplaceConf requests the whole configuration of the given snaps, keyed
+// by snap name, to be replaced with the provided one. The configure hook
+// of every snap whose configuration changes is run once, if one of them
+// fails the configuration of all the snaps is restored.
+func (client *Client) ReplaceConf(confs map[string]map[string]interface{}) (changeID string, err error) {
+	b, err := json.Marshal(confs)
+	if err != nil {
+		return "", err
+	}
+	return client.doAsync("PUT", "/v2/conf", nil, nil, bytes.NewReader(b))
+}
@@ -21,8 +21,12 @@ package client_test
 
 import (
 	"encoding/json"
+	"time"
 
 	"gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/client"
+	"github.com/snapcore/snapd/snap/configschema"
 )
 
 func (cs *clientSuite) TestClientSetConfCallsEndpoint(c *check.C) {
@@ -103,3 +107,117 @@ func (cs *clientSuite) TestClientGetConfMultipleKeys(c *check.C) {
 		"test-key2": "test-value2",
 	})
 }
+
+func (cs *clientSuite) TestClientConfSchema(c *check.C) {
+	cs.rsp = `{
+		"type": "sync",
+		"status-code": 200,
+		"result": {
+			"type": "object",
+			"properties": {
+				"port": {"type": "integer", "description": "The port", "default": 80}
+			}
+		}
+	}`
+	schema, err := cs.cli.ConfSchema("snap-name")
+	c.Assert(err, check.IsNil)
+	c.Check(cs.req.Method, check.Equals, "GET")
+	c.Check(cs.req.URL.Path, check.Equals, "/v2/snaps/snap-name/conf")
+	c.Check(cs.req.URL.Query().Get("schema"), check.Equals, "true")
+	c.Check(schema.Type, check.Equals, "object")
+	c.Check(schema.Lookup("port"), check.DeepEquals, &configschema.Schema{
+		Type:        "integer",
+		Description: "The port",
+		Default:     json.Number("80"),
+	})
+}
+
+func (cs *clientSuite) TestClientConfHistory(c *check.C) {
+	cs.rsp = `{
+		"type": "sync",
+		"status-code": 200,
+		"result": [
+			{"revision": 1, "time": "0001-01-01T00:00:00Z", "config": {"key": "value1"}},
+			{"revision": 2, "time": "2021-06-01T10:00:00Z", "initiator": "user", "change-id": "42", "config": {"key": 2}}
+		]
+	}`
+	history, err := cs.cli.ConfHistory("snap-name")
+	c.Assert(err, check.IsNil)
+	c.Check(cs.req.Method, check.Equals, "GET")
+	c.Check(cs.req.URL.Path, check.Equals, "/v2/snaps/snap-name/conf")
+	c.Check(cs.req.URL.Query().Get("history"), check.Equals, "true")
+	c.Check(history, check.DeepEquals, []*client.ConfHistoryEntry{{
+		Revision: 1,
+		Config:   map[string]interface{}{"key": "value1"},
+	}, {
+		Revision:  2,
+		Time:      time.Date(2021, 6, 1, 10, 0, 0, 0, time.UTC),
+		Initiator: "user",
+		ChangeID:  "42",
+		Config:    map[string]interface{}{"key": json.Number("2")},
+	}})
+}
+
+func (cs *clientSuite) TestClientRevertConf(c *check.C) {
+	cs.status = 202
+	cs.rsp = `{
+		"type": "async",
+		"status-code": 202,
+		"result": { },
+		"change": "foo"
+	}`
+	id, err := cs.cli.RevertConf("snap-name", 3)
+	c.Assert(err, check.IsNil)
+	c.Check(id, check.Equals, "foo")
+	c.Check(cs.req.Method, check.Equals, "POST")
+	c.Check(cs.req.URL.Path, check.Equals, "/v2/snaps/snap-name/conf")
+	var body map[string]interface{}
+	c.Assert(json.NewDecoder(cs.req.Body).Decode(&body), check.IsNil)
+	c.Check(body, check.DeepEquals, map[string]interface{}{
+		"action":   "revert",
+		"revision": 3.,
+	})
+}
+
+func (cs *clientSuite) TestClientExportConf(c *check.C) {
+	cs.rsp = `{
+		"type": "sync",
+		"status-code": 200,
+		"result": {"system": {}, "snap-name": {"key": 1, "nested": {"a": "b"}}}
+	}`
+	confs, err := cs.cli.ExportConf([]string{"system", "snap-name"})
+	c.Assert(err, check.IsNil)
+	c.Check(cs.req.Method, check.Equals, "GET")
+	c.Check(cs.req.URL.Path, check.Equals, "/v2/conf")
+	c.Check(cs.req.URL.Query().Get("snaps"), check.Equals, "system,snap-name")
+	c.Check(confs, check.DeepEquals, map[string]map[string]interface{}{
+		"system":    {},
+		"snap-name": {"key": json.Number("1"), "nested": map[string]interface{}{"a": "b"}},
+	})
+
+	_, err = cs.cli.ExportConf(nil)
+	c.Assert(err, check.IsNil)
+	c.Check(cs.req.URL.RawQuery, check.Equals, "")
+}
+
+func (cs *clientSuite) TestClientReplaceConf(c *check.C) {
+	cs.status = 202
+	cs.rsp = `{
+		"type": "async",
+		"status-code": 202,
+		"result": { },
+		"change": "foo"
+	}`
+	id, err := cs.cli.ReplaceConf(map[string]map[string]interface{}{
+		"snap-name": {"key": "value"},
+	})
+	c.Assert(err, check.IsNil)
+	c.Check(id, check.Equals, "foo")
+	c.Check(cs.req.Method, check.Equals, "PUT")
+	c.Check(cs.req.URL.Path, check.Equals, "/v2/conf")
+	var body map[string]interface{}
+	c.Assert(json.NewDecoder(cs.req.Body).Decode(&body), check.IsNil)
+	c.Check(body, check.DeepEquals, map[string]interface{}{
+		"snap-name": map[string]interface{}{"key": "value"},
+	})
+}
@@ -21,6 +21,7 @@ package client
 
 import (
 	"net/url"
+	"time"
 )
 
 // Connection describes a connection between a plug and a slot.
@@ -49,6 +50,23 @@ type Connections struct {
 	Slots     []Slot       `json:"slots"`
 }
 
+// ConnectionEvent records a plug and slot being connected or
+// disconnected.
+type ConnectionEvent struct {
+	Time time.Time `json:"time"`
+	// Action is either "connect", "disconnect" or "forget".
+	Action    string  `json:"action"`
+	Plug      PlugRef `json:"plug"`
+	Slot      SlotRef `json:"slot"`
+	Interface string  `json:"interface"`
+	// Initiator is the username or email of the authenticated user, or
+	// "uid:<uid>" of the local user, that requested the change, or one
+	// of "auto-connect", "gadget", "hotplug" and "snapd".
+	Initiator string `json:"initiator"`
+	// Reason is the summary of the change that caused the event.
+	Reason string `json:"reason,omitempty"`
+}
+
 // ConnectionOptions contains criteria for selecting matching connections, plugs
 // and slots.
 type ConnectionOptions struct {
@@ -79,3 +97,18 @@ func (client *Client) Connections(opts *ConnectionOptions) (Connections, error)
 	_, err := client.doSync("GET", "/v2/connections", query, nil, nil, &conns)
 	return conns, err
 }
+
+// ConnectionHistory returns the recorded connection events, oldest
+// first, restricted to the snap and interface of the options if set.
+func (client *Client) ConnectionHistory(opts *ConnectionOptions) ([]ConnectionEvent, error) {
+	var events []ConnectionEvent
+	query := url.Values{"select": []string{"history"}}
+	if opts != nil && opts.Snap != "" {
+		query.Set("snap", opts.Snap)
+	}
+	if opts != nil && opts.Interface != "" {
+		query.Set("interface", opts.Interface)
+	}
+	_, err := client.doSync("GET", "/v2/connections", query, nil, nil, &events)
+	return events, err
+}
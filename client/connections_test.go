@@ -21,6 +21,7 @@ package client_test
 
 import (
 	"net/url"
+	"time"
 
 	"gopkg.in/check.v1"
 
@@ -268,3 +269,36 @@ func (cs *clientSuite) TestClientConnectionsFilter(c *check.C) {
 		"snap":      []string{"foo"},
 	})
 }
+
+func (cs *clientSuite) TestClientConnectionHistory(c *check.C) {
+	cs.rsp = `{
+		"type": "sync",
+		"result": [{
+			"time": "2021-02-03T09:41:18Z",
+			"action": "connect",
+			"plug": {"snap": "foo", "plug": "camera"},
+			"slot": {"snap": "core", "slot": "camera"},
+			"interface": "camera",
+			"initiator": "uid:1000",
+			"reason": "Connect foo:camera to core:camera"
+		}]
+	}`
+	events, err := cs.cli.ConnectionHistory(&client.ConnectionOptions{Snap: "foo", Interface: "camera"})
+	c.Assert(err, check.IsNil)
+	c.Check(cs.req.Method, check.Equals, "GET")
+	c.Check(cs.req.URL.Path, check.Equals, "/v2/connections")
+	c.Check(cs.req.URL.Query(), check.DeepEquals, url.Values{
+		"select":    []string{"history"},
+		"snap":      []string{"foo"},
+		"interface": []string{"camera"},
+	})
+	c.Check(events, check.DeepEquals, []client.ConnectionEvent{{
+		Time:      time.Date(2021, 2, 3, 9, 41, 18, 0, time.UTC),
+		Action:    "connect",
+		Plug:      client.PlugRef{Snap: "foo", Name: "camera"},
+		Slot:      client.SlotRef{Snap: "core", Name: "camera"},
+		Interface: "camera",
+		Initiator: "uid:1000",
+		Reason:    "Connect foo:camera to core:camera",
+	}})
+}
@@ -0,0 +1,73 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package client
+
+import (
+	"net/url"
+	"time"
+)
+
+// A Denial is an access denied by the AppArmor or seccomp sandbox of a
+// snap, as recorded in the kernel audit log.
+type Denial struct {
+	Time time.Time `json:"time"`
+	// Kind is either "apparmor" or "seccomp".
+	Kind  string `json:"kind"`
+	Label string `json:"label"`
+	Snap  string `json:"snap"`
+	App   string `json:"app,omitempty"`
+	Hook  string `json:"hook,omitempty"`
+	PID   int    `json:"pid,omitempty"`
+	Comm  string `json:"comm,omitempty"`
+
+	Operation     string `json:"operation,omitempty"`
+	Path          string `json:"path,omitempty"`
+	RequestedMask string `json:"requested-mask,omitempty"`
+	DeniedMask    string `json:"denied-mask,omitempty"`
+	Capability    string `json:"capability,omitempty"`
+
+	Syscall int `json:"syscall,omitempty"`
+	// SyscallName is the name of the syscall, when known.
+	SyscallName string `json:"syscall-name,omitempty"`
+	Arch        string `json:"arch,omitempty"`
+
+	// Suggestions lists the interfaces that would grant the access.
+	Suggestions []DenialSuggestion `json:"suggestions,omitempty"`
+}
+
+// A DenialSuggestion is an interface that would grant a denied access.
+type DenialSuggestion struct {
+	Interface string `json:"interface"`
+	// Plug is the plug of the snap for the interface, if it has one.
+	Plug      string `json:"plug,omitempty"`
+	Connected bool   `json:"connected,omitempty"`
+}
+
+// Denials returns the sandbox denials of the snaps recorded since the
+// system booted, optionally restricted to the given snap.
+func (client *Client) Denials(snapName string) ([]*Denial, error) {
+	q := make(url.Values)
+	if snapName != "" {
+		q.Set("snap", snapName)
+	}
+	var denials []*Denial
+	_, err := client.doSync("GET", "/v2/debug/denials", q, nil, nil, &denials)
+	return denials, err
+}
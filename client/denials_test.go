@@ -0,0 +1,73 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package client_test
+
+import (
+	"time"
+
+	"gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/client"
+)
+
+func (cs *clientSuite) TestClientDenials(c *check.C) {
+	cs.rsp = `{
+		"type": "sync",
+		"result": [{
+			"time": "2021-02-03T09:41:18Z",
+			"kind": "apparmor",
+			"label": "snap.foo.app",
+			"snap": "foo",
+			"app": "app",
+			"operation": "open",
+			"path": "/sys/class/net/eth0/statistics/rx_bytes",
+			"requested-mask": "r",
+			"denied-mask": "r",
+			"suggestions": [{"interface": "network-observe", "plug": "network-observe"}]
+		}]
+	}`
+	denials, err := cs.cli.Denials("foo")
+	c.Assert(err, check.IsNil)
+	c.Check(cs.req.Method, check.Equals, "GET")
+	c.Check(cs.req.URL.Path, check.Equals, "/v2/debug/denials")
+	c.Check(cs.req.URL.Query().Get("snap"), check.Equals, "foo")
+	c.Check(denials, check.DeepEquals, []*client.Denial{{
+		Time:          time.Date(2021, 2, 3, 9, 41, 18, 0, time.UTC),
+		Kind:          "apparmor",
+		Label:         "snap.foo.app",
+		Snap:          "foo",
+		App:           "app",
+		Operation:     "open",
+		Path:          "/sys/class/net/eth0/statistics/rx_bytes",
+		RequestedMask: "r",
+		DeniedMask:    "r",
+		Suggestions: []client.DenialSuggestion{
+			{Interface: "network-observe", Plug: "network-observe"},
+		},
+	}})
+}
+
+func (cs *clientSuite) TestClientDenialsAllSnaps(c *check.C) {
+	cs.rsp = `{"type": "sync", "result": []}`
+	denials, err := cs.cli.Denials("")
+	c.Assert(err, check.IsNil)
+	c.Check(denials, check.HasLen, 0)
+	c.Check(cs.req.URL.Query(), check.HasLen, 0)
+}
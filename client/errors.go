@@ -51,6 +51,9 @@ const (
 	ErrorKindPasswordPolicy ErrorKind = "password-policy"
 	// ErrorKindAuthCancelled: authentication was cancelled by the user.
 	ErrorKindAuthCancelled ErrorKind = "auth-cancelled"
+	// ErrorKindAccessPolicyDenied: the local access policy does not
+	// allow the user to perform the requested operation.
+	ErrorKindAccessPolicyDenied ErrorKind = "access-policy-denied"
 
 	// ErrorKindTermsNotAccepted: deprecated, do not document.
 	ErrorKindTermsNotAccepted ErrorKind = "terms-not-accepted"
@@ -124,6 +127,9 @@ const (
 	// ErrorKindConfigNoSuchOption: the given configuration option
 	// does not exist.
 	ErrorKindConfigNoSuchOption ErrorKind = "option-not-found"
+	// ErrorKindConfigInvalidOption: the given configuration value
+	// does not match the configuration schema of the snap.
+	ErrorKindConfigInvalidOption ErrorKind = "option-invalid"
 
 	// ErrorKindAssertionNotFound: assertion can not be found.
 	ErrorKindAssertionNotFound ErrorKind = "assertion-not-found"
@@ -0,0 +1,66 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// CheckPolicyOptions selects the plug and slot whose connection policy
+// is checked.
+type CheckPolicyOptions struct {
+	// Plug and Slot are in the <snap>:<name> form.
+	Plug string `json:"plug"`
+	Slot string `json:"slot"`
+	// Snaps are snap.yaml documents of plug or slot snaps to check
+	// instead of the installed ones.
+	Snaps []string `json:"snaps,omitempty"`
+	// SnapDeclarations are snap-declaration assertions to check
+	// against instead of the ones in the system assertion database.
+	SnapDeclarations []string `json:"snap-declarations,omitempty"`
+}
+
+// A PolicyCheck is the outcome of one of the checks of the policy
+// governing a connection.
+type PolicyCheck struct {
+	// Check is one of "plug-installation", "slot-installation",
+	// "connection" and "auto-connection".
+	Check   string `json:"check"`
+	Allowed bool   `json:"allowed"`
+	// Rule describes the declaration rule that decided the check.
+	Rule string `json:"rule,omitempty"`
+	// Explanation tells which alternative of the rule matched and on
+	// which constraints, or why none of them did.
+	Explanation string `json:"explanation,omitempty"`
+	Error       string `json:"error,omitempty"`
+}
+
+// CheckPolicy evaluates the installation, connection and
+// auto-connection policies for the given plug and slot.
+func (client *Client) CheckPolicy(opts *CheckPolicyOptions) ([]PolicyCheck, error) {
+	b, err := json.Marshal(opts)
+	if err != nil {
+		return nil, err
+	}
+	var checks []PolicyCheck
+	_, err = client.doSync("POST", "/v2/debug/check-policy", nil, nil, bytes.NewReader(b), &checks)
+	return checks, err
+}
@@ -0,0 +1,59 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package client_test
+
+import (
+	"encoding/json"
+
+	"gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/client"
+)
+
+func (cs *clientSuite) TestClientCheckPolicy(c *check.C) {
+	cs.rsp = `{
+		"type": "sync",
+		"result": [
+			{"check": "plug-installation", "allowed": true},
+			{"check": "connection", "allowed": false, "rule": "slot rule of interface \"test\" in the base declaration", "explanation": "no alternative matched", "error": "connection not allowed"}
+		]
+	}`
+	checks, err := cs.cli.CheckPolicy(&client.CheckPolicyOptions{
+		Plug:             "foo:plug",
+		Slot:             "bar:slot",
+		Snaps:            []string{"name: foo\n..."},
+		SnapDeclarations: []string{"type: snap-declaration\n..."},
+	})
+	c.Assert(err, check.IsNil)
+	c.Check(cs.req.Method, check.Equals, "POST")
+	c.Check(cs.req.URL.Path, check.Equals, "/v2/debug/check-policy")
+	var body map[string]interface{}
+	c.Assert(json.NewDecoder(cs.req.Body).Decode(&body), check.IsNil)
+	c.Check(body, check.DeepEquals, map[string]interface{}{
+		"plug":              "foo:plug",
+		"slot":              "bar:slot",
+		"snaps":             []interface{}{"name: foo\n..."},
+		"snap-declarations": []interface{}{"type: snap-declaration\n..."},
+	})
+	c.Check(checks, check.DeepEquals, []client.PolicyCheck{
+		{Check: "plug-installation", Allowed: true},
+		{Check: "connection", Rule: `slot rule of interface "test" in the base declaration`, Explanation: "no alternative matched", Error: "connection not allowed"},
+	})
+}
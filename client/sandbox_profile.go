@@ -0,0 +1,59 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package client
+
+import (
+	"net/url"
+)
+
+// SandboxProfile holds the security snippets generated for an app or
+// hook of a snap by each of the security backends.
+type SandboxProfile struct {
+	SecurityTag string                  `json:"security-tag"`
+	Backends    []SandboxProfileBackend `json:"backends"`
+}
+
+// SandboxProfileBackend holds the snippets generated by a security
+// backend.
+type SandboxProfileBackend struct {
+	Name     string                  `json:"name"`
+	Snippets []SandboxProfileSnippet `json:"snippets,omitempty"`
+}
+
+// SandboxProfileSnippet is a snippet contributed by an interface. Both
+// the plug and the slot are set when it comes from their connection,
+// and only one of them when it comes from the plug or slot on its own.
+type SandboxProfileSnippet struct {
+	Interface string `json:"interface"`
+	Plug      string `json:"plug,omitempty"`
+	Slot      string `json:"slot,omitempty"`
+	Snippet   string `json:"snippet"`
+}
+
+// SandboxProfile returns the security snippets generated for the given
+// app or hook, in the <snap>.<app> or <snap>.hook.<hook> form.
+func (client *Client) SandboxProfile(app string) (*SandboxProfile, error) {
+	q := url.Values{"app": []string{app}}
+	var profile SandboxProfile
+	if _, err := client.doSync("GET", "/v2/debug/sandbox-profile", q, nil, nil, &profile); err != nil {
+		return nil, err
+	}
+	return &profile, nil
+}
@@ -0,0 +1,62 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package client_test
+
+import (
+	"gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/client"
+)
+
+func (cs *clientSuite) TestClientSandboxProfile(c *check.C) {
+	cs.rsp = `{
+		"type": "sync",
+		"result": {
+			"security-tag": "snap.foo.app",
+			"backends": [
+				{"name": "apparmor", "snippets": [
+					{"interface": "network", "plug": "foo:network", "slot": "core:network", "snippet": "network inet,\n"}
+				]},
+				{"name": "kmod"}
+			]
+		}
+	}`
+	profile, err := cs.cli.SandboxProfile("foo.app")
+	c.Assert(err, check.IsNil)
+	c.Check(cs.req.Method, check.Equals, "GET")
+	c.Check(cs.req.URL.Path, check.Equals, "/v2/debug/sandbox-profile")
+	c.Check(cs.req.URL.Query().Get("app"), check.Equals, "foo.app")
+	c.Check(profile, check.DeepEquals, &client.SandboxProfile{
+		SecurityTag: "snap.foo.app",
+		Backends: []client.SandboxProfileBackend{
+			{Name: "apparmor", Snippets: []client.SandboxProfileSnippet{
+				{Interface: "network", Plug: "foo:network", Slot: "core:network", Snippet: "network inet,\n"},
+			}},
+			{Name: "kmod"},
+		},
+	})
+}
+
+func (cs *clientSuite) TestClientSandboxProfileError(c *check.C) {
+	cs.status = 404
+	cs.rsp = `{"type": "error", "result": {"message": "snap \"foo\" is not installed", "kind": "snap-not-found"}}`
+	_, err := cs.cli.SandboxProfile("foo.app")
+	c.Assert(err, check.ErrorMatches, `snap "foo" is not installed`)
+}
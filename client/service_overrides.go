@@ -0,0 +1,98 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/url"
+	"strings"
+
+	"golang.org/x/xerrors"
+)
+
+// ServiceOverride holds the settings of a service overridden by the
+// administrator.
+type ServiceOverride struct {
+	Snap string `json:"snap"`
+	App  string `json:"app"`
+	// Settings are the overridden settings, by key as given to
+	// SetServiceOverride.
+	Settings map[string]string `json:"settings"`
+}
+
+type postServiceOverrideData struct {
+	Action   string            `json:"action"`
+	Name     string            `json:"name"`
+	Settings map[string]string `json:"settings,omitempty"`
+	Keys     []string          `json:"keys,omitempty"`
+}
+
+func (client *Client) postServiceOverride(data *postServiceOverrideData) (changeID string, err error) {
+	var body bytes.Buffer
+	if err := json.NewEncoder(&body).Encode(data); err != nil {
+		return "", err
+	}
+	return client.doAsync("POST", "/v2/service-overrides", nil, nil, &body)
+}
+
+// SetServiceOverride overrides the given settings of a service, named as
+// snap.app. The keys of the settings are restart, restart-delay,
+// start-timeout, stop-timeout, watchdog-timeout and environment.<NAME>.
+func (client *Client) SetServiceOverride(name string, settings map[string]string) (changeID string, err error) {
+	if name == "" {
+		return "", xerrors.Errorf("cannot override service settings without a service name")
+	}
+	if len(settings) == 0 {
+		return "", xerrors.Errorf("cannot override service settings without settings")
+	}
+	return client.postServiceOverride(&postServiceOverrideData{
+		Action:   "set",
+		Name:     name,
+		Settings: settings,
+	})
+}
+
+// ResetServiceOverride resets the given overridden settings of a service,
+// named as snap.app, or all of them if no keys are given.
+func (client *Client) ResetServiceOverride(name string, keys []string) (changeID string, err error) {
+	if name == "" {
+		return "", xerrors.Errorf("cannot reset service settings without a service name")
+	}
+	return client.postServiceOverride(&postServiceOverrideData{
+		Action: "reset",
+		Name:   name,
+		Keys:   keys,
+	})
+}
+
+// ServiceOverrides lists the settings overridden for services, optionally
+// only for the given snaps or snap.app services.
+func (client *Client) ServiceOverrides(names []string) ([]*ServiceOverride, error) {
+	q := make(url.Values)
+	if len(names) > 0 {
+		q.Set("names", strings.Join(names, ","))
+	}
+	var res []*ServiceOverride
+	if _, err := client.doSync("GET", "/v2/service-overrides", q, nil, nil, &res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
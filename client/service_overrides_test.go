@@ -0,0 +1,107 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package client_test
+
+import (
+	"encoding/json"
+
+	"gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/client"
+)
+
+func (cs *clientSuite) TestSetServiceOverride(c *check.C) {
+	cs.status = 202
+	cs.rsp = `{
+		"type": "async",
+		"status-code": 202,
+		"change": "42"
+	}`
+
+	chgID, err := cs.cli.SetServiceOverride("foo.svc", map[string]string{
+		"restart":         "always",
+		"environment.FOO": "bar",
+	})
+	c.Assert(err, check.IsNil)
+	c.Check(chgID, check.Equals, "42")
+	c.Check(cs.req.Method, check.Equals, "POST")
+	c.Check(cs.req.URL.Path, check.Equals, "/v2/service-overrides")
+	var req map[string]interface{}
+	c.Assert(json.NewDecoder(cs.req.Body).Decode(&req), check.IsNil)
+	c.Check(req, check.DeepEquals, map[string]interface{}{
+		"action": "set",
+		"name":   "foo.svc",
+		"settings": map[string]interface{}{
+			"restart":         "always",
+			"environment.FOO": "bar",
+		},
+	})
+}
+
+func (cs *clientSuite) TestSetServiceOverrideInvalid(c *check.C) {
+	_, err := cs.cli.SetServiceOverride("", map[string]string{"restart": "always"})
+	c.Check(err, check.ErrorMatches, `cannot override service settings without a service name`)
+	_, err = cs.cli.SetServiceOverride("foo.svc", nil)
+	c.Check(err, check.ErrorMatches, `cannot override service settings without settings`)
+}
+
+func (cs *clientSuite) TestResetServiceOverride(c *check.C) {
+	cs.status = 202
+	cs.rsp = `{
+		"type": "async",
+		"status-code": 202,
+		"change": "42"
+	}`
+
+	chgID, err := cs.cli.ResetServiceOverride("foo.svc", []string{"restart"})
+	c.Assert(err, check.IsNil)
+	c.Check(chgID, check.Equals, "42")
+	c.Check(cs.req.Method, check.Equals, "POST")
+	c.Check(cs.req.URL.Path, check.Equals, "/v2/service-overrides")
+	var req map[string]interface{}
+	c.Assert(json.NewDecoder(cs.req.Body).Decode(&req), check.IsNil)
+	c.Check(req, check.DeepEquals, map[string]interface{}{
+		"action": "reset",
+		"name":   "foo.svc",
+		"keys":   []interface{}{"restart"},
+	})
+
+	_, err = cs.cli.ResetServiceOverride("", nil)
+	c.Check(err, check.ErrorMatches, `cannot reset service settings without a service name`)
+}
+
+func (cs *clientSuite) TestServiceOverrides(c *check.C) {
+	cs.rsp = `{
+		"type": "sync",
+		"status-code": 200,
+		"result": [{"snap": "foo", "app": "svc", "settings": {"restart": "always"}}]
+	}`
+
+	overrides, err := cs.cli.ServiceOverrides([]string{"foo", "bar.svc"})
+	c.Assert(err, check.IsNil)
+	c.Check(cs.req.Method, check.Equals, "GET")
+	c.Check(cs.req.URL.Path, check.Equals, "/v2/service-overrides")
+	c.Check(cs.req.URL.Query().Get("names"), check.Equals, "foo,bar.svc")
+	c.Check(overrides, check.DeepEquals, []*client.ServiceOverride{{
+		Snap:     "foo",
+		App:      "svc",
+		Settings: map[string]string{"restart": "always"},
+	}})
+}
@@ -0,0 +1,83 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package client
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"golang.org/x/xerrors"
+
+	"github.com/snapcore/snapd/gadget/quantity"
+)
+
+// ResourceUsage describes the resources used by running processes.
+type ResourceUsage struct {
+	// CPUTime is the total CPU time consumed so far.
+	CPUTime   time.Duration `json:"cpu-time"`
+	Memory    quantity.Size `json:"memory"`
+	Processes int           `json:"processes"`
+	IORead    quantity.Size `json:"io-read"`
+	IOWrite   quantity.Size `json:"io-write"`
+	// Unavailable lists the resources, out of cpu-time, memory and io,
+	// whose usage is not accounted for on the system.
+	Unavailable []string `json:"unavailable,omitempty"`
+}
+
+// AppUsage is the resource usage of the processes of an app or hook, the
+// name of hooks is of the form hook.<name>.
+type AppUsage struct {
+	Name string `json:"name"`
+	ResourceUsage
+}
+
+// SnapUsage is the resource usage of all the processes of a snap, and of
+// each of its apps and hooks with running processes.
+type SnapUsage struct {
+	Snap string `json:"snap"`
+	ResourceUsage
+	Apps []AppUsage `json:"apps,omitempty"`
+}
+
+// SnapUsage returns the resource usage of the processes of the given snap.
+func (client *Client) SnapUsage(name string) (*SnapUsage, error) {
+	var usage SnapUsage
+	path := fmt.Sprintf("/v2/snaps/%s/usage", name)
+	if _, err := client.doSync("GET", path, nil, nil, nil, &usage); err != nil {
+		return nil, xerrors.Errorf("cannot get resource usage of snap %q: %w", name, err)
+	}
+	return &usage, nil
+}
+
+// Usage returns the resource usage of the processes of the given snaps, or
+// of all installed snaps if none are given.
+func (client *Client) Usage(names []string) ([]SnapUsage, error) {
+	q := url.Values{}
+	if len(names) > 0 {
+		q.Set("snaps", strings.Join(names, ","))
+	}
+	var usage []SnapUsage
+	if _, err := client.doSync("GET", "/v2/usage", q, nil, nil, &usage); err != nil {
+		return nil, xerrors.Errorf("cannot get resource usage of snaps: %w", err)
+	}
+	return usage, nil
+}
@@ -0,0 +1,111 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package client_test
+
+import (
+	"time"
+
+	"gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/client"
+)
+
+func (cs *clientSuite) TestClientSnapUsage(c *check.C) {
+	cs.rsp = `{
+		"type": "sync",
+		"result": {
+			"snap": "foo",
+			"cpu-time": 2000000000,
+			"memory": 4096,
+			"processes": 3,
+			"io-read": 10,
+			"io-write": 20,
+			"apps": [
+				{"name": "app", "cpu-time": 1000000000, "memory": 1024, "processes": 1, "io-read": 10, "io-write": 0},
+				{"name": "hook.configure", "cpu-time": 1000000000, "memory": 3072, "processes": 2, "io-read": 0, "io-write": 20}
+			]
+		}
+	}`
+	usage, err := cs.cli.SnapUsage("foo")
+	c.Assert(err, check.IsNil)
+	c.Check(cs.req.Method, check.Equals, "GET")
+	c.Check(cs.req.URL.Path, check.Equals, "/v2/snaps/foo/usage")
+	c.Check(usage, check.DeepEquals, &client.SnapUsage{
+		Snap: "foo",
+		ResourceUsage: client.ResourceUsage{
+			CPUTime: 2 * time.Second, Memory: 4096, Processes: 3, IORead: 10, IOWrite: 20,
+		},
+		Apps: []client.AppUsage{{
+			Name: "app",
+			ResourceUsage: client.ResourceUsage{
+				CPUTime: time.Second, Memory: 1024, Processes: 1, IORead: 10,
+			},
+		}, {
+			Name: "hook.configure",
+			ResourceUsage: client.ResourceUsage{
+				CPUTime: time.Second, Memory: 3072, Processes: 2, IOWrite: 20,
+			},
+		}},
+	})
+}
+
+func (cs *clientSuite) TestClientSnapUsageError(c *check.C) {
+	cs.status = 404
+	cs.rsp = `{"type": "error", "result": {"message": "snap \"foo\" is not installed", "kind": "snap-not-found"}}`
+	_, err := cs.cli.SnapUsage("foo")
+	c.Assert(err, check.ErrorMatches, `cannot get resource usage of snap "foo": snap "foo" is not installed`)
+}
+
+func (cs *clientSuite) TestClientUsage(c *check.C) {
+	cs.rsp = `{
+		"type": "sync",
+		"result": [
+			{"snap": "bar", "cpu-time": 0, "memory": 0, "processes": 0, "io-read": 0, "io-write": 0, "unavailable": ["io", "memory"]},
+			{"snap": "foo", "cpu-time": 1000000000, "memory": 1024, "processes": 1, "io-read": 0, "io-write": 0,
+			 "apps": [{"name": "app", "cpu-time": 1000000000, "memory": 1024, "processes": 1, "io-read": 0, "io-write": 0}]}
+		]
+	}`
+	usage, err := cs.cli.Usage([]string{"foo", "bar"})
+	c.Assert(err, check.IsNil)
+	c.Check(cs.req.Method, check.Equals, "GET")
+	c.Check(cs.req.URL.Path, check.Equals, "/v2/usage")
+	c.Check(cs.req.URL.Query().Get("snaps"), check.Equals, "foo,bar")
+	c.Check(usage, check.DeepEquals, []client.SnapUsage{{
+		Snap: "bar",
+		ResourceUsage: client.ResourceUsage{
+			Unavailable: []string{"io", "memory"},
+		},
+	}, {
+		Snap: "foo",
+		ResourceUsage: client.ResourceUsage{
+			CPUTime: time.Second, Memory: 1024, Processes: 1,
+		},
+		Apps: []client.AppUsage{{
+			Name: "app",
+			ResourceUsage: client.ResourceUsage{
+				CPUTime: time.Second, Memory: 1024, Processes: 1,
+			},
+		}},
+	}})
+
+	_, err = cs.cli.Usage(nil)
+	c.Assert(err, check.IsNil)
+	c.Check(cs.req.URL.RawQuery, check.Equals, "")
+}
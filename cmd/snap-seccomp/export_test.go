@@ -24,6 +24,7 @@ var (
 	SeccompResolver   = seccompResolver
 	VersionInfo       = versionInfo
 	GoSeccompFeatures = goSeccompFeatures
+	SyscallNames      = syscallNames
 )
 
 func MockArchDpkgArchitecture(f func() string) (restore func()) {
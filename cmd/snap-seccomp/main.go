@@ -832,6 +832,54 @@ func showSeccompLibraryVersion() error {
 	return nil
 }
 
+// auditArchToScmpArch maps the architectures of the seccomp audit
+// records, the AUDIT_ARCH_* values of <linux/audit.h> in hex, to the
+// seccomp.ScmpArch as used in the libseccomp-golang library.
+var auditArchToScmpArch = map[string]seccomp.ScmpArch{
+	"c000003e": seccomp.ArchAMD64,
+	"40000003": seccomp.ArchX86,
+	"c00000b7": seccomp.ArchARM64,
+	"40000028": seccomp.ArchARM,
+	"14":       seccomp.ArchPPC,
+	"80000015": seccomp.ArchPPC64,
+	"c0000015": seccomp.ArchPPC64LE,
+	"80000016": seccomp.ArchS390X,
+}
+
+// syscallNames returns the name of each of the given syscall numbers of
+// the architecture of a seccomp audit record, as "<number> <name>"
+// lines. Syscalls unknown to libseccomp are left out.
+func syscallNames(auditArch string, nrs []string) ([]string, error) {
+	scmpArch, ok := auditArchToScmpArch[strings.ToLower(auditArch)]
+	if !ok {
+		return nil, fmt.Errorf("unsupported audit architecture %q", auditArch)
+	}
+	lines := make([]string, 0, len(nrs))
+	for _, s := range nrs {
+		nr, err := strconv.ParseInt(s, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid syscall number %q", s)
+		}
+		name, err := seccomp.ScmpSyscall(nr).GetNameByArch(scmpArch)
+		if err != nil {
+			continue
+		}
+		lines = append(lines, fmt.Sprintf("%d %s", nr, name))
+	}
+	return lines, nil
+}
+
+func showSyscallNames(auditArch string, nrs []string) error {
+	lines, err := syscallNames(auditArch, nrs)
+	if err != nil {
+		return err
+	}
+	for _, line := range lines {
+		fmt.Fprintln(os.Stdout, line)
+	}
+	return nil
+}
+
 func main() {
 	var err error
 	var content []byte
@@ -857,6 +905,12 @@ func main() {
 		err = showSeccompLibraryVersion()
 	case "version-info":
 		err = showVersionInfo()
+	case "syscall-names":
+		if len(os.Args) < 3 {
+			fmt.Println("syscall-names needs an audit architecture")
+			os.Exit(1)
+		}
+		err = showSyscallNames(os.Args[2], os.Args[3:])
 	default:
 		err = fmt.Errorf("unsupported argument %q", cmd)
 	}
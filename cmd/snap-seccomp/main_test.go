@@ -364,6 +364,22 @@ func (s *snapSeccompSuite) TestUnrestricted(c *C) {
 	c.Check(outPath, testutil.FileEquals, inp)
 }
 
+func (s *snapSeccompSuite) TestSyscallNames(c *C) {
+	// amd64 and i386 number their syscalls differently
+	lines, err := main.SyscallNames("c000003e", []string{"2", "165", "99999"})
+	c.Assert(err, IsNil)
+	c.Check(lines, DeepEquals, []string{"2 open", "165 mount"})
+
+	lines, err = main.SyscallNames("40000003", []string{"5", "21"})
+	c.Assert(err, IsNil)
+	c.Check(lines, DeepEquals, []string{"5 open", "21 mount"})
+
+	_, err = main.SyscallNames("1234", []string{"2"})
+	c.Check(err, ErrorMatches, `unsupported audit architecture "1234"`)
+	_, err = main.SyscallNames("c000003e", []string{"open"})
+	c.Check(err, ErrorMatches, `invalid syscall number "open"`)
+}
+
 // TestCompile iterates over a range of textual seccomp whitelist rules and
 // mocked kernel syscall input. For each rule, the test consists of compiling
 // the rule into a bpf program and then running that program on a virtual bpf
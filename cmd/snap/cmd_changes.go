@@ -43,6 +43,7 @@ change.
 type cmdChanges struct {
 	clientMixin
 	timeMixin
+	formatMixin
 	Positional struct {
 		Snap string `positional-arg-name:"<snap>"`
 	} `positional-args:"yes"`
@@ -50,6 +51,7 @@ type cmdChanges struct {
 
 type cmdTasks struct {
 	timeMixin
+	formatMixin
 	changeIDMixin
 }
 
@@ -106,6 +108,14 @@ func (c *cmdChanges) Execute(args []string) error {
 		return err
 	}
 
+	if c.structuredOutput() {
+		sort.Sort(changesByTime(changes))
+		if changes == nil {
+			changes = []*client.Change{}
+		}
+		return c.printStructured(changes)
+	}
+
 	if len(changes) == 0 {
 		fmt.Fprintln(Stderr, i18n.G("no changes found"))
 		return nil
@@ -160,6 +170,10 @@ func (c *cmdTasks) showChange(chid string) error {
 		return err
 	}
 
+	if c.structuredOutput() {
+		return c.printStructured(chg)
+	}
+
 	w := tabWriter()
 
 	fmt.Fprintf(w, i18n.G("Status\tSpawn\tReady\tSummary\n"))
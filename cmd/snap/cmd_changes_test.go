@@ -20,6 +20,7 @@
 package main_test
 
 import (
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"strings"
@@ -69,6 +70,26 @@ Do +2016-04-21T01:02:03Z +2016-04-21T01:02:04Z +some summary
 	c.Check(s.Stderr(), check.Equals, "")
 }
 
+func (s *SnapSuite) TestTasksFormatJSON(c *check.C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		c.Check(r.URL.Path, check.Equals, "/v2/changes/42")
+		fmt.Fprintln(w, mockChangeJSON)
+	})
+	rest, err := snap.Parser(snap.Client()).ParseArgs([]string{"tasks", "--format=json", "42"})
+	c.Assert(err, check.IsNil)
+	c.Assert(rest, check.DeepEquals, []string{})
+
+	var chg map[string]interface{}
+	c.Assert(json.Unmarshal([]byte(s.Stdout()), &chg), check.IsNil)
+	c.Check(chg["id"], check.Equals, "uno")
+	c.Check(chg["kind"], check.Equals, "foo")
+	tasks, ok := chg["tasks"].([]interface{})
+	c.Assert(ok, check.Equals, true)
+	c.Assert(tasks, check.HasLen, 1)
+	c.Check(tasks[0].(map[string]interface{})["summary"], check.Equals, "some summary")
+	c.Check(s.Stderr(), check.Equals, "")
+}
+
 func (s *SnapSuite) TestChangeSimpleRebooting(c *check.C) {
 	n := 0
 	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
@@ -250,3 +271,25 @@ func (s *SnapSuite) TestNoChanges(c *check.C) {
 	c.Assert(err, check.IsNil)
 	c.Check(s.Stderr(), check.Equals, "no changes found\n")
 }
+
+func (s *SnapSuite) TestChangesFormatYAML(c *check.C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		c.Check(r.URL.Path, check.Equals, "/v2/changes")
+		fmt.Fprintln(w, mockChangesJSON)
+	})
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"changes", "--format=yaml"})
+	c.Assert(err, check.IsNil)
+	// sorted by spawn time as in the table
+	c.Check(s.Stdout(), check.Matches, `(?ms)^- id: four$.*^- id: three$.*^- id: one$.*^- id: two$.*`)
+	c.Check(s.Stderr(), check.Equals, "")
+}
+
+func (s *SnapSuite) TestNoChangesFormatJSON(c *check.C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, `{"type": "sync", "result": []}`)
+	})
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"changes", "--format=json"})
+	c.Assert(err, check.IsNil)
+	c.Check(s.Stdout(), check.Equals, "[]\n")
+	c.Check(s.Stderr(), check.Equals, "")
+}
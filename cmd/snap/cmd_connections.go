@@ -32,7 +32,13 @@ import (
 
 type cmdConnections struct {
 	clientMixin
-	All         bool `long:"all"`
+	timeMixin
+	// Format shadows the global --format option as it can also be
+	// "dot"
+	Format      string `long:"format" choice:"json" choice:"yaml" choice:"dot"`
+	All         bool   `long:"all"`
+	History     bool   `long:"history"`
+	Graph       bool   `long:"graph"`
 	Positionals struct {
 		Snap installedSnapName
 	} `positional-args:"true"`
@@ -51,14 +57,32 @@ $ snap connections <snap>
 
 Lists connected and unconnected plugs and slots for the specified
 snap.
+
+$ snap connections --history [<snap>]
+
+Lists when plugs and slots were connected and disconnected, who or what
+initiated it and why, optionally for the specified snap only.
+
+$ snap connections --graph [--format=json|yaml|dot] [<snap>]
+
+Prints snaps as nodes and the connections between their plugs and slots
+as edges, including unconnected plugs and slots, in the dot language of
+graphviz unless another format is requested. --format=dot implies
+--graph.
 `)
 
 func init() {
 	addCommand("connections", shortConnectionsHelp, longConnectionsHelp, func() flags.Commander {
 		return &cmdConnections{}
-	}, map[string]string{
-		"all": i18n.G("Show connected and unconnected plugs and slots"),
-	}, []argDesc{{
+	}, timeDescs.also(map[string]string{
+		// TRANSLATORS: This should not start with a lowercase letter.
+		"format": i18n.G("Print the output as json or yaml instead of a table, or as a graph in the dot language"),
+		"all":    i18n.G("Show connected and unconnected plugs and slots"),
+		// TRANSLATORS: This should not start with a lowercase letter.
+		"history": i18n.G("Show the history of connections instead"),
+		// TRANSLATORS: This should not start with a lowercase letter.
+		"graph": i18n.G("Show the connections as a graph"),
+	}), []argDesc{{
 		// TRANSLATORS: This needs to be wrapped in <>s.
 		name: "<snap>",
 		// TRANSLATORS: This should not start with a lowercase letter.
@@ -131,11 +155,36 @@ func interfaceDeterminant(conn *client.Connection) string {
 	return fmt.Sprintf("[%v]", value)
 }
 
+// structuredOutput makes the command accept the global --format
+// option too.
+func (x *cmdConnections) structuredOutput() bool {
+	return x.Format != "" || optionsData.Format != ""
+}
+
 func (x *cmdConnections) Execute(args []string) error {
 	if len(args) > 0 {
 		return ErrExtraArgs
 	}
 
+	if x.Format == "" {
+		x.Format = optionsData.Format
+	}
+	if x.Format == "dot" {
+		x.Graph = true
+	}
+	if x.History {
+		if x.All {
+			return fmt.Errorf(i18n.G("cannot use --all with --history"))
+		}
+		if x.Graph {
+			return fmt.Errorf(i18n.G("cannot show the history of connections as a graph"))
+		}
+		return x.showHistory()
+	}
+	if x.Graph {
+		return x.showGraph()
+	}
+
 	opts := client.ConnectionOptions{
 		All: x.All,
 	}
@@ -158,6 +207,9 @@ func (x *cmdConnections) Execute(args []string) error {
 	if err != nil {
 		return err
 	}
+	if x.Format != "" {
+		return printStructured(x.Format, connections)
+	}
 	if len(connections.Plugs) == 0 && len(connections.Slots) == 0 {
 		return nil
 	}
@@ -212,3 +264,53 @@ func (x *cmdConnections) Execute(args []string) error {
 	}
 	return nil
 }
+
+func (x *cmdConnections) showHistory() error {
+	events, err := x.client.ConnectionHistory(&client.ConnectionOptions{
+		Snap: string(x.Positionals.Snap),
+	})
+	if err != nil {
+		return err
+	}
+	if x.Format != "" {
+		return printStructured(x.Format, events)
+	}
+	if len(events) == 0 {
+		fmt.Fprintln(Stderr, i18n.G("No connection events found."))
+		return nil
+	}
+
+	w := tabWriter()
+	defer w.Flush()
+	fmt.Fprintln(w, i18n.G("Time\tAction\tInterface\tPlug\tSlot\tInitiator\tReason"))
+	for _, event := range events {
+		reason := event.Reason
+		if reason == "" {
+			reason = "-"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", x.fmtTime(event.Time), event.Action, event.Interface,
+			endpoint(event.Plug.Snap, event.Plug.Name), endpoint(event.Slot.Snap, event.Slot.Name), event.Initiator, reason)
+	}
+	return nil
+}
+
+func (x *cmdConnections) showGraph() error {
+	wanted := string(x.Positionals.Snap)
+	if x.All && wanted != "" {
+		return fmt.Errorf(i18n.G("cannot use --all with snap name"))
+	}
+	// unconnected plugs and slots are part of the graph
+	connections, err := x.client.Connections(&client.ConnectionOptions{
+		Snap: wanted,
+		All:  true,
+	})
+	if err != nil {
+		return err
+	}
+
+	graph := newConnectionGraph(&connections, wanted)
+	if x.Format == "json" || x.Format == "yaml" {
+		return printStructured(x.Format, graph)
+	}
+	return graph.writeDot(Stdout)
+}
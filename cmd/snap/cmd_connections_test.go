@@ -20,6 +20,7 @@
 package main_test
 
 import (
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"net/http"
@@ -851,3 +852,189 @@ func (s *SnapSuite) TestConnectionsDefiningAttribute(c *C) {
 	c.Assert(s.Stdout(), Equals, expectedStdout)
 	c.Assert(s.Stderr(), Equals, "")
 }
+
+func (s *SnapSuite) TestConnectionsHistory(c *C) {
+	query := url.Values{
+		"select": []string{"history"},
+		"snap":   []string{"foo"},
+	}
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		c.Check(r.Method, Equals, "GET")
+		c.Check(r.URL.Path, Equals, "/v2/connections")
+		c.Check(r.URL.Query(), DeepEquals, query)
+		fmt.Fprintln(w, `{"type": "sync", "result": [
+  {"time": "2021-02-03T09:41:18Z", "action": "connect", "plug": {"snap": "foo", "plug": "camera"}, "slot": {"snap": "core", "slot": "camera"}, "interface": "camera", "initiator": "uid:1000", "reason": "Connect foo:camera to core:camera"},
+  {"time": "2021-02-03T10:00:00Z", "action": "disconnect", "plug": {"snap": "foo", "plug": "camera"}, "slot": {"snap": "core", "slot": "camera"}, "interface": "camera", "initiator": "hotplug"}
+]}`)
+	})
+	rest, err := Parser(Client()).ParseArgs([]string{"connections", "--history", "--abs-time", "foo"})
+	c.Assert(err, IsNil)
+	c.Assert(rest, DeepEquals, []string{})
+	c.Check(s.Stdout(), Equals, ""+
+		"Time                  Action      Interface  Plug        Slot     Initiator  Reason\n"+
+		"2021-02-03T09:41:18Z  connect     camera     foo:camera  :camera  uid:1000   Connect foo:camera to core:camera\n"+
+		"2021-02-03T10:00:00Z  disconnect  camera     foo:camera  :camera  hotplug    -\n")
+	c.Check(s.Stderr(), Equals, "")
+}
+
+func (s *SnapSuite) TestConnectionsHistoryEmpty(c *C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		c.Check(r.URL.Query(), DeepEquals, url.Values{"select": []string{"history"}})
+		fmt.Fprintln(w, `{"type": "sync", "result": []}`)
+	})
+	_, err := Parser(Client()).ParseArgs([]string{"connections", "--history"})
+	c.Assert(err, IsNil)
+	c.Check(s.Stdout(), Equals, "")
+	c.Check(s.Stderr(), Equals, "No connection events found.\n")
+}
+
+func (s *SnapSuite) TestConnectionsHistoryWithAll(c *C) {
+	_, err := Parser(Client()).ParseArgs([]string{"connections", "--history", "--all"})
+	c.Assert(err, ErrorMatches, "cannot use --all with --history")
+}
+
+const connectionsGraphResult = `{"type": "sync", "result": {
+  "established": [
+    {"plug": {"snap": "foo", "plug": "data"}, "slot": {"snap": "bar", "slot": "files"}, "interface": "content", "manual": true, "plug-attrs": {"content": "shared"}},
+    {"plug": {"snap": "foo", "plug": "network"}, "slot": {"snap": "core", "slot": "network"}, "interface": "network", "gadget": true}
+  ],
+  "plugs": [
+    {"snap": "foo", "plug": "network", "interface": "network", "connections": [{"snap": "core", "slot": "network"}]},
+    {"snap": "foo", "plug": "data", "interface": "content", "connections": [{"snap": "bar", "slot": "files"}]},
+    {"snap": "foo", "plug": "camera", "interface": "camera"}
+  ],
+  "slots": [
+    {"snap": "bar", "slot": "files", "interface": "content", "connections": [{"snap": "foo", "plug": "data"}]},
+    {"snap": "bar", "slot": "dbus-svc", "interface": "dbus"},
+    {"snap": "core", "slot": "network", "interface": "network", "connections": [{"snap": "foo", "plug": "network"}]},
+    {"snap": "core", "slot": "camera", "interface": "camera"}
+  ]
+}}`
+
+func (s *SnapSuite) TestConnectionsGraphDot(c *C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		c.Check(r.URL.Path, Equals, "/v2/connections")
+		c.Check(r.URL.Query(), DeepEquals, url.Values{"select": []string{"all"}})
+		fmt.Fprintln(w, connectionsGraphResult)
+	})
+	for _, args := range [][]string{
+		{"connections", "--graph"},
+		{"connections", "--format=dot"},
+	} {
+		s.ResetStdStreams()
+		rest, err := Parser(Client()).ParseArgs(args)
+		c.Assert(err, IsNil)
+		c.Assert(rest, DeepEquals, []string{})
+		c.Check(s.Stdout(), Equals, `digraph connections {
+	rankdir=LR;
+	node [shape=box];
+	"bar";
+	"core";
+	"foo";
+	"foo" -> "bar" [label="content[shared]", taillabel="data", headlabel="files", style=solid];
+	"foo" -> "core" [label="network", taillabel="network", headlabel="network", style=dashed, color=blue];
+	"bar:slot:dbus-svc" [label="dbus-svc", shape=plaintext];
+	"bar:slot:dbus-svc" -> "bar" [label="dbus", style=dotted];
+	"foo:plug:camera" [label="camera", shape=plaintext];
+	"foo" -> "foo:plug:camera" [label="camera", style=dotted];
+}
+`, Commentf("%v", args))
+		c.Check(s.Stderr(), Equals, "")
+	}
+}
+
+func (s *SnapSuite) TestConnectionsGraphJSON(c *C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		c.Check(r.URL.Query(), DeepEquals, url.Values{"select": []string{"all"}, "snap": []string{"foo"}})
+		fmt.Fprintln(w, connectionsGraphResult)
+	})
+	_, err := Parser(Client()).ParseArgs([]string{"connections", "--graph", "--format=json", "foo"})
+	c.Assert(err, IsNil)
+	var graph map[string]interface{}
+	c.Assert(json.Unmarshal([]byte(s.Stdout()), &graph), IsNil)
+	c.Check(graph["nodes"], DeepEquals, []interface{}{
+		map[string]interface{}{
+			"snap": "bar",
+			"slots": []interface{}{
+				map[string]interface{}{"name": "dbus-svc", "interface": "dbus", "connected": false},
+				map[string]interface{}{"name": "files", "interface": "content", "connected": true},
+			},
+		},
+		map[string]interface{}{
+			"snap": "core",
+			"slots": []interface{}{
+				map[string]interface{}{"name": "network", "interface": "network", "connected": true},
+			},
+		},
+		map[string]interface{}{
+			"snap": "foo",
+			"plugs": []interface{}{
+				map[string]interface{}{"name": "camera", "interface": "camera", "connected": false},
+				map[string]interface{}{"name": "data", "interface": "content", "connected": true},
+				map[string]interface{}{"name": "network", "interface": "network", "connected": true},
+			},
+		},
+	})
+	c.Check(graph["edges"], DeepEquals, []interface{}{
+		map[string]interface{}{
+			"plug":      map[string]interface{}{"snap": "foo", "plug": "data"},
+			"slot":      map[string]interface{}{"snap": "bar", "slot": "files"},
+			"interface": "content",
+			"label":     "content[shared]",
+			"auto":      false,
+		},
+		map[string]interface{}{
+			"plug":      map[string]interface{}{"snap": "foo", "plug": "network"},
+			"slot":      map[string]interface{}{"snap": "core", "slot": "network"},
+			"interface": "network",
+			"label":     "network",
+			"auto":      true,
+			"gadget":    true,
+		},
+	})
+}
+
+func (s *SnapSuite) TestConnectionsGraphWithHistory(c *C) {
+	_, err := Parser(Client()).ParseArgs([]string{"connections", "--history", "--format=dot"})
+	c.Assert(err, ErrorMatches, "cannot show the history of connections as a graph")
+}
+
+func (s *SnapSuite) TestConnectionsFormat(c *C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		c.Check(r.URL.Path, Equals, "/v2/connections")
+		c.Check(r.URL.Query(), DeepEquals, url.Values{"select": []string{"all"}, "snap": []string{"foo"}})
+		fmt.Fprintln(w, connectionsGraphResult)
+	})
+	for _, args := range [][]string{
+		{"connections", "--format=json", "foo"},
+		// the global option is honoured too
+		{"--format=json", "connections", "foo"},
+	} {
+		s.ResetStdStreams()
+		_, err := Parser(Client()).ParseArgs(args)
+		c.Assert(err, IsNil, Commentf("%v", args))
+		var conns map[string]interface{}
+		c.Assert(json.Unmarshal([]byte(s.Stdout()), &conns), IsNil)
+		c.Check(conns["established"], HasLen, 2)
+		c.Check(conns["plugs"], HasLen, 3)
+		c.Check(conns["slots"], HasLen, 4)
+		c.Check(s.Stderr(), Equals, "")
+	}
+
+	s.ResetStdStreams()
+	_, err := Parser(Client()).ParseArgs([]string{"connections", "--format=yaml", "foo"})
+	c.Assert(err, IsNil)
+	c.Check(s.Stdout(), Matches, `(?ms)^established:\n- gadget: false\n  interface: content\n  manual: true\n.*`)
+	c.Check(s.Stdout(), Matches, `(?ms).*^  plug-attrs:\n    content: shared\n.*`)
+}
+
+func (s *SnapSuite) TestConnectionsHistoryFormatJSON(c *C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		c.Check(r.URL.Query(), DeepEquals, url.Values{"select": []string{"history"}})
+		fmt.Fprintln(w, `{"type": "sync", "result": []}`)
+	})
+	_, err := Parser(Client()).ParseArgs([]string{"connections", "--history", "--format=json"})
+	c.Assert(err, IsNil)
+	c.Check(s.Stdout(), Equals, "[]\n")
+	c.Check(s.Stderr(), Equals, "")
+}
@@ -0,0 +1,144 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+
+	"github.com/jessevdk/go-flags"
+
+	"github.com/snapcore/snapd/client"
+	"github.com/snapcore/snapd/i18n"
+	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/snap/snapfile"
+)
+
+type cmdDebugCheckPolicy struct {
+	clientMixin
+	formatMixin
+	Plug             string   `long:"plug" value-name:"<snap>:<plug>" required:"yes"`
+	Slot             string   `long:"slot" value-name:"<snap>:<slot>" required:"yes"`
+	Snaps            []string `long:"snap" value-name:"<file>"`
+	SnapDeclarations []string `long:"snap-declaration" value-name:"<file>"`
+}
+
+var shortDebugCheckPolicyHelp = i18n.G("Explain the policy deciding a connection")
+var longDebugCheckPolicyHelp = i18n.G(`
+The check-policy command evaluates the rules of the base declaration and
+of the snap declarations deciding whether the given plug and slot may be
+installed, connected and auto-connected, and shows which alternative
+of which rule decided each of them and on which constraints.
+
+The plug and slot snaps are the installed ones unless they are given
+with --snap, as a snap file or its snap.yaml, which allows to check snaps
+before they are installed.
+
+The snap declarations in the system assertion database are used unless
+snap declarations for the plug or slot snaps are given with
+--snap-declaration, which allows to try declarations before they are
+published.
+`)
+
+func init() {
+	addDebugCommand("check-policy", shortDebugCheckPolicyHelp, longDebugCheckPolicyHelp, func() flags.Commander {
+		return &cmdDebugCheckPolicy{}
+	}, map[string]string{
+		// TRANSLATORS: This should not start with a lowercase letter.
+		"plug": i18n.G("The plug to check, as <snap>:<plug>"),
+		// TRANSLATORS: This should not start with a lowercase letter.
+		"slot": i18n.G("The slot to check, as <snap>:<slot>"),
+		// TRANSLATORS: This should not start with a lowercase letter.
+		"snap": i18n.G("Use the snap in the given snap file or snap.yaml (can be repeated)"),
+		// TRANSLATORS: This should not start with a lowercase letter.
+		"snap-declaration": i18n.G("Use the snap declaration in the given file (can be repeated)"),
+	}, nil)
+}
+
+// readSnapYaml returns the snap.yaml of the given snap file or directory,
+// or the content of the file if it is not a snap.
+func readSnapYaml(path string) ([]byte, error) {
+	container, err := snapfile.Open(path)
+	if err != nil {
+		if _, ok := err.(snap.NotSnapError); ok {
+			return ioutil.ReadFile(path)
+		}
+		return nil, err
+	}
+	return container.ReadFile("meta/snap.yaml")
+}
+
+func fmtPolicyCheckExplanation(check *client.PolicyCheck) string {
+	switch {
+	case check.Explanation != "":
+		return check.Explanation
+	case check.Error != "":
+		return check.Error
+	case check.Rule != "":
+		return fmt.Sprintf(i18n.G("allowed by %s"), check.Rule)
+	}
+	return i18n.G("no rule")
+}
+
+func (x *cmdDebugCheckPolicy) Execute(args []string) error {
+	if len(args) > 0 {
+		return ErrExtraArgs
+	}
+
+	opts := &client.CheckPolicyOptions{
+		Plug: x.Plug,
+		Slot: x.Slot,
+	}
+	for _, fn := range x.Snaps {
+		data, err := readSnapYaml(fn)
+		if err != nil {
+			return fmt.Errorf(i18n.G("cannot read snap: %v"), err)
+		}
+		opts.Snaps = append(opts.Snaps, string(data))
+	}
+	for _, fn := range x.SnapDeclarations {
+		data, err := ioutil.ReadFile(fn)
+		if err != nil {
+			return fmt.Errorf(i18n.G("cannot read snap declaration: %v"), err)
+		}
+		opts.SnapDeclarations = append(opts.SnapDeclarations, string(data))
+	}
+
+	checks, err := x.client.CheckPolicy(opts)
+	if err != nil {
+		return err
+	}
+	if x.structuredOutput() {
+		return x.printStructured(checks)
+	}
+
+	w := tabWriter()
+	defer w.Flush()
+	fmt.Fprintln(w, i18n.G("Check\tResult\tExplanation"))
+	for i := range checks {
+		check := &checks[i]
+		result := i18n.G("allowed")
+		if !check.Allowed {
+			result = i18n.G("denied")
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", check.Check, result, fmtPolicyCheckExplanation(check))
+	}
+	return nil
+}
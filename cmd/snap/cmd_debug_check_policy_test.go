@@ -0,0 +1,114 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package main_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/check.v1"
+
+	snap "github.com/snapcore/snapd/cmd/snap"
+)
+
+func (s *SnapSuite) TestDebugCheckPolicy(c *check.C) {
+	declFile := filepath.Join(c.MkDir(), "decl.assert")
+	c.Assert(ioutil.WriteFile(declFile, []byte("type: snap-declaration\n"), 0644), check.IsNil)
+	snapYaml := filepath.Join(c.MkDir(), "snap.yaml")
+	c.Assert(ioutil.WriteFile(snapYaml, []byte("name: foo\n"), 0644), check.IsNil)
+	// a snap given as a directory, like with snap try
+	snapDir := c.MkDir()
+	c.Assert(os.Mkdir(filepath.Join(snapDir, "meta"), 0755), check.IsNil)
+	c.Assert(ioutil.WriteFile(filepath.Join(snapDir, "meta", "snap.yaml"), []byte("name: core\n"), 0644), check.IsNil)
+
+	n := 0
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		switch n {
+		case 0:
+			c.Check(r.Method, check.Equals, "POST")
+			c.Check(r.URL.Path, check.Equals, "/v2/debug/check-policy")
+			c.Check(DecodedRequestBody(c, r), check.DeepEquals, map[string]interface{}{
+				"plug":              "foo:firewall-control",
+				"slot":              "core:firewall-control",
+				"snaps":             []interface{}{"name: foo\n", "name: core\n"},
+				"snap-declarations": []interface{}{"type: snap-declaration\n"},
+			})
+			fmt.Fprintln(w, `{"type": "sync", "result": [
+  {"check": "plug-installation", "allowed": true},
+  {"check": "slot-installation", "allowed": true, "rule": "slot rule of interface \"firewall-control\" in the base declaration", "explanation": "alternative 1 of allow-installation in slot rule of interface \"firewall-control\" in the base declaration matched on slot-snap-type"},
+  {"check": "connection", "allowed": true, "rule": "slot rule of interface \"firewall-control\" in the base declaration"},
+  {"check": "auto-connection", "allowed": false, "rule": "slot rule of interface \"firewall-control\" in the base declaration", "error": "auto-connection denied by slot rule of interface \"firewall-control\""}
+]}`)
+		default:
+			c.Fatalf("expected to get 1 requests, now on %d", n+1)
+		}
+		n++
+	})
+	rest, err := snap.Parser(snap.Client()).ParseArgs([]string{"debug", "check-policy", "--plug", "foo:firewall-control", "--slot", "core:firewall-control", "--snap", snapYaml, "--snap", snapDir, "--snap-declaration", declFile})
+	c.Assert(err, check.IsNil)
+	c.Assert(rest, check.DeepEquals, []string{})
+	c.Check(s.Stdout(), check.Equals, `Check              Result   Explanation
+plug-installation  allowed  no rule
+slot-installation  allowed  alternative 1 of allow-installation in slot rule of interface "firewall-control" in the base declaration matched on slot-snap-type
+connection         allowed  allowed by slot rule of interface "firewall-control" in the base declaration
+auto-connection    denied   auto-connection denied by slot rule of interface "firewall-control"
+`)
+	c.Check(s.Stderr(), check.Equals, "")
+	c.Check(n, check.Equals, 1)
+}
+
+func (s *SnapSuite) TestDebugCheckPolicyMissingDeclaration(c *check.C) {
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"debug", "check-policy", "--plug", "foo:plug", "--slot", "bar:slot", "--snap-declaration", filepath.Join(c.MkDir(), "missing")})
+	c.Assert(err, check.ErrorMatches, `cannot read snap declaration: open .*/missing: no such file or directory`)
+}
+
+func (s *SnapSuite) TestDebugCheckPolicyMissingSnap(c *check.C) {
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"debug", "check-policy", "--plug", "foo:plug", "--slot", "bar:slot", "--snap", filepath.Join(c.MkDir(), "missing")})
+	c.Assert(err, check.ErrorMatches, `cannot read snap: open .*/missing: no such file or directory`)
+}
+
+func (s *SnapSuite) TestDebugCheckPolicyRequiresPlugAndSlot(c *check.C) {
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"debug", "check-policy", "--plug", "foo:plug"})
+	c.Assert(err, check.ErrorMatches, "the required flag `--slot' was not specified")
+}
+
+func (s *SnapSuite) TestDebugCheckPolicyFormatYAML(c *check.C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		c.Check(r.URL.Path, check.Equals, "/v2/debug/check-policy")
+		fmt.Fprintln(w, `{"type": "sync", "result": [
+  {"check": "plug-installation", "allowed": true},
+  {"check": "auto-connection", "allowed": false, "rule": "slot rule of interface \"firewall-control\" in the base declaration", "error": "auto-connection denied by slot rule of interface \"firewall-control\""}
+]}`)
+	})
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"debug", "check-policy", "--format=yaml", "--plug", "foo:firewall-control", "--slot", "core:firewall-control"})
+	c.Assert(err, check.IsNil)
+	c.Check(s.Stdout(), check.Equals, `
+- allowed: true
+  check: plug-installation
+- allowed: false
+  check: auto-connection
+  error: auto-connection denied by slot rule of interface "firewall-control"
+  rule: slot rule of interface "firewall-control" in the base declaration
+`[1:])
+	c.Check(s.Stderr(), check.Equals, "")
+}
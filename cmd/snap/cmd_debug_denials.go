@@ -0,0 +1,129 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jessevdk/go-flags"
+
+	"github.com/snapcore/snapd/client"
+	"github.com/snapcore/snapd/i18n"
+)
+
+type cmdDebugDenials struct {
+	clientMixin
+	timeMixin
+	formatMixin
+	Positionals struct {
+		Snap installedSnapName
+	} `positional-args:"true"`
+}
+
+var shortDebugDenialsHelp = i18n.G("List the sandbox denials of snaps")
+var longDebugDenialsHelp = i18n.G(`
+The denials command lists the accesses denied by the AppArmor and
+seccomp sandbox to the apps and hooks of snaps since the system booted,
+as recorded in the kernel audit log.
+
+For each denial, the interfaces that would grant the access are
+suggested, noting whether the snap has a plug for the interface and
+whether it is connected.
+`)
+
+func init() {
+	addDebugCommand("denials", shortDebugDenialsHelp, longDebugDenialsHelp, func() flags.Commander {
+		return &cmdDebugDenials{}
+	}, timeDescs, []argDesc{{
+		// TRANSLATORS: This needs to be wrapped in <>s.
+		name: "<snap>",
+		// TRANSLATORS: This should not start with a lowercase letter.
+		desc: i18n.G("Constrain listing to a specific snap"),
+	}})
+}
+
+func fmtDenial(d *client.Denial) string {
+	switch {
+	case d.Kind == "seccomp" && d.SyscallName != "":
+		return fmt.Sprintf("syscall %s (%d)", d.SyscallName, d.Syscall)
+	case d.Kind == "seccomp":
+		return fmt.Sprintf("syscall %d", d.Syscall)
+	case d.Capability != "":
+		return fmt.Sprintf("capability %s", d.Capability)
+	case d.Path != "":
+		return fmt.Sprintf("%s %s (%s)", d.Operation, d.Path, d.DeniedMask)
+	}
+	return d.Operation
+}
+
+func fmtDenialSuggestions(d *client.Denial) string {
+	if len(d.Suggestions) == 0 {
+		return "-"
+	}
+	suggestions := make([]string, len(d.Suggestions))
+	for i, s := range d.Suggestions {
+		switch {
+		case s.Connected:
+			// TRANSLATORS: %s is the name of an interface the snap is connected to
+			suggestions[i] = fmt.Sprintf(i18n.G("%s (connected)"), s.Interface)
+		case s.Plug != "":
+			// TRANSLATORS: %s is the name of an interface the snap has a disconnected plug for
+			suggestions[i] = fmt.Sprintf(i18n.G("%s (disconnected)"), s.Interface)
+		default:
+			// TRANSLATORS: %s is the name of an interface the snap has no plug for
+			suggestions[i] = fmt.Sprintf(i18n.G("%s (no plug)"), s.Interface)
+		}
+	}
+	return strings.Join(suggestions, ", ")
+}
+
+func (x *cmdDebugDenials) Execute(args []string) error {
+	if len(args) > 0 {
+		return ErrExtraArgs
+	}
+
+	denials, err := x.client.Denials(string(x.Positionals.Snap))
+	if err != nil {
+		return err
+	}
+	if x.structuredOutput() {
+		return x.printStructured(denials)
+	}
+	if len(denials) == 0 {
+		fmt.Fprintln(Stderr, i18n.G("No denials found."))
+		return nil
+	}
+
+	w := tabWriter()
+	defer w.Flush()
+	fmt.Fprintln(w, i18n.G("Time\tSnap\tApp\tKind\tDenial\tSuggestion"))
+	for _, d := range denials {
+		app := d.App
+		switch {
+		case d.Hook != "":
+			app = "hook:" + d.Hook
+		case app == "":
+			app = "-"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", x.fmtTime(d.Time), d.Snap, app, d.Kind, fmtDenial(d), fmtDenialSuggestions(d))
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package main_test
+
+import (
+	"fmt"
+	"net/http"
+
+	"gopkg.in/check.v1"
+
+	snap "github.com/snapcore/snapd/cmd/snap"
+)
+
+const denialsJSON = `{"type": "sync", "result": [
+  {"time": "2021-02-03T09:41:18Z", "kind": "apparmor", "label": "snap.foo.app", "snap": "foo", "app": "app",
+   "operation": "open", "path": "/proc/42/net/dev", "requested-mask": "r", "denied-mask": "r",
+   "suggestions": [{"interface": "network-observe", "plug": "netobs", "connected": true}, {"interface": "network-control"}]},
+  {"time": "2021-02-03T09:41:19Z", "kind": "apparmor", "label": "snap.foo.hook.configure", "snap": "foo", "hook": "configure",
+   "operation": "capable", "capability": "net_admin",
+   "suggestions": [{"interface": "firewall-control", "plug": "firewall-control"}]},
+  {"time": "2021-02-03T09:41:20Z", "kind": "seccomp", "label": "snap.foo.app", "snap": "foo", "app": "app", "syscall": 165, "arch": "c000003e"},
+  {"time": "2021-02-03T09:41:21Z", "kind": "seccomp", "label": "snap.foo.app", "snap": "foo", "app": "app", "syscall": 165, "syscall-name": "mount", "arch": "c000003e",
+   "suggestions": [{"interface": "network-control"}]}
+]}`
+
+func (s *SnapSuite) TestDebugDenials(c *check.C) {
+	n := 0
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		switch n {
+		case 0:
+			c.Check(r.Method, check.Equals, "GET")
+			c.Check(r.URL.Path, check.Equals, "/v2/debug/denials")
+			c.Check(r.URL.RawQuery, check.Equals, "snap=foo")
+			fmt.Fprintln(w, denialsJSON)
+		default:
+			c.Fatalf("expected to get 1 requests, now on %d", n+1)
+		}
+		n++
+	})
+	rest, err := snap.Parser(snap.Client()).ParseArgs([]string{"debug", "denials", "--abs-time", "foo"})
+	c.Assert(err, check.IsNil)
+	c.Assert(rest, check.DeepEquals, []string{})
+	c.Check(s.Stdout(), check.Equals, `
+Time                  Snap  App             Kind      Denial                     Suggestion
+2021-02-03T09:41:18Z  foo   app             apparmor  open /proc/42/net/dev (r)  network-observe (connected), network-control (no plug)
+2021-02-03T09:41:19Z  foo   hook:configure  apparmor  capability net_admin       firewall-control (disconnected)
+2021-02-03T09:41:20Z  foo   app             seccomp   syscall 165                -
+2021-02-03T09:41:21Z  foo   app             seccomp   syscall mount (165)        network-control (no plug)
+`[1:])
+	c.Check(s.Stderr(), check.Equals, "")
+	c.Check(n, check.Equals, 1)
+}
+
+func (s *SnapSuite) TestDebugDenialsNone(c *check.C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		c.Check(r.URL.RawQuery, check.Equals, "")
+		fmt.Fprintln(w, `{"type": "sync", "result": []}`)
+	})
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"debug", "denials"})
+	c.Assert(err, check.IsNil)
+	c.Check(s.Stdout(), check.Equals, "")
+	c.Check(s.Stderr(), check.Equals, "No denials found.\n")
+}
+
+func (s *SnapSuite) TestDebugDenialsJSON(c *check.C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, `{"type": "sync", "result": [{"time": "2021-02-03T09:41:20Z", "kind": "seccomp", "label": "snap.foo.app", "snap": "foo", "app": "app", "syscall": 165}]}`)
+	})
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"debug", "denials", "--format=json"})
+	c.Assert(err, check.IsNil)
+	c.Check(s.Stdout(), check.Equals, `[
+  {
+    "time": "2021-02-03T09:41:20Z",
+    "kind": "seccomp",
+    "label": "snap.foo.app",
+    "snap": "foo",
+    "app": "app",
+    "syscall": 165
+  }
+]
+`)
+}
@@ -0,0 +1,103 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jessevdk/go-flags"
+
+	"github.com/snapcore/snapd/client"
+	"github.com/snapcore/snapd/i18n"
+)
+
+type cmdDebugSandboxProfile struct {
+	clientMixin
+	formatMixin
+	Positionals struct {
+		App string `positional-arg-name:"<snap.app>" required:"yes"`
+	} `positional-args:"true"`
+}
+
+var shortDebugSandboxProfileHelp = i18n.G("Show the security profile snippets of an app")
+var longDebugSandboxProfileHelp = i18n.G(`
+The sandbox-profile command shows, for each security backend, the
+snippets generated for the given app or hook of a snap by its plugs,
+slots and connections, with the interface contributing each of them.
+
+Hooks are given as <snap>.hook.<hook>.
+`)
+
+func init() {
+	addDebugCommand("sandbox-profile", shortDebugSandboxProfileHelp, longDebugSandboxProfileHelp, func() flags.Commander {
+		return &cmdDebugSandboxProfile{}
+	}, nil, []argDesc{{
+		// TRANSLATORS: This needs to be wrapped in <>s.
+		name: "<snap.app>",
+		// TRANSLATORS: This should not start with a lowercase letter.
+		desc: i18n.G("The app or hook to show the profile of"),
+	}})
+}
+
+func fmtSnippetSource(s *client.SandboxProfileSnippet) string {
+	switch {
+	case s.Plug != "" && s.Slot != "":
+		return fmt.Sprintf("%s (%s, %s)", s.Interface, s.Plug, s.Slot)
+	case s.Plug != "":
+		return fmt.Sprintf("%s (%s)", s.Interface, s.Plug)
+	default:
+		return fmt.Sprintf("%s (%s)", s.Interface, s.Slot)
+	}
+}
+
+func (x *cmdDebugSandboxProfile) Execute(args []string) error {
+	if len(args) > 0 {
+		return ErrExtraArgs
+	}
+
+	profile, err := x.client.SandboxProfile(x.Positionals.App)
+	if err != nil {
+		return err
+	}
+	if x.structuredOutput() {
+		return x.printStructured(profile)
+	}
+
+	for _, backend := range profile.Backends {
+		fmt.Fprintf(Stdout, "%s:\n", backend.Name)
+		if len(backend.Snippets) == 0 {
+			fmt.Fprintln(Stdout, "  -")
+			continue
+		}
+		for i := range backend.Snippets {
+			snippet := &backend.Snippets[i]
+			fmt.Fprintf(Stdout, "  %s:\n", fmtSnippetSource(snippet))
+			for _, line := range strings.Split(strings.TrimRight(snippet.Snippet, "\n"), "\n") {
+				if line == "" {
+					fmt.Fprintln(Stdout)
+					continue
+				}
+				fmt.Fprintf(Stdout, "    %s\n", line)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package main_test
+
+import (
+	"fmt"
+	"net/http"
+
+	"gopkg.in/check.v1"
+
+	snap "github.com/snapcore/snapd/cmd/snap"
+)
+
+func (s *SnapSuite) TestDebugSandboxProfile(c *check.C) {
+	n := 0
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		switch n {
+		case 0:
+			c.Check(r.Method, check.Equals, "GET")
+			c.Check(r.URL.Path, check.Equals, "/v2/debug/sandbox-profile")
+			c.Check(r.URL.RawQuery, check.Equals, "app=foo.app")
+			fmt.Fprintln(w, `{"type": "sync", "result": {
+  "security-tag": "snap.foo.app",
+  "backends": [
+    {"name": "apparmor", "snippets": [
+      {"interface": "network", "plug": "foo:network", "slot": "core:network", "snippet": "# network\n\nnetwork inet,\n"},
+      {"interface": "content", "plug": "foo:data", "snippet": "/data r,\n"}
+    ]},
+    {"name": "kmod"}
+  ]
+}}`)
+		default:
+			c.Fatalf("expected to get 1 requests, now on %d", n+1)
+		}
+		n++
+	})
+	rest, err := snap.Parser(snap.Client()).ParseArgs([]string{"debug", "sandbox-profile", "foo.app"})
+	c.Assert(err, check.IsNil)
+	c.Assert(rest, check.DeepEquals, []string{})
+	c.Check(s.Stdout(), check.Equals, `apparmor:
+  network (foo:network, core:network):
+    # network
+
+    network inet,
+  content (foo:data):
+    /data r,
+kmod:
+  -
+`)
+	c.Check(s.Stderr(), check.Equals, "")
+	c.Check(n, check.Equals, 1)
+}
+
+func (s *SnapSuite) TestDebugSandboxProfileRequiresApp(c *check.C) {
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"debug", "sandbox-profile"})
+	c.Assert(err, check.ErrorMatches, "the required argument `<snap.app>` was not provided")
+}
+
+func (s *SnapSuite) TestDebugSandboxProfileFormatJSON(c *check.C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		c.Check(r.URL.Path, check.Equals, "/v2/debug/sandbox-profile")
+		fmt.Fprintln(w, `{"type": "sync", "result": {
+  "security-tag": "snap.foo.app",
+  "backends": [
+    {"name": "apparmor", "snippets": [{"interface": "content", "plug": "foo:data", "snippet": "/data r,\n"}]},
+    {"name": "kmod"}
+  ]
+}}`)
+	})
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"debug", "sandbox-profile", "--format=json", "foo.app"})
+	c.Assert(err, check.IsNil)
+	c.Check(s.Stdout(), check.Equals, `{
+  "security-tag": "snap.foo.app",
+  "backends": [
+    {
+      "name": "apparmor",
+      "snippets": [
+        {
+          "interface": "content",
+          "plug": "foo:data",
+          "snippet": "/data r,\n"
+        }
+      ]
+    },
+    {
+      "name": "kmod"
+    }
+  ]
+}
+`)
+	c.Check(s.Stderr(), check.Equals, "")
+}
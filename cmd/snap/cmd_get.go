@@ -22,12 +22,15 @@ package main
 import (
 	"encoding/json"
 	"fmt"
+	"reflect"
 	"sort"
 	"strings"
 
 	"github.com/jessevdk/go-flags"
+	"gopkg.in/yaml.v2"
 
 	"github.com/snapcore/snapd/i18n"
+	"github.com/snapcore/snapd/snap/configschema"
 )
 
 var shortGetHelp = i18n.G("Print configuration options")
@@ -48,30 +51,64 @@ Nested values may be retrieved via a dotted path:
 
     $ snap get snap-name author.name
     frank
+
+Options the snap declares a default for in its configuration schema are
+set to that default when the snap is configured, if they are unset.
+
+The --schema option prints the configuration schema declared by the snap
+instead, with the type, default and description of each option:
+
+    $ snap get --schema snap-name
+    Key       Type                Default  Description
+    username  string              -        The name of the user
+    mode      string (fast|safe)  safe     How to run the service
+
+The --history option lists the recent changes of the configuration, with
+who initiated each of them and the options they changed. Use
+'snap revert-config' to restore a previous configuration. The history keeps
+the whole configuration after each change, so the previous values of
+options that were changed or unset remain in it until the snap is removed
+or they are pushed out by the last 20 changes.
+
+The --export option prints the whole configuration of the snap as YAML,
+in a form that can be given to 'snap set --from-file'. Without a snap name,
+the configuration of all the snaps that have some and of the system is
+exported, keyed by snap name. The options snapd keeps in the system
+configuration for its own use are left out.
 `)
 
 type cmdGet struct {
 	clientMixin
+	timeMixin
 	Positional struct {
-		Snap installedSnapName `required:"yes"`
+		Snap installedSnapName
 		Keys []string
 	} `positional-args:"yes"`
 
 	Typed    bool `short:"t"`
 	Document bool `short:"d"`
 	List     bool `short:"l"`
+	Schema   bool `long:"schema"`
+	History  bool `long:"history"`
+	Export   bool `long:"export"`
 }
 
 func init() {
 	addCommand("get", shortGetHelp, longGetHelp, func() flags.Commander { return &cmdGet{} },
-		map[string]string{
+		timeDescs.also(map[string]string{
 			// TRANSLATORS: This should not start with a lowercase letter.
 			"d": i18n.G("Always return document, even with single key"),
 			// TRANSLATORS: This should not start with a lowercase letter.
 			"l": i18n.G("Always return list, even with single key"),
 			// TRANSLATORS: This should not start with a lowercase letter.
 			"t": i18n.G("Strict typing with nulls and quoted strings"),
-		}, []argDesc{
+			// TRANSLATORS: This should not start with a lowercase letter.
+			"schema": i18n.G("Print the configuration schema of the snap instead of the values"),
+			// TRANSLATORS: This should not start with a lowercase letter.
+			"history": i18n.G("Print the recent changes of the configuration of the snap"),
+			// TRANSLATORS: This should not start with a lowercase letter.
+			"export": i18n.G("Print the whole configuration as YAML, of all snaps if none is given"),
+		}), []argDesc{
 			{
 				name: "<snap>",
 				// TRANSLATORS: This should not start with a lowercase letter.
@@ -242,6 +279,30 @@ func (x *cmdGet) Execute(args []string) error {
 	snapName := string(x.Positional.Snap)
 	confKeys := x.Positional.Keys
 
+	if x.Export {
+		if x.Schema || x.History || x.Document || x.List || x.Typed || len(confKeys) > 0 {
+			return fmt.Errorf("cannot use --export with --schema, --history, -d, -l, -t or configuration keys")
+		}
+		return x.exportConf(snapName)
+	}
+	if snapName == "" {
+		return fmt.Errorf("the required argument `<snap>` was not provided")
+	}
+
+	if x.History {
+		if x.Schema || x.Typed || len(confKeys) > 0 {
+			return fmt.Errorf("cannot use --history with --schema, -t or configuration keys")
+		}
+		return x.showHistory(snapName)
+	}
+
+	if x.Schema {
+		if x.Typed {
+			return fmt.Errorf(i18n.G("cannot use --schema and -t together"))
+		}
+		return x.showSchema(snapName, confKeys)
+	}
+
 	conf, err := x.client.Conf(snapName, confKeys)
 	if err != nil {
 		return err
@@ -256,3 +317,231 @@ func (x *cmdGet) Execute(args []string) error {
 		return x.outputDefault(conf, snapName, confKeys)
 	}
 }
+
+func (x *cmdGet) exportConf(snapName string) error {
+	var snapNames []string
+	if snapName != "" {
+		snapNames = []string{snapName}
+	}
+	confs, err := x.client.ExportConf(snapNames)
+	if err != nil {
+		return err
+	}
+
+	var doc interface{} = confs
+	if snapName != "" {
+		// the system may be requested as "core" but is exported
+		// as "system", there is a single entry either way
+		for _, conf := range confs {
+			doc = conf
+		}
+	}
+	out, err := yaml.Marshal(yamlValue(doc))
+	if err != nil {
+		return err
+	}
+	Stdout.Write(out)
+	return nil
+}
+
+// yamlValue turns the json.Numbers of the given configuration value into
+// numbers, which would otherwise be marshalled as strings in YAML.
+func yamlValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i
+		}
+		if f, err := v.Float64(); err == nil {
+			return f
+		}
+		return v.String()
+	case map[string]map[string]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for k, item := range v {
+			m[k] = yamlValue(item)
+		}
+		return m
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for k, item := range v {
+			m[k] = yamlValue(item)
+		}
+		return m
+	case []interface{}:
+		l := make([]interface{}, len(v))
+		for i, item := range v {
+			l[i] = yamlValue(item)
+		}
+		return l
+	}
+	return value
+}
+
+func (x *cmdGet) showSchema(snapName string, confKeys []string) error {
+	schema, err := x.client.ConfSchema(snapName)
+	if err != nil {
+		return err
+	}
+
+	var options []schemaOption
+	if len(confKeys) == 0 {
+		options = flattenSchema("", schema)
+	}
+	for _, key := range confKeys {
+		option := schema.Lookup(key)
+		if option == nil {
+			return fmt.Errorf(i18n.G("snap %q has no %q option in its configuration schema"), snapName, key)
+		}
+		options = append(options, schemaOption{key, option})
+		options = append(options, flattenSchema(key, option)...)
+	}
+
+	if x.Document {
+		if len(confKeys) == 0 {
+			return x.outputJson(schema)
+		}
+		doc := make(map[string]*configschema.Schema, len(confKeys))
+		for _, key := range confKeys {
+			doc[key] = schema.Lookup(key)
+		}
+		return x.outputJson(doc)
+	}
+
+	w := tabWriter()
+	defer w.Flush()
+
+	fmt.Fprintf(w, i18n.G("Key\tType\tDefault\tDescription\n"))
+	for _, opt := range options {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", opt.Path, schemaType(opt.Schema), schemaValue(opt.Schema.Default), placeholder(opt.Schema.Description))
+	}
+	return nil
+}
+
+type schemaOption struct {
+	Path   string
+	Schema *configschema.Schema
+}
+
+// flattenSchema returns the options nested under the given one, sorted
+// by their dotted path.
+func flattenSchema(path string, schema *configschema.Schema) []schemaOption {
+	names := make([]string, 0, len(schema.Properties))
+	for name := range schema.Properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var options []schemaOption
+	for _, name := range names {
+		p := name
+		if path != "" {
+			p = path + "." + name
+		}
+		prop := schema.Properties[name]
+		options = append(options, schemaOption{p, prop})
+		options = append(options, flattenSchema(p, prop)...)
+	}
+	return options
+}
+
+func schemaType(schema *configschema.Schema) string {
+	typ := schema.Type
+	if typ == "" {
+		typ = "any"
+	}
+	if schema.Type == configschema.TypeArray && schema.Items != nil && schema.Items.Type != "" {
+		typ = fmt.Sprintf("array of %s", schema.Items.Type)
+	}
+	if len(schema.Enum) > 0 {
+		values := make([]string, len(schema.Enum))
+		for i, v := range schema.Enum {
+			values[i] = schemaValue(v)
+		}
+		typ = fmt.Sprintf("%s (%s)", typ, strings.Join(values, "|"))
+	}
+	return typ
+}
+
+func schemaValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "-"
+	case string:
+		return v
+	}
+	b, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(b)
+}
+
+func (x *cmdGet) showHistory(snapName string) error {
+	history, err := x.client.ConfHistory(snapName)
+	if err != nil {
+		return err
+	}
+
+	if x.Document {
+		return x.outputJson(history)
+	}
+
+	if len(history) == 0 {
+		fmt.Fprintf(Stderr, i18n.G("No configuration changes of snap %q are recorded.\n"), snapName)
+		return nil
+	}
+
+	w := tabWriter()
+	defer w.Flush()
+
+	fmt.Fprintf(w, i18n.G("Rev\tTime\tInitiator\tChange\tChanged\n"))
+	var previous map[string]interface{}
+	for _, entry := range history {
+		when := "-"
+		if !entry.Time.IsZero() {
+			when = x.fmtTime(entry.Time)
+		}
+		changed := strings.Join(changedConfigPaths(previous, entry.Config), ",")
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\n", entry.Revision, when, placeholder(entry.Initiator), placeholder(entry.ChangeID), placeholder(changed))
+		previous = entry.Config
+	}
+	return nil
+}
+
+func placeholder(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+// changedConfigPaths returns the dotted paths of the options whose values
+// differ between the two configurations, sorted.
+func changedConfigPaths(before, after map[string]interface{}) []string {
+	var paths []string
+	var walk func(prefix string, a, b map[string]interface{})
+	walk = func(prefix string, a, b map[string]interface{}) {
+		keys := make(map[string]bool, len(a)+len(b))
+		for k := range a {
+			keys[k] = true
+		}
+		for k := range b {
+			keys[k] = true
+		}
+		for k := range keys {
+			path := prefix + k
+			am, aIsMap := a[k].(map[string]interface{})
+			bm, bIsMap := b[k].(map[string]interface{})
+			switch {
+			case aIsMap && bIsMap:
+				walk(path+".", am, bm)
+			case !reflect.DeepEqual(a[k], b[k]):
+				paths = append(paths, path)
+			}
+		}
+	}
+	walk("", before, after)
+	sort.Strings(paths)
+	return paths
+}
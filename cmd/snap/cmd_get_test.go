@@ -144,6 +144,133 @@ func (s *SnapSuite) TestSnapGetNoConfiguration(c *C) {
 	s.runTests(getNoConfigTests, c)
 }
 
+var getSchemaTests = []getCmdArgs{{
+	args: "get --schema snapname",
+	stdout: "Key            Type                Default  Description\n" +
+		"mode           string (fast|safe)  safe     How to run\n" +
+		"port           integer             8080     The port to listen on\n" +
+		"proxy          object              -        -\n" +
+		"proxy.timeout  integer             30       -\n" +
+		"proxy.url      string              -        The proxy URL\n" +
+		"servers        array of string     -        -\n",
+}, {
+	args: "get --schema snapname proxy",
+	stdout: "Key            Type     Default  Description\n" +
+		"proxy          object   -        -\n" +
+		"proxy.timeout  integer  30       -\n" +
+		"proxy.url      string   -        The proxy URL\n",
+}, {
+	args:   "get --schema -d snapname port",
+	stdout: "{\n\t\"port\": {\n\t\t\"type\": \"integer\",\n\t\t\"description\": \"The port to listen on\",\n\t\t\"default\": 8080\n\t}\n}\n",
+}, {
+	args:  "get --schema snapname missing",
+	error: `snap "snapname" has no "missing" option in its configuration schema`,
+}, {
+	args:  "get --schema -t snapname",
+	error: `cannot use --schema and -t together`,
+}}
+
+func (s *SnapSuite) TestSnapGetSchema(c *C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		c.Check(r.URL.Path, Equals, "/v2/snaps/snapname/conf")
+		c.Check(r.Method, Equals, "GET")
+		c.Check(r.URL.Query().Get("schema"), Equals, "true")
+		fmt.Fprintln(w, `{"type":"sync", "status-code": 200, "result": {
+			"type": "object",
+			"properties": {
+				"port": {"type": "integer", "description": "The port to listen on", "default": 8080},
+				"mode": {"type": "string", "description": "How to run", "enum": ["fast", "safe"], "default": "safe"},
+				"servers": {"type": "array", "items": {"type": "string"}},
+				"proxy": {"type": "object", "properties": {
+					"url": {"type": "string", "description": "The proxy URL"},
+					"timeout": {"type": "integer", "default": 30}
+				}}
+			}
+		}}`)
+	})
+	s.runTests(getSchemaTests, c)
+}
+
+func (s *SnapSuite) TestSnapGetSchemaNoSchema(c *C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(400)
+		fmt.Fprintln(w, `{"type":"error", "status-code": 400, "result": {"message": "snap \"snapname\" has no configuration schema"}}`)
+	})
+	_, err := snapset.Parser(snapset.Client()).ParseArgs([]string{"get", "--schema", "snapname"})
+	c.Check(err, ErrorMatches, `snap "snapname" has no configuration schema`)
+}
+
+var getHistoryTests = []getCmdArgs{{
+	args: "get --history --abs-time snapname",
+	stdout: "Rev  Time                  Initiator  Change  Changed\n" +
+		"1    -                     -          -       foo,nested\n" +
+		"2    2021-06-01T10:00:00Z  user1      42      foo\n" +
+		"3    2021-06-01T11:00:00Z  snapctl    -       nested.b,new\n",
+}, {
+	args:   "get --history -d snapname",
+	stdout: "[\n\t{\n\t\t\"revision\": 1,\n\t\t\"time\": \"0001-01-01T00:00:00Z\",\n\t\t\"config\": {\n\t\t\t\"foo\": \"a\",\n\t\t\t\"nested\": {\n\t\t\t\t\"a\": 1\n\t\t\t}\n\t\t}\n\t},\n\t{\n\t\t\"revision\": 2,\n\t\t\"time\": \"2021-06-01T10:00:00Z\",\n\t\t\"initiator\": \"user1\",\n\t\t\"change-id\": \"42\",\n\t\t\"config\": {\n\t\t\t\"foo\": \"b\",\n\t\t\t\"nested\": {\n\t\t\t\t\"a\": 1\n\t\t\t}\n\t\t}\n\t},\n\t{\n\t\t\"revision\": 3,\n\t\t\"time\": \"2021-06-01T11:00:00Z\",\n\t\t\"initiator\": \"snapctl\",\n\t\t\"config\": {\n\t\t\t\"foo\": \"b\",\n\t\t\t\"nested\": {\n\t\t\t\t\"a\": 1,\n\t\t\t\t\"b\": true\n\t\t\t},\n\t\t\t\"new\": \"x\"\n\t\t}\n\t}\n]\n",
+}, {
+	args:  "get --history snapname foo",
+	error: `cannot use --history with --schema, -t or configuration keys`,
+}}
+
+func (s *SnapSuite) TestSnapGetHistory(c *C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		c.Check(r.URL.Path, Equals, "/v2/snaps/snapname/conf")
+		c.Check(r.Method, Equals, "GET")
+		c.Check(r.URL.Query().Get("history"), Equals, "true")
+		fmt.Fprintln(w, `{"type":"sync", "status-code": 200, "result": [
+			{"revision": 1, "time": "0001-01-01T00:00:00Z", "config": {"foo": "a", "nested": {"a": 1}}},
+			{"revision": 2, "time": "2021-06-01T10:00:00Z", "initiator": "user1", "change-id": "42", "config": {"foo": "b", "nested": {"a": 1}}},
+			{"revision": 3, "time": "2021-06-01T11:00:00Z", "initiator": "snapctl", "config": {"foo": "b", "nested": {"a": 1, "b": true}, "new": "x"}}
+		]}`)
+	})
+	s.runTests(getHistoryTests, c)
+}
+
+func (s *SnapSuite) TestSnapGetHistoryEmpty(c *C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, `{"type":"sync", "status-code": 200, "result": []}`)
+	})
+	_, err := snapset.Parser(snapset.Client()).ParseArgs([]string{"get", "--history", "snapname"})
+	c.Assert(err, IsNil)
+	c.Check(s.Stdout(), Equals, "")
+	c.Check(s.Stderr(), Equals, "No configuration changes of snap \"snapname\" are recorded.\n")
+}
+
+var getExportTests = []getCmdArgs{{
+	args:   "get --export snapname",
+	stdout: "key: value\nnested:\n  a: 1\n  b:\n  - 1.5\n  - x\n",
+}, {
+	args:   "get --export",
+	stdout: "snapname:\n  key: value\n  nested:\n    a: 1\n    b:\n    - 1.5\n    - x\nsystem: {}\n",
+}, {
+	args:  "get --export snapname key",
+	error: `cannot use --export with --schema, --history, -d, -l, -t or configuration keys`,
+}, {
+	args:  "get --export -d snapname",
+	error: `cannot use --export with --schema, --history, -d, -l, -t or configuration keys`,
+}, {
+	args:  "get",
+	error: "the required argument `<snap>` was not provided",
+}}
+
+func (s *SnapSuite) TestSnapGetExport(c *C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		c.Check(r.URL.Path, Equals, "/v2/conf")
+		c.Check(r.Method, Equals, "GET")
+		switch r.URL.Query().Get("snaps") {
+		case "snapname":
+			fmt.Fprintln(w, `{"type":"sync", "status-code": 200, "result": {"snapname": {"key": "value", "nested": {"a": 1, "b": [1.5, "x"]}}}}`)
+		case "":
+			fmt.Fprintln(w, `{"type":"sync", "status-code": 200, "result": {"system": {}, "snapname": {"key": "value", "nested": {"a": 1, "b": [1.5, "x"]}}}}`)
+		default:
+			c.Errorf("unexpected snaps %q", r.URL.Query().Get("snaps"))
+		}
+	})
+	s.runTests(getExportTests, c)
+}
+
 func (s *SnapSuite) TestSortByPath(c *C) {
 	values := []snapset.ConfigValue{
 		{Path: "test-key3.b"},
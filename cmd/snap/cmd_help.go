@@ -62,6 +62,7 @@ func addHelp(parser *flags.Parser) error {
 			return &flags.Error{Type: flags.ErrCommandRequired}
 		}
 		// not toplevel, so ask for regular help
+		showFormatHelp(parser)
 		return &flags.Error{Type: flags.ErrHelp}
 	}
 	hlpgrp, err := parser.AddGroup("Help Options", "", &help)
@@ -164,6 +165,7 @@ func (cmd cmdHelp) Execute(args []string) error {
 		cmd.parser.Command.Active = subcmd
 	}
 	if subcmd != cmd.parser.Command {
+		showFormatHelp(cmd.parser)
 		return &flags.Error{Type: flags.ErrHelp}
 	}
 	return &flags.Error{Type: flags.ErrCommandRequired}
@@ -200,7 +202,7 @@ var helpCategories = []helpCategory{
 	}, {
 		Label:       i18n.G("Daemons"),
 		Description: i18n.G("manage services"),
-		Commands:    []string{"services", "start", "stop", "restart", "logs"},
+		Commands:    []string{"services", "start", "stop", "restart", "logs", "top", "service-overrides", "set-service-override", "reset-service-override"},
 	}, {
 		Label:       i18n.G("Permissions"),
 		Description: i18n.G("manage permissions"),
@@ -208,7 +210,7 @@ var helpCategories = []helpCategory{
 	}, {
 		Label:       i18n.G("Configuration"),
 		Description: i18n.G("system administration and configuration"),
-		Commands:    []string{"get", "set", "unset", "wait"},
+		Commands:    []string{"get", "set", "unset", "revert-config", "wait"},
 	}, {
 		Label:       i18n.G("App Aliases"),
 		Description: i18n.G("manage aliases"),
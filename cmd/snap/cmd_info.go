@@ -31,6 +31,7 @@ import (
 	"unicode/utf8"
 
 	"github.com/jessevdk/go-flags"
+	"golang.org/x/xerrors"
 	"gopkg.in/yaml.v2"
 
 	"github.com/snapcore/snapd/asserts"
@@ -48,6 +49,7 @@ type infoCmd struct {
 	clientMixin
 	colorMixin
 	timeMixin
+	formatMixin
 
 	Verbose    bool `long:"verbose"`
 	Positional struct {
@@ -675,7 +677,64 @@ func (chInfos *channelInfos) dump(w io.Writer) {
 	}
 }
 
+// infoStructured is what --format prints for each of the given snaps.
+type infoStructured struct {
+	Name      string       `json:"name"`
+	Path      string       `json:"path,omitempty"`
+	File      *client.Snap `json:"file,omitempty"`
+	Installed *client.Snap `json:"installed,omitempty"`
+	Store     *client.Snap `json:"store,omitempty"`
+}
+
+// isSnapNotFound returns whether the error reports that the snap was
+// not found.
+func isSnapNotFound(err error) bool {
+	var e *client.Error
+	return xerrors.As(err, &e) && e.Kind == client.ErrorKindSnapNotFound
+}
+
+func (x *infoCmd) printStructuredInfo() error {
+	infos := make([]*infoStructured, 0, len(x.Positional.Snaps))
+	for _, snapName := range x.Positional.Snaps {
+		snapName := string(snapName)
+		info := &infoStructured{Name: snapName}
+		if diskSnap, err := clientSnapFromPath(snapName); err == nil {
+			info.Name = diskSnap.Name
+			info.Path = norm(snapName)
+			info.File = diskSnap
+		} else {
+			// unlike in the table, errors other than the snap not
+			// being found are not hidden from scripts
+			var err error
+			info.Store, _, err = x.client.FindOne(snap.InstanceSnap(snapName))
+			if err != nil && !isSnapNotFound(err) {
+				return err
+			}
+			info.Installed, _, err = x.client.Snap(snapName)
+			if err != nil && !isSnapNotFound(err) {
+				return err
+			}
+		}
+		if info.File == nil && info.Installed == nil && info.Store == nil {
+			if len(x.Positional.Snaps) == 1 {
+				return fmt.Errorf("no snap found for %q", snapName)
+			}
+			fmt.Fprintf(Stderr, i18n.G("warning: no snap found for %q\n"), snapName)
+			continue
+		}
+		infos = append(infos, info)
+	}
+	if len(infos) == 0 {
+		return fmt.Errorf(i18n.G("no valid snaps given"))
+	}
+	return x.printStructured(infos)
+}
+
 func (x *infoCmd) Execute([]string) error {
+	if x.structuredOutput() {
+		return x.printStructuredInfo()
+	}
+
 	termWidth, _ := termSize()
 	termWidth -= 3
 	if termWidth > 100 {
@@ -1230,3 +1230,50 @@ installed:     2.10            (100)  1kB disabled
 `, refreshDate))
 	c.Check(s.Stderr(), check.Equals, "")
 }
+
+func (s *infoSuite) TestInfoFormatJSON(c *check.C) {
+	n := 0
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		switch n {
+		case 0:
+			c.Check(r.URL.Path, check.Equals, "/v2/find")
+			fmt.Fprintln(w, mockInfoJSON)
+		case 1:
+			c.Check(r.URL.Path, check.Equals, "/v2/snaps/hello")
+			w.WriteHeader(404)
+			fmt.Fprintln(w, `{"type":"error","status-code":404,"status":"Not Found","result":{"message":"No.","kind":"snap-not-found","value":"hello"}}`)
+		default:
+			c.Fatalf("expected to get 2 requests, now on %d (%v)", n+1, r)
+		}
+		n++
+	})
+	rest, err := snap.Parser(snap.Client()).ParseArgs([]string{"info", "--format=json", "hello"})
+	c.Assert(err, check.IsNil)
+	c.Assert(rest, check.DeepEquals, []string{})
+	c.Check(s.Stdout(), check.Matches, `(?ms)^\[\n  \{\n    "name": "hello",\n    "store": \{\n.*      "license": "MIT"\n    \}\n  \}\n\]\n$`)
+	c.Check(s.Stdout(), check.Not(check.Matches), `(?ms).*"installed":.*`)
+	c.Check(s.Stderr(), check.Equals, "")
+	c.Check(n, check.Equals, 2)
+}
+
+func (s *infoSuite) TestInfoFormatYAMLNotFound(c *check.C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(404)
+		fmt.Fprintln(w, `{"type":"error","status-code":404,"status":"Not Found","result":{"message":"No.","kind":"snap-not-found","value":"x"}}`)
+	})
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"info", "--format=yaml", "x"})
+	c.Check(err, check.ErrorMatches, `no snap found for "x"`)
+	c.Check(s.Stdout(), check.Equals, "")
+}
+
+func (s *infoSuite) TestInfoFormatError(c *check.C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		c.Check(r.URL.Path, check.Equals, "/v2/find")
+		w.WriteHeader(400)
+		fmt.Fprintln(w, `{"type":"error","status-code":400,"status":"Bad Request","result":{"message":"cannot reach the store"}}`)
+	})
+	// unlike the table the structured output does not hide errors
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"info", "--format=json", "hello"})
+	c.Check(err, check.ErrorMatches, `cannot find snap "hello": cannot reach the store`)
+	c.Check(s.Stdout(), check.Equals, "")
+}
@@ -30,6 +30,7 @@ import (
 
 type cmdInterfaces struct {
 	clientMixin
+	formatMixin
 	Interface   string `short:"i"`
 	Positionals struct {
 		Query interfacesSlotOrPlugSpec `skip-help:"true"`
@@ -95,6 +96,10 @@ func (x *cmdInterfaces) Execute(args []string) error {
 
 	defer fmt.Fprintln(Stderr, "\n"+fill(interfacesDeprecationNotice, 0))
 
+	if x.structuredOutput() {
+		return x.printStructured(x.filterStructured(&ifaces))
+	}
+
 	w := tabWriter()
 	defer w.Flush()
 	fmt.Fprintln(w, i18n.G("Slot\tPlug"))
@@ -179,3 +184,39 @@ func (x *cmdInterfaces) Execute(args []string) error {
 	}
 	return nil
 }
+
+// filterStructured applies the plug or slot name and the interface
+// constraints to the connections printed with --format.
+func (x *cmdInterfaces) filterStructured(ifaces *client.Connections) *client.Connections {
+	name := x.Positionals.Query.Name
+	filtered := &client.Connections{
+		Established: []client.Connection{},
+		Undesired:   []client.Connection{},
+		Plugs:       []client.Plug{},
+		Slots:       []client.Slot{},
+	}
+	wantedConn := func(conn client.Connection) bool {
+		return (name == "" || name == conn.Plug.Name || name == conn.Slot.Name) && (x.Interface == "" || x.Interface == conn.Interface)
+	}
+	for _, conn := range ifaces.Established {
+		if wantedConn(conn) {
+			filtered.Established = append(filtered.Established, conn)
+		}
+	}
+	for _, conn := range ifaces.Undesired {
+		if wantedConn(conn) {
+			filtered.Undesired = append(filtered.Undesired, conn)
+		}
+	}
+	for _, plug := range ifaces.Plugs {
+		if (name == "" || name == plug.Name) && (x.Interface == "" || x.Interface == plug.Interface) {
+			filtered.Plugs = append(filtered.Plugs, plug)
+		}
+	}
+	for _, slot := range ifaces.Slots {
+		if (name == "" || name == slot.Name) && (x.Interface == "" || x.Interface == slot.Interface) {
+			filtered.Slots = append(filtered.Slots, slot)
+		}
+	}
+	return filtered
+}
@@ -672,3 +672,35 @@ func (s *SnapSuite) checkConnectionsSystemCoreRemapping(c *C, apiSnapName, cliSn
 	c.Assert(s.Stdout(), Equals, expectedStdout)
 	c.Assert(s.Stderr(), testutil.EqualsWrapped, InterfacesDeprecationNotice)
 }
+
+func (s *SnapSuite) TestInterfacesFormatJSON(c *C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		c.Check(r.URL.Path, Equals, "/v2/connections")
+		EncodeResponseBody(c, w, map[string]interface{}{
+			"type": "sync",
+			"result": client.Connections{
+				Slots: []client.Slot{
+					{Snap: "canonical-pi2", Name: "debug-console", Interface: "serial-port"},
+					{Snap: "canonical-pi2", Name: "pin-13", Interface: "bool-file"},
+				},
+			},
+		})
+	})
+	rest, err := Parser(Client()).ParseArgs([]string{"interfaces", "--format=json", "-i=serial-port"})
+	c.Assert(err, IsNil)
+	c.Assert(rest, DeepEquals, []string{})
+	c.Check(s.Stdout(), Equals, `{
+  "established": [],
+  "undesired": [],
+  "plugs": [],
+  "slots": [
+    {
+      "snap": "canonical-pi2",
+      "slot": "debug-console",
+      "interface": "serial-port"
+    }
+  ]
+}
+`)
+	c.Assert(s.Stderr(), testutil.EqualsWrapped, InterfacesDeprecationNotice)
+}
@@ -48,6 +48,7 @@ type cmdList struct {
 
 	All bool `long:"all"`
 	colorMixin
+	formatMixin
 }
 
 func init() {
@@ -94,6 +95,9 @@ func (x *cmdList) Execute(args []string) error {
 	if err != nil {
 		if err == client.ErrNoSnapsInstalled {
 			if len(names) == 0 {
+				if x.structuredOutput() {
+					return x.printStructured([]*client.Snap{})
+				}
 				fmt.Fprintln(Stderr, i18n.G("No snaps are installed yet. Try 'snap install hello-world'."))
 				return nil
 			} else {
@@ -106,6 +110,10 @@ func (x *cmdList) Execute(args []string) error {
 	}
 	sort.Sort(snapsByName(snaps))
 
+	if x.structuredOutput() {
+		return x.printStructured(snaps)
+	}
+
 	esc := x.getEscapes()
 	w := tabWriter()
 
@@ -20,6 +20,7 @@
 package main_test
 
 import (
+	"encoding/json"
 	"fmt"
 	"net/http"
 
@@ -37,6 +38,10 @@ The list command displays a summary of snaps installed in the current system.
 A green check mark (given color and unicode support) after a publisher name
 indicates that the publisher has been verified.
 
+Application Options:
+  --format=[json|yaml]                Print the output as json or yaml instead
+                                      of a table
+
 [list command options]
       --all                           Show all revisions
       --color=[auto|never|always]     Use a little bit of color to highlight
@@ -47,6 +52,71 @@ indicates that the publisher has been verified.
 	s.testSubCommandHelp(c, "list", msg)
 }
 
+const mockListJSON = `{"type": "sync", "result": [
+{
+  "name": "foo",
+  "status": "active",
+  "version": "4.2",
+  "developer": "bar",
+  "publisher": {"id": "bar-id", "username": "bar", "display-name": "Bar", "validation": "unproven"},
+  "revision": 17,
+  "installed-size": 1234567,
+  "tracking-channel": "potatoes"
+}]}`
+
+func (s *SnapSuite) TestListFormatJSON(c *check.C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		c.Check(r.URL.Path, check.Equals, "/v2/snaps")
+		fmt.Fprintln(w, mockListJSON)
+	})
+	rest, err := snap.Parser(snap.Client()).ParseArgs([]string{"list", "--format=json"})
+	c.Assert(err, check.IsNil)
+	c.Assert(rest, check.DeepEquals, []string{})
+
+	var snaps []map[string]interface{}
+	c.Assert(json.Unmarshal([]byte(s.Stdout()), &snaps), check.IsNil)
+	c.Assert(snaps, check.HasLen, 1)
+	c.Check(snaps[0]["name"], check.Equals, "foo")
+	c.Check(snaps[0]["revision"], check.Equals, "17")
+	c.Check(snaps[0]["installed-size"], check.Equals, 1234567.0)
+	c.Check(snaps[0]["tracking-channel"], check.Equals, "potatoes")
+	c.Check(snaps[0]["publisher"], check.DeepEquals, map[string]interface{}{
+		"id": "bar-id", "username": "bar", "display-name": "Bar", "validation": "unproven",
+	})
+	c.Check(s.Stderr(), check.Equals, "")
+}
+
+func (s *SnapSuite) TestListFormatYAML(c *check.C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		c.Check(r.URL.Path, check.Equals, "/v2/snaps")
+		fmt.Fprintln(w, mockListJSON)
+	})
+	rest, err := snap.Parser(snap.Client()).ParseArgs([]string{"list", "--format", "yaml"})
+	c.Assert(err, check.IsNil)
+	c.Assert(rest, check.DeepEquals, []string{})
+	// same keys as json, integers are kept as such
+	c.Check(s.Stdout(), check.Matches, `(?ms)^- .*^  name: foo$.*`)
+	c.Check(s.Stdout(), check.Matches, `(?ms).*^  installed-size: 1234567$.*`)
+	c.Check(s.Stdout(), check.Matches, `(?ms).*^  tracking-channel: potatoes$.*`)
+	c.Check(s.Stdout(), check.Matches, `(?ms).*^    display-name: Bar$.*`)
+	c.Check(s.Stderr(), check.Equals, "")
+}
+
+func (s *SnapSuite) TestListFormatNoSnaps(c *check.C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, `{"type": "sync", "result": []}`)
+	})
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"list", "--format=json"})
+	c.Assert(err, check.IsNil)
+	c.Check(s.Stdout(), check.Equals, "[]\n")
+	c.Check(s.Stderr(), check.Equals, "")
+}
+
+func (s *SnapSuite) TestListFormatInvalid(c *check.C) {
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"list", "--format=csv"})
+	c.Assert(err, check.ErrorMatches, `Invalid value .csv. for option .--format.*`)
+}
+
 func (s *SnapSuite) TestList(c *check.C) {
 	n := 0
 	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
@@ -286,6 +286,7 @@ func (x *cmdRemoveQuota) Execute(args []string) (err error) {
 
 type cmdQuotas struct {
 	clientMixin
+	formatMixin
 }
 
 func (x *cmdQuotas) Execute(args []string) (err error) {
@@ -293,6 +294,12 @@ func (x *cmdQuotas) Execute(args []string) (err error) {
 	if err != nil {
 		return err
 	}
+	if x.structuredOutput() {
+		if res == nil {
+			res = []*client.QuotaGroupResult{}
+		}
+		return x.printStructured(res)
+	}
 	if len(res) == 0 {
 		fmt.Fprintln(Stdout, i18n.G("No quota groups defined."))
 		return nil
@@ -497,3 +497,41 @@ func (s *quotaSuite) TestNoQuotaGroups(c *check.C) {
 	c.Check(s.Stderr(), check.Equals, "")
 	c.Check(s.Stdout(), check.Equals, "No quota groups defined.\n")
 }
+
+func (s *quotaSuite) TestGetAllQuotaGroupsFormatYAML(c *check.C) {
+	s.RedirectClientToTestServer(makeFakeGetQuotaGroupsHandler(c,
+		`{"type": "sync", "status-code": 200, "result": [
+			{"group-name":"aaa","parent":"zzz","snaps":["foo"],"constraints":{"memory":1000},"current":{"memory":400}},
+			{"group-name":"zzz","subgroups":["aaa"],"constraints":{"memory":5000}}
+			]}`))
+
+	rest, err := main.Parser(main.Client()).ParseArgs([]string{"quotas", "--format=yaml"})
+	c.Assert(err, check.IsNil)
+	c.Check(rest, check.HasLen, 0)
+	c.Check(s.Stderr(), check.Equals, "")
+	c.Check(s.Stdout(), check.Equals, `
+- constraints:
+    memory: 1000
+  current:
+    memory: 400
+  group-name: aaa
+  parent: zzz
+  snaps:
+  - foo
+- constraints:
+    memory: 5000
+  group-name: zzz
+  subgroups:
+  - aaa
+`[1:])
+}
+
+func (s *quotaSuite) TestNoQuotaGroupsFormatJSON(c *check.C) {
+	s.RedirectClientToTestServer(makeFakeGetQuotaGroupsHandler(c,
+		`{"type": "sync", "status-code": 200, "result": []}`))
+
+	_, err := main.Parser(main.Client()).ParseArgs([]string{"quotas", "--format=json"})
+	c.Assert(err, check.IsNil)
+	c.Check(s.Stderr(), check.Equals, "")
+	c.Check(s.Stdout(), check.Equals, "[]\n")
+}
@@ -0,0 +1,91 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package main
+
+import (
+	"fmt"
+
+	"github.com/jessevdk/go-flags"
+
+	"github.com/snapcore/snapd/i18n"
+)
+
+var shortRevertConfigHelp = i18n.G("Restore a previous configuration of a snap")
+var longRevertConfigHelp = i18n.G(`
+The revert-config command restores the configuration of a snap as it was
+before its last change, or as recorded in the given revision of its
+configuration history:
+
+    $ snap revert-config snap-name
+    $ snap revert-config --to-revision=3 snap-name
+
+The configuration history is shown by 'snap get --history'. The previous
+configuration is applied like any other change, through the snap's
+configuration hook, and becomes a new revision of the history itself.
+`)
+
+type cmdRevertConfig struct {
+	waitMixin
+	ToRevision int `long:"to-revision"`
+	Positional struct {
+		Snap installedSnapName
+	} `positional-args:"yes" required:"yes"`
+}
+
+func init() {
+	addCommand("revert-config", shortRevertConfigHelp, longRevertConfigHelp, func() flags.Commander { return &cmdRevertConfig{} },
+		waitDescs.also(map[string]string{
+			// TRANSLATORS: This should not start with a lowercase letter.
+			"to-revision": i18n.G("Restore the configuration recorded in this revision of the history"),
+		}), []argDesc{{
+			name: "<snap>",
+			// TRANSLATORS: This should not start with a lowercase letter.
+			desc: i18n.G("The snap to configure (e.g. hello-world)"),
+		}})
+}
+
+func (x *cmdRevertConfig) Execute(args []string) error {
+	if len(args) > 0 {
+		return ErrExtraArgs
+	}
+	if x.ToRevision < 0 {
+		return fmt.Errorf(i18n.G("invalid configuration revision: %d"), x.ToRevision)
+	}
+
+	snapName := string(x.Positional.Snap)
+	id, err := x.client.RevertConf(snapName, x.ToRevision)
+	if err != nil {
+		return err
+	}
+
+	if _, err := x.wait(id); err != nil {
+		if err == noWait {
+			return nil
+		}
+		return err
+	}
+
+	if x.ToRevision != 0 {
+		fmt.Fprintf(Stdout, i18n.G("Configuration of %q reverted to revision %d\n"), snapName, x.ToRevision)
+	} else {
+		fmt.Fprintf(Stdout, i18n.G("Configuration of %q reverted\n"), snapName)
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package main_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"gopkg.in/check.v1"
+
+	snap "github.com/snapcore/snapd/cmd/snap"
+)
+
+func (s *snapSetSuite) mockRevertConfigServer(c *check.C, expectedRevision int) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v2/snaps/snapname/conf":
+			c.Check(r.Method, check.Equals, "POST")
+			c.Check(DecodedRequestBody(c, r), check.DeepEquals, map[string]interface{}{
+				"action":   "revert",
+				"revision": json.Number(fmt.Sprint(expectedRevision)),
+			})
+			w.WriteHeader(202)
+			fmt.Fprintln(w, `{"type":"async", "status-code": 202, "change": "zzz"}`)
+			s.setConfApiCalls += 1
+		case "/v2/changes/zzz":
+			c.Check(r.Method, check.Equals, "GET")
+			fmt.Fprintln(w, `{"type":"sync", "result":{"ready": true, "status": "Done"}}`)
+		default:
+			c.Fatalf("unexpected path %q", r.URL.Path)
+		}
+	})
+}
+
+func (s *snapSetSuite) TestRevertConfig(c *check.C) {
+	s.mockRevertConfigServer(c, 0)
+
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"revert-config", "snapname"})
+	c.Assert(err, check.IsNil)
+	c.Check(s.setConfApiCalls, check.Equals, 1)
+	c.Check(s.Stdout(), check.Equals, "Configuration of \"snapname\" reverted\n")
+}
+
+func (s *snapSetSuite) TestRevertConfigToRevision(c *check.C) {
+	s.mockRevertConfigServer(c, 3)
+
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"revert-config", "--to-revision=3", "snapname"})
+	c.Assert(err, check.IsNil)
+	c.Check(s.setConfApiCalls, check.Equals, 1)
+	c.Check(s.Stdout(), check.Equals, "Configuration of \"snapname\" reverted to revision 3\n")
+}
+
+func (s *snapSetSuite) TestRevertConfigInvalid(c *check.C) {
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"revert-config"})
+	c.Check(err, check.ErrorMatches, "the required argument `<snap>` was not provided")
+
+	_, err = snap.Parser(snap.Client()).ParseArgs([]string{"revert-config", "--to-revision=-1", "snapname"})
+	c.Check(err, check.ErrorMatches, "invalid configuration revision: -1")
+	c.Check(s.setConfApiCalls, check.Equals, 0)
+}
@@ -0,0 +1,191 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/jessevdk/go-flags"
+
+	"github.com/snapcore/snapd/i18n"
+)
+
+var (
+	shortSetServiceOverrideHelp = i18n.G("Override settings of a service")
+	longSetServiceOverrideHelp  = i18n.G(`
+The set-service-override command overrides settings of the unit of the given
+service, whatever the snap declares, and restarts the service if it is
+running. The overrides are kept when the snap is refreshed.
+
+The settings that can be overridden are:
+
+    restart           when to restart the service: always, on-success,
+                      on-failure, on-abnormal, on-abort, on-watchdog or never
+    restart-delay     how long to wait before restarting the service
+    start-timeout     how long to wait for the service to start
+    stop-timeout      how long to wait for the service to stop
+    watchdog-timeout  how long the service can go without notifying the
+                      watchdog
+    environment.NAME  the value of the NAME environment variable
+
+For example:
+
+    $ snap set-service-override snap-name.app restart=always restart-delay=10s
+`)
+	shortResetServiceOverrideHelp = i18n.G("Reset overridden settings of a service")
+	longResetServiceOverrideHelp  = i18n.G(`
+The reset-service-override command resets the given overridden settings of
+the given service to the ones of the snap, or all of them if none is given.
+`)
+	shortServiceOverridesHelp = i18n.G("List overridden settings of services")
+	longServiceOverridesHelp  = i18n.G(`
+The service-overrides command lists the settings overridden for the services
+specified, or for the services in all installed snaps.
+`)
+)
+
+type cmdSetServiceOverride struct {
+	waitMixin
+	Positional struct {
+		Service  serviceName `required:"yes"`
+		Settings []string    `required:"1"`
+	} `positional-args:"yes" required:"yes"`
+}
+
+type cmdResetServiceOverride struct {
+	waitMixin
+	Positional struct {
+		Service serviceName `required:"yes"`
+		Keys    []string
+	} `positional-args:"yes" required:"yes"`
+}
+
+type cmdServiceOverrides struct {
+	clientMixin
+	Positional struct {
+		ServiceNames []serviceName
+	} `positional-args:"yes"`
+}
+
+func init() {
+	serviceArgDesc := argDesc{
+		// TRANSLATORS: This needs to begin with < and end with >
+		name: i18n.G("<service>"),
+		// TRANSLATORS: This should not start with a lowercase letter.
+		desc: i18n.G("The service to override settings of, as <snap>.<app>"),
+	}
+	addCommand("set-service-override", shortSetServiceOverrideHelp, longSetServiceOverrideHelp, func() flags.Commander { return &cmdSetServiceOverride{} }, waitDescs, []argDesc{
+		serviceArgDesc, {
+			// TRANSLATORS: This needs to begin with < and end with >
+			name: i18n.G("<setting>"),
+			// TRANSLATORS: This should not start with a lowercase letter.
+			desc: i18n.G("Setting to override (key=value)"),
+		},
+	})
+	addCommand("reset-service-override", shortResetServiceOverrideHelp, longResetServiceOverrideHelp, func() flags.Commander { return &cmdResetServiceOverride{} }, waitDescs, []argDesc{
+		serviceArgDesc, {
+			// TRANSLATORS: This needs to begin with < and end with >
+			name: i18n.G("<key>"),
+			// TRANSLATORS: This should not start with a lowercase letter.
+			desc: i18n.G("Setting to reset"),
+		},
+	})
+	addCommand("service-overrides", shortServiceOverridesHelp, longServiceOverridesHelp, func() flags.Commander { return &cmdServiceOverrides{} }, nil, []argDesc{{
+		// TRANSLATORS: This needs to begin with < and end with >
+		name: i18n.G("<service>"),
+		// TRANSLATORS: This should not start with a lowercase letter.
+		desc: i18n.G("A service specification, which can be just a snap name (for all services in the snap), or <snap>.<app> for a single service."),
+	}})
+}
+
+func (x *cmdSetServiceOverride) Execute(args []string) error {
+	if len(args) > 0 {
+		return ErrExtraArgs
+	}
+	settings := make(map[string]string, len(x.Positional.Settings))
+	for _, setting := range x.Positional.Settings {
+		parts := strings.SplitN(setting, "=", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return fmt.Errorf(i18n.G("invalid setting: %q (want key=value)"), setting)
+		}
+		settings[parts[0]] = parts[1]
+	}
+
+	id, err := x.client.SetServiceOverride(string(x.Positional.Service), settings)
+	if err != nil {
+		return err
+	}
+	if _, err := x.wait(id); err != nil {
+		if err == noWait {
+			return nil
+		}
+		return err
+	}
+	return nil
+}
+
+func (x *cmdResetServiceOverride) Execute(args []string) error {
+	if len(args) > 0 {
+		return ErrExtraArgs
+	}
+	id, err := x.client.ResetServiceOverride(string(x.Positional.Service), x.Positional.Keys)
+	if err != nil {
+		return err
+	}
+	if _, err := x.wait(id); err != nil {
+		if err == noWait {
+			return nil
+		}
+		return err
+	}
+	return nil
+}
+
+func (x *cmdServiceOverrides) Execute(args []string) error {
+	if len(args) > 0 {
+		return ErrExtraArgs
+	}
+	overrides, err := x.client.ServiceOverrides(svcNames(x.Positional.ServiceNames))
+	if err != nil {
+		return err
+	}
+	if len(overrides) == 0 {
+		fmt.Fprintln(Stderr, i18n.G("No service settings are overridden."))
+		return nil
+	}
+
+	w := tabWriter()
+	defer w.Flush()
+
+	fmt.Fprintln(w, i18n.G("Service\tSetting\tValue"))
+	for _, o := range overrides {
+		keys := make([]string, 0, len(o.Settings))
+		for k := range o.Settings {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			fmt.Fprintf(w, "%s.%s\t%s\t%s\n", o.Snap, o.App, k, o.Settings[k])
+		}
+	}
+	return nil
+}
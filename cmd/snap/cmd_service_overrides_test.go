@@ -0,0 +1,162 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package main_test
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/client"
+	snap "github.com/snapcore/snapd/cmd/snap"
+)
+
+type serviceOverridesSuite struct {
+	BaseSnapSuite
+}
+
+var _ = check.Suite(&serviceOverridesSuite{})
+
+func (s *serviceOverridesSuite) SetUpTest(c *check.C) {
+	s.BaseSnapSuite.SetUpTest(c)
+
+	s.AddCleanup(client.MockDoTimings(time.Millisecond, time.Second))
+	s.AddCleanup(snap.MockPollTime(time.Millisecond))
+}
+
+func (s *serviceOverridesSuite) mockPostServiceOverride(c *check.C, expectedBody map[string]interface{}) *int {
+	n := 0
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		switch n {
+		case 0:
+			c.Check(r.Method, check.Equals, "POST")
+			c.Check(r.URL.Path, check.Equals, "/v2/service-overrides")
+			c.Check(DecodedRequestBody(c, r), check.DeepEquals, expectedBody)
+			w.WriteHeader(202)
+			fmt.Fprintln(w, `{"type":"async", "change": "42", "status-code": 202}`)
+		case 1:
+			c.Check(r.Method, check.Equals, "GET")
+			c.Check(r.URL.Path, check.Equals, "/v2/changes/42")
+			fmt.Fprintln(w, `{"type": "sync", "result": {"ready": true, "status": "Done"}}`)
+		default:
+			c.Fatalf("expected to get 2 requests, now on %d", n+1)
+		}
+		n++
+	})
+	return &n
+}
+
+func (s *serviceOverridesSuite) TestSetServiceOverride(c *check.C) {
+	n := s.mockPostServiceOverride(c, map[string]interface{}{
+		"action": "set",
+		"name":   "foo.svc",
+		"settings": map[string]interface{}{
+			"restart":         "always",
+			"restart-delay":   "10s",
+			"environment.FOO": "bar=baz",
+		},
+	})
+
+	rest, err := snap.Parser(snap.Client()).ParseArgs([]string{"set-service-override", "foo.svc", "restart=always", "restart-delay=10s", "environment.FOO=bar=baz"})
+	c.Assert(err, check.IsNil)
+	c.Check(rest, check.HasLen, 0)
+	c.Check(*n, check.Equals, 2)
+	c.Check(s.Stdout(), check.Equals, "")
+	c.Check(s.Stderr(), check.Equals, "")
+}
+
+func (s *serviceOverridesSuite) TestSetServiceOverrideInvalid(c *check.C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		c.Fatalf("unexpected request")
+	})
+
+	for _, setting := range []string{"restart", "=always"} {
+		_, err := snap.Parser(snap.Client()).ParseArgs([]string{"set-service-override", "foo.svc", setting})
+		c.Check(err, check.ErrorMatches, fmt.Sprintf(`invalid setting: %q \(want key=value\)`, setting))
+	}
+
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"set-service-override", "foo.svc"})
+	c.Check(err, check.ErrorMatches, `the required argument .* was not provided`)
+}
+
+func (s *serviceOverridesSuite) TestResetServiceOverride(c *check.C) {
+	n := s.mockPostServiceOverride(c, map[string]interface{}{
+		"action": "reset",
+		"name":   "foo.svc",
+		"keys":   []interface{}{"restart", "environment.FOO"},
+	})
+
+	rest, err := snap.Parser(snap.Client()).ParseArgs([]string{"reset-service-override", "foo.svc", "restart", "environment.FOO"})
+	c.Assert(err, check.IsNil)
+	c.Check(rest, check.HasLen, 0)
+	c.Check(*n, check.Equals, 2)
+}
+
+func (s *serviceOverridesSuite) TestResetServiceOverrideAll(c *check.C) {
+	n := s.mockPostServiceOverride(c, map[string]interface{}{
+		"action": "reset",
+		"name":   "foo.svc",
+	})
+
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"reset-service-override", "foo.svc"})
+	c.Assert(err, check.IsNil)
+	c.Check(*n, check.Equals, 2)
+}
+
+func (s *serviceOverridesSuite) TestServiceOverrides(c *check.C) {
+	n := 0
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		n++
+		c.Check(r.Method, check.Equals, "GET")
+		c.Check(r.URL.Path, check.Equals, "/v2/service-overrides")
+		c.Check(r.URL.Query().Get("names"), check.Equals, "foo,bar.svc")
+		fmt.Fprintln(w, `{"type": "sync", "result": [
+			{"snap": "bar", "app": "svc", "settings": {"start-timeout": "1m0s"}},
+			{"snap": "foo", "app": "foo", "settings": {"restart-delay": "10s", "environment.FOO": "bar", "restart": "always"}}
+		]}`)
+	})
+
+	rest, err := snap.Parser(snap.Client()).ParseArgs([]string{"service-overrides", "foo", "bar.svc"})
+	c.Assert(err, check.IsNil)
+	c.Check(rest, check.HasLen, 0)
+	c.Check(n, check.Equals, 1)
+	c.Check(s.Stdout(), check.Equals, `
+Service  Setting          Value
+bar.svc  start-timeout    1m0s
+foo.foo  environment.FOO  bar
+foo.foo  restart          always
+foo.foo  restart-delay    10s
+`[1:])
+	c.Check(s.Stderr(), check.Equals, "")
+}
+
+func (s *serviceOverridesSuite) TestServiceOverridesNone(c *check.C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		c.Check(r.URL.Query(), check.HasLen, 0)
+		fmt.Fprintln(w, `{"type": "sync", "result": []}`)
+	})
+
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"service-overrides"})
+	c.Assert(err, check.IsNil)
+	c.Check(s.Stdout(), check.Equals, "")
+	c.Check(s.Stderr(), check.Equals, "No service settings are overridden.\n")
+}
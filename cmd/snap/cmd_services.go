@@ -20,8 +20,11 @@
 package main
 
 import (
+	"encoding/json"
 	"fmt"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/jessevdk/go-flags"
 
@@ -31,8 +34,39 @@ import (
 	"github.com/snapcore/snapd/snap"
 )
 
+// userMixin selects the user sessions whose user daemons a service
+// command inspects or controls.
+type userMixin struct {
+	User  bool   `long:"user"`
+	Users string `long:"users" choice:"all"`
+}
+
+var userDescs = mixinDescs{
+	// TRANSLATORS: This should not start with a lowercase letter.
+	"user": i18n.G("Operate on the user services in the session of the current user"),
+	// TRANSLATORS: This should not start with a lowercase letter.
+	"users": i18n.G("Operate on the user services in the sessions of all the logged in users"),
+}
+
+func (mx userMixin) userSelection() (client.UserSelection, error) {
+	switch {
+	case mx.User && mx.Users != "":
+		return "", fmt.Errorf(i18n.G("cannot use --user and --users together"))
+	case mx.User:
+		return client.UserSelectionSelf, nil
+	case mx.Users == "all":
+		return client.UserSelectionAll, nil
+	}
+	return client.UserSelectionNone, nil
+}
+
 type svcStatus struct {
 	clientMixin
+	formatMixin
+	timeMixin
+	userMixin
+	Timers     bool `long:"timers"`
+	Sockets    bool `long:"sockets"`
 	Positional struct {
 		ServiceNames []serviceName
 	} `positional-args:"yes"`
@@ -42,6 +76,12 @@ type svcLogs struct {
 	clientMixin
 	N          string `short:"n" default:"10"`
 	Follow     bool   `short:"f"`
+	Since      string `long:"since"`
+	Until      string `long:"until"`
+	Priority   string `long:"priority"`
+	Boot       string `long:"boot" optional:"yes" optional-value:"current" choice:"current" choice:"previous"`
+	Grep       string `long:"grep"`
+	Output     string `short:"o" long:"output" choice:"short" choice:"json" default:"short"`
 	Positional struct {
 		ServiceNames []serviceName `required:"1"`
 	} `positional-args:"yes" required:"yes"`
@@ -52,19 +92,45 @@ var (
 	longServicesHelp  = i18n.G(`
 The services command lists information about the services specified, or about
 the services in all currently installed snaps.
+
+With --timers it lists the timers activating the services instead, with their
+schedule, when they last and will next elapse, and the result of the service
+when they last elapsed. With --sockets it lists the sockets activating the
+services, with the addresses they listen on.
+
+With --user it lists the status of the user services in the session of the
+current user, and with --users=all in the sessions of all the logged in
+users, one line per user. Using --users=all requires root or a logged in
+snap user.
 `)
 	shortLogsHelp = i18n.G("Retrieve logs for services")
 	longLogsHelp  = i18n.G(`
 The logs command fetches logs of the given services and displays them in
 chronological order.
+
+The --since and --until options take either a time in RFC3339 format, as
+in 2021-03-01T10:00:00Z or 2021-03-01T10:00:00.5Z, or a duration before
+now, as in 2h30m. The --priority option takes a syslog level, from emerg or
+0 to debug or 7, and shows only the logs of that priority or a more
+important one. The --grep option takes a PCRE2 regular expression, which
+matches case-insensitively if it has no uppercase letters, and needs
+systemd 237 or later. With
+--output=json each log is printed as a JSON object with all its journal
+fields.
 `)
 	shortStartHelp = i18n.G("Start services")
 	longStartHelp  = i18n.G(`
 The start command starts, and optionally enables, the given services.
+
+With --user or --users=all only the user services are started, in the
+session of the current user or in the sessions of all the logged in users.
 `)
 	shortStopHelp = i18n.G("Stop services")
 	longStopHelp  = i18n.G(`
 The stop command stops, and optionally disables, the given services.
+
+With --user or --users=all only the user services are stopped, in the
+session of the current user or in the sessions of all the logged in users.
 `)
 	shortRestartHelp = i18n.G("Restart services")
 	longRestartHelp  = i18n.G(`
@@ -72,6 +138,9 @@ The restart command restarts the given services.
 
 If the --reload option is given, for each service whose app has a reload
 command, a reload is performed instead of a restart.
+
+With --user or --users=all only the user services are restarted, in the
+session of the current user or in the sessions of all the logged in users.
 `)
 )
 
@@ -82,27 +151,45 @@ func init() {
 		// TRANSLATORS: This should not start with a lowercase letter.
 		desc: i18n.G("A service specification, which can be just a snap name (for all services in the snap), or <snap>.<app> for a single service."),
 	}}
-	addCommand("services", shortServicesHelp, longServicesHelp, func() flags.Commander { return &svcStatus{} }, nil, argdescs)
+	addCommand("services", shortServicesHelp, longServicesHelp, func() flags.Commander { return &svcStatus{} },
+		timeDescs.also(userDescs).also(map[string]string{
+			// TRANSLATORS: This should not start with a lowercase letter.
+			"timers": i18n.G("List the timers activating the services"),
+			// TRANSLATORS: This should not start with a lowercase letter.
+			"sockets": i18n.G("List the sockets activating the services"),
+		}), argdescs)
 	addCommand("logs", shortLogsHelp, longLogsHelp, func() flags.Commander { return &svcLogs{} },
 		map[string]string{
 			// TRANSLATORS: This should not start with a lowercase letter.
 			"n": i18n.G("Show only the given number of lines, or 'all'."),
 			// TRANSLATORS: This should not start with a lowercase letter.
 			"f": i18n.G("Wait for new lines and print them as they come in."),
+			// TRANSLATORS: This should not start with a lowercase letter.
+			"since": i18n.G("Show only the lines logged at or after the given time"),
+			// TRANSLATORS: This should not start with a lowercase letter.
+			"until": i18n.G("Show only the lines logged at or before the given time"),
+			// TRANSLATORS: This should not start with a lowercase letter.
+			"priority": i18n.G("Show only the lines of the given priority or a more important one"),
+			// TRANSLATORS: This should not start with a lowercase letter.
+			"boot": i18n.G("Show only the lines of the current boot, or of the previous one"),
+			// TRANSLATORS: This should not start with a lowercase letter.
+			"grep": i18n.G("Show only the lines whose message matches the given regular expression"),
+			// TRANSLATORS: This should not start with a lowercase letter.
+			"output": i18n.G("Print the lines as text or as JSON objects with all their fields"),
 		}, argdescs)
 
 	addCommand("start", shortStartHelp, longStartHelp, func() flags.Commander { return &svcStart{} },
-		waitDescs.also(map[string]string{
+		waitDescs.also(userDescs).also(map[string]string{
 			// TRANSLATORS: This should not start with a lowercase letter.
 			"enable": i18n.G("As well as starting the service now, arrange for it to be started on boot."),
 		}), argdescs)
 	addCommand("stop", shortStopHelp, longStopHelp, func() flags.Commander { return &svcStop{} },
-		waitDescs.also(map[string]string{
+		waitDescs.also(userDescs).also(map[string]string{
 			// TRANSLATORS: This should not start with a lowercase letter.
 			"disable": i18n.G("As well as stopping the service now, arrange for it to no longer be started on boot."),
 		}), argdescs)
 	addCommand("restart", shortRestartHelp, longRestartHelp, func() flags.Commander { return &svcRestart{} },
-		waitDescs.also(map[string]string{
+		waitDescs.also(userDescs).also(map[string]string{
 			// TRANSLATORS: This should not start with a lowercase letter.
 			"reload": i18n.G("If the service has a reload command, use it instead of restarting."),
 		}), argdescs)
@@ -120,17 +207,47 @@ func (s *svcStatus) Execute(args []string) error {
 	if len(args) > 0 {
 		return ErrExtraArgs
 	}
+	if s.Timers && s.Sockets {
+		return fmt.Errorf(i18n.G("cannot use --timers and --sockets together"))
+	}
+	users, err := s.userSelection()
+	if err != nil {
+		return err
+	}
+	if users != client.UserSelectionNone && (s.Timers || s.Sockets) {
+		return fmt.Errorf(i18n.G("cannot use --timers or --sockets with --user or --users"))
+	}
 
-	services, err := s.client.Apps(svcNames(s.Positional.ServiceNames), client.AppOptions{Service: true})
+	services, err := s.client.Apps(svcNames(s.Positional.ServiceNames), client.AppOptions{Service: true, Users: users})
 	if err != nil {
 		return err
 	}
 
+	if s.structuredOutput() {
+		if services == nil {
+			services = []*client.AppInfo{}
+		}
+		return s.printStructured(services)
+	}
+
 	if len(services) == 0 {
+		if users != client.UserSelectionNone {
+			fmt.Fprintln(Stderr, i18n.G("There are no user services provided by installed snaps."))
+			return nil
+		}
 		fmt.Fprintln(Stderr, i18n.G("There are no services provided by installed snaps."))
 		return nil
 	}
 
+	switch {
+	case users != client.UserSelectionNone:
+		return s.printUserStatus(services)
+	case s.Timers:
+		return s.printTimers(services)
+	case s.Sockets:
+		return s.printSockets(services)
+	}
+
 	w := tabWriter()
 	defer w.Flush()
 
@@ -153,6 +270,77 @@ func (s *svcStatus) Execute(args []string) error {
 	return nil
 }
 
+func (s *svcStatus) printUserStatus(services []*client.AppInfo) error {
+	w := tabWriter()
+	defer w.Flush()
+
+	fmt.Fprintln(w, i18n.G("Service\tUser\tStartup\tCurrent\tNotes"))
+	for _, svc := range services {
+		for _, st := range svc.UserStatus {
+			user := st.User
+			if user == "" {
+				user = strconv.Itoa(st.Uid)
+			}
+			startup := i18n.G("disabled")
+			if st.Enabled {
+				startup = i18n.G("enabled")
+			}
+			current := i18n.G("inactive")
+			if st.Active {
+				current = i18n.G("active")
+			}
+			fmt.Fprintf(w, "%s.%s\t%s\t%s\t%s\t%s\n", svc.Snap, svc.Name, user, startup, current, clientutil.ClientAppInfoNotes(svc))
+		}
+	}
+	return nil
+}
+
+func (s *svcStatus) printTimers(services []*client.AppInfo) error {
+	w := tabWriter()
+	defer w.Flush()
+
+	fmt.Fprintln(w, i18n.G("Service\tSchedule\tLast\tNext\tResult"))
+	for _, svc := range services {
+		for _, act := range svc.Activators {
+			if act.Type != "timer" {
+				continue
+			}
+			last, next, result := "-", "-", "-"
+			if !act.LastRun.IsZero() {
+				last = s.fmtTime(act.LastRun)
+			}
+			if !act.NextRun.IsZero() {
+				next = s.fmtTime(act.NextRun)
+			}
+			if act.LastResult != "" {
+				result = act.LastResult
+			}
+			fmt.Fprintf(w, "%s.%s\t%s\t%s\t%s\t%s\n", svc.Snap, svc.Name, act.Schedule, last, next, result)
+		}
+	}
+	return nil
+}
+
+func (s *svcStatus) printSockets(services []*client.AppInfo) error {
+	w := tabWriter()
+	defer w.Flush()
+
+	fmt.Fprintln(w, i18n.G("Service\tSocket\tListen"))
+	for _, svc := range services {
+		for _, act := range svc.Activators {
+			if act.Type != "socket" {
+				continue
+			}
+			listen := "-"
+			if len(act.Listen) > 0 {
+				listen = strings.Join(act.Listen, ",")
+			}
+			fmt.Fprintf(w, "%s.%s\t%s\t%s\n", svc.Snap, svc.Name, act.Name, listen)
+		}
+	}
+	return nil
+}
+
 func (s *svcLogs) Execute(args []string) error {
 	if len(args) > 0 {
 		return ErrExtraArgs
@@ -167,20 +355,61 @@ func (s *svcLogs) Execute(args []string) error {
 		sN = int(n)
 	}
 
-	logs, err := s.client.Logs(svcNames(s.Positional.ServiceNames), client.LogOptions{N: sN, Follow: s.Follow})
+	opts := client.LogOptions{
+		N:        sN,
+		Follow:   s.Follow,
+		Priority: s.Priority,
+		Boot:     s.Boot,
+		Grep:     s.Grep,
+		Fields:   s.Output == "json",
+	}
+	var err error
+	if s.Since != "" {
+		if opts.Since, err = parseLogTime(s.Since); err != nil {
+			return fmt.Errorf(i18n.G("invalid argument for flag ‘--since’: %v"), err)
+		}
+	}
+	if s.Until != "" {
+		if opts.Until, err = parseLogTime(s.Until); err != nil {
+			return fmt.Errorf(i18n.G("invalid argument for flag ‘--until’: %v"), err)
+		}
+	}
+
+	logs, err := s.client.Logs(svcNames(s.Positional.ServiceNames), opts)
 	if err != nil {
 		return err
 	}
 
+	enc := json.NewEncoder(Stdout)
 	for log := range logs {
+		if s.Output == "json" {
+			if err := enc.Encode(log); err != nil {
+				return err
+			}
+			continue
+		}
 		fmt.Fprintln(Stdout, log)
 	}
 
 	return nil
 }
 
+// parseLogTime parses the argument of --since and --until, either a time
+// in RFC3339 format or a duration before now.
+func parseLogTime(s string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 {
+		return time.Time{}, fmt.Errorf(i18n.G("expected a time in RFC3339 format or a duration, not %q"), s)
+	}
+	return timeNow().Add(-d), nil
+}
+
 type svcStart struct {
 	waitMixin
+	userMixin
 	Positional struct {
 		ServiceNames []serviceName `required:"1"`
 	} `positional-args:"yes" required:"yes"`
@@ -191,8 +420,12 @@ func (s *svcStart) Execute(args []string) error {
 	if len(args) > 0 {
 		return ErrExtraArgs
 	}
+	users, err := s.userSelection()
+	if err != nil {
+		return err
+	}
 	names := svcNames(s.Positional.ServiceNames)
-	changeID, err := s.client.Start(names, client.StartOptions{Enable: s.Enable})
+	changeID, err := s.client.Start(names, client.StartOptions{Enable: s.Enable, Users: users})
 	if err != nil {
 		return err
 	}
@@ -210,6 +443,7 @@ func (s *svcStart) Execute(args []string) error {
 
 type svcStop struct {
 	waitMixin
+	userMixin
 	Positional struct {
 		ServiceNames []serviceName `required:"1"`
 	} `positional-args:"yes" required:"yes"`
@@ -220,8 +454,12 @@ func (s *svcStop) Execute(args []string) error {
 	if len(args) > 0 {
 		return ErrExtraArgs
 	}
+	users, err := s.userSelection()
+	if err != nil {
+		return err
+	}
 	names := svcNames(s.Positional.ServiceNames)
-	changeID, err := s.client.Stop(names, client.StopOptions{Disable: s.Disable})
+	changeID, err := s.client.Stop(names, client.StopOptions{Disable: s.Disable, Users: users})
 	if err != nil {
 		return err
 	}
@@ -239,6 +477,7 @@ func (s *svcStop) Execute(args []string) error {
 
 type svcRestart struct {
 	waitMixin
+	userMixin
 	Positional struct {
 		ServiceNames []serviceName `required:"1"`
 	} `positional-args:"yes" required:"yes"`
@@ -249,8 +488,12 @@ func (s *svcRestart) Execute(args []string) error {
 	if len(args) > 0 {
 		return ErrExtraArgs
 	}
+	users, err := s.userSelection()
+	if err != nil {
+		return err
+	}
 	names := svcNames(s.Positional.ServiceNames)
-	changeID, err := s.client.Restart(names, client.RestartOptions{Reload: s.Reload})
+	changeID, err := s.client.Restart(names, client.RestartOptions{Reload: s.Reload, Users: users})
 	if err != nil {
 		return err
 	}
@@ -23,6 +23,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"net/url"
 	"sort"
 	"strings"
 	"time"
@@ -240,6 +241,170 @@ foo.zed  enabled  active    -
 	c.Check(n, check.Equals, 1)
 }
 
+func (s *appOpSuite) mockAppsWithActivators(c *check.C) *int {
+	n := 0
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		n++
+		c.Check(r.URL.Path, check.Equals, "/v2/apps")
+		c.Check(r.URL.Query().Get("select"), check.Equals, "service")
+		c.Check(r.Method, check.Equals, "GET")
+		fmt.Fprintln(w, `{"type": "sync", "status-code": 200, "result": [
+			{"snap": "foo", "name": "backup", "daemon": "oneshot", "activators": [
+				{"name": "backup", "type": "timer", "active": true, "enabled": true, "schedule": "00:00",
+				 "last-run": "2021-03-01T00:00:00Z", "next-run": "2021-03-02T00:00:00Z", "last-result": "exit-code"}
+			]},
+			{"snap": "foo", "name": "cleanup", "daemon": "oneshot", "activators": [
+				{"name": "cleanup", "type": "timer", "active": true, "enabled": true, "schedule": "mon,10:00-12:00"}
+			]},
+			{"snap": "foo", "name": "srv", "daemon": "simple", "activators": [
+				{"name": "sock1", "type": "socket", "active": true, "enabled": true, "listen": ["/var/snap/foo/common/sock1", "[::]:8080"]},
+				{"name": "sock2", "type": "socket", "active": false, "enabled": true}
+			]},
+			{"snap": "foo", "name": "zed", "daemon": "simple", "active": true, "enabled": true}
+		]}`)
+	})
+	return &n
+}
+
+func (s *appOpSuite) TestAppStatusTimers(c *check.C) {
+	n := s.mockAppsWithActivators(c)
+
+	rest, err := snap.Parser(snap.Client()).ParseArgs([]string{"services", "--timers", "--abs-time"})
+	c.Assert(err, check.IsNil)
+	c.Assert(rest, check.HasLen, 0)
+	c.Check(s.Stderr(), check.Equals, "")
+	c.Check(s.Stdout(), check.Equals, `
+Service      Schedule         Last                  Next                  Result
+foo.backup   00:00            2021-03-01T00:00:00Z  2021-03-02T00:00:00Z  exit-code
+foo.cleanup  mon,10:00-12:00  -                     -                     -
+`[1:])
+	c.Check(*n, check.Equals, 1)
+}
+
+func (s *appOpSuite) TestAppStatusSockets(c *check.C) {
+	n := s.mockAppsWithActivators(c)
+
+	rest, err := snap.Parser(snap.Client()).ParseArgs([]string{"services", "--sockets"})
+	c.Assert(err, check.IsNil)
+	c.Assert(rest, check.HasLen, 0)
+	c.Check(s.Stderr(), check.Equals, "")
+	c.Check(s.Stdout(), check.Equals, `
+Service  Socket  Listen
+foo.srv  sock1   /var/snap/foo/common/sock1,[::]:8080
+foo.srv  sock2   -
+`[1:])
+	c.Check(*n, check.Equals, 1)
+}
+
+func (s *appOpSuite) TestAppStatusTimersAndSockets(c *check.C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		c.Fatalf("unexpected request")
+	})
+
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"services", "--timers", "--sockets"})
+	c.Assert(err, check.ErrorMatches, "cannot use --timers and --sockets together")
+}
+
+func (s *appOpSuite) TestAppStatusUsers(c *check.C) {
+	for _, tc := range []struct {
+		args  []string
+		users string
+	}{
+		{[]string{"services", "--user"}, "self"},
+		{[]string{"services", "--users=all"}, "all"},
+	} {
+		n := 0
+		s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+			n++
+			c.Check(r.URL.Path, check.Equals, "/v2/apps")
+			c.Check(r.URL.Query(), check.HasLen, 2)
+			c.Check(r.URL.Query().Get("select"), check.Equals, "service")
+			c.Check(r.URL.Query().Get("users"), check.Equals, tc.users)
+			fmt.Fprintln(w, `{"type": "sync", "result": [
+{"snap": "foo", "name": "sync", "daemon": "simple", "daemon-scope": "user", "user-status": [
+  {"uid": 1000, "user": "jane", "enabled": true, "active": true},
+  {"uid": 1001, "enabled": true}
+]},
+{"snap": "foo", "name": "idle", "daemon": "simple", "daemon-scope": "user", "user-status": [
+  {"uid": 1000, "user": "jane"}
+]}
+]}`)
+		})
+		s.ResetStdStreams()
+
+		rest, err := snap.Parser(snap.Client()).ParseArgs(tc.args)
+		c.Assert(err, check.IsNil)
+		c.Assert(rest, check.HasLen, 0)
+		c.Check(s.Stderr(), check.Equals, "")
+		c.Check(s.Stdout(), check.Equals, `
+Service   User  Startup   Current   Notes
+foo.sync  jane  enabled   active    user
+foo.sync  1001  enabled   inactive  user
+foo.idle  jane  disabled  inactive  user
+`[1:])
+		c.Check(n, check.Equals, 1)
+	}
+}
+
+func (s *appOpSuite) TestAppStatusUsersNoServices(c *check.C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, `{"type": "sync", "result": []}`)
+	})
+
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"services", "--user"})
+	c.Assert(err, check.IsNil)
+	c.Check(s.Stdout(), check.Equals, "")
+	c.Check(s.Stderr(), check.Equals, "There are no user services provided by installed snaps.\n")
+}
+
+func (s *appOpSuite) TestAppUsersErrors(c *check.C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		c.Fatalf("unexpected request")
+	})
+
+	for _, tc := range []struct {
+		args []string
+		err  string
+	}{
+		{[]string{"services", "--user", "--users=all"}, "cannot use --user and --users together"},
+		{[]string{"services", "--user", "--timers"}, "cannot use --timers or --sockets with --user or --users"},
+		{[]string{"services", "--users=all", "--sockets"}, "cannot use --timers or --sockets with --user or --users"},
+		{[]string{"start", "--user", "--users=all", "foo"}, "cannot use --user and --users together"},
+		{[]string{"services", "--users=some"}, `.*Invalid value .some. for option .--users.*`},
+	} {
+		_, err := snap.Parser(snap.Client()).ParseArgs(tc.args)
+		c.Check(err, check.ErrorMatches, tc.err, check.Commentf("%v", tc.args))
+	}
+}
+
+func (s *appOpSuite) TestAppOpsUsers(c *check.C) {
+	for _, tc := range []struct {
+		op    string
+		flag  string
+		users string
+	}{
+		{"start", "--user", "self"},
+		{"stop", "--users=all", "all"},
+		{"restart", "--user", "self"},
+	} {
+		s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+			c.Check(r.URL.Path, check.Equals, "/v2/apps")
+			c.Check(DecodedRequestBody(c, r), check.DeepEquals, map[string]interface{}{
+				"action": tc.op,
+				"names":  []interface{}{"foo"},
+				"users":  tc.users,
+			})
+			w.WriteHeader(202)
+			fmt.Fprintln(w, `{"type":"async", "change": "42", "status-code": 202}`)
+		})
+		s.ResetStdStreams()
+
+		_, err := snap.Parser(snap.Client()).ParseArgs([]string{tc.op, "--no-wait", tc.flag, "foo"})
+		c.Assert(err, check.IsNil)
+		c.Check(s.Stdout(), check.Equals, "42\n")
+	}
+}
+
 func (s *appOpSuite) TestServiceCompletion(c *check.C) {
 	n := 0
 	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
@@ -285,6 +450,49 @@ func (s *appOpSuite) TestServiceCompletion(c *check.C) {
 	c.Check(n, check.Equals, 7)
 }
 
+func (s *appOpSuite) TestAppStatusFormatJSON(c *check.C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		c.Check(r.URL.Path, check.Equals, "/v2/apps")
+		c.Check(r.URL.Query().Get("select"), check.Equals, "service")
+		fmt.Fprintln(w, `{"type": "sync", "status-code": 200, "result": [
+			{"snap": "foo", "name": "qux", "daemon": "simple", "daemon-scope": "user", "enabled": true},
+			{"snap": "foo", "name": "zed", "daemon": "simple", "daemon-scope": "system", "active": true, "enabled": true}
+		]}`)
+	})
+	rest, err := snap.Parser(snap.Client()).ParseArgs([]string{"services", "--format=json"})
+	c.Assert(err, check.IsNil)
+	c.Assert(rest, check.HasLen, 0)
+	c.Check(s.Stderr(), check.Equals, "")
+	c.Check(s.Stdout(), check.Equals, `[
+  {
+    "snap": "foo",
+    "name": "qux",
+    "daemon": "simple",
+    "daemon-scope": "user",
+    "enabled": true
+  },
+  {
+    "snap": "foo",
+    "name": "zed",
+    "daemon": "simple",
+    "daemon-scope": "system",
+    "enabled": true,
+    "active": true
+  }
+]
+`)
+}
+
+func (s *appOpSuite) TestAppStatusNoServicesFormatYAML(c *check.C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, `{"type": "sync", "status-code": 200, "result": []}`)
+	})
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"services", "--format=yaml"})
+	c.Assert(err, check.IsNil)
+	c.Check(s.Stdout(), check.Equals, "[]\n")
+	c.Check(s.Stderr(), check.Equals, "")
+}
+
 func (s *appOpSuite) TestAppStatusNoServices(c *check.C) {
 	n := 0
 	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
@@ -315,3 +523,67 @@ func (s *appOpSuite) TestAppStatusNoServices(c *check.C) {
 	// ensure that the fake server api was actually hit
 	c.Check(n, check.Equals, 1)
 }
+
+func (s *appOpSuite) TestLogs(c *check.C) {
+	n := 0
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		n++
+		c.Check(r.Method, check.Equals, "GET")
+		c.Check(r.URL.Path, check.Equals, "/v2/logs")
+		c.Check(r.URL.Query(), check.DeepEquals, url.Values{
+			"names": {"foo.bar"},
+			"n":     {"10"},
+		})
+		fmt.Fprint(w, "\x1e"+`{"timestamp":"2021-03-01T10:00:00Z","message":"hello","sid":"foo.bar","pid":"42"}`+"\n")
+	})
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"logs", "foo.bar"})
+	c.Assert(err, check.IsNil)
+	c.Check(s.Stdout(), check.Equals, "2021-03-01T10:00:00Z foo.bar[42]: hello\n")
+	c.Check(s.Stderr(), check.Equals, "")
+	c.Check(n, check.Equals, 1)
+}
+
+func (s *appOpSuite) TestLogsFilter(c *check.C) {
+	restore := snap.MockTimeNow(func() time.Time {
+		return time.Date(2021, 3, 2, 10, 0, 0, 0, time.UTC)
+	})
+	defer restore()
+
+	n := 0
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		n++
+		c.Check(r.URL.Path, check.Equals, "/v2/logs")
+		c.Check(r.URL.Query(), check.DeepEquals, url.Values{
+			"names":    {"foo"},
+			"n":        {"-1"},
+			"since":    {"2021-03-01T10:00:00Z"},
+			"until":    {"2021-03-02T09:30:00.25Z"},
+			"priority": {"err"},
+			"boot":     {"previous"},
+			"grep":     {"crash"},
+			"fields":   {"true"},
+		})
+		fmt.Fprint(w, "\x1e"+`{"timestamp":"2021-03-01T10:00:00Z","message":"crash","sid":"foo.bar","pid":"42","fields":{"MESSAGE":"crash","PRIORITY":"3"}}`+"\n")
+	})
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"logs", "-n", "all",
+		"--since", "24h", "--until", "2021-03-02T09:30:00.25Z", "--priority", "err",
+		"--boot=previous", "--grep", "crash", "-o", "json", "foo"})
+	c.Assert(err, check.IsNil)
+	c.Check(s.Stdout(), check.Equals, `{"timestamp":"2021-03-01T10:00:00Z","message":"crash","sid":"foo.bar","pid":"42","fields":{"MESSAGE":"crash","PRIORITY":"3"}}`+"\n")
+	c.Check(n, check.Equals, 1)
+}
+
+func (s *appOpSuite) TestLogsBootDefaultsToCurrent(c *check.C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		c.Check(r.URL.Query().Get("boot"), check.Equals, "current")
+	})
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"logs", "--boot", "foo"})
+	c.Assert(err, check.IsNil)
+}
+
+func (s *appOpSuite) TestLogsBadTime(c *check.C) {
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"logs", "--since", "yesterday", "foo"})
+	c.Assert(err, check.ErrorMatches, `invalid argument for flag ‘--since’: expected a time in RFC3339 format or a duration, not "yesterday"`)
+	_, err = snap.Parser(snap.Client()).ParseArgs([]string{"logs", "--until=-1h", "foo"})
+	c.Assert(err, check.ErrorMatches, `invalid argument for flag ‘--until’: expected a time in RFC3339 format or a duration, not "-1h"`)
+}
@@ -21,12 +21,15 @@ package main
 
 import (
 	"fmt"
+	"io/ioutil"
 	"strings"
 
 	"github.com/jessevdk/go-flags"
+	"gopkg.in/yaml.v2"
 
 	"github.com/snapcore/snapd/i18n"
 	"github.com/snapcore/snapd/jsonutil"
+	"github.com/snapcore/snapd/metautil"
 )
 
 var shortSetHelp = i18n.G("Change configuration options")
@@ -44,18 +47,36 @@ Nested values may be modified via a dotted path:
 
 Configuration option may be unset with exclamation mark:
     $ snap set snap-name author!
+
+The --from-file option replaces the whole configuration of the snap with
+the YAML or JSON document in the given file, or read from the standard
+input if the file is "-", as exported by 'snap get --export'. Options that
+are not part of the document are unset, and the configure hook of the snap
+runs once for all the changes:
+
+    $ snap set --from-file=conf.yaml snap-name
+
+Without a snap name, the document maps snap names, and "system" for the
+system configuration, to their whole configuration, as exported by
+'snap get --export' without a snap name. Snaps that are not part of the
+document are left alone. If the configure hook of one of the snaps fails,
+the configuration of all of them is restored.
 `)
 
 type cmdSet struct {
 	waitMixin
+	FromFile   string `long:"from-file"`
 	Positional struct {
 		Snap       installedSnapName
-		ConfValues []string `required:"1"`
-	} `positional-args:"yes" required:"yes"`
+		ConfValues []string
+	} `positional-args:"yes"`
 }
 
 func init() {
-	addCommand("set", shortSetHelp, longSetHelp, func() flags.Commander { return &cmdSet{} }, waitDescs, []argDesc{
+	addCommand("set", shortSetHelp, longSetHelp, func() flags.Commander { return &cmdSet{} }, waitDescs.also(map[string]string{
+		// TRANSLATORS: This should not start with a lowercase letter.
+		"from-file": i18n.G("Replace the whole configuration with the one in the given file"),
+	}), []argDesc{
 		{
 			name: "<snap>",
 			// TRANSLATORS: This should not start with a lowercase letter.
@@ -70,6 +91,19 @@ func init() {
 }
 
 func (x *cmdSet) Execute(args []string) error {
+	if x.FromFile != "" {
+		if len(x.Positional.ConfValues) > 0 {
+			return fmt.Errorf(i18n.G("cannot use --from-file with configuration values"))
+		}
+		return x.replaceConf()
+	}
+	if x.Positional.Snap == "" {
+		return fmt.Errorf("the required argument `<snap>` was not provided")
+	}
+	if len(x.Positional.ConfValues) == 0 {
+		return fmt.Errorf("the required argument `<conf value>` (at least 1 argument) was not provided")
+	}
+
 	patchValues := make(map[string]interface{})
 	for _, patchValue := range x.Positional.ConfValues {
 		parts := strings.SplitN(patchValue, "=", 2)
@@ -95,6 +129,10 @@ func (x *cmdSet) Execute(args []string) error {
 		return err
 	}
 
+	return x.waitConf(id)
+}
+
+func (x *cmdSet) waitConf(id string) error {
 	if _, err := x.wait(id); err != nil {
 		if err == noWait {
 			return nil
@@ -104,3 +142,56 @@ func (x *cmdSet) Execute(args []string) error {
 
 	return nil
 }
+
+func (x *cmdSet) replaceConf() error {
+	var data []byte
+	var err error
+	if x.FromFile == "-" {
+		data, err = ioutil.ReadAll(Stdin)
+	} else {
+		data, err = ioutil.ReadFile(x.FromFile)
+	}
+	if err != nil {
+		return fmt.Errorf(i18n.G("cannot read configuration: %v"), err)
+	}
+
+	doc, err := parseConfDocument(data)
+	if err != nil {
+		return err
+	}
+
+	var confs map[string]map[string]interface{}
+	if snapName := string(x.Positional.Snap); snapName != "" {
+		confs = map[string]map[string]interface{}{snapName: doc}
+	} else {
+		confs = make(map[string]map[string]interface{}, len(doc))
+		for snapName, value := range doc {
+			conf, ok := value.(map[string]interface{})
+			if !ok {
+				return fmt.Errorf(i18n.G("invalid configuration of snap %q: expected a map of options"), snapName)
+			}
+			confs[snapName] = conf
+		}
+	}
+
+	id, err := x.client.ReplaceConf(confs)
+	if err != nil {
+		return err
+	}
+
+	return x.waitConf(id)
+}
+
+// parseConfDocument parses a YAML, or JSON, configuration document into
+// values that can be sent as JSON.
+func parseConfDocument(data []byte) (map[string]interface{}, error) {
+	var doc map[string]interface{}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf(i18n.G("cannot parse configuration: %v"), err)
+	}
+	conf, err := metautil.NormalizeValue(doc)
+	if err != nil {
+		return nil, fmt.Errorf(i18n.G("invalid configuration: %v"), err)
+	}
+	return conf.(map[string]interface{}), nil
+}
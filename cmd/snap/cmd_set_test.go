@@ -22,7 +22,10 @@ package main_test
 import (
 	"encoding/json"
 	"fmt"
+	"io/ioutil"
 	"net/http"
+	"os"
+	"path/filepath"
 
 	"gopkg.in/check.v1"
 
@@ -128,3 +131,94 @@ func (s *snapSetSuite) mockSetConfigServer(c *check.C, expectedValue interface{}
 		}
 	})
 }
+
+func (s *snapSetSuite) mockReplaceConfigServer(c *check.C, expected map[string]interface{}) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v2/conf":
+			c.Check(r.Method, check.Equals, "PUT")
+			c.Check(DecodedRequestBody(c, r), check.DeepEquals, expected)
+			w.WriteHeader(202)
+			fmt.Fprintln(w, `{"type":"async", "status-code": 202, "change": "zzz"}`)
+			s.setConfApiCalls += 1
+		case "/v2/changes/zzz":
+			c.Check(r.Method, check.Equals, "GET")
+			fmt.Fprintln(w, `{"type":"sync", "result":{"ready": true, "status": "Done"}}`)
+		default:
+			c.Fatalf("unexpected path %q", r.URL.Path)
+		}
+	})
+}
+
+func (s *snapSetSuite) TestSnapSetFromFile(c *check.C) {
+	s.mockReplaceConfigServer(c, map[string]interface{}{
+		"snapname": map[string]interface{}{
+			"key":    "value",
+			"number": json.Number("42"),
+			"nested": map[string]interface{}{"a": true, "b": []interface{}{"x", json.Number("1.5")}},
+		},
+	})
+
+	confFile := filepath.Join(c.MkDir(), "conf.yaml")
+	err := ioutil.WriteFile(confFile, []byte(`
+key: value
+number: 42
+nested:
+  a: true
+  b: [x, 1.5]
+`), 0644)
+	c.Assert(err, check.IsNil)
+
+	_, err = snapset.Parser(snapset.Client()).ParseArgs([]string{"set", "--from-file", confFile, "snapname"})
+	c.Assert(err, check.IsNil)
+	c.Check(s.setConfApiCalls, check.Equals, 1)
+}
+
+func (s *snapSetSuite) TestSnapSetFromFileAllSnaps(c *check.C) {
+	s.mockReplaceConfigServer(c, map[string]interface{}{
+		"system":   map[string]interface{}{"service": map[string]interface{}{"ssh": map[string]interface{}{"disable": true}}},
+		"snapname": map[string]interface{}{"key": "value"},
+	})
+
+	// JSON is fine as well, read from stdin here
+	s.stdin.WriteString(`{"system": {"service": {"ssh": {"disable": true}}}, "snapname": {"key": "value"}}`)
+
+	_, err := snapset.Parser(snapset.Client()).ParseArgs([]string{"set", "--from-file=-"})
+	c.Assert(err, check.IsNil)
+	c.Check(s.setConfApiCalls, check.Equals, 1)
+}
+
+func (s *snapSetSuite) TestSnapSetFromFileErrors(c *check.C) {
+	dir := c.MkDir()
+	for _, tc := range []struct {
+		args    []string
+		content string
+		err     string
+	}{
+		{[]string{"snapname", "key=value"}, "key: value", `cannot use --from-file with configuration values`},
+		{[]string{"snapname"}, "", `cannot read configuration: open .*: no such file or directory`},
+		{[]string{"snapname"}, "key: [", `cannot parse configuration: .*`},
+		{[]string{"snapname"}, "- a", `(?s)cannot parse configuration: .*`},
+		{[]string{"snapname"}, "key: ~", `invalid configuration: invalid scalar: <nil>`},
+		{nil, "snapname: value", `invalid configuration of snap "snapname": expected a map of options`},
+	} {
+		confFile := filepath.Join(dir, "conf.yaml")
+		os.Remove(confFile)
+		if tc.content != "" {
+			c.Assert(ioutil.WriteFile(confFile, []byte(tc.content), 0644), check.IsNil)
+		}
+		args := append([]string{"set", "--from-file", confFile}, tc.args...)
+		_, err := snapset.Parser(snapset.Client()).ParseArgs(args)
+		c.Check(err, check.ErrorMatches, tc.err, check.Commentf("%v", tc))
+	}
+	c.Check(s.setConfApiCalls, check.Equals, 0)
+}
+
+func (s *snapSetSuite) TestSnapSetMissingArguments(c *check.C) {
+	_, err := snapset.Parser(snapset.Client()).ParseArgs([]string{"set"})
+	c.Check(err, check.ErrorMatches, "the required argument `<snap>` was not provided")
+
+	_, err = snapset.Parser(snapset.Client()).ParseArgs([]string{"set", "snapname"})
+	c.Check(err, check.ErrorMatches, `the required argument .<conf value>. \(at least 1 argument\) was not provided`)
+	c.Check(s.setConfApiCalls, check.Equals, 0)
+}
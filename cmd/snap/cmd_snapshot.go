@@ -28,6 +28,7 @@ import (
 
 	"github.com/jessevdk/go-flags"
 
+	"github.com/snapcore/snapd/client"
 	"github.com/snapcore/snapd/i18n"
 	"github.com/snapcore/snapd/strutil"
 	"github.com/snapcore/snapd/strutil/quantity"
@@ -114,6 +115,7 @@ with a new snapshot ID and can be restored using the restore command.
 type savedCmd struct {
 	clientMixin
 	durationMixin
+	formatMixin
 	ID         snapshotID `long:"id"`
 	Positional struct {
 		Snaps []installedSnapName `positional-arg-name:"<snap>"`
@@ -134,6 +136,12 @@ func (x *savedCmd) Execute([]string) error {
 	if err != nil {
 		return err
 	}
+	if x.structuredOutput() {
+		if list == nil {
+			list = []client.SnapshotSet{}
+		}
+		return x.printStructured(list)
+	}
 	if len(list) == 0 {
 		fmt.Fprintln(Stdout, i18n.G("No snapshots found."))
 		return nil
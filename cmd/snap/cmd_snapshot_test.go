@@ -101,6 +101,15 @@ func (s *SnapSuite) TestSnapSnaphotsTest(c *C) {
 	}
 }
 
+func (s *SnapSuite) TestSnapshotSavedFormatYAML(c *C) {
+	s.mockSnapshotsServer(c)
+
+	_, err := main.Parser(main.Client()).ParseArgs([]string{"saved", "--id=3", "--format=yaml"})
+	c.Assert(err, IsNil)
+	c.Check(s.Stderr(), Equals, "")
+	c.Check(s.Stdout(), Matches, `(?ms)^- id: 3\n  snapshots:\n  - auto: true\n.*^    revision: "1168"\n    set: 3\n.*^    size: 1\n    snap: htop\n.*`)
+}
+
 func (s *SnapSuite) TestSnapshotExportHappy(c *C) {
 	s.mockSnapshotsServer(c)
 
@@ -0,0 +1,188 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package main
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/jessevdk/go-flags"
+
+	"github.com/snapcore/snapd/client"
+	"github.com/snapcore/snapd/i18n"
+)
+
+var shortTopHelp = i18n.G("Show the resource usage of snaps")
+var longTopHelp = i18n.G(`
+The top command periodically shows the CPU, memory, process and block IO
+usage of the processes of snaps, ordered by CPU usage.
+
+Without arguments, snaps with running processes are shown. The CPU column
+is the share of a single CPU used since the previous refresh.
+
+Usage is only accounted for processes tracked by snapd, and depending on
+the system some of it may not be available, in which case it is shown as -.
+`)
+
+type cmdTop struct {
+	clientMixin
+	Apps       bool          `long:"apps"`
+	Delay      time.Duration `short:"d" long:"delay" default:"2s"`
+	Iterations int           `short:"n" long:"iterations"`
+	Positional struct {
+		Snaps []installedSnapName `positional-arg-name:"<snap>"`
+	} `positional-args:"yes"`
+}
+
+var topSleep = time.Sleep
+
+func init() {
+	addCommand("top", shortTopHelp, longTopHelp, func() flags.Commander {
+		return &cmdTop{}
+	}, map[string]string{
+		// TRANSLATORS: This should not start with a lowercase letter.
+		"apps": i18n.G("Show the usage of each app and hook instead of snaps"),
+		// TRANSLATORS: This should not start with a lowercase letter.
+		"delay": i18n.G("Time between refreshes"),
+		// TRANSLATORS: This should not start with a lowercase letter.
+		"iterations": i18n.G("Exit after this many refreshes (0 means never)"),
+	}, []argDesc{{
+		name: "<snap>",
+		// TRANSLATORS: This should not start with a lowercase letter.
+		desc: i18n.G("Only show the given snaps"),
+	}})
+}
+
+// topEntry is the usage of a snap, or of an app or hook of a snap, in one
+// refresh.
+type topEntry struct {
+	name  string
+	usage client.ResourceUsage
+	// cpu is the percentage of a CPU used since the previous refresh
+	cpu float64
+}
+
+// topSample collects the usage of the given snaps, or of all snaps, keyed
+// by the name shown to the user.
+func (x *cmdTop) topSample(snaps []string) (map[string]client.ResourceUsage, error) {
+	usages, err := x.client.Usage(snaps)
+	if err != nil {
+		return nil, err
+	}
+	sample := make(map[string]client.ResourceUsage)
+	for _, usage := range usages {
+		if !x.Apps {
+			sample[usage.Snap] = usage.ResourceUsage
+			continue
+		}
+		for _, app := range usage.Apps {
+			sample[usage.Snap+"."+app.Name] = app.ResourceUsage
+		}
+	}
+	return sample, nil
+}
+
+func (x *cmdTop) Execute(args []string) error {
+	if len(args) > 0 {
+		return ErrExtraArgs
+	}
+	if x.Delay <= 0 {
+		return fmt.Errorf(i18n.G("delay must be positive, not %s"), x.Delay)
+	}
+
+	names := installedSnapNames(x.Positional.Snaps)
+	prev, err := x.topSample(names)
+	if err != nil {
+		return err
+	}
+	prevTime := timeNow()
+	for i := 0; x.Iterations == 0 || i < x.Iterations; i++ {
+		topSleep(x.Delay)
+
+		sample, err := x.topSample(names)
+		if err != nil {
+			return err
+		}
+		now := timeNow()
+		elapsed := now.Sub(prevTime)
+
+		entries := make([]*topEntry, 0, len(sample))
+		for name, usage := range sample {
+			// without arguments only snaps which are running
+			// are of interest
+			if len(names) == 0 && usage.Processes == 0 {
+				continue
+			}
+			entry := &topEntry{name: name, usage: usage}
+			if elapsed > 0 {
+				if delta := usage.CPUTime - prev[name].CPUTime; delta > 0 {
+					entry.cpu = 100 * float64(delta) / float64(elapsed)
+				}
+			}
+			entries = append(entries, entry)
+		}
+		sort.Slice(entries, func(i, j int) bool {
+			if entries[i].cpu != entries[j].cpu {
+				return entries[i].cpu > entries[j].cpu
+			}
+			return entries[i].name < entries[j].name
+		})
+
+		if isStdoutTTY && x.Iterations != 1 {
+			// clear the screen and move the cursor home
+			fmt.Fprint(Stdout, "\033[H\033[2J")
+		}
+		x.showTop(entries)
+
+		prev, prevTime = sample, now
+	}
+	return nil
+}
+
+func (x *cmdTop) showTop(entries []*topEntry) {
+	w := tabWriter()
+	defer w.Flush()
+
+	nameHeader := i18n.G("Snap")
+	if x.Apps {
+		nameHeader = i18n.G("App")
+	}
+	fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", nameHeader, i18n.G("CPU%"), i18n.G("CPU-time"), i18n.G("Memory"), i18n.G("Procs"), i18n.G("Read"), i18n.G("Write"))
+	for _, entry := range entries {
+		usage := entry.usage
+		cpu, cpuTime := fmt.Sprintf("%.1f", entry.cpu), usage.CPUTime.Round(10*time.Millisecond).String()
+		memory := fmtSize(int64(usage.Memory))
+		read, write := fmtSize(int64(usage.IORead)), fmtSize(int64(usage.IOWrite))
+		// resources which are not accounted for on the system
+		for _, resource := range usage.Unavailable {
+			switch resource {
+			case "cpu-time":
+				cpu, cpuTime = "-", "-"
+			case "memory":
+				memory = "-"
+			case "io":
+				read, write = "-", "-"
+			}
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%d\t%s\t%s\n",
+			entry.name, cpu, cpuTime, memory, usage.Processes, read, write)
+	}
+}
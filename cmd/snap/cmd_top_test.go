@@ -0,0 +1,143 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package main_test
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"gopkg.in/check.v1"
+
+	snap "github.com/snapcore/snapd/cmd/snap"
+)
+
+func (s *SnapSuite) mockTop(c *check.C) (sleeps *[]time.Duration) {
+	now := time.Date(2021, 3, 1, 10, 0, 0, 0, time.UTC)
+	s.AddCleanup(snap.MockTimeNow(func() time.Time { return now }))
+	sleeps = &[]time.Duration{}
+	s.AddCleanup(snap.MockTopSleep(func(d time.Duration) {
+		*sleeps = append(*sleeps, d)
+		now = now.Add(d)
+	}))
+
+	// each sample foo uses another second of CPU time, bar is idle and
+	// baz is not running
+	samples := 0
+	usages := map[string]func() string{
+		"foo": func() string {
+			return fmt.Sprintf(`{"snap": "foo", "cpu-time": %d, "memory": 2097152, "processes": 3, "io-read": 1024, "io-write": 0,
+"apps": [{"name": "app", "cpu-time": %[1]d, "memory": 1048576, "processes": 1, "io-read": 1024, "io-write": 0},
+	{"name": "daemon", "cpu-time": 0, "memory": 1048576, "processes": 2, "io-read": 0, "io-write": 0}]}`, samples*int(time.Second))
+		},
+		"bar": func() string {
+			return `{"snap": "bar", "cpu-time": 5000000000, "memory": 1024, "processes": 1, "io-read": 0, "io-write": 0,
+"apps": [{"name": "daemon", "cpu-time": 5000000000, "memory": 1024, "processes": 1, "io-read": 0, "io-write": 0}]}`
+		},
+		"baz": func() string {
+			return `{"snap": "baz", "cpu-time": 0, "memory": 0, "processes": 0, "io-read": 0, "io-write": 0}`
+		},
+	}
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		c.Check(r.Method, check.Equals, "GET")
+		c.Check(r.URL.Path, check.Equals, "/v2/usage")
+		samples++
+		names := []string{"foo", "bar", "baz"}
+		if q := r.URL.Query().Get("snaps"); q != "" {
+			names = strings.Split(q, ",")
+		}
+		var result []string
+		for _, name := range names {
+			result = append(result, usages[name]())
+		}
+		fmt.Fprintf(w, `{"type": "sync", "result": [%s]}`, strings.Join(result, ","))
+	})
+	return sleeps
+}
+
+func (s *SnapSuite) TestTop(c *check.C) {
+	sleeps := s.mockTop(c)
+
+	rest, err := snap.Parser(snap.Client()).ParseArgs([]string{"top", "-n", "2"})
+	c.Assert(err, check.IsNil)
+	c.Assert(rest, check.DeepEquals, []string{})
+	c.Check(*sleeps, check.DeepEquals, []time.Duration{2 * time.Second, 2 * time.Second})
+	c.Check(s.Stdout(), check.Equals, `
+Snap  CPU%  CPU-time  Memory  Procs  Read    Write
+foo   50.0  2s        2.10MB  3       1024B      0B
+bar   0.0   5s         1024B  1          0B      0B
+Snap  CPU%  CPU-time  Memory  Procs  Read    Write
+foo   50.0  3s        2.10MB  3       1024B      0B
+bar   0.0   5s         1024B  1          0B      0B
+`[1:])
+	c.Check(s.Stderr(), check.Equals, "")
+}
+
+func (s *SnapSuite) TestTopApps(c *check.C) {
+	sleeps := s.mockTop(c)
+	restore := snap.MockIsStdoutTTY(true)
+	defer restore()
+
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"top", "--apps", "-n", "1", "--delay", "1s", "foo", "baz"})
+	c.Assert(err, check.IsNil)
+	c.Check(*sleeps, check.DeepEquals, []time.Duration{time.Second})
+	// a single refresh does not clear the screen
+	c.Check(s.Stdout(), check.Equals, `
+App         CPU%   CPU-time  Memory  Procs  Read    Write
+foo.app     100.0  2s        1.05MB  1       1024B      0B
+foo.daemon  0.0    0s        1.05MB  2          0B      0B
+`[1:])
+}
+
+func (s *SnapSuite) TestTopClearsScreen(c *check.C) {
+	s.mockTop(c)
+	restore := snap.MockIsStdoutTTY(true)
+	defer restore()
+
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"top", "-n", "2", "bar"})
+	c.Assert(err, check.IsNil)
+	c.Check(s.Stdout(), check.Equals, `
+`[1:]+"\033[H\033[2J"+`Snap  CPU%  CPU-time  Memory  Procs  Read    Write
+bar   0.0   5s         1024B  1          0B      0B
+`+"\033[H\033[2J"+`Snap  CPU%  CPU-time  Memory  Procs  Read    Write
+bar   0.0   5s         1024B  1          0B      0B
+`)
+}
+
+func (s *SnapSuite) TestTopBadDelay(c *check.C) {
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"top", "--delay", "0s"})
+	c.Assert(err, check.ErrorMatches, "delay must be positive, not 0s")
+}
+
+func (s *SnapSuite) TestTopUnavailable(c *check.C) {
+	s.AddCleanup(snap.MockTopSleep(func(time.Duration) {}))
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		c.Check(r.URL.Path, check.Equals, "/v2/usage")
+		fmt.Fprintln(w, `{"type": "sync", "result": [{"snap": "foo", "cpu-time": 0, "memory": 0, "processes": 1, "io-read": 0, "io-write": 0, "unavailable": ["cpu-time", "io", "memory"]}]}`)
+	})
+
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"top", "-n", "1"})
+	c.Assert(err, check.IsNil)
+	c.Check(s.Stdout(), check.Equals, `
+Snap  CPU%  CPU-time  Memory  Procs  Read  Write
+foo   -     -         -       1      -     -
+`[1:])
+}
@@ -41,6 +41,7 @@ type cmdValidate struct {
 		ValidationSet string `positional-arg-name:"<validation-set>"`
 	} `positional-args:"yes"`
 	colorMixin
+	formatMixin
 }
 
 var shortValidateHelp = i18n.G("List or apply validation sets")
@@ -131,6 +132,9 @@ func (cmd *cmdValidate) Execute(args []string) error {
 	if cmd.Positional.ValidationSet == "" && action != "" {
 		return fmt.Errorf("missing validation set argument")
 	}
+	if cmd.structuredOutput() && action != "" {
+		return fmt.Errorf("cannot use --format with --%s", action)
+	}
 
 	var accountID, name string
 	var seq int
@@ -161,6 +165,12 @@ func (cmd *cmdValidate) Execute(args []string) error {
 		if err != nil {
 			return err
 		}
+		if cmd.structuredOutput() {
+			if vsets == nil {
+				vsets = []*client.ValidationSetResult{}
+			}
+			return cmd.printStructured(vsets)
+		}
 		if len(vsets) == 0 {
 			fmt.Fprintln(Stderr, i18n.G("No validations are available"))
 			return nil
@@ -190,6 +200,9 @@ func (cmd *cmdValidate) Execute(args []string) error {
 		if err != nil {
 			return err
 		}
+		if cmd.structuredOutput() {
+			return cmd.printStructured(vset)
+		}
 		fmt.Fprintf(Stdout, fmtValid(vset))
 		// XXX: exit status 1 if invalid?
 	}
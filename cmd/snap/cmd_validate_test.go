@@ -233,3 +233,42 @@ func (s *validateSuite) TestValidationSetsListEmpty(c *check.C) {
 	c.Check(s.Stderr(), check.Equals, "No validations are available\n")
 	c.Check(s.Stdout(), check.Equals, "")
 }
+
+func (s *validateSuite) TestValidateQueryOneFormatJSON(c *check.C) {
+	s.RedirectClientToTestServer(makeFakeValidationSetQueryHandler(c, `{"type": "sync", "status-code": 200, "result": {"account-id":"foo","name":"bar","mode":"monitor","sequence":3,"valid":true}}`))
+
+	_, err := main.Parser(main.Client()).ParseArgs([]string{"validate", "--format=json", "foo/bar"})
+	c.Assert(err, check.IsNil)
+	c.Check(s.Stderr(), check.Equals, "")
+	c.Check(s.Stdout(), check.Equals, `{
+  "account-id": "foo",
+  "name": "bar",
+  "sequence": 3,
+  "mode": "monitor",
+  "valid": true
+}
+`)
+}
+
+func (s *validateSuite) TestValidationSetsListFormatYAML(c *check.C) {
+	s.RedirectClientToTestServer(makeFakeListValidationsSetsHandler(c, `{"type": "sync", "status-code": 200, "result": [
+		{"account-id":"foo","name":"bar","mode":"monitor","pinned-at":2,"sequence":3,"valid":true}
+	]}`))
+
+	_, err := main.Parser(main.Client()).ParseArgs([]string{"validate", "--format=yaml"})
+	c.Assert(err, check.IsNil)
+	c.Check(s.Stderr(), check.Equals, "")
+	c.Check(s.Stdout(), check.Equals, `
+- account-id: foo
+  mode: monitor
+  name: bar
+  pinned-at: 2
+  sequence: 3
+  valid: true
+`[1:])
+}
+
+func (s *validateSuite) TestValidateFormatWithAction(c *check.C) {
+	_, err := main.Parser(main.Client()).ParseArgs([]string{"validate", "--format=json", "--monitor", "foo/bar"})
+	c.Assert(err, check.ErrorMatches, `cannot use --format with --monitor`)
+}
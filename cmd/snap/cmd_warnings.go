@@ -39,6 +39,7 @@ type cmdWarnings struct {
 	clientMixin
 	timeMixin
 	unicodeMixin
+	formatMixin
 	All     bool `long:"all"`
 	Verbose bool `long:"verbose"`
 }
@@ -84,6 +85,17 @@ func (cmd *cmdWarnings) Execute(args []string) error {
 	if err != nil {
 		return err
 	}
+	if cmd.structuredOutput() {
+		if len(warnings) == 0 {
+			return cmd.printStructured([]*client.Warning{})
+		}
+		// as with the table, listing the warnings allows to
+		// acknowledge them with 'snap okay'
+		if err := writeWarningTimestamp(now); err != nil {
+			return err
+		}
+		return cmd.printStructured(warnings)
+	}
 	if len(warnings) == 0 {
 		if t, _ := lastWarningTimestamp(); t.IsZero() {
 			fmt.Fprintln(Stdout, i18n.G("No warnings."))
@@ -227,3 +227,30 @@ Name  Version  Rev    Tracking  Publisher  Notes
 	c.Check(s.Stderr(), check.Equals, "WARNING: There are 2 new warnings. See 'snap warnings'.\n")
 
 }
+
+func (s *warningSuite) TestWarningsFormatJSON(c *check.C) {
+	s.RedirectClientToTestServer(mkWarningsFakeHandler(c, twoWarnings))
+
+	rest, err := snap.Parser(snap.Client()).ParseArgs([]string{"warnings", "--format=json"})
+	c.Assert(err, check.IsNil)
+	c.Check(rest, check.HasLen, 0)
+	c.Check(s.Stderr(), check.Equals, "")
+	c.Check(s.Stdout(), check.Matches, `(?ms)^\[\n  \{\n    "message": "hello world number one",\n.*    "message": "hello world number two",\n.*\]\n$`)
+
+	// the listed warnings can be acknowledged
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		c.Check(r.Method, check.Equals, "POST")
+		fmt.Fprintln(w, `{"type": "sync", "status-code": 200, "status": "OK"}`)
+	})
+	_, err = snap.Parser(snap.Client()).ParseArgs([]string{"okay"})
+	c.Assert(err, check.IsNil)
+}
+
+func (s *warningSuite) TestNoWarningsFormatYAML(c *check.C) {
+	s.RedirectClientToTestServer(mkWarningsFakeHandler(c, `{"type": "sync", "status-code": 200, "result": []}`))
+
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"warnings", "--format=yaml"})
+	c.Assert(err, check.IsNil)
+	c.Check(s.Stderr(), check.Equals, "")
+	c.Check(s.Stdout(), check.Equals, "[]\n")
+}
@@ -0,0 +1,177 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+
+	"github.com/snapcore/snapd/client"
+)
+
+// connectionGraph represents the snaps of the system as nodes and the
+// connections between their plugs and slots as edges.
+type connectionGraph struct {
+	Nodes []*graphNode `json:"nodes"`
+	Edges []*graphEdge `json:"edges"`
+}
+
+type graphNode struct {
+	Snap  string          `json:"snap"`
+	Plugs []graphEndpoint `json:"plugs,omitempty"`
+	Slots []graphEndpoint `json:"slots,omitempty"`
+}
+
+// graphEndpoint is a plug or a slot of a snap.
+type graphEndpoint struct {
+	Name      string `json:"name"`
+	Interface string `json:"interface"`
+	Connected bool   `json:"connected"`
+}
+
+type graphEdge struct {
+	Plug      client.PlugRef `json:"plug"`
+	Slot      client.SlotRef `json:"slot"`
+	Interface string         `json:"interface"`
+	// Label is the interface, qualified with the content tag for
+	// content connections.
+	Label  string `json:"label"`
+	Auto   bool   `json:"auto"`
+	Gadget bool   `json:"gadget,omitempty"`
+}
+
+// newConnectionGraph builds the graph of the given connections, plugs
+// and slots. As in the table output, the unconnected slots of the
+// system snap are left out unless it is the wanted snap.
+func newConnectionGraph(conns *client.Connections, wanted string) *connectionGraph {
+	nodes := make(map[string]*graphNode)
+	node := func(snap string) *graphNode {
+		n := nodes[snap]
+		if n == nil {
+			n = &graphNode{Snap: snap}
+			nodes[snap] = n
+		}
+		return n
+	}
+
+	for _, plug := range conns.Plugs {
+		n := node(plug.Snap)
+		n.Plugs = append(n.Plugs, graphEndpoint{
+			Name:      plug.Name,
+			Interface: plug.Interface,
+			Connected: len(plug.Connections) > 0,
+		})
+	}
+	for _, slot := range conns.Slots {
+		if len(slot.Connections) == 0 && !isSystemSnap(wanted) && isSystemSnap(slot.Snap) {
+			continue
+		}
+		n := node(slot.Snap)
+		n.Slots = append(n.Slots, graphEndpoint{
+			Name:      slot.Name,
+			Interface: slot.Interface,
+			Connected: len(slot.Connections) > 0,
+		})
+	}
+
+	g := &connectionGraph{
+		Nodes: make([]*graphNode, 0, len(nodes)),
+		Edges: make([]*graphEdge, 0, len(conns.Established)),
+	}
+	for i := range conns.Established {
+		conn := &conns.Established[i]
+		// make sure both ends are nodes when filtering by snap
+		node(conn.Plug.Snap)
+		node(conn.Slot.Snap)
+		g.Edges = append(g.Edges, &graphEdge{
+			Plug:      conn.Plug,
+			Slot:      conn.Slot,
+			Interface: conn.Interface,
+			Label:     conn.Interface + interfaceDeterminant(conn),
+			Auto:      !conn.Manual,
+			Gadget:    conn.Gadget,
+		})
+	}
+
+	for _, n := range nodes {
+		sort.Slice(n.Plugs, func(i, j int) bool { return n.Plugs[i].Name < n.Plugs[j].Name })
+		sort.Slice(n.Slots, func(i, j int) bool { return n.Slots[i].Name < n.Slots[j].Name })
+		g.Nodes = append(g.Nodes, n)
+	}
+	sort.Slice(g.Nodes, func(i, j int) bool { return g.Nodes[i].Snap < g.Nodes[j].Snap })
+	sort.Slice(g.Edges, func(i, j int) bool {
+		ei, ej := g.Edges[i], g.Edges[j]
+		if ei.Interface != ej.Interface {
+			return ei.Interface < ej.Interface
+		}
+		if ei.Plug != ej.Plug {
+			return endpoint(ei.Plug.Snap, ei.Plug.Name) < endpoint(ej.Plug.Snap, ej.Plug.Name)
+		}
+		return endpoint(ei.Slot.Snap, ei.Slot.Name) < endpoint(ej.Slot.Snap, ej.Slot.Name)
+	})
+	return g
+}
+
+// writeDot writes the graph in the dot language of graphviz. Edges go
+// from the snap with the plug to the snap with the slot, with the plug
+// and slot names at their ends. Auto-connections are dashed, and
+// unconnected plugs and slots point to or come from a plain text node.
+func (g *connectionGraph) writeDot(w io.Writer) error {
+	q := strconv.Quote
+	fmt.Fprintln(w, "digraph connections {")
+	fmt.Fprintln(w, "\trankdir=LR;")
+	fmt.Fprintln(w, "\tnode [shape=box];")
+	for _, n := range g.Nodes {
+		fmt.Fprintf(w, "\t%s;\n", q(n.Snap))
+	}
+	for _, e := range g.Edges {
+		style := "solid"
+		if e.Auto {
+			style = "dashed"
+		}
+		attrs := fmt.Sprintf("label=%s, taillabel=%s, headlabel=%s, style=%s", q(e.Label), q(e.Plug.Name), q(e.Slot.Name), style)
+		if e.Gadget {
+			attrs += ", color=blue"
+		}
+		fmt.Fprintf(w, "\t%s -> %s [%s];\n", q(e.Plug.Snap), q(e.Slot.Snap), attrs)
+	}
+	for _, n := range g.Nodes {
+		for _, plug := range n.Plugs {
+			if plug.Connected {
+				continue
+			}
+			id := q(n.Snap + ":plug:" + plug.Name)
+			fmt.Fprintf(w, "\t%s [label=%s, shape=plaintext];\n", id, q(plug.Name))
+			fmt.Fprintf(w, "\t%s -> %s [label=%s, style=dotted];\n", q(n.Snap), id, q(plug.Interface))
+		}
+		for _, slot := range n.Slots {
+			if slot.Connected {
+				continue
+			}
+			id := q(n.Snap + ":slot:" + slot.Name)
+			fmt.Fprintf(w, "\t%s [label=%s, shape=plaintext];\n", id, q(slot.Name))
+			fmt.Fprintf(w, "\t%s -> %s [label=%s, style=dotted];\n", id, q(n.Snap), q(slot.Interface))
+		}
+	}
+	_, err := fmt.Fprintln(w, "}")
+	return err
+}
@@ -411,3 +411,11 @@ func MockOsChmod(f func(string, os.FileMode) error) (restore func()) {
 		osChmod = old
 	}
 }
+
+func MockTopSleep(f func(time.Duration)) (restore func()) {
+	old := topSleep
+	topSleep = f
+	return func() {
+		topSleep = old
+	}
+}
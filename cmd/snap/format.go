@@ -0,0 +1,140 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"github.com/jessevdk/go-flags"
+	"gopkg.in/yaml.v2"
+
+	"github.com/snapcore/snapd/i18n"
+)
+
+// formatMixin marks commands that can print their output in a machine
+// readable format, as requested with the global --format option,
+// instead of a table. Other commands refuse the option.
+type formatMixin struct{}
+
+// structuredOutputter is implemented by the commands embedding
+// formatMixin.
+type structuredOutputter interface {
+	structuredOutput() bool
+}
+
+// structuredOutput returns whether output in a machine readable
+// format was requested.
+func (formatMixin) structuredOutput() bool {
+	return optionsData.Format != ""
+}
+
+// printStructured prints v, usually a client type, in the requested
+// format.
+func (formatMixin) printStructured(v interface{}) error {
+	return printStructured(optionsData.Format, v)
+}
+
+// checkFormatSupported fails if the global --format option was given
+// to a command that does not support it.
+func checkFormatSupported(command flags.Commander) error {
+	if optionsData.Format == "" {
+		return nil
+	}
+	if _, ok := command.(structuredOutputter); ok {
+		return nil
+	}
+	return fmt.Errorf(i18n.G("this command does not support --format"))
+}
+
+// structuredCommands holds the commands of the current parser that
+// support the --format option.
+var structuredCommands map[*flags.Command]bool
+
+// showFormatHelp lists the --format option in the help of the active
+// command only when the command supports it.
+func showFormatHelp(parser *flags.Parser) {
+	format := parser.FindOptionByLongName("format")
+	if format == nil {
+		return
+	}
+	cmd := parser.Command
+	for cmd.Active != nil {
+		cmd = cmd.Active
+	}
+	format.Hidden = !structuredCommands[cmd]
+}
+
+// printStructured prints v in the given format. The yaml output uses
+// the same keys as the json one.
+func printStructured(format string, v interface{}) error {
+	switch format {
+	case "json":
+		enc := json.NewEncoder(Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v)
+	case "yaml":
+		generic, err := jsonRoundTrip(v)
+		if err != nil {
+			return err
+		}
+		enc := yaml.NewEncoder(Stdout)
+		defer enc.Close()
+		return enc.Encode(generic)
+	}
+	return fmt.Errorf("internal error: unsupported output format %q", format)
+}
+
+// jsonRoundTrip converts v to the generic representation of its json
+// encoding, keeping integers as such.
+func jsonRoundTrip(v interface{}) (interface{}, error) {
+	buf, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(buf))
+	dec.UseNumber()
+	var generic interface{}
+	if err := dec.Decode(&generic); err != nil {
+		return nil, err
+	}
+	return fromJSONNumbers(generic), nil
+}
+
+func fromJSONNumbers(v interface{}) interface{} {
+	switch v := v.(type) {
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i
+		}
+		f, _ := v.Float64()
+		return f
+	case map[string]interface{}:
+		for k, e := range v {
+			v[k] = fromJSONNumbers(e)
+		}
+	case []interface{}:
+		for i, e := range v {
+			v[i] = fromJSONNumbers(e)
+		}
+	}
+	return v
+}
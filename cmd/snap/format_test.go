@@ -0,0 +1,81 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package main_test
+
+import (
+	"bytes"
+	"net/http"
+
+	"github.com/jessevdk/go-flags"
+	"gopkg.in/check.v1"
+
+	snap "github.com/snapcore/snapd/cmd/snap"
+)
+
+func (s *SnapSuite) TestFormatUnsupportedCommand(c *check.C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		c.Fatalf("unexpected request to %s", r.URL.Path)
+	})
+	for _, args := range [][]string{
+		{"okay", "--format=json"},
+		{"--format=yaml", "okay"},
+		{"debug", "state", "--format=json", "state.json"},
+	} {
+		_, err := snap.Parser(snap.Client()).ParseArgs(args)
+		c.Check(err, check.ErrorMatches, "this command does not support --format", check.Commentf("%v", args))
+	}
+}
+
+func (s *SnapSuite) TestFormatGlobalPosition(c *check.C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		c.Check(r.URL.Path, check.Equals, "/v2/snaps")
+		w.Write([]byte(`{"type": "sync", "result": []}`))
+	})
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"--format=json", "list"})
+	c.Assert(err, check.IsNil)
+	c.Check(s.Stdout(), check.Equals, "[]\n")
+}
+
+func (s *SnapSuite) TestFormatHelpOnlyForSupportingCommands(c *check.C) {
+	for _, tc := range []struct {
+		args   []string
+		listed bool
+	}{
+		{[]string{"warnings", "--help"}, true},
+		{[]string{"help", "warnings"}, true},
+		{[]string{"debug", "denials", "--help"}, true},
+		{[]string{"help", "debug", "denials"}, true},
+		{[]string{"okay", "--help"}, false},
+		{[]string{"help", "okay"}, false},
+		// connections lists its own --format option
+		{[]string{"connections", "--help"}, true},
+	} {
+		parser := snap.Parser(snap.Client())
+		_, err := parser.ParseArgs(tc.args)
+		c.Assert(err, check.DeepEquals, &flags.Error{Type: flags.ErrHelp})
+		var buf bytes.Buffer
+		parser.WriteHelp(&buf)
+		expected := 0
+		if tc.listed {
+			expected = 1
+		}
+		c.Check(bytes.Count(buf.Bytes(), []byte("  --format=[")), check.Equals, expected, check.Commentf("%v", tc.args))
+	}
+}
@@ -73,6 +73,7 @@ var (
 
 type options struct {
 	Version func() `long:"version"`
+	Format  string `long:"format" choice:"json" choice:"yaml"`
 }
 
 type argDesc struct {
@@ -268,6 +269,11 @@ func registerCommands(cli *client.Client, parser *flags.Parser, baseCmd *flags.C
 			logger.Panicf("cannot add command %q: %v", c.name, err)
 		}
 		cmd.Hidden = c.hidden
+		// commands with a --format option of their own list
+		// that one in their help instead
+		if _, ok := obj.(structuredOutputter); ok && cmd.Group.FindOptionByLongName("format") == nil {
+			structuredCommands[cmd] = true
+		}
 		if c.alias != "" {
 			cmd.Aliases = append(cmd.Aliases, c.alias)
 		}
@@ -324,8 +330,19 @@ func Parser(cli *client.Client) *flags.Parser {
 	if firstNonOptionIsRun() {
 		flagopts |= flags.PassAfterNonOption
 	}
+	optionsData.Format = ""
+	structuredCommands = make(map[*flags.Command]bool)
 	parser := flags.NewParser(&optionsData, flagopts)
 	parser.CompletionHandler = completionHandler
+	parser.CommandHandler = func(command flags.Commander, args []string) error {
+		if command == nil {
+			return nil
+		}
+		if err := checkFormatSupported(command); err != nil {
+			return err
+		}
+		return command.Execute(args)
+	}
 	parser.ShortDescription = i18n.G("Tool to interact with snaps")
 	parser.LongDescription = longSnapDescription
 	// hide the unhelpful "[OPTIONS]" from help output
@@ -334,6 +351,12 @@ func Parser(cli *client.Client) *flags.Parser {
 		version.Description = i18n.G("Print the version and exit")
 		version.Hidden = true
 	}
+	if format := parser.FindOptionByLongName("format"); format != nil {
+		// TRANSLATORS: This should not start with a lowercase letter.
+		format.Description = i18n.G("Print the output as json or yaml instead of a table")
+		// only listed in the help of the commands supporting it
+		format.Hidden = true
+	}
 	// add --help like what go-flags would do for us, but hidden
 	addHelp(parser)
 
@@ -68,6 +68,28 @@ type accessChecker interface {
 	CheckAccess(d *Daemon, r *http.Request, ucred *ucrednet, user *auth.UserState) *apiError
 }
 
+// remoteAccessChecker is implemented by access checkers that allow
+// requests from clients of the remote management listener. Those
+// clients were already authenticated by their TLS certificate.
+type remoteAccessChecker interface {
+	CheckRemoteAccess(d *Daemon, r *http.Request, rc *remoteClient) *apiError
+}
+
+// checkRemoteAccess checks a request received via the remote
+// management listener. Access checkers not implementing
+// remoteAccessChecker deny all remote requests.
+func checkRemoteAccess(ac accessChecker, d *Daemon, r *http.Request, rc *remoteClient) *apiError {
+	if rac, ok := ac.(remoteAccessChecker); ok {
+		return rac.CheckRemoteAccess(d, r, rc)
+	}
+	return Forbidden("access denied")
+}
+
+// isReadRequest returns whether the request only reads.
+func isReadRequest(r *http.Request) bool {
+	return r.Method == "GET" || r.Method == "HEAD"
+}
+
 // requireSnapdSocket ensures the request was received via snapd.socket.
 func requireSnapdSocket(ucred *ucrednet) *apiError {
 	if ucred == nil {
@@ -89,14 +111,26 @@ func (ac openAccess) CheckAccess(d *Daemon, r *http.Request, ucred *ucrednet, us
 	return requireSnapdSocket(ucred)
 }
 
+// CheckRemoteAccess allows remote clients to read.
+func (ac openAccess) CheckRemoteAccess(d *Daemon, r *http.Request, rc *remoteClient) *apiError {
+	if isReadRequest(r) {
+		return nil
+	}
+	return Forbidden("access denied")
+}
+
 // authenticatedAccess allows requests from authenticated users,
 // provided they were not received on snapd-snap.socket
 //
 // A user is considered authenticated if they provide a macaroon, are
-// the root user according to peer credentials, or granted access by
-// Polkit.
+// the root user according to peer credentials, are granted the
+// requested operations by the local access policy, or granted access
+// by Polkit.
 type authenticatedAccess struct {
 	Polkit string
+	// Policy, if set, enables checking the request against the local
+	// access policy file.
+	Policy accessPolicyScope
 }
 
 func (ac authenticatedAccess) CheckAccess(d *Daemon, r *http.Request, ucred *ucrednet, user *auth.UserState) *apiError {
@@ -112,16 +146,50 @@ func (ac authenticatedAccess) CheckAccess(d *Daemon, r *http.Request, ucred *ucr
 		return nil
 	}
 
+	var policyErr *apiError
+	if ac.Policy != "" {
+		policyErr = checkAccessPolicy(d, r, ucred, ac.Policy)
+		if policyErr == nil {
+			return nil
+		}
+		if policyErr.Kind != client.ErrorKindAccessPolicyDenied {
+			// the policy does not apply to the user
+			policyErr = nil
+		}
+	}
+
 	// We check polkit last because it may result in the user
 	// being prompted for authorisation. This should be avoided if
 	// access is otherwise granted.
 	if ac.Polkit != "" {
-		return checkPolkitAction(r, ucred, ac.Polkit)
+		rspe := checkPolkitAction(r, ucred, ac.Polkit)
+		if rspe == nil || policyErr == nil || rspe.Kind == client.ErrorKindAuthCancelled {
+			return rspe
+		}
+	}
+
+	// report why the policy denied the request
+	if policyErr != nil {
+		return policyErr
 	}
 
 	return Unauthorized("access denied")
 }
 
+// CheckRemoteAccess allows remote clients, which are authenticated by
+// their certificate, to read. Requests checked against the access
+// policy must be allowed for the client by the policy, all other
+// requests changing the system are denied.
+func (ac authenticatedAccess) CheckRemoteAccess(d *Daemon, r *http.Request, rc *remoteClient) *apiError {
+	if ac.Policy != "" {
+		return checkRemoteAccessPolicy(d, r, rc, ac.Policy)
+	}
+	if isReadRequest(r) {
+		return nil
+	}
+	return Forbidden("access denied")
+}
+
 // rootAccess allows requests from the root uid, provided they
 // were not received on snapd-snap.socket
 type rootAccess struct{}
@@ -0,0 +1,726 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package daemon
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"os"
+	"os/user"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/logger"
+	"github.com/snapcore/snapd/overlord/snapstate"
+	"github.com/snapcore/snapd/snap/channel"
+	"github.com/snapcore/snapd/strutil"
+)
+
+// accessPolicyScope identifies how the operations performed by a
+// request are extracted so that they can be checked against the local
+// access policy.
+type accessPolicyScope string
+
+const (
+	// accessPolicyScopeSnap covers single snap operations via
+	// /v2/snaps/{name}.
+	accessPolicyScopeSnap accessPolicyScope = "snap"
+	// accessPolicyScopeSnaps covers multi-snap operations via
+	// /v2/snaps.
+	accessPolicyScopeSnaps accessPolicyScope = "snaps"
+	// accessPolicyScopeApps covers service operations via /v2/apps.
+	accessPolicyScopeApps accessPolicyScope = "apps"
+	// accessPolicyScopeLogs covers reading service logs via /v2/logs.
+	accessPolicyScopeLogs accessPolicyScope = "logs"
+	// accessPolicyScopeBatch covers the operations of a batch via
+	// /v2/batch.
+	accessPolicyScopeBatch accessPolicyScope = "batch"
+)
+
+// operations that can be granted by the access policy
+var (
+	accessPolicySnapOperations    = []string{"install", "refresh", "remove", "revert", "enable", "disable", "switch"}
+	accessPolicyServiceOperations = []string{"start", "stop", "restart"}
+	accessPolicyLogsOperation     = "logs"
+	// operations for which the grant can be restricted by channel
+	accessPolicyChannelOperations = []string{"install", "refresh", "switch"}
+)
+
+// accessPolicyField describes how a field of a request body is
+// treated when checking the request against the access policy.
+type accessPolicyField int
+
+const (
+	// accessPolicyFieldHandled fields select the operation, the snaps
+	// and the channel, and are checked by the scope itself.
+	accessPolicyFieldHandled accessPolicyField = iota
+	// accessPolicyFieldHarmless fields do not change what the
+	// operation is allowed to do.
+	accessPolicyFieldHarmless
+	// accessPolicyFieldOption fields raise the privilege of the
+	// operation and must be granted explicitly as options.
+	accessPolicyFieldOption
+)
+
+// fields of the snap operations, any field not listed is denied
+var accessPolicySnapFields = map[string]accessPolicyField{
+	"action":            accessPolicyFieldHandled,
+	"channel":           accessPolicyFieldHandled,
+	"snaps":             accessPolicyFieldHandled,
+	"unaliased":         accessPolicyFieldHarmless,
+	"leave-cohort":      accessPolicyFieldHarmless,
+	"amend":             accessPolicyFieldOption,
+	"classic":           accessPolicyFieldOption,
+	"cohort-key":        accessPolicyFieldOption,
+	"devmode":           accessPolicyFieldOption,
+	"ignore-running":    accessPolicyFieldOption,
+	"ignore-validation": accessPolicyFieldOption,
+	"jailmode":          accessPolicyFieldOption,
+	"purge":             accessPolicyFieldOption,
+	"revision":          accessPolicyFieldOption,
+	"users":             accessPolicyFieldOption,
+}
+
+// fields of the service operations, any field not listed is denied
+var accessPolicyServiceFields = map[string]accessPolicyField{
+	"action":  accessPolicyFieldHandled,
+	"names":   accessPolicyFieldHandled,
+	"reload":  accessPolicyFieldHarmless,
+	"enable":  accessPolicyFieldOption,
+	"disable": accessPolicyFieldOption,
+	"users":   accessPolicyFieldOption,
+}
+
+// fields of the operations of a batch, any field not listed is denied;
+// connect, disconnect and set operations are never granted so their
+// fields need no checking
+var accessPolicyBatchFields = map[string]accessPolicyField{
+	"action":       accessPolicyFieldHandled,
+	"channel":      accessPolicyFieldHandled,
+	"snap":         accessPolicyFieldHandled,
+	"plug":         accessPolicyFieldHandled,
+	"slot":         accessPolicyFieldHandled,
+	"values":       accessPolicyFieldHandled,
+	"leave-cohort": accessPolicyFieldHarmless,
+	"classic":      accessPolicyFieldOption,
+	"cohort-key":   accessPolicyFieldOption,
+	"devmode":      accessPolicyFieldOption,
+	"jailmode":     accessPolicyFieldOption,
+	"purge":        accessPolicyFieldOption,
+	"revision":     accessPolicyFieldOption,
+}
+
+func accessPolicyOptionsOf(fields map[string]accessPolicyField) []string {
+	var options []string
+	for name, kind := range fields {
+		if kind == accessPolicyFieldOption {
+			options = append(options, name)
+		}
+	}
+	sort.Strings(options)
+	return options
+}
+
+// accessPolicy grants local users and groups, and clients of the
+// remote management listener, access to specific API operations
+// without requiring them to be root.
+//
+// An example policy file looks like:
+//
+//   rules:
+//     - groups: [operators]
+//       allow:
+//         - operation: restart
+//           snaps: [my-app]
+//         - operation: install
+//           snaps: [my-app]
+//           channels: [stable, candidate]
+//         - operation: refresh
+//           snaps: [my-app]
+//           options: [revision]
+//         - operation: logs
+//           snaps: [my-app]
+//     - clients: [9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08]
+//       allow:
+//         - operation: refresh
+//           snaps: [my-app]
+type accessPolicy struct {
+	Rules []*accessPolicyRule `yaml:"rules"`
+}
+
+// accessPolicyRule grants the operations in Allow to the listed users,
+// members of the listed groups and remote clients identified by the
+// SHA256 fingerprint of their certificate.
+type accessPolicyRule struct {
+	Users   []string             `yaml:"users,omitempty"`
+	Groups  []string             `yaml:"groups,omitempty"`
+	Clients []string             `yaml:"clients,omitempty"`
+	Allow   []*accessPolicyGrant `yaml:"allow"`
+}
+
+// accessPolicyGrant allows one operation, optionally restricted to
+// some snaps (or snap.app services) and channels. Options that raise
+// the privilege of the operation, like installing a classic snap or a
+// specific revision, are only allowed when listed in Options.
+type accessPolicyGrant struct {
+	Operation string   `yaml:"operation"`
+	Snaps     []string `yaml:"snaps,omitempty"`
+	Channels  []string `yaml:"channels,omitempty"`
+	Options   []string `yaml:"options,omitempty"`
+}
+
+// accessPolicyOperation is a single operation performed by a request.
+type accessPolicyOperation struct {
+	Operation string
+	// Snap is the snap or snap.app the operation applies to, empty
+	// means all snaps.
+	Snap string
+	// Channel is the channel requested for install, refresh and
+	// switch operations.
+	Channel string
+	// Options are the privilege raising options of the request.
+	Options []string
+}
+
+func (op *accessPolicyOperation) String() string {
+	what := "all snaps"
+	if op.Snap != "" {
+		what = strconv.Quote(op.Snap)
+	}
+	var desc string
+	switch {
+	case op.Operation == accessPolicyLogsOperation:
+		desc = fmt.Sprintf("read logs of %s", what)
+	case op.Channel != "":
+		desc = fmt.Sprintf("%s %s from channel %q", op.Operation, what, op.Channel)
+	default:
+		desc = fmt.Sprintf("%s %s", op.Operation, what)
+	}
+	if len(op.Options) > 0 {
+		desc += " with " + strutil.Quoted(op.Options)
+	}
+	return desc
+}
+
+func (g *accessPolicyGrant) validate() error {
+	op := g.Operation
+	if !strutil.ListContains(accessPolicySnapOperations, op) && !strutil.ListContains(accessPolicyServiceOperations, op) && op != accessPolicyLogsOperation {
+		return fmt.Errorf("unknown operation %q", op)
+	}
+	var options []string
+	switch {
+	case strutil.ListContains(accessPolicySnapOperations, op):
+		options = accessPolicyOptionsOf(accessPolicySnapFields)
+	case strutil.ListContains(accessPolicyServiceOperations, op):
+		options = accessPolicyOptionsOf(accessPolicyServiceFields)
+	}
+	for _, opt := range g.Options {
+		if !strutil.ListContains(options, opt) {
+			return fmt.Errorf("operation %q has no option %q", op, opt)
+		}
+	}
+	if len(g.Channels) > 0 {
+		if !strutil.ListContains(accessPolicyChannelOperations, op) {
+			return fmt.Errorf("operation %q cannot be restricted by channel", op)
+		}
+		for _, ch := range g.Channels {
+			if _, err := channel.Full(ch); err != nil {
+				return fmt.Errorf("invalid channel %q: %v", ch, err)
+			}
+		}
+	}
+	return nil
+}
+
+func (p *accessPolicy) validate() error {
+	for i, rule := range p.Rules {
+		if len(rule.Users) == 0 && len(rule.Groups) == 0 && len(rule.Clients) == 0 {
+			return fmt.Errorf("rule %d does not apply to any user, group or client", i+1)
+		}
+		for j, fp := range rule.Clients {
+			norm, err := strutil.NormalizeCertificateFingerprint(fp)
+			if err != nil {
+				return fmt.Errorf("rule %d: %v", i+1, err)
+			}
+			rule.Clients[j] = norm
+		}
+		for _, g := range rule.Allow {
+			if err := g.validate(); err != nil {
+				return fmt.Errorf("rule %d: %v", i+1, err)
+			}
+		}
+	}
+	return nil
+}
+
+// readAccessPolicy reads the local access policy file. A missing
+// file results in an empty policy.
+func readAccessPolicy() (*accessPolicy, error) {
+	data, err := ioutil.ReadFile(dirs.SnapdAccessPolicyFile)
+	if os.IsNotExist(err) {
+		return &accessPolicy{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var policy accessPolicy
+	if err := yaml.UnmarshalStrict(data, &policy); err != nil {
+		return nil, fmt.Errorf("cannot parse access policy: %v", err)
+	}
+	if err := policy.validate(); err != nil {
+		return nil, fmt.Errorf("invalid access policy: %v", err)
+	}
+	return &policy, nil
+}
+
+var accessPolicyUserLookup = accessPolicyUserLookupImpl
+
+// accessPolicyUserLookupImpl returns the name and the names of the
+// groups of the user with the given uid.
+func accessPolicyUserLookupImpl(uid uint32) (username string, groups []string, err error) {
+	u, err := user.LookupId(strconv.FormatUint(uint64(uid), 10))
+	if err != nil {
+		return "", nil, err
+	}
+	gids, err := u.GroupIds()
+	if err != nil {
+		return "", nil, err
+	}
+	groups = make([]string, 0, len(gids))
+	for _, gid := range gids {
+		g, err := user.LookupGroupId(gid)
+		if err != nil {
+			logger.Debugf("cannot lookup group %s of user %q: %v", gid, u.Username, err)
+			continue
+		}
+		groups = append(groups, g.Name)
+	}
+	return u.Username, groups, nil
+}
+
+// rulesForClient returns the rules that apply to the remote client
+// with the given certificate fingerprint.
+func (p *accessPolicy) rulesForClient(fingerprint string) []*accessPolicyRule {
+	var rules []*accessPolicyRule
+	for _, rule := range p.Rules {
+		if strutil.ListContains(rule.Clients, fingerprint) {
+			rules = append(rules, rule)
+		}
+	}
+	return rules
+}
+
+// rulesFor returns the rules that apply to the given user.
+func (p *accessPolicy) rulesFor(username string, groups []string) []*accessPolicyRule {
+	var rules []*accessPolicyRule
+	for _, rule := range p.Rules {
+		if strutil.ListContains(rule.Users, username) {
+			rules = append(rules, rule)
+			continue
+		}
+		for _, group := range groups {
+			if strutil.ListContains(rule.Groups, group) {
+				rules = append(rules, rule)
+				break
+			}
+		}
+	}
+	return rules
+}
+
+func snapsMatch(grantSnaps []string, snapOrApp string) bool {
+	if len(grantSnaps) == 0 {
+		return true
+	}
+	if snapOrApp == "" {
+		// operation on all snaps needs an unrestricted grant
+		return false
+	}
+	if strutil.ListContains(grantSnaps, snapOrApp) {
+		return true
+	}
+	// a grant for a snap covers all of its apps
+	if idx := strings.IndexByte(snapOrApp, '.'); idx > 0 {
+		return strutil.ListContains(grantSnaps, snapOrApp[:idx])
+	}
+	return false
+}
+
+func channelsMatch(grantChannels []string, snapOrApp, requested string) bool {
+	if len(grantChannels) == 0 {
+		return true
+	}
+	if snapOrApp == "" {
+		// the channels of all snaps cannot be checked
+		return false
+	}
+	if requested == "" {
+		requested = "stable"
+	}
+	full, err := channel.Full(requested)
+	if err != nil {
+		return false
+	}
+	for _, ch := range grantChannels {
+		if grantFull, err := channel.Full(ch); err == nil && grantFull == full {
+			return true
+		}
+	}
+	return false
+}
+
+func (g *accessPolicyGrant) allows(op *accessPolicyOperation) bool {
+	if g.Operation != op.Operation {
+		return false
+	}
+	if !snapsMatch(g.Snaps, op.Snap) {
+		return false
+	}
+	if strutil.ListContains(accessPolicyChannelOperations, op.Operation) && !channelsMatch(g.Channels, op.Snap, op.Channel) {
+		return false
+	}
+	for _, opt := range op.Options {
+		if !strutil.ListContains(g.Options, opt) {
+			return false
+		}
+	}
+	return true
+}
+
+func rulesAllow(rules []*accessPolicyRule, op *accessPolicyOperation) bool {
+	for _, rule := range rules {
+		for _, g := range rule.Allow {
+			if g.allows(op) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// maxAccessPolicyBodySize is the size limit of the request bodies
+// that are read to check them against the access policy.
+const maxAccessPolicyBodySize = 1024 * 1024
+
+// peekRequestBody reads the full body of the request and replaces it
+// so that it can be read again by the actual handler.
+func peekRequestBody(r *http.Request) ([]byte, error) {
+	if r.Body == nil {
+		return nil, nil
+	}
+	body, err := ioutil.ReadAll(http.MaxBytesReader(nil, r.Body, maxAccessPolicyBodySize))
+	r.Body.Close()
+	r.Body = ioutil.NopCloser(bytes.NewReader(body))
+	return body, err
+}
+
+// decodeRequestFields decodes the JSON object in the body of the
+// request into its fields, and returns the names of the privilege
+// raising options that are set. A field that is not known is an
+// error.
+func decodeRequestFields(r *http.Request, known map[string]accessPolicyField) (fields map[string]json.RawMessage, options []string, err error) {
+	body, err := peekRequestBody(r)
+	if err != nil {
+		return nil, nil, err
+	}
+	return decodeFields(body, known)
+}
+
+// decodeFields decodes the given JSON object like decodeRequestFields.
+func decodeFields(data []byte, known map[string]accessPolicyField) (fields map[string]json.RawMessage, options []string, err error) {
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, nil, err
+	}
+	for name, value := range fields {
+		kind, ok := known[name]
+		if !ok {
+			return nil, nil, fmt.Errorf("unknown field %q", name)
+		}
+		if kind == accessPolicyFieldOption && !isZeroJSONValue(value) {
+			options = append(options, name)
+		}
+	}
+	sort.Strings(options)
+	return fields, options, nil
+}
+
+func isZeroJSONValue(value json.RawMessage) bool {
+	switch string(bytes.TrimSpace(value)) {
+	case "", "null", "false", `""`, "0", "[]", "{}":
+		return true
+	}
+	return false
+}
+
+// decodeField decodes the given field, if present, into v.
+func decodeField(fields map[string]json.RawMessage, name string, v interface{}) error {
+	value, ok := fields[name]
+	if !ok {
+		return nil
+	}
+	if err := json.Unmarshal(value, v); err != nil {
+		return fmt.Errorf("cannot decode field %q: %v", name, err)
+	}
+	return nil
+}
+
+var accessPolicyTrackingChannel = accessPolicyTrackingChannelImpl
+
+// accessPolicyTrackingChannelImpl returns the channel tracked by the
+// given snap, which is what a refresh or a switch without a channel
+// uses, or an empty string if the snap is not installed.
+func accessPolicyTrackingChannelImpl(d *Daemon, snapName string) string {
+	if d == nil {
+		return ""
+	}
+	st := d.overlord.State()
+	st.Lock()
+	defer st.Unlock()
+	var snapst snapstate.SnapState
+	if err := snapstate.Get(st, snapName, &snapst); err != nil {
+		return ""
+	}
+	return snapst.TrackingChannel
+}
+
+// requestedChannel returns the channel the operation on the given snap
+// uses.
+func requestedChannel(d *Daemon, action, snapName, channel string) string {
+	if channel == "" && snapName != "" && (action == "refresh" || action == "switch") {
+		channel = accessPolicyTrackingChannel(d, snapName)
+	}
+	return channel
+}
+
+// operationsFor returns the operations the request would perform
+// according to the given scope.
+func (scope accessPolicyScope) operationsFor(d *Daemon, r *http.Request) ([]*accessPolicyOperation, error) {
+	switch scope {
+	case accessPolicyScopeSnap:
+		fields, options, err := decodeRequestFields(r, accessPolicySnapFields)
+		if err != nil {
+			return nil, err
+		}
+		var action, channel string
+		if err := decodeField(fields, "action", &action); err != nil {
+			return nil, err
+		}
+		if err := decodeField(fields, "channel", &channel); err != nil {
+			return nil, err
+		}
+		name := muxVars(r)["name"]
+		return []*accessPolicyOperation{{
+			Operation: action,
+			Snap:      name,
+			Channel:   requestedChannel(d, action, name, channel),
+			Options:   options,
+		}}, nil
+	case accessPolicyScopeSnaps:
+		mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		if err != nil || mediaType != "application/json" {
+			// sideloading is never granted by the policy
+			return nil, fmt.Errorf("unsupported content type")
+		}
+		fields, options, err := decodeRequestFields(r, accessPolicySnapFields)
+		if err != nil {
+			return nil, err
+		}
+		var action, channel string
+		var snaps []string
+		if err := decodeField(fields, "action", &action); err != nil {
+			return nil, err
+		}
+		if err := decodeField(fields, "channel", &channel); err != nil {
+			return nil, err
+		}
+		if err := decodeField(fields, "snaps", &snaps); err != nil {
+			return nil, err
+		}
+		if len(snaps) == 0 {
+			return []*accessPolicyOperation{{Operation: action, Channel: channel, Options: options}}, nil
+		}
+		ops := make([]*accessPolicyOperation, len(snaps))
+		for i, name := range snaps {
+			ops[i] = &accessPolicyOperation{
+				Operation: action,
+				Snap:      name,
+				Channel:   requestedChannel(d, action, name, channel),
+				Options:   options,
+			}
+		}
+		return ops, nil
+	case accessPolicyScopeApps:
+		fields, options, err := decodeRequestFields(r, accessPolicyServiceFields)
+		if err != nil {
+			return nil, err
+		}
+		var action string
+		var names []string
+		if err := decodeField(fields, "action", &action); err != nil {
+			return nil, err
+		}
+		if err := decodeField(fields, "names", &names); err != nil {
+			return nil, err
+		}
+		if len(names) == 0 {
+			return nil, fmt.Errorf("no services specified")
+		}
+		ops := make([]*accessPolicyOperation, len(names))
+		for i, name := range names {
+			ops[i] = &accessPolicyOperation{Operation: action, Snap: name, Options: options}
+		}
+		return ops, nil
+	case accessPolicyScopeBatch:
+		body, err := peekRequestBody(r)
+		if err != nil {
+			return nil, err
+		}
+		var batch struct {
+			Operations []json.RawMessage `json:"operations"`
+		}
+		if err := json.Unmarshal(body, &batch); err != nil {
+			return nil, err
+		}
+		if len(batch.Operations) == 0 {
+			return nil, fmt.Errorf("no operations specified")
+		}
+		ops := make([]*accessPolicyOperation, len(batch.Operations))
+		for i, data := range batch.Operations {
+			fields, options, err := decodeFields(data, accessPolicyBatchFields)
+			if err != nil {
+				return nil, err
+			}
+			var action, snapName, channel string
+			if err := decodeField(fields, "action", &action); err != nil {
+				return nil, err
+			}
+			if err := decodeField(fields, "snap", &snapName); err != nil {
+				return nil, err
+			}
+			if err := decodeField(fields, "channel", &channel); err != nil {
+				return nil, err
+			}
+			if !strutil.ListContains(accessPolicySnapOperations, action) {
+				// connecting, disconnecting and configuring are not
+				// operations the policy can grant
+				ops[i] = &accessPolicyOperation{Operation: action, Snap: snapName}
+				continue
+			}
+			if snapName == "" {
+				return nil, fmt.Errorf("snap name not specified")
+			}
+			ops[i] = &accessPolicyOperation{
+				Operation: action,
+				Snap:      snapName,
+				Channel:   requestedChannel(d, action, snapName, channel),
+				Options:   options,
+			}
+		}
+		return ops, nil
+	case accessPolicyScopeLogs:
+		names := strutil.CommaSeparatedList(r.URL.Query().Get("names"))
+		if len(names) == 0 {
+			return []*accessPolicyOperation{{Operation: accessPolicyLogsOperation}}, nil
+		}
+		ops := make([]*accessPolicyOperation, len(names))
+		for i, name := range names {
+			ops[i] = &accessPolicyOperation{Operation: accessPolicyLogsOperation, Snap: name}
+		}
+		return ops, nil
+	}
+	return nil, fmt.Errorf("internal error: unknown access policy scope %q", scope)
+}
+
+// checkAccessPolicy checks whether the local access policy allows the
+// user identified by ucred to perform the operations of the request.
+//
+// It returns nil if the request is allowed. If the policy has rules
+// for the user, but none of them allows the request, an error
+// explaining which operation was denied is returned. Otherwise
+// Unauthorized is returned so that the caller can fall back to other
+// means of authorization.
+func checkAccessPolicy(d *Daemon, r *http.Request, ucred *ucrednet, scope accessPolicyScope) *apiError {
+	policy, err := readAccessPolicy()
+	if err != nil {
+		logger.Noticef("cannot use access policy: %v", err)
+		return Unauthorized("access denied")
+	}
+	if len(policy.Rules) == 0 {
+		return Unauthorized("access denied")
+	}
+
+	username, groups, err := accessPolicyUserLookup(ucred.Uid)
+	if err != nil {
+		logger.Noticef("cannot lookup user %d for access policy: %v", ucred.Uid, err)
+		return Unauthorized("access denied")
+	}
+	rules := policy.rulesFor(username, groups)
+	if len(rules) == 0 {
+		return Unauthorized("access denied")
+	}
+
+	return checkOperationsAllowed(d, r, scope, rules, func(op *accessPolicyOperation) *apiError {
+		return AccessPolicyDenied(username, op)
+	})
+}
+
+// checkOperationsAllowed checks that the rules allow all the
+// operations of the request, otherwise the error built by denied for
+// the first operation not allowed is returned.
+func checkOperationsAllowed(d *Daemon, r *http.Request, scope accessPolicyScope, rules []*accessPolicyRule, denied func(op *accessPolicyOperation) *apiError) *apiError {
+	ops, err := scope.operationsFor(d, r)
+	if err != nil {
+		logger.Debugf("cannot determine operations for access policy: %v", err)
+		return denied(nil)
+	}
+	for _, op := range ops {
+		if !rulesAllow(rules, op) {
+			return denied(op)
+		}
+	}
+	return nil
+}
+
+// checkRemoteAccessPolicy checks whether the access policy allows the
+// remote client to perform the operations of the request. Unlike for
+// local users, there is no other means of authorization to fall back
+// to, so the request is denied unless the policy allows it.
+func checkRemoteAccessPolicy(d *Daemon, r *http.Request, rc *remoteClient, scope accessPolicyScope) *apiError {
+	policy, err := readAccessPolicy()
+	if err != nil {
+		logger.Noticef("cannot use access policy: %v", err)
+		return Forbidden("access denied")
+	}
+	rules := policy.rulesForClient(rc.Fingerprint)
+	if len(rules) == 0 {
+		return Forbidden("access denied")
+	}
+	return checkOperationsAllowed(d, r, scope, rules, func(op *accessPolicyOperation) *apiError {
+		return RemoteAccessPolicyDenied(rc.Fingerprint, op)
+	})
+}
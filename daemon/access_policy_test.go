@@ -0,0 +1,426 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package daemon_test
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/client"
+	"github.com/snapcore/snapd/daemon"
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/logger"
+	"github.com/snapcore/snapd/testutil"
+)
+
+type accessPolicySuite struct {
+	testutil.BaseTest
+}
+
+var _ = Suite(&accessPolicySuite{})
+
+const testAccessPolicy = `
+rules:
+  - groups: [operators]
+    allow:
+      - operation: restart
+        snaps: [foo, bar.svc]
+      - operation: install
+        snaps: [foo]
+        channels: [stable, candidate]
+      - operation: logs
+        snaps: [foo]
+  - users: [admin]
+    allow:
+      - operation: refresh
+`
+
+func (s *accessPolicySuite) SetUpTest(c *C) {
+	s.BaseTest.SetUpTest(c)
+
+	dirs.SetRootDir(c.MkDir())
+	s.AddCleanup(func() { dirs.SetRootDir("") })
+
+	s.AddCleanup(daemon.MockAccessPolicyUserLookup(func(uid uint32) (string, []string, error) {
+		switch uid {
+		case 1000:
+			return "alice", []string{"alice", "operators"}, nil
+		case 1001:
+			return "admin", []string{"admin"}, nil
+		}
+		return "bob", []string{"bob"}, nil
+	}))
+	s.AddCleanup(daemon.MockCheckPolkitAction(func(r *http.Request, ucred *daemon.Ucrednet, action string) *daemon.APIError {
+		return daemon.Unauthorized("access denied")
+	}))
+	s.AddCleanup(daemon.MockMuxVars(func(r *http.Request) map[string]string {
+		return map[string]string{"name": strings.TrimPrefix(r.URL.Path, "/v2/snaps/")}
+	}))
+}
+
+func (s *accessPolicySuite) writePolicy(c *C, policy string) {
+	c.Assert(os.MkdirAll(filepath.Dir(dirs.SnapdAccessPolicyFile), 0755), IsNil)
+	c.Assert(ioutil.WriteFile(dirs.SnapdAccessPolicyFile, []byte(policy), 0644), IsNil)
+}
+
+func (s *accessPolicySuite) checkAccess(scope daemon.AccessPolicyScope, uid uint32, req *http.Request) *daemon.APIError {
+	ac := daemon.AuthenticatedAccess{Polkit: "action-id", Policy: scope}
+	ucred := &daemon.Ucrednet{Uid: uid, Pid: 100, Socket: dirs.SnapdSocket}
+	return ac.CheckAccess(nil, req, ucred, nil)
+}
+
+func jsonRequest(method, url, body string) *http.Request {
+	req := httptest.NewRequest(method, url, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	return req
+}
+
+func (s *accessPolicySuite) TestNoPolicyFile(c *C) {
+	req := jsonRequest("POST", "/v2/apps", `{"action": "restart", "names": ["foo"]}`)
+	c.Check(s.checkAccess(daemon.AccessPolicyScopeApps, 1000, req), DeepEquals, errUnauthorized)
+}
+
+func (s *accessPolicySuite) TestServices(c *C) {
+	s.writePolicy(c, testAccessPolicy)
+
+	for _, t := range []struct {
+		body string
+		err  string
+	}{
+		{`{"action": "restart", "names": ["foo"]}`, ""},
+		{`{"action": "restart", "names": ["foo.svc", "bar.svc"]}`, ""},
+		{`{"action": "restart", "names": ["bar"]}`, `access policy does not allow user "alice" to restart "bar"`},
+		{`{"action": "restart", "names": ["foo", "bar.other"]}`, `access policy does not allow user "alice" to restart "bar.other"`},
+		{`{"action": "stop", "names": ["foo"]}`, `access policy does not allow user "alice" to stop "foo"`},
+		{`{"action": "restart"}`, `access policy does not allow user "alice" to perform this request`},
+	} {
+		req := jsonRequest("POST", "/v2/apps", t.body)
+		rspe := s.checkAccess(daemon.AccessPolicyScopeApps, 1000, req)
+		if t.err == "" {
+			c.Check(rspe, IsNil, Commentf(t.body))
+			// the body can still be read by the handler
+			body, err := ioutil.ReadAll(req.Body)
+			c.Assert(err, IsNil)
+			c.Check(string(body), Equals, t.body)
+			continue
+		}
+		c.Assert(rspe, NotNil, Commentf(t.body))
+		c.Check(rspe.Status, Equals, 403)
+		c.Check(rspe.Kind, Equals, client.ErrorKindAccessPolicyDenied)
+		c.Check(rspe.Message, Equals, t.err)
+	}
+}
+
+func (s *accessPolicySuite) TestInstallChannels(c *C) {
+	s.writePolicy(c, testAccessPolicy)
+
+	for _, t := range []struct {
+		url, body string
+		err       string
+	}{
+		{"/v2/snaps/foo", `{"action": "install"}`, ""},
+		{"/v2/snaps/foo", `{"action": "install", "channel": "latest/candidate"}`, ""},
+		{"/v2/snaps/foo", `{"action": "install", "channel": "edge"}`, `access policy does not allow user "alice" to install "foo" from channel "edge"`},
+		{"/v2/snaps/other", `{"action": "install"}`, `access policy does not allow user "alice" to install "other"`},
+		{"/v2/snaps/foo", `{"action": "remove"}`, `access policy does not allow user "alice" to remove "foo"`},
+	} {
+		req := jsonRequest("POST", t.url, t.body)
+		rspe := s.checkAccess(daemon.AccessPolicyScopeSnap, 1000, req)
+		if t.err == "" {
+			c.Check(rspe, IsNil, Commentf(t.body))
+			continue
+		}
+		c.Assert(rspe, NotNil, Commentf(t.body))
+		c.Check(rspe.Message, Equals, t.err)
+	}
+
+	req := jsonRequest("POST", "/v2/snaps/foo", `{"action": "install", "channel": "edge"}`)
+	rspe := s.checkAccess(daemon.AccessPolicyScopeSnap, 1000, req)
+	c.Check(rspe.Value, DeepEquals, map[string]interface{}{
+		"user":      "alice",
+		"operation": "install",
+		"snap":      "foo",
+		"channel":   "edge",
+	})
+}
+
+const testOptionsAccessPolicy = `
+rules:
+  - groups: [operators]
+    allow:
+      - operation: install
+        snaps: [foo]
+        channels: [stable]
+      - operation: install
+        snaps: [bar]
+        options: [classic, revision]
+      - operation: refresh
+        snaps: [foo, bar]
+        channels: [stable]
+      - operation: restart
+        snaps: [foo]
+`
+
+func (s *accessPolicySuite) TestPrivilegedOptions(c *C) {
+	s.writePolicy(c, testOptionsAccessPolicy)
+
+	for _, t := range []struct {
+		url, body string
+		err       string
+	}{
+		// options with zero values are fine
+		{"/v2/snaps/foo", `{"action": "install", "classic": false, "revision": "", "cohort-key": null}`, ""},
+		{"/v2/snaps/foo", `{"action": "install", "unaliased": true}`, ""},
+		{"/v2/snaps/foo", `{"action": "install", "classic": true}`, `access policy does not allow user "alice" to install "foo" with "classic"`},
+		{"/v2/snaps/foo", `{"action": "install", "devmode": true}`, `access policy does not allow user "alice" to install "foo" with "devmode"`},
+		{"/v2/snaps/foo", `{"action": "install", "jailmode": true}`, `access policy does not allow user "alice" to install "foo" with "jailmode"`},
+		{"/v2/snaps/foo", `{"action": "install", "ignore-validation": true}`, `access policy does not allow user "alice" to install "foo" with "ignore-validation"`},
+		{"/v2/snaps/foo", `{"action": "install", "cohort-key": "xyz"}`, `access policy does not allow user "alice" to install "foo" with "cohort-key"`},
+		// a revision would get around the channel restriction
+		{"/v2/snaps/foo", `{"action": "install", "revision": "x1"}`, `access policy does not allow user "alice" to install "foo" with "revision"`},
+		{"/v2/snaps/bar", `{"action": "install", "classic": true, "revision": "12"}`, ""},
+		{"/v2/snaps/bar", `{"action": "install", "classic": true, "devmode": true}`, `access policy does not allow user "alice" to install "bar" with "classic", "devmode"`},
+		// unknown fields are denied
+		{"/v2/snaps/foo", `{"action": "install", "frobnicate": true}`, `access policy does not allow user "alice" to perform this request`},
+	} {
+		req := jsonRequest("POST", t.url, t.body)
+		rspe := s.checkAccess(daemon.AccessPolicyScopeSnap, 1000, req)
+		if t.err == "" {
+			c.Check(rspe, IsNil, Commentf(t.body))
+			continue
+		}
+		c.Assert(rspe, NotNil, Commentf(t.body))
+		c.Check(rspe.Message, Equals, t.err, Commentf(t.body))
+	}
+
+	req := jsonRequest("POST", "/v2/snaps", `{"action": "remove", "snaps": ["foo"], "purge": true}`)
+	rspe := s.checkAccess(daemon.AccessPolicyScopeSnaps, 1000, req)
+	c.Assert(rspe, NotNil)
+	c.Check(rspe.Value, DeepEquals, map[string]interface{}{
+		"user":      "alice",
+		"operation": "remove",
+		"snap":      "foo",
+		"options":   []string{"purge"},
+	})
+
+	req = jsonRequest("POST", "/v2/apps", `{"action": "restart", "names": ["foo"], "reload": true}`)
+	c.Check(s.checkAccess(daemon.AccessPolicyScopeApps, 1000, req), IsNil)
+	req = jsonRequest("POST", "/v2/apps", `{"action": "restart", "names": ["foo"], "enable": true}`)
+	rspe = s.checkAccess(daemon.AccessPolicyScopeApps, 1000, req)
+	c.Assert(rspe, NotNil)
+	c.Check(rspe.Message, Equals, `access policy does not allow user "alice" to restart "foo" with "enable"`)
+}
+
+func (s *accessPolicySuite) TestRefreshTrackingChannel(c *C) {
+	s.writePolicy(c, testOptionsAccessPolicy)
+
+	s.AddCleanup(daemon.MockAccessPolicyTrackingChannel(func(d *daemon.Daemon, snapName string) string {
+		if snapName == "bar" {
+			return "latest/edge"
+		}
+		return ""
+	}))
+
+	// without a channel the refresh stays on the tracked channel
+	req := jsonRequest("POST", "/v2/snaps/foo", `{"action": "refresh"}`)
+	c.Check(s.checkAccess(daemon.AccessPolicyScopeSnap, 1000, req), IsNil)
+	req = jsonRequest("POST", "/v2/snaps/bar", `{"action": "refresh"}`)
+	rspe := s.checkAccess(daemon.AccessPolicyScopeSnap, 1000, req)
+	c.Assert(rspe, NotNil)
+	c.Check(rspe.Message, Equals, `access policy does not allow user "alice" to refresh "bar" from channel "latest/edge"`)
+	req = jsonRequest("POST", "/v2/snaps/bar", `{"action": "refresh", "channel": "stable"}`)
+	c.Check(s.checkAccess(daemon.AccessPolicyScopeSnap, 1000, req), IsNil)
+
+	req = jsonRequest("POST", "/v2/snaps", `{"action": "refresh", "snaps": ["foo", "bar"]}`)
+	rspe = s.checkAccess(daemon.AccessPolicyScopeSnaps, 1000, req)
+	c.Assert(rspe, NotNil)
+	c.Check(rspe.Message, Equals, `access policy does not allow user "alice" to refresh "bar" from channel "latest/edge"`)
+
+	// the channels of all snaps cannot be checked
+	req = jsonRequest("POST", "/v2/snaps", `{"action": "refresh", "channel": "stable"}`)
+	c.Check(s.checkAccess(daemon.AccessPolicyScopeSnaps, 1000, req), NotNil)
+}
+
+func (s *accessPolicySuite) TestBodyTooLarge(c *C) {
+	s.writePolicy(c, testOptionsAccessPolicy)
+
+	body := `{"action": "install", "unaliased": ` + strings.Repeat(" ", 2*1024*1024) + `true}`
+	req := jsonRequest("POST", "/v2/snaps/foo", body)
+	rspe := s.checkAccess(daemon.AccessPolicyScopeSnap, 1000, req)
+	c.Assert(rspe, NotNil)
+	c.Check(rspe.Message, Equals, `access policy does not allow user "alice" to perform this request`)
+}
+
+func (s *accessPolicySuite) TestMultiSnap(c *C) {
+	s.writePolicy(c, testAccessPolicy)
+
+	// refresh of all snaps needs an unrestricted grant
+	req := jsonRequest("POST", "/v2/snaps", `{"action": "refresh"}`)
+	c.Check(s.checkAccess(daemon.AccessPolicyScopeSnaps, 1001, req), IsNil)
+	req = jsonRequest("POST", "/v2/snaps", `{"action": "refresh", "snaps": ["foo", "bar"]}`)
+	c.Check(s.checkAccess(daemon.AccessPolicyScopeSnaps, 1001, req), IsNil)
+	req = jsonRequest("POST", "/v2/snaps", `{"action": "remove", "snaps": ["foo"]}`)
+	c.Check(s.checkAccess(daemon.AccessPolicyScopeSnaps, 1001, req).Kind, Equals, client.ErrorKindAccessPolicyDenied)
+
+	// sideloading is never granted
+	req = httptest.NewRequest("POST", "/v2/snaps", strings.NewReader("----hello--\r\n"))
+	req.Header.Set("Content-Type", "multipart/thing; boundary=--hello--")
+	c.Check(s.checkAccess(daemon.AccessPolicyScopeSnaps, 1001, req).Kind, Equals, client.ErrorKindAccessPolicyDenied)
+}
+
+func (s *accessPolicySuite) TestBatch(c *C) {
+	s.writePolicy(c, testAccessPolicy)
+
+	for _, t := range []struct {
+		body string
+		err  string
+	}{
+		{`{"operations": [{"action": "install", "snap": "foo", "channel": "candidate"}]}`, ""},
+		{`{"operations": [{"action": "install", "snap": "foo"}, {"action": "install", "snap": "bar"}]}`, `access policy does not allow user "alice" to install "bar"`},
+		{`{"operations": [{"action": "install", "snap": "foo", "devmode": true}]}`, `access policy does not allow user "alice" to install "foo" with "devmode"`},
+		// connecting, disconnecting and configuring are never granted
+		{`{"operations": [{"action": "install", "snap": "foo"}, {"action": "set", "snap": "foo", "values": {"a": 1}}]}`, `access policy does not allow user "alice" to set "foo"`},
+		{`{"operations": [{"action": "connect", "plug": {"snap": "foo", "plug": "network"}, "slot": {"snap": "core", "slot": "network"}}]}`, `access policy does not allow user "alice" to connect all snaps`},
+		{`{"operations": [{"action": "install", "snap": "foo", "unknown": 1}]}`, `access policy does not allow user "alice" to perform this request`},
+		{`{"operations": []}`, `access policy does not allow user "alice" to perform this request`},
+	} {
+		req := jsonRequest("POST", "/v2/batch", t.body)
+		rspe := s.checkAccess(daemon.AccessPolicyScopeBatch, 1000, req)
+		if t.err == "" {
+			c.Check(rspe, IsNil, Commentf(t.body))
+			continue
+		}
+		c.Assert(rspe, NotNil, Commentf(t.body))
+		c.Check(rspe.Kind, Equals, client.ErrorKindAccessPolicyDenied)
+		c.Check(rspe.Message, Equals, t.err)
+	}
+}
+
+func (s *accessPolicySuite) TestLogs(c *C) {
+	s.writePolicy(c, testAccessPolicy)
+
+	req := httptest.NewRequest("GET", "/v2/logs?names=foo.svc", nil)
+	c.Check(s.checkAccess(daemon.AccessPolicyScopeLogs, 1000, req), IsNil)
+
+	req = httptest.NewRequest("GET", "/v2/logs", nil)
+	rspe := s.checkAccess(daemon.AccessPolicyScopeLogs, 1000, req)
+	c.Assert(rspe, NotNil)
+	c.Check(rspe.Message, Equals, `access policy does not allow user "alice" to read logs of all snaps`)
+}
+
+func (s *accessPolicySuite) TestUserNotInPolicy(c *C) {
+	s.writePolicy(c, testAccessPolicy)
+
+	// users without rules get the usual error
+	req := jsonRequest("POST", "/v2/apps", `{"action": "restart", "names": ["foo"]}`)
+	c.Check(s.checkAccess(daemon.AccessPolicyScopeApps, 1002, req), DeepEquals, errUnauthorized)
+}
+
+func (s *accessPolicySuite) TestPolkitStillConsulted(c *C) {
+	s.writePolicy(c, testAccessPolicy)
+
+	restore := daemon.MockCheckPolkitAction(func(r *http.Request, ucred *daemon.Ucrednet, action string) *daemon.APIError {
+		return nil
+	})
+	defer restore()
+
+	req := jsonRequest("POST", "/v2/apps", `{"action": "stop", "names": ["foo"]}`)
+	c.Check(s.checkAccess(daemon.AccessPolicyScopeApps, 1000, req), IsNil)
+}
+
+func (s *accessPolicySuite) TestInvalidPolicy(c *C) {
+	logbuf, restore := logger.MockLogger()
+	defer restore()
+
+	for _, t := range []struct {
+		policy, err string
+	}{
+		{"rules: [{allow: [{operation: restart}]}]", `invalid access policy: rule 1 does not apply to any user, group or client`},
+		{"rules: [{clients: [abcd], allow: [{operation: restart}]}]", `invalid access policy: rule 1: invalid SHA256 certificate fingerprint "abcd"`},
+		{"rules: [{users: [a], allow: [{operation: frobnicate}]}]", `invalid access policy: rule 1: unknown operation "frobnicate"`},
+		{"rules: [{users: [a], allow: [{operation: restart, channels: [edge]}]}]", `invalid access policy: rule 1: operation "restart" cannot be restricted by channel`},
+		{"rules: [{users: [a], allow: [{operation: install, options: [frobnicate]}]}]", `invalid access policy: rule 1: operation "install" has no option "frobnicate"`},
+		{"rules: [{users: [a], allow: [{operation: logs, options: [classic]}]}]", `invalid access policy: rule 1: operation "logs" has no option "classic"`},
+		{"rules: [{users: [a], unknown: true}]", `cannot parse access policy: .*`},
+	} {
+		logbuf.Reset()
+		s.writePolicy(c, t.policy)
+		req := jsonRequest("POST", "/v2/apps", `{"action": "restart", "names": ["foo"]}`)
+		c.Check(s.checkAccess(daemon.AccessPolicyScopeApps, 1000, req), DeepEquals, errUnauthorized)
+		c.Check(logbuf.String(), Matches, `(?s).*cannot use access policy: `+t.err+"\n")
+	}
+}
+
+const testRemoteAccessPolicy = `
+rules:
+  - clients: ["9F:86:D0:81:88:4C:7D:65:9A:2F:EA:A0:C5:5A:D0:15:A3:BF:4F:1B:2B:0B:82:2C:D1:5D:6C:15:B0:F0:0A:08"]
+    allow:
+      - operation: restart
+        snaps: [foo]
+      - operation: refresh
+        snaps: [foo]
+  - users: [alice]
+    allow:
+      - operation: remove
+`
+
+func (s *accessPolicySuite) TestRemoteClient(c *C) {
+	s.writePolicy(c, testRemoteAccessPolicy)
+
+	const fp = "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"
+	check := func(ac daemon.AuthenticatedAccess, fingerprint string, req *http.Request) *daemon.APIError {
+		return daemon.CheckRemoteAccess(ac, req, fingerprint)
+	}
+	apps := daemon.AuthenticatedAccess{Polkit: "action-id", Policy: daemon.AccessPolicyScopeApps}
+	snap := daemon.AuthenticatedAccess{Polkit: "action-id", Policy: daemon.AccessPolicyScopeSnap}
+
+	req := jsonRequest("POST", "/v2/apps", `{"action": "restart", "names": ["foo"]}`)
+	c.Check(check(apps, fp, req), IsNil)
+	req = jsonRequest("POST", "/v2/snaps/foo", `{"action": "refresh"}`)
+	c.Check(check(snap, fp, req), IsNil)
+
+	// anything not in scope is denied
+	req = jsonRequest("POST", "/v2/snaps/foo", `{"action": "refresh", "devmode": true}`)
+	rspe := check(snap, fp, req)
+	c.Assert(rspe, NotNil)
+	c.Check(rspe.Kind, Equals, client.ErrorKindAccessPolicyDenied)
+	c.Check(rspe.Message, Equals, `access policy does not allow remote client "`+fp+`" to refresh "foo" with "devmode"`)
+	c.Check(rspe.Value, DeepEquals, map[string]interface{}{
+		"client":    fp,
+		"operation": "refresh",
+		"snap":      "foo",
+		"options":   []string{"devmode"},
+	})
+	req = jsonRequest("POST", "/v2/snaps/foo", `{"action": "remove"}`)
+	rspe = check(snap, fp, req)
+	c.Assert(rspe, NotNil)
+	c.Check(rspe.Message, Equals, `access policy does not allow remote client "`+fp+`" to remove "foo"`)
+
+	// clients not in the policy are denied
+	other := "60303ae22b998861bce3b28f33eec1be758a213c86c93c076dbe9f558c11c752"
+	req = jsonRequest("POST", "/v2/apps", `{"action": "restart", "names": ["foo"]}`)
+	c.Check(check(apps, other, req), DeepEquals, daemon.Forbidden("access denied"))
+}
@@ -47,6 +47,9 @@ var api = []*Command{
 	snapFileCmd,
 	snapDownloadCmd,
 	snapConfCmd,
+	confCmd,
+	snapUsageCmd,
+	usageCmd,
 	interfacesCmd,
 	assertsCmd,
 	assertsFindManyCmd,
@@ -64,6 +67,9 @@ var api = []*Command{
 	warningsCmd,
 	debugPprofCmd,
 	debugCmd,
+	debugDenialsCmd,
+	debugSandboxProfileCmd,
+	debugCheckPolicyCmd,
 	snapshotCmd,
 	snapshotExportCmd,
 	connectionsCmd,
@@ -79,6 +85,9 @@ var api = []*Command{
 	systemRecoveryKeysCmd,
 	quotaGroupsCmd,
 	quotaGroupInfoCmd,
+	serviceOverridesCmd,
+	batchCmd,
+	openAPICmd,
 }
 
 const (
@@ -163,6 +172,26 @@ func newChange(st *state.State, kind, summary string, tsets []*state.TaskSet, sn
 	return chg
 }
 
+// changeInitiator describes who requested a change, as recorded in its
+// "initiator" and from there in the connection and configuration
+// histories: the snapd account of an authenticated user, or otherwise
+// the uid of the local peer as "uid:<uid>".
+func changeInitiator(r *http.Request, user *auth.UserState) string {
+	if user != nil {
+		if user.Username != "" {
+			return user.Username
+		}
+		if user.Email != "" {
+			return user.Email
+		}
+	}
+	ucred, err := ucrednetGet(r.RemoteAddr)
+	if err != nil {
+		return ""
+	}
+	return fmt.Sprintf("uid:%d", ucred.Uid)
+}
+
 func isTrue(form *multipart.Form, key string) bool {
 	value := form.Value[key]
 	if len(value) == 0 {
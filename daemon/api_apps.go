@@ -23,10 +23,13 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"net/url"
 	"sort"
 	"strconv"
 	"strings"
+	"time"
 
+	"github.com/snapcore/snapd/client"
 	"github.com/snapcore/snapd/client/clientutil"
 	"github.com/snapcore/snapd/overlord/auth"
 	"github.com/snapcore/snapd/overlord/servicestate"
@@ -43,13 +46,13 @@ var (
 		GET:         getAppsInfo,
 		POST:        postApps,
 		ReadAccess:  openAccess{},
-		WriteAccess: authenticatedAccess{},
+		WriteAccess: authenticatedAccess{Policy: accessPolicyScopeApps},
 	}
 
 	logsCmd = &Command{
 		Path:       "/v2/logs",
 		GET:        getLogs,
-		ReadAccess: authenticatedAccess{Polkit: polkitActionManage},
+		ReadAccess: authenticatedAccess{Polkit: polkitActionManage, Policy: accessPolicyScopeLogs},
 	}
 )
 
@@ -66,12 +69,25 @@ func getAppsInfo(c *Command, r *http.Request, user *auth.UserState) Response {
 		return BadRequest("invalid select parameter: %q", sel)
 	}
 
+	users, rspe := userSelectionFromString(query.Get("users"))
+	if rspe != nil {
+		return rspe
+	}
+
 	appInfos, rspe := appInfosFor(c.d.overlord.State(), strutil.CommaSeparatedList(query.Get("names")), opts)
 	if rspe != nil {
 		return rspe
 	}
 
-	sd := servicestate.NewStatusDecorator(progress.Null)
+	var sd clientutil.StatusDecorator = servicestate.NewStatusDecorator(progress.Null)
+	if users != client.UserSelectionNone {
+		uids, rspe := uidsForUserSelection(r, user, users)
+		if rspe != nil {
+			return rspe
+		}
+		appInfos = userDaemons(appInfos)
+		sd = newUserStatusDecorator(uids, appInfos)
+	}
 
 	clientAppInfos, err := clientutil.ClientAppInfosFromSnapAppInfos(appInfos, sd)
 	if err != nil {
@@ -81,6 +97,50 @@ func getAppsInfo(c *Command, r *http.Request, user *auth.UserState) Response {
 	return SyncResponse(clientAppInfos)
 }
 
+var newUserStatusDecorator = func(uids []int, apps []*snap.AppInfo) clientutil.StatusDecorator {
+	return servicestate.NewUserStatusDecorator(uids, apps)
+}
+
+func userSelectionFromString(s string) (client.UserSelection, *apiError) {
+	switch users := client.UserSelection(s); users {
+	case client.UserSelectionNone, client.UserSelectionSelf, client.UserSelectionAll:
+		return users, nil
+	default:
+		return "", BadRequest("invalid users parameter: %q", s)
+	}
+}
+
+// uidsForUserSelection returns the users whose sessions are selected by
+// users, or nil if all the active sessions are. Only root or an
+// authenticated user can select the sessions of all the users.
+func uidsForUserSelection(r *http.Request, user *auth.UserState, users client.UserSelection) ([]int, *apiError) {
+	if users == client.UserSelectionNone {
+		return nil, nil
+	}
+	ucred, err := ucrednetGet(r.RemoteAddr)
+	if err != nil {
+		return nil, BadRequest("cannot find out the user of the request: %v", err)
+	}
+	if users == client.UserSelectionAll {
+		if ucred.Uid != 0 && user == nil {
+			return nil, Forbidden("cannot select the sessions of all users: root or an authenticated user is required")
+		}
+		return nil, nil
+	}
+	return []int{int(ucred.Uid)}, nil
+}
+
+// userDaemons returns the apps that are daemons running in user sessions.
+func userDaemons(appInfos []*snap.AppInfo) []*snap.AppInfo {
+	userApps := make([]*snap.AppInfo, 0, len(appInfos))
+	for _, app := range appInfos {
+		if app.IsService() && app.DaemonScope == snap.UserDaemon {
+			userApps = append(userApps, app)
+		}
+	}
+	return userApps
+}
+
 type appInfoOptions struct {
 	service bool
 }
@@ -198,6 +258,18 @@ func getLogs(c *Command, r *http.Request, user *auth.UserState) Response {
 		}
 		follow = f
 	}
+	filter, rspe := logFilterFromQuery(query)
+	if rspe != nil {
+		return rspe
+	}
+	fields := false
+	if s := query.Get("fields"); s != "" {
+		f, err := strconv.ParseBool(s)
+		if err != nil {
+			return BadRequest(`invalid value for fields: %q: %v`, s, err)
+		}
+		fields = f
+	}
 
 	// only services have logs for now
 	opts := appInfoOptions{service: true}
@@ -215,7 +287,7 @@ func getLogs(c *Command, r *http.Request, user *auth.UserState) Response {
 	}
 
 	sysd := systemd.New(systemd.SystemMode, progress.Null)
-	reader, err := sysd.LogReader(serviceNames, n, follow)
+	reader, err := sysd.LogReader(serviceNames, n, follow, filter)
 	if err != nil {
 		return InternalError("cannot get logs: %v", err)
 	}
@@ -223,9 +295,78 @@ func getLogs(c *Command, r *http.Request, user *auth.UserState) Response {
 	return &journalLineReaderSeqResponse{
 		ReadCloser: reader,
 		follow:     follow,
+		fields:     fields,
 	}
 }
 
+// logPriorities are the syslog priorities, from the most to the least
+// important, as named by journalctl.
+var logPriorities = []string{"emerg", "alert", "crit", "err", "warning", "notice", "info", "debug"}
+
+// logBoots maps the boots that can be asked for to their journalctl
+// --boot offsets.
+var logBoots = map[string]string{
+	"current":  "0",
+	"previous": "-1",
+}
+
+var systemdEnsureJournalGrep = systemd.EnsureJournalGrep
+
+func logFilterFromQuery(query url.Values) (*systemd.LogFilter, *apiError) {
+	var filter systemd.LogFilter
+	for _, t := range []struct {
+		key string
+		dst *time.Time
+	}{
+		{"since", &filter.Since},
+		{"until", &filter.Until},
+	} {
+		s := query.Get(t.key)
+		if s == "" {
+			continue
+		}
+		tm, err := time.Parse(time.RFC3339Nano, s)
+		if err != nil {
+			return nil, BadRequest(`invalid value for %s: %q: %v`, t.key, s, err)
+		}
+		*t.dst = tm
+	}
+	if !filter.Since.IsZero() && !filter.Until.IsZero() && filter.Until.Before(filter.Since) {
+		return nil, BadRequest("invalid time range: until is before since")
+	}
+
+	if s := query.Get("priority"); s != "" {
+		for i, prio := range logPriorities {
+			if s == prio || s == strconv.Itoa(i) {
+				filter.Priority = prio
+				break
+			}
+		}
+		if filter.Priority == "" {
+			return nil, BadRequest(`invalid value for priority: %q (expected one of %s or 0 to 7)`, s, strutil.Quoted(logPriorities))
+		}
+	}
+
+	if s := query.Get("boot"); s != "" {
+		boot, ok := logBoots[s]
+		if !ok {
+			return nil, BadRequest(`invalid value for boot: %q (expected "current" or "previous")`, s)
+		}
+		filter.Boot = boot
+	}
+
+	if s := query.Get("grep"); s != "" {
+		// the pattern is a PCRE2 one, which journalctl itself
+		// checks, Go regular expressions are a different dialect
+		if err := systemdEnsureJournalGrep(); err != nil {
+			return nil, BadRequest("cannot filter logs by message: %v", err)
+		}
+		filter.Grep = s
+	}
+
+	return &filter, nil
+}
+
 var servicestateControl = servicestate.Control
 
 func postApps(c *Command, r *http.Request, user *auth.UserState) Response {
@@ -251,6 +392,24 @@ func postApps(c *Command, r *http.Request, user *auth.UserState) Response {
 		return InternalError("no services found")
 	}
 
+	if inst.Users != client.UserSelectionNone {
+		if _, rspe := userSelectionFromString(string(inst.Users)); rspe != nil {
+			return rspe
+		}
+		if inst.Enable || inst.Disable {
+			return BadRequest("cannot enable or disable services in the sessions of selected users")
+		}
+		appInfos = userDaemons(appInfos)
+		if len(appInfos) == 0 {
+			return BadRequest("cannot perform operation on user services: no user services to operate on")
+		}
+		uids, rspe := uidsForUserSelection(r, user, inst.Users)
+		if rspe != nil {
+			return rspe
+		}
+		inst.Uids = uids
+	}
+
 	// do not pass flags - only create service-control tasks, do not create
 	// exec-command tasks for old snapd. These are not needed since we are
 	// handling momentary snap service commands.
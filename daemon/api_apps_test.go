@@ -29,14 +29,18 @@ import (
 	"math"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"sort"
 	"strconv"
 	"strings"
+	"time"
 
 	"gopkg.in/check.v1"
 
 	"github.com/snapcore/snapd/client"
+	"github.com/snapcore/snapd/client/clientutil"
 	"github.com/snapcore/snapd/daemon"
+	"github.com/snapcore/snapd/overlord/auth"
 	"github.com/snapcore/snapd/overlord/hookstate"
 	"github.com/snapcore/snapd/overlord/servicestate"
 	"github.com/snapcore/snapd/overlord/snapstate"
@@ -55,6 +59,7 @@ type appsSuite struct {
 	jctlSvcses         [][]string
 	jctlNs             []int
 	jctlFollows        []bool
+	jctlFilters        []*systemd.LogFilter
 	jctlRCs            []io.ReadCloser
 	jctlErrs           []error
 
@@ -64,10 +69,11 @@ type appsSuite struct {
 	infoA, infoB, infoC, infoD, infoE *snap.Info
 }
 
-func (s *appsSuite) journalctl(svcs []string, n int, follow bool) (rc io.ReadCloser, err error) {
+func (s *appsSuite) journalctl(svcs []string, n int, follow bool, filter *systemd.LogFilter) (rc io.ReadCloser, err error) {
 	s.jctlSvcses = append(s.jctlSvcses, svcs)
 	s.jctlNs = append(s.jctlNs, n)
 	s.jctlFollows = append(s.jctlFollows, follow)
+	s.jctlFilters = append(s.jctlFilters, filter)
 
 	if len(s.jctlErrs) > 0 {
 		err, s.jctlErrs = s.jctlErrs[0], s.jctlErrs[1:]
@@ -83,6 +89,8 @@ type serviceControlArgs struct {
 	action  string
 	options string
 	names   []string
+	users   client.UserSelection
+	uids    []int
 }
 
 func (s *appsSuite) fakeServiceControl(st *state.State, appInfos []*snap.AppInfo, inst *servicestate.Instruction, flags *servicestate.Flags, context *hookstate.Context) ([]*state.TaskSet, error) {
@@ -94,7 +102,7 @@ func (s *appsSuite) fakeServiceControl(st *state.State, appInfos []*snap.AppInfo
 		return nil, s.serviceControlError
 	}
 
-	serviceCommand := serviceControlArgs{action: inst.Action}
+	serviceCommand := serviceControlArgs{action: inst.Action, users: inst.Users, uids: inst.Uids}
 	if inst.RestartOptions.Reload {
 		serviceCommand.options = "reload"
 	}
@@ -129,9 +137,14 @@ func (s *appsSuite) TearDownSuite(c *check.C) {
 func (s *appsSuite) SetUpTest(c *check.C) {
 	s.apiBaseSuite.SetUpTest(c)
 
+	s.expectWriteAccess(daemon.AuthenticatedAccess{Policy: daemon.AccessPolicyScopeApps})
+
+	s.AddCleanup(daemon.MockSystemdEnsureJournalGrep(func() error { return nil }))
+
 	s.jctlSvcses = nil
 	s.jctlNs = nil
 	s.jctlFollows = nil
+	s.jctlFilters = nil
 	s.jctlRCs = nil
 	s.jctlErrs = nil
 
@@ -317,6 +330,79 @@ UnitFileState=enabled
 	c.Check(sort.StringsAreSorted(appNames), check.Equals, true)
 }
 
+type fakeUserStatusDecorator struct {
+	uids []int
+}
+
+func (sd *fakeUserStatusDecorator) DecorateWithStatus(appInfo *client.AppInfo, snapApp *snap.AppInfo) error {
+	for _, uid := range sd.uids {
+		appInfo.UserStatus = append(appInfo.UserStatus, client.AppUserStatus{Uid: uid, Active: true})
+	}
+	return nil
+}
+
+func (s *appsSuite) TestGetAppsInfoUsers(c *check.C) {
+	var decoratorUids [][]int
+	restore := daemon.MockNewUserStatusDecorator(func(uids []int, apps []*snap.AppInfo) clientutil.StatusDecorator {
+		decoratorUids = append(decoratorUids, uids)
+		// only the user services are decorated
+		c.Check(apps, check.HasLen, 1)
+		if uids == nil {
+			uids = []int{42, 1000}
+		}
+		return &fakeUserStatusDecorator{uids: uids}
+	})
+	defer restore()
+
+	for _, tc := range []struct {
+		users      string
+		remoteAddr string
+		user       *auth.UserState
+		expected   []client.AppUserStatus
+		uids       []int
+	}{
+		{"self", "pid=100;uid=1000;socket=;", nil, []client.AppUserStatus{{Uid: 1000, Active: true}}, []int{1000}},
+		{"all", "pid=100;uid=0;socket=;", nil, []client.AppUserStatus{{Uid: 42, Active: true}, {Uid: 1000, Active: true}}, nil},
+		{"all", "pid=100;uid=1000;socket=;", &auth.UserState{ID: 1}, []client.AppUserStatus{{Uid: 42, Active: true}, {Uid: 1000, Active: true}}, nil},
+	} {
+		decoratorUids = nil
+		req, err := http.NewRequest("GET", "/v2/apps?select=service&users="+tc.users, nil)
+		c.Assert(err, check.IsNil)
+		req.RemoteAddr = tc.remoteAddr
+
+		rsp := s.syncReq(c, req, tc.user)
+		c.Assert(rsp.Status, check.Equals, 200)
+		// only the user services are listed
+		c.Check(rsp.Result, check.DeepEquals, []client.AppInfo{{
+			Snap:        "snap-e",
+			Name:        "svc4",
+			Daemon:      "simple",
+			DaemonScope: snap.UserDaemon,
+			UserStatus:  tc.expected,
+		}})
+		c.Check(decoratorUids, check.DeepEquals, [][]int{tc.uids})
+	}
+}
+
+func (s *appsSuite) TestGetAppsInfoAllUsersForbidden(c *check.C) {
+	req, err := http.NewRequest("GET", "/v2/apps?select=service&users=all", nil)
+	c.Assert(err, check.IsNil)
+	req.RemoteAddr = "pid=100;uid=1000;socket=;"
+
+	rspe := s.errorReq(c, req, nil)
+	c.Check(rspe.Status, check.Equals, 403)
+	c.Check(rspe.Message, check.Equals, "cannot select the sessions of all users: root or an authenticated user is required")
+}
+
+func (s *appsSuite) TestGetAppsInfoBadUsers(c *check.C) {
+	req, err := http.NewRequest("GET", "/v2/apps?users=potato", nil)
+	c.Assert(err, check.IsNil)
+
+	rspe := s.errorReq(c, req, nil)
+	c.Check(rspe.Status, check.Equals, 400)
+	c.Check(rspe.Message, check.Equals, `invalid users parameter: "potato"`)
+}
+
 func (s *appsSuite) TestGetAppsInfoBadSelect(c *check.C) {
 	req, err := http.NewRequest("GET", "/v2/apps?select=potato", nil)
 	c.Assert(err, check.IsNil)
@@ -572,6 +658,67 @@ func (s *appsSuite) TestPostAppsDisableNow(c *check.C) {
 	s.testPostApps(c, inst, expected)
 }
 
+func (s *appsSuite) TestPostAppsUsers(c *check.C) {
+	for _, tc := range []struct {
+		users      client.UserSelection
+		remoteAddr string
+		user       *auth.UserState
+		uids       []int
+	}{
+		{client.UserSelectionSelf, "pid=100;uid=1000;socket=;", nil, []int{1000}},
+		{client.UserSelectionAll, "pid=100;uid=0;socket=;", nil, nil},
+		{client.UserSelectionAll, "pid=100;uid=1000;socket=;", &auth.UserState{ID: 1}, nil},
+	} {
+		s.serviceControlCalls = nil
+		postBody, err := json.Marshal(map[string]interface{}{
+			"action": "restart",
+			"names":  []string{"snap-a", "snap-e"},
+			"users":  tc.users,
+		})
+		c.Assert(err, check.IsNil)
+		req, err := http.NewRequest("POST", "/v2/apps", bytes.NewBuffer(postBody))
+		c.Assert(err, check.IsNil)
+		req.RemoteAddr = tc.remoteAddr
+
+		rsp := s.asyncReq(c, req, tc.user)
+		c.Assert(rsp.Status, check.Equals, 202)
+		// the system services of snap-a are left alone
+		c.Check(s.serviceControlCalls, check.DeepEquals, []serviceControlArgs{
+			{action: "restart", names: []string{"snap-e.svc4"}, users: tc.users, uids: tc.uids},
+		})
+	}
+}
+
+func (s *appsSuite) TestPostAppsAllUsersForbidden(c *check.C) {
+	req, err := http.NewRequest("POST", "/v2/apps", bytes.NewBufferString(`{"action": "restart", "names": ["snap-e"], "users": "all"}`))
+	c.Assert(err, check.IsNil)
+	req.RemoteAddr = "pid=100;uid=1000;socket=;"
+
+	rspe := s.errorReq(c, req, nil)
+	c.Check(rspe.Status, check.Equals, 403)
+	c.Check(rspe.Message, check.Equals, "cannot select the sessions of all users: root or an authenticated user is required")
+	c.Check(s.serviceControlCalls, check.HasLen, 0)
+}
+
+func (s *appsSuite) TestPostAppsUsersErrors(c *check.C) {
+	for _, tc := range []struct {
+		body string
+		err  string
+	}{
+		{`{"action": "start", "names": ["snap-e"], "users": "potato"}`, `invalid users parameter: "potato"`},
+		{`{"action": "start", "names": ["snap-e"], "users": "all", "enable": true}`, `cannot enable or disable services in the sessions of selected users`},
+		{`{"action": "stop", "names": ["snap-e"], "users": "self", "disable": true}`, `cannot enable or disable services in the sessions of selected users`},
+		{`{"action": "start", "names": ["snap-a"], "users": "all"}`, `cannot perform operation on user services: no user services to operate on`},
+	} {
+		req, err := http.NewRequest("POST", "/v2/apps", bytes.NewBufferString(tc.body))
+		c.Assert(err, check.IsNil)
+		rspe := s.errorReq(c, req, nil)
+		c.Check(rspe.Status, check.Equals, 400, check.Commentf(tc.body))
+		c.Check(rspe.Message, check.Equals, tc.err, check.Commentf(tc.body))
+	}
+	c.Check(s.serviceControlCalls, check.HasLen, 0)
+}
+
 func (s *appsSuite) TestPostAppsBadJSON(c *check.C) {
 	req, err := http.NewRequest("POST", "/v2/apps", bytes.NewBufferString(`'junk`))
 	c.Assert(err, check.IsNil)
@@ -623,7 +770,7 @@ func (s *appsSuite) TestPostAppsConflict(c *check.C) {
 }
 
 func (s *appsSuite) expectLogsAccess() {
-	s.expectReadAccess(daemon.AuthenticatedAccess{Polkit: "io.snapcraft.snapd.manage"})
+	s.expectReadAccess(daemon.AuthenticatedAccess{Polkit: "io.snapcraft.snapd.manage", Policy: daemon.AccessPolicyScopeLogs})
 }
 
 func (s *appsSuite) TestLogs(c *check.C) {
@@ -646,6 +793,7 @@ func (s *appsSuite) TestLogs(c *check.C) {
 	c.Check(s.jctlSvcses, check.DeepEquals, [][]string{{"snap.snap-a.svc2.service"}})
 	c.Check(s.jctlNs, check.DeepEquals, []int{42})
 	c.Check(s.jctlFollows, check.DeepEquals, []bool{false})
+	c.Check(s.jctlFilters, check.DeepEquals, []*systemd.LogFilter{{}})
 
 	c.Check(rec.Code, check.Equals, 200)
 	c.Check(rec.HeaderMap.Get("Content-Type"), check.Equals, "application/json-seq")
@@ -731,6 +879,122 @@ func (s *appsSuite) TestLogsBadFollow(c *check.C) {
 	c.Assert(rspe.Status, check.Equals, 400)
 }
 
+func (s *appsSuite) TestLogsFilter(c *check.C) {
+	s.expectLogsAccess()
+
+	s.jctlRCs = []io.ReadCloser{ioutil.NopCloser(strings.NewReader(""))}
+
+	q := url.Values{
+		"since":    {"2021-03-01T10:00:00Z"},
+		"until":    {"2021-03-02T12:00:00+02:00"},
+		"priority": {"4"},
+		"boot":     {"previous"},
+		"grep":     {"(?i)segfault|crash(?=ed)"},
+	}
+	req, err := http.NewRequest("GET", "/v2/logs?"+q.Encode(), nil)
+	c.Assert(err, check.IsNil)
+
+	rec := httptest.NewRecorder()
+	s.req(c, req, nil).ServeHTTP(rec, req)
+	c.Check(rec.Code, check.Equals, 200)
+
+	c.Assert(s.jctlFilters, check.HasLen, 1)
+	filter := s.jctlFilters[0]
+	c.Check(filter.Since.Equal(time.Date(2021, 3, 1, 10, 0, 0, 0, time.UTC)), check.Equals, true)
+	c.Check(filter.Until.Equal(time.Date(2021, 3, 2, 10, 0, 0, 0, time.UTC)), check.Equals, true)
+	c.Check(filter.Priority, check.Equals, "warning")
+	c.Check(filter.Boot, check.Equals, "-1")
+	c.Check(filter.Grep, check.Equals, "(?i)segfault|crash(?=ed)")
+}
+
+func (s *appsSuite) TestLogsPriorityAndBoot(c *check.C) {
+	s.expectLogsAccess()
+
+	for _, t := range []struct {
+		query    string
+		priority string
+		boot     string
+	}{
+		{"priority=err", "err", ""},
+		{"priority=0", "emerg", ""},
+		{"priority=debug", "debug", ""},
+		{"boot=current", "", "0"},
+		{"boot=previous&priority=notice", "notice", "-1"},
+	} {
+		s.jctlRCs = []io.ReadCloser{ioutil.NopCloser(strings.NewReader(""))}
+		s.jctlFilters = nil
+
+		req, err := http.NewRequest("GET", "/v2/logs?"+t.query, nil)
+		c.Assert(err, check.IsNil)
+		rec := httptest.NewRecorder()
+		s.req(c, req, nil).ServeHTTP(rec, req)
+
+		c.Assert(s.jctlFilters, check.HasLen, 1, check.Commentf(t.query))
+		c.Check(s.jctlFilters[0].Priority, check.Equals, t.priority, check.Commentf(t.query))
+		c.Check(s.jctlFilters[0].Boot, check.Equals, t.boot, check.Commentf(t.query))
+	}
+}
+
+func (s *appsSuite) TestLogsBadFilter(c *check.C) {
+	s.expectLogsAccess()
+
+	for _, t := range []struct {
+		query string
+		err   string
+	}{
+		{"since=yesterday", `invalid value for since: "yesterday": .*`},
+		{"until=2021-03-01", `invalid value for until: "2021-03-01": .*`},
+		{"since=2021-03-02T00:00:00Z&until=2021-03-01T00:00:00Z", `invalid time range: until is before since`},
+		{"priority=8", `invalid value for priority: "8" \(expected one of "emerg", .* "debug" or 0 to 7\)`},
+		{"priority=error", `invalid value for priority: "error" .*`},
+		{"boot=-1", `invalid value for boot: "-1" \(expected "current" or "previous"\)`},
+		{"fields=maybe", `invalid value for fields: "maybe": .*`},
+	} {
+		req, err := http.NewRequest("GET", "/v2/logs?"+t.query, nil)
+		c.Assert(err, check.IsNil)
+
+		rspe := s.errorReq(c, req, nil)
+		c.Check(rspe.Status, check.Equals, 400, check.Commentf(t.query))
+		c.Check(rspe.Message, check.Matches, t.err, check.Commentf(t.query))
+	}
+	c.Check(s.jctlFilters, check.HasLen, 0)
+}
+
+func (s *appsSuite) TestLogsGrepUnsupported(c *check.C) {
+	s.expectLogsAccess()
+	restore := daemon.MockSystemdEnsureJournalGrep(func() error {
+		return errors.New("journalctl is built without PCRE2 support")
+	})
+	defer restore()
+
+	// patterns are not checked as Go regular expressions
+	req, err := http.NewRequest("GET", "/v2/logs?grep=%28%3F%3Dlookahead%29", nil)
+	c.Assert(err, check.IsNil)
+	rspe := s.errorReq(c, req, nil)
+	c.Check(rspe.Status, check.Equals, 400)
+	c.Check(rspe.Message, check.Equals, "cannot filter logs by message: journalctl is built without PCRE2 support")
+	c.Check(s.jctlFilters, check.HasLen, 0)
+}
+
+func (s *appsSuite) TestLogsFields(c *check.C) {
+	s.expectLogsAccess()
+
+	s.jctlRCs = []io.ReadCloser{ioutil.NopCloser(strings.NewReader(`
+{"MESSAGE": "hello1", "SYSLOG_IDENTIFIER": "xyzzy", "_PID": "42", "__REALTIME_TIMESTAMP": "42", "PRIORITY": "6"}
+	`))}
+
+	req, err := http.NewRequest("GET", "/v2/logs?fields=true", nil)
+	c.Assert(err, check.IsNil)
+
+	rec := httptest.NewRecorder()
+	s.req(c, req, nil).ServeHTTP(rec, req)
+
+	c.Check(rec.Code, check.Equals, 200)
+	c.Check(rec.Body.String(), check.Equals, `
+{"timestamp":"1970-01-01T00:00:00.000042Z","message":"hello1","sid":"xyzzy","pid":"42","fields":{"MESSAGE":"hello1","PRIORITY":"6","SYSLOG_IDENTIFIER":"xyzzy","_PID":"42","__REALTIME_TIMESTAMP":"42"}}
+`[1:])
+}
+
 func (s *appsSuite) TestLogsBadName(c *check.C) {
 	s.expectLogsAccess()
 
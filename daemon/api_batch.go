@@ -0,0 +1,395 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package daemon
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+
+	"github.com/snapcore/snapd/i18n"
+	"github.com/snapcore/snapd/interfaces"
+	"github.com/snapcore/snapd/jsonutil"
+	"github.com/snapcore/snapd/overlord/auth"
+	"github.com/snapcore/snapd/overlord/configstate"
+	"github.com/snapcore/snapd/overlord/ifacestate"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/snap"
+)
+
+var batchCmd = &Command{
+	Path:        "/v2/batch",
+	POST:        postBatch,
+	WriteAccess: authenticatedAccess{Polkit: polkitActionManage, Policy: accessPolicyScopeBatch},
+}
+
+// batchOperation is a single operation of a batch request. Which
+// fields are meaningful depends on the action.
+type batchOperation struct {
+	Action string `json:"action"`
+
+	// snap operations and set
+	Snap string `json:"snap,omitempty"`
+	snapRevisionOptions
+	DevMode  bool `json:"devmode,omitempty"`
+	JailMode bool `json:"jailmode,omitempty"`
+	Classic  bool `json:"classic,omitempty"`
+	Purge    bool `json:"purge,omitempty"`
+
+	// connect and disconnect
+	Plug *interfaces.PlugRef `json:"plug,omitempty"`
+	Slot *interfaces.SlotRef `json:"slot,omitempty"`
+
+	// set
+	Values map[string]interface{} `json:"values,omitempty"`
+}
+
+type batchInstruction struct {
+	Operations []*batchOperation `json:"operations"`
+}
+
+// batchSnapChange records the snap operation of a batch that changes
+// a snap.
+type batchSnapChange struct {
+	action string
+	// index is the 1-based index of the operation in the batch
+	index int
+}
+
+// batchBuilder accumulates the task sets of the operations of a batch,
+// making each operation wait for the previous one.
+//
+// The operations are planned against the state before the batch, so
+// a snap can be the target of only one snap operation, and operations
+// that would need to be planned against the state left by an earlier
+// snap operation are either deferred, like connecting a snap installed
+// by the batch, or rejected, like configuring a removed snap.
+type batchBuilder struct {
+	st     *state.State
+	repo   *interfaces.Repository
+	ifaces *ifacestate.InterfaceManager
+	ctx    context.Context
+	userID int
+
+	// index of the operation being planned
+	index int
+	// snaps changed by an earlier snap operation of the batch
+	changed map[string]batchSnapChange
+
+	summaries []string
+	affected  map[string]bool
+	tasksets  []*state.TaskSet
+	previous  []*state.TaskSet
+}
+
+// changedBy returns the earlier snap operation of the batch that
+// changes the given snap, if any.
+func (b *batchBuilder) changedBy(snapName string) (batchSnapChange, bool) {
+	change, ok := b.changed[snapName]
+	return change, ok
+}
+
+// discard removes the tasks built so far, when the batch is rejected.
+func (b *batchBuilder) discard() {
+	var tasks []*state.Task
+	for _, ts := range b.tasksets {
+		tasks = append(tasks, ts.Tasks()...)
+	}
+	b.st.DiscardTasks(tasks)
+}
+
+func (b *batchBuilder) add(summary string, affected []string, tasksets []*state.TaskSet) {
+	b.summaries = append(b.summaries, summary)
+	for _, name := range affected {
+		b.affected[name] = true
+	}
+	if len(tasksets) == 0 {
+		// nothing to do, e.g. already connected
+		return
+	}
+	for _, ts := range tasksets {
+		for _, prev := range b.previous {
+			ts.WaitAll(prev)
+		}
+	}
+	b.tasksets = append(b.tasksets, tasksets...)
+	b.previous = tasksets
+}
+
+func (b *batchBuilder) affectedSnaps() []string {
+	names := make([]string, 0, len(b.affected))
+	for name := range b.affected {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+var batchSnapActions = map[string]bool{
+	"install": true,
+	"refresh": true,
+	"remove":  true,
+	"revert":  true,
+	"enable":  true,
+	"disable": true,
+	"switch":  true,
+}
+
+func (b *batchBuilder) snapOp(op *batchOperation) *apiError {
+	if op.Snap == "" {
+		return BadRequest("snap name not specified")
+	}
+	if op.Plug != nil || op.Slot != nil || op.Values != nil {
+		return BadRequest("unsupported option provided for %s", op.Action)
+	}
+	if change, ok := b.changedBy(op.Snap); ok {
+		return BadRequest("cannot %s snap %q changed by operation %d of the batch", op.Action, op.Snap, change.index)
+	}
+	inst := snapInstruction{
+		Action:              op.Action,
+		snapRevisionOptions: op.snapRevisionOptions,
+		DevMode:             op.DevMode,
+		JailMode:            op.JailMode,
+		Classic:             op.Classic,
+		Purge:               op.Purge,
+		Snaps:               []string{op.Snap},
+		userID:              b.userID,
+		ctx:                 b.ctx,
+	}
+	if err := inst.validate(); err != nil {
+		return BadRequest("%s", err)
+	}
+	msg, tsets, err := inst.dispatch()(&inst, b.st)
+	if err != nil {
+		return inst.errToResponse(err)
+	}
+	b.changed[op.Snap] = batchSnapChange{action: op.Action, index: b.index}
+	b.add(msg, inst.Snaps, tsets)
+	return nil
+}
+
+func (b *batchBuilder) connectionRefs(op *batchOperation) (plug *interfaces.PlugRef, slot *interfaces.SlotRef, rspe *apiError) {
+	if op.Plug == nil || op.Slot == nil {
+		return nil, nil, BadRequest("%s requires a plug and a slot", op.Action)
+	}
+	if op.Snap != "" || op.Values != nil {
+		return nil, nil, BadRequest("unsupported option provided for %s", op.Action)
+	}
+	plug = &interfaces.PlugRef{Snap: ifacestate.RemapSnapFromRequest(op.Plug.Snap), Name: op.Plug.Name}
+	slot = &interfaces.SlotRef{Snap: ifacestate.RemapSnapFromRequest(op.Slot.Snap), Name: op.Slot.Name}
+	for _, snapName := range []string{plug.Snap, slot.Snap} {
+		change, ok := b.changedBy(snapName)
+		if !ok {
+			continue
+		}
+		switch {
+		case change.action == "remove":
+			return nil, nil, BadRequest("cannot %s snap %q removed by operation %d of the batch", op.Action, snapName, change.index)
+		case change.action == "install" && op.Action == "disconnect":
+			return nil, nil, BadRequest("cannot disconnect snap %q installed by operation %d of the batch", snapName, change.index)
+		}
+	}
+	return plug, slot, nil
+}
+
+func (b *batchBuilder) connect(op *batchOperation) *apiError {
+	plug, slot, rspe := b.connectionRefs(op)
+	if rspe != nil {
+		return rspe
+	}
+	_, plugChanged := b.changedBy(plug.Snap)
+	_, slotChanged := b.changedBy(slot.Snap)
+	if plugChanged || slotChanged {
+		// the plug and slot are only known once the earlier
+		// operations are done
+		summary := fmt.Sprintf("Connect %s:%s to %s:%s", plug.Snap, plug.Name, slot.Snap, slot.Name)
+		var affected []string
+		for _, snapName := range []string{plug.Snap, slot.Snap} {
+			if snapName != "" {
+				affected = append(affected, snapName)
+			}
+		}
+		ts := ifacestate.DeferredConnect(b.st, plug.Snap, plug.Name, slot.Snap, slot.Name)
+		b.add(summary, affected, []*state.TaskSet{ts})
+		return nil
+	}
+	connRef, err := b.repo.ResolveConnect(plug.Snap, plug.Name, slot.Snap, slot.Name)
+	if err != nil {
+		return BadRequest("%v", err)
+	}
+	affected := snapNamesFromConns([]*interfaces.ConnRef{connRef})
+	summary := fmt.Sprintf("Connect %s:%s to %s:%s", connRef.PlugRef.Snap, connRef.PlugRef.Name, connRef.SlotRef.Snap, connRef.SlotRef.Name)
+	ts, err := ifacestate.Connect(b.st, connRef.PlugRef.Snap, connRef.PlugRef.Name, connRef.SlotRef.Snap, connRef.SlotRef.Name)
+	if _, ok := err.(*ifacestate.ErrAlreadyConnected); ok {
+		b.add(summary, affected, nil)
+		return nil
+	}
+	if err != nil {
+		return errToResponse(err, affected, BadRequest, "%v")
+	}
+	b.add(summary, affected, []*state.TaskSet{ts})
+	return nil
+}
+
+func (b *batchBuilder) disconnect(op *batchOperation) *apiError {
+	plug, slot, rspe := b.connectionRefs(op)
+	if rspe != nil {
+		return rspe
+	}
+	conns, err := b.ifaces.ResolveDisconnect(plug.Snap, plug.Name, slot.Snap, slot.Name, false)
+	if err != nil {
+		return BadRequest("%v", err)
+	}
+	affected := snapNamesFromConns(conns)
+	summary := fmt.Sprintf("Disconnect %s:%s from %s:%s", plug.Snap, plug.Name, slot.Snap, slot.Name)
+	var tasksets []*state.TaskSet
+	for _, connRef := range conns {
+		conn, err := b.repo.Connection(connRef)
+		if err != nil {
+			return BadRequest("%v", err)
+		}
+		ts, err := ifacestate.Disconnect(b.st, conn)
+		if err != nil {
+			return errToResponse(err, affected, BadRequest, "%v")
+		}
+		tasksets = append(tasksets, ts)
+	}
+	b.add(summary, affected, tasksets)
+	return nil
+}
+
+func (b *batchBuilder) configure(op *batchOperation) *apiError {
+	if op.Snap == "" {
+		return BadRequest("snap name not specified")
+	}
+	if len(op.Values) == 0 {
+		return BadRequest("no configuration values provided")
+	}
+	if op.Plug != nil || op.Slot != nil {
+		return BadRequest("unsupported option provided for %s", op.Action)
+	}
+	snapName := configstate.RemapSnapFromRequest(op.Snap)
+	change, changed := b.changedBy(snapName)
+	if changed && change.action == "remove" {
+		return BadRequest("cannot set configuration of snap %q removed by operation %d of the batch", snapName, change.index)
+	}
+
+	var ts *state.TaskSet
+	var err error
+	if changed && change.action == "install" {
+		// the checks of ConfigureInstalled cannot pass yet, the
+		// configuration is applied once the install is done
+		ts = configstate.Configure(b.st, snapName, op.Values, 0)
+	} else {
+		ts, err = configstate.ConfigureInstalled(b.st, snapName, op.Values, 0)
+	}
+	if err != nil {
+		if _, ok := err.(*snap.NotInstalledError); ok {
+			return SnapNotFound(snapName, err)
+		}
+		return errToResponse(err, []string{snapName}, InternalError, "%v")
+	}
+	summary := fmt.Sprintf("Change configuration of %q snap", snapName)
+	b.add(summary, []string{snapName}, []*state.TaskSet{ts})
+	return nil
+}
+
+// postBatch performs an ordered list of heterogeneous operations as a
+// single change, each operation waiting for the previous one to be
+// done. All operations are checked for conflicts against the current
+// state when the batch is submitted, and the change is only created
+// once the tasks of all operations could be built.
+func postBatch(c *Command, r *http.Request, user *auth.UserState) Response {
+	var batch batchInstruction
+	if err := jsonutil.DecodeWithNumber(r.Body, &batch); err != nil {
+		return BadRequest("cannot decode request body into batch: %v", err)
+	}
+	if len(batch.Operations) == 0 {
+		return BadRequest("batch contains no operations")
+	}
+
+	st := c.d.overlord.State()
+	st.Lock()
+	defer st.Unlock()
+
+	ifaces := c.d.overlord.InterfaceManager()
+	b := &batchBuilder{
+		st:       st,
+		repo:     ifaces.Repository(),
+		ifaces:   ifaces,
+		ctx:      r.Context(),
+		changed:  make(map[string]batchSnapChange),
+		affected: make(map[string]bool),
+	}
+	if user != nil {
+		b.userID = user.ID
+	}
+
+	for i, op := range batch.Operations {
+		b.index = i + 1
+		if op == nil {
+			b.discard()
+			return BadRequest("batch operation %d is empty", i+1)
+		}
+		var rspe *apiError
+		switch {
+		case batchSnapActions[op.Action]:
+			rspe = b.snapOp(op)
+		case op.Action == "connect":
+			rspe = b.connect(op)
+		case op.Action == "disconnect":
+			rspe = b.disconnect(op)
+		case op.Action == "set":
+			rspe = b.configure(op)
+		case op.Action == "":
+			rspe = BadRequest("action not specified")
+		default:
+			rspe = BadRequest("unknown action %q", op.Action)
+		}
+		if rspe != nil {
+			b.discard()
+			rspe.Message = fmt.Sprintf("batch operation %d: %s", i+1, rspe.Message)
+			return rspe
+		}
+	}
+
+	summary := b.summaries[0]
+	if len(b.summaries) > 1 {
+		summary = fmt.Sprintf(i18n.G("Perform batch of %d operations"), len(b.summaries))
+	}
+	affected := b.affectedSnaps()
+
+	var chg *state.Change
+	if len(b.tasksets) == 0 {
+		chg = st.NewChange("batch", summary)
+		chg.SetStatus(state.DoneStatus)
+	} else {
+		chg = newChange(st, "batch", summary, b.tasksets, affected)
+		ensureStateSoon(st)
+	}
+	chg.Set("api-data", map[string]interface{}{
+		"snap-names": affected,
+		"summaries":  b.summaries,
+	})
+	chg.Set("initiator", changeInitiator(r, user))
+
+	return AsyncResponse(nil, chg.ID())
+}
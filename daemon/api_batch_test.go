@@ -0,0 +1,236 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package daemon_test
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/client"
+	"github.com/snapcore/snapd/daemon"
+	"github.com/snapcore/snapd/interfaces"
+	"github.com/snapcore/snapd/interfaces/builtin"
+	"github.com/snapcore/snapd/interfaces/ifacetest"
+	"github.com/snapcore/snapd/overlord/snapstate"
+	"github.com/snapcore/snapd/overlord/state"
+)
+
+var _ = check.Suite(&batchSuite{})
+
+type batchSuite struct {
+	apiBaseSuite
+}
+
+func (s *batchSuite) SetUpTest(c *check.C) {
+	s.apiBaseSuite.SetUpTest(c)
+
+	s.expectWriteAccess(daemon.AuthenticatedAccess{Polkit: "io.snapcraft.snapd.manage", Policy: daemon.AccessPolicyScopeBatch})
+
+	_, restore := daemon.MockEnsureStateSoon(func(*state.State) {})
+	s.AddCleanup(restore)
+
+	s.AddCleanup(builtin.MockInterface(&ifacetest.TestInterface{InterfaceName: "test"}))
+	s.AddCleanup(daemon.MockSnapstateInstall(func(ctx context.Context, st *state.State, name string, opts *snapstate.RevisionOptions, userID int, flags snapstate.Flags) (*state.TaskSet, error) {
+		t := st.NewTask("fake-install-snap", "Install "+name+" from "+opts.Channel)
+		return state.NewTaskSet(t), nil
+	}))
+	s.AddCleanup(daemon.MockSnapstateSwitch(func(st *state.State, name string, opts *snapstate.RevisionOptions) (*state.TaskSet, error) {
+		t := st.NewTask("fake-switch-snap", "Switch "+name+" to cohort "+opts.CohortKey)
+		return state.NewTaskSet(t), nil
+	}))
+}
+
+func (s *batchSuite) postBatch(body string) *http.Request {
+	req, err := http.NewRequest("POST", "/v2/batch", strings.NewReader(body))
+	if err != nil {
+		panic(err)
+	}
+	return req
+}
+
+func (s *batchSuite) TestBatch(c *check.C) {
+	d := s.daemon(c)
+	s.mockSnap(c, consumerYaml)
+	s.mockSnap(c, producerYaml)
+
+	req := s.postBatch(`{"operations": [
+		{"action": "install", "snap": "foo", "channel": "beta"},
+		{"action": "set", "snap": "foo", "values": {"key": 42}},
+		{"action": "switch", "snap": "bar", "cohort-key": "some-cohort"},
+		{"action": "connect", "plug": {"snap": "consumer", "plug": "plug"}, "slot": {"snap": "producer", "slot": "slot"}}
+	]}`)
+	req.RemoteAddr = "pid=100;uid=1000;socket=;"
+	rsp := s.asyncReq(c, req, nil)
+
+	st := d.Overlord().State()
+	st.Lock()
+	defer st.Unlock()
+	chg := st.Change(rsp.Change)
+	c.Assert(chg, check.NotNil)
+	c.Check(chg.Kind(), check.Equals, "batch")
+	c.Check(chg.Summary(), check.Equals, "Perform batch of 4 operations")
+	// the connection and configuration histories record who asked for
+	// the batch
+	var initiator string
+	c.Assert(chg.Get("initiator", &initiator), check.IsNil)
+	c.Check(initiator, check.Equals, "uid:1000")
+
+	var apiData map[string]interface{}
+	c.Assert(chg.Get("api-data", &apiData), check.IsNil)
+	c.Check(apiData, check.DeepEquals, map[string]interface{}{
+		"snap-names": []interface{}{"bar", "consumer", "foo", "producer"},
+		"summaries": []interface{}{
+			`Install "foo" snap from "beta" channel`,
+			`Change configuration of "foo" snap`,
+			`Switch "bar" snap to cohort "…me-cohort"`,
+			"Connect consumer:plug to producer:slot",
+		},
+	})
+
+	byKind := make(map[string]*state.Task)
+	for _, t := range chg.Tasks() {
+		byKind[t.Kind()] = t
+	}
+	install := byKind["fake-install-snap"]
+	configure := byKind["run-hook"]
+	switchSnap := byKind["fake-switch-snap"]
+	connect := byKind["connect"]
+	c.Assert(install, check.NotNil)
+	c.Assert(configure, check.NotNil)
+	c.Assert(switchSnap, check.NotNil)
+	c.Assert(connect, check.NotNil)
+
+	c.Check(install.Summary(), check.Equals, "Install foo from beta")
+	c.Check(configure.WaitTasks(), check.DeepEquals, []*state.Task{install})
+	c.Check(switchSnap.WaitTasks(), check.DeepEquals, []*state.Task{configure})
+	// the connect task set starts with the connect task itself
+	c.Check(connect.WaitTasks(), check.DeepEquals, []*state.Task{switchSnap})
+}
+
+func (s *batchSuite) TestBatchAlreadyConnected(c *check.C) {
+	d := s.daemon(c)
+	s.mockSnap(c, consumerYaml)
+	s.mockSnap(c, producerYaml)
+
+	st := d.Overlord().State()
+	st.Lock()
+	st.Set("conns", map[string]interface{}{
+		"consumer:plug producer:slot": map[string]interface{}{"interface": "test"},
+	})
+	st.Unlock()
+
+	req := s.postBatch(`{"operations": [
+		{"action": "connect", "plug": {"snap": "consumer", "plug": "plug"}, "slot": {"snap": "producer", "slot": "slot"}}
+	]}`)
+	rsp := s.asyncReq(c, req, nil)
+
+	st.Lock()
+	defer st.Unlock()
+	chg := st.Change(rsp.Change)
+	c.Assert(chg, check.NotNil)
+	c.Check(chg.Summary(), check.Equals, "Connect consumer:plug to producer:slot")
+	c.Check(chg.Tasks(), check.HasLen, 0)
+	c.Check(chg.Status(), check.Equals, state.DoneStatus)
+}
+
+func (s *batchSuite) TestBatchErrors(c *check.C) {
+	d := s.daemon(c)
+	s.mockSnap(c, consumerYaml)
+	s.mockSnap(c, producerYaml)
+	st := d.Overlord().State()
+
+	for _, t := range []struct {
+		body   string
+		status int
+		kind   client.ErrorKind
+		err    string
+	}{
+		{`{}`, 400, "", `batch contains no operations`},
+		{`{"operations": [{"action": "frobnicate"}]}`, 400, "", `batch operation 1: unknown action "frobnicate"`},
+		{`{"operations": [{"snap": "foo"}]}`, 400, "", `batch operation 1: action not specified`},
+		{`{"operations": [{"action": "install"}]}`, 400, "", `batch operation 1: snap name not specified`},
+		{`{"operations": [{"action": "install", "snap": "foo"}, {"action": "install", "snap": "ubuntu-core"}]}`, 400, "", `batch operation 2: cannot install "ubuntu-core", please use "core" instead`},
+		{`{"operations": [{"action": "remove", "snap": "foo", "values": {"a": 1}}]}`, 400, "", `batch operation 1: unsupported option provided for remove`},
+		{`{"operations": [{"action": "remove", "snap": "foo"}]}`, 400, client.ErrorKindSnapNotInstalled, `batch operation 1: snap "foo" is not installed`},
+		{`{"operations": [{"action": "set", "snap": "foo"}]}`, 400, "", `batch operation 1: no configuration values provided`},
+		{`{"operations": [{"action": "set", "snap": "foo", "values": {"a": 1}}]}`, 404, client.ErrorKindSnapNotFound, `batch operation 1: snap "foo" is not installed`},
+		{`{"operations": [{"action": "connect", "plug": {"snap": "consumer", "plug": "plug"}}]}`, 400, "", `batch operation 1: connect requires a plug and a slot`},
+		// operations planned against the state left by earlier ones
+		{`{"operations": [{"action": "switch", "snap": "consumer"}, {"action": "revert", "snap": "consumer"}]}`, 400, "", `batch operation 2: cannot revert snap "consumer" changed by operation 1 of the batch`},
+		{`{"operations": [{"action": "remove", "snap": "consumer"}, {"action": "install", "snap": "consumer"}]}`, 400, "", `batch operation 2: cannot install snap "consumer" changed by operation 1 of the batch`},
+		{`{"operations": [{"action": "remove", "snap": "consumer"}, {"action": "set", "snap": "consumer", "values": {"a": 1}}]}`, 400, "", `batch operation 2: cannot set configuration of snap "consumer" removed by operation 1 of the batch`},
+		{`{"operations": [{"action": "remove", "snap": "producer"}, {"action": "connect", "plug": {"snap": "consumer", "plug": "plug"}, "slot": {"snap": "producer", "slot": "slot"}}]}`, 400, "", `batch operation 2: cannot connect snap "producer" removed by operation 1 of the batch`},
+		{`{"operations": [{"action": "install", "snap": "foo"}, {"action": "disconnect", "plug": {"snap": "foo", "plug": "plug"}, "slot": {"snap": "producer", "slot": "slot"}}]}`, 400, "", `batch operation 2: cannot disconnect snap "foo" installed by operation 1 of the batch`},
+		{`{"operations": [{"action": "disconnect", "plug": {"snap": "consumer", "plug": "plug"}, "slot": {"snap": "producer", "slot": "slot"}}]}`, 400, "", `batch operation 1: cannot disconnect consumer:plug from producer:slot, it is not connected`},
+	} {
+		st.Lock()
+		taskCount := st.TaskCount()
+		st.Unlock()
+
+		rspe := s.errorReq(c, s.postBatch(t.body), nil)
+		c.Check(rspe.Status, check.Equals, t.status, check.Commentf(t.body))
+		c.Check(rspe.Kind, check.Equals, t.kind, check.Commentf(t.body))
+		c.Check(rspe.Message, check.Matches, t.err, check.Commentf(t.body))
+
+		// the tasks of the operations before the failing one are
+		// discarded
+		st.Lock()
+		c.Check(st.TaskCount(), check.Equals, taskCount, check.Commentf(t.body))
+		c.Check(st.Changes(), check.HasLen, 0)
+		st.Unlock()
+	}
+}
+
+func (s *batchSuite) TestBatchConnectChangedSnaps(c *check.C) {
+	d := s.daemon(c)
+	s.mockSnap(c, producerYaml)
+
+	req := s.postBatch(`{"operations": [
+		{"action": "install", "snap": "consumer"},
+		{"action": "connect", "plug": {"snap": "consumer", "plug": "plug"}, "slot": {"snap": "producer", "slot": "slot"}}
+	]}`)
+	rsp := s.asyncReq(c, req, nil)
+
+	st := d.Overlord().State()
+	st.Lock()
+	defer st.Unlock()
+	chg := st.Change(rsp.Change)
+	c.Assert(chg, check.NotNil)
+
+	var apiData map[string]interface{}
+	c.Assert(chg.Get("api-data", &apiData), check.IsNil)
+	c.Check(apiData["snap-names"], check.DeepEquals, []interface{}{"consumer", "producer"})
+
+	// the connection is planned once the snap is installed
+	c.Assert(chg.Tasks(), check.HasLen, 2)
+	install, connect := chg.Tasks()[0], chg.Tasks()[1]
+	c.Check(install.Kind(), check.Equals, "fake-install-snap")
+	c.Check(connect.Kind(), check.Equals, "deferred-connect")
+	c.Check(connect.WaitTasks(), check.DeepEquals, []*state.Task{install})
+	var plug interfaces.PlugRef
+	var slot interfaces.SlotRef
+	c.Assert(connect.Get("plug", &plug), check.IsNil)
+	c.Assert(connect.Get("slot", &slot), check.IsNil)
+	c.Check(plug, check.Equals, interfaces.PlugRef{Snap: "consumer", Name: "plug"})
+	c.Check(slot, check.Equals, interfaces.SlotRef{Snap: "producer", Name: "slot"})
+}
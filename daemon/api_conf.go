@@ -0,0 +1,171 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package daemon
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+
+	"github.com/snapcore/snapd/i18n"
+	"github.com/snapcore/snapd/jsonutil"
+	"github.com/snapcore/snapd/overlord/auth"
+	"github.com/snapcore/snapd/overlord/configstate"
+	"github.com/snapcore/snapd/overlord/configstate/config"
+	"github.com/snapcore/snapd/overlord/configstate/configcore"
+	"github.com/snapcore/snapd/overlord/snapstate"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/strutil"
+)
+
+var confCmd = &Command{
+	Path:        "/v2/conf",
+	GET:         getConf,
+	PUT:         replaceConf,
+	ReadAccess:  authenticatedAccess{},
+	WriteAccess: authenticatedAccess{},
+}
+
+// getConf returns the whole stored configuration of the given snaps, or
+// of all the configured snaps and the system if none are given. The
+// options snapd keeps in the system configuration for its own use are
+// left out.
+func getConf(c *Command, r *http.Request, user *auth.UserState) Response {
+	names := strutil.CommaSeparatedList(r.URL.Query().Get("snaps"))
+
+	st := c.d.overlord.State()
+	st.Lock()
+	defer st.Unlock()
+
+	if len(names) == 0 {
+		configured, err := config.ConfiguredSnaps(st)
+		if err != nil {
+			return InternalError("%v", err)
+		}
+		names = append(names, "system")
+		for _, name := range configured {
+			if name != "core" {
+				names = append(names, name)
+			}
+		}
+	}
+
+	confs := make(map[string]interface{}, len(names))
+	for _, name := range names {
+		snapName := configstate.RemapSnapFromRequest(name)
+		if snapName != "core" {
+			var snapst snapstate.SnapState
+			if err := snapstate.Get(st, snapName, &snapst); err != nil {
+				if err == state.ErrNoState {
+					return SnapNotFound(snapName, &snap.NotInstalledError{Snap: snapName})
+				}
+				return InternalError("%v", err)
+			}
+		}
+		cfg, err := config.GetSnapConfig(st, snapName)
+		if err != nil {
+			return InternalError("%v", err)
+		}
+		if cfg == nil {
+			empty := json.RawMessage("{}")
+			cfg = &empty
+		}
+		if snapName == "core" {
+			// only export what can be imported back
+			var conf map[string]interface{}
+			if err := jsonutil.DecodeWithNumber(bytes.NewReader(*cfg), &conf); err != nil {
+				return InternalError("cannot unmarshal system configuration: %v", err)
+			}
+			confs["system"] = configcore.WithoutInternalOptions(conf)
+			continue
+		}
+		confs[configstate.RemapSnapToResponse(snapName)] = cfg
+	}
+
+	return SyncResponse(confs)
+}
+
+// replaceConf replaces the whole configuration of the given snaps, running
+// the configure hook of each of them once in a single change. If a hook
+// fails, the configure hooks of the snaps already configured run again to
+// restore their previous configuration.
+func replaceConf(c *Command, r *http.Request, user *auth.UserState) Response {
+	var confs map[string]map[string]interface{}
+	if err := jsonutil.DecodeWithNumber(r.Body, &confs); err != nil {
+		return BadRequest("cannot decode request body into configurations: %v", err)
+	}
+	if len(confs) == 0 {
+		return BadRequest("no configuration to replace")
+	}
+
+	names := make([]string, 0, len(confs))
+	for name := range confs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	st := c.d.overlord.State()
+	st.Lock()
+	defer st.Unlock()
+
+	var tss []*state.TaskSet
+	var snapNames []string
+	for _, name := range names {
+		snapName := configstate.RemapSnapFromRequest(name)
+		ts, err := configstate.ReplaceConfig(st, snapName, confs[name])
+		if err != nil {
+			if _, ok := err.(*snap.NotInstalledError); ok {
+				return SnapNotFound(snapName, err)
+			}
+			return errToResponse(err, []string{snapName}, BadRequest, "%v")
+		}
+		if ts == nil {
+			continue
+		}
+		// the hooks run one after the other, so that the
+		// configuration of all the snaps is restored when one fails
+		if len(tss) > 0 {
+			ts.WaitAll(tss[len(tss)-1])
+		}
+		tss = append(tss, ts)
+		snapNames = append(snapNames, snapName)
+	}
+
+	var summary string
+	if len(names) == 1 {
+		summary = fmt.Sprintf(i18n.G("Replace configuration of %q snap"), names[0])
+	} else {
+		summary = fmt.Sprintf(i18n.G("Replace configuration of snaps %s"), strutil.Quoted(names))
+	}
+	var chg *state.Change
+	if len(tss) == 0 {
+		chg = st.NewChange("replace-config", summary)
+		chg.SetStatus(state.DoneStatus)
+	} else {
+		chg = newChange(st, "replace-config", summary, tss, snapNames)
+		ensureStateSoon(st)
+	}
+	chg.Set("initiator", changeInitiator(r, user))
+
+	return AsyncResponse(nil, chg.ID())
+}
@@ -0,0 +1,256 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package daemon_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/overlord/configstate/config"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/testutil"
+)
+
+var _ = check.Suite(&confSuite{})
+
+type confSuite struct {
+	apiBaseSuite
+}
+
+func (s *confSuite) SetUpTest(c *check.C) {
+	s.apiBaseSuite.SetUpTest(c)
+
+	s.expectAuthenticatedAccess()
+}
+
+func (s *confSuite) setConfig(c *check.C, st *state.State, snapName string, cfg map[string]interface{}) {
+	st.Lock()
+	defer st.Unlock()
+	tr := config.NewTransaction(st)
+	c.Assert(config.Patch(tr, snapName, cfg), check.IsNil)
+	tr.Commit()
+}
+
+func (s *confSuite) TestGetConfAll(c *check.C) {
+	d := s.daemon(c)
+	s.mockSnap(c, configYaml)
+	s.mockSnap(c, "name: other-snap\nversion: 1")
+	s.setConfig(c, d.Overlord().State(), "config-snap", map[string]interface{}{"key": "value", "nested": map[string]interface{}{"a": 1}})
+
+	req, err := http.NewRequest("GET", "/v2/conf", nil)
+	c.Assert(err, check.IsNil)
+	rsp := s.syncReq(c, req, nil)
+	c.Check(rsp.Status, check.Equals, 200)
+	// the system is always part of the export, snaps only if they
+	// have some configuration
+	confs := rsp.Result.(map[string]interface{})
+	c.Check(confs, check.HasLen, 2)
+	c.Check(confs["system"], check.DeepEquals, map[string]interface{}{})
+	c.Check(string(*confs["config-snap"].(*json.RawMessage)), check.Equals, `{"key":"value","nested":{"a":1}}`)
+}
+
+func (s *confSuite) TestGetConfSnaps(c *check.C) {
+	d := s.daemon(c)
+	s.mockSnap(c, configYaml)
+	s.mockSnap(c, "name: other-snap\nversion: 1")
+	s.setConfig(c, d.Overlord().State(), "core", map[string]interface{}{"service.ssh.disable": true, "seed.loaded": true})
+
+	req, err := http.NewRequest("GET", "/v2/conf?snaps=other-snap,system", nil)
+	c.Assert(err, check.IsNil)
+	rsp := s.syncReq(c, req, nil)
+	confs := rsp.Result.(map[string]interface{})
+	c.Check(confs, check.HasLen, 2)
+	c.Check(string(*confs["other-snap"].(*json.RawMessage)), check.Equals, `{}`)
+	// the options snapd keeps for its own use are left out
+	c.Check(confs["system"], check.DeepEquals, map[string]interface{}{
+		"service": map[string]interface{}{"ssh": map[string]interface{}{"disable": true}},
+	})
+
+	req, err = http.NewRequest("GET", "/v2/conf?snaps=missing-snap", nil)
+	c.Assert(err, check.IsNil)
+	rspe := s.errorReq(c, req, nil)
+	c.Check(rspe.Status, check.Equals, 404)
+	c.Check(rspe.Message, check.Equals, `snap "missing-snap" is not installed`)
+}
+
+func (s *confSuite) TestReplaceConf(c *check.C) {
+	d := s.daemon(c)
+	s.mockSnap(c, configYaml)
+	s.mockSnap(c, `
+name: other-snap
+version: 1
+hooks:
+    configure:
+`)
+	s.mockSnap(c, "name: same-snap\nversion: 1")
+	st := d.Overlord().State()
+	s.setConfig(c, st, "config-snap", map[string]interface{}{"key": "value", "old": true})
+	s.setConfig(c, st, "same-snap", map[string]interface{}{"key": "value"})
+
+	// Mock the hook runner
+	hookRunner := testutil.MockCommand(c, "snap", "")
+	defer hookRunner.Restore()
+
+	d.Overlord().Loop()
+	defer d.Overlord().Stop()
+
+	body := `{
+"config-snap": {"key": "new", "nested": {"a": 1}},
+"other-snap": {"key": "value"},
+"same-snap": {"key": "value"}
+}`
+	req, err := http.NewRequest("PUT", "/v2/conf", strings.NewReader(body))
+	c.Assert(err, check.IsNil)
+	rsp := s.asyncReq(c, req, nil)
+
+	st.Lock()
+	chg := st.Change(rsp.Change)
+	c.Assert(chg, check.NotNil)
+	c.Check(chg.Kind(), check.Equals, "replace-config")
+	c.Check(chg.Summary(), check.Equals, `Replace configuration of snaps "config-snap", "other-snap", "same-snap"`)
+	// one configure hook per snap whose configuration changes
+	tasks := chg.Tasks()
+	c.Assert(tasks, check.HasLen, 2)
+	var hookContext map[string]interface{}
+	c.Assert(tasks[0].Get("hook-context", &hookContext), check.IsNil)
+	c.Check(hookContext["patch"], check.DeepEquals, map[string]interface{}{
+		"key":    "new",
+		"nested": map[string]interface{}{"a": 1.},
+		"old":    nil,
+	})
+	c.Check(tasks[1].WaitTasks(), check.DeepEquals, []*state.Task{tasks[0]})
+	st.Unlock()
+
+	<-chg.Ready()
+
+	st.Lock()
+	defer st.Unlock()
+	c.Assert(chg.Err(), check.IsNil)
+	cfg, err := config.GetSnapConfig(st, "config-snap")
+	c.Assert(err, check.IsNil)
+	c.Check(string(*cfg), check.Equals, `{"key":"new","nested":{"a":1}}`)
+	cfg, err = config.GetSnapConfig(st, "other-snap")
+	c.Assert(err, check.IsNil)
+	c.Check(string(*cfg), check.Equals, `{"key":"value"}`)
+
+	c.Check(hookRunner.Calls(), check.DeepEquals, [][]string{
+		{"snap", "run", "--hook", "configure", "-r", "unset", "config-snap"},
+		{"snap", "run", "--hook", "configure", "-r", "unset", "other-snap"},
+	})
+}
+
+func (s *confSuite) TestReplaceConfUndo(c *check.C) {
+	d := s.daemon(c)
+	s.mockSnap(c, configYaml)
+	s.mockSnap(c, `
+name: other-snap
+version: 1
+hooks:
+    configure:
+`)
+	st := d.Overlord().State()
+	s.setConfig(c, st, "config-snap", map[string]interface{}{"key": "value", "old": true})
+
+	// the configure hook of other-snap fails
+	hookRunner := testutil.MockCommand(c, "snap", `if [ "$6" = other-snap ]; then exit 1; fi`)
+	defer hookRunner.Restore()
+
+	d.Overlord().Loop()
+	defer d.Overlord().Stop()
+
+	body := `{"config-snap": {"key": "new"}, "other-snap": {"key": "value"}}`
+	req, err := http.NewRequest("PUT", "/v2/conf", strings.NewReader(body))
+	c.Assert(err, check.IsNil)
+	rsp := s.asyncReq(c, req, nil)
+
+	st.Lock()
+	chg := st.Change(rsp.Change)
+	c.Assert(chg, check.NotNil)
+	st.Unlock()
+
+	<-chg.Ready()
+
+	st.Lock()
+	defer st.Unlock()
+	c.Check(chg.Err(), check.ErrorMatches, `(?s).*run hook "configure".*`)
+	// the configuration of both snaps is the one they had before
+	cfg, err := config.GetSnapConfig(st, "config-snap")
+	c.Assert(err, check.IsNil)
+	c.Check(string(*cfg), check.Equals, `{"key":"value","old":true}`)
+	cfg, err = config.GetSnapConfig(st, "other-snap")
+	c.Assert(err, check.IsNil)
+	c.Check(cfg, check.IsNil)
+
+	// the configure hook of config-snap ran again to restore it
+	c.Check(hookRunner.Calls(), check.DeepEquals, [][]string{
+		{"snap", "run", "--hook", "configure", "-r", "unset", "config-snap"},
+		{"snap", "run", "--hook", "configure", "-r", "unset", "other-snap"},
+		{"snap", "run", "--hook", "configure", "-r", "unset", "config-snap"},
+	})
+}
+
+func (s *confSuite) TestReplaceConfNothingToDo(c *check.C) {
+	d := s.daemon(c)
+	s.mockSnap(c, configYaml)
+	st := d.Overlord().State()
+	s.setConfig(c, st, "config-snap", map[string]interface{}{"key": "value"})
+
+	req, err := http.NewRequest("PUT", "/v2/conf", strings.NewReader(`{"config-snap": {"key": "value"}}`))
+	c.Assert(err, check.IsNil)
+	rsp := s.asyncReq(c, req, nil)
+
+	st.Lock()
+	defer st.Unlock()
+	chg := st.Change(rsp.Change)
+	c.Assert(chg, check.NotNil)
+	c.Check(chg.Summary(), check.Equals, `Replace configuration of "config-snap" snap`)
+	c.Check(chg.Status(), check.Equals, state.DoneStatus)
+	c.Check(chg.Tasks(), check.HasLen, 0)
+}
+
+func (s *confSuite) TestReplaceConfErrors(c *check.C) {
+	d := s.daemon(c)
+	s.mockSnap(c, configYaml)
+
+	for _, tc := range []struct {
+		body    string
+		status  int
+		message string
+	}{
+		{`}`, 400, `cannot decode request body into configurations: .*`},
+		{`{}`, 400, `no configuration to replace`},
+		{`{"config-snap": {"key": "value"}, "missing-snap": {}}`, 404, `snap "missing-snap" is not installed`},
+	} {
+		req, err := http.NewRequest("PUT", "/v2/conf", strings.NewReader(tc.body))
+		c.Assert(err, check.IsNil)
+		rspe := s.errorReq(c, req, nil)
+		c.Check(rspe.Status, check.Equals, tc.status, check.Commentf(tc.body))
+		c.Check(rspe.Message, check.Matches, tc.message, check.Commentf(tc.body))
+	}
+
+	// nothing was changed
+	st := d.Overlord().State()
+	st.Lock()
+	defer st.Unlock()
+	c.Check(st.Changes(), check.HasLen, 0)
+}
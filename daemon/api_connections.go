@@ -23,6 +23,7 @@ import (
 	"net/http"
 	"sort"
 
+	"github.com/snapcore/snapd/client"
 	"github.com/snapcore/snapd/interfaces"
 	"github.com/snapcore/snapd/overlord/auth"
 	"github.com/snapcore/snapd/overlord/ifacestate"
@@ -257,12 +258,16 @@ func getConnections(c *Command, r *http.Request, user *auth.UserState) Response
 	snapName := query.Get("snap")
 	ifaceName := query.Get("interface")
 	qselect := query.Get("select")
-	if qselect != "all" && qselect != "" {
+	if qselect != "all" && qselect != "history" && qselect != "" {
 		return BadRequest("unsupported select qualifier")
 	}
 	onlyConnected := qselect == ""
 
 	snapName = ifacestate.RemapSnapFromRequest(snapName)
+	if qselect == "history" {
+		// the history covers snaps that were removed since
+		return getConnectionHistory(c, snapName, ifaceName)
+	}
 	if snapName != "" {
 		if err := checkSnapInstalled(c.d.overlord.State(), snapName); err != nil {
 			if err == state.ErrNoState {
@@ -285,3 +290,33 @@ func getConnections(c *Command, r *http.Request, user *auth.UserState) Response
 
 	return SyncResponse(connsjson)
 }
+
+func getConnectionHistory(c *Command, snapName, ifaceName string) Response {
+	st := c.d.overlord.State()
+	st.Lock()
+	defer st.Unlock()
+
+	history, err := ifacestate.ConnectionHistory(st)
+	if err != nil {
+		return InternalError("%v", err)
+	}
+	events := make([]client.ConnectionEvent, 0, len(history))
+	for _, event := range history {
+		if snapName != "" && event.Plug.Snap != snapName && event.Slot.Snap != snapName {
+			continue
+		}
+		if ifaceName != "" && event.Interface != ifaceName {
+			continue
+		}
+		events = append(events, client.ConnectionEvent{
+			Time:      event.Time,
+			Action:    event.Action,
+			Plug:      client.PlugRef{Snap: event.Plug.Snap, Name: event.Plug.Name},
+			Slot:      client.SlotRef{Snap: event.Slot.Snap, Name: event.Slot.Name},
+			Interface: event.Interface,
+			Initiator: event.Initiator,
+			Reason:    event.Reason,
+		})
+	}
+	return SyncResponse(events)
+}
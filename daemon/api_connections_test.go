@@ -1042,3 +1042,63 @@ slots:
 		"type":        "sync",
 	})
 }
+
+func (s *interfacesSuite) TestConnectionsHistory(c *check.C) {
+	d := s.daemon(c)
+
+	st := d.Overlord().State()
+	st.Lock()
+	st.Set("conns-history", []map[string]interface{}{{
+		"time":      "2021-02-03T09:41:18Z",
+		"action":    "connect",
+		"plug":      map[string]interface{}{"snap": "consumer", "plug": "plug"},
+		"slot":      map[string]interface{}{"snap": "producer", "slot": "slot"},
+		"interface": "test",
+		"initiator": "uid:1000",
+		"reason":    "Connect consumer:plug to producer:slot",
+	}, {
+		"time":      "2021-02-03T10:00:00Z",
+		"action":    "disconnect",
+		"plug":      map[string]interface{}{"snap": "other", "plug": "camera"},
+		"slot":      map[string]interface{}{"snap": "core", "slot": "camera"},
+		"interface": "camera",
+		"initiator": "hotplug",
+	}})
+	st.Unlock()
+
+	connectEvent := map[string]interface{}{
+		"time":      "2021-02-03T09:41:18Z",
+		"action":    "connect",
+		"plug":      map[string]interface{}{"snap": "consumer", "plug": "plug"},
+		"slot":      map[string]interface{}{"snap": "producer", "slot": "slot"},
+		"interface": "test",
+		"initiator": "uid:1000",
+		"reason":    "Connect consumer:plug to producer:slot",
+	}
+	disconnectEvent := map[string]interface{}{
+		"time":      "2021-02-03T10:00:00Z",
+		"action":    "disconnect",
+		"plug":      map[string]interface{}{"snap": "other", "plug": "camera"},
+		"slot":      map[string]interface{}{"snap": "core", "slot": "camera"},
+		"interface": "camera",
+		"initiator": "hotplug",
+	}
+	for _, t := range []struct {
+		query    string
+		expected []interface{}
+	}{
+		{"", []interface{}{connectEvent, disconnectEvent}},
+		// snaps that are not installed are fine
+		{"&snap=producer", []interface{}{connectEvent}},
+		{"&snap=core", []interface{}{disconnectEvent}},
+		{"&interface=camera", []interface{}{disconnectEvent}},
+		{"&snap=consumer&interface=camera", []interface{}{}},
+	} {
+		s.testConnections(c, "/v2/connections?select=history"+t.query, map[string]interface{}{
+			"result":      t.expected,
+			"status":      "OK",
+			"status-code": 200.0,
+			"type":        "sync",
+		})
+	}
+}
@@ -0,0 +1,303 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package daemon
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/snapcore/snapd/client"
+	"github.com/snapcore/snapd/interfaces"
+	"github.com/snapcore/snapd/interfaces/apparmor"
+	"github.com/snapcore/snapd/interfaces/seccomp"
+	"github.com/snapcore/snapd/logger"
+	"github.com/snapcore/snapd/overlord/auth"
+	"github.com/snapcore/snapd/overlord/snapstate"
+	"github.com/snapcore/snapd/sandbox/denials"
+	seccomp_compiler "github.com/snapcore/snapd/sandbox/seccomp"
+	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/snap/naming"
+	"github.com/snapcore/snapd/snapdtool"
+)
+
+var debugDenialsCmd = &Command{
+	Path:       "/v2/debug/denials",
+	GET:        getDenials,
+	ReadAccess: rootAccess{},
+}
+
+// maxDenials is the maximum number of denials returned, the most
+// recent ones being kept.
+const maxDenials = 100
+
+var denialsFromJournal = denials.FromJournal
+
+// seccompSyscallNames resolves the syscall numbers of seccomp denials
+// with the name table of the seccomp compiler, which knows the
+// syscalls of every architecture.
+var seccompSyscallNames = func(auditArch string, syscalls []int) (map[int]string, error) {
+	compiler, err := seccomp_compiler.NewCompiler(snapdtool.InternalToolPath)
+	if err != nil {
+		return nil, err
+	}
+	return compiler.SyscallNames(auditArch, syscalls)
+}
+
+// resolveSyscallNames returns copies of the given denials with the
+// names of the syscalls of the seccomp denials filled in where they
+// are known.
+func resolveSyscallNames(found []*denials.Denial) []*denials.Denial {
+	syscalls := make(map[string]map[int]bool)
+	for _, d := range found {
+		if d.Kind == denials.KindSeccomp && d.Arch != "" {
+			if syscalls[d.Arch] == nil {
+				syscalls[d.Arch] = make(map[int]bool)
+			}
+			syscalls[d.Arch][d.Syscall] = true
+		}
+	}
+	names := make(map[string]map[int]string, len(syscalls))
+	for arch, set := range syscalls {
+		nrs := make([]int, 0, len(set))
+		for nr := range set {
+			nrs = append(nrs, nr)
+		}
+		sort.Ints(nrs)
+		archNames, err := seccompSyscallNames(arch, nrs)
+		if err != nil {
+			logger.Noticef("cannot resolve the names of the denied syscalls: %v", err)
+			continue
+		}
+		names[arch] = archNames
+	}
+	resolved := make([]*denials.Denial, len(found))
+	for i, d := range found {
+		cp := *d
+		if d.Kind == denials.KindSeccomp {
+			cp.SyscallName = names[d.Arch][d.Syscall]
+		}
+		resolved[i] = &cp
+	}
+	return resolved
+}
+
+// denialOwner returns the snap, and the app or hook of the snap, a
+// denial was recorded for from the label of the denial. ok is false for
+// denials outside of snaps.
+func denialOwner(label string) (snapName, app, hook string, ok bool) {
+	if strings.HasPrefix(label, "snap-update-ns.") {
+		// the profile of snap-update-ns for the snap
+		return strings.TrimPrefix(label, "snap-update-ns."), "", "", true
+	}
+	tag, err := naming.ParseSecurityTag(label)
+	if err != nil {
+		return "", "", "", false
+	}
+	switch tag := tag.(type) {
+	case naming.AppSecurityTag:
+		app = tag.AppName()
+	case naming.HookSecurityTag:
+		hook = tag.HookName()
+	}
+	return tag.InstanceName(), app, hook, true
+}
+
+func getDenials(c *Command, r *http.Request, user *auth.UserState) Response {
+	snapName := r.URL.Query().Get("snap")
+	if snapName != "" {
+		if err := naming.ValidateInstance(snapName); err != nil {
+			return BadRequest("invalid snap name: %v", err)
+		}
+	}
+
+	// only the denials of the requested snaps are kept while reading the
+	// journal, so that the most recent of them are returned and no
+	// syscall names are resolved for denials that are dropped
+	found, err := denialsFromJournal(maxDenials, func(d *denials.Denial) bool {
+		owner, _, _, ok := denialOwner(d.Label)
+		return ok && (snapName == "" || owner == snapName)
+	})
+	if err != nil {
+		return InternalError("%v", err)
+	}
+	found = resolveSyscallNames(found)
+
+	st := c.d.overlord.State()
+	st.Lock()
+	defer st.Unlock()
+	repo := c.d.overlord.InterfaceManager().Repository()
+
+	result := make([]*client.Denial, 0, len(found))
+	suggesters := make(map[string]*denialSuggester)
+	for _, d := range found {
+		cd := &client.Denial{
+			Time:          d.Time,
+			Kind:          d.Kind,
+			Label:         d.Label,
+			PID:           d.PID,
+			Comm:          d.Comm,
+			Operation:     d.Operation,
+			Path:          d.Path,
+			RequestedMask: d.RequestedMask,
+			DeniedMask:    d.DeniedMask,
+			Capability:    d.Capability,
+			Syscall:       d.Syscall,
+			SyscallName:   d.SyscallName,
+			Arch:          d.Arch,
+		}
+		cd.Snap, cd.App, cd.Hook, _ = denialOwner(d.Label)
+
+		suggester, ok := suggesters[cd.Snap]
+		if !ok {
+			// the snap may have been removed since
+			info, err := snapstate.CurrentInfo(st, cd.Snap)
+			if err == nil {
+				suggester = newDenialSuggester(repo, info)
+			}
+			suggesters[cd.Snap] = suggester
+		}
+		if suggester != nil {
+			cd.Suggestions = suggester.suggest(d)
+		}
+		result = append(result, cd)
+	}
+	return SyncResponse(result)
+}
+
+type interfaceGrant struct {
+	iface       string
+	plug        string
+	connected   bool
+	spec        *apparmor.Specification
+	seccompSpec *seccomp.Specification
+}
+
+// denialSuggester finds the interfaces that would grant the accesses
+// denied to a snap.
+type denialSuggester struct {
+	vars   map[string]string
+	grants []*interfaceGrant
+}
+
+// newDenialSuggester computes the AppArmor and seccomp rules that the snap would
+// get from each interface with a slot on the system, using the plug of
+// the snap for the interface if it has one or a plug bound to all its
+// apps and hooks otherwise.
+func newDenialSuggester(repo *interfaces.Repository, info *snap.Info) *denialSuggester {
+	s := &denialSuggester{
+		vars: map[string]string{
+			"SNAP_NAME":          info.SnapName(),
+			"SNAP_INSTANCE_NAME": info.InstanceName(),
+			"SNAP_REVISION":      info.Revision.String(),
+		},
+	}
+	plugs := repo.Plugs(info.InstanceName())
+	for _, iface := range repo.AllInterfaces() {
+		slots := repo.AllSlots(iface.Name())
+		if len(slots) == 0 {
+			continue
+		}
+		grant := &interfaceGrant{iface: iface.Name()}
+		var plugInfo *snap.PlugInfo
+		slotInfo := slots[0]
+		for _, plug := range plugs {
+			if plug.Interface != iface.Name() {
+				continue
+			}
+			plugInfo = plug
+			conns, _ := repo.Connected(info.InstanceName(), plug.Name)
+			if len(conns) > 0 {
+				if slot := repo.Slot(conns[0].SlotRef.Snap, conns[0].SlotRef.Name); slot != nil {
+					slotInfo = slot
+				}
+				grant.connected = true
+				break
+			}
+		}
+		if plugInfo != nil {
+			grant.plug = plugInfo.Name
+		} else {
+			plugInfo = &snap.PlugInfo{
+				Snap:      info,
+				Name:      iface.Name(),
+				Interface: iface.Name(),
+				Attrs:     make(map[string]interface{}),
+				Apps:      info.Apps,
+				Hooks:     info.Hooks,
+			}
+			if err := interfaces.BeforePreparePlug(iface, plugInfo); err != nil {
+				continue
+			}
+		}
+		plug := interfaces.NewConnectedPlug(plugInfo, nil, nil)
+		slot := interfaces.NewConnectedSlot(slotInfo, nil, nil)
+		spec := &apparmor.Specification{}
+		if err := spec.AddConnectedPlug(iface, plug, slot); err != nil {
+			continue
+		}
+		if err := spec.AddPermanentPlug(iface, plugInfo); err != nil {
+			continue
+		}
+		seccompSpec := &seccomp.Specification{}
+		if err := seccompSpec.AddConnectedPlug(iface, plug, slot); err != nil {
+			continue
+		}
+		if err := seccompSpec.AddPermanentPlug(iface, plugInfo); err != nil {
+			continue
+		}
+		grant.spec = spec
+		grant.seccompSpec = seccompSpec
+		s.grants = append(s.grants, grant)
+	}
+	// list the interfaces the snap already uses first
+	sort.Slice(s.grants, func(i, j int) bool {
+		gi, gj := s.grants[i], s.grants[j]
+		if gi.connected != gj.connected {
+			return gi.connected
+		}
+		if (gi.plug != "") != (gj.plug != "") {
+			return gi.plug != ""
+		}
+		return gi.iface < gj.iface
+	})
+	return s
+}
+
+func (s *denialSuggester) suggest(d *denials.Denial) []client.DenialSuggestion {
+	var suggestions []client.DenialSuggestion
+	for _, grant := range s.grants {
+		var allows bool
+		switch d.Kind {
+		case denials.KindAppArmor:
+			allows = denials.AppArmorSnippetAllows(grant.spec.SnippetForTag(d.Label), d, s.vars)
+		case denials.KindSeccomp:
+			allows = denials.SeccompSnippetAllows(grant.seccompSpec.SnippetForTag(d.Label), d)
+		}
+		if allows {
+			suggestions = append(suggestions, client.DenialSuggestion{
+				Interface: grant.iface,
+				Plug:      grant.plug,
+				Connected: grant.connected,
+			})
+		}
+	}
+	return suggestions
+}
@@ -0,0 +1,230 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package daemon_test
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/client"
+	"github.com/snapcore/snapd/daemon"
+	"github.com/snapcore/snapd/interfaces"
+	"github.com/snapcore/snapd/sandbox/denials"
+)
+
+var _ = Suite(&denialsDebugSuite{})
+
+type denialsDebugSuite struct {
+	apiBaseSuite
+}
+
+func (s *denialsDebugSuite) SetUpTest(c *C) {
+	s.apiBaseSuite.SetUpTest(c)
+	s.expectReadAccess(daemon.RootAccess{})
+}
+
+var denialsTime = time.Date(2021, 2, 3, 9, 41, 18, 0, time.UTC)
+
+var mockedDenials = []*denials.Denial{
+	{Time: denialsTime, Kind: denials.KindAppArmor, Label: "snap.consumer.app", PID: 42, Comm: "app", Operation: "open", Path: "/proc/42/net/dev", RequestedMask: "r", DeniedMask: "r"},
+	{Time: denialsTime, Kind: denials.KindAppArmor, Label: "snap.consumer.hook.configure", Operation: "capable", Capability: "net_admin"},
+	{Time: denialsTime, Kind: denials.KindSeccomp, Label: "snap.consumer.app", Syscall: 165, Arch: "c000003e"},
+	{Time: denialsTime, Kind: denials.KindAppArmor, Label: "/usr/sbin/cupsd", Operation: "open", Path: "/etc/foo", DeniedMask: "r"},
+	{Time: denialsTime, Kind: denials.KindAppArmor, Label: "snap.other.app", Operation: "open", Path: "/etc/foo", DeniedMask: "r"},
+}
+
+const denialsConsumerYaml = `name: consumer
+version: 1
+plugs:
+  netobs:
+    interface: network-observe
+apps:
+  app:
+    command: bin/app
+hooks:
+  configure:
+`
+
+const denialsCoreYaml = `name: core
+version: 1
+type: os
+slots:
+  network-observe:
+  network-control:
+  firewall-control:
+`
+
+// mockJournal returns a replacement of denials.FromJournal reading the
+// given denials.
+func mockJournal(c *C, journal []*denials.Denial) func(n int, keep func(*denials.Denial) bool) ([]*denials.Denial, error) {
+	return func(n int, keep func(*denials.Denial) bool) ([]*denials.Denial, error) {
+		c.Check(n, Equals, daemon.MaxDenials)
+		var found []*denials.Denial
+		for _, d := range journal {
+			if keep(d) {
+				found = append(found, d)
+			}
+		}
+		if len(found) > n {
+			found = found[len(found)-n:]
+		}
+		return found, nil
+	}
+}
+
+func (s *denialsDebugSuite) getDenials(c *C, query string) []*client.Denial {
+	req, err := http.NewRequest("GET", "/v2/debug/denials"+query, nil)
+	c.Assert(err, IsNil)
+	rsp := s.syncReq(c, req, nil)
+	result, ok := rsp.Result.([]*client.Denial)
+	c.Assert(ok, Equals, true)
+	return result
+}
+
+func (s *denialsDebugSuite) TestDenials(c *C) {
+	s.daemon(c)
+	s.mockSnap(c, denialsCoreYaml)
+	s.mockSnap(c, denialsConsumerYaml)
+	repo := s.d.Overlord().InterfaceManager().Repository()
+	_, err := repo.Connect(interfaces.NewConnRef(repo.Plug("consumer", "netobs"), repo.Slot("core", "network-observe")), nil, nil, nil, nil, nil)
+	c.Assert(err, IsNil)
+
+	s.AddCleanup(daemon.MockDenialsFromJournal(mockJournal(c, mockedDenials)))
+	s.AddCleanup(daemon.MockSeccompSyscallNames(func(auditArch string, syscalls []int) (map[int]string, error) {
+		c.Check(auditArch, Equals, "c000003e")
+		c.Check(syscalls, DeepEquals, []int{165})
+		return map[int]string{165: "mount"}, nil
+	}))
+
+	found := s.getDenials(c, "?snap=consumer")
+	c.Assert(found, HasLen, 3)
+	c.Check(found[0], DeepEquals, &client.Denial{
+		Time:          denialsTime,
+		Kind:          "apparmor",
+		Label:         "snap.consumer.app",
+		Snap:          "consumer",
+		App:           "app",
+		PID:           42,
+		Comm:          "app",
+		Operation:     "open",
+		Path:          "/proc/42/net/dev",
+		RequestedMask: "r",
+		DeniedMask:    "r",
+		Suggestions: []client.DenialSuggestion{
+			{Interface: "network-observe", Plug: "netobs", Connected: true},
+			{Interface: "firewall-control"},
+			{Interface: "network-control"},
+		},
+	})
+	c.Check(found[1].Hook, Equals, "configure")
+	c.Check(found[1].Suggestions, DeepEquals, []client.DenialSuggestion{
+		{Interface: "firewall-control"},
+		{Interface: "network-control"},
+	})
+	c.Check(found[2].Kind, Equals, "seccomp")
+	c.Check(found[2].Syscall, Equals, 165)
+	c.Check(found[2].SyscallName, Equals, "mount")
+	c.Check(found[2].Suggestions, DeepEquals, []client.DenialSuggestion{
+		{Interface: "network-control"},
+	})
+
+	// denials of snaps no longer installed are reported without
+	// suggestions while those of other programs are ignored
+	found = s.getDenials(c, "")
+	c.Assert(found, HasLen, 4)
+	c.Check(found[3].Snap, Equals, "other")
+	c.Check(found[3].Suggestions, HasLen, 0)
+}
+
+func (s *denialsDebugSuite) TestDenialsUnresolvedSyscalls(c *C) {
+	s.daemon(c)
+	s.mockSnap(c, denialsCoreYaml)
+	s.mockSnap(c, denialsConsumerYaml)
+
+	s.AddCleanup(daemon.MockDenialsFromJournal(mockJournal(c, mockedDenials)))
+	s.AddCleanup(daemon.MockSeccompSyscallNames(func(auditArch string, syscalls []int) (map[int]string, error) {
+		return nil, errors.New("cannot find snap-seccomp")
+	}))
+
+	// the denials are still reported, without the name of the syscall
+	// nor suggestions
+	found := s.getDenials(c, "?snap=consumer")
+	c.Assert(found, HasLen, 3)
+	c.Check(found[2].Syscall, Equals, 165)
+	c.Check(found[2].SyscallName, Equals, "")
+	c.Check(found[2].Suggestions, HasLen, 0)
+}
+
+func (s *denialsDebugSuite) TestDenialsMostRecentOfSnap(c *C) {
+	s.daemon(c)
+	s.mockSnap(c, denialsCoreYaml)
+	s.mockSnap(c, denialsConsumerYaml)
+
+	// the denials of the snap are followed by many more of other snaps
+	// and programs, and many of the same syscall
+	journal := append([]*denials.Denial(nil), mockedDenials[:3]...)
+	for i := 0; i < 2*daemon.MaxDenials; i++ {
+		journal = append(journal,
+			&denials.Denial{Time: denialsTime, Kind: denials.KindSeccomp, Label: "snap.other.app", Syscall: 165 + i%2, Arch: "c000003e"},
+			&denials.Denial{Time: denialsTime, Kind: denials.KindAppArmor, Label: "/usr/sbin/cupsd", Operation: "open", Path: "/etc/foo", DeniedMask: "r"})
+	}
+	s.AddCleanup(daemon.MockDenialsFromJournal(mockJournal(c, journal)))
+	var resolved [][]int
+	s.AddCleanup(daemon.MockSeccompSyscallNames(func(auditArch string, syscalls []int) (map[int]string, error) {
+		resolved = append(resolved, syscalls)
+		return map[int]string{165: "mount", 166: "umount2"}, nil
+	}))
+
+	found := s.getDenials(c, "?snap=consumer")
+	c.Assert(found, HasLen, 3)
+	c.Check(found[2].SyscallName, Equals, "mount")
+	c.Check(resolved, DeepEquals, [][]int{{165}})
+
+	// the syscalls are resolved once for the most recent denials only
+	resolved = nil
+	found = s.getDenials(c, "")
+	c.Assert(found, HasLen, daemon.MaxDenials)
+	c.Check(found[0].Snap, Equals, "other")
+	c.Check(found[0].SyscallName, Equals, "mount")
+	c.Check(found[1].SyscallName, Equals, "umount2")
+	c.Check(resolved, DeepEquals, [][]int{{165, 166}})
+}
+
+func (s *denialsDebugSuite) TestDenialsErrors(c *C) {
+	s.daemon(c)
+
+	req, err := http.NewRequest("GET", "/v2/debug/denials?snap=in--valid", nil)
+	c.Assert(err, IsNil)
+	rspe := s.errorReq(c, req, nil)
+	c.Check(rspe.Status, Equals, 400)
+	c.Check(rspe.Message, Matches, "invalid snap name: .*")
+
+	s.AddCleanup(daemon.MockDenialsFromJournal(func(n int, keep func(*denials.Denial) bool) ([]*denials.Denial, error) {
+		return nil, errors.New("cannot get journal: boom")
+	}))
+	req, err = http.NewRequest("GET", "/v2/debug/denials", nil)
+	c.Assert(err, IsNil)
+	rspe = s.errorReq(c, req, nil)
+	c.Check(rspe.Status, Equals, 500)
+	c.Check(rspe.Message, Equals, "cannot get journal: boom")
+}
@@ -0,0 +1,105 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package daemon
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/snapcore/snapd/asserts"
+	"github.com/snapcore/snapd/client"
+	"github.com/snapcore/snapd/interfaces"
+	"github.com/snapcore/snapd/overlord/auth"
+	"github.com/snapcore/snapd/snap"
+)
+
+var debugCheckPolicyCmd = &Command{
+	Path:        "/v2/debug/check-policy",
+	POST:        postCheckPolicy,
+	WriteAccess: rootAccess{},
+}
+
+// parseSnapAndName parses a plug or slot given as <snap>:<name>.
+func parseSnapAndName(ref string) (snapName, name string, ok bool) {
+	parts := strings.Split(ref, ":")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", false
+	}
+	return parts[0], parts[1], true
+}
+
+func postCheckPolicy(c *Command, r *http.Request, user *auth.UserState) Response {
+	var opts client.CheckPolicyOptions
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&opts); err != nil {
+		return BadRequest("cannot decode request body: %v", err)
+	}
+
+	var plugRef interfaces.PlugRef
+	var slotRef interfaces.SlotRef
+	var ok bool
+	if plugRef.Snap, plugRef.Name, ok = parseSnapAndName(opts.Plug); !ok {
+		return BadRequest("invalid plug %q, expected <snap>:<plug>", opts.Plug)
+	}
+	if slotRef.Snap, slotRef.Name, ok = parseSnapAndName(opts.Slot); !ok {
+		return BadRequest("invalid slot %q, expected <snap>:<slot>", opts.Slot)
+	}
+
+	snaps := make([]*snap.Info, 0, len(opts.Snaps))
+	for _, snapYaml := range opts.Snaps {
+		info, err := snap.InfoFromSnapYaml([]byte(snapYaml))
+		if err != nil {
+			return BadRequest("cannot read snap.yaml: %v", err)
+		}
+		snaps = append(snaps, info)
+	}
+
+	// the declarations are only evaluated, not added to the
+	// assertion database, so their signatures are not checked
+	snapDecls := make([]*asserts.SnapDeclaration, 0, len(opts.SnapDeclarations))
+	for _, encoded := range opts.SnapDeclarations {
+		a, err := asserts.Decode([]byte(encoded))
+		if err != nil {
+			return BadRequest("cannot decode snap declaration: %v", err)
+		}
+		snapDecl, ok := a.(*asserts.SnapDeclaration)
+		if !ok {
+			return BadRequest("assertion is not a snap declaration: %v", a.Type().Name)
+		}
+		snapDecls = append(snapDecls, snapDecl)
+	}
+
+	checks, err := c.d.overlord.InterfaceManager().CheckPolicy(&plugRef, &slotRef, snaps, snapDecls)
+	if err != nil {
+		return BadRequest("cannot check policy: %v", err)
+	}
+	result := make([]client.PolicyCheck, len(checks))
+	for i, check := range checks {
+		result[i] = client.PolicyCheck{
+			Check:       check.Kind,
+			Allowed:     check.Allowed,
+			Rule:        check.Rule,
+			Explanation: check.Explanation,
+			Error:       check.Error,
+		}
+	}
+	return SyncResponse(result)
+}
@@ -0,0 +1,164 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package daemon_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/asserts"
+	"github.com/snapcore/snapd/client"
+	"github.com/snapcore/snapd/daemon"
+	"github.com/snapcore/snapd/snap"
+)
+
+var _ = Suite(&checkPolicyDebugSuite{})
+
+type checkPolicyDebugSuite struct {
+	apiBaseSuite
+}
+
+func (s *checkPolicyDebugSuite) SetUpTest(c *C) {
+	s.apiBaseSuite.SetUpTest(c)
+	s.expectWriteAccess(daemon.RootAccess{})
+}
+
+// mockPolicySnaps installs local snaps, without snap-id, which are
+// checked against the base declaration unless declarations are given.
+func (s *checkPolicyDebugSuite) mockPolicySnaps(c *C, names ...string) {
+	extraYaml := map[string]string{
+		"core":     "type: os\nslots:\n  network:\n  firewall-control:\n",
+		"consumer": "plugs:\n  network:\n  firewall-control:\n",
+	}
+	for _, name := range names {
+		s.mkInstalledInState(c, s.d, name, "", "1", snap.R(-1), true, extraYaml[name])
+	}
+}
+
+func (s *checkPolicyDebugSuite) checkPolicyReq(c *C, opts *client.CheckPolicyOptions) *http.Request {
+	b, err := json.Marshal(opts)
+	c.Assert(err, IsNil)
+	req, err := http.NewRequest("POST", "/v2/debug/check-policy", bytes.NewReader(b))
+	c.Assert(err, IsNil)
+	return req
+}
+
+func (s *checkPolicyDebugSuite) TestCheckPolicy(c *C) {
+	s.daemon(c)
+	s.mockPolicySnaps(c, "core", "consumer")
+
+	req := s.checkPolicyReq(c, &client.CheckPolicyOptions{
+		Plug: "consumer:firewall-control",
+		Slot: "core:firewall-control",
+	})
+	rsp := s.syncReq(c, req, nil)
+	checks, ok := rsp.Result.([]client.PolicyCheck)
+	c.Assert(ok, Equals, true)
+	c.Assert(checks, HasLen, 4)
+	c.Check(checks[0].Check, Equals, "plug-installation")
+	c.Check(checks[0].Allowed, Equals, true)
+	c.Check(checks[1].Check, Equals, "slot-installation")
+	c.Check(checks[1].Allowed, Equals, true)
+	c.Check(checks[1].Rule, Equals, `slot rule of interface "firewall-control" in the base declaration`)
+	c.Check(checks[2].Check, Equals, "connection")
+	c.Check(checks[2].Allowed, Equals, true)
+	c.Check(checks[3].Check, Equals, "auto-connection")
+	c.Check(checks[3].Allowed, Equals, false)
+	c.Check(checks[3].Rule, Equals, `slot rule of interface "firewall-control" in the base declaration`)
+	c.Check(checks[3].Explanation, Equals, `alternative 1 of deny-auto-connection in slot rule of interface "firewall-control" in the base declaration matched unconditionally`)
+	c.Check(checks[3].Error, Equals, `auto-connection denied by slot rule of interface "firewall-control"`)
+}
+
+func (s *checkPolicyDebugSuite) TestCheckPolicyWithSnap(c *C) {
+	s.daemon(c)
+	s.mockPolicySnaps(c, "core")
+
+	// the plug snap is not installed
+	req := s.checkPolicyReq(c, &client.CheckPolicyOptions{
+		Plug:  "other:fw",
+		Slot:  "core:firewall-control",
+		Snaps: []string{"name: other\nversion: 1\nplugs:\n  fw:\n    interface: firewall-control\n"},
+	})
+	rsp := s.syncReq(c, req, nil)
+	checks := rsp.Result.([]client.PolicyCheck)
+	c.Assert(checks, HasLen, 4)
+	c.Check(checks[2].Check, Equals, "connection")
+	c.Check(checks[2].Allowed, Equals, true)
+	c.Check(checks[3].Check, Equals, "auto-connection")
+	c.Check(checks[3].Allowed, Equals, false)
+}
+
+func (s *checkPolicyDebugSuite) TestCheckPolicyWithSnapDeclaration(c *C) {
+	s.daemon(c)
+	s.mockPolicySnaps(c, "core", "consumer")
+
+	decl, err := s.StoreSigning.Sign(asserts.SnapDeclarationType, map[string]interface{}{
+		"format":       "1",
+		"series":       "16",
+		"snap-id":      "consumer-id",
+		"snap-name":    "consumer",
+		"publisher-id": "can0nical",
+		"plugs": map[string]interface{}{
+			"firewall-control": map[string]interface{}{
+				"allow-auto-connection": "true",
+			},
+		},
+		"timestamp": time.Now().Format(time.RFC3339),
+	}, nil, "")
+	c.Assert(err, IsNil)
+
+	req := s.checkPolicyReq(c, &client.CheckPolicyOptions{
+		Plug:             "consumer:firewall-control",
+		Slot:             "core:firewall-control",
+		SnapDeclarations: []string{string(asserts.Encode(decl))},
+	})
+	rsp := s.syncReq(c, req, nil)
+	checks := rsp.Result.([]client.PolicyCheck)
+	c.Assert(checks, HasLen, 4)
+	c.Check(checks[3].Check, Equals, "auto-connection")
+	c.Check(checks[3].Allowed, Equals, true)
+	c.Check(checks[3].Rule, Equals, `plug rule of interface "firewall-control" for "consumer" snap`)
+}
+
+func (s *checkPolicyDebugSuite) TestCheckPolicyErrors(c *C) {
+	s.daemon(c)
+	s.mockPolicySnaps(c, "core", "consumer")
+
+	for _, t := range []struct {
+		opts    client.CheckPolicyOptions
+		message string
+	}{
+		{client.CheckPolicyOptions{Plug: "consumer", Slot: "core:network"}, `invalid plug "consumer", expected <snap>:<plug>`},
+		{client.CheckPolicyOptions{Plug: "consumer:network", Slot: ":network"}, `invalid slot ":network", expected <snap>:<slot>`},
+		{client.CheckPolicyOptions{Plug: "consumer:other", Slot: "core:network"}, `cannot check policy: snap "consumer" has no plug named "other"`},
+		{client.CheckPolicyOptions{Plug: "other:network", Slot: "core:network"}, `cannot check policy: snap "other" is not installed`},
+		{client.CheckPolicyOptions{Plug: "consumer:network", Slot: "core:network", Snaps: []string{"name: -"}}, `cannot read snap.yaml: .*`},
+		{client.CheckPolicyOptions{Plug: "consumer:network", Slot: "core:network", SnapDeclarations: []string{"garbage"}}, `cannot decode snap declaration: .*`},
+		{client.CheckPolicyOptions{Plug: "consumer:network", Slot: "core:network", SnapDeclarations: []string{string(asserts.Encode(s.StoreSigning.StoreAccountKey("")))}}, `assertion is not a snap declaration: account-key`},
+	} {
+		rspe := s.errorReq(c, s.checkPolicyReq(c, &t.opts), nil)
+		c.Check(rspe.Status, Equals, 400)
+		c.Check(rspe.Message, Matches, t.message)
+	}
+}
@@ -0,0 +1,153 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package daemon
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/snapcore/snapd/client"
+	"github.com/snapcore/snapd/interfaces"
+	"github.com/snapcore/snapd/interfaces/apparmor"
+	"github.com/snapcore/snapd/interfaces/dbus"
+	"github.com/snapcore/snapd/interfaces/kmod"
+	"github.com/snapcore/snapd/interfaces/mount"
+	"github.com/snapcore/snapd/interfaces/seccomp"
+	"github.com/snapcore/snapd/interfaces/systemd"
+	"github.com/snapcore/snapd/interfaces/udev"
+	"github.com/snapcore/snapd/overlord/auth"
+	"github.com/snapcore/snapd/overlord/snapstate"
+	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/snap/naming"
+)
+
+var debugSandboxProfileCmd = &Command{
+	Path:       "/v2/debug/sandbox-profile",
+	GET:        getSandboxProfile,
+	ReadAccess: rootAccess{},
+}
+
+func getSandboxProfile(c *Command, r *http.Request, user *auth.UserState) Response {
+	app := r.URL.Query().Get("app")
+	tag, err := naming.ParseSecurityTag("snap." + app)
+	if err != nil {
+		return BadRequest("invalid app %q, expected <snap>.<app> or <snap>.hook.<hook>", app)
+	}
+	snapName := tag.InstanceName()
+
+	st := c.d.overlord.State()
+	st.Lock()
+	defer st.Unlock()
+
+	info, err := snapstate.CurrentInfo(st, snapName)
+	if _, ok := err.(*snap.NotInstalledError); ok {
+		return SnapNotFound(snapName, err)
+	}
+	if err != nil {
+		return InternalError("%v", err)
+	}
+	switch tag := tag.(type) {
+	case naming.AppSecurityTag:
+		if info.Apps[tag.AppName()] == nil {
+			return AppNotFound("snap %q has no app %q", snapName, tag.AppName())
+		}
+	case naming.HookSecurityTag:
+		if info.Hooks[tag.HookName()] == nil {
+			return AppNotFound("snap %q has no hook %q", snapName, tag.HookName())
+		}
+	}
+
+	profile := client.SandboxProfile{
+		SecurityTag: tag.String(),
+		Backends:    []client.SandboxProfileBackend{},
+	}
+	repo := c.d.overlord.InterfaceManager().Repository()
+	for _, backend := range repo.Backends() {
+		sources, err := repo.SnapSpecificationSources(backend.Name(), snapName)
+		if err != nil {
+			return InternalError("cannot compute %s profile of %q: %v", backend.Name(), tag, err)
+		}
+		profileBackend := client.SandboxProfileBackend{Name: string(backend.Name())}
+		for _, source := range sources {
+			snippet := renderSpecSnippet(source.Spec, tag.String())
+			if snippet == "" {
+				continue
+			}
+			ps := client.SandboxProfileSnippet{
+				Interface: source.Interface,
+				Snippet:   snippet,
+			}
+			if source.Plug != nil {
+				ps.Plug = source.Plug.String()
+			}
+			if source.Slot != nil {
+				ps.Slot = source.Slot.String()
+			}
+			profileBackend.Snippets = append(profileBackend.Snippets, ps)
+		}
+		profile.Backends = append(profile.Backends, profileBackend)
+	}
+	return SyncResponse(profile)
+}
+
+// renderSpecSnippet renders what the specification of a security
+// backend holds for the given security tag. Backends that do not
+// handle apps and hooks separately (kmod, mount and systemd) render
+// their whole specification.
+func renderSpecSnippet(spec interfaces.Specification, securityTag string) string {
+	var lines []string
+	switch spec := spec.(type) {
+	case *apparmor.Specification:
+		return spec.SnippetForTag(securityTag)
+	case *seccomp.Specification:
+		return spec.SnippetForTag(securityTag)
+	case *dbus.Specification:
+		return spec.SnippetForTag(securityTag)
+	case *udev.Specification:
+		lines = spec.SnippetsForTag(securityTag)
+	case *kmod.Specification:
+		for module := range spec.Modules() {
+			lines = append(lines, module)
+		}
+		sort.Strings(lines)
+	case *mount.Specification:
+		for _, entry := range spec.MountEntries() {
+			lines = append(lines, entry.String())
+		}
+		for _, entry := range spec.UserMountEntries() {
+			lines = append(lines, entry.String())
+		}
+	case *systemd.Specification:
+		services := spec.Services()
+		for name := range services {
+			lines = append(lines, name)
+		}
+		sort.Strings(lines)
+		for i, name := range lines {
+			lines[i] = fmt.Sprintf("# %s\n%s", name, strings.TrimSuffix(services[name].String(), "\n"))
+		}
+	}
+	if len(lines) == 0 {
+		return ""
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
@@ -0,0 +1,144 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package daemon_test
+
+import (
+	"net/http"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/client"
+	"github.com/snapcore/snapd/daemon"
+	"github.com/snapcore/snapd/interfaces"
+	"github.com/snapcore/snapd/interfaces/apparmor"
+	"github.com/snapcore/snapd/interfaces/kmod"
+	"github.com/snapcore/snapd/interfaces/seccomp"
+	"github.com/snapcore/snapd/testutil"
+)
+
+var _ = Suite(&sandboxProfileDebugSuite{})
+
+type sandboxProfileDebugSuite struct {
+	apiBaseSuite
+}
+
+func (s *sandboxProfileDebugSuite) SetUpTest(c *C) {
+	s.apiBaseSuite.SetUpTest(c)
+	s.expectReadAccess(daemon.RootAccess{})
+}
+
+const sandboxConsumerYaml = `name: consumer
+version: 1
+plugs:
+  network:
+  firewall-control:
+apps:
+  app:
+    command: bin/app
+    plugs: [network, firewall-control]
+hooks:
+  configure:
+    plugs: [network]
+`
+
+const sandboxCoreYaml = `name: core
+version: 1
+type: os
+slots:
+  network:
+  firewall-control:
+`
+
+func (s *sandboxProfileDebugSuite) mockConnectedSnaps(c *C) {
+	s.daemon(c)
+	repo := s.d.Overlord().InterfaceManager().Repository()
+	for _, backend := range []interfaces.SecurityBackend{&apparmor.Backend{}, &seccomp.Backend{}, &kmod.Backend{}} {
+		c.Assert(repo.AddBackend(backend), IsNil)
+	}
+	s.mockSnap(c, sandboxCoreYaml)
+	s.mockSnap(c, sandboxConsumerYaml)
+	for _, name := range []string{"network", "firewall-control"} {
+		_, err := repo.Connect(interfaces.NewConnRef(repo.Plug("consumer", name), repo.Slot("core", name)), nil, nil, nil, nil, nil)
+		c.Assert(err, IsNil)
+	}
+}
+
+func (s *sandboxProfileDebugSuite) TestSandboxProfile(c *C) {
+	s.mockConnectedSnaps(c)
+
+	req, err := http.NewRequest("GET", "/v2/debug/sandbox-profile?app=consumer.app", nil)
+	c.Assert(err, IsNil)
+	rsp := s.syncReq(c, req, nil)
+	profile, ok := rsp.Result.(client.SandboxProfile)
+	c.Assert(ok, Equals, true)
+	c.Check(profile.SecurityTag, Equals, "snap.consumer.app")
+	c.Assert(profile.Backends, HasLen, 3)
+
+	apparmorBackend := profile.Backends[0]
+	c.Check(apparmorBackend.Name, Equals, "apparmor")
+	c.Assert(apparmorBackend.Snippets, HasLen, 2)
+	c.Check(apparmorBackend.Snippets[0].Interface, Equals, "firewall-control")
+	c.Check(apparmorBackend.Snippets[0].Plug, Equals, "consumer:firewall-control")
+	c.Check(apparmorBackend.Snippets[0].Slot, Equals, "core:firewall-control")
+	c.Check(apparmorBackend.Snippets[0].Snippet, testutil.Contains, "capability net_admin,")
+	c.Check(apparmorBackend.Snippets[1].Interface, Equals, "network")
+	c.Check(apparmorBackend.Snippets[1].Snippet, testutil.Contains, "network netlink dgram,")
+
+	seccompBackend := profile.Backends[1]
+	c.Check(seccompBackend.Name, Equals, "seccomp")
+	c.Check(seccompBackend.Snippets, Not(HasLen), 0)
+
+	kmodBackend := profile.Backends[2]
+	c.Check(kmodBackend.Name, Equals, "kmod")
+	c.Assert(kmodBackend.Snippets, HasLen, 1)
+	c.Check(kmodBackend.Snippets[0].Interface, Equals, "firewall-control")
+	c.Check(kmodBackend.Snippets[0].Snippet, testutil.Contains, "iptable_filter\n")
+
+	// the configure hook only has the network plug
+	req, err = http.NewRequest("GET", "/v2/debug/sandbox-profile?app=consumer.hook.configure", nil)
+	c.Assert(err, IsNil)
+	rsp = s.syncReq(c, req, nil)
+	profile = rsp.Result.(client.SandboxProfile)
+	c.Check(profile.SecurityTag, Equals, "snap.consumer.hook.configure")
+	c.Assert(profile.Backends[0].Snippets, HasLen, 1)
+	c.Check(profile.Backends[0].Snippets[0].Interface, Equals, "network")
+}
+
+func (s *sandboxProfileDebugSuite) TestSandboxProfileErrors(c *C) {
+	s.mockConnectedSnaps(c)
+
+	for _, t := range []struct {
+		app     string
+		status  int
+		message string
+	}{
+		{"", 400, `invalid app "", expected <snap>.<app> or <snap>.hook.<hook>`},
+		{"consumer", 400, `invalid app "consumer", expected <snap>.<app> or <snap>.hook.<hook>`},
+		{"other.app", 404, `snap "other" is not installed`},
+		{"consumer.other", 404, `snap "consumer" has no app "other"`},
+		{"consumer.hook.install", 404, `snap "consumer" has no hook "install"`},
+	} {
+		req, err := http.NewRequest("GET", "/v2/debug/sandbox-profile?app="+t.app, nil)
+		c.Assert(err, IsNil)
+		rspe := s.errorReq(c, req, nil)
+		c.Check(rspe.Status, Equals, t.status, Commentf("%s", t.app))
+		c.Check(rspe.Message, Equals, t.message)
+	}
+}
@@ -235,6 +235,8 @@ func changeInterfaces(c *Command, r *http.Request, user *auth.UserState) Respons
 	}
 
 	change := newChange(st, a.Action+"-snap", summary, tasksets, affected)
+	// record who asked for the change in the connection history
+	change.Set("initiator", changeInitiator(r, user))
 	st.EnsureBefore(0)
 
 	return AsyncResponse(nil, change.ID())
@@ -147,6 +147,7 @@ func (s *interfacesSuite) TestConnectPlugSuccess(c *check.C) {
 	buf := bytes.NewBuffer(text)
 	req, err := http.NewRequest("POST", "/v2/interfaces", buf)
 	c.Assert(err, check.IsNil)
+	req.RemoteAddr = "pid=100;uid=1000;socket=;"
 	rec := httptest.NewRecorder()
 	s.req(c, req, nil).ServeHTTP(rec, req)
 	c.Check(rec.Code, check.Equals, 202)
@@ -165,8 +166,16 @@ func (s *interfacesSuite) TestConnectPlugSuccess(c *check.C) {
 
 	st.Lock()
 	err = chg.Err()
+	history, herr := ifacestate.ConnectionHistory(st)
 	st.Unlock()
 	c.Assert(err, check.IsNil)
+	c.Assert(herr, check.IsNil)
+
+	// the connection is recorded as requested by the user
+	c.Assert(history, check.HasLen, 1)
+	c.Check(history[0].Action, check.Equals, "connect")
+	c.Check(history[0].Initiator, check.Equals, "uid:1000")
+	c.Check(history[0].Reason, check.Equals, "Connect consumer:plug to producer:slot")
 
 	repo := d.Overlord().InterfaceManager().Repository()
 	ifaces := repo.Interfaces()
@@ -0,0 +1,615 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package daemon
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path"
+	"reflect"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/snapcore/snapd/client"
+	"github.com/snapcore/snapd/overlord/auth"
+	"github.com/snapcore/snapd/overlord/servicestate"
+	"github.com/snapcore/snapd/snap"
+)
+
+var openAPICmd = &Command{
+	Path:       "/v2/openapi",
+	GET:        getOpenAPI,
+	ReadAccess: openAccess{},
+}
+
+// apiOperation describes one method of an API route for the OpenAPI
+// description of the API.
+type apiOperation struct {
+	Summary string
+	// Query lists the query parameters understood by the operation.
+	Query []string
+	// Body is a value of the type the request body is decoded into,
+	// if any.
+	Body interface{}
+	// BodyType is the content type of the request body, if it is
+	// not JSON.
+	BodyType string
+	// Result is a value of the type of the result of a synchronous
+	// response, if any.
+	Result interface{}
+	// ResultType is the content type of the response, if it is not
+	// a JSON document.
+	ResultType string
+	// Async is set if the operation starts a change.
+	Async bool
+}
+
+// apiDocs describes the operations of all the routes in api, by path
+// (or path prefix) and method.
+var apiDocs = map[string]map[string]*apiOperation{
+	"/": {
+		"GET": {Summary: "Placeholder for the API root", Result: []string{}},
+	},
+	"/v2/system-info": {
+		"GET": {Summary: "Get information about the system", Result: client.SysInfo{}},
+	},
+	"/v2/login": {
+		"POST": {Summary: "Log into the store", Body: struct {
+			Username string `json:"username"`
+			Email    string `json:"email"`
+			Password string `json:"password"`
+			Otp      string `json:"otp"`
+		}{}, Result: userResponseData{}},
+	},
+	"/v2/logout": {
+		"POST": {Summary: "Log out of the store"},
+	},
+	"/v2/icons/{name}/icon": {
+		"GET": {Summary: "Get the icon of an installed snap", ResultType: "image/*"},
+	},
+	"/v2/find": {
+		"GET": {Summary: "Search the store", Query: []string{"q", "name", "common-id", "section", "scope", "select"}, Result: []client.Snap{}},
+	},
+	"/v2/snaps": {
+		"GET":  {Summary: "List installed snaps, or search the store as with /v2/find if sources asks for it", Query: []string{"select", "snaps", "sources", "q", "name", "common-id", "section", "scope"}, Result: []client.Snap{}},
+		"POST": {Summary: "Act on multiple snaps, or sideload or try a snap using a multipart/form-data body", Body: snapInstruction{}, Async: true},
+	},
+	"/v2/snaps/{name}": {
+		"GET":  {Summary: "Get details of an installed snap", Result: client.Snap{}},
+		"POST": {Summary: "Install, refresh, remove, revert, enable, disable or switch a snap", Body: snapInstruction{}, Async: true},
+	},
+	"/v2/snaps/{name}/file": {
+		"GET": {Summary: "Download the file of an installed snap", ResultType: "application/octet-stream"},
+	},
+	"/v2/download": {
+		"POST": {Summary: "Download a snap from the store", Body: snapDownloadAction{}, ResultType: "application/octet-stream"},
+	},
+	"/v2/snaps/{name}/conf": {
+		"GET":  {Summary: "Get the configuration of a snap, its configuration schema or history", Query: []string{"keys", "schema", "history"}, Result: map[string]interface{}{}},
+		"PUT":  {Summary: "Change the configuration of a snap", Body: map[string]interface{}{}, Async: true},
+		"POST": {Summary: "Restore a previous configuration of a snap", Body: snapConfInstruction{}, Async: true},
+	},
+	"/v2/conf": {
+		"GET": {Summary: "Export the whole configuration of snaps, or of all configured snaps and the system", Query: []string{"snaps"}, Result: map[string]interface{}{}},
+		"PUT": {Summary: "Replace the whole configuration of snaps", Body: map[string]interface{}{}, Async: true},
+	},
+	"/v2/snaps/{name}/usage": {
+		"GET": {Summary: "Get the resource usage of the processes of a snap, by app and hook", Result: client.SnapUsage{}},
+	},
+	"/v2/usage": {
+		"GET": {Summary: "Get the resource usage of the processes of the given snaps, or of all installed snaps", Query: []string{"snaps"}, Result: []client.SnapUsage{}},
+	},
+	"/v2/interfaces": {
+		"GET":  {Summary: "List interfaces, or plugs, slots and connections with the legacy API", Query: []string{"select", "names", "doc", "plugs", "slots"}, Result: []client.Interface{}},
+		"POST": {Summary: "Connect or disconnect plugs and slots", Body: client.InterfaceAction{}, Async: true},
+	},
+	"/v2/assertions": {
+		"GET":  {Summary: "List the assertion types", Result: map[string][]string{}},
+		"POST": {Summary: "Add an assertion to the system database", BodyType: "application/x.ubuntu.assertion"},
+	},
+	"/v2/assertions/{assertType}": {
+		"GET": {Summary: "Find assertions of the given type, filtering by their headers", Query: []string{"remote", "json"}, ResultType: "application/x.ubuntu.assertion"},
+	},
+	"/v2/changes/{id}": {
+		"GET": {Summary: "Get a change", Result: client.Change{}},
+		"POST": {Summary: "Abort a change", Body: struct {
+			Action string `json:"action"`
+		}{}, Result: client.Change{}},
+	},
+	"/v2/changes": {
+		"GET": {Summary: "List changes", Query: []string{"select", "for"}, Result: []client.Change{}},
+	},
+	"/v2/create-user": {
+		"POST": {Summary: "Create a local user", Body: postUserCreateData{}, Result: []userResponseData{}},
+	},
+	"/v2/buy": {
+		"POST": {Summary: "Buy a snap", Body: client.BuyOptions{}, Result: client.BuyResult{}},
+	},
+	"/v2/buy/ready": {
+		"GET": {Summary: "Check whether the user is ready to buy snaps", Result: true},
+	},
+	"/v2/snapctl": {
+		"POST": {Summary: "Run snapctl on behalf of a snap", Body: client.SnapCtlPostData{}, Result: map[string]string{}},
+	},
+	"/v2/users": {
+		"GET":  {Summary: "List the users known to snapd", Result: []userResponseData{}},
+		"POST": {Summary: "Create or remove a local user", Body: postUserData{}, Result: map[string][]userResponseData{}},
+	},
+	"/v2/sections": {
+		"GET": {Summary: "List the store sections", Result: []string{}},
+	},
+	"/v2/aliases": {
+		"GET":  {Summary: "List aliases", Result: map[string]map[string]aliasStatus{}},
+		"POST": {Summary: "Alias, unalias or prefer a snap", Body: aliasAction{}, Async: true},
+	},
+	"/v2/apps": {
+		"GET":  {Summary: "List apps and services", Query: []string{"select", "names", "users"}, Result: []client.AppInfo{}},
+		"POST": {Summary: "Start, stop or restart services", Body: servicestate.Instruction{}, Async: true},
+	},
+	"/v2/logs": {
+		"GET": {Summary: "Get the logs of services", Query: []string{"names", "n", "follow", "since", "until", "priority", "boot", "grep", "fields"}, Result: client.Log{}, ResultType: "application/json-seq"},
+	},
+	"/v2/warnings": {
+		"GET": {Summary: "List warnings", Query: []string{"select"}, Result: []client.Warning{}},
+		"POST": {Summary: "Acknowledge warnings", Body: struct {
+			Action    string    `json:"action"`
+			Timestamp time.Time `json:"timestamp"`
+		}{}, Result: 0},
+	},
+	"/v2/debug/pprof/": {
+		"GET": {Summary: "Get profiling data", ResultType: "application/octet-stream"},
+	},
+	"/v2/debug": {
+		"GET":  {Summary: "Get debugging information", Query: []string{"aspect", "change-id", "ensure", "startup", "all"}, Result: map[string]interface{}{}},
+		"POST": {Summary: "Perform a debugging action", Body: debugAction{}, Result: map[string]interface{}{}},
+	},
+	"/v2/debug/denials": {
+		"GET": {Summary: "List the sandbox denials of snaps with the interfaces granting them", Query: []string{"snap"}, Result: []client.Denial{}},
+	},
+	"/v2/debug/sandbox-profile": {
+		"GET": {Summary: "Get the security snippets of an app or hook with the interfaces contributing them", Query: []string{"app"}, Result: client.SandboxProfile{}},
+	},
+	"/v2/debug/check-policy": {
+		"POST": {Summary: "Explain the declaration rules deciding the installation and connection of a plug and slot", Body: client.CheckPolicyOptions{}, Result: []client.PolicyCheck{}},
+	},
+	"/v2/snapshots": {
+		"GET":  {Summary: "List snapshots", Query: []string{"set", "snaps"}, Result: []client.SnapshotSet{}},
+		"POST": {Summary: "Check, restore or forget snapshots", Body: snapshotAction{}, Async: true},
+	},
+	"/v2/snapshots/{id}/export": {
+		"GET": {Summary: "Export a snapshot", ResultType: "application/x.snapd.snapshot"},
+	},
+	"/v2/connections": {
+		"GET": {Summary: "List connections, plugs and slots, or the history of connections with select=history", Query: []string{"snap", "interface", "select"}, Result: client.Connections{}},
+	},
+	"/v2/model": {
+		"GET":  {Summary: "Get the model assertion", Query: []string{"json"}, ResultType: "application/x.ubuntu.assertion"},
+		"POST": {Summary: "Remodel the system", Body: postModelData{}, Async: true},
+	},
+	"/v2/cohorts": {
+		"POST": {Summary: "Create cohorts", Body: client.CohortAction{}, Result: map[string]string{}},
+	},
+	"/v2/model/serial": {
+		"GET": {Summary: "Get the serial assertion", Query: []string{"json"}, ResultType: "application/x.ubuntu.assertion"},
+	},
+	"/v2/systems": {
+		"GET":  {Summary: "List the recovery systems", Result: systemsResponse{}},
+		"POST": {Summary: "Perform an action on the current system", Body: systemActionRequest{}},
+	},
+	"/v2/systems/{label}": {
+		"POST": {Summary: "Perform an action on a recovery system", Body: systemActionRequest{}},
+	},
+	"/v2/accessories/themes": {
+		"GET":  {Summary: "Check the availability of themes", Query: []string{"gtk-theme", "icon-theme", "sound-theme"}, Result: themeStatusResponse{}},
+		"POST": {Summary: "Install themes", Body: themeInstallReq{}, Async: true},
+	},
+	"/v2/validation-sets": {
+		"GET": {Summary: "List validation sets", Result: []validationSetResult{}},
+	},
+	"/v2/validation-sets/{account}/{name}": {
+		"GET":  {Summary: "Get a validation set", Query: []string{"sequence"}, Result: validationSetResult{}},
+		"POST": {Summary: "Monitor or forget a validation set", Body: validationSetApplyRequest{}, Result: validationSetResult{}},
+	},
+	"/v2/internal/console-conf-start": {
+		"POST": {Summary: "Notify snapd that console-conf is starting", Result: consoleConfStartRoutineResult{}},
+	},
+	"/v2/system-recovery-keys": {
+		"GET": {Summary: "Get the recovery keys", Result: client.SystemRecoveryKeysResponse{}},
+	},
+	"/v2/quotas": {
+		"GET":  {Summary: "List quota groups", Result: []client.QuotaGroupResult{}},
+		"POST": {Summary: "Create, update or remove a quota group", Body: postQuotaGroupData{}, Async: true},
+	},
+	"/v2/quotas/{group}": {
+		"GET": {Summary: "Get a quota group", Result: client.QuotaGroupResult{}},
+	},
+	"/v2/service-overrides": {
+		"GET":  {Summary: "List the settings overridden for services", Query: []string{"names"}, Result: []client.ServiceOverride{}},
+		"POST": {Summary: "Override settings of a service or reset them", Body: postServiceOverrideData{}, Async: true},
+	},
+	"/v2/batch": {
+		"POST": {Summary: "Perform an ordered list of operations as a single change", Body: batchInstruction{}, Async: true},
+	},
+	"/v2/openapi": {
+		"GET": {Summary: "Get the OpenAPI description of the API"},
+	},
+}
+
+// openAPIKnownSchemas maps types with a custom JSON encoding that
+// cannot be derived from their fields to their schema. Other types
+// with custom encodings are described by their fields.
+var openAPIKnownSchemas = map[reflect.Type]map[string]interface{}{
+	reflect.TypeOf(time.Time{}):       {"type": "string", "format": "date-time"},
+	reflect.TypeOf(time.Duration(0)):  {"type": "integer", "description": "nanoseconds"},
+	reflect.TypeOf(snap.Revision{}):   {"type": "string"},
+	reflect.TypeOf(json.RawMessage{}): {},
+}
+
+var textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+
+// openAPISchemas collects the schemas of the named types referenced
+// by the description.
+type openAPISchemas map[string]interface{}
+
+func (schemas openAPISchemas) schemaFor(t reflect.Type) map[string]interface{} {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if s, ok := openAPIKnownSchemas[t]; ok {
+		return s
+	}
+	if t.Implements(textMarshalerType) || reflect.PtrTo(t).Implements(textMarshalerType) {
+		return map[string]interface{}{"type": "string"}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Interface:
+		return map[string]interface{}{}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return map[string]interface{}{"type": "string", "format": "byte"}
+		}
+		return map[string]interface{}{"type": "array", "items": schemas.schemaFor(t.Elem())}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": schemas.schemaFor(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return schemas.objectFor(t)
+		}
+		name := path.Base(t.PkgPath()) + "." + t.Name()
+		if _, ok := schemas[name]; !ok {
+			// placeholder, for recursive types
+			schemas[name] = nil
+			schemas[name] = schemas.objectFor(t)
+		}
+		return map[string]interface{}{"$ref": "#/components/schemas/" + name}
+	}
+	return map[string]interface{}{}
+}
+
+func (schemas openAPISchemas) objectFor(t reflect.Type) map[string]interface{} {
+	properties := make(map[string]interface{})
+	schemas.addFields(properties, t)
+	return map[string]interface{}{"type": "object", "properties": properties}
+}
+
+func (schemas openAPISchemas) addFields(properties map[string]interface{}, t reflect.Type) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name := strings.Split(tag, ",")[0]
+		ft := f.Type
+		for ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		if f.Anonymous && name == "" && ft.Kind() == reflect.Struct {
+			// embedded structs are flattened
+			schemas.addFields(properties, ft)
+			continue
+		}
+		if f.PkgPath != "" {
+			// unexported
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		properties[name] = schemas.schemaFor(f.Type)
+	}
+}
+
+var openAPIPathParam = regexp.MustCompile(`{([^}]+)}`)
+
+// openAPIAccessDescription explains the x-snapd-* access extensions
+// of the operations.
+const openAPIAccessDescription = `The access required by each operation is given by x-snapd-access:
+
+- open: any local client, except via snapd-snap.socket
+- authenticated: root, a user logged in with a macaroon, a user granted
+  the operation by the local access policy, or a user authorized via
+  the Polkit action in x-snapd-polkit-action
+- root: only root
+- snap: only snaps, via snapd-snap.socket
+
+Operations with x-snapd-access-policy can be granted to local users and
+groups, and to remote clients, by the rules of the access policy file
+/etc/snapd/access-policy.yaml. The value is the scope the operations
+of the request are extracted from: "snap" and "snaps" for snap
+operations, "apps" for service operations, "logs" for reading the
+logs of services and "batch" for the operations of a batch, each of
+which must be granted. Snap operations can be restricted to channels and the
+options that raise privileges must be granted explicitly.
+
+When core.remote-api.listen-address is set, the API is also served over
+TLS to the clients whose certificate fingerprint is listed in
+core.remote-api.allowed-fingerprints. x-snapd-remote-access tells how
+their requests are checked:
+
+- allowed: the request is allowed
+- access-policy: the request must be granted to the client by a rule of
+  the access policy listing its fingerprint under clients
+- denied: the request is denied
+`
+
+func accessDescription(ac accessChecker) string {
+	switch ac.(type) {
+	case openAccess:
+		return "open"
+	case authenticatedAccess:
+		return "authenticated"
+	case rootAccess:
+		return "root"
+	case snapAccess:
+		return "snap"
+	}
+	return "unknown"
+}
+
+// remoteAccessDescription describes how requests received via the
+// remote management listener are checked, see remoteAccessChecker.
+func remoteAccessDescription(ac accessChecker, method string) string {
+	if _, ok := ac.(remoteAccessChecker); !ok {
+		return "denied"
+	}
+	if aa, ok := ac.(authenticatedAccess); ok && aa.Policy != "" {
+		return "access-policy"
+	}
+	if method == "GET" {
+		return "allowed"
+	}
+	return "denied"
+}
+
+func (schemas openAPISchemas) operation(op *apiOperation, method string, ac accessChecker, pathParams []string) map[string]interface{} {
+	o := map[string]interface{}{
+		"summary":               op.Summary,
+		"x-snapd-access":        accessDescription(ac),
+		"x-snapd-remote-access": remoteAccessDescription(ac, method),
+	}
+	if aa, ok := ac.(authenticatedAccess); ok {
+		if aa.Polkit != "" {
+			o["x-snapd-polkit-action"] = aa.Polkit
+		}
+		if aa.Policy != "" {
+			o["x-snapd-access-policy"] = string(aa.Policy)
+		}
+	}
+
+	var params []interface{}
+	for _, p := range pathParams {
+		params = append(params, map[string]interface{}{
+			"name":     p,
+			"in":       "path",
+			"required": true,
+			"schema":   map[string]interface{}{"type": "string"},
+		})
+	}
+	for _, q := range op.Query {
+		params = append(params, map[string]interface{}{
+			"name":   q,
+			"in":     "query",
+			"schema": map[string]interface{}{"type": "string"},
+		})
+	}
+	if params != nil {
+		o["parameters"] = params
+	}
+
+	switch {
+	case op.BodyType != "":
+		o["requestBody"] = map[string]interface{}{
+			"content": map[string]interface{}{op.BodyType: map[string]interface{}{}},
+		}
+	case op.Body != nil:
+		o["requestBody"] = map[string]interface{}{
+			"content": map[string]interface{}{
+				"application/json": map[string]interface{}{"schema": schemas.schemaFor(reflect.TypeOf(op.Body))},
+			},
+		}
+	}
+
+	responses := map[string]interface{}{
+		"default": map[string]interface{}{
+			"description": "error",
+			"content": map[string]interface{}{
+				"application/json": map[string]interface{}{"schema": map[string]interface{}{"$ref": "#/components/schemas/Error"}},
+			},
+		},
+	}
+	switch {
+	case op.Async:
+		responses["202"] = map[string]interface{}{
+			"description": "the ID of the change that was started",
+			"content": map[string]interface{}{
+				"application/json": map[string]interface{}{"schema": map[string]interface{}{"$ref": "#/components/schemas/AsyncResponse"}},
+			},
+		}
+	case op.ResultType != "":
+		content := map[string]interface{}{}
+		if op.Result != nil {
+			content["schema"] = schemas.schemaFor(reflect.TypeOf(op.Result))
+		}
+		responses["200"] = map[string]interface{}{
+			"description": "success",
+			"content":     map[string]interface{}{op.ResultType: content},
+		}
+	default:
+		result := map[string]interface{}{}
+		if op.Result != nil {
+			result = schemas.schemaFor(reflect.TypeOf(op.Result))
+		}
+		responses["200"] = map[string]interface{}{
+			"description": "success",
+			"content": map[string]interface{}{
+				"application/json": map[string]interface{}{"schema": map[string]interface{}{
+					"allOf": []interface{}{
+						map[string]interface{}{"$ref": "#/components/schemas/SyncResponse"},
+						map[string]interface{}{
+							"type":       "object",
+							"properties": map[string]interface{}{"result": result},
+						},
+					},
+				}},
+			},
+		}
+	}
+	o["responses"] = responses
+	return o
+}
+
+// openAPIDescription returns the OpenAPI description of the given
+// commands, or an error if any of them is not described in apiDocs.
+func openAPIDescription(cmds []*Command, version string) (map[string]interface{}, error) {
+	schemas := openAPISchemas{
+		"SyncResponse": map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"type":        map[string]interface{}{"type": "string", "enum": []string{"sync"}},
+				"status-code": map[string]interface{}{"type": "integer"},
+				"status":      map[string]interface{}{"type": "string"},
+				"result":      map[string]interface{}{},
+			},
+		},
+		"AsyncResponse": map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"type":        map[string]interface{}{"type": "string", "enum": []string{"async"}},
+				"status-code": map[string]interface{}{"type": "integer"},
+				"status":      map[string]interface{}{"type": "string"},
+				"change":      map[string]interface{}{"type": "string"},
+			},
+		},
+		"Error": map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"type":        map[string]interface{}{"type": "string", "enum": []string{"error"}},
+				"status-code": map[string]interface{}{"type": "integer"},
+				"status":      map[string]interface{}{"type": "string"},
+				"result": map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"message": map[string]interface{}{"type": "string"},
+						"kind":    map[string]interface{}{"type": "string"},
+						"value":   map[string]interface{}{},
+					},
+				},
+			},
+		},
+	}
+
+	paths := make(map[string]interface{})
+	for _, c := range cmds {
+		route, docPath := c.Path, c.Path
+		if route == "" {
+			route = c.PathPrefix
+			docPath = c.PathPrefix + "{path}"
+		}
+		var pathParams []string
+		for _, match := range openAPIPathParam.FindAllStringSubmatch(docPath, -1) {
+			pathParams = append(pathParams, match[1])
+		}
+		docs := apiDocs[route]
+		item := make(map[string]interface{})
+		for _, m := range []struct {
+			method string
+			f      ResponseFunc
+			ac     accessChecker
+		}{
+			{"GET", c.GET, c.ReadAccess},
+			{"PUT", c.PUT, c.WriteAccess},
+			{"POST", c.POST, c.WriteAccess},
+		} {
+			if m.f == nil {
+				continue
+			}
+			op := docs[m.method]
+			if op == nil {
+				return nil, fmt.Errorf("internal error: %s %s is not described", m.method, route)
+			}
+			item[strings.ToLower(m.method)] = schemas.operation(op, m.method, m.ac, pathParams)
+		}
+		paths[docPath] = item
+	}
+
+	return map[string]interface{}{
+		"openapi": "3.0.3",
+		"info": map[string]interface{}{
+			"title":       "snapd REST API",
+			"version":     version,
+			"description": openAPIAccessDescription,
+		},
+		"paths":      paths,
+		"components": map[string]interface{}{"schemas": schemas},
+	}, nil
+}
+
+func getOpenAPI(c *Command, r *http.Request, user *auth.UserState) Response {
+	// the commands are taken from the router as referring to api
+	// here would be an initialization loop
+	var cmds []*Command
+	c.d.router.Walk(func(route *mux.Route, router *mux.Router, ancestors []*mux.Route) error {
+		if cmd, ok := route.GetHandler().(*Command); ok {
+			cmds = append(cmds, cmd)
+		}
+		return nil
+	})
+	desc, err := openAPIDescription(cmds, c.d.Version)
+	if err != nil {
+		return InternalError("%v", err)
+	}
+	return SyncResponse(desc)
+}
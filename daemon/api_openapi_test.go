@@ -0,0 +1,375 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package daemon_test
+
+import (
+	"encoding/json"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"net/http"
+	"os"
+	"reflect"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/daemon"
+	"github.com/snapcore/snapd/overlord/auth"
+	"github.com/snapcore/snapd/strutil"
+	"github.com/snapcore/snapd/testutil"
+)
+
+var _ = check.Suite(&openAPISuite{})
+
+type openAPISuite struct {
+	apiBaseSuite
+}
+
+func (s *openAPISuite) TestEveryRouteIsDescribed(c *check.C) {
+	described := daemon.APIDocsRoutes()
+
+	routes := make(map[string][]string)
+	for _, cmd := range daemon.APICommands() {
+		path := cmd.Path
+		if path == "" {
+			path = cmd.PathPrefix
+		}
+		if cmd.GET != nil {
+			routes[path] = append(routes[path], "GET")
+		}
+		if cmd.PUT != nil {
+			routes[path] = append(routes[path], "PUT")
+		}
+		if cmd.POST != nil {
+			routes[path] = append(routes[path], "POST")
+		}
+	}
+
+	for _, methods := range described {
+		sort.Strings(methods)
+	}
+	for path, methods := range routes {
+		sort.Strings(methods)
+		c.Check(described[path], check.DeepEquals, methods, check.Commentf("route %q is not described in apiDocs", path))
+	}
+	for path := range described {
+		_, ok := routes[path]
+		c.Check(ok, check.Equals, true, check.Commentf("apiDocs describes unknown route %q", path))
+	}
+
+	_, err := daemon.OpenAPIDescription(daemon.APICommands())
+	c.Check(err, check.IsNil)
+}
+
+func (s *openAPISuite) TestUndescribedRoute(c *check.C) {
+	cmds := []*daemon.Command{{Path: "/v2/not-described", GET: func(*daemon.Command, *http.Request, *auth.UserState) daemon.Response { return nil }}}
+	_, err := daemon.OpenAPIDescription(cmds)
+	c.Check(err, check.ErrorMatches, `internal error: GET /v2/not-described is not described`)
+}
+
+func (s *openAPISuite) TestGetOpenAPI(c *check.C) {
+	s.daemon(c)
+
+	req, err := http.NewRequest("GET", "/v2/openapi", nil)
+	c.Assert(err, check.IsNil)
+	rsp := s.syncReq(c, req, nil)
+
+	// round-trip to check the document is valid JSON and to
+	// inspect it generically
+	b, err := json.Marshal(rsp.Result)
+	c.Assert(err, check.IsNil)
+	var doc struct {
+		OpenAPI string `json:"openapi"`
+		Info    struct {
+			Description string `json:"description"`
+		} `json:"info"`
+		Paths map[string]map[string]struct {
+			Summary      string                 `json:"summary"`
+			Access       string                 `json:"x-snapd-access"`
+			Polkit       string                 `json:"x-snapd-polkit-action"`
+			AccessPolicy string                 `json:"x-snapd-access-policy"`
+			RemoteAccess string                 `json:"x-snapd-remote-access"`
+			RequestBody  map[string]interface{} `json:"requestBody"`
+			Responses    map[string]interface{} `json:"responses"`
+		} `json:"paths"`
+		Components struct {
+			Schemas map[string]map[string]interface{} `json:"schemas"`
+		} `json:"components"`
+	}
+	c.Assert(json.Unmarshal(b, &doc), check.IsNil)
+
+	c.Check(doc.OpenAPI, check.Equals, "3.0.3")
+	c.Check(doc.Info.Description, testutil.Contains, "/etc/snapd/access-policy.yaml")
+	c.Check(doc.Info.Description, testutil.Contains, "core.remote-api.allowed-fingerprints")
+
+	getSnap := doc.Paths["/v2/snaps/{name}"]["get"]
+	c.Check(getSnap.Summary, check.Equals, "Get details of an installed snap")
+	c.Check(getSnap.Access, check.Equals, "open")
+	c.Check(getSnap.Responses["200"], check.NotNil)
+	c.Check(getSnap.RemoteAccess, check.Equals, "allowed")
+	c.Check(getSnap.AccessPolicy, check.Equals, "")
+
+	postSnap := doc.Paths["/v2/snaps/{name}"]["post"]
+	c.Check(postSnap.Access, check.Equals, "authenticated")
+	c.Check(postSnap.Polkit, check.Equals, "io.snapcraft.snapd.manage")
+	c.Check(postSnap.RequestBody, check.NotNil)
+	c.Check(postSnap.Responses["202"], check.NotNil)
+	c.Check(postSnap.AccessPolicy, check.Equals, "snap")
+	c.Check(postSnap.RemoteAccess, check.Equals, "access-policy")
+
+	getLogs := doc.Paths["/v2/logs"]["get"]
+	c.Check(getLogs.AccessPolicy, check.Equals, "logs")
+	c.Check(getLogs.RemoteAccess, check.Equals, "access-policy")
+
+	// changing the system without the access policy is not possible
+	// remotely
+	c.Check(doc.Paths["/v2/snaps/{name}/conf"]["put"].RemoteAccess, check.Equals, "denied")
+	c.Check(doc.Paths["/v2/snapctl"]["post"].RemoteAccess, check.Equals, "denied")
+
+	c.Check(doc.Paths["/v2/debug/pprof/{path}"]["get"].Access, check.Equals, "root")
+
+	snapSchema := doc.Components.Schemas["client.Snap"]
+	c.Assert(snapSchema, check.NotNil)
+	props := snapSchema["properties"].(map[string]interface{})
+	c.Check(props["name"], check.DeepEquals, map[string]interface{}{"type": "string"})
+	c.Check(props["revision"], check.DeepEquals, map[string]interface{}{"type": "string"})
+	c.Check(props["install-date"], check.DeepEquals, map[string]interface{}{"type": "string", "format": "date-time"})
+}
+
+// queryParamsReader finds the query parameters read by the functions
+// of the daemon package by inspecting their source.
+type queryParamsReader struct {
+	funcs map[string]*ast.FuncDecl
+	cache map[string]*queryParams
+}
+
+type queryParams struct {
+	names []string
+	// dynamic is set if some parameters are read through names
+	// that are not literals
+	dynamic bool
+}
+
+func newQueryParamsReader(c *check.C) *queryParamsReader {
+	fset := token.NewFileSet()
+	notTest := func(fi os.FileInfo) bool { return !strings.HasSuffix(fi.Name(), "_test.go") }
+	pkgs, err := parser.ParseDir(fset, ".", notTest, 0)
+	c.Assert(err, check.IsNil)
+	c.Assert(pkgs["daemon"], check.NotNil)
+
+	funcs := make(map[string]*ast.FuncDecl)
+	for _, f := range pkgs["daemon"].Files {
+		for _, decl := range f.Decls {
+			if fd, ok := decl.(*ast.FuncDecl); ok && fd.Recv == nil {
+				funcs[fd.Name.Name] = fd
+			}
+		}
+	}
+	return &queryParamsReader{funcs: funcs, cache: make(map[string]*queryParams)}
+}
+
+func isSelector(expr ast.Expr, x, sel string) bool {
+	s, ok := expr.(*ast.SelectorExpr)
+	if !ok || s.Sel.Name != sel {
+		return false
+	}
+	id, ok := s.X.(*ast.Ident)
+	return ok && id.Name == x
+}
+
+// isURLQuery returns whether expr is <request>.URL.Query().
+func isURLQuery(expr ast.Expr) bool {
+	call, ok := expr.(*ast.CallExpr)
+	if !ok {
+		return false
+	}
+	sel, ok := call.Fun.(*ast.SelectorExpr)
+	if !ok || sel.Sel.Name != "Query" {
+		return false
+	}
+	url, ok := sel.X.(*ast.SelectorExpr)
+	return ok && url.Sel.Name == "URL"
+}
+
+func stringLit(expr ast.Expr) (string, bool) {
+	lit, ok := expr.(*ast.BasicLit)
+	if !ok || lit.Kind != token.STRING {
+		return "", false
+	}
+	s, err := strconv.Unquote(lit.Value)
+	return s, err == nil
+}
+
+// read returns the query parameters read by the named function and
+// by the functions it passes the request or its query to.
+func (qr *queryParamsReader) read(name string) *queryParams {
+	if params, ok := qr.cache[name]; ok {
+		return params
+	}
+	params := &queryParams{}
+	// guard against recursion
+	qr.cache[name] = params
+	fd := qr.funcs[name]
+	if fd == nil {
+		return params
+	}
+
+	// the variables holding the request or its query
+	requests := make(map[string]bool)
+	queries := make(map[string]bool)
+	for _, field := range fd.Type.Params.List {
+		var isReq, isQuery bool
+		switch t := field.Type.(type) {
+		case *ast.StarExpr:
+			isReq = isSelector(t.X, "http", "Request")
+		case *ast.SelectorExpr:
+			isQuery = isSelector(t, "url", "Values")
+		}
+		for _, n := range field.Names {
+			requests[n.Name] = isReq
+			queries[n.Name] = isQuery
+		}
+	}
+	isQuery := func(expr ast.Expr) bool {
+		if id, ok := expr.(*ast.Ident); ok {
+			return queries[id.Name]
+		}
+		return isURLQuery(expr)
+	}
+	// the keys of range loops over a query
+	rangeKeys := make(map[string]bool)
+	ast.Inspect(fd.Body, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.AssignStmt:
+			for i, rhs := range n.Rhs {
+				if id, ok := n.Lhs[i].(*ast.Ident); ok && isURLQuery(rhs) {
+					queries[id.Name] = true
+				}
+			}
+		case *ast.RangeStmt:
+			if id, ok := n.Key.(*ast.Ident); ok && isQuery(n.X) {
+				rangeKeys[id.Name] = true
+			}
+		}
+		return true
+	})
+
+	seen := make(map[string]bool)
+	ast.Inspect(fd.Body, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.CallExpr:
+			if sel, ok := n.Fun.(*ast.SelectorExpr); ok && sel.Sel.Name == "Get" && isQuery(sel.X) && len(n.Args) == 1 {
+				if key, ok := stringLit(n.Args[0]); ok {
+					seen[key] = true
+				} else {
+					params.dynamic = true
+				}
+			}
+			callee, ok := n.Fun.(*ast.Ident)
+			if !ok || qr.funcs[callee.Name] == nil {
+				break
+			}
+			for _, arg := range n.Args {
+				id, ok := arg.(*ast.Ident)
+				if isQuery(arg) || ok && requests[id.Name] {
+					calleeParams := qr.read(callee.Name)
+					for _, key := range calleeParams.names {
+						seen[key] = true
+					}
+					params.dynamic = params.dynamic || calleeParams.dynamic
+					break
+				}
+			}
+		case *ast.IndexExpr:
+			if key, ok := stringLit(n.Index); ok && isQuery(n.X) {
+				seen[key] = true
+			}
+		case *ast.SwitchStmt:
+			if id, ok := n.Tag.(*ast.Ident); ok && rangeKeys[id.Name] {
+				for _, stmt := range n.Body.List {
+					for _, expr := range stmt.(*ast.CaseClause).List {
+						if key, ok := stringLit(expr); ok {
+							seen[key] = true
+						}
+					}
+				}
+			}
+		}
+		return true
+	})
+
+	params.names = make([]string, 0, len(seen))
+	for key := range seen {
+		params.names = append(params.names, key)
+	}
+	sort.Strings(params.names)
+	return params
+}
+
+func (s *openAPISuite) TestDescribedQueryParams(c *check.C) {
+	qr := newQueryParamsReader(c)
+	// parameters that are parsed by shared helpers but have no
+	// effect on the route
+	ignored := map[string][]string{
+		"GET /v2/model":        {"remote"},
+		"GET /v2/model/serial": {"remote"},
+	}
+
+	for _, cmd := range daemon.APICommands() {
+		path := cmd.Path
+		if path == "" {
+			path = cmd.PathPrefix
+		}
+		for _, m := range []struct {
+			method string
+			f      daemon.ResponseFunc
+		}{
+			{"GET", cmd.GET},
+			{"PUT", cmd.PUT},
+			{"POST", cmd.POST},
+		} {
+			if m.f == nil {
+				continue
+			}
+			name := runtime.FuncForPC(reflect.ValueOf(m.f).Pointer()).Name()
+			name = name[strings.LastIndex(name, ".")+1:]
+			c.Assert(qr.funcs[name], check.NotNil, check.Commentf("cannot find the source of the handler of %s %s", m.method, path))
+
+			route := m.method + " " + path
+			described := append([]string{}, daemon.APIDocsQuery(path, m.method)...)
+			described = append(described, ignored[route]...)
+			sort.Strings(described)
+			read := qr.read(name)
+			comment := check.Commentf("query parameters of %s", route)
+			if !read.dynamic {
+				c.Check(described, check.DeepEquals, read.names, comment)
+				continue
+			}
+			// the names that are not literals cannot be checked
+			for _, name := range read.names {
+				c.Check(strutil.SortedListContains(described, name), check.Equals, true, comment)
+			}
+		}
+	}
+}
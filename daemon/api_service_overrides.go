@@ -0,0 +1,136 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package daemon
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+
+	"github.com/snapcore/snapd/client"
+	"github.com/snapcore/snapd/overlord/auth"
+	"github.com/snapcore/snapd/overlord/servicestate"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/strutil"
+)
+
+var serviceOverridesCmd = &Command{
+	Path: "/v2/service-overrides",
+	GET:  getServiceOverrides,
+	POST: postServiceOverride,
+	// the overridden environment might hold secrets
+	ReadAccess:  authenticatedAccess{Polkit: polkitActionManage},
+	WriteAccess: rootAccess{},
+}
+
+type postServiceOverrideData struct {
+	// Action can be "set" or "reset"
+	Action string `json:"action"`
+	// Name is the service, as snap.app
+	Name string `json:"name"`
+	// Settings are the settings to override, for "set"
+	Settings map[string]string `json:"settings,omitempty"`
+	// Keys are the settings to reset, all if empty, for "reset"
+	Keys []string `json:"keys,omitempty"`
+}
+
+var (
+	servicestateSetServiceOverride   = servicestate.SetServiceOverride
+	servicestateResetServiceOverride = servicestate.ResetServiceOverride
+)
+
+// getServiceOverrides returns the settings overridden for services, sorted
+// by snap and app, optionally only for the snaps or snap.app services given
+// in names.
+func getServiceOverrides(c *Command, r *http.Request, _ *auth.UserState) Response {
+	names := strutil.CommaSeparatedList(r.URL.Query().Get("names"))
+
+	st := c.d.overlord.State()
+	st.Lock()
+	defer st.Unlock()
+
+	all, err := servicestate.AllServiceOverrides(st)
+	if err != nil {
+		return InternalError(err.Error())
+	}
+
+	results := []client.ServiceOverride{}
+	for snapName, overrides := range all {
+		for appName, override := range overrides {
+			if len(names) > 0 && !strutil.ListContains(names, snapName) && !strutil.ListContains(names, snap.JoinSnapApp(snapName, appName)) {
+				continue
+			}
+			results = append(results, client.ServiceOverride{
+				Snap:     snapName,
+				App:      appName,
+				Settings: servicestate.ServiceOverrideSettings(override),
+			})
+		}
+	}
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Snap != results[j].Snap {
+			return results[i].Snap < results[j].Snap
+		}
+		return results[i].App < results[j].App
+	})
+	return SyncResponse(results)
+}
+
+// postServiceOverride overrides settings of a service or resets them.
+func postServiceOverride(c *Command, r *http.Request, _ *auth.UserState) Response {
+	var data postServiceOverrideData
+	if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
+		return BadRequest("cannot decode service override action from request body: %v", err)
+	}
+	if data.Name == "" {
+		return BadRequest("cannot override service settings without a service name")
+	}
+	snapName, appName := snap.SplitSnapApp(data.Name)
+
+	st := c.d.overlord.State()
+	st.Lock()
+	defer st.Unlock()
+
+	var ts *state.TaskSet
+	var err error
+	var chgSummary string
+	switch data.Action {
+	case "set":
+		ts, err = servicestateSetServiceOverride(st, snapName, appName, data.Settings)
+		if err != nil {
+			return errToResponse(err, []string{snapName}, BadRequest, "cannot override service settings: %v")
+		}
+		chgSummary = "Override settings of service %q"
+	case "reset":
+		ts, err = servicestateResetServiceOverride(st, snapName, appName, data.Keys)
+		if err != nil {
+			return errToResponse(err, []string{snapName}, BadRequest, "cannot reset service settings: %v")
+		}
+		chgSummary = "Reset overridden settings of service %q"
+	default:
+		return BadRequest("unknown service override action %q", data.Action)
+	}
+
+	chg := newChange(st, "service-override", fmt.Sprintf(chgSummary, data.Name), []*state.TaskSet{ts}, []string{snapName})
+	ensureStateSoon(st)
+	return AsyncResponse(nil, chg.ID())
+}
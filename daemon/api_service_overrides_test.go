@@ -0,0 +1,204 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package daemon_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/client"
+	"github.com/snapcore/snapd/daemon"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/timeout"
+	"github.com/snapcore/snapd/wrappers"
+)
+
+var _ = check.Suite(&apiServiceOverridesSuite{})
+
+type apiServiceOverridesSuite struct {
+	apiBaseSuite
+
+	ensureSoonCalled int
+}
+
+func (s *apiServiceOverridesSuite) SetUpTest(c *check.C) {
+	s.apiBaseSuite.SetUpTest(c)
+	s.daemon(c)
+
+	s.expectReadAccess(daemon.AuthenticatedAccess{Polkit: "io.snapcraft.snapd.manage"})
+	s.expectWriteAccess(daemon.RootAccess{})
+
+	s.ensureSoonCalled = 0
+	_, r := daemon.MockEnsureStateSoon(func(st *state.State) {
+		s.ensureSoonCalled++
+	})
+	s.AddCleanup(r)
+}
+
+func (s *apiServiceOverridesSuite) postServiceOverride(c *check.C, data daemon.PostServiceOverrideData) *http.Request {
+	body, err := json.Marshal(data)
+	c.Assert(err, check.IsNil)
+	req, err := http.NewRequest("POST", "/v2/service-overrides", bytes.NewBuffer(body))
+	c.Assert(err, check.IsNil)
+	return req
+}
+
+func (s *apiServiceOverridesSuite) TestGetServiceOverrides(c *check.C) {
+	st := s.d.Overlord().State()
+	st.Lock()
+	st.Set("service-overrides", map[string]map[string]*wrappers.ServiceOverride{
+		"foo": {
+			"svc2": {Restart: snap.RestartAlways},
+			"svc1": {
+				RestartDelay: timeout.Timeout(10 * time.Second),
+				Environment:  map[string]string{"FOO": "bar"},
+			},
+		},
+		"bar": {
+			"bar": {StartTimeout: timeout.Timeout(time.Minute)},
+		},
+	})
+	st.Unlock()
+
+	req, err := http.NewRequest("GET", "/v2/service-overrides", nil)
+	c.Assert(err, check.IsNil)
+	rsp := s.syncReq(c, req, nil)
+	c.Assert(rsp.Status, check.Equals, 200)
+	c.Check(rsp.Result, check.DeepEquals, []client.ServiceOverride{
+		{Snap: "bar", App: "bar", Settings: map[string]string{"start-timeout": "1m0s"}},
+		{Snap: "foo", App: "svc1", Settings: map[string]string{"restart-delay": "10s", "environment.FOO": "bar"}},
+		{Snap: "foo", App: "svc2", Settings: map[string]string{"restart": "always"}},
+	})
+
+	// filtered by snap or service
+	req, err = http.NewRequest("GET", "/v2/service-overrides?names=bar,foo.svc2", nil)
+	c.Assert(err, check.IsNil)
+	rsp = s.syncReq(c, req, nil)
+	c.Check(rsp.Result, check.DeepEquals, []client.ServiceOverride{
+		{Snap: "bar", App: "bar", Settings: map[string]string{"start-timeout": "1m0s"}},
+		{Snap: "foo", App: "svc2", Settings: map[string]string{"restart": "always"}},
+	})
+}
+
+func (s *apiServiceOverridesSuite) TestGetServiceOverridesNone(c *check.C) {
+	req, err := http.NewRequest("GET", "/v2/service-overrides", nil)
+	c.Assert(err, check.IsNil)
+	rsp := s.syncReq(c, req, nil)
+	c.Check(rsp.Result, check.DeepEquals, []client.ServiceOverride{})
+}
+
+func (s *apiServiceOverridesSuite) TestPostSetServiceOverride(c *check.C) {
+	called := 0
+	r := daemon.MockServicestateSetServiceOverride(func(st *state.State, instanceName, appName string, settings map[string]string) (*state.TaskSet, error) {
+		called++
+		c.Check(instanceName, check.Equals, "foo")
+		c.Check(appName, check.Equals, "svc")
+		c.Check(settings, check.DeepEquals, map[string]string{"restart": "always"})
+		return state.NewTaskSet(st.NewTask("service-override", "...")), nil
+	})
+	defer r()
+
+	req := s.postServiceOverride(c, daemon.PostServiceOverrideData{
+		Action:   "set",
+		Name:     "foo.svc",
+		Settings: map[string]string{"restart": "always"},
+	})
+	rsp := s.asyncReq(c, req, nil)
+	c.Check(called, check.Equals, 1)
+	c.Check(s.ensureSoonCalled, check.Equals, 1)
+
+	st := s.d.Overlord().State()
+	st.Lock()
+	defer st.Unlock()
+	chg := st.Change(rsp.Change)
+	c.Assert(chg, check.NotNil)
+	c.Check(chg.Kind(), check.Equals, "service-override")
+	c.Check(chg.Summary(), check.Equals, `Override settings of service "foo.svc"`)
+	var snapNames []string
+	c.Assert(chg.Get("snap-names", &snapNames), check.IsNil)
+	c.Check(snapNames, check.DeepEquals, []string{"foo"})
+}
+
+func (s *apiServiceOverridesSuite) TestPostResetServiceOverride(c *check.C) {
+	called := 0
+	r := daemon.MockServicestateResetServiceOverride(func(st *state.State, instanceName, appName string, keys []string) (*state.TaskSet, error) {
+		called++
+		// the app named as the snap
+		c.Check(instanceName, check.Equals, "foo")
+		c.Check(appName, check.Equals, "foo")
+		c.Check(keys, check.DeepEquals, []string{"environment.FOO"})
+		return state.NewTaskSet(st.NewTask("service-override", "...")), nil
+	})
+	defer r()
+
+	req := s.postServiceOverride(c, daemon.PostServiceOverrideData{
+		Action: "reset",
+		Name:   "foo",
+		Keys:   []string{"environment.FOO"},
+	})
+	rsp := s.asyncReq(c, req, nil)
+	c.Check(called, check.Equals, 1)
+
+	st := s.d.Overlord().State()
+	st.Lock()
+	defer st.Unlock()
+	chg := st.Change(rsp.Change)
+	c.Assert(chg, check.NotNil)
+	c.Check(chg.Summary(), check.Equals, `Reset overridden settings of service "foo"`)
+}
+
+func (s *apiServiceOverridesSuite) TestPostServiceOverrideErrors(c *check.C) {
+	r := daemon.MockServicestateSetServiceOverride(func(st *state.State, instanceName, appName string, settings map[string]string) (*state.TaskSet, error) {
+		switch instanceName {
+		case "missing":
+			return nil, &snap.NotInstalledError{Snap: "missing"}
+		default:
+			return nil, errors.New(`invalid restart condition "sometimes"`)
+		}
+	})
+	defer r()
+
+	for _, t := range []struct {
+		data    daemon.PostServiceOverrideData
+		status  int
+		message string
+	}{
+		{daemon.PostServiceOverrideData{Action: "set"}, 400, `cannot override service settings without a service name`},
+		{daemon.PostServiceOverrideData{Action: "frob", Name: "foo.svc"}, 400, `unknown service override action "frob"`},
+		{daemon.PostServiceOverrideData{Action: "set", Name: "missing.svc"}, 400, `snap "missing" is not installed`},
+		{daemon.PostServiceOverrideData{Action: "set", Name: "foo.svc"}, 400, `cannot override service settings: invalid restart condition "sometimes"`},
+	} {
+		rspe := s.errorReq(c, s.postServiceOverride(c, t.data), nil)
+		c.Check(rspe.Status, check.Equals, t.status, check.Commentf("%+v", t.data))
+		c.Check(rspe.Message, check.Equals, t.message, check.Commentf("%+v", t.data))
+	}
+
+	req, err := http.NewRequest("POST", "/v2/service-overrides", bytes.NewBufferString("{"))
+	c.Assert(err, check.IsNil)
+	rspe := s.errorReq(c, req, nil)
+	c.Check(rspe.Status, check.Equals, 400)
+	c.Check(rspe.Message, check.Matches, `cannot decode service override action from request body: .*`)
+}
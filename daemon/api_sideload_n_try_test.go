@@ -57,7 +57,7 @@ type sideloadSuite struct {
 func (s *sideloadSuite) SetUpTest(c *check.C) {
 	s.apiBaseSuite.SetUpTest(c)
 
-	s.expectWriteAccess(daemon.AuthenticatedAccess{Polkit: "io.snapcraft.snapd.manage"})
+	s.expectWriteAccess(daemon.AuthenticatedAccess{Polkit: "io.snapcraft.snapd.manage", Policy: daemon.AccessPolicyScopeSnaps})
 }
 
 var sideLoadBodyWithoutDevMode = "" +
@@ -486,7 +486,7 @@ type trySuite struct {
 func (s *trySuite) SetUpTest(c *check.C) {
 	s.apiBaseSuite.SetUpTest(c)
 
-	s.expectWriteAccess(daemon.AuthenticatedAccess{Polkit: "io.snapcraft.snapd.manage"})
+	s.expectWriteAccess(daemon.AuthenticatedAccess{Polkit: "io.snapcraft.snapd.manage", Policy: daemon.AccessPolicyScopeSnaps})
 }
 
 func (s *trySuite) TestTrySnap(c *check.C) {
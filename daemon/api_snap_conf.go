@@ -30,6 +30,7 @@ import (
 	"github.com/snapcore/snapd/overlord/configstate/config"
 	"github.com/snapcore/snapd/overlord/state"
 	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/snap/configschema"
 	"github.com/snapcore/snapd/strutil"
 )
 
@@ -38,6 +39,7 @@ var (
 		Path:        "/v2/snaps/{name}/conf",
 		GET:         getSnapConf,
 		PUT:         setSnapConf,
+		POST:        postSnapConf,
 		ReadAccess:  authenticatedAccess{},
 		WriteAccess: authenticatedAccess{},
 	}
@@ -47,12 +49,35 @@ func getSnapConf(c *Command, r *http.Request, user *auth.UserState) Response {
 	vars := muxVars(r)
 	snapName := configstate.RemapSnapFromRequest(vars["name"])
 
-	keys := strutil.CommaSeparatedList(r.URL.Query().Get("keys"))
+	query := r.URL.Query()
+	keys := strutil.CommaSeparatedList(query.Get("keys"))
 
 	s := c.d.overlord.State()
+	if query.Get("schema") == "true" {
+		s.Lock()
+		schema, err := configstate.ConfigSchema(s, snapName)
+		s.Unlock()
+		if err != nil {
+			return InternalError("%v", err)
+		}
+		return getSnapConfSchema(snapName, schema, keys)
+	}
+
 	s.Lock()
 	tr := config.NewTransaction(s)
 	s.Unlock()
+	if query.Get("history") == "true" {
+		s.Lock()
+		defer s.Unlock()
+		history, err := config.History(s, snapName)
+		if err != nil {
+			return InternalError("%v", err)
+		}
+		if history == nil {
+			history = []*config.HistoryEntry{}
+		}
+		return SyncResponse(history)
+	}
 
 	currentConfValues := make(map[string]interface{})
 	// Special case - return root document
@@ -91,6 +116,29 @@ func getSnapConf(c *Command, r *http.Request, user *auth.UserState) Response {
 	return SyncResponse(currentConfValues)
 }
 
+func getSnapConfSchema(snapName string, schema *configschema.Schema, keys []string) Response {
+	snapName = configstate.RemapSnapToResponse(snapName)
+	if schema == nil {
+		return BadRequest("snap %q has no configuration schema", snapName)
+	}
+	if len(keys) == 0 {
+		return SyncResponse(schema)
+	}
+	schemas := make(map[string]*configschema.Schema, len(keys))
+	for _, key := range keys {
+		option := schema.Lookup(key)
+		if option == nil {
+			return &apiError{
+				Status:  400,
+				Message: fmt.Sprintf("snap %q has no %q option in its configuration schema", snapName, key),
+				Kind:    client.ErrorKindConfigNoSuchOption,
+			}
+		}
+		schemas[key] = option
+	}
+	return SyncResponse(schemas)
+}
+
 func setSnapConf(c *Command, r *http.Request, user *auth.UserState) Response {
 	vars := muxVars(r)
 	snapName := configstate.RemapSnapFromRequest(vars["name"])
@@ -115,6 +163,48 @@ func setSnapConf(c *Command, r *http.Request, user *auth.UserState) Response {
 
 	summary := fmt.Sprintf("Change configuration of %q snap", snapName)
 	change := newChange(st, "configure-snap", summary, []*state.TaskSet{taskset}, []string{snapName})
+	change.Set("initiator", changeInitiator(r, user))
+
+	st.EnsureBefore(0)
+
+	return AsyncResponse(nil, change.ID())
+}
+
+type snapConfInstruction struct {
+	Action   string `json:"action"`
+	Revision int    `json:"revision,omitempty"`
+}
+
+func postSnapConf(c *Command, r *http.Request, user *auth.UserState) Response {
+	vars := muxVars(r)
+	snapName := configstate.RemapSnapFromRequest(vars["name"])
+
+	var inst snapConfInstruction
+	if err := jsonutil.DecodeWithNumber(r.Body, &inst); err != nil {
+		return BadRequest("cannot decode request body into configuration action: %v", err)
+	}
+	if inst.Action != "revert" {
+		return BadRequest("unknown configuration action %q", inst.Action)
+	}
+	if inst.Revision < 0 {
+		return BadRequest("invalid configuration revision %d", inst.Revision)
+	}
+
+	st := c.d.overlord.State()
+	st.Lock()
+	defer st.Unlock()
+
+	taskset, err := configstate.RevertConfig(st, snapName, inst.Revision)
+	if err != nil {
+		if _, ok := err.(*snap.NotInstalledError); ok {
+			return SnapNotFound(snapName, err)
+		}
+		return errToResponse(err, []string{snapName}, BadRequest, "%v")
+	}
+
+	summary := fmt.Sprintf("Revert configuration of %q snap", snapName)
+	change := newChange(st, "revert-snap-config", summary, []*state.TaskSet{taskset}, []string{snapName})
+	change.Set("initiator", changeInitiator(r, user))
 
 	st.EnsureBefore(0)
 
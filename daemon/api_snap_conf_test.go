@@ -31,8 +31,10 @@ import (
 
 	"gopkg.in/check.v1"
 
+	"github.com/snapcore/snapd/client"
 	"github.com/snapcore/snapd/dirs"
 	"github.com/snapcore/snapd/overlord/configstate/config"
+	"github.com/snapcore/snapd/snap/configschema"
 	"github.com/snapcore/snapd/testutil"
 )
 
@@ -129,6 +131,233 @@ func (s *snapConfSuite) TestGetConfBadKey(c *check.C) {
 	c.Check(result, check.DeepEquals, map[string]interface{}{"message": `invalid option name: ""`})
 }
 
+const configSchemaYaml = `
+properties:
+  port:
+    type: integer
+    description: The port to listen on
+    default: 8080
+  proxy:
+    type: object
+    properties:
+      url:
+        type: string
+      timeout:
+        type: integer
+        default: 30
+`
+
+func (s *snapConfSuite) mockSnapWithSchema(c *check.C) {
+	info := s.mockSnap(c, configYaml)
+	err := ioutil.WriteFile(filepath.Join(info.MountDir(), "meta", "config-schema.yaml"), []byte(configSchemaYaml), 0644)
+	c.Assert(err, check.IsNil)
+}
+
+func (s *snapConfSuite) TestGetConfSchema(c *check.C) {
+	s.daemon(c)
+	s.mockSnapWithSchema(c)
+
+	req, err := http.NewRequest("GET", "/v2/snaps/config-snap/conf?schema=true&keys=port,proxy.url", nil)
+	c.Assert(err, check.IsNil)
+	rsp := s.syncReq(c, req, nil)
+	c.Check(rsp.Result, check.DeepEquals, map[string]*configschema.Schema{
+		"port": {
+			Type:        "integer",
+			Description: "The port to listen on",
+			Default:     int64(8080),
+		},
+		"proxy.url": {
+			Type: "string",
+		},
+	})
+
+	req, err = http.NewRequest("GET", "/v2/snaps/config-snap/conf?schema=true", nil)
+	c.Assert(err, check.IsNil)
+	rsp = s.syncReq(c, req, nil)
+	schema, ok := rsp.Result.(*configschema.Schema)
+	c.Assert(ok, check.Equals, true)
+	c.Check(schema.Properties, check.HasLen, 2)
+
+	req, err = http.NewRequest("GET", "/v2/snaps/config-snap/conf?schema=true&keys=missing", nil)
+	c.Assert(err, check.IsNil)
+	rspe := s.errorReq(c, req, nil)
+	c.Check(rspe.Status, check.Equals, 400)
+	c.Check(rspe.Message, check.Equals, `snap "config-snap" has no "missing" option in its configuration schema`)
+}
+
+func (s *snapConfSuite) TestGetConfSchemaNoSchema(c *check.C) {
+	s.daemon(c)
+	s.mockSnap(c, configYaml)
+
+	req, err := http.NewRequest("GET", "/v2/snaps/config-snap/conf?schema=true", nil)
+	c.Assert(err, check.IsNil)
+	rspe := s.errorReq(c, req, nil)
+	c.Check(rspe.Status, check.Equals, 400)
+	c.Check(rspe.Message, check.Equals, `snap "config-snap" has no configuration schema`)
+}
+
+func (s *snapConfSuite) TestGetConfDoesNotReadSchema(c *check.C) {
+	d := s.daemon(c)
+	info := s.mockSnap(c, configYaml)
+	err := ioutil.WriteFile(filepath.Join(info.MountDir(), "meta", "config-schema.yaml"), []byte("type: array"), 0644)
+	c.Assert(err, check.IsNil)
+
+	d.Overlord().State().Lock()
+	tr := config.NewTransaction(d.Overlord().State())
+	tr.Set("config-snap", "port", "8080")
+	tr.Commit()
+	d.Overlord().State().Unlock()
+
+	// the schema is only read when asked for
+	result := s.runGetConf(c, "config-snap", []string{"port"}, 200)
+	c.Check(result, check.DeepEquals, map[string]interface{}{"port": "8080"})
+
+	req, err := http.NewRequest("GET", "/v2/snaps/config-snap/conf?schema=true", nil)
+	c.Assert(err, check.IsNil)
+	rspe := s.errorReq(c, req, nil)
+	c.Check(rspe.Status, check.Equals, 500)
+	c.Check(rspe.Message, check.Matches, "invalid configuration schema: .*")
+}
+
+func (s *snapConfSuite) TestSetConfSchemaInvalid(c *check.C) {
+	s.daemon(c)
+	s.mockSnapWithSchema(c)
+
+	text, err := json.Marshal(map[string]interface{}{"port": "abc"})
+	c.Assert(err, check.IsNil)
+
+	req, err := http.NewRequest("PUT", "/v2/snaps/config-snap/conf", bytes.NewBuffer(text))
+	c.Assert(err, check.IsNil)
+	rspe := s.errorReq(c, req, nil)
+	c.Check(rspe.Status, check.Equals, 400)
+	c.Check(rspe.Kind, check.Equals, client.ErrorKindConfigInvalidOption)
+	c.Check(rspe.Message, check.Equals, `invalid value for option "port": expected integer, got string "abc"`)
+	c.Check(rspe.Value, check.Equals, "port")
+}
+
+func (s *snapConfSuite) recordConfig(c *check.C, cfg map[string]interface{}, initiator string) {
+	st := s.d.Overlord().State()
+	st.Lock()
+	defer st.Unlock()
+	previous, err := config.GetSnapConfig(st, "config-snap")
+	c.Assert(err, check.IsNil)
+	tr := config.NewTransaction(st)
+	c.Assert(config.Patch(tr, "config-snap", cfg), check.IsNil)
+	tr.Commit()
+	c.Assert(config.RecordHistory(st, "config-snap", previous, initiator, ""), check.IsNil)
+}
+
+func (s *snapConfSuite) TestGetConfHistory(c *check.C) {
+	s.daemon(c)
+
+	req, err := http.NewRequest("GET", "/v2/snaps/config-snap/conf?history=true", nil)
+	c.Assert(err, check.IsNil)
+	rsp := s.syncReq(c, req, nil)
+	c.Check(rsp.Result, check.DeepEquals, []*config.HistoryEntry{})
+
+	s.recordConfig(c, map[string]interface{}{"key": "value1"}, "user1")
+	s.recordConfig(c, map[string]interface{}{"key": "value2"}, "user2")
+
+	rsp = s.syncReq(c, req, nil)
+	history, ok := rsp.Result.([]*config.HistoryEntry)
+	c.Assert(ok, check.Equals, true)
+	c.Assert(history, check.HasLen, 2)
+	c.Check(history[0].Revision, check.Equals, 1)
+	c.Check(history[0].Initiator, check.Equals, "user1")
+	c.Check(string(*history[0].Config), check.Equals, `{"key":"value1"}`)
+	c.Check(history[1].Revision, check.Equals, 2)
+	c.Check(history[1].Initiator, check.Equals, "user2")
+}
+
+func (s *snapConfSuite) TestPostConfRevert(c *check.C) {
+	d := s.daemon(c)
+	s.mockSnap(c, configYaml)
+
+	// Mock the hook runner
+	hookRunner := testutil.MockCommand(c, "snap", "")
+	defer hookRunner.Restore()
+
+	d.Overlord().Loop()
+	defer d.Overlord().Stop()
+
+	s.recordConfig(c, map[string]interface{}{"key": "value1"}, "user1")
+	s.recordConfig(c, map[string]interface{}{"key": "value2", "other": true}, "user2")
+
+	req, err := http.NewRequest("POST", "/v2/snaps/config-snap/conf", strings.NewReader(`{"action": "revert", "revision": 1}`))
+	c.Assert(err, check.IsNil)
+	s.asRootAuth(req)
+	rsp := s.asyncReq(c, req, nil)
+
+	st := d.Overlord().State()
+	st.Lock()
+	defer st.Unlock()
+	chg := st.Change(rsp.Change)
+	c.Assert(chg, check.NotNil)
+	c.Check(chg.Kind(), check.Equals, "revert-snap-config")
+	c.Check(chg.Summary(), check.Equals, `Revert configuration of "config-snap" snap`)
+	var initiator string
+	c.Assert(chg.Get("initiator", &initiator), check.IsNil)
+	c.Check(initiator, check.Equals, "uid:0")
+
+	tasks := chg.Tasks()
+	c.Assert(tasks, check.HasLen, 1)
+	var hookContext map[string]interface{}
+	c.Assert(tasks[0].Get("hook-context", &hookContext), check.IsNil)
+	c.Check(hookContext["patch"], check.DeepEquals, map[string]interface{}{"key": "value1", "other": nil})
+}
+
+func (s *snapConfSuite) TestPostConfRevertErrors(c *check.C) {
+	s.daemon(c)
+	s.mockSnap(c, configYaml)
+
+	for _, tc := range []struct {
+		body    string
+		status  int
+		message string
+	}{
+		{`{"action": "foo"}`, 400, `unknown configuration action "foo"`},
+		{`{"action": "revert", "revision": -1}`, 400, `invalid configuration revision -1`},
+		{`{"action": "revert"}`, 400, `snap "config-snap" has no previous configuration`},
+		{`{"action": "revert", "revision": 3}`, 400, `snap "config-snap" has no configuration revision 3`},
+		{`}`, 400, `cannot decode request body into configuration action: .*`},
+	} {
+		req, err := http.NewRequest("POST", "/v2/snaps/config-snap/conf", strings.NewReader(tc.body))
+		c.Assert(err, check.IsNil)
+		rspe := s.errorReq(c, req, nil)
+		c.Check(rspe.Status, check.Equals, tc.status, check.Commentf(tc.body))
+		c.Check(rspe.Message, check.Matches, tc.message, check.Commentf(tc.body))
+	}
+
+	req, err := http.NewRequest("POST", "/v2/snaps/other-snap/conf", strings.NewReader(`{"action": "revert"}`))
+	c.Assert(err, check.IsNil)
+	rspe := s.errorReq(c, req, nil)
+	c.Check(rspe.Status, check.Equals, 404)
+}
+
+func (s *snapConfSuite) TestSetConfInitiator(c *check.C) {
+	d := s.daemon(c)
+	s.mockSnap(c, configYaml)
+
+	// Mock the hook runner
+	hookRunner := testutil.MockCommand(c, "snap", "")
+	defer hookRunner.Restore()
+
+	d.Overlord().Loop()
+	defer d.Overlord().Stop()
+
+	req, err := http.NewRequest("PUT", "/v2/snaps/config-snap/conf", strings.NewReader(`{"key": "value"}`))
+	c.Assert(err, check.IsNil)
+	s.asUserAuth(c, req)
+	rsp := s.asyncReq(c, req, s.authUser)
+
+	st := d.Overlord().State()
+	st.Lock()
+	defer st.Unlock()
+	var initiator string
+	c.Assert(st.Change(rsp.Change).Get("initiator", &initiator), check.IsNil)
+	c.Check(initiator, check.Equals, "username")
+}
+
 const configYaml = `
 name: config-snap
 version: 1
@@ -0,0 +1,168 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package daemon
+
+import (
+	"net/http"
+	"sort"
+
+	"github.com/snapcore/snapd/client"
+	"github.com/snapcore/snapd/gadget/quantity"
+	"github.com/snapcore/snapd/overlord/auth"
+	"github.com/snapcore/snapd/overlord/snapstate"
+	"github.com/snapcore/snapd/sandbox/cgroup"
+	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/snap/naming"
+	"github.com/snapcore/snapd/strutil"
+)
+
+var (
+	snapUsageCmd = &Command{
+		Path:       "/v2/snaps/{name}/usage",
+		GET:        getSnapUsage,
+		ReadAccess: openAccess{},
+	}
+
+	usageCmd = &Command{
+		Path:       "/v2/usage",
+		GET:        getUsage,
+		ReadAccess: openAccess{},
+	}
+)
+
+var (
+	cgroupUsageOfSnap     = cgroup.UsageOfSnap
+	cgroupUsageOfAllSnaps = cgroup.UsageOfAllSnaps
+)
+
+func resourceUsage(usage *cgroup.Usage) client.ResourceUsage {
+	return client.ResourceUsage{
+		CPUTime:     usage.CPUTime,
+		Memory:      quantity.Size(usage.Memory),
+		Processes:   usage.Processes,
+		IORead:      quantity.Size(usage.IORead),
+		IOWrite:     quantity.Size(usage.IOWrite),
+		Unavailable: usage.Unavailable,
+	}
+}
+
+// snapUsage builds the usage of the given snap out of the usage of the
+// security tags of any snaps.
+func snapUsage(snapName string, usageByTag map[string]*cgroup.Usage) (client.SnapUsage, error) {
+	var total cgroup.Usage
+	apps := make([]client.AppUsage, 0, len(usageByTag))
+	for tag, usage := range usageByTag {
+		parsedTag, err := naming.ParseSecurityTag(tag)
+		if err != nil {
+			return client.SnapUsage{}, err
+		}
+		if parsedTag.InstanceName() != snapName {
+			continue
+		}
+		total.Add(usage)
+		var name string
+		switch parsedTag := parsedTag.(type) {
+		case naming.AppSecurityTag:
+			name = parsedTag.AppName()
+		case naming.HookSecurityTag:
+			name = "hook." + parsedTag.HookName()
+		}
+		apps = append(apps, client.AppUsage{Name: name, ResourceUsage: resourceUsage(usage)})
+	}
+	sort.Slice(apps, func(i, j int) bool { return apps[i].Name < apps[j].Name })
+
+	return client.SnapUsage{
+		Snap:          snapName,
+		ResourceUsage: resourceUsage(&total),
+		Apps:          apps,
+	}, nil
+}
+
+func getSnapUsage(c *Command, r *http.Request, user *auth.UserState) Response {
+	snapName := muxVars(r)["name"]
+
+	st := c.d.overlord.State()
+	st.Lock()
+	_, err := snapstate.CurrentInfo(st, snapName)
+	st.Unlock()
+	if _, ok := err.(*snap.NotInstalledError); ok {
+		return SnapNotFound(snapName, err)
+	}
+	if err != nil {
+		return InternalError("%v", err)
+	}
+
+	usageByTag, err := cgroupUsageOfSnap(snapName)
+	if err != nil {
+		return InternalError("cannot obtain resource usage of snap %q: %v", snapName, err)
+	}
+	usage, err := snapUsage(snapName, usageByTag)
+	if err != nil {
+		return InternalError("%v", err)
+	}
+	return SyncResponse(usage)
+}
+
+// getUsage returns the resource usage of the given snaps, or of all
+// installed snaps, out of a single scan of the cgroups.
+func getUsage(c *Command, r *http.Request, user *auth.UserState) Response {
+	names := strutil.CommaSeparatedList(r.URL.Query().Get("snaps"))
+
+	st := c.d.overlord.State()
+	st.Lock()
+	if len(names) == 0 {
+		all, err := snapstate.All(st)
+		if err != nil {
+			st.Unlock()
+			return InternalError("%v", err)
+		}
+		for name := range all {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+	} else {
+		for _, name := range names {
+			_, err := snapstate.CurrentInfo(st, name)
+			if _, ok := err.(*snap.NotInstalledError); ok {
+				st.Unlock()
+				return SnapNotFound(name, err)
+			}
+			if err != nil {
+				st.Unlock()
+				return InternalError("%v", err)
+			}
+		}
+	}
+	st.Unlock()
+
+	usageByTag, err := cgroupUsageOfAllSnaps()
+	if err != nil {
+		return InternalError("cannot obtain resource usage of snaps: %v", err)
+	}
+	usages := make([]client.SnapUsage, 0, len(names))
+	for _, name := range names {
+		usage, err := snapUsage(name, usageByTag)
+		if err != nil {
+			return InternalError("%v", err)
+		}
+		usages = append(usages, usage)
+	}
+	return SyncResponse(usages)
+}
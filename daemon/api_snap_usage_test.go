@@ -0,0 +1,208 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package daemon_test
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/client"
+	"github.com/snapcore/snapd/daemon"
+	"github.com/snapcore/snapd/sandbox/cgroup"
+)
+
+var _ = Suite(&snapUsageSuite{})
+
+type snapUsageSuite struct {
+	apiBaseSuite
+}
+
+func (s *snapUsageSuite) SetUpTest(c *C) {
+	s.apiBaseSuite.SetUpTest(c)
+	s.expectOpenAccess()
+}
+
+const usageSnapYaml = `name: foo
+version: 1
+apps:
+  app:
+  daemon:
+    daemon: simple
+`
+
+func (s *snapUsageSuite) TestSnapUsage(c *C) {
+	s.daemon(c)
+	s.mockSnap(c, usageSnapYaml)
+	restore := daemon.MockCgroupUsageOfSnap(func(snapName string) (map[string]*cgroup.Usage, error) {
+		c.Check(snapName, Equals, "foo")
+		return map[string]*cgroup.Usage{
+			"snap.foo.daemon": {
+				CPUTime: 2 * time.Second, Memory: 2048, Processes: 2, IORead: 10, IOWrite: 20,
+			},
+			"snap.foo.hook.configure": {
+				CPUTime: time.Second, Memory: 1024, Processes: 1,
+			},
+		}, nil
+	})
+	defer restore()
+
+	req, err := http.NewRequest("GET", "/v2/snaps/foo/usage", nil)
+	c.Assert(err, IsNil)
+	rsp := s.syncReq(c, req, nil)
+	c.Check(rsp.Result, DeepEquals, client.SnapUsage{
+		Snap: "foo",
+		ResourceUsage: client.ResourceUsage{
+			CPUTime: 3 * time.Second, Memory: 3072, Processes: 3, IORead: 10, IOWrite: 20,
+		},
+		Apps: []client.AppUsage{{
+			Name: "daemon",
+			ResourceUsage: client.ResourceUsage{
+				CPUTime: 2 * time.Second, Memory: 2048, Processes: 2, IORead: 10, IOWrite: 20,
+			},
+		}, {
+			Name: "hook.configure",
+			ResourceUsage: client.ResourceUsage{
+				CPUTime: time.Second, Memory: 1024, Processes: 1,
+			},
+		}},
+	})
+}
+
+func (s *snapUsageSuite) TestSnapUsageNotRunning(c *C) {
+	s.daemon(c)
+	s.mockSnap(c, usageSnapYaml)
+	restore := daemon.MockCgroupUsageOfSnap(func(snapName string) (map[string]*cgroup.Usage, error) {
+		return nil, nil
+	})
+	defer restore()
+
+	req, err := http.NewRequest("GET", "/v2/snaps/foo/usage", nil)
+	c.Assert(err, IsNil)
+	rsp := s.syncReq(c, req, nil)
+	c.Check(rsp.Result, DeepEquals, client.SnapUsage{
+		Snap: "foo",
+		Apps: []client.AppUsage{},
+	})
+}
+
+func (s *snapUsageSuite) TestSnapUsageErrors(c *C) {
+	s.daemon(c)
+	s.mockSnap(c, usageSnapYaml)
+	restore := daemon.MockCgroupUsageOfSnap(func(snapName string) (map[string]*cgroup.Usage, error) {
+		return nil, errors.New("boom")
+	})
+	defer restore()
+
+	req, err := http.NewRequest("GET", "/v2/snaps/bar/usage", nil)
+	c.Assert(err, IsNil)
+	rspe := s.errorReq(c, req, nil)
+	c.Check(rspe.Status, Equals, 404)
+	c.Check(rspe.Message, Equals, `snap "bar" is not installed`)
+
+	req, err = http.NewRequest("GET", "/v2/snaps/foo/usage", nil)
+	c.Assert(err, IsNil)
+	rspe = s.errorReq(c, req, nil)
+	c.Check(rspe.Status, Equals, 500)
+	c.Check(rspe.Message, Equals, `cannot obtain resource usage of snap "foo": boom`)
+}
+
+func (s *snapUsageSuite) TestUsage(c *C) {
+	s.daemon(c)
+	s.mockSnap(c, usageSnapYaml)
+	s.mockSnap(c, "name: bar\nversion: 1\napps:\n  app:\n")
+	calls := 0
+	restore := daemon.MockCgroupUsageOfAllSnaps(func() (map[string]*cgroup.Usage, error) {
+		calls++
+		return map[string]*cgroup.Usage{
+			"snap.foo.daemon": {
+				CPUTime: 2 * time.Second, Memory: 2048, Processes: 2,
+			},
+			"snap.other.app": {
+				CPUTime: time.Second, Processes: 1,
+			},
+			"snap.bar.app": {
+				Processes: 1, Unavailable: []string{"cpu-time"},
+			},
+		}, nil
+	})
+	defer restore()
+
+	fooUsage := client.SnapUsage{
+		Snap: "foo",
+		ResourceUsage: client.ResourceUsage{
+			CPUTime: 2 * time.Second, Memory: 2048, Processes: 2,
+		},
+		Apps: []client.AppUsage{{
+			Name: "daemon",
+			ResourceUsage: client.ResourceUsage{
+				CPUTime: 2 * time.Second, Memory: 2048, Processes: 2,
+			},
+		}},
+	}
+	barUsage := client.SnapUsage{
+		Snap: "bar",
+		ResourceUsage: client.ResourceUsage{
+			Processes: 1, Unavailable: []string{"cpu-time"},
+		},
+		Apps: []client.AppUsage{{
+			Name: "app",
+			ResourceUsage: client.ResourceUsage{
+				Processes: 1, Unavailable: []string{"cpu-time"},
+			},
+		}},
+	}
+
+	// all installed snaps
+	req, err := http.NewRequest("GET", "/v2/usage", nil)
+	c.Assert(err, IsNil)
+	rsp := s.syncReq(c, req, nil)
+	c.Check(rsp.Result, DeepEquals, []client.SnapUsage{barUsage, fooUsage})
+
+	// the given ones
+	req, err = http.NewRequest("GET", "/v2/usage?snaps=foo", nil)
+	c.Assert(err, IsNil)
+	rsp = s.syncReq(c, req, nil)
+	c.Check(rsp.Result, DeepEquals, []client.SnapUsage{fooUsage})
+	c.Check(calls, Equals, 2)
+}
+
+func (s *snapUsageSuite) TestUsageErrors(c *C) {
+	s.daemon(c)
+	s.mockSnap(c, usageSnapYaml)
+	restore := daemon.MockCgroupUsageOfAllSnaps(func() (map[string]*cgroup.Usage, error) {
+		return nil, errors.New("boom")
+	})
+	defer restore()
+
+	req, err := http.NewRequest("GET", "/v2/usage?snaps=foo,bar", nil)
+	c.Assert(err, IsNil)
+	rspe := s.errorReq(c, req, nil)
+	c.Check(rspe.Status, Equals, 404)
+	c.Check(rspe.Message, Equals, `snap "bar" is not installed`)
+
+	req, err = http.NewRequest("GET", "/v2/usage", nil)
+	c.Assert(err, IsNil)
+	rspe = s.errorReq(c, req, nil)
+	c.Check(rspe.Status, Equals, 500)
+	c.Check(rspe.Message, Equals, `cannot obtain resource usage of snaps: boom`)
+}
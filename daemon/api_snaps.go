@@ -49,7 +49,7 @@ var (
 		GET:         getSnapInfo,
 		POST:        postSnap,
 		ReadAccess:  openAccess{},
-		WriteAccess: authenticatedAccess{Polkit: polkitActionManage},
+		WriteAccess: authenticatedAccess{Polkit: polkitActionManage, Policy: accessPolicyScopeSnap},
 	}
 
 	snapsCmd = &Command{
@@ -57,7 +57,7 @@ var (
 		GET:         getSnapsInfo,
 		POST:        postSnaps,
 		ReadAccess:  openAccess{},
-		WriteAccess: authenticatedAccess{Polkit: polkitActionManage},
+		WriteAccess: authenticatedAccess{Polkit: polkitActionManage, Policy: accessPolicyScopeSnaps},
 	}
 )
 
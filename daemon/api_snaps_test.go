@@ -61,7 +61,11 @@ var _ = check.Suite(&snapsSuite{})
 func (s *snapsSuite) SetUpTest(c *check.C) {
 	s.apiBaseSuite.SetUpTest(c)
 
-	s.expectWriteAccess(daemon.AuthenticatedAccess{Polkit: "io.snapcraft.snapd.manage"})
+	s.expectWriteAccess(daemon.AuthenticatedAccess{Polkit: "io.snapcraft.snapd.manage", Policy: daemon.AccessPolicyScopeSnap})
+}
+
+func (s *snapsSuite) expectSnapsWriteAccess() {
+	s.expectWriteAccess(daemon.AuthenticatedAccess{Polkit: "io.snapcraft.snapd.manage", Policy: daemon.AccessPolicyScopeSnaps})
 }
 
 func (s *snapsSuite) TestSnapsInfoIntegration(c *check.C) {
@@ -418,6 +422,7 @@ func (s *snapsSuite) TestSnapsInfoFilterRemote(c *check.C) {
 }
 
 func (s *snapsSuite) TestPostSnapsVerifyMultiSnapInstruction(c *check.C) {
+	s.expectSnapsWriteAccess()
 	s.daemonWithOverlordMockAndStore(c)
 
 	buf := strings.NewReader(`{"action": "install","snaps":["ubuntu-core"]}`)
@@ -431,6 +436,7 @@ func (s *snapsSuite) TestPostSnapsVerifyMultiSnapInstruction(c *check.C) {
 }
 
 func (s *snapsSuite) TestPostSnapsUnsupportedMultiOp(c *check.C) {
+	s.expectSnapsWriteAccess()
 	s.daemonWithOverlordMockAndStore(c)
 
 	buf := strings.NewReader(`{"action": "switch","snaps":["foo"]}`)
@@ -444,6 +450,7 @@ func (s *snapsSuite) TestPostSnapsUnsupportedMultiOp(c *check.C) {
 }
 
 func (s *snapsSuite) TestPostSnapsNoWeirdses(c *check.C) {
+	s.expectSnapsWriteAccess()
 	s.daemonWithOverlordMockAndStore(c)
 
 	// one could add more actions here ... 🤷
@@ -478,6 +485,7 @@ func (s *snapsSuite) TestPostSnapsOpMoreComplexContentType(c *check.C) {
 }
 
 func (s *snapsSuite) testPostSnapsOp(c *check.C, contentType string) {
+	s.expectSnapsWriteAccess()
 	defer daemon.MockAssertstateRefreshSnapDeclarations(func(*state.State, int) error { return nil })()
 	defer daemon.MockSnapstateUpdateMany(func(_ context.Context, s *state.State, names []string, userID int, flags *snapstate.Flags) ([]string, []*state.TaskSet, error) {
 		c.Check(names, check.HasLen, 0)
@@ -505,6 +513,7 @@ func (s *snapsSuite) testPostSnapsOp(c *check.C, contentType string) {
 }
 
 func (s *snapsSuite) TestPostSnapsOpInvalidCharset(c *check.C) {
+	s.expectSnapsWriteAccess()
 	s.daemon(c)
 
 	buf := bytes.NewBufferString(`{"action": "refresh"}`)
@@ -754,6 +763,8 @@ UnitFileState=static
 		[]byte(`Id=snap.foo.svc5.timer
 ActiveState=active
 UnitFileState=enabled
+NextElapseUSecRealtime=Mon 2021-03-08 12:15:00 UTC
+LastTriggerUSec=n/a
 `),
 		[]byte(`Type=simple
 Id=snap.foo.svc6.service
@@ -763,6 +774,7 @@ UnitFileState=static
 		[]byte(`Id=snap.foo.svc6.sock.socket
 ActiveState=active
 UnitFileState=enabled
+Listen=/var/snap/foo/common/sock.socket (Stream)
 `),
 		[]byte(`Type=simple
 Id=snap.foo.svc7.service
@@ -882,7 +894,7 @@ UnitFileState=enabled
 					Enabled:     true,
 					Active:      false,
 					Activators: []client.AppActivator{
-						{Name: "svc5", Type: "timer", Active: true, Enabled: true},
+						{Name: "svc5", Type: "timer", Active: true, Enabled: true, Schedule: "mon1,12:15", NextRun: time.Date(2021, 3, 8, 12, 15, 0, 0, time.UTC)},
 					},
 				}, {
 					Snap: "foo", Name: "svc6",
@@ -891,7 +903,7 @@ UnitFileState=enabled
 					Enabled:     true,
 					Active:      false,
 					Activators: []client.AppActivator{
-						{Name: "sock", Type: "socket", Active: true, Enabled: true},
+						{Name: "sock", Type: "socket", Active: true, Enabled: true, Listen: []string{"/var/snap/foo/common/sock.socket"}},
 					},
 				}, {
 					Snap: "foo", Name: "svc7",
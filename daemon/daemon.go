@@ -69,10 +69,14 @@ type Daemon struct {
 	state           *state.State
 	snapdListener   net.Listener
 	snapListener    net.Listener
+	remoteListener  net.Listener
 	connTracker     *connTracker
 	serve           *http.Server
 	tomb            tomb.Tomb
 	router          *mux.Router
+	remoteRouter    *mux.Router
+	remoteServe     *http.Server
+	remoteClients   remoteClients
 	standbyOpinions *standby.StandbyOpinions
 
 	// set to what kind of restart was requested if any
@@ -150,7 +154,12 @@ func (c *Command) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if rspe := access.CheckAccess(c.d, r, ucred, user); rspe != nil {
+	if rc := remoteClientFromRequest(r); rc != nil {
+		if rspe := checkRemoteAccess(access, c.d, r, rc); rspe != nil {
+			rspe.ServeHTTP(w, r)
+			return
+		}
+	} else if rspe := access.CheckAccess(c.d, r, ucred, user); rspe != nil {
 		rspe.ServeHTTP(w, r)
 		return
 	}
@@ -239,6 +248,14 @@ func (d *Daemon) Init() error {
 
 	d.addRoutes()
 
+	if d.overlord != nil {
+		// the remote listener is optional, do not prevent snapd
+		// from starting if it cannot be setup
+		if err := d.initRemoteListener(); err != nil {
+			logger.Noticef("cannot start remote API listener: %v", err)
+		}
+	}
+
 	logger.Noticef("started %v.", snapdenv.UserAgent())
 
 	return nil
@@ -345,6 +362,16 @@ func (d *Daemon) Start() error {
 		Handler:   logit(d.router),
 		ConnState: d.connTracker.trackConn,
 	}
+	if d.remoteListener != nil {
+		d.remoteServe = &http.Server{
+			Handler:           logRemote(d.remoteRouter),
+			ConnState:         d.connTracker.trackConn,
+			ReadHeaderTimeout: remoteReadHeaderTimeout,
+			ReadTimeout:       remoteReadTimeout,
+			IdleTimeout:       remoteIdleTimeout,
+			MaxHeaderBytes:    remoteMaxHeaderBytes,
+		}
+	}
 
 	// enable standby handling
 	d.initStandbyHandling()
@@ -370,6 +397,16 @@ func (d *Daemon) Start() error {
 			})
 		}
 
+		if d.remoteListener != nil {
+			d.tomb.Go(func() error {
+				if err := d.remoteServe.Serve(d.remoteListener); err != http.ErrServerClosed && d.tomb.Err() == tomb.ErrStillAlive {
+					return err
+				}
+
+				return nil
+			})
+		}
+
 		if err := d.serve.Serve(d.snapdListener); err != http.ErrServerClosed && d.tomb.Err() == tomb.ErrStillAlive {
 			return err
 		}
@@ -507,6 +544,10 @@ func (d *Daemon) Stop(sigCh chan<- os.Signal) error {
 		d.snapListener.Close()
 	}
 
+	if d.remoteListener != nil {
+		d.remoteListener.Close()
+	}
+
 	if needsFullShutdown {
 		// give time to polling clients to notice restart
 		time.Sleep(rebootNoticeWait)
@@ -516,6 +557,9 @@ func (d *Daemon) Stop(sigCh chan<- os.Signal) error {
 	// context will likely already have been cancelled when we are
 	// called.
 	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	if d.remoteServe != nil {
+		d.remoteServe.Shutdown(ctx)
+	}
 	d.tomb.Kill(d.serve.Shutdown(ctx))
 	cancel()
 
@@ -29,6 +29,7 @@ import (
 	"github.com/snapcore/snapd/overlord/servicestate"
 	"github.com/snapcore/snapd/overlord/snapstate"
 	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/snap/configschema"
 	"github.com/snapcore/snapd/store"
 )
 
@@ -264,6 +265,45 @@ func AuthCancelled(format string, v ...interface{}) *apiError {
 	}
 }
 
+// AccessPolicyDenied is an error responder used when the local access
+// policy has rules for the user but none of them allows the requested
+// operation.
+func AccessPolicyDenied(username string, op *accessPolicyOperation) *apiError {
+	return accessPolicyDenied("user", "user", username, op)
+}
+
+// RemoteAccessPolicyDenied is an error responder used when the access
+// policy does not allow a remote client to perform an operation.
+func RemoteAccessPolicyDenied(fingerprint string, op *accessPolicyOperation) *apiError {
+	return accessPolicyDenied("client", "remote client", fingerprint, op)
+}
+
+func accessPolicyDenied(key, kind, who string, op *accessPolicyOperation) *apiError {
+	value := map[string]interface{}{
+		key: who,
+	}
+	msg := fmt.Sprintf("access policy does not allow %s %q to perform this request", kind, who)
+	if op != nil {
+		value["operation"] = op.Operation
+		if op.Snap != "" {
+			value["snap"] = op.Snap
+		}
+		if op.Channel != "" {
+			value["channel"] = op.Channel
+		}
+		if len(op.Options) > 0 {
+			value["options"] = op.Options
+		}
+		msg = fmt.Sprintf("access policy does not allow %s %q to %s", kind, who, op)
+	}
+	return &apiError{
+		Status:  403,
+		Message: msg,
+		Kind:    client.ErrorKindAccessPolicyDenied,
+		Value:   value,
+	}
+}
+
 // InterfacesUnchanged is an error responder used when an operation
 // that would normally change interfaces finds it has nothing to do
 func InterfacesUnchanged(format string, v ...interface{}) *apiError {
@@ -332,6 +372,13 @@ func errToResponse(err error, snaps []string, fallback errorResponder, format st
 			snapName = err.Snap
 		case *snapstate.InsufficientSpaceError:
 			return InsufficientSpace(err)
+		case *configschema.ValidationError:
+			return &apiError{
+				Status:  400,
+				Message: err.Error(),
+				Kind:    client.ErrorKindConfigInvalidOption,
+				Value:   err.Key,
+			}
 		case net.Error:
 			if err.Timeout() {
 				kind = client.ErrorKindNetworkTimeout
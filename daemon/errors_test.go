@@ -31,6 +31,7 @@ import (
 	"github.com/snapcore/snapd/daemon"
 	"github.com/snapcore/snapd/overlord/snapstate"
 	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/snap/configschema"
 	"github.com/snapcore/snapd/store"
 )
 
@@ -139,6 +140,7 @@ func (s *errorsSuite) TestErrToResponse(c *C) {
 		"bar": store.ErrSnapNotFound,
 	}}
 	saOe := &store.SnapActionError{Other: []error{e}}
+	cve := &configschema.ValidationError{Key: "port", Msg: "expected integer, got string \"abc\""}
 	// this one can't happen (but fun to test):
 	saXe := &store.SnapActionError{Refresh: map[string]error{"foo": sa1e}}
 
@@ -165,6 +167,7 @@ func (s *errorsSuite) TestErrToResponse(c *C) {
 		{nce, makeErrorRsp(client.ErrorKindSnapNeedsClassic, nce, "foo")},
 		{ncse, makeErrorRsp(client.ErrorKindSnapNeedsClassicSystem, ncse, "foo")},
 		{cce, daemon.SnapChangeConflict(cce)},
+		{cve, makeErrorRsp(client.ErrorKindConfigInvalidOption, cve, "port")},
 		{nettoute, makeErrorRsp(client.ErrorKindNetworkTimeout, nettoute, "")},
 		{netoe, daemon.BadRequest("ERR: %v", netoe)},
 		{nettmpe, daemon.BadRequest("ERR: %v", nettmpe)},
@@ -26,7 +26,8 @@ import (
 )
 
 type (
-	AccessChecker = accessChecker
+	AccessChecker     = accessChecker
+	AccessPolicyScope = accessPolicyScope
 
 	OpenAccess          = openAccess
 	AuthenticatedAccess = authenticatedAccess
@@ -34,6 +35,14 @@ type (
 	SnapAccess          = snapAccess
 )
 
+const (
+	AccessPolicyScopeSnap  = accessPolicyScopeSnap
+	AccessPolicyScopeSnaps = accessPolicyScopeSnaps
+	AccessPolicyScopeApps  = accessPolicyScopeApps
+	AccessPolicyScopeLogs  = accessPolicyScopeLogs
+	AccessPolicyScopeBatch = accessPolicyScopeBatch
+)
+
 var CheckPolkitActionImpl = checkPolkitActionImpl
 
 func MockCheckPolkitAction(new func(r *http.Request, ucred *Ucrednet, action string) *APIError) (restore func()) {
@@ -44,6 +53,14 @@ func MockCheckPolkitAction(new func(r *http.Request, ucred *Ucrednet, action str
 	}
 }
 
+func MockAccessPolicyUserLookup(new func(uid uint32) (username string, groups []string, err error)) (restore func()) {
+	old := accessPolicyUserLookup
+	accessPolicyUserLookup = new
+	return func() {
+		accessPolicyUserLookup = old
+	}
+}
+
 func MockPolkitCheckAuthorization(new func(pid int32, uid uint32, actionId string, details map[string]string, flags polkit.CheckFlags) (bool, error)) (restore func()) {
 	old := polkitCheckAuthorization
 	polkitCheckAuthorization = new
@@ -51,3 +68,15 @@ func MockPolkitCheckAuthorization(new func(pid int32, uid uint32, actionId strin
 		polkitCheckAuthorization = old
 	}
 }
+
+func MockAccessPolicyTrackingChannel(new func(d *Daemon, snapName string) string) (restore func()) {
+	old := accessPolicyTrackingChannel
+	accessPolicyTrackingChannel = new
+	return func() {
+		accessPolicyTrackingChannel = old
+	}
+}
+
+func CheckRemoteAccess(ac AccessChecker, r *http.Request, fingerprint string) *APIError {
+	return checkRemoteAccess(ac, nil, r, &remoteClient{Fingerprint: fingerprint})
+}
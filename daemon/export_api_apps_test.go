@@ -20,6 +20,7 @@
 package daemon
 
 import (
+	"github.com/snapcore/snapd/client/clientutil"
 	"github.com/snapcore/snapd/overlord/hookstate"
 	"github.com/snapcore/snapd/overlord/servicestate"
 	"github.com/snapcore/snapd/overlord/state"
@@ -34,6 +35,22 @@ func MockServicestateControl(f func(st *state.State, appInfos []*snap.AppInfo, i
 	}
 }
 
+func MockNewUserStatusDecorator(f func(uids []int, apps []*snap.AppInfo) clientutil.StatusDecorator) (restore func()) {
+	old := newUserStatusDecorator
+	newUserStatusDecorator = f
+	return func() {
+		newUserStatusDecorator = old
+	}
+}
+
+func MockSystemdEnsureJournalGrep(f func() error) (restore func()) {
+	old := systemdEnsureJournalGrep
+	systemdEnsureJournalGrep = f
+	return func() {
+		systemdEnsureJournalGrep = old
+	}
+}
+
 type (
 	AppInfoOptions = appInfoOptions
 )
@@ -19,10 +19,31 @@
 
 package daemon
 
+import (
+	"github.com/snapcore/snapd/sandbox/denials"
+)
+
 type (
 	ConnectivityStatus = connectivityStatus
 )
 
 var (
-	MinLane = minLane
+	MinLane    = minLane
+	MaxDenials = maxDenials
 )
+
+func MockDenialsFromJournal(f func(n int, keep func(*denials.Denial) bool) ([]*denials.Denial, error)) (restore func()) {
+	old := denialsFromJournal
+	denialsFromJournal = f
+	return func() {
+		denialsFromJournal = old
+	}
+}
+
+func MockSeccompSyscallNames(f func(auditArch string, syscalls []int) (map[int]string, error)) (restore func()) {
+	old := seccompSyscallNames
+	seccompSyscallNames = f
+	return func() {
+		seccompSyscallNames = old
+	}
+}
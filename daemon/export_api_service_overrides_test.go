@@ -0,0 +1,44 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package daemon
+
+import (
+	"github.com/snapcore/snapd/overlord/state"
+)
+
+type (
+	PostServiceOverrideData = postServiceOverrideData
+)
+
+func MockServicestateSetServiceOverride(f func(st *state.State, instanceName, appName string, settings map[string]string) (*state.TaskSet, error)) (restore func()) {
+	old := servicestateSetServiceOverride
+	servicestateSetServiceOverride = f
+	return func() {
+		servicestateSetServiceOverride = old
+	}
+}
+
+func MockServicestateResetServiceOverride(f func(st *state.State, instanceName, appName string, keys []string) (*state.TaskSet, error)) (restore func()) {
+	old := servicestateResetServiceOverride
+	servicestateResetServiceOverride = f
+	return func() {
+		servicestateResetServiceOverride = old
+	}
+}
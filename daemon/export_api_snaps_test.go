@@ -21,6 +21,7 @@ package daemon
 
 import (
 	"github.com/snapcore/snapd/overlord/snapstate"
+	"github.com/snapcore/snapd/sandbox/cgroup"
 	"github.com/snapcore/snapd/snap"
 )
 
@@ -31,3 +32,19 @@ func MakeAboutSnap(info *snap.Info, snapst *snapstate.SnapState) aboutSnap {
 var (
 	MapLocal = mapLocal
 )
+
+func MockCgroupUsageOfSnap(f func(snapName string) (map[string]*cgroup.Usage, error)) (restore func()) {
+	old := cgroupUsageOfSnap
+	cgroupUsageOfSnap = f
+	return func() {
+		cgroupUsageOfSnap = old
+	}
+}
+
+func MockCgroupUsageOfAllSnaps(f func() (map[string]*cgroup.Usage, error)) (restore func()) {
+	old := cgroupUsageOfAllSnaps
+	cgroupUsageOfAllSnaps = f
+	return func() {
+		cgroupUsageOfAllSnaps = old
+	}
+}
@@ -36,6 +36,27 @@ func APICommands() []*Command {
 	return api
 }
 
+func OpenAPIDescription(cmds []*Command) (map[string]interface{}, error) {
+	return openAPIDescription(cmds, "1.0")
+}
+
+func APIDocsRoutes() map[string][]string {
+	routes := make(map[string][]string, len(apiDocs))
+	for path, ops := range apiDocs {
+		for method := range ops {
+			routes[path] = append(routes[path], method)
+		}
+	}
+	return routes
+}
+
+func APIDocsQuery(path, method string) []string {
+	if op := apiDocs[path][method]; op != nil {
+		return op.Query
+	}
+	return nil
+}
+
 func NewAndAddRoutes() (*Daemon, error) {
 	d, err := New()
 	if err != nil {
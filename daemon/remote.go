@@ -0,0 +1,299 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package daemon
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/logger"
+	"github.com/snapcore/snapd/osutil"
+	"github.com/snapcore/snapd/overlord/configstate/config"
+	"github.com/snapcore/snapd/strutil"
+)
+
+// remoteAPI is the subset of the API served by the remote management
+// listener.
+var remoteAPI = []*Command{
+	sysInfoCmd,
+	snapsCmd,
+	snapCmd,
+	appsCmd,
+	logsCmd,
+	stateChangeCmd,
+	stateChangesCmd,
+	connectionsCmd,
+	batchCmd,
+}
+
+const (
+	remoteAPIServerCertFile = "server.crt"
+	remoteAPIServerKeyFile  = "server.key"
+)
+
+// The remote listener is reachable over the network, clients must not be
+// able to hold connections open indefinitely. There is no write timeout as
+// following logs streams the response for as long as the client wants.
+var (
+	remoteReadHeaderTimeout = 10 * time.Second
+	// the whole request, including the body of sideloaded snaps
+	remoteReadTimeout    = 10 * time.Minute
+	remoteIdleTimeout    = 2 * time.Minute
+	remoteMaxHeaderBytes = 64 * 1024
+)
+
+// remoteClient describes a client of the remote management listener,
+// as identified by its TLS client certificate.
+type remoteClient struct {
+	Fingerprint string
+}
+
+// remoteClientFromRequest returns the remote client that sent the
+// request, or nil if the request was not received via the remote
+// management listener.
+func remoteClientFromRequest(r *http.Request) *remoteClient {
+	// only the remote listener uses TLS
+	if r.TLS == nil {
+		return nil
+	}
+	if len(r.TLS.PeerCertificates) == 0 {
+		// cannot happen, the handshake requires a certificate
+		return &remoteClient{}
+	}
+	return &remoteClient{Fingerprint: certificateFingerprint(r.TLS.PeerCertificates[0].Raw)}
+}
+
+// certificateFingerprint returns the SHA256 fingerprint of the given
+// DER encoded certificate.
+func certificateFingerprint(der []byte) string {
+	h := sha256.Sum256(der)
+	return hex.EncodeToString(h[:])
+}
+
+// remoteAPIListenAddress returns the address of the remote management
+// listener from the system configuration.
+func remoteAPIListenAddress(d *Daemon) (address string, err error) {
+	st := d.state
+	st.Lock()
+	defer st.Unlock()
+
+	tr := config.NewTransaction(st)
+	if err := tr.GetMaybe("core", "remote-api.listen-address", &address); err != nil {
+		return "", err
+	}
+	return address, nil
+}
+
+// remoteClients caches the allowed client certificate fingerprints,
+// which are exported by the configuration of the remote API, so that
+// the state does not need to be locked for every connection.
+type remoteClients struct {
+	mu           sync.Mutex
+	modTime      time.Time
+	size         int64
+	fingerprints []string
+}
+
+// allowed returns the currently allowed client certificate
+// fingerprints, reloading them if the file changed.
+func (rc *remoteClients) allowed() ([]string, error) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	fi, err := os.Stat(dirs.SnapdRemoteAPIClientsFile)
+	if os.IsNotExist(err) {
+		rc.modTime, rc.size, rc.fingerprints = time.Time{}, 0, nil
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if fi.ModTime().Equal(rc.modTime) && fi.Size() == rc.size {
+		return rc.fingerprints, nil
+	}
+	data, err := ioutil.ReadFile(dirs.SnapdRemoteAPIClientsFile)
+	if err != nil {
+		return nil, err
+	}
+	var fingerprints []string
+	for _, line := range strings.Split(string(data), "\n") {
+		if line == "" {
+			continue
+		}
+		fp, err := strutil.NormalizeCertificateFingerprint(line)
+		if err != nil {
+			return nil, err
+		}
+		fingerprints = append(fingerprints, fp)
+	}
+	rc.modTime, rc.size, rc.fingerprints = fi.ModTime(), fi.Size(), fingerprints
+	return fingerprints, nil
+}
+
+// verifyRemoteClientCertificate checks the certificate presented by
+// a remote client against the currently configured fingerprints.
+func (d *Daemon) verifyRemoteClientCertificate(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+	if len(rawCerts) == 0 {
+		return fmt.Errorf("no client certificate")
+	}
+	allowed, err := d.remoteClients.allowed()
+	if err != nil {
+		logger.Noticef("cannot read allowed remote API clients: %v", err)
+		return fmt.Errorf("cannot verify client certificate")
+	}
+	fp := certificateFingerprint(rawCerts[0])
+	if !strutil.ListContains(allowed, fp) {
+		logger.Noticef("rejected remote API client with certificate %s", fp)
+		return fmt.Errorf("client certificate not allowed")
+	}
+	return nil
+}
+
+// remoteAPIServerCertificate loads the certificate of the remote
+// management listener, generating a self-signed one the first time.
+func remoteAPIServerCertificate() (tls.Certificate, error) {
+	certPath := filepath.Join(dirs.SnapdRemoteAPIDir, remoteAPIServerCertFile)
+	keyPath := filepath.Join(dirs.SnapdRemoteAPIDir, remoteAPIServerKeyFile)
+	if osutil.FileExists(certPath) && osutil.FileExists(keyPath) {
+		return tls.LoadX509KeyPair(certPath, keyPath)
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	hostname, _ := os.Hostname()
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: hostname, Organization: []string{"snapd"}},
+		NotBefore:    now.Add(-time.Hour),
+		NotAfter:     now.AddDate(10, 0, 0),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	if hostname != "" {
+		template.DNSNames = []string{hostname}
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+
+	if err := os.MkdirAll(dirs.SnapdRemoteAPIDir, 0700); err != nil {
+		return tls.Certificate{}, err
+	}
+	if err := osutil.AtomicWriteFile(keyPath, keyPEM, 0600, 0); err != nil {
+		return tls.Certificate{}, err
+	}
+	if err := osutil.AtomicWriteFile(certPath, certPEM, 0644, 0); err != nil {
+		return tls.Certificate{}, err
+	}
+	return tls.X509KeyPair(certPEM, keyPEM)
+}
+
+var netListen = net.Listen
+
+// initRemoteListener sets up the opt-in remote management listener if
+// an address is configured. Changes to the address take effect when
+// snapd is restarted, the allowed fingerprints are checked on every
+// connection.
+func (d *Daemon) initRemoteListener() error {
+	address, err := remoteAPIListenAddress(d)
+	if err != nil {
+		return err
+	}
+	if address == "" {
+		return nil
+	}
+
+	cert, err := remoteAPIServerCertificate()
+	if err != nil {
+		return fmt.Errorf("cannot setup remote API server certificate: %v", err)
+	}
+
+	listener, err := netListen("tcp", address)
+	if err != nil {
+		return err
+	}
+	tlsConfig := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		// the client certificates are self-signed, they are
+		// verified by fingerprint instead
+		ClientAuth:            tls.RequireAnyClientCert,
+		VerifyPeerCertificate: d.verifyRemoteClientCertificate,
+		MinVersion:            tls.VersionTLS12,
+	}
+	d.remoteListener = tls.NewListener(listener, tlsConfig)
+
+	d.remoteRouter = mux.NewRouter()
+	for _, c := range remoteAPI {
+		d.remoteRouter.Handle(c.Path, c).Name(c.Path)
+	}
+	d.remoteRouter.NotFoundHandler = NotFound("not found")
+
+	logger.Noticef("serving remote API on %s, server certificate %s", listener.Addr(), certificateFingerprint(cert.Certificate[0]))
+	return nil
+}
+
+// logRemote logs every request received via the remote management
+// listener together with the client certificate fingerprint, for
+// auditing.
+func logRemote(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ww := &wrappedWriter{w: w}
+		handler.ServeHTTP(ww, r)
+		fingerprint := ""
+		if rc := remoteClientFromRequest(r); rc != nil {
+			fingerprint = rc.Fingerprint
+		}
+		logger.Noticef("remote API: %s %s %s from %s: %d", r.RemoteAddr, r.Method, r.URL, fingerprint, ww.s)
+	})
+}
@@ -0,0 +1,257 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package daemon
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/overlord/configstate/config"
+	"github.com/snapcore/snapd/testutil"
+)
+
+func makeTestClientCertificate(c *check.C) tls.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	c.Assert(err, check.IsNil)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "orchestrator"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	c.Assert(err, check.IsNil)
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+func (s *daemonSuite) setRemoteAPIConfig(c *check.C, d *Daemon, address string, fingerprints ...string) {
+	st := d.overlord.State()
+	st.Lock()
+	defer st.Unlock()
+	tr := config.NewTransaction(st)
+	tr.Set("core", "remote-api.listen-address", address)
+	tr.Commit()
+
+	// the allowed fingerprints are exported by configcore
+	s.writeRemoteAPIClients(c, fingerprints...)
+}
+
+func (s *daemonSuite) writeRemoteAPIClients(c *check.C, fingerprints ...string) {
+	c.Assert(os.MkdirAll(dirs.SnapdRemoteAPIDir, 0700), check.IsNil)
+	content := strings.Join(fingerprints, "\n") + "\n"
+	c.Assert(ioutil.WriteFile(dirs.SnapdRemoteAPIClientsFile, []byte(content), 0600), check.IsNil)
+}
+
+func (s *daemonSuite) TestRemoteListenerNotConfigured(c *check.C) {
+	d := newTestDaemon(c)
+
+	c.Assert(d.initRemoteListener(), check.IsNil)
+	c.Check(d.remoteListener, check.IsNil)
+	c.Check(filepath.Join(dirs.SnapdRemoteAPIDir, "server.crt"), testutil.FileAbsent)
+}
+
+func (s *daemonSuite) TestRemoteClientsReload(c *check.C) {
+	var rc remoteClients
+
+	allowed, err := rc.allowed()
+	c.Assert(err, check.IsNil)
+	c.Check(allowed, check.HasLen, 0)
+
+	const fp1 = "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"
+	const fp2 = "60303ae22b998861bce3b28f33eec1be758a213c86c93c076dbe9f558c11c752"
+	s.writeRemoteAPIClients(c, fp1)
+	allowed, err = rc.allowed()
+	c.Assert(err, check.IsNil)
+	c.Check(allowed, check.DeepEquals, []string{fp1})
+
+	// changes to the file are picked up
+	s.writeRemoteAPIClients(c, fp1, fp2)
+	allowed, err = rc.allowed()
+	c.Assert(err, check.IsNil)
+	c.Check(allowed, check.DeepEquals, []string{fp1, fp2})
+
+	s.writeRemoteAPIClients(c, "bad")
+	_, err = rc.allowed()
+	c.Check(err, check.ErrorMatches, `invalid SHA256 certificate fingerprint "bad"`)
+
+	c.Assert(os.Remove(dirs.SnapdRemoteAPIClientsFile), check.IsNil)
+	allowed, err = rc.allowed()
+	c.Assert(err, check.IsNil)
+	c.Check(allowed, check.HasLen, 0)
+}
+
+func (s *daemonSuite) TestRemoteListenerServesSubset(c *check.C) {
+	d := newTestDaemon(c)
+	s.markSeeded(d)
+
+	allowed := makeTestClientCertificate(c)
+	other := makeTestClientCertificate(c)
+	s.setRemoteAPIConfig(c, d, "127.0.0.1:0", certificateFingerprint(allowed.Certificate[0]))
+
+	c.Assert(d.initRemoteListener(), check.IsNil)
+	c.Assert(d.remoteListener, check.NotNil)
+	// the server certificate was generated and is reused
+	serverCert, err := remoteAPIServerCertificate()
+	c.Assert(err, check.IsNil)
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	c.Assert(err, check.IsNil)
+	d.snapdListener = &witnessAcceptListener{Listener: l, accept: make(chan struct{}, 1)}
+
+	c.Assert(d.Start(), check.IsNil)
+	defer d.Stop(nil)
+
+	pool := x509.NewCertPool()
+	leaf, err := x509.ParseCertificate(serverCert.Certificate[0])
+	c.Assert(err, check.IsNil)
+	pool.AddCert(leaf)
+	newClient := func(cert tls.Certificate) *http.Client {
+		return &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{
+			Certificates: []tls.Certificate{cert},
+			RootCAs:      pool,
+			ServerName:   leaf.DNSNames[0],
+		}}}
+	}
+	base := fmt.Sprintf("https://%s", d.remoteListener.Addr())
+
+	cli := newClient(allowed)
+	rsp, err := cli.Get(base + "/v2/system-info")
+	c.Assert(err, check.IsNil)
+	rsp.Body.Close()
+	c.Check(rsp.StatusCode, check.Equals, 200)
+
+	// not part of the remote API
+	rsp, err = cli.Get(base + "/v2/find?q=foo")
+	c.Assert(err, check.IsNil)
+	rsp.Body.Close()
+	c.Check(rsp.StatusCode, check.Equals, 404)
+
+	// changes need to be allowed by the access policy
+	rsp, err = cli.Post(base+"/v2/snaps/foo", "application/json", strings.NewReader(`{"action": "remove"}`))
+	c.Assert(err, check.IsNil)
+	rsp.Body.Close()
+	c.Check(rsp.StatusCode, check.Equals, 403)
+
+	// so do batches, operation by operation
+	c.Assert(os.MkdirAll(filepath.Dir(dirs.SnapdAccessPolicyFile), 0755), check.IsNil)
+	policy := fmt.Sprintf("rules:\n  - clients: [%s]\n    allow:\n      - operation: remove\n        snaps: [foo]\n", certificateFingerprint(allowed.Certificate[0]))
+	c.Assert(ioutil.WriteFile(dirs.SnapdAccessPolicyFile, []byte(policy), 0644), check.IsNil)
+	rsp, err = cli.Post(base+"/v2/batch", "application/json", strings.NewReader(`{"operations": [{"action": "remove", "snap": "foo"}, {"action": "remove", "snap": "bar"}]}`))
+	c.Assert(err, check.IsNil)
+	rsp.Body.Close()
+	c.Check(rsp.StatusCode, check.Equals, 403)
+	rsp, err = cli.Post(base+"/v2/batch", "application/json", strings.NewReader(`{"operations": [{"action": "remove", "snap": "foo"}]}`))
+	c.Assert(err, check.IsNil)
+	body, err := ioutil.ReadAll(rsp.Body)
+	rsp.Body.Close()
+	c.Assert(err, check.IsNil)
+	// allowed by the policy, the batch itself fails as foo is not installed
+	c.Check(rsp.StatusCode, check.Equals, 400, check.Commentf("%s", body))
+	c.Check(string(body), testutil.Contains, `snap \"foo\" is not installed`)
+
+	// certificates with fingerprints not in the config are rejected
+	_, err = newClient(other).Get(base + "/v2/system-info")
+	c.Check(err, check.NotNil)
+}
+
+func (s *daemonSuite) TestRemoteListenerTimeouts(c *check.C) {
+	oldReadHeaderTimeout := remoteReadHeaderTimeout
+	remoteReadHeaderTimeout = 100 * time.Millisecond
+	defer func() { remoteReadHeaderTimeout = oldReadHeaderTimeout }()
+
+	d := newTestDaemon(c)
+	s.markSeeded(d)
+
+	allowed := makeTestClientCertificate(c)
+	s.setRemoteAPIConfig(c, d, "127.0.0.1:0", certificateFingerprint(allowed.Certificate[0]))
+	c.Assert(d.initRemoteListener(), check.IsNil)
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	c.Assert(err, check.IsNil)
+	d.snapdListener = &witnessAcceptListener{Listener: l, accept: make(chan struct{}, 1)}
+
+	c.Assert(d.Start(), check.IsNil)
+	defer d.Stop(nil)
+
+	c.Check(d.remoteServe.ReadHeaderTimeout, check.Equals, 100*time.Millisecond)
+	c.Check(d.remoteServe.ReadTimeout, check.Equals, 10*time.Minute)
+	c.Check(d.remoteServe.IdleTimeout, check.Equals, 2*time.Minute)
+	c.Check(d.remoteServe.MaxHeaderBytes, check.Equals, 64*1024)
+
+	// a client that completes the handshake but sends no request is
+	// disconnected
+	conn, err := tls.Dial("tcp", d.remoteListener.Addr().String(), &tls.Config{
+		Certificates:       []tls.Certificate{allowed},
+		InsecureSkipVerify: true,
+	})
+	c.Assert(err, check.IsNil)
+	defer conn.Close()
+	c.Assert(conn.SetReadDeadline(time.Now().Add(5*time.Second)), check.IsNil)
+	_, err = ioutil.ReadAll(conn)
+	if nerr, ok := err.(net.Error); ok {
+		c.Check(nerr.Timeout(), check.Equals, false)
+	}
+}
+
+func (s *daemonSuite) TestRemoteAccessMapping(c *check.C) {
+	rc := &remoteClient{Fingerprint: "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"}
+
+	for _, t := range []struct {
+		ac      accessChecker
+		method  string
+		allowed bool
+	}{
+		{openAccess{}, "GET", true},
+		{openAccess{}, "POST", false},
+		{authenticatedAccess{}, "GET", true},
+		{authenticatedAccess{Polkit: polkitActionManage}, "POST", false},
+		// no access policy
+		{authenticatedAccess{Polkit: polkitActionManage, Policy: accessPolicyScopeApps}, "POST", false},
+		{authenticatedAccess{Polkit: polkitActionManage, Policy: accessPolicyScopeLogs}, "GET", false},
+		{rootAccess{}, "GET", false},
+		{snapAccess{}, "GET", false},
+	} {
+		req := httptest.NewRequest(t.method, "/", nil)
+		rspe := checkRemoteAccess(t.ac, nil, req, rc)
+		if t.allowed {
+			c.Check(rspe, check.IsNil, check.Commentf("%T %s", t.ac, t.method))
+		} else {
+			c.Check(rspe, check.DeepEquals, Forbidden("access denied"), check.Commentf("%T %s", t.ac, t.method))
+		}
+	}
+}
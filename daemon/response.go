@@ -273,7 +273,8 @@ func (f fileResponse) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 // be, each one on its own, a JSON dump of a systemd.Log, as output by
 // journalctl -o json) from an io.ReadCloser, loads that into a client.Log, and
 // outputs the json dump of that, padded with RS and LF to make it a valid
-// json-seq response.
+// json-seq response. If fields is set all the fields of the journal entries
+// are included.
 //
 // The reader is always closed when done (this is important for
 // osutil.WatingStdoutPipe).
@@ -282,6 +283,7 @@ func (f fileResponse) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 type journalLineReaderSeqResponse struct {
 	io.ReadCloser
 	follow bool
+	fields bool
 }
 
 func (rr *journalLineReaderSeqResponse) ServeHTTP(w http.ResponseWriter, r *http.Request) {
@@ -303,12 +305,16 @@ func (rr *journalLineReaderSeqResponse) ServeHTTP(w http.ResponseWriter, r *http
 
 		// ignore the error...
 		t, _ := log.Time()
-		if err = enc.Encode(client.Log{
+		clog := client.Log{
 			Timestamp: t,
 			Message:   log.Message(),
 			SID:       log.SID(),
 			PID:       log.PID(),
-		}); err != nil {
+		}
+		if rr.fields {
+			clog.Fields = log.Fields()
+		}
+		if err = enc.Encode(clog); err != nil {
 			break
 		}
 
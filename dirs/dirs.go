@@ -60,6 +60,11 @@ var (
 
 	SnapdMaintenanceFile string
 
+	SnapdAccessPolicyFile     string
+	SnapdRemoteAPIDir         string
+	SnapdRemoteAPIClientsFile string
+	SnapdLogForwardDir        string
+
 	SnapdStoreSSLCertsDir string
 
 	SnapSeedDir   string
@@ -87,6 +92,7 @@ var (
 	SnapSectionsFile    string
 	SnapCommandsDB      string
 	SnapAuxStoreInfoDir string
+	SnapSeccompCacheDir string
 
 	SnapBinariesDir     string
 	SnapServicesDir     string
@@ -325,6 +331,11 @@ func SetRootDir(rootdir string) {
 	SnapMountPolicyDir = filepath.Join(rootdir, snappyDir, "mount")
 	SnapMetaDir = filepath.Join(rootdir, snappyDir, "meta")
 	SnapdMaintenanceFile = filepath.Join(rootdir, snappyDir, "maintenance.json")
+
+	SnapdAccessPolicyFile = filepath.Join(rootdir, "/etc/snapd/access-policy.yaml")
+	SnapdRemoteAPIDir = filepath.Join(rootdir, snappyDir, "remote-api")
+	SnapdRemoteAPIClientsFile = filepath.Join(SnapdRemoteAPIDir, "allowed-clients")
+	SnapdLogForwardDir = filepath.Join(rootdir, snappyDir, "log-forward")
 	SnapBlobDir = SnapBlobDirUnder(rootdir)
 	// ${snappyDir}/desktop is added to $XDG_DATA_DIRS.
 	// Subdirectories are interpreted according to the relevant
@@ -356,6 +367,7 @@ func SetRootDir(rootdir string) {
 	SnapSectionsFile = filepath.Join(SnapCacheDir, "sections")
 	SnapCommandsDB = filepath.Join(SnapCacheDir, "commands.db")
 	SnapAuxStoreInfoDir = filepath.Join(SnapCacheDir, "aux")
+	SnapSeccompCacheDir = filepath.Join(SnapCacheDir, "seccomp")
 
 	SnapSeedDir = SnapSeedDirUnder(rootdir)
 	SnapDeviceDir = SnapDeviceDirUnder(rootdir)
@@ -19,6 +19,18 @@
 
 package builtin
 
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/snapcore/snapd/interfaces"
+	"github.com/snapcore/snapd/interfaces/apparmor"
+	"github.com/snapcore/snapd/interfaces/hotplug"
+	"github.com/snapcore/snapd/interfaces/udev"
+	"github.com/snapcore/snapd/snap"
+)
+
 const cameraSummary = `allows access to all cameras`
 
 const cameraBaseDeclarationSlots = `
@@ -35,7 +47,9 @@ const cameraConnectedPlugAppArmor = `
 
 # VideoCore cameras (shared device with VideoCore/EGL)
 /dev/vchiq rw,
+` + cameraDetectionConnectedPlugAppArmor
 
+const cameraDetectionConnectedPlugAppArmor = `
 # Allow detection of cameras. Leaks plugged in USB device info
 /sys/bus/usb/devices/ r,
 /sys/devices/pci**/usb*/**/busnum r,
@@ -57,8 +71,58 @@ var cameraConnectedPlugUDev = []string{
 	`KERNEL=="vchiq"`,
 }
 
+// Pattern to match the video4linux capture devices for which slots are
+// created when they are hotplugged.
+var cameraDeviceNodePattern = regexp.MustCompile("^/dev/video[0-9]+$")
+
+// cameraInterface grants access to all cameras through the implicit
+// slot and to a single camera through slots created for hotplugged
+// devices, which have a path attribute.
+type cameraInterface struct {
+	commonInterface
+}
+
+func (iface *cameraInterface) BeforePrepareSlot(slot *snap.SlotInfo) error {
+	return verifyOptionalSlotPathAttribute(slot, cameraDeviceNodePattern)
+}
+
+func (iface *cameraInterface) AppArmorConnectedPlug(spec *apparmor.Specification, plug *interfaces.ConnectedPlug, slot *interfaces.ConnectedSlot) error {
+	path, ok := hotplugDevicePath(slot)
+	if !ok {
+		return iface.commonInterface.AppArmorConnectedPlug(spec, plug, slot)
+	}
+	spec.AddSnippet(fmt.Sprintf("# Access to a single camera\n%s rw,\n%s", path, cameraDetectionConnectedPlugAppArmor))
+	return nil
+}
+
+func (iface *cameraInterface) UDevConnectedPlug(spec *udev.Specification, plug *interfaces.ConnectedPlug, slot *interfaces.ConnectedSlot) error {
+	path, ok := hotplugDevicePath(slot)
+	if !ok {
+		return iface.commonInterface.UDevConnectedPlug(spec, plug, slot)
+	}
+	spec.TagDevice(fmt.Sprintf(`SUBSYSTEM=="video4linux", KERNEL=="%s"`, strings.TrimPrefix(path, "/dev/")))
+	return nil
+}
+
+func (iface *cameraInterface) HotplugDeviceDetected(di *hotplug.HotplugDeviceInfo) (*hotplug.ProposedSlot, error) {
+	// only propose slots for capture devices, not for the metadata
+	// nodes some cameras have as well
+	if caps, _ := di.Attribute("ID_V4L_CAPABILITIES"); !strings.Contains(caps, ":capture:") {
+		return nil, nil
+	}
+	return hotplugDeviceNodeSlot(di, "video4linux", cameraDeviceNodePattern), nil
+}
+
+func (iface *cameraInterface) HotplugKey(di *hotplug.HotplugDeviceInfo) (snap.HotplugKey, error) {
+	return hotplugPortKey(di, "ID_USB_INTERFACE_NUM")
+}
+
+func (iface *cameraInterface) HandledByGadget(di *hotplug.HotplugDeviceInfo, slot *snap.SlotInfo) bool {
+	return handledByGadgetPath(di, slot)
+}
+
 func init() {
-	registerIface(&commonInterface{
+	registerIface(&cameraInterface{commonInterface{
 		name:                  "camera",
 		summary:               cameraSummary,
 		implicitOnCore:        true,
@@ -66,5 +130,5 @@ func init() {
 		baseDeclarationSlots:  cameraBaseDeclarationSlots,
 		connectedPlugAppArmor: cameraConnectedPlugAppArmor,
 		connectedPlugUDev:     cameraConnectedPlugUDev,
-	})
+	}})
 }
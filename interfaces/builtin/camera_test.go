@@ -28,6 +28,7 @@ import (
 	"github.com/snapcore/snapd/interfaces"
 	"github.com/snapcore/snapd/interfaces/apparmor"
 	"github.com/snapcore/snapd/interfaces/builtin"
+	"github.com/snapcore/snapd/interfaces/hotplug"
 	"github.com/snapcore/snapd/interfaces/udev"
 	"github.com/snapcore/snapd/snap"
 	"github.com/snapcore/snapd/testutil"
@@ -109,3 +110,72 @@ func (s *CameraInterfaceSuite) TestAutoConnect(c *C) {
 func (s *CameraInterfaceSuite) TestInterfaces(c *C) {
 	c.Check(builtin.Interfaces(), testutil.DeepContains, s.iface)
 }
+
+func (s *CameraInterfaceSuite) TestHotplugDeviceDetected(c *C) {
+	hotplugIface := s.iface.(hotplug.Definer)
+	di, err := hotplug.NewHotplugDeviceInfo(map[string]string{"DEVPATH": "/sys/foo/bar", "DEVNAME": "/dev/video2", "ID_VENDOR_ID": "046d", "ID_MODEL_ID": "0825", "ID_V4L_CAPABILITIES": ":capture:", "ACTION": "add", "SUBSYSTEM": "video4linux"})
+	c.Assert(err, IsNil)
+	proposedSlot, err := hotplugIface.HotplugDeviceDetected(di)
+	c.Assert(err, IsNil)
+	c.Assert(proposedSlot, DeepEquals, &hotplug.ProposedSlot{Attrs: map[string]interface{}{"path": "/dev/video2", "usb-vendor": "046d", "usb-product": "0825"}})
+
+	// metadata nodes are ignored
+	di, err = hotplug.NewHotplugDeviceInfo(map[string]string{"DEVPATH": "/sys/foo/bar", "DEVNAME": "/dev/video3", "ID_V4L_CAPABILITIES": ":", "ACTION": "add", "SUBSYSTEM": "video4linux"})
+	c.Assert(err, IsNil)
+	proposedSlot, err = hotplugIface.HotplugDeviceDetected(di)
+	c.Assert(err, IsNil)
+	c.Assert(proposedSlot, IsNil)
+}
+
+func (s *CameraInterfaceSuite) TestHotplugKey(c *C) {
+	keyHandler := s.iface.(hotplug.HotplugKeyHandler)
+	attrs := map[string]string{"DEVPATH": "/sys/foo/bar", "DEVNAME": "/dev/video2", "ID_PATH": "pci-0000:00:14.0-usb-0:2:1.0", "ID_SERIAL": "046d_0825_1234", "ID_USB_INTERFACE_NUM": "00", "SUBSYSTEM": "video4linux"}
+	di, err := hotplug.NewHotplugDeviceInfo(attrs)
+	c.Assert(err, IsNil)
+	key1, err := keyHandler.HotplugKey(di)
+	c.Assert(err, IsNil)
+	c.Check(key1, Not(Equals), snap.HotplugKey(""))
+
+	// the same camera on another port gets another key
+	attrs["ID_PATH"] = "pci-0000:00:14.0-usb-0:3:1.0"
+	di, err = hotplug.NewHotplugDeviceInfo(attrs)
+	c.Assert(err, IsNil)
+	key2, err := keyHandler.HotplugKey(di)
+	c.Assert(err, IsNil)
+	c.Check(key2, Not(Equals), key1)
+
+	// without a physical path the default key is used
+	delete(attrs, "ID_PATH")
+	di, err = hotplug.NewHotplugDeviceInfo(attrs)
+	c.Assert(err, IsNil)
+	key3, err := keyHandler.HotplugKey(di)
+	c.Assert(err, IsNil)
+	c.Check(key3, Equals, snap.HotplugKey(""))
+}
+
+func (s *CameraInterfaceSuite) TestHotplugSlot(c *C) {
+	slotInfo := MockHotplugSlot(c, cameraCoreYaml, nil, "1234", "camera", "camera-1", map[string]interface{}{"path": "/dev/video2"})
+	slotInfo.Interface = "camera"
+	c.Assert(interfaces.BeforePrepareSlot(s.iface, slotInfo), IsNil)
+	slot := interfaces.NewConnectedSlot(slotInfo, nil, nil)
+
+	apparmorSpec := &apparmor.Specification{}
+	c.Assert(apparmorSpec.AddConnectedPlug(s.iface, s.plug, slot), IsNil)
+	c.Check(apparmorSpec.SnippetForTag("snap.consumer.app"), testutil.Contains, "/dev/video2 rw,")
+	c.Check(apparmorSpec.SnippetForTag("snap.consumer.app"), Not(testutil.Contains), "/dev/video[0-9]* rw")
+
+	udevSpec := &udev.Specification{}
+	c.Assert(udevSpec.AddConnectedPlug(s.iface, s.plug, slot), IsNil)
+	c.Assert(udevSpec.Snippets(), HasLen, 2)
+	c.Check(udevSpec.Snippets(), testutil.Contains, `# camera
+SUBSYSTEM=="video4linux", KERNEL=="video2", TAG+="snap_consumer_app"`)
+
+	byGadgetPred := s.iface.(hotplug.HandledByGadgetPredicate)
+	di, err := hotplug.NewHotplugDeviceInfo(map[string]string{"DEVPATH": "/sys/foo/bar", "DEVNAME": "/dev/video2", "SUBSYSTEM": "video4linux"})
+	c.Assert(err, IsNil)
+	c.Check(byGadgetPred.HandledByGadget(di, slotInfo), Equals, true)
+	c.Check(byGadgetPred.HandledByGadget(di, s.slotInfo), Equals, false)
+
+	slotInfo.Attrs["path"] = "/dev/foo"
+	c.Check(interfaces.BeforePrepareSlot(s.iface, slotInfo), ErrorMatches, `slot "core:camera-1" path attribute must be a valid device node`)
+}
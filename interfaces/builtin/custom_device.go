@@ -0,0 +1,402 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package builtin
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/snapcore/snapd/interfaces"
+	"github.com/snapcore/snapd/interfaces/apparmor"
+	"github.com/snapcore/snapd/interfaces/kmod"
+	"github.com/snapcore/snapd/interfaces/udev"
+	"github.com/snapcore/snapd/snap"
+)
+
+const customDeviceSummary = `provides access to custom devices specified via the slot`
+
+// The slot grants access to arbitrary devices, files and kernel modules,
+// so installing it requires a snap declaration, which is how brands
+// approve the snaps declaring their hardware. Plugs can only connect to
+// slots describing the same custom device.
+const customDeviceBaseDeclarationSlots = `
+  custom-device:
+    allow-installation: false
+    allow-connection:
+      plug-attributes:
+        custom-device: $SLOT(custom-device)
+    deny-auto-connection: true
+`
+
+// customDeviceInterface lets the slot describe the devices, files and
+// kernel modules plugs are granted access to, through these attributes:
+//
+//  - custom-device: the name of the device, the slot or plug name by default
+//  - devices: device nodes plugs can read and write, as globs
+//  - read-devices: device nodes plugs can only read, as globs
+//  - files: map with read and write lists of other paths
+//  - kernel-modules: modules loaded when a plug is connected
+//  - udev-tagging: list of rules matching the devices, with a kernel
+//    key and optional subsystem, environment and attributes keys;
+//    without it the devices are matched by their kernel name
+type customDeviceInterface struct{}
+
+func (iface *customDeviceInterface) Name() string {
+	return "custom-device"
+}
+
+func (iface *customDeviceInterface) StaticInfo() interfaces.StaticInfo {
+	return interfaces.StaticInfo{
+		Summary:              customDeviceSummary,
+		BaseDeclarationSlots: customDeviceBaseDeclarationSlots,
+	}
+}
+
+func (iface *customDeviceInterface) String() string {
+	return iface.Name()
+}
+
+var (
+	customDeviceNamePattern   = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+	customDeviceModulePattern = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
+	customDeviceUDevKeyRegexp = regexp.MustCompile(`^[a-zA-Z0-9_./-]+$`)
+)
+
+func validateCustomDeviceName(attrs map[string]interface{}, defaultName string) error {
+	name, ok := attrs["custom-device"]
+	if !ok {
+		attrs["custom-device"] = defaultName
+		return nil
+	}
+	s, ok := name.(string)
+	if !ok || !customDeviceNamePattern.MatchString(s) {
+		return fmt.Errorf(`custom-device attribute must be a valid device name`)
+	}
+	return nil
+}
+
+// validateCustomDevicePath checks a device node or file path; device
+// nodes may use the * glob in their last component.
+func validateCustomDevicePath(p string, device bool) error {
+	if filepath.Clean(p) != p || !filepath.IsAbs(p) {
+		return fmt.Errorf("%q is not a clean absolute path", p)
+	}
+	underDev := strings.HasPrefix(p, "/dev/")
+	if device != underDev {
+		if device {
+			return fmt.Errorf("%q must be under /dev/", p)
+		}
+		return fmt.Errorf("%q must not be under /dev/, use devices or read-devices", p)
+	}
+	noGlob := p
+	if device {
+		dir, base := filepath.Split(p)
+		if strings.Contains(base, "**") {
+			return fmt.Errorf(`%q cannot contain "**"`, p)
+		}
+		noGlob = dir + strings.Replace(base, "*", "", -1)
+	}
+	if err := apparmor.ValidateNoAppArmorRegexp(noGlob); err != nil {
+		return err
+	}
+	if strings.ContainsAny(p, ",\n\t ") {
+		return fmt.Errorf("%q cannot contain commas or whitespace", p)
+	}
+	return nil
+}
+
+func customDeviceStrings(attrs map[string]interface{}, attrName string) ([]string, error) {
+	raw, ok := attrs[attrName]
+	if !ok {
+		return nil, nil
+	}
+	list, ok := raw.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%q must be a list of strings", attrName)
+	}
+	strs := make([]string, 0, len(list))
+	for _, v := range list {
+		s, ok := v.(string)
+		if !ok {
+			return nil, fmt.Errorf("%q must be a list of strings", attrName)
+		}
+		strs = append(strs, s)
+	}
+	return strs, nil
+}
+
+func validateCustomDeviceUDevValue(what, v string) error {
+	if v == "" || strings.ContainsAny(v, "\"\\\n") {
+		return fmt.Errorf("%s %q cannot be empty or contain quotes, backslashes or newlines", what, v)
+	}
+	return nil
+}
+
+func customDeviceUDevMap(rule map[string]interface{}, key string) (map[string]string, error) {
+	raw, ok := rule[key]
+	if !ok {
+		return nil, nil
+	}
+	m, ok := raw.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("udev-tagging %s must be a map of strings", key)
+	}
+	res := make(map[string]string, len(m))
+	for k, v := range m {
+		s, ok := v.(string)
+		if !ok {
+			return nil, fmt.Errorf("udev-tagging %s must be a map of strings", key)
+		}
+		if !customDeviceUDevKeyRegexp.MatchString(k) {
+			return nil, fmt.Errorf("udev-tagging %s key %q is invalid", key, k)
+		}
+		if err := validateCustomDeviceUDevValue("udev-tagging "+key+" value", s); err != nil {
+			return nil, err
+		}
+		res[k] = s
+	}
+	return res, nil
+}
+
+// customDeviceUDevRules returns the udev match rules for the devices of
+// the slot.
+func customDeviceUDevRules(attrs map[string]interface{}) ([]string, error) {
+	raw, ok := attrs["udev-tagging"]
+	if !ok {
+		var rules []string
+		for _, attr := range []string{"devices", "read-devices"} {
+			paths, err := customDeviceStrings(attrs, attr)
+			if err != nil {
+				return nil, err
+			}
+			for _, p := range paths {
+				// the kernel name of the device is the name of
+				// its node, even when udev creates the node in a
+				// subdirectory such as /dev/input/
+				rules = append(rules, fmt.Sprintf(`KERNEL=="%s"`, filepath.Base(p)))
+			}
+		}
+		return rules, nil
+	}
+
+	list, ok := raw.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf(`"udev-tagging" must be a list of maps`)
+	}
+	rules := make([]string, 0, len(list))
+	for _, r := range list {
+		rule, ok := r.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf(`"udev-tagging" must be a list of maps`)
+		}
+		for k := range rule {
+			switch k {
+			case "kernel", "subsystem", "environment", "attributes":
+			default:
+				return nil, fmt.Errorf("unknown udev-tagging key %q", k)
+			}
+		}
+		kernel, ok := rule["kernel"].(string)
+		if !ok {
+			return nil, fmt.Errorf("udev-tagging rules must have a kernel key")
+		}
+		if err := validateCustomDeviceUDevValue("udev-tagging kernel", kernel); err != nil {
+			return nil, err
+		}
+		if strings.Contains(kernel, "/") {
+			return nil, fmt.Errorf("udev-tagging kernel %q must be the name of the device, not a path", kernel)
+		}
+		buf := bytes.NewBufferString(fmt.Sprintf(`KERNEL=="%s"`, kernel))
+		if subsystem, ok := rule["subsystem"]; ok {
+			s, ok := subsystem.(string)
+			if !ok {
+				return nil, fmt.Errorf("udev-tagging subsystem must be a string")
+			}
+			if err := validateCustomDeviceUDevValue("udev-tagging subsystem", s); err != nil {
+				return nil, err
+			}
+			fmt.Fprintf(buf, `, SUBSYSTEM=="%s"`, s)
+		}
+		for _, kv := range []struct{ key, match string }{
+			{"environment", "ENV"},
+			{"attributes", "ATTR"},
+		} {
+			m, err := customDeviceUDevMap(rule, kv.key)
+			if err != nil {
+				return nil, err
+			}
+			keys := make([]string, 0, len(m))
+			for k := range m {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			for _, k := range keys {
+				fmt.Fprintf(buf, `, %s{%s}=="%s"`, kv.match, k, m[k])
+			}
+		}
+		rules = append(rules, buf.String())
+	}
+	return rules, nil
+}
+
+func (iface *customDeviceInterface) BeforePreparePlug(plug *snap.PlugInfo) error {
+	if plug.Attrs == nil {
+		plug.Attrs = make(map[string]interface{})
+	}
+	return validateCustomDeviceName(plug.Attrs, plug.Name)
+}
+
+func (iface *customDeviceInterface) BeforePrepareSlot(slot *snap.SlotInfo) error {
+	if slot.Attrs == nil {
+		slot.Attrs = make(map[string]interface{})
+	}
+	if err := validateCustomDeviceName(slot.Attrs, slot.Name); err != nil {
+		return err
+	}
+
+	hasDevices := false
+	for _, attr := range []string{"devices", "read-devices"} {
+		paths, err := customDeviceStrings(slot.Attrs, attr)
+		if err != nil {
+			return err
+		}
+		for _, p := range paths {
+			if err := validateCustomDevicePath(p, true); err != nil {
+				return fmt.Errorf("cannot use %s: %v", attr, err)
+			}
+		}
+		hasDevices = hasDevices || len(paths) > 0
+	}
+	if !hasDevices {
+		return fmt.Errorf(`custom-device slot must have "devices" or "read-devices" attributes`)
+	}
+
+	if files, ok := slot.Attrs["files"]; ok {
+		m, ok := files.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf(`"files" must be a map with read and write lists`)
+		}
+		for k := range m {
+			if k != "read" && k != "write" {
+				return fmt.Errorf(`"files" must be a map with read and write lists`)
+			}
+			paths, err := customDeviceStrings(m, k)
+			if err != nil {
+				return err
+			}
+			for _, p := range paths {
+				if err := validateCustomDevicePath(p, false); err != nil {
+					return fmt.Errorf("cannot use files: %v", err)
+				}
+			}
+		}
+	}
+
+	modules, err := customDeviceStrings(slot.Attrs, "kernel-modules")
+	if err != nil {
+		return err
+	}
+	for _, m := range modules {
+		if !customDeviceModulePattern.MatchString(m) {
+			return fmt.Errorf("invalid kernel module name %q", m)
+		}
+	}
+
+	_, err = customDeviceUDevRules(slot.Attrs)
+	return err
+}
+
+func (iface *customDeviceInterface) AppArmorConnectedPlug(spec *apparmor.Specification, plug *interfaces.ConnectedPlug, slot *interfaces.ConnectedSlot) error {
+	attrs := slot.StaticAttrs()
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "# Description: access to the custom device %q\n", attrs["custom-device"])
+	for _, p := range []struct {
+		attr  string
+		attrs map[string]interface{}
+		perms string
+	}{
+		{"devices", attrs, "rwk"},
+		{"read-devices", attrs, "r"},
+	} {
+		paths, err := customDeviceStrings(p.attrs, p.attr)
+		if err != nil {
+			return err
+		}
+		for _, path := range paths {
+			fmt.Fprintf(&buf, "%s %s,\n", path, p.perms)
+		}
+	}
+	if files, ok := attrs["files"].(map[string]interface{}); ok {
+		for _, p := range []struct {
+			attr string
+			perm filesAAPerm
+		}{
+			{"read", filesRead},
+			{"write", filesWrite},
+		} {
+			paths, err := customDeviceStrings(files, p.attr)
+			if err != nil {
+				return err
+			}
+			for _, path := range paths {
+				fmt.Fprintf(&buf, "%q %s,\n", path, p.perm)
+			}
+		}
+	}
+	spec.AddSnippet(buf.String())
+	return nil
+}
+
+func (iface *customDeviceInterface) UDevConnectedPlug(spec *udev.Specification, plug *interfaces.ConnectedPlug, slot *interfaces.ConnectedSlot) error {
+	rules, err := customDeviceUDevRules(slot.StaticAttrs())
+	if err != nil {
+		return err
+	}
+	for _, rule := range rules {
+		spec.TagDevice(rule)
+	}
+	return nil
+}
+
+func (iface *customDeviceInterface) KModConnectedPlug(spec *kmod.Specification, plug *interfaces.ConnectedPlug, slot *interfaces.ConnectedSlot) error {
+	modules, err := customDeviceStrings(slot.StaticAttrs(), "kernel-modules")
+	if err != nil {
+		return err
+	}
+	for _, m := range modules {
+		if err := spec.AddModule(m); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (iface *customDeviceInterface) AutoConnect(*snap.PlugInfo, *snap.SlotInfo) bool {
+	// allow what declarations allowed
+	return true
+}
+
+func init() {
+	registerIface(&customDeviceInterface{})
+}
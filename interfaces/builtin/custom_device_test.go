@@ -0,0 +1,217 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package builtin_test
+
+import (
+	"fmt"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/interfaces"
+	"github.com/snapcore/snapd/interfaces/apparmor"
+	"github.com/snapcore/snapd/interfaces/builtin"
+	"github.com/snapcore/snapd/interfaces/kmod"
+	"github.com/snapcore/snapd/interfaces/udev"
+	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/snap/snaptest"
+	"github.com/snapcore/snapd/testutil"
+)
+
+type CustomDeviceInterfaceSuite struct {
+	iface    interfaces.Interface
+	slot     *interfaces.ConnectedSlot
+	slotInfo *snap.SlotInfo
+	plug     *interfaces.ConnectedPlug
+	plugInfo *snap.PlugInfo
+}
+
+var _ = Suite(&CustomDeviceInterfaceSuite{
+	iface: builtin.MustInterface("custom-device"),
+})
+
+const customDeviceConsumerYaml = `name: consumer
+version: 0
+plugs:
+  fpga:
+    interface: custom-device
+apps:
+  app:
+    plugs: [fpga]
+`
+
+const customDeviceGadgetYaml = `name: gadget
+version: 0
+type: gadget
+slots:
+  fpga:
+    interface: custom-device
+    devices:
+      - /dev/fpga0
+      - /dev/fpga-mgr*
+    read-devices:
+      - /dev/fpga-status
+    files:
+      read:
+        - /sys/class/fpga_manager/fpga0/state
+      write:
+        - /sys/class/fpga_manager/fpga0/firmware
+    kernel-modules:
+      - fpga_mgr
+    udev-tagging:
+      - kernel: fpga0
+        subsystem: fpga
+        environment:
+          ID_BUS: platform
+        attributes:
+          name: my-fpga
+`
+
+func (s *CustomDeviceInterfaceSuite) SetUpTest(c *C) {
+	plugSnap := snaptest.MockInfo(c, customDeviceConsumerYaml, nil)
+	s.plugInfo = plugSnap.Plugs["fpga"]
+	c.Assert(interfaces.BeforePreparePlug(s.iface, s.plugInfo), IsNil)
+	s.plug = interfaces.NewConnectedPlug(s.plugInfo, nil, nil)
+
+	slotSnap := snaptest.MockInfo(c, customDeviceGadgetYaml, nil)
+	s.slotInfo = slotSnap.Slots["fpga"]
+	c.Assert(interfaces.BeforePrepareSlot(s.iface, s.slotInfo), IsNil)
+	s.slot = interfaces.NewConnectedSlot(s.slotInfo, nil, nil)
+}
+
+func (s *CustomDeviceInterfaceSuite) TestName(c *C) {
+	c.Assert(s.iface.Name(), Equals, "custom-device")
+}
+
+func (s *CustomDeviceInterfaceSuite) TestDefaultDeviceName(c *C) {
+	c.Check(s.plugInfo.Attrs["custom-device"], Equals, "fpga")
+	c.Check(s.slotInfo.Attrs["custom-device"], Equals, "fpga")
+}
+
+func (s *CustomDeviceInterfaceSuite) TestSanitizeSlotErrors(c *C) {
+	for _, t := range []struct {
+		attrs string
+		err   string
+	}{
+		{"custom-device: Bad_Name\n    devices: [/dev/foo]", `custom-device attribute must be a valid device name`},
+		{"files:\n      read: [/etc/foo]", `custom-device slot must have "devices" or "read-devices" attributes`},
+		{"devices: /dev/foo", `"devices" must be a list of strings`},
+		{"devices: [/etc/foo]", `cannot use devices: "/etc/foo" must be under /dev/`},
+		{"devices: [/dev/../etc/foo]", `cannot use devices: "/dev/../etc/foo" is not a clean absolute path`},
+		{"devices: [/dev/**]", `cannot use devices: "/dev/\*\*" cannot contain "\*\*"`},
+		{"devices: [/dev/*/foo]", `cannot use devices: "/dev/\*/foo" contains a reserved apparmor char from .*`},
+		{"read-devices: ['/dev/foo[0-9]']", `cannot use read-devices: "/dev/foo\[0-9\]" contains a reserved apparmor char from .*`},
+		{"devices: [/dev/foo]\n    files:\n      read: [/dev/bar]", `cannot use files: "/dev/bar" must not be under /dev/, use devices or read-devices`},
+		{"devices: [/dev/foo]\n    files:\n      exec: [/bin/bar]", `"files" must be a map with read and write lists`},
+		{"devices: [/dev/foo]\n    files:\n      write: [/sys/foo*]", `cannot use files: "/sys/foo\*" contains a reserved apparmor char from .*`},
+		{"devices: [/dev/foo]\n    kernel-modules: [foo.ko]", `invalid kernel module name "foo.ko"`},
+		{"devices: [/dev/foo]\n    udev-tagging: [{subsystem: foo}]", `udev-tagging rules must have a kernel key`},
+		{"devices: [/dev/foo]\n    udev-tagging: [{kernel: foo, action: add}]", `unknown udev-tagging key "action"`},
+		{"devices: [/dev/foo]\n    udev-tagging: [{kernel: 'fo\"o'}]", `udev-tagging kernel "fo\\"o" cannot be empty or contain quotes, backslashes or newlines`},
+		{"devices: [/dev/foo]\n    udev-tagging: [{kernel: foo, environment: {'A B': x}}]", `udev-tagging environment key "A B" is invalid`},
+		{"devices: [/dev/input/event0]\n    udev-tagging: [{kernel: input/event0}]", `udev-tagging kernel "input/event0" must be the name of the device, not a path`},
+	} {
+		yaml := fmt.Sprintf("name: gadget\nversion: 0\ntype: gadget\nslots:\n  fpga:\n    interface: custom-device\n    %s\n", t.attrs)
+		slotInfo := snaptest.MockInfo(c, yaml, nil).Slots["fpga"]
+		c.Check(interfaces.BeforePrepareSlot(s.iface, slotInfo), ErrorMatches, t.err, Commentf("%s", t.attrs))
+	}
+}
+
+func (s *CustomDeviceInterfaceSuite) TestAppArmorSpec(c *C) {
+	spec := &apparmor.Specification{}
+	c.Assert(spec.AddConnectedPlug(s.iface, s.plug, s.slot), IsNil)
+	c.Assert(spec.SecurityTags(), DeepEquals, []string{"snap.consumer.app"})
+	c.Check(spec.SnippetForTag("snap.consumer.app"), Equals, `# Description: access to the custom device "fpga"
+/dev/fpga0 rwk,
+/dev/fpga-mgr* rwk,
+/dev/fpga-status r,
+"/sys/class/fpga_manager/fpga0/state" rk,
+"/sys/class/fpga_manager/fpga0/firmware" rwkl,
+`)
+}
+
+func (s *CustomDeviceInterfaceSuite) TestUDevSpec(c *C) {
+	spec := &udev.Specification{}
+	c.Assert(spec.AddConnectedPlug(s.iface, s.plug, s.slot), IsNil)
+	c.Assert(spec.Snippets(), HasLen, 2)
+	c.Check(spec.Snippets(), testutil.Contains, `# custom-device
+KERNEL=="fpga0", SUBSYSTEM=="fpga", ENV{ID_BUS}=="platform", ATTR{name}=="my-fpga", TAG+="snap_consumer_app"`)
+}
+
+func (s *CustomDeviceInterfaceSuite) TestUDevSpecFromDevices(c *C) {
+	delete(s.slotInfo.Attrs, "udev-tagging")
+	slot := interfaces.NewConnectedSlot(s.slotInfo, nil, nil)
+
+	spec := &udev.Specification{}
+	c.Assert(spec.AddConnectedPlug(s.iface, s.plug, slot), IsNil)
+	c.Assert(spec.Snippets(), HasLen, 4)
+	c.Check(spec.Snippets(), testutil.Contains, `# custom-device
+KERNEL=="fpga0", TAG+="snap_consumer_app"`)
+	c.Check(spec.Snippets(), testutil.Contains, `# custom-device
+KERNEL=="fpga-mgr*", TAG+="snap_consumer_app"`)
+	c.Check(spec.Snippets(), testutil.Contains, `# custom-device
+KERNEL=="fpga-status", TAG+="snap_consumer_app"`)
+}
+
+func (s *CustomDeviceInterfaceSuite) TestUDevSpecFromNestedDevices(c *C) {
+	const yaml = `name: gadget
+version: 0
+type: gadget
+slots:
+  input:
+    interface: custom-device
+    devices: [/dev/input/event*]
+    read-devices: [/dev/dri/card0]
+`
+	slotInfo := snaptest.MockInfo(c, yaml, nil).Slots["input"]
+	c.Assert(interfaces.BeforePrepareSlot(s.iface, slotInfo), IsNil)
+	slot := interfaces.NewConnectedSlot(slotInfo, nil, nil)
+
+	// udev matches the kernel name of the devices, which does not
+	// include the directories of their nodes
+	spec := &udev.Specification{}
+	c.Assert(spec.AddConnectedPlug(s.iface, s.plug, slot), IsNil)
+	c.Assert(spec.Snippets(), HasLen, 3)
+	c.Check(spec.Snippets(), testutil.Contains, `# custom-device
+KERNEL=="event*", TAG+="snap_consumer_app"`)
+	c.Check(spec.Snippets(), testutil.Contains, `# custom-device
+KERNEL=="card0", TAG+="snap_consumer_app"`)
+}
+
+func (s *CustomDeviceInterfaceSuite) TestKModSpec(c *C) {
+	spec := &kmod.Specification{}
+	c.Assert(spec.AddConnectedPlug(s.iface, s.plug, s.slot), IsNil)
+	c.Check(spec.Modules(), DeepEquals, map[string]bool{"fpga_mgr": true})
+}
+
+func (s *CustomDeviceInterfaceSuite) TestStaticInfo(c *C) {
+	si := interfaces.StaticInfoOf(s.iface)
+	c.Check(si.ImplicitOnCore, Equals, false)
+	c.Check(si.ImplicitOnClassic, Equals, false)
+	c.Check(si.Summary, Equals, `provides access to custom devices specified via the slot`)
+	c.Check(si.BaseDeclarationSlots, testutil.Contains, "custom-device")
+}
+
+func (s *CustomDeviceInterfaceSuite) TestAutoConnect(c *C) {
+	c.Check(s.iface.AutoConnect(s.plugInfo, s.slotInfo), Equals, true)
+}
+
+func (s *CustomDeviceInterfaceSuite) TestInterfaces(c *C) {
+	c.Check(builtin.Interfaces(), testutil.DeepContains, s.iface)
+}
@@ -27,6 +27,7 @@ import (
 
 	"github.com/snapcore/snapd/interfaces"
 	"github.com/snapcore/snapd/interfaces/apparmor"
+	"github.com/snapcore/snapd/interfaces/hotplug"
 	"github.com/snapcore/snapd/interfaces/udev"
 	"github.com/snapcore/snapd/snap"
 )
@@ -193,6 +194,29 @@ func (iface *hidrawInterface) AutoConnect(*snap.PlugInfo, *snap.SlotInfo) bool {
 	return true
 }
 
+func (iface *hidrawInterface) HotplugDeviceDetected(di *hotplug.HotplugDeviceInfo) (*hotplug.ProposedSlot, error) {
+	if bus, _ := di.Attribute("ID_BUS"); bus != "usb" {
+		return nil, nil
+	}
+	return hotplugDeviceNodeSlot(di, "hidraw", hidrawDeviceNodePattern), nil
+}
+
+func (iface *hidrawInterface) HotplugKey(di *hotplug.HotplugDeviceInfo) (snap.HotplugKey, error) {
+	return hotplugPortKey(di, "ID_USB_INTERFACE_NUM")
+}
+
+func (iface *hidrawInterface) HandledByGadget(di *hotplug.HotplugDeviceInfo, slot *snap.SlotInfo) bool {
+	// if the slot has vendor and product set, check if they match
+	var usbVendor, usbProduct int64
+	if err := slot.Attr("usb-vendor", &usbVendor); err == nil {
+		if err := slot.Attr("usb-product", &usbProduct); err != nil {
+			return false
+		}
+		return slotDeviceAttrEqual(di, "ID_VENDOR_ID", usbVendor) && slotDeviceAttrEqual(di, "ID_MODEL_ID", usbProduct)
+	}
+	return handledByGadgetPath(di, slot)
+}
+
 func (iface *hidrawInterface) hasUsbAttrs(attrs interfaces.Attrer) bool {
 	var v int64
 	if err := attrs.Attr("usb-vendor", &v); err == nil {
@@ -28,6 +28,7 @@ import (
 	"github.com/snapcore/snapd/interfaces"
 	"github.com/snapcore/snapd/interfaces/apparmor"
 	"github.com/snapcore/snapd/interfaces/builtin"
+	"github.com/snapcore/snapd/interfaces/hotplug"
 	"github.com/snapcore/snapd/interfaces/udev"
 	"github.com/snapcore/snapd/snap"
 	"github.com/snapcore/snapd/snap/snaptest"
@@ -365,3 +366,34 @@ SUBSYSTEM=="hidraw", SUBSYSTEMS=="usb", ATTRS{idVendor}=="ffff", ATTRS{idProduct
 func (s *HidrawInterfaceSuite) TestInterfaces(c *C) {
 	c.Check(builtin.Interfaces(), testutil.DeepContains, s.iface)
 }
+
+func (s *HidrawInterfaceSuite) TestHotplugDeviceDetected(c *C) {
+	hotplugIface := s.iface.(hotplug.Definer)
+	di, err := hotplug.NewHotplugDeviceInfo(map[string]string{"DEVPATH": "/sys/foo/bar", "DEVNAME": "/dev/hidraw3", "ID_VENDOR_ID": "0c2e", "ID_MODEL_ID": "0901", "ACTION": "add", "SUBSYSTEM": "hidraw", "ID_BUS": "usb"})
+	c.Assert(err, IsNil)
+	proposedSlot, err := hotplugIface.HotplugDeviceDetected(di)
+	c.Assert(err, IsNil)
+	c.Assert(proposedSlot, DeepEquals, &hotplug.ProposedSlot{Attrs: map[string]interface{}{"path": "/dev/hidraw3", "usb-vendor": "0c2e", "usb-product": "0901"}})
+
+	// the proposed slot is valid
+	slotInfo := MockHotplugSlot(c, "name: core\nversion: 0\ntype: os", nil, "1234", "hidraw", "hidraw-1", proposedSlot.Attrs)
+	slotInfo.Interface = "hidraw"
+	c.Check(interfaces.BeforePrepareSlot(s.iface, slotInfo), IsNil)
+
+	// not a usb device
+	di, err = hotplug.NewHotplugDeviceInfo(map[string]string{"DEVPATH": "/sys/foo/bar", "DEVNAME": "/dev/hidraw4", "ACTION": "add", "SUBSYSTEM": "hidraw", "ID_BUS": "i2c"})
+	c.Assert(err, IsNil)
+	proposedSlot, err = hotplugIface.HotplugDeviceDetected(di)
+	c.Assert(err, IsNil)
+	c.Assert(proposedSlot, IsNil)
+}
+
+func (s *HidrawInterfaceSuite) TestHotplugHandledByGadget(c *C) {
+	byGadgetPred := s.iface.(hotplug.HandledByGadgetPredicate)
+	di, err := hotplug.NewHotplugDeviceInfo(map[string]string{"DEVPATH": "/sys/foo/bar", "DEVNAME": "/dev/hidraw0", "ID_VENDOR_ID": "0001", "ID_MODEL_ID": "0001", "ACTION": "add", "SUBSYSTEM": "hidraw", "ID_BUS": "usb"})
+	c.Assert(err, IsNil)
+	c.Check(byGadgetPred.HandledByGadget(di, s.testUDev1Info), Equals, true)
+	c.Check(byGadgetPred.HandledByGadget(di, s.testUDev2Info), Equals, false)
+	c.Check(byGadgetPred.HandledByGadget(di, s.testSlot1Info), Equals, true)
+	c.Check(byGadgetPred.HandledByGadget(di, s.testSlot2Info), Equals, false)
+}
@@ -0,0 +1,128 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package builtin
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"regexp"
+
+	"github.com/snapcore/snapd/interfaces"
+	"github.com/snapcore/snapd/interfaces/hotplug"
+	"github.com/snapcore/snapd/snap"
+)
+
+// hotplugPortKeyVersion is the version of the keys computed by
+// hotplugPortKey and hotplugNetKey. Any future change to the attributes
+// used by the keys requires a new version.
+const hotplugPortKeyVersion = 0
+
+// hotplugPortKey computes a hotplug key for devices that may be present
+// several times with identical vendor, model and serial attributes
+// (e.g. two cameras of the same model, or the interfaces of a composite
+// usb device), which the default key cannot tell apart. The key is
+// derived from the physical path of the device (ID_PATH), its serial
+// and the given extra attributes, so a device keeps its slot as long as
+// it is plugged into the same port. An empty key is returned when the device
+// has no ID_PATH, in which case the default key is used.
+func hotplugPortKey(di *hotplug.HotplugDeviceInfo, extraAttrs ...string) (snap.HotplugKey, error) {
+	if val, _ := di.Attribute("ID_PATH"); val == "" {
+		return "", nil
+	}
+	return hotplugAttrsKey(di, append([]string{"ID_PATH", "ID_SERIAL"}, extraAttrs...)), nil
+}
+
+// hotplugNetKey computes a hotplug key for network interfaces from the
+// name udev derives from their MAC address (ID_NET_NAME_MAC) and from
+// their physical path (ID_NET_NAME_PATH), which stay the same when the
+// kernel names the interfaces in a different order. An empty key is
+// returned when the interface has neither, in which case the default
+// key is used.
+func hotplugNetKey(di *hotplug.HotplugDeviceInfo) (snap.HotplugKey, error) {
+	mac, _ := di.Attribute("ID_NET_NAME_MAC")
+	path, _ := di.Attribute("ID_NET_NAME_PATH")
+	if mac == "" && path == "" {
+		return "", nil
+	}
+	return hotplugAttrsKey(di, []string{"ID_NET_NAME_MAC", "ID_NET_NAME_PATH"}), nil
+}
+
+func hotplugAttrsKey(di *hotplug.HotplugDeviceInfo, attrs []string) snap.HotplugKey {
+	key := sha256.New()
+	for _, attr := range attrs {
+		val, _ := di.Attribute(attr)
+		key.Write([]byte(attr))
+		key.Write([]byte{0})
+		key.Write([]byte(val))
+		key.Write([]byte{0})
+	}
+	return snap.HotplugKey(fmt.Sprintf("%x%x", hotplugPortKeyVersion, key.Sum(nil)))
+}
+
+// hotplugDeviceNodeSlot returns the slot proposed for a device handled
+// through its device node when the name of the node matches the given
+// pattern, or nil otherwise. As for serial-port, the usb vendor and
+// product identifiers are informational only and the node is accessed
+// through its path.
+func hotplugDeviceNodeSlot(di *hotplug.HotplugDeviceInfo, subsystem string, devicePattern *regexp.Regexp) *hotplug.ProposedSlot {
+	if di.Subsystem() != subsystem || !devicePattern.MatchString(di.DeviceName()) {
+		return nil
+	}
+	slot := hotplug.ProposedSlot{
+		Attrs: map[string]interface{}{
+			"path": di.DeviceName(),
+		},
+	}
+	if vendor, ok := di.Attribute("ID_VENDOR_ID"); ok {
+		slot.Attrs["usb-vendor"] = vendor
+	}
+	if product, ok := di.Attribute("ID_MODEL_ID"); ok {
+		slot.Attrs["usb-product"] = product
+	}
+	return &slot
+}
+
+// hotplugDevicePath returns the path attribute of slots restricted to a
+// single device node, as created for hotplugged devices.
+func hotplugDevicePath(attrs interfaces.Attrer) (string, bool) {
+	var path string
+	if err := attrs.Attr("path", &path); err != nil || path == "" {
+		return "", false
+	}
+	return path, true
+}
+
+// verifyOptionalSlotPathAttribute checks the path attribute of slots of
+// interfaces that grant access to a whole class of devices unless the
+// slot is restricted to a single device node.
+func verifyOptionalSlotPathAttribute(slot *snap.SlotInfo, reg *regexp.Regexp) error {
+	if _, ok := slot.Attrs["path"]; !ok {
+		return nil
+	}
+	_, err := verifySlotPathAttribute(&interfaces.SlotRef{Snap: slot.Snap.InstanceName(), Name: slot.Name}, slot, reg, invalidDeviceNodeSlotPathErrFmt)
+	return err
+}
+
+// handledByGadgetPath returns whether the device is the one of the
+// given gadget slot with a path attribute.
+func handledByGadgetPath(di *hotplug.HotplugDeviceInfo, slot *snap.SlotInfo) bool {
+	path, ok := hotplugDevicePath(slot)
+	return ok && di.DeviceName() == path
+}
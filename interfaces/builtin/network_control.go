@@ -20,7 +20,13 @@
 package builtin
 
 import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/snapcore/snapd/interfaces/hotplug"
 	"github.com/snapcore/snapd/osutil"
+	"github.com/snapcore/snapd/snap"
 )
 
 const networkControlSummary = `allows configuring networking and network namespaces`
@@ -331,8 +337,70 @@ mount options=(rw bind) /var/lib/snapd/hostfs/var/lib/dhcp/ -> /var/lib/dhcp/,
 umount /var/lib/dhcp/,
 `
 
+// Pattern to match valid network interface names, see dev_valid_name()
+// in the kernel.
+var networkInterfaceNamePattern = regexp.MustCompile(`^[^/:\s]{1,15}$`)
+
+// networkControlInterface creates slots for hotplugged physical network
+// interfaces, naming the network interface in the interface attribute.
+// The interface grants control of all of networking regardless, the
+// slots let the plugs know which network interface they are meant to
+// manage.
+type networkControlInterface struct {
+	commonInterface
+}
+
+func (iface *networkControlInterface) BeforePrepareSlot(slot *snap.SlotInfo) error {
+	name, ok := slot.Attrs["interface"]
+	if !ok {
+		return nil
+	}
+	if s, ok := name.(string); !ok || s == "." || s == ".." || !networkInterfaceNamePattern.MatchString(s) {
+		return fmt.Errorf("network-control interface attribute must be a valid network interface name")
+	}
+	return nil
+}
+
+func (iface *networkControlInterface) HotplugDeviceDetected(di *hotplug.HotplugDeviceInfo) (*hotplug.ProposedSlot, error) {
+	name, _ := di.Attribute("INTERFACE")
+	if di.Subsystem() != "net" || name == "" {
+		return nil, nil
+	}
+	// loopback, bridges, veth pairs and the like are not hotplugged
+	// hardware
+	devpath, _ := di.Attribute("DEVPATH")
+	if strings.HasPrefix(devpath, "/devices/virtual/") {
+		return nil, nil
+	}
+	slot := hotplug.ProposedSlot{
+		Attrs: map[string]interface{}{
+			"interface": name,
+		},
+	}
+	if vendor, ok := di.Attribute("ID_VENDOR_ID"); ok {
+		slot.Attrs["usb-vendor"] = vendor
+	}
+	if product, ok := di.Attribute("ID_MODEL_ID"); ok {
+		slot.Attrs["usb-product"] = product
+	}
+	return &slot, nil
+}
+
+func (iface *networkControlInterface) HotplugKey(di *hotplug.HotplugDeviceInfo) (snap.HotplugKey, error) {
+	return hotplugNetKey(di)
+}
+
+func (iface *networkControlInterface) HandledByGadget(di *hotplug.HotplugDeviceInfo, slot *snap.SlotInfo) bool {
+	var name string
+	if err := slot.Attr("interface", &name); err != nil {
+		return false
+	}
+	ifname, _ := di.Attribute("INTERFACE")
+	return ifname == name
+}
+
 func init() {
-	registerIface(&commonInterface{
+	registerIface(&networkControlInterface{commonInterface{
 		name:                  "network-control",
 		summary:               networkControlSummary,
 		implicitOnCore:        true,
@@ -346,6 +414,6 @@ func init() {
 		connectedPlugUpdateNSAppArmor: networkControlConnectedPlugUpdateNSAppArmor,
 
 		suppressPtraceTrace: true,
-	})
+	}})
 
 }
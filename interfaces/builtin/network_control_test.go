@@ -28,6 +28,7 @@ import (
 	"github.com/snapcore/snapd/interfaces"
 	"github.com/snapcore/snapd/interfaces/apparmor"
 	"github.com/snapcore/snapd/interfaces/builtin"
+	"github.com/snapcore/snapd/interfaces/hotplug"
 	"github.com/snapcore/snapd/interfaces/mount"
 	"github.com/snapcore/snapd/interfaces/seccomp"
 	"github.com/snapcore/snapd/interfaces/udev"
@@ -139,3 +140,65 @@ func (s *NetworkControlInterfaceSuite) TestAutoConnect(c *C) {
 func (s *NetworkControlInterfaceSuite) TestInterfaces(c *C) {
 	c.Check(builtin.Interfaces(), testutil.DeepContains, s.iface)
 }
+
+func (s *NetworkControlInterfaceSuite) TestHotplugDeviceDetected(c *C) {
+	hotplugIface := s.iface.(hotplug.Definer)
+	di, err := hotplug.NewHotplugDeviceInfo(map[string]string{"DEVPATH": "/devices/pci0000:00/0000:00:14.0/usb1/1-3/1-3:1.0/net/enx00e04c680001", "INTERFACE": "enx00e04c680001", "ID_VENDOR_ID": "0bda", "ID_MODEL_ID": "8153", "ACTION": "add", "SUBSYSTEM": "net"})
+	c.Assert(err, IsNil)
+	proposedSlot, err := hotplugIface.HotplugDeviceDetected(di)
+	c.Assert(err, IsNil)
+	c.Assert(proposedSlot, DeepEquals, &hotplug.ProposedSlot{Attrs: map[string]interface{}{"interface": "enx00e04c680001", "usb-vendor": "0bda", "usb-product": "8153"}})
+
+	byGadgetPred := s.iface.(hotplug.HandledByGadgetPredicate)
+	slotInfo := MockHotplugSlot(c, networkControlCoreYaml, nil, "1234", "network-control", "network-control-1", proposedSlot.Attrs)
+	slotInfo.Interface = "network-control"
+	c.Check(interfaces.BeforePrepareSlot(s.iface, slotInfo), IsNil)
+	c.Check(byGadgetPred.HandledByGadget(di, slotInfo), Equals, true)
+	c.Check(byGadgetPred.HandledByGadget(di, s.slotInfo), Equals, false)
+
+	slotInfo.Attrs["interface"] = "bad/name"
+	c.Check(interfaces.BeforePrepareSlot(s.iface, slotInfo), ErrorMatches, "network-control interface attribute must be a valid network interface name")
+
+	// virtual network interfaces are ignored
+	di, err = hotplug.NewHotplugDeviceInfo(map[string]string{"DEVPATH": "/devices/virtual/net/veth1234", "INTERFACE": "veth1234", "ACTION": "add", "SUBSYSTEM": "net"})
+	c.Assert(err, IsNil)
+	proposedSlot, err = hotplugIface.HotplugDeviceDetected(di)
+	c.Assert(err, IsNil)
+	c.Assert(proposedSlot, IsNil)
+}
+
+func (s *NetworkControlInterfaceSuite) TestHotplugKey(c *C) {
+	keyHandler := s.iface.(hotplug.HotplugKeyHandler)
+	attrs := map[string]string{"DEVPATH": "/devices/pci0000:00/0000:00:14.0/usb1/1-3/1-3:1.0/net/eth1", "INTERFACE": "eth1", "ID_NET_NAME_MAC": "enx00e04c680001", "ID_NET_NAME_PATH": "enp0s20f0u3", "SUBSYSTEM": "net"}
+	di, err := hotplug.NewHotplugDeviceInfo(attrs)
+	c.Assert(err, IsNil)
+	key, err := keyHandler.HotplugKey(di)
+	c.Assert(err, IsNil)
+	c.Check(key, Not(Equals), snap.HotplugKey(""))
+
+	// the key does not depend on the name the kernel gave the interface
+	attrs["INTERFACE"] = "eth2"
+	attrs["DEVPATH"] = "/devices/pci0000:00/0000:00:14.0/usb1/1-3/1-3:1.0/net/eth2"
+	di, err = hotplug.NewHotplugDeviceInfo(attrs)
+	c.Assert(err, IsNil)
+	renamedKey, err := keyHandler.HotplugKey(di)
+	c.Assert(err, IsNil)
+	c.Check(renamedKey, Equals, key)
+
+	// another adapter of the same model in the same port has another MAC
+	attrs["ID_NET_NAME_MAC"] = "enx00e04c680002"
+	di, err = hotplug.NewHotplugDeviceInfo(attrs)
+	c.Assert(err, IsNil)
+	otherKey, err := keyHandler.HotplugKey(di)
+	c.Assert(err, IsNil)
+	c.Check(otherKey, Not(Equals), key)
+
+	// the default key is used without MAC or path
+	delete(attrs, "ID_NET_NAME_MAC")
+	delete(attrs, "ID_NET_NAME_PATH")
+	di, err = hotplug.NewHotplugDeviceInfo(attrs)
+	c.Assert(err, IsNil)
+	defaultKey, err := keyHandler.HotplugKey(di)
+	c.Assert(err, IsNil)
+	c.Check(defaultKey, Equals, snap.HotplugKey(""))
+}
@@ -19,6 +19,18 @@
 
 package builtin
 
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/snapcore/snapd/interfaces"
+	"github.com/snapcore/snapd/interfaces/apparmor"
+	"github.com/snapcore/snapd/interfaces/hotplug"
+	"github.com/snapcore/snapd/interfaces/udev"
+	"github.com/snapcore/snapd/snap"
+)
+
 const rawusbSummary = `allows raw access to all USB devices`
 
 const rawusbBaseDeclarationSlots = `
@@ -40,7 +52,9 @@ const rawusbConnectedPlugAppArmor = `
 
 # Allow raw access to USB printers (i.e. for receipt printers in POS systems).
 /dev/usb/lp[0-9]* rwk,
+` + rawusbDetectionConnectedPlugAppArmor
 
+const rawusbDetectionConnectedPlugAppArmor = `
 # Allow detection of usb devices. Leaks plugged in USB device info
 /sys/bus/usb/devices/ r,
 /sys/devices/pci**/usb[0-9]** r,
@@ -66,8 +80,61 @@ var rawusbConnectedPlugUDev = []string{
 	`SUBSYSTEM=="tty", ENV{ID_BUS}=="usb"`,
 }
 
+// Pattern to match the usbfs device nodes of USB devices for which slots
+// are created when they are hotplugged.
+var rawusbDeviceNodePattern = regexp.MustCompile("^/dev/bus/usb/[0-9]{3}/[0-9]{3}$")
+
+// rawusbInterface grants access to all USB devices through the implicit
+// slot and to a single device through slots created for hotplugged
+// devices, which have a path attribute.
+type rawusbInterface struct {
+	commonInterface
+}
+
+func (iface *rawusbInterface) BeforePrepareSlot(slot *snap.SlotInfo) error {
+	return verifyOptionalSlotPathAttribute(slot, rawusbDeviceNodePattern)
+}
+
+func (iface *rawusbInterface) AppArmorConnectedPlug(spec *apparmor.Specification, plug *interfaces.ConnectedPlug, slot *interfaces.ConnectedSlot) error {
+	path, ok := hotplugDevicePath(slot)
+	if !ok {
+		return iface.commonInterface.AppArmorConnectedPlug(spec, plug, slot)
+	}
+	spec.AddSnippet(fmt.Sprintf("# Description: Allow raw access to a single USB device.\n%s rw,\n%s", path, rawusbDetectionConnectedPlugAppArmor))
+	return nil
+}
+
+func (iface *rawusbInterface) UDevConnectedPlug(spec *udev.Specification, plug *interfaces.ConnectedPlug, slot *interfaces.ConnectedSlot) error {
+	path, ok := hotplugDevicePath(slot)
+	if !ok {
+		return iface.commonInterface.UDevConnectedPlug(spec, plug, slot)
+	}
+	spec.TagDevice(fmt.Sprintf(`SUBSYSTEM=="usb", ENV{DEVNAME}=="%s"`, path))
+	return nil
+}
+
+func (iface *rawusbInterface) HotplugDeviceDetected(di *hotplug.HotplugDeviceInfo) (*hotplug.ProposedSlot, error) {
+	if di.DeviceType() != "usb_device" {
+		return nil, nil
+	}
+	// hubs are not interesting on their own, the devices plugged
+	// into them get slots of their own
+	if ifaces, _ := di.Attribute("ID_USB_INTERFACES"); strings.Contains(ifaces, ":09") {
+		return nil, nil
+	}
+	return hotplugDeviceNodeSlot(di, "usb", rawusbDeviceNodePattern), nil
+}
+
+func (iface *rawusbInterface) HotplugKey(di *hotplug.HotplugDeviceInfo) (snap.HotplugKey, error) {
+	return hotplugPortKey(di)
+}
+
+func (iface *rawusbInterface) HandledByGadget(di *hotplug.HotplugDeviceInfo, slot *snap.SlotInfo) bool {
+	return handledByGadgetPath(di, slot)
+}
+
 func init() {
-	registerIface(&commonInterface{
+	registerIface(&rawusbInterface{commonInterface{
 		name:                  "raw-usb",
 		summary:               rawusbSummary,
 		implicitOnCore:        true,
@@ -76,5 +143,5 @@ func init() {
 		connectedPlugAppArmor: rawusbConnectedPlugAppArmor,
 		connectedPlugSecComp:  rawusbConnectedPlugSecComp,
 		connectedPlugUDev:     rawusbConnectedPlugUDev,
-	})
+	}})
 }
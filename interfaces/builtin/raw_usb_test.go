@@ -28,6 +28,7 @@ import (
 	"github.com/snapcore/snapd/interfaces"
 	"github.com/snapcore/snapd/interfaces/apparmor"
 	"github.com/snapcore/snapd/interfaces/builtin"
+	"github.com/snapcore/snapd/interfaces/hotplug"
 	"github.com/snapcore/snapd/interfaces/seccomp"
 	"github.com/snapcore/snapd/interfaces/udev"
 	"github.com/snapcore/snapd/snap"
@@ -119,3 +120,47 @@ func (s *RawUsbInterfaceSuite) TestAutoConnect(c *C) {
 func (s *RawUsbInterfaceSuite) TestInterfaces(c *C) {
 	c.Check(builtin.Interfaces(), testutil.DeepContains, s.iface)
 }
+
+func (s *RawUsbInterfaceSuite) TestHotplugDeviceDetected(c *C) {
+	hotplugIface := s.iface.(hotplug.Definer)
+	di, err := hotplug.NewHotplugDeviceInfo(map[string]string{"DEVPATH": "/sys/foo/bar", "DEVNAME": "/dev/bus/usb/001/007", "DEVTYPE": "usb_device", "ID_VENDOR_ID": "05e0", "ID_MODEL_ID": "1200", "ID_USB_INTERFACES": ":030101:", "ACTION": "add", "SUBSYSTEM": "usb"})
+	c.Assert(err, IsNil)
+	proposedSlot, err := hotplugIface.HotplugDeviceDetected(di)
+	c.Assert(err, IsNil)
+	c.Assert(proposedSlot, DeepEquals, &hotplug.ProposedSlot{Attrs: map[string]interface{}{"path": "/dev/bus/usb/001/007", "usb-vendor": "05e0", "usb-product": "1200"}})
+
+	// hubs are ignored
+	di, err = hotplug.NewHotplugDeviceInfo(map[string]string{"DEVPATH": "/sys/foo/bar", "DEVNAME": "/dev/bus/usb/001/002", "DEVTYPE": "usb_device", "ID_USB_INTERFACES": ":090000:", "ACTION": "add", "SUBSYSTEM": "usb"})
+	c.Assert(err, IsNil)
+	proposedSlot, err = hotplugIface.HotplugDeviceDetected(di)
+	c.Assert(err, IsNil)
+	c.Assert(proposedSlot, IsNil)
+
+	// so are usb interfaces
+	di, err = hotplug.NewHotplugDeviceInfo(map[string]string{"DEVPATH": "/sys/foo/bar", "DEVTYPE": "usb_interface", "ACTION": "add", "SUBSYSTEM": "usb"})
+	c.Assert(err, IsNil)
+	proposedSlot, err = hotplugIface.HotplugDeviceDetected(di)
+	c.Assert(err, IsNil)
+	c.Assert(proposedSlot, IsNil)
+}
+
+func (s *RawUsbInterfaceSuite) TestHotplugSlot(c *C) {
+	slotInfo := MockHotplugSlot(c, rawusbCoreYaml, nil, "1234", "raw-usb", "raw-usb-1", map[string]interface{}{"path": "/dev/bus/usb/001/007"})
+	slotInfo.Interface = "raw-usb"
+	c.Assert(interfaces.BeforePrepareSlot(s.iface, slotInfo), IsNil)
+	slot := interfaces.NewConnectedSlot(slotInfo, nil, nil)
+
+	apparmorSpec := &apparmor.Specification{}
+	c.Assert(apparmorSpec.AddConnectedPlug(s.iface, s.plug, slot), IsNil)
+	c.Check(apparmorSpec.SnippetForTag("snap.consumer.app"), testutil.Contains, "/dev/bus/usb/001/007 rw,")
+	c.Check(apparmorSpec.SnippetForTag("snap.consumer.app"), Not(testutil.Contains), "/dev/bus/usb/[0-9][0-9][0-9]/[0-9][0-9][0-9] rw,")
+
+	udevSpec := &udev.Specification{}
+	c.Assert(udevSpec.AddConnectedPlug(s.iface, s.plug, slot), IsNil)
+	c.Assert(udevSpec.Snippets(), HasLen, 2)
+	c.Check(udevSpec.Snippets(), testutil.Contains, `# raw-usb
+SUBSYSTEM=="usb", ENV{DEVNAME}=="/dev/bus/usb/001/007", TAG+="snap_consumer_app"`)
+
+	slotInfo.Attrs["path"] = "/dev/sda"
+	c.Check(interfaces.BeforePrepareSlot(s.iface, slotInfo), ErrorMatches, `slot "core:raw-usb-1" path attribute must be a valid device node`)
+}
@@ -19,6 +19,18 @@
 
 package builtin
 
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/snapcore/snapd/interfaces"
+	"github.com/snapcore/snapd/interfaces/apparmor"
+	"github.com/snapcore/snapd/interfaces/hotplug"
+	"github.com/snapcore/snapd/interfaces/udev"
+	"github.com/snapcore/snapd/snap"
+)
+
 const removableMediaSummary = `allows access to mounted removable storage`
 
 const removableMediaBaseDeclarationSlots = `
@@ -49,13 +61,92 @@ const removableMediaConnectedPlugAppArmor = `
 /mnt/** rwkl,
 `
 
+// Pattern to match the whole disks for which slots are created when
+// they are hotplugged, the slots cover the partitions of the disk too.
+var removableMediaDeviceNodePattern = regexp.MustCompile("^/dev/(sd[a-z]{1,3}|mmcblk[0-9]{1,3})$")
+
+// removableMediaInterface grants access to mounted removable storage
+// through the implicit slot and raw access to a single disk and its
+// partitions through slots created for hotplugged USB storage devices,
+// which have a path attribute.
+type removableMediaInterface struct {
+	commonInterface
+}
+
+// removableMediaPartitions returns the glob matching the kernel names of
+// the partitions of the given disk, the kernel separates the partition
+// number with a "p" when the disk name ends with a digit.
+func removableMediaPartitions(disk string) string {
+	if last := disk[len(disk)-1]; last >= '0' && last <= '9' {
+		return disk + "p[0-9]*"
+	}
+	return disk + "[0-9]*"
+}
+
+func (iface *removableMediaInterface) BeforePrepareSlot(slot *snap.SlotInfo) error {
+	return verifyOptionalSlotPathAttribute(slot, removableMediaDeviceNodePattern)
+}
+
+func (iface *removableMediaInterface) AppArmorConnectedPlug(spec *apparmor.Specification, plug *interfaces.ConnectedPlug, slot *interfaces.ConnectedSlot) error {
+	path, ok := hotplugDevicePath(slot)
+	if !ok {
+		return iface.commonInterface.AppArmorConnectedPlug(spec, plug, slot)
+	}
+	spec.AddSnippet(fmt.Sprintf("# Raw access to a single removable storage device and its partitions\n%s rwk,\n%s rwk,", path, removableMediaPartitions(path)))
+	return nil
+}
+
+func (iface *removableMediaInterface) UDevConnectedPlug(spec *udev.Specification, plug *interfaces.ConnectedPlug, slot *interfaces.ConnectedSlot) error {
+	path, ok := hotplugDevicePath(slot)
+	if !ok {
+		return nil
+	}
+	disk := strings.TrimPrefix(path, "/dev/")
+	spec.TagDevice(fmt.Sprintf(`SUBSYSTEM=="block", KERNEL=="%s"`, disk))
+	spec.TagDevice(fmt.Sprintf(`SUBSYSTEM=="block", KERNEL=="%s"`, removableMediaPartitions(disk)))
+	return nil
+}
+
+func (iface *removableMediaInterface) HotplugDeviceDetected(di *hotplug.HotplugDeviceInfo) (*hotplug.ProposedSlot, error) {
+	// only storage attached through USB is considered removable, as
+	// internal disks and SD cards may hold the system itself
+	if bus, _ := di.Attribute("ID_BUS"); bus != "usb" {
+		return nil, nil
+	}
+	// the slot of the disk covers its partitions
+	if di.DeviceType() != "disk" {
+		return nil, nil
+	}
+	return hotplugDeviceNodeSlot(di, "block", removableMediaDeviceNodePattern), nil
+}
+
+func (iface *removableMediaInterface) HotplugKey(di *hotplug.HotplugDeviceInfo) (snap.HotplugKey, error) {
+	// the disk keeps its slot when plugged into another port as long
+	// as it has a serial, the partitions share the path and serial of
+	// the disk but must not have the key of its slot or removing one of
+	// them would remove the slot
+	path, _ := di.Attribute("ID_PATH")
+	serial, _ := di.Attribute("ID_SERIAL")
+	if path == "" && serial == "" {
+		return "", nil
+	}
+	if serial != "" {
+		return hotplugAttrsKey(di, []string{"ID_SERIAL", "DEVTYPE"}), nil
+	}
+	return hotplugAttrsKey(di, []string{"ID_PATH", "DEVTYPE"}), nil
+}
+
+func (iface *removableMediaInterface) HandledByGadget(di *hotplug.HotplugDeviceInfo, slot *snap.SlotInfo) bool {
+	return handledByGadgetPath(di, slot)
+}
+
 func init() {
-	registerIface(&commonInterface{
+	registerIface(&removableMediaInterface{commonInterface{
 		name:                  "removable-media",
 		summary:               removableMediaSummary,
 		implicitOnCore:        true,
 		implicitOnClassic:     true,
 		baseDeclarationSlots:  removableMediaBaseDeclarationSlots,
 		connectedPlugAppArmor: removableMediaConnectedPlugAppArmor,
-	})
+	}})
 }
@@ -25,6 +25,8 @@ import (
 	"github.com/snapcore/snapd/interfaces"
 	"github.com/snapcore/snapd/interfaces/apparmor"
 	"github.com/snapcore/snapd/interfaces/builtin"
+	"github.com/snapcore/snapd/interfaces/hotplug"
+	"github.com/snapcore/snapd/interfaces/udev"
 	"github.com/snapcore/snapd/snap"
 	"github.com/snapcore/snapd/snap/snaptest"
 	"github.com/snapcore/snapd/testutil"
@@ -86,3 +88,102 @@ func (s *RemovableMediaInterfaceSuite) TestUsedSecuritySystems(c *C) {
 func (s *RemovableMediaInterfaceSuite) TestInterfaces(c *C) {
 	c.Check(builtin.Interfaces(), testutil.DeepContains, s.iface)
 }
+
+func (s *RemovableMediaInterfaceSuite) TestHotplugDeviceDetected(c *C) {
+	hotplugIface := s.iface.(hotplug.Definer)
+	di, err := hotplug.NewHotplugDeviceInfo(map[string]string{"DEVPATH": "/sys/foo/bar", "DEVNAME": "/dev/sdb", "DEVTYPE": "disk", "ID_BUS": "usb", "ID_VENDOR_ID": "0781", "ID_MODEL_ID": "5567", "ACTION": "add", "SUBSYSTEM": "block"})
+	c.Assert(err, IsNil)
+	proposedSlot, err := hotplugIface.HotplugDeviceDetected(di)
+	c.Assert(err, IsNil)
+	c.Assert(proposedSlot, DeepEquals, &hotplug.ProposedSlot{Attrs: map[string]interface{}{"path": "/dev/sdb", "usb-vendor": "0781", "usb-product": "5567"}})
+
+	byGadgetPred := s.iface.(hotplug.HandledByGadgetPredicate)
+	s.slotInfo.Attrs = map[string]interface{}{"path": "/dev/sdb"}
+	c.Check(byGadgetPred.HandledByGadget(di, s.slotInfo), Equals, true)
+	s.slotInfo.Attrs = map[string]interface{}{"path": "/dev/sdc"}
+	c.Check(byGadgetPred.HandledByGadget(di, s.slotInfo), Equals, false)
+
+	// the partitions are covered by the slot of the disk
+	di, err = hotplug.NewHotplugDeviceInfo(map[string]string{"DEVPATH": "/sys/foo/bar1", "DEVNAME": "/dev/sdb1", "DEVTYPE": "partition", "ID_BUS": "usb", "ACTION": "add", "SUBSYSTEM": "block"})
+	c.Assert(err, IsNil)
+	proposedSlot, err = hotplugIface.HotplugDeviceDetected(di)
+	c.Assert(err, IsNil)
+	c.Assert(proposedSlot, IsNil)
+
+	// internal disks are ignored
+	di, err = hotplug.NewHotplugDeviceInfo(map[string]string{"DEVPATH": "/sys/foo/bar", "DEVNAME": "/dev/sda", "DEVTYPE": "disk", "ID_BUS": "ata", "ACTION": "add", "SUBSYSTEM": "block"})
+	c.Assert(err, IsNil)
+	proposedSlot, err = hotplugIface.HotplugDeviceDetected(di)
+	c.Assert(err, IsNil)
+	c.Assert(proposedSlot, IsNil)
+}
+
+func (s *RemovableMediaInterfaceSuite) TestHotplugKey(c *C) {
+	keyHandler := s.iface.(hotplug.HotplugKeyHandler)
+	attrs := map[string]string{"DEVPATH": "/sys/foo/bar", "DEVNAME": "/dev/sdb", "DEVTYPE": "disk", "ID_PATH": "pci-0000:00:14.0-usb-0:1:1.0-scsi-0:0:0:0", "ID_SERIAL": "Flash_Disk_1234", "SUBSYSTEM": "block"}
+	di, err := hotplug.NewHotplugDeviceInfo(attrs)
+	c.Assert(err, IsNil)
+	diskKey, err := keyHandler.HotplugKey(di)
+	c.Assert(err, IsNil)
+	c.Check(diskKey, Not(Equals), snap.HotplugKey(""))
+
+	// the disk keeps its key in another port
+	attrs["ID_PATH"] = "pci-0000:00:14.0-usb-0:2:1.0-scsi-0:0:0:0"
+	di, err = hotplug.NewHotplugDeviceInfo(attrs)
+	c.Assert(err, IsNil)
+	movedKey, err := keyHandler.HotplugKey(di)
+	c.Assert(err, IsNil)
+	c.Check(movedKey, Equals, diskKey)
+
+	// removing a partition does not remove the slot of the disk
+	attrs["DEVNAME"] = "/dev/sdb1"
+	attrs["DEVTYPE"] = "partition"
+	di, err = hotplug.NewHotplugDeviceInfo(attrs)
+	c.Assert(err, IsNil)
+	partKey, err := keyHandler.HotplugKey(di)
+	c.Assert(err, IsNil)
+	c.Check(partKey, Not(Equals), diskKey)
+
+	// without a serial the disk is told apart by its port
+	delete(attrs, "ID_SERIAL")
+	attrs["DEVNAME"] = "/dev/sdb"
+	attrs["DEVTYPE"] = "disk"
+	di, err = hotplug.NewHotplugDeviceInfo(attrs)
+	c.Assert(err, IsNil)
+	portKey, err := keyHandler.HotplugKey(di)
+	c.Assert(err, IsNil)
+	c.Check(portKey, Not(Equals), snap.HotplugKey(""))
+	c.Check(portKey, Not(Equals), diskKey)
+}
+
+func (s *RemovableMediaInterfaceSuite) TestHotplugSlot(c *C) {
+	s.slotInfo.Attrs = map[string]interface{}{"path": "/dev/sdb"}
+	c.Assert(interfaces.BeforePrepareSlot(s.iface, s.slotInfo), IsNil)
+	s.slot = interfaces.NewConnectedSlot(s.slotInfo, nil, nil)
+
+	apparmorSpec := &apparmor.Specification{}
+	c.Assert(apparmorSpec.AddConnectedPlug(s.iface, s.plug, s.slot), IsNil)
+	c.Check(apparmorSpec.SnippetForTag("snap.client-snap.other"), testutil.Contains, "/dev/sdb rwk,\n/dev/sdb[0-9]* rwk,")
+	c.Check(apparmorSpec.SnippetForTag("snap.client-snap.other"), Not(testutil.Contains), "/{,run/}media/*/** rwkl,")
+
+	udevSpec := &udev.Specification{}
+	c.Assert(udevSpec.AddConnectedPlug(s.iface, s.plug, s.slot), IsNil)
+	c.Check(udevSpec.Snippets(), testutil.Contains, `# removable-media
+SUBSYSTEM=="block", KERNEL=="sdb", TAG+="snap_client-snap_other"`)
+	c.Check(udevSpec.Snippets(), testutil.Contains, `# removable-media
+SUBSYSTEM=="block", KERNEL=="sdb[0-9]*", TAG+="snap_client-snap_other"`)
+
+	// the partitions of mmc disks have a separator
+	s.slotInfo.Attrs["path"] = "/dev/mmcblk1"
+	c.Assert(interfaces.BeforePrepareSlot(s.iface, s.slotInfo), IsNil)
+	s.slot = interfaces.NewConnectedSlot(s.slotInfo, nil, nil)
+	apparmorSpec = &apparmor.Specification{}
+	c.Assert(apparmorSpec.AddConnectedPlug(s.iface, s.plug, s.slot), IsNil)
+	c.Check(apparmorSpec.SnippetForTag("snap.client-snap.other"), testutil.Contains, "/dev/mmcblk1 rwk,\n/dev/mmcblk1p[0-9]* rwk,")
+
+	// slots are created for whole disks only
+	s.slotInfo.Attrs["path"] = "/dev/sdb1"
+	c.Check(interfaces.BeforePrepareSlot(s.iface, s.slotInfo), ErrorMatches, `slot "core:removable-media" path attribute must be a valid device node`)
+	s.slotInfo.Attrs["path"] = "/dev/nvme0n1"
+	c.Check(interfaces.BeforePrepareSlot(s.iface, s.slotInfo), ErrorMatches, `slot "core:removable-media" path attribute must be a valid device node`)
+}
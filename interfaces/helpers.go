@@ -21,14 +21,29 @@ package interfaces
 
 import (
 	"fmt"
+	"sync"
 
 	"github.com/snapcore/snapd/snap"
 	"github.com/snapcore/snapd/timings"
 )
 
+// serialSetupBackends are the backends whose Setup() must not run for
+// several snaps in parallel. The systemd and udev backends reload system
+// wide services which would only contend with each other.
+var serialSetupBackends = map[SecuritySystem]bool{
+	SecuritySystemd: true,
+	SecurityUDev:    true,
+}
+
 // SetupMany generates profiles of snaps using either SetupMany() method of the security backend (if implemented), or Setup(). All errors are logged.
 // The return value indicates if all profiles were successfully generated.
-func SetupMany(repo *Repository, backend SecurityBackend, snaps []*snap.Info, confinementOpts func(snapName string) ConfinementOptions, tm timings.Measurer) []error {
+//
+// When the backend does not implement SetupMany(), up to concurrency snaps
+// are set up in parallel. The profiles of distinct snaps are independent of
+// each other, so only the order between backends needs to be preserved by
+// the caller. Backends listed in serialSetupBackends always set up one snap
+// at a time.
+func SetupMany(repo *Repository, backend SecurityBackend, snaps []*snap.Info, confinementOpts func(snapName string) ConfinementOptions, concurrency int, tm timings.Measurer) []error {
 	var errors []error
 	// use .SetupMany() if implemented by the backend, otherwise fall back to .Setup()
 	if setupManyInterface, ok := backend.(SecurityBackendSetupMany); ok {
@@ -36,18 +51,53 @@ func SetupMany(repo *Repository, backend SecurityBackend, snaps []*snap.Info, co
 			errors = setupManyInterface.SetupMany(snaps, confinementOpts, repo, nesttm)
 		})
 	} else {
-		// For each snap:
-		for _, snapInfo := range snaps {
-			snapName := snapInfo.InstanceName()
-			// Compute confinement options
-			opts := confinementOpts(snapName)
-
-			// Refresh security of this snap and backend
-			timings.Run(tm, "setup-security-backend", fmt.Sprintf("setup security backend %q for snap %q", backend.Name(), snapInfo.InstanceName()), func(nesttm timings.Measurer) {
-				if err := backend.Setup(snapInfo, opts, repo, nesttm); err != nil {
-					errors = append(errors, err)
+		// Compute confinement options upfront, the callback is not
+		// expected to be safe for concurrent use
+		opts := make([]ConfinementOptions, len(snaps))
+		for i, snapInfo := range snaps {
+			opts[i] = confinementOpts(snapInfo.InstanceName())
+		}
+
+		if concurrency < 1 || serialSetupBackends[backend.Name()] {
+			concurrency = 1
+		}
+		if concurrency > len(snaps) {
+			concurrency = len(snaps)
+		}
+
+		// the measurer is not safe for concurrent use either, nested
+		// measurements are started under the lock
+		var tmLock sync.Mutex
+		errs := make([]error, len(snaps))
+		queue := make(chan int, len(snaps))
+		for i := range snaps {
+			queue <- i
+		}
+		close(queue)
+
+		var wg sync.WaitGroup
+		wg.Add(concurrency)
+		for w := 0; w < concurrency; w++ {
+			go func() {
+				defer wg.Done()
+				for i := range queue {
+					snapInfo := snaps[i]
+					// Refresh security of this snap and backend
+					tmLock.Lock()
+					nesttm := tm.StartSpan("setup-security-backend", fmt.Sprintf("setup security backend %q for snap %q", backend.Name(), snapInfo.InstanceName()))
+					tmLock.Unlock()
+					errs[i] = backend.Setup(snapInfo, opts[i], repo, nesttm)
+					nesttm.Stop()
 				}
-			})
+			}()
+		}
+		wg.Wait()
+
+		// report errors in the order of snaps
+		for _, err := range errs {
+			if err != nil {
+				errors = append(errors, err)
+			}
 		}
 	}
 	return errors
@@ -21,6 +21,8 @@ package interfaces_test
 
 import (
 	"fmt"
+	"sync"
+	"time"
 
 	. "gopkg.in/check.v1"
 
@@ -94,7 +96,7 @@ func (s *HelpersSuite) TestSetupManyRunsSetupManyIfImplemented(c *C) {
 		},
 	}
 
-	errs := interfaces.SetupMany(s.repo, backend, []*snap.Info{s.snap1, s.snap2}, confinementOpts, s.tm)
+	errs := interfaces.SetupMany(s.repo, backend, []*snap.Info{s.snap1, s.snap2}, confinementOpts, 1, s.tm)
 	c.Check(errs, HasLen, 0)
 	c.Check(setupManyCalls, Equals, 1)
 	c.Check(setupCalls, Equals, 0)
@@ -117,7 +119,7 @@ func (s *HelpersSuite) TestSetupManyRunsSetupIfSetupManyNotImplemented(c *C) {
 		return interfaces.ConfinementOptions{}
 	}
 
-	errs := interfaces.SetupMany(s.repo, backend, []*snap.Info{s.snap1, s.snap2}, confinementOpts, s.tm)
+	errs := interfaces.SetupMany(s.repo, backend, []*snap.Info{s.snap1, s.snap2}, confinementOpts, 1, s.tm)
 	c.Check(errs, HasLen, 0)
 	c.Check(setupCalls, Equals, 2)
 	c.Check(confinementOptsCalls, Equals, 2)
@@ -146,7 +148,7 @@ func (s *HelpersSuite) TestSetupManySetupManyNotOK(c *C) {
 		},
 	}
 
-	errs := interfaces.SetupMany(s.repo, backend, []*snap.Info{s.snap1, s.snap2}, confinementOpts, s.tm)
+	errs := interfaces.SetupMany(s.repo, backend, []*snap.Info{s.snap1, s.snap2}, confinementOpts, 1, s.tm)
 	c.Check(errs, HasLen, 2)
 	c.Check(setupManyCalls, Equals, 1)
 	c.Check(setupCalls, Equals, 0)
@@ -166,7 +168,98 @@ func (s *HelpersSuite) TestSetupManySetupNotOK(c *C) {
 		},
 	}
 
-	errs := interfaces.SetupMany(s.repo, backend, []*snap.Info{s.snap1, s.snap2}, confinementOpts, s.tm)
+	errs := interfaces.SetupMany(s.repo, backend, []*snap.Info{s.snap1, s.snap2}, confinementOpts, 1, s.tm)
 	c.Check(errs, HasLen, 2)
 	c.Check(setupCalls, Equals, 2)
 }
+
+type blockingSetupBackend struct {
+	ifacetest.TestSecurityBackend
+	started chan string
+	release chan struct{}
+}
+
+func (b *blockingSetupBackend) Setup(snapInfo *snap.Info, opts interfaces.ConfinementOptions, repo *interfaces.Repository, tm timings.Measurer) error {
+	b.started <- snapInfo.InstanceName()
+	<-b.release
+	return fmt.Errorf("error %s", snapInfo.InstanceName())
+}
+
+func (s *HelpersSuite) TestSetupManySetupConcurrently(c *C) {
+	confinementOpts := func(snapName string) interfaces.ConfinementOptions {
+		return interfaces.ConfinementOptions{}
+	}
+
+	backend := &blockingSetupBackend{
+		TestSecurityBackend: ifacetest.TestSecurityBackend{BackendName: "fake"},
+		started:             make(chan string, 2),
+		release:             make(chan struct{}),
+	}
+
+	done := make(chan []error)
+	go func() {
+		done <- interfaces.SetupMany(s.repo, backend, []*snap.Info{s.snap1, s.snap2}, confinementOpts, 4, s.tm)
+	}()
+
+	// both snaps are set up at the same time
+	started := make(map[string]bool)
+	for len(started) < 2 {
+		select {
+		case name := <-backend.started:
+			started[name] = true
+		case <-time.After(5 * time.Second):
+			c.Fatalf("snaps not set up concurrently, started: %v", started)
+		}
+	}
+	close(backend.release)
+
+	errs := <-done
+	// errors are reported in the order of snaps
+	c.Assert(errs, HasLen, 2)
+	c.Check(errs[0], ErrorMatches, "error some-snap")
+	c.Check(errs[1], ErrorMatches, "error other-snap")
+}
+
+type countingSetupBackend struct {
+	ifacetest.TestSecurityBackend
+	lock    sync.Mutex
+	running int
+	maxRun  int
+}
+
+func (b *countingSetupBackend) Setup(snapInfo *snap.Info, opts interfaces.ConfinementOptions, repo *interfaces.Repository, tm timings.Measurer) error {
+	b.lock.Lock()
+	b.running++
+	if b.running > b.maxRun {
+		b.maxRun = b.running
+	}
+	b.lock.Unlock()
+	time.Sleep(10 * time.Millisecond)
+	b.lock.Lock()
+	b.running--
+	b.lock.Unlock()
+	return nil
+}
+
+func (s *HelpersSuite) TestSetupManySerialBackends(c *C) {
+	confinementOpts := func(snapName string) interfaces.ConfinementOptions {
+		return interfaces.ConfinementOptions{}
+	}
+
+	for _, name := range []interfaces.SecuritySystem{interfaces.SecuritySystemd, interfaces.SecurityUDev} {
+		backend := &countingSetupBackend{
+			TestSecurityBackend: ifacetest.TestSecurityBackend{BackendName: name},
+		}
+		errs := interfaces.SetupMany(s.repo, backend, []*snap.Info{s.snap1, s.snap2}, confinementOpts, 4, s.tm)
+		c.Check(errs, HasLen, 0)
+		c.Check(backend.maxRun, Equals, 1, Commentf("backend %s", name))
+	}
+
+	// the seccomp backend is safe for concurrent use
+	backend := &countingSetupBackend{
+		TestSecurityBackend: ifacetest.TestSecurityBackend{BackendName: interfaces.SecuritySecComp},
+	}
+	errs := interfaces.SetupMany(s.repo, backend, []*snap.Info{s.snap1, s.snap2}, confinementOpts, 4, s.tm)
+	c.Check(errs, HasLen, 0)
+	c.Check(backend.maxRun, Equals, 2)
+}
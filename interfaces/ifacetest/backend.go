@@ -20,6 +20,8 @@
 package ifacetest
 
 import (
+	"sync"
+
 	"github.com/snapcore/snapd/interfaces"
 	"github.com/snapcore/snapd/snap"
 	"github.com/snapcore/snapd/timings"
@@ -38,6 +40,10 @@ type TestSecurityBackend struct {
 	RemoveCallback func(snapName string) error
 	// SandboxFeaturesCallback is a callback that is optionally called in SandboxFeatures
 	SandboxFeaturesCallback func() []string
+
+	// setupLock serializes calls to Setup, which may be made
+	// concurrently for distinct snaps
+	setupLock sync.Mutex
 }
 
 // TestSetupCall stores details about calls to TestSecurityBackend.Setup
@@ -60,6 +66,8 @@ func (b *TestSecurityBackend) Name() interfaces.SecuritySystem {
 
 // Setup records information about the call and calls the setup callback if one is defined.
 func (b *TestSecurityBackend) Setup(snapInfo *snap.Info, opts interfaces.ConfinementOptions, repo *interfaces.Repository, tm timings.Measurer) error {
+	b.setupLock.Lock()
+	defer b.setupLock.Unlock()
 	b.SetupCalls = append(b.SetupCalls, TestSetupCall{SnapInfo: snapInfo, Options: opts})
 	if b.SetupCallback == nil {
 		return nil
@@ -645,6 +645,7 @@ var (
 		"x11":                       {"app", "core"},
 		// snowflakes
 		"classic-support": nil,
+		"custom-device":   nil,
 		"docker":          nil,
 		"lxd":             nil,
 	}
@@ -698,6 +699,13 @@ func (s *baseDeclSuite) TestSlotInstallation(c *C) {
 	err = ic.Check()
 	c.Assert(err, Not(IsNil))
 	c.Assert(err, ErrorMatches, "installation not allowed by \"lxd\" slot rule of interface \"lxd\"")
+
+	// test custom-device specially
+	for _, snapType := range snapTypeMap {
+		ic = s.installSlotCand(c, "custom-device", snapType, ``)
+		err = ic.Check()
+		c.Assert(err, ErrorMatches, "installation not allowed by \"custom-device\" slot rule of interface \"custom-device\"")
+	}
 }
 
 func (s *baseDeclSuite) TestPlugInstallation(c *C) {
@@ -762,6 +770,7 @@ func (s *baseDeclSuite) TestConnection(c *C) {
 	noconnect := map[string]bool{
 		"content":          true,
 		"cups":             true,
+		"custom-device":    true,
 		"docker":           true,
 		"fwupd":            true,
 		"location-control": true,
@@ -1228,3 +1237,26 @@ plugs:
 	_, err = cand.CheckAutoConnect()
 	c.Check(err, IsNil)
 }
+
+func (s *baseDeclSuite) TestCustomDeviceConnection(c *C) {
+	slotYaml := `name: slot-snap
+type: gadget
+version: 0
+slots:
+  custom-device:
+    custom-device: fpga
+`
+	plugYaml := `name: plug-snap
+version: 0
+plugs:
+  custom-device:
+    custom-device: %s
+`
+	cand := s.connectCand(c, "custom-device", slotYaml, fmt.Sprintf(plugYaml, "fpga"))
+	c.Check(cand.Check(), IsNil)
+	_, err := cand.CheckAutoConnect()
+	c.Check(err, ErrorMatches, "auto-connection denied by slot rule of interface \"custom-device\"")
+
+	cand = s.connectCand(c, "custom-device", slotYaml, fmt.Sprintf(plugYaml, "sensor"))
+	c.Check(cand.Check(), ErrorMatches, "connection not allowed by slot rule of interface \"custom-device\"")
+}
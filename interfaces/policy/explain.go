@@ -0,0 +1,152 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package policy
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/snapcore/snapd/asserts"
+)
+
+// An Explanation tells which part of a declaration rule decided a
+// policy check.
+type Explanation struct {
+	// Rule describes the declaration rule that decided the check.
+	Rule string
+	// Section is the deny-* or allow-* section of the rule that decided
+	// the check, e.g. "allow-auto-connection".
+	Section string
+	// Alternative is the 1-based index of the alternative of the
+	// section that matched, or 0 if none did.
+	Alternative int
+	// Constraints lists the constraints of the matching alternative,
+	// it is empty if the alternative matches unconditionally.
+	Constraints []string
+	// Mismatches gives for each alternative of the section the
+	// constraint it failed, when none of them matched.
+	Mismatches []string
+}
+
+func (e *Explanation) String() string {
+	if e.Alternative == 0 {
+		return fmt.Sprintf("no alternative of %s in %s matched: %s", e.Section, e.Rule, strings.Join(e.Mismatches, "; "))
+	}
+	if len(e.Constraints) == 0 {
+		return fmt.Sprintf("alternative %d of %s in %s matched unconditionally", e.Alternative, e.Section, e.Rule)
+	}
+	return fmt.Sprintf("alternative %d of %s in %s matched on %s", e.Alternative, e.Section, e.Rule, strings.Join(e.Constraints, ", "))
+}
+
+// ruleSection gives access to the alternative constraints of a section
+// of a rule.
+type ruleSection struct {
+	name        string
+	n           int
+	check       func(i int) error
+	constraints func(i int) []string
+}
+
+// explain evaluates the sections of a rule like the checks do: the
+// check is denied if an alternative of the deny section matches and
+// otherwise allowed if one of the allow section does.
+func explain(rule string, deny, allow *ruleSection) *Explanation {
+	for i := 0; i < deny.n; i++ {
+		if deny.check(i) == nil {
+			return &Explanation{Rule: rule, Section: deny.name, Alternative: i + 1, Constraints: deny.constraints(i)}
+		}
+	}
+	e := &Explanation{Rule: rule, Section: allow.name}
+	for i := 0; i < allow.n; i++ {
+		err := allow.check(i)
+		if err == nil {
+			return &Explanation{Rule: rule, Section: allow.name, Alternative: i + 1, Constraints: allow.constraints(i)}
+		}
+		e.Mismatches = append(e.Mismatches, fmt.Sprintf("alternative %d: %v", i+1, err))
+	}
+	return e
+}
+
+// constraintNames collects the names of the constraints used by an
+// alternative.
+type constraintNames []string
+
+func (names *constraintNames) add(name string, used bool) {
+	if used {
+		*names = append(*names, name)
+	}
+}
+
+func (names *constraintNames) addAttributes(name string, c *asserts.AttributeConstraints) {
+	names.add(name, c != nil && c != asserts.AlwaysMatchAttributes)
+}
+
+func (names *constraintNames) addScope(onClassic *asserts.OnClassicConstraint, scope *asserts.DeviceScopeConstraint) {
+	names.add("on-classic", onClassic != nil)
+	if scope != nil {
+		names.add("on-store", len(scope.Store) != 0)
+		names.add("on-brand", len(scope.Brand) != 0)
+		names.add("on-model", len(scope.Model) != 0)
+	}
+}
+
+func plugInstallationConstraintNames(c *asserts.PlugInstallationConstraints) []string {
+	var names constraintNames
+	names.add("plug-snap-type", len(c.PlugSnapTypes) != 0)
+	names.add("plug-names", c.PlugNames != nil)
+	names.addAttributes("plug-attributes", c.PlugAttributes)
+	names.addScope(c.OnClassic, c.DeviceScope)
+	return names
+}
+
+func slotInstallationConstraintNames(c *asserts.SlotInstallationConstraints) []string {
+	var names constraintNames
+	names.add("slot-snap-type", len(c.SlotSnapTypes) != 0)
+	names.add("slot-names", c.SlotNames != nil)
+	names.addAttributes("slot-attributes", c.SlotAttributes)
+	names.addScope(c.OnClassic, c.DeviceScope)
+	return names
+}
+
+func plugConnectionConstraintNames(c *asserts.PlugConnectionConstraints) []string {
+	var names constraintNames
+	names.add("slot-snap-type", len(c.SlotSnapTypes) != 0)
+	names.add("slot-snap-id", len(c.SlotSnapIDs) != 0)
+	names.add("slot-publisher-id", len(c.SlotPublisherIDs) != 0)
+	names.add("plug-names", c.PlugNames != nil)
+	names.add("slot-names", c.SlotNames != nil)
+	names.addAttributes("plug-attributes", c.PlugAttributes)
+	names.addAttributes("slot-attributes", c.SlotAttributes)
+	names.addScope(c.OnClassic, c.DeviceScope)
+	return names
+}
+
+func slotConnectionConstraintNames(c *asserts.SlotConnectionConstraints) []string {
+	var names constraintNames
+	names.add("plug-snap-type", len(c.PlugSnapTypes) != 0)
+	names.add("plug-snap-id", len(c.PlugSnapIDs) != 0)
+	names.add("plug-publisher-id", len(c.PlugPublisherIDs) != 0)
+	names.add("plug-names", c.PlugNames != nil)
+	names.add("slot-names", c.SlotNames != nil)
+	names.addAttributes("plug-attributes", c.PlugAttributes)
+	names.addAttributes("slot-attributes", c.SlotAttributes)
+	names.addScope(c.OnClassic, c.DeviceScope)
+	return names
+}
@@ -69,32 +69,101 @@ func (ic *InstallCandidate) checkPlugRule(plug *snap.PlugInfo, rule *asserts.Plu
 	return nil
 }
 
-func (ic *InstallCandidate) checkSlot(slot *snap.SlotInfo) error {
+func (ic *InstallCandidate) slotRule(slot *snap.SlotInfo) (rule *asserts.SlotRule, snapRule bool) {
 	iface := slot.Interface
 	if snapDecl := ic.SnapDeclaration; snapDecl != nil {
 		if rule := snapDecl.SlotRule(iface); rule != nil {
-			return ic.checkSlotRule(slot, rule, true)
+			return rule, true
 		}
 	}
-	if rule := ic.BaseDeclaration.SlotRule(iface); rule != nil {
-		return ic.checkSlotRule(slot, rule, false)
-	}
-	return nil
+	return ic.BaseDeclaration.SlotRule(iface), false
 }
 
-func (ic *InstallCandidate) checkPlug(plug *snap.PlugInfo) error {
+func (ic *InstallCandidate) plugRule(plug *snap.PlugInfo) (rule *asserts.PlugRule, snapRule bool) {
 	iface := plug.Interface
 	if snapDecl := ic.SnapDeclaration; snapDecl != nil {
 		if rule := snapDecl.PlugRule(iface); rule != nil {
-			return ic.checkPlugRule(plug, rule, true)
+			return rule, true
 		}
 	}
-	if rule := ic.BaseDeclaration.PlugRule(iface); rule != nil {
-		return ic.checkPlugRule(plug, rule, false)
+	return ic.BaseDeclaration.PlugRule(iface), false
+}
+
+func (ic *InstallCandidate) checkSlot(slot *snap.SlotInfo) error {
+	if rule, snapRule := ic.slotRule(slot); rule != nil {
+		return ic.checkSlotRule(slot, rule, snapRule)
 	}
 	return nil
 }
 
+func (ic *InstallCandidate) checkPlug(plug *snap.PlugInfo) error {
+	if rule, snapRule := ic.plugRule(plug); rule != nil {
+		return ic.checkPlugRule(plug, rule, snapRule)
+	}
+	return nil
+}
+
+// CheckSlot checks whether the installation of the given slot of the
+// snap is allowed.
+func (ic *InstallCandidate) CheckSlot(slot *snap.SlotInfo) error {
+	if ic.BaseDeclaration == nil {
+		return fmt.Errorf("internal error: improperly initialized InstallCandidate")
+	}
+	return ic.checkSlot(slot)
+}
+
+// CheckPlug checks whether the installation of the given plug of the
+// snap is allowed.
+func (ic *InstallCandidate) CheckPlug(plug *snap.PlugInfo) error {
+	if ic.BaseDeclaration == nil {
+		return fmt.Errorf("internal error: improperly initialized InstallCandidate")
+	}
+	return ic.checkPlug(plug)
+}
+
+// ExplainSlot tells which part of the rule deciding the installation of
+// the given slot decided it, or returns nil if there is no such rule.
+func (ic *InstallCandidate) ExplainSlot(slot *snap.SlotInfo) *Explanation {
+	rule, snapRule := ic.slotRule(slot)
+	if rule == nil {
+		return nil
+	}
+	check := func(alts []*asserts.SlotInstallationConstraints) func(int) error {
+		return func(i int) error { return checkSlotInstallationConstraints1(ic, slot, alts[i]) }
+	}
+	names := func(alts []*asserts.SlotInstallationConstraints) func(int) []string {
+		return func(i int) []string { return slotInstallationConstraintNames(alts[i]) }
+	}
+	return explain(ruleSource("slot", slot.Interface, ic.SnapDeclaration, snapRule),
+		&ruleSection{"deny-installation", len(rule.DenyInstallation), check(rule.DenyInstallation), names(rule.DenyInstallation)},
+		&ruleSection{"allow-installation", len(rule.AllowInstallation), check(rule.AllowInstallation), names(rule.AllowInstallation)})
+}
+
+// ExplainPlug tells which part of the rule deciding the installation of
+// the given plug decided it, or returns nil if there is no such rule.
+func (ic *InstallCandidate) ExplainPlug(plug *snap.PlugInfo) *Explanation {
+	rule, snapRule := ic.plugRule(plug)
+	if rule == nil {
+		return nil
+	}
+	check := func(alts []*asserts.PlugInstallationConstraints) func(int) error {
+		return func(i int) error { return checkPlugInstallationConstraints1(ic, plug, alts[i]) }
+	}
+	names := func(alts []*asserts.PlugInstallationConstraints) func(int) []string {
+		return func(i int) []string { return plugInstallationConstraintNames(alts[i]) }
+	}
+	return explain(ruleSource("plug", plug.Interface, ic.SnapDeclaration, snapRule),
+		&ruleSection{"deny-installation", len(rule.DenyInstallation), check(rule.DenyInstallation), names(rule.DenyInstallation)},
+		&ruleSection{"allow-installation", len(rule.AllowInstallation), check(rule.AllowInstallation), names(rule.AllowInstallation)})
+}
+
+func ruleSource(side, iface string, snapDecl *asserts.SnapDeclaration, snapRule bool) string {
+	if snapRule {
+		return fmt.Sprintf("%s rule of interface %q for %q snap", side, iface, snapDecl.SnapName())
+	}
+	return fmt.Sprintf("%s rule of interface %q in the base declaration", side, iface)
+}
+
 // Check checks whether the installation is allowed.
 func (ic *InstallCandidate) Check() error {
 	if ic.BaseDeclaration == nil {
@@ -232,23 +301,92 @@ func (connc *ConnectCandidate) check(kind string) (interfaces.SideArity, error)
 		return nil, fmt.Errorf("cannot connect mismatched plug interface %q to slot interface %q", iface, connc.Slot.Interface())
 	}
 
+	plugRule, slotRule, snapRule := connc.rule()
+	if plugRule != nil {
+		return connc.checkPlugRule(kind, plugRule, snapRule)
+	}
+	if slotRule != nil {
+		return connc.checkSlotRule(kind, slotRule, snapRule)
+	}
+	return nil, nil
+}
+
+// rule returns the plug or slot rule deciding the connection, giving
+// precedence to the rules of the snap declarations.
+func (connc *ConnectCandidate) rule() (plugRule *asserts.PlugRule, slotRule *asserts.SlotRule, snapRule bool) {
+	iface := connc.Plug.Interface()
 	if plugDecl := connc.PlugSnapDeclaration; plugDecl != nil {
 		if rule := plugDecl.PlugRule(iface); rule != nil {
-			return connc.checkPlugRule(kind, rule, true)
+			return rule, nil, true
 		}
 	}
 	if slotDecl := connc.SlotSnapDeclaration; slotDecl != nil {
 		if rule := slotDecl.SlotRule(iface); rule != nil {
-			return connc.checkSlotRule(kind, rule, true)
+			return nil, rule, true
 		}
 	}
-	if rule := baseDecl.PlugRule(iface); rule != nil {
-		return connc.checkPlugRule(kind, rule, false)
+	if rule := connc.BaseDeclaration.PlugRule(iface); rule != nil {
+		return rule, nil, false
 	}
-	if rule := baseDecl.SlotRule(iface); rule != nil {
-		return connc.checkSlotRule(kind, rule, false)
+	return nil, connc.BaseDeclaration.SlotRule(iface), false
+}
+
+// explain tells which part of the rule deciding the connection or
+// auto-connection, depending on kind, decided it.
+func (connc *ConnectCandidate) explain(kind string) *Explanation {
+	if connc.BaseDeclaration == nil {
+		return nil
 	}
-	return nil, nil
+	denySection, allowSection := "deny-connection", "allow-connection"
+	if kind == "auto-connection" {
+		denySection, allowSection = "deny-auto-connection", "allow-auto-connection"
+	}
+	plugRule, slotRule, snapRule := connc.rule()
+	switch {
+	case plugRule != nil:
+		deny, allow := plugRule.DenyConnection, plugRule.AllowConnection
+		if kind == "auto-connection" {
+			deny, allow = plugRule.DenyAutoConnection, plugRule.AllowAutoConnection
+		}
+		check := func(alts []*asserts.PlugConnectionConstraints) func(int) error {
+			return func(i int) error { return checkPlugConnectionConstraints1(connc, alts[i]) }
+		}
+		names := func(alts []*asserts.PlugConnectionConstraints) func(int) []string {
+			return func(i int) []string { return plugConnectionConstraintNames(alts[i]) }
+		}
+		return explain(ruleSource("plug", connc.Plug.Interface(), connc.PlugSnapDeclaration, snapRule),
+			&ruleSection{denySection, len(deny), check(deny), names(deny)},
+			&ruleSection{allowSection, len(allow), check(allow), names(allow)})
+	case slotRule != nil:
+		deny, allow := slotRule.DenyConnection, slotRule.AllowConnection
+		if kind == "auto-connection" {
+			deny, allow = slotRule.DenyAutoConnection, slotRule.AllowAutoConnection
+		}
+		check := func(alts []*asserts.SlotConnectionConstraints) func(int) error {
+			return func(i int) error { return checkSlotConnectionConstraints1(connc, alts[i]) }
+		}
+		names := func(alts []*asserts.SlotConnectionConstraints) func(int) []string {
+			return func(i int) []string { return slotConnectionConstraintNames(alts[i]) }
+		}
+		return explain(ruleSource("slot", connc.Plug.Interface(), connc.SlotSnapDeclaration, snapRule),
+			&ruleSection{denySection, len(deny), check(deny), names(deny)},
+			&ruleSection{allowSection, len(allow), check(allow), names(allow)})
+	}
+	return nil
+}
+
+// ExplainConnection tells which part of the rule deciding whether the
+// connection is allowed decided it, or returns nil if there is no such
+// rule.
+func (connc *ConnectCandidate) ExplainConnection() *Explanation {
+	return connc.explain("connection")
+}
+
+// ExplainAutoConnection tells which part of the rule deciding whether
+// the auto-connection is allowed decided it, or returns nil if there is
+// no such rule.
+func (connc *ConnectCandidate) ExplainAutoConnection() *Explanation {
+	return connc.explain("auto-connection")
 }
 
 // Check checks whether the connection is allowed.
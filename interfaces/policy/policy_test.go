@@ -1048,6 +1048,39 @@ func (s *policySuite) TestSnapDeclAllowDenyConnection(c *C) {
 	}
 }
 
+func (s *policySuite) TestExplainConnection(c *C) {
+	tests := []struct {
+		iface       string
+		explanation string
+	}{
+		{"random", ""},
+		{"base-plug-allow", `alternative 1 of allow-connection in plug rule of interface "base-plug-allow" in the base declaration matched unconditionally`},
+		{"base-slot-deny", `alternative 1 of deny-connection in slot rule of interface "base-slot-deny" in the base declaration matched unconditionally`},
+		{"snap-plug-deny", `alternative 1 of deny-connection in plug rule of interface "snap-plug-deny" for "plug-snap" snap matched unconditionally`},
+		{"snap-slot-allow", `alternative 1 of allow-connection in slot rule of interface "snap-slot-allow" for "slot-snap" snap matched unconditionally`},
+		{"base-deny-snap-slot-allow", `alternative 1 of allow-connection in slot rule of interface "base-deny-snap-slot-allow" for "slot-snap" snap matched unconditionally`},
+		{"snap-slot-deny-snap-plug-allow", `alternative 1 of allow-connection in plug rule of interface "snap-slot-deny-snap-plug-allow" for "plug-snap" snap matched unconditionally`},
+		{"base-plug-not-allow-slots", `no alternative of allow-connection in plug rule of interface "base-plug-not-allow-slots" in the base declaration matched: alternative 1: attribute "s" has constraints but is unset`},
+	}
+
+	for _, t := range tests {
+		cand := policy.ConnectCandidate{
+			Plug:                interfaces.NewConnectedPlug(s.plugSnap.Plugs[t.iface], nil, nil),
+			Slot:                interfaces.NewConnectedSlot(s.slotSnap.Slots[t.iface], nil, nil),
+			PlugSnapDeclaration: s.plugDecl,
+			SlotSnapDeclaration: s.slotDecl,
+			BaseDeclaration:     s.baseDecl,
+		}
+		explanation := cand.ExplainConnection()
+		if t.explanation == "" {
+			c.Check(explanation, IsNil, Commentf(t.iface))
+			continue
+		}
+		c.Assert(explanation, NotNil, Commentf(t.iface))
+		c.Check(explanation.String(), Equals, t.explanation, Commentf(t.iface))
+	}
+}
+
 func (s *policySuite) TestSnapDeclAllowDenyAutoConnection(c *C) {
 	tests := []struct {
 		iface    string
@@ -1601,6 +1634,71 @@ AXNpZw==`, "@plugsSlots@", strings.TrimSpace(t.plugsSlots), 1)))
 	}
 }
 
+func (s *policySuite) TestExplainInstallation(c *C) {
+	installSnap := snaptest.MockInfo(c, `name: install-snap
+version: 0
+plugs:
+  install-plug-base-deny-snap-allow:
+    attr: attrvalue
+  install-plug-or:
+    p: P1
+  random:
+slots:
+  install-slot-coreonly:
+`, nil)
+	a, err := asserts.Decode([]byte(`type: snap-declaration
+authority-id: canonical
+series: 16
+snap-name: install-snap
+snap-id: installsnap6idididididididididid
+publisher-id: publisher
+plugs:
+  install-plug-base-deny-snap-allow:
+    allow-installation:
+      plug-attributes:
+        attr: attrvalue
+timestamp: 2016-09-30T12:00:00Z
+sign-key-sha3-384: Jv8_JiHiIzJVcO9M55pPdqSDWUvuhfDIBJUS-3VW7F_idjix7Ffn5qMxB21ZQuij
+
+AXNpZw==`))
+	c.Assert(err, IsNil)
+
+	cand := policy.InstallCandidate{
+		Snap:            installSnap,
+		SnapDeclaration: a.(*asserts.SnapDeclaration),
+		BaseDeclaration: s.baseDecl,
+	}
+
+	plug := installSnap.Plugs["install-plug-base-deny-snap-allow"]
+	c.Check(cand.CheckPlug(plug), IsNil)
+	c.Check(cand.ExplainPlug(plug), DeepEquals, &policy.Explanation{
+		Rule:        `plug rule of interface "install-plug-base-deny-snap-allow" for "install-snap" snap`,
+		Section:     "allow-installation",
+		Alternative: 1,
+		Constraints: []string{"plug-attributes"},
+	})
+
+	plug = installSnap.Plugs["install-plug-or"]
+	c.Check(cand.CheckPlug(plug), NotNil)
+	c.Check(cand.ExplainPlug(plug).String(), Equals, `alternative 1 of deny-installation in plug rule of interface "install-plug-or" in the base declaration matched on plug-attributes`)
+
+	plug = installSnap.Plugs["random"]
+	c.Check(cand.CheckPlug(plug), IsNil)
+	c.Check(cand.ExplainPlug(plug), IsNil)
+
+	slot := installSnap.Slots["install-slot-coreonly"]
+	c.Check(cand.CheckSlot(slot), ErrorMatches, `installation not allowed by "install-slot-coreonly" slot rule of interface "install-slot-coreonly"`)
+	c.Check(cand.ExplainSlot(slot), DeepEquals, &policy.Explanation{
+		Rule:       `slot rule of interface "install-slot-coreonly" in the base declaration`,
+		Section:    "allow-installation",
+		Mismatches: []string{"alternative 1: snap type does not match"},
+	})
+
+	cand.BaseDeclaration = nil
+	c.Check(cand.CheckPlug(plug), ErrorMatches, "internal error: improperly initialized InstallCandidate")
+	c.Check(cand.CheckSlot(slot), ErrorMatches, "internal error: improperly initialized InstallCandidate")
+}
+
 func (s *policySuite) TestBaseDeclAllowDenyInstallationMinimalCheck(c *C) {
 	tests := []struct {
 		installYaml string
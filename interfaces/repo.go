@@ -911,20 +911,23 @@ func (r *Repository) Interfaces() *Interfaces {
 	return ifaces
 }
 
+func (r *Repository) backend(securitySystem SecuritySystem, snapName string) (SecurityBackend, error) {
+	for _, b := range r.backends {
+		if b.Name() == securitySystem {
+			return b, nil
+		}
+	}
+	return nil, fmt.Errorf("cannot handle interfaces of snap %q, security system %q is not known", snapName, securitySystem)
+}
+
 // SnapSpecification returns the specification of a given snap in a given security system.
 func (r *Repository) SnapSpecification(securitySystem SecuritySystem, snapName string) (Specification, error) {
 	r.m.Lock()
 	defer r.m.Unlock()
 
-	var backend SecurityBackend
-	for _, b := range r.backends {
-		if b.Name() == securitySystem {
-			backend = b
-			break
-		}
-	}
-	if backend == nil {
-		return nil, fmt.Errorf("cannot handle interfaces of snap %q, security system %q is not known", snapName, securitySystem)
+	backend, err := r.backend(securitySystem, snapName)
+	if err != nil {
+		return nil, err
 	}
 
 	spec := backend.NewSpecification()
@@ -956,6 +959,86 @@ func (r *Repository) SnapSpecification(securitySystem SecuritySystem, snapName s
 	return spec, nil
 }
 
+// SpecificationSource is the part of the specification of a snap
+// contributed by one of its plugs or slots, either on its own or as
+// part of a connection, in which case both Plug and Slot are set.
+type SpecificationSource struct {
+	Interface string
+	Plug      *PlugRef
+	Slot      *SlotRef
+	Spec      Specification
+}
+
+// SnapSpecificationSources returns the specification of a given snap in a
+// given security system split by the plugs, slots and connections that
+// contributed to it. Merging all of them gives the specification
+// returned by SnapSpecification.
+func (r *Repository) SnapSpecificationSources(securitySystem SecuritySystem, snapName string) ([]*SpecificationSource, error) {
+	r.m.Lock()
+	defer r.m.Unlock()
+
+	backend, err := r.backend(securitySystem, snapName)
+	if err != nil {
+		return nil, err
+	}
+
+	var sources []*SpecificationSource
+	newSource := func(iface Interface, plugRef *PlugRef, slotRef *SlotRef) *SpecificationSource {
+		source := &SpecificationSource{
+			Interface: iface.Name(),
+			Plug:      plugRef,
+			Slot:      slotRef,
+			Spec:      backend.NewSpecification(),
+		}
+		sources = append(sources, source)
+		return source
+	}
+
+	// slot side
+	for _, slotName := range sortedSlotNames(r.slots[snapName]) {
+		slotInfo := r.slots[snapName][slotName]
+		iface := r.ifaces[slotInfo.Interface]
+		slotRef := &SlotRef{Snap: snapName, Name: slotName}
+		if err := newSource(iface, nil, slotRef).Spec.AddPermanentSlot(iface, slotInfo); err != nil {
+			return nil, err
+		}
+		plugs := make([]*snap.PlugInfo, 0, len(r.slotPlugs[slotInfo]))
+		for plugInfo := range r.slotPlugs[slotInfo] {
+			plugs = append(plugs, plugInfo)
+		}
+		sort.Sort(byPlugSnapAndName(plugs))
+		for _, plugInfo := range plugs {
+			conn := r.slotPlugs[slotInfo][plugInfo]
+			plugRef := &PlugRef{Snap: plugInfo.Snap.InstanceName(), Name: plugInfo.Name}
+			if err := newSource(iface, plugRef, slotRef).Spec.AddConnectedSlot(iface, conn.Plug, conn.Slot); err != nil {
+				return nil, err
+			}
+		}
+	}
+	// plug side
+	for _, plugName := range sortedPlugNames(r.plugs[snapName]) {
+		plugInfo := r.plugs[snapName][plugName]
+		iface := r.ifaces[plugInfo.Interface]
+		plugRef := &PlugRef{Snap: snapName, Name: plugName}
+		if err := newSource(iface, plugRef, nil).Spec.AddPermanentPlug(iface, plugInfo); err != nil {
+			return nil, err
+		}
+		slots := make([]*snap.SlotInfo, 0, len(r.plugSlots[plugInfo]))
+		for slotInfo := range r.plugSlots[plugInfo] {
+			slots = append(slots, slotInfo)
+		}
+		sort.Sort(bySlotSnapAndName(slots))
+		for _, slotInfo := range slots {
+			conn := r.plugSlots[plugInfo][slotInfo]
+			slotRef := &SlotRef{Snap: slotInfo.Snap.InstanceName(), Name: slotInfo.Name}
+			if err := newSource(iface, plugRef, slotRef).Spec.AddConnectedPlug(iface, conn.Plug, conn.Slot); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return sources, nil
+}
+
 // AddSnap adds plugs and slots declared by the given snap to the repository.
 //
 // This function can be used to implement snap install or, when used along with
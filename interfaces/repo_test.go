@@ -1321,6 +1321,42 @@ func (s *RepositorySuite) TestSnapSpecificationFailureWithPermanentSnippets(c *C
 	c.Assert(spec, IsNil)
 }
 
+func (s *RepositorySuite) TestSnapSpecificationSources(c *C) {
+	repo := s.emptyRepo
+	backend := &ifacetest.TestSecurityBackend{BackendName: testSecurity}
+	c.Assert(repo.AddBackend(backend), IsNil)
+	c.Assert(repo.AddInterface(testInterface), IsNil)
+	c.Assert(repo.AddPlug(s.plug), IsNil)
+	c.Assert(repo.AddSlot(s.slot), IsNil)
+	connRef := NewConnRef(s.plug, s.slot)
+	_, err := repo.Connect(connRef, nil, nil, nil, nil, nil)
+	c.Assert(err, IsNil)
+
+	sources, err := repo.SnapSpecificationSources(testSecurity, s.plug.Snap.InstanceName())
+	c.Assert(err, IsNil)
+	c.Assert(sources, HasLen, 2)
+	c.Check(sources[0].Interface, Equals, "interface")
+	c.Check(sources[0].Plug, DeepEquals, &connRef.PlugRef)
+	c.Check(sources[0].Slot, IsNil)
+	c.Check(sources[0].Spec.(*ifacetest.Specification).Snippets, DeepEquals, []string{"static plug snippet"})
+	c.Check(sources[1].Plug, DeepEquals, &connRef.PlugRef)
+	c.Check(sources[1].Slot, DeepEquals, &connRef.SlotRef)
+	c.Check(sources[1].Spec.(*ifacetest.Specification).Snippets, DeepEquals, []string{"connection-specific plug snippet"})
+
+	sources, err = repo.SnapSpecificationSources(testSecurity, s.slot.Snap.InstanceName())
+	c.Assert(err, IsNil)
+	c.Assert(sources, HasLen, 2)
+	c.Check(sources[0].Plug, IsNil)
+	c.Check(sources[0].Slot, DeepEquals, &connRef.SlotRef)
+	c.Check(sources[0].Spec.(*ifacetest.Specification).Snippets, DeepEquals, []string{"static slot snippet"})
+	c.Check(sources[1].Plug, DeepEquals, &connRef.PlugRef)
+	c.Check(sources[1].Slot, DeepEquals, &connRef.SlotRef)
+	c.Check(sources[1].Spec.(*ifacetest.Specification).Snippets, DeepEquals, []string{"connection-specific slot snippet"})
+
+	_, err = repo.SnapSpecificationSources("other", s.plug.Snap.InstanceName())
+	c.Assert(err, ErrorMatches, `cannot handle interfaces of snap "consumer", security system "other" is not known`)
+}
+
 type testSideArity struct {
 	sideSnapName string
 }
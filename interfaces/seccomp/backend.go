@@ -25,8 +25,10 @@
 // profile is read and "compiled" to an eBPF program and injected into the
 // kernel for the duration of the execution of the process.
 //
-// There is no binary cache for seccomp, each time the launcher starts an
-// application the profile is parsed and re-compiled.
+// Compiled programs are kept in a content addressed cache in
+// /var/cache/snapd/seccomp so that identical profiles are compiled once.
+// The launcher itself has no cache, it loads the compiled program of each
+// application as it starts.
 //
 // The actual profiles are stored in /var/lib/snappy/seccomp/bpf/*.{src,bin}.
 // This directory is hard-coded in snap-confine.
@@ -35,14 +37,17 @@ package seccomp
 import (
 	"bytes"
 	"fmt"
+	"io/ioutil"
 	"os"
 	"path/filepath"
 	"runtime"
 	"strings"
+	"sync"
 
 	"github.com/snapcore/snapd/arch"
 	"github.com/snapcore/snapd/dirs"
 	"github.com/snapcore/snapd/interfaces"
+	"github.com/snapcore/snapd/logger"
 	"github.com/snapcore/snapd/osutil"
 	"github.com/snapcore/snapd/release"
 	"github.com/snapcore/snapd/sandbox/apparmor"
@@ -77,6 +82,13 @@ type Compiler interface {
 type Backend struct {
 	snapSeccomp Compiler
 	versionInfo seccomp.VersionInfo
+	cache       *bpfCache
+
+	// cacheLock is held for reading while the profiles of a snap are
+	// written and compiled, and for writing while the cache is pruned,
+	// so that snaps can be set up concurrently without the programs
+	// just cached for one of them being pruned by another
+	cacheLock sync.RWMutex
 }
 
 var globalProfileLE = []byte{
@@ -146,6 +158,13 @@ func (b *Backend) Initialize(*interfaces.SecurityBackendOptions) error {
 		return fmt.Errorf("cannot obtain snap-seccomp version information: %v", err)
 	}
 	b.versionInfo = versionInfo
+
+	// the cache only saves compilation time, profiles are compiled
+	// directly when it cannot be used
+	b.cache, err = openBpfCache(dirs.SnapSeccompCacheDir, versionInfo)
+	if err != nil {
+		logger.Noticef("cannot use seccomp cache: %v", err)
+	}
 	return nil
 }
 
@@ -162,23 +181,102 @@ func bpfBinPath(srcName string) string {
 	return filepath.Join(dirs.SnapSeccompDir, strings.TrimSuffix(srcName, ".src")+".bin")
 }
 
-func parallelCompile(compiler Compiler, profiles []string) error {
+// compileJob describes profiles with identical content, which need to be
+// compiled only once.
+type compileJob struct {
+	// key is the cache key of the profiles, it is empty when no cache is
+	// used
+	key      string
+	profiles []string
+}
+
+func compileJobs(cache *bpfCache, profiles []string) ([]*compileJob, error) {
+	jobs := make([]*compileJob, 0, len(profiles))
+	if cache == nil {
+		for _, p := range profiles {
+			jobs = append(jobs, &compileJob{profiles: []string{p}})
+		}
+		return jobs, nil
+	}
+	byKey := make(map[string]*compileJob, len(profiles))
+	for _, p := range profiles {
+		src, err := ioutil.ReadFile(bpfSrcPath(p))
+		if err != nil {
+			return nil, err
+		}
+		key := cache.key(src)
+		if job := byKey[key]; job != nil {
+			job.profiles = append(job.profiles, p)
+			continue
+		}
+		job := &compileJob{key: key, profiles: []string{p}}
+		byKey[key] = job
+		jobs = append(jobs, job)
+	}
+	return jobs, nil
+}
+
+func (job *compileJob) run(compiler Compiler, cache *bpfCache) error {
+	// remove the old profiles first so that we are not loading them
+	// accidentally should the compilation fail
+	for _, p := range job.profiles {
+		if err := os.Remove(bpfBinPath(p)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+
+	in := bpfSrcPath(job.profiles[0])
+	out := bpfBinPath(job.profiles[0])
+	cached := false
+	if cache != nil {
+		var err error
+		cached, err = cache.get(job.key, out)
+		if err != nil {
+			logger.Noticef("cannot use cached seccomp program for %s: %v", in, err)
+		}
+	}
+	if !cached {
+		// snap-seccomp uses AtomicWriteFile internally, on failure the
+		// output file is unlinked
+		if err := compiler.Compile(in, out); err != nil {
+			return fmt.Errorf("cannot compile %s: %v", in, err)
+		}
+		if cache != nil {
+			if err := cache.put(job.key, out); err != nil {
+				logger.Noticef("cannot cache seccomp program for %s: %v", in, err)
+			}
+		}
+	}
+	for _, p := range job.profiles[1:] {
+		if err := osutil.AtomicWriteFileCopy(bpfBinPath(p), out, 0); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func parallelCompile(compiler Compiler, cache *bpfCache, profiles []string) error {
 	if len(profiles) == 0 {
 		// no profiles, nothing to do
 		return nil
 	}
 
-	profilesQueue := make(chan string, len(profiles))
+	jobs, err := compileJobs(cache, profiles)
+	if err != nil {
+		return err
+	}
+
+	jobsQueue := make(chan *compileJob, len(jobs))
 	numWorkers := runtime.NumCPU()
 	if numWorkers >= 2 {
 		numWorkers -= 1
 	}
-	if numWorkers > len(profiles) {
-		numWorkers = len(profiles)
+	if numWorkers > len(jobs) {
+		numWorkers = len(jobs)
 	}
 	resultsBufferSize := numWorkers * 2
-	if resultsBufferSize > len(profiles) {
-		resultsBufferSize = len(profiles)
+	if resultsBufferSize > len(jobs) {
+		resultsBufferSize = len(jobs)
 	}
 	res := make(chan error, resultsBufferSize)
 
@@ -186,39 +284,23 @@ func parallelCompile(compiler Compiler, profiles []string) error {
 	for i := 0; i < numWorkers; i++ {
 		go func() {
 			for {
-				profile, ok := <-profilesQueue
+				job, ok := <-jobsQueue
 				if !ok {
 					break
 				}
-				in := bpfSrcPath(profile)
-				out := bpfBinPath(profile)
-				// remove the old profile first so that we are
-				// not loading it accidentally should the
-				// compilation fail
-				if err := os.Remove(out); err != nil && !os.IsNotExist(err) {
-					res <- err
-					continue
-				}
-
-				// snap-seccomp uses AtomicWriteFile internally, on failure the
-				// output file is unlinked
-				if err := compiler.Compile(in, out); err != nil {
-					res <- fmt.Errorf("cannot compile %s: %v", in, err)
-				} else {
-					res <- nil
-				}
+				res <- job.run(compiler, cache)
 			}
 		}()
 	}
 
-	for _, p := range profiles {
-		profilesQueue <- p
+	for _, job := range jobs {
+		jobsQueue <- job
 	}
 	// signal workers to exit
-	close(profilesQueue)
+	close(jobsQueue)
 
 	var firstErr error
-	for i := 0; i < len(profiles); i++ {
+	for i := 0; i < len(jobs); i++ {
 		maybeErr := <-res
 		if maybeErr != nil && firstErr == nil {
 			firstErr = maybeErr
@@ -261,10 +343,26 @@ func (b *Backend) Setup(snapInfo *snap.Info, opts interfaces.ConfinementOptions,
 		return fmt.Errorf("cannot obtain expected security files for snap %q: %s", snapName, err)
 	}
 
+	changed, removed, err := b.writeAndCompile(snapName, content)
+	if err != nil {
+		return err
+	}
+	if len(changed) > 0 || len(removed) > 0 {
+		b.pruneCache()
+	}
+	return nil
+}
+
+// writeAndCompile writes the profiles of the snap and compiles those that
+// changed, it returns the names of the changed and removed profiles.
+func (b *Backend) writeAndCompile(snapName string, content map[string]osutil.FileState) (changed, removed []string, err error) {
+	b.cacheLock.RLock()
+	defer b.cacheLock.RUnlock()
+
 	glob := interfaces.SecurityTagGlob(snapName) + ".src"
 	dir := dirs.SnapSeccompDir
 	if err := os.MkdirAll(dir, 0755); err != nil {
-		return fmt.Errorf("cannot create directory for seccomp profiles %q: %s", dir, err)
+		return nil, nil, fmt.Errorf("cannot create directory for seccomp profiles %q: %s", dir, err)
 	}
 	// There is a delicate interaction between `snap run`, `snap-confine`
 	// and compilation of profiles:
@@ -275,30 +373,65 @@ func (b *Backend) Setup(snapInfo *snap.Info, opts interfaces.ConfinementOptions,
 	// - whenever the binary file does not exist, `snap-confine` will poll
 	//   and wait for SNAP_CONFINE_MAX_PROFILE_WAIT, if the profile does not
 	//   appear in that time, `snap-confine` will fail and die
-	changed, removed, err := osutil.EnsureDirState(dir, glob, content)
+	changed, removed, err = osutil.EnsureDirState(dir, glob, content)
 	if err != nil {
-		return fmt.Errorf("cannot synchronize security files for snap %q: %s", snapName, err)
+		return nil, nil, fmt.Errorf("cannot synchronize security files for snap %q: %s", snapName, err)
 	}
 	for _, c := range removed {
 		err := os.Remove(bpfBinPath(c))
 		if err != nil && !os.IsNotExist(err) {
-			return err
+			return nil, nil, err
 		}
 	}
 
-	return parallelCompile(b.snapSeccomp, changed)
+	if err := parallelCompile(b.snapSeccomp, b.cache, changed); err != nil {
+		return nil, nil, err
+	}
+	return changed, removed, nil
 }
 
 // Remove removes seccomp profiles of a given snap.
 func (b *Backend) Remove(snapName string) error {
 	glob := interfaces.SecurityTagGlob(snapName)
-	_, _, err := osutil.EnsureDirState(dirs.SnapSeccompDir, glob, nil)
+	_, removed, err := osutil.EnsureDirState(dirs.SnapSeccompDir, glob, nil)
 	if err != nil {
 		return fmt.Errorf("cannot synchronize security files for snap %q: %s", snapName, err)
 	}
+	if len(removed) > 0 {
+		b.pruneCache()
+	}
 	return nil
 }
 
+// pruneCache drops the cached programs which none of the profiles present
+// on disk compile to. Failing to do so only leaves stale entries behind.
+// It waits for the snaps being set up to have their profiles written and
+// compiled.
+func (b *Backend) pruneCache() {
+	if b.cache == nil {
+		return
+	}
+	b.cacheLock.Lock()
+	defer b.cacheLock.Unlock()
+	profiles, err := filepath.Glob(filepath.Join(dirs.SnapSeccompDir, "*.src"))
+	if err != nil {
+		logger.Noticef("cannot prune seccomp cache: %v", err)
+		return
+	}
+	keep := make(map[string]bool, len(profiles))
+	for _, profile := range profiles {
+		src, err := ioutil.ReadFile(profile)
+		if err != nil {
+			logger.Noticef("cannot prune seccomp cache: %v", err)
+			return
+		}
+		keep[b.cache.key(src)] = true
+	}
+	if err := b.cache.prune(keep); err != nil {
+		logger.Noticef("cannot prune seccomp cache: %v", err)
+	}
+}
+
 // Obtain the privilege dropping snippet
 func uidGidChownSnippet(name string) (string, error) {
 	tmp := strings.Replace(privDropAndChownSyscalls, "###USERNAME###", name, -1)
@@ -28,7 +28,9 @@ import (
 	"path/filepath"
 	"runtime"
 	"sort"
+	"strings"
 	"sync"
+	"time"
 
 	. "gopkg.in/check.v1"
 
@@ -54,7 +56,8 @@ type backendSuite struct {
 	profileHeader string
 	meas          *timings.Span
 
-	restoreReadlink func()
+	restoreReadlink  func()
+	restoreSystemKey func()
 }
 
 var _ = Suite(&backendSuite{})
@@ -84,7 +87,10 @@ func (s *backendSuite) SetUpTest(c *C) {
 	s.snapSeccomp = testutil.MockLockedCommand(c, snapSeccompPath, `
 if [ "$1" = "version-info" ]; then
     echo "abcdef 1.2.3 1234abcd -"
+elif [ "$1" = "compile" ]; then
+    echo "compiled" > "$3"
 fi`)
+	s.restoreSystemKey = interfaces.MockSystemKey(`{"build-id": "abcde"}`)
 
 	s.Backend.Initialize(nil)
 	s.profileHeader = `# snap-seccomp version information:
@@ -105,6 +111,7 @@ func (s *backendSuite) TearDownTest(c *C) {
 
 	s.snapSeccomp.Restore()
 	s.restoreReadlink()
+	s.restoreSystemKey()
 }
 
 func (s *backendSuite) TestInitialize(c *C) {
@@ -216,8 +223,9 @@ func (s *backendSuite) TestUpdatingSnapToOneWithMoreApps(c *C) {
 		_, err := os.Stat(profile + ".src")
 		// file called "snap.sambda.nmbd" was created
 		c.Check(err, IsNil)
-		// and got compiled
-		c.Check(s.snapSeccomp.Calls(), testutil.DeepContains, []string{"snap-seccomp", "compile", profile + ".src", profile + ".bin"})
+		// and got compiled, or copied from the identical profile
+		// compiled earlier
+		c.Check(profile+".bin", testutil.FilePresent)
 		s.snapSeccomp.ForgetCalls()
 
 		s.RemoveSnap(c, snapInfo)
@@ -233,8 +241,9 @@ func (s *backendSuite) TestUpdatingSnapToOneWithHooks(c *C) {
 		_, err := os.Stat(profile + ".src")
 		// Verify that profile "snap.samba.hook.configure" was created.
 		c.Check(err, IsNil)
-		// and got compiled
-		c.Check(s.snapSeccomp.Calls(), testutil.DeepContains, []string{"snap-seccomp", "compile", profile + ".src", profile + ".bin"})
+		// and got compiled, or copied from the identical profile
+		// compiled earlier
+		c.Check(profile+".bin", testutil.FilePresent)
 		s.snapSeccomp.ForgetCalls()
 
 		s.RemoveSnap(c, snapInfo)
@@ -857,21 +866,15 @@ fi
 		c.Check(profile+".bin", testutil.FileAbsent)
 	}
 
-	// 2 compile calls + 1 version-info
-	c.Check(snapSeccomp.Calls(), HasLen, 3)
-	seen := make(map[string]bool, 2)
-	for _, call := range snapSeccomp.Calls() {
-		if len(call) == 2 && call[1] == "version-info" {
-			continue
-		}
-		c.Assert(call, HasLen, 4)
-		seen[call[2]] = true
-	}
-	c.Check(seen, DeepEquals, map[string]bool{
-		nmbdProfile + ".src": true,
-		smbdProfile + ".src": true,
+	// the profiles of smbd and nmbd are identical and compiled only once
+	c.Check(snapSeccomp.Calls(), DeepEquals, [][]string{
+		{"snap-seccomp", "version-info"},
+		{"snap-seccomp", "compile", nmbdProfile + ".src", nmbdProfile + ".bin"},
 	})
-
+	// and nothing was cached
+	cached, err := filepath.Glob(filepath.Join(dirs.SnapSeccompCacheDir, "*.bin"))
+	c.Assert(err, IsNil)
+	c.Check(cached, HasLen, 0)
 }
 
 type mockedSyncedCompiler struct {
@@ -968,3 +971,237 @@ func (s *backendSuite) TestParallelCompileRemovesFirst(c *C) {
 	err = seccomp.ParallelCompile(&m, []string{"profile-001"})
 	c.Assert(err, ErrorMatches, "remove .*/profile-001.bin: permission denied")
 }
+
+const otherSambaYaml = `
+name: other-samba
+version: 1
+apps:
+    smbd:
+`
+
+func mockShortTemplate() (restore func()) {
+	restores := []func(){
+		apparmor_sandbox.MockLevel(apparmor_sandbox.Full),
+		seccomp_sandbox.MockActions([]string{"log"}),
+		seccomp.MockRequiresSocketcall(func(string) bool { return false }),
+		// NOTE: replace the real template with a shorter variant
+		seccomp.MockTemplate([]byte("\ndefault\n")),
+	}
+	return func() {
+		for _, restore := range restores {
+			restore()
+		}
+	}
+}
+
+func (s *backendSuite) cachedPrograms(c *C) []string {
+	cached, err := filepath.Glob(filepath.Join(dirs.SnapSeccompCacheDir, "*.bin"))
+	c.Assert(err, IsNil)
+	return cached
+}
+
+func (s *backendSuite) TestSetupCompilesIdenticalProfilesOnce(c *C) {
+	restore := mockShortTemplate()
+	defer restore()
+
+	smbdProfile := filepath.Join(dirs.SnapSeccompDir, "snap.samba.smbd")
+	nmbdProfile := filepath.Join(dirs.SnapSeccompDir, "snap.samba.nmbd")
+
+	snapInfo := snaptest.MockInfo(c, ifacetest.SambaYamlV1WithNmbd, nil)
+	err := s.Backend.Setup(snapInfo, interfaces.ConfinementOptions{}, s.Repo, s.meas)
+	c.Assert(err, IsNil)
+
+	c.Check(s.snapSeccomp.Calls(), DeepEquals, [][]string{
+		{"snap-seccomp", "compile", nmbdProfile + ".src", nmbdProfile + ".bin"},
+	})
+	c.Check(nmbdProfile+".bin", testutil.FileEquals, "compiled\n")
+	c.Check(smbdProfile+".bin", testutil.FileEquals, "compiled\n")
+	cached := s.cachedPrograms(c)
+	c.Assert(cached, HasLen, 1)
+	c.Check(cached[0], testutil.FileEquals, "compiled\n")
+
+	// an identical profile of another snap is taken from the cache
+	s.snapSeccomp.ForgetCalls()
+	otherProfile := filepath.Join(dirs.SnapSeccompDir, "snap.other-samba.smbd")
+	otherInfo := snaptest.MockInfo(c, otherSambaYaml, nil)
+	err = s.Backend.Setup(otherInfo, interfaces.ConfinementOptions{}, s.Repo, s.meas)
+	c.Assert(err, IsNil)
+	c.Check(s.snapSeccomp.Calls(), HasLen, 0)
+	c.Check(otherProfile+".bin", testutil.FileEquals, "compiled\n")
+
+	// while a different one is compiled
+	err = s.Backend.Setup(otherInfo, interfaces.ConfinementOptions{DevMode: true}, s.Repo, s.meas)
+	c.Assert(err, IsNil)
+	c.Check(s.snapSeccomp.Calls(), DeepEquals, [][]string{
+		{"snap-seccomp", "compile", otherProfile + ".src", otherProfile + ".bin"},
+	})
+	c.Check(s.cachedPrograms(c), HasLen, 2)
+}
+
+func (s *backendSuite) TestSetupCacheKeyHasArchitecture(c *C) {
+	restore := mockShortTemplate()
+	defer restore()
+
+	snapInfo := snaptest.MockInfo(c, ifacetest.SambaYamlV1, nil)
+	err := s.Backend.Setup(snapInfo, interfaces.ConfinementOptions{}, s.Repo, s.meas)
+	c.Assert(err, IsNil)
+
+	// an identical profile is not taken from the cache for another
+	// architecture
+	s.snapSeccomp.ForgetCalls()
+	restore = seccomp.MockDpkgKernelArchitecture(func() string { return "other-arch" })
+	defer restore()
+	otherProfile := filepath.Join(dirs.SnapSeccompDir, "snap.other-samba.smbd")
+	otherInfo := snaptest.MockInfo(c, otherSambaYaml, nil)
+	err = s.Backend.Setup(otherInfo, interfaces.ConfinementOptions{}, s.Repo, s.meas)
+	c.Assert(err, IsNil)
+	c.Check(s.snapSeccomp.Calls(), DeepEquals, [][]string{
+		{"snap-seccomp", "compile", otherProfile + ".src", otherProfile + ".bin"},
+	})
+}
+
+func (s *backendSuite) TestCachePrunedOnSetupAndRemove(c *C) {
+	restore := mockShortTemplate()
+	defer restore()
+
+	snapInfo := snaptest.MockInfo(c, ifacetest.SambaYamlV1, nil)
+	err := s.Backend.Setup(snapInfo, interfaces.ConfinementOptions{}, s.Repo, s.meas)
+	c.Assert(err, IsNil)
+	otherInfo := snaptest.MockInfo(c, otherSambaYaml, nil)
+	err = s.Backend.Setup(otherInfo, interfaces.ConfinementOptions{DevMode: true}, s.Repo, s.meas)
+	c.Assert(err, IsNil)
+	c.Check(s.cachedPrograms(c), HasLen, 2)
+
+	// a stale entry no profile refers to
+	stale := filepath.Join(dirs.SnapSeccompCacheDir, "stale.bin")
+	c.Assert(ioutil.WriteFile(stale, nil, 0644), IsNil)
+
+	// changing the profile drops the program it used to compile to
+	err = s.Backend.Setup(otherInfo, interfaces.ConfinementOptions{}, s.Repo, s.meas)
+	c.Assert(err, IsNil)
+	c.Check(s.cachedPrograms(c), HasLen, 1)
+	c.Check(stale, testutil.FileAbsent)
+
+	// the program is kept as long as a profile refers to it
+	c.Assert(s.Backend.Remove("other-samba"), IsNil)
+	c.Check(s.cachedPrograms(c), HasLen, 1)
+	c.Assert(s.Backend.Remove("samba"), IsNil)
+	c.Check(s.cachedPrograms(c), HasLen, 0)
+	c.Check(filepath.Join(dirs.SnapSeccompCacheDir, "system-key"), testutil.FilePresent)
+}
+
+func (s *backendSuite) TestCacheInvalidatedOnSystemKeyChange(c *C) {
+	restore := mockShortTemplate()
+	defer restore()
+
+	profile := filepath.Join(dirs.SnapSeccompDir, "snap.samba.smbd")
+	snapInfo := snaptest.MockInfo(c, ifacetest.SambaYamlV1, nil)
+	err := s.Backend.Setup(snapInfo, interfaces.ConfinementOptions{}, s.Repo, s.meas)
+	c.Assert(err, IsNil)
+	c.Check(s.cachedPrograms(c), HasLen, 1)
+	c.Check(filepath.Join(dirs.SnapSeccompCacheDir, "system-key"), testutil.FileContains, `"build-id":"abcde"`)
+
+	// the same system key keeps the cache
+	err = s.Backend.Initialize(nil)
+	c.Assert(err, IsNil)
+	c.Check(s.cachedPrograms(c), HasLen, 1)
+
+	restore = interfaces.MockSystemKey(`{"build-id": "other"}`)
+	defer restore()
+	err = s.Backend.Initialize(nil)
+	c.Assert(err, IsNil)
+	c.Check(s.cachedPrograms(c), HasLen, 0)
+	c.Check(filepath.Join(dirs.SnapSeccompCacheDir, "system-key"), testutil.FileContains, `"build-id":"other"`)
+
+	c.Assert(s.Backend.Remove("samba"), IsNil)
+	s.snapSeccomp.ForgetCalls()
+	err = s.Backend.Setup(snapInfo, interfaces.ConfinementOptions{}, s.Repo, s.meas)
+	c.Assert(err, IsNil)
+	c.Check(s.snapSeccomp.Calls(), DeepEquals, [][]string{
+		{"snap-seccomp", "compile", profile + ".src", profile + ".bin"},
+	})
+}
+
+func (s *backendSuite) TestSetupWithoutCache(c *C) {
+	restore := mockShortTemplate()
+	defer restore()
+
+	// the cache cannot be used when the system key is not known
+	restore = interfaces.MockSystemKey(`{"build-id": "other"}`)
+	defer restore()
+	c.Assert(os.MkdirAll(dirs.SnapCacheDir, 0755), IsNil)
+	c.Assert(os.RemoveAll(dirs.SnapSeccompCacheDir), IsNil)
+	c.Assert(ioutil.WriteFile(dirs.SnapSeccompCacheDir, nil, 0644), IsNil)
+	err := s.Backend.Initialize(nil)
+	c.Assert(err, IsNil)
+
+	smbdProfile := filepath.Join(dirs.SnapSeccompDir, "snap.samba.smbd")
+	nmbdProfile := filepath.Join(dirs.SnapSeccompDir, "snap.samba.nmbd")
+	snapInfo := snaptest.MockInfo(c, ifacetest.SambaYamlV1WithNmbd, nil)
+	err = s.Backend.Setup(snapInfo, interfaces.ConfinementOptions{}, s.Repo, s.meas)
+	c.Assert(err, IsNil)
+	c.Check(s.snapSeccomp.Calls(), HasLen, 3)
+	c.Check(s.snapSeccomp.Calls(), testutil.DeepContains, []string{"snap-seccomp", "compile", nmbdProfile + ".src", nmbdProfile + ".bin"})
+	c.Check(s.snapSeccomp.Calls(), testutil.DeepContains, []string{"snap-seccomp", "compile", smbdProfile + ".src", smbdProfile + ".bin"})
+}
+
+type blockingCompiler struct {
+	seccomp.Compiler
+	blockedProfile string
+	started        chan bool
+	release        chan bool
+}
+
+func (compiler *blockingCompiler) Compile(in, out string) error {
+	if strings.Contains(in, compiler.blockedProfile) {
+		compiler.started <- true
+		<-compiler.release
+	}
+	return ioutil.WriteFile(out, []byte("compiled\n"), 0644)
+}
+
+func (s *backendSuite) TestConcurrentSetupKeepsCompiledPrograms(c *C) {
+	restore := mockShortTemplate()
+	defer restore()
+
+	backend := s.Backend.(*seccomp.Backend)
+	compiler := &blockingCompiler{
+		blockedProfile: "snap.samba.smbd",
+		started:        make(chan bool, 1),
+		release:        make(chan bool),
+	}
+	restore = seccomp.MockBackendCompiler(backend, compiler)
+	defer restore()
+
+	// the compilation of the profile of samba is in flight
+	snapInfo := snaptest.MockInfo(c, ifacetest.SambaYamlV1, nil)
+	sambaDone := make(chan error)
+	go func() {
+		sambaDone <- backend.Setup(snapInfo, interfaces.ConfinementOptions{}, s.Repo, nil)
+	}()
+	select {
+	case <-compiler.started:
+	case <-time.After(5 * time.Second):
+		c.Fatal("profile of samba not compiled")
+	}
+
+	// another snap is set up meanwhile but does not prune the cache
+	// before samba has cached its program
+	otherInfo := snaptest.MockInfo(c, otherSambaYaml, nil)
+	otherDone := make(chan error)
+	go func() {
+		otherDone <- backend.Setup(otherInfo, interfaces.ConfinementOptions{DevMode: true}, s.Repo, nil)
+	}()
+	select {
+	case err := <-otherDone:
+		c.Fatalf("cache pruned while samba is set up: %v", err)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	close(compiler.release)
+	c.Check(<-sambaDone, IsNil)
+	c.Check(<-otherDone, IsNil)
+	c.Check(s.cachedPrograms(c), HasLen, 2)
+	c.Check(filepath.Join(dirs.SnapSeccompDir, "snap.samba.smbd.bin"), testutil.FileEquals, "compiled\n")
+	c.Check(filepath.Join(dirs.SnapSeccompDir, "snap.other-samba.smbd.bin"), testutil.FileEquals, "compiled\n")
+}
@@ -0,0 +1,134 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package seccomp
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/snapcore/snapd/interfaces"
+	"github.com/snapcore/snapd/osutil"
+	"github.com/snapcore/snapd/sandbox/seccomp"
+)
+
+var currentSystemKey = interfaces.CurrentSystemKey
+
+// bpfCache is a content addressed cache of compiled seccomp profiles.
+//
+// Profiles are commonly identical across applications and snaps, the cache
+// allows to compile each distinct profile only once. Entries are keyed on
+// the profile source, the compiler version information and the
+// architecture. The whole cache is dropped whenever the system key changes,
+// as the compiled programs may no longer match the running system, and
+// programs no profile refers to any more are pruned as profiles are set up
+// and removed.
+type bpfCache struct {
+	dir         string
+	versionInfo seccomp.VersionInfo
+}
+
+const bpfCacheSystemKey = "system-key"
+
+// openBpfCache prepares the cache in the given directory, removing any
+// entries which were compiled for a different system key.
+func openBpfCache(dir string, versionInfo seccomp.VersionInfo) (*bpfCache, error) {
+	sk, err := currentSystemKey()
+	if err != nil {
+		return nil, fmt.Errorf("cannot obtain system key: %v", err)
+	}
+	skJSON, err := json.Marshal(sk)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("cannot create directory for seccomp cache %q: %v", dir, err)
+	}
+
+	skPath := filepath.Join(dir, bpfCacheSystemKey)
+	recorded, err := ioutil.ReadFile(skPath)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	if !bytes.Equal(recorded, skJSON) {
+		// the system key is written last, a cache in which it is
+		// missing or different is discarded entirely
+		if _, _, err := osutil.EnsureDirState(dir, "*", nil); err != nil {
+			return nil, fmt.Errorf("cannot invalidate seccomp cache: %v", err)
+		}
+		if err := osutil.AtomicWriteFile(skPath, skJSON, 0644, 0); err != nil {
+			return nil, err
+		}
+	}
+	return &bpfCache{dir: dir, versionInfo: versionInfo}, nil
+}
+
+// key returns the cache key of the given profile source.
+func (c *bpfCache) key(src []byte) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\x00%s\x00", c.versionInfo, dpkgKernelArchitecture())
+	h.Write(src)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func (c *bpfCache) path(key string) string {
+	return filepath.Join(c.dir, key+".bin")
+}
+
+// get copies the cached program with the given key to out, it returns false
+// if there is no such program.
+func (c *bpfCache) get(key, out string) (bool, error) {
+	cached := c.path(key)
+	if !osutil.FileExists(cached) {
+		return false, nil
+	}
+	if err := osutil.AtomicWriteFileCopy(out, cached, 0); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// put stores the program compiled to bin under the given key.
+func (c *bpfCache) put(key, bin string) error {
+	return osutil.AtomicWriteFileCopy(c.path(key), bin, 0)
+}
+
+// prune removes all cached programs whose key is not in keep.
+func (c *bpfCache) prune(keep map[string]bool) error {
+	cached, err := filepath.Glob(filepath.Join(c.dir, "*.bin"))
+	if err != nil {
+		return err
+	}
+	for _, p := range cached {
+		if keep[strings.TrimSuffix(filepath.Base(p), ".bin")] {
+			continue
+		}
+		if err := os.Remove(p); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
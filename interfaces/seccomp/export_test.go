@@ -86,6 +86,14 @@ func MockSeccompCompilerLookup(f func(string) (string, error)) (restore func())
 	}
 }
 
+func MockBackendCompiler(b *Backend, compiler Compiler) (restore func()) {
+	old := b.snapSeccomp
+	b.snapSeccomp = compiler
+	return func() {
+		b.snapSeccomp = old
+	}
+}
+
 func (b *Backend) VersionInfo() seccomp_compiler.VersionInfo {
 	return b.versionInfo
 }
@@ -97,5 +105,7 @@ var (
 	GlobalProfileBE = globalProfileBE
 	IsBigEndian     = isBigEndian
 
-	ParallelCompile = parallelCompile
+	ParallelCompile = func(compiler Compiler, profiles []string) error {
+		return parallelCompile(compiler, nil, profiles)
+	}
 )
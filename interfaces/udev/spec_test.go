@@ -131,6 +131,17 @@ kernel="voodoo", TAG+="snap_snap1_hook_configure"`,
 kernel="hoodoo", TAG+="snap_snap1_hook_configure"`,
 		fmt.Sprintf(`TAG=="snap_snap1_hook_configure", RUN+="%[1]s/snap-device-helper $env{ACTION} snap_snap1_hook_configure $devpath $major:$minor"`, helperDir),
 	})
+
+	s.spec.AddSnippet("untagged")
+	c.Assert(s.spec.SnippetsForTag("snap.snap1.hook.configure"), DeepEquals, []string{
+		"untagged",
+		`# iface-1
+kernel="voodoo", TAG+="snap_snap1_hook_configure"`,
+		`# iface-2
+kernel="hoodoo", TAG+="snap_snap1_hook_configure"`,
+		fmt.Sprintf(`TAG=="snap_snap1_hook_configure", RUN+="%[1]s/snap-device-helper $env{ACTION} snap_snap1_hook_configure $devpath $major:$minor"`, helperDir),
+	})
+	c.Assert(s.spec.SnippetsForTag("snap.snap1.other"), DeepEquals, []string{"untagged"})
 }
 
 func (s *specSuite) TestTagDevice(c *C) {
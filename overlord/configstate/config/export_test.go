@@ -21,6 +21,7 @@ package config
 
 import (
 	"encoding/json"
+	"time"
 )
 
 var PurgeNulls = purgeNulls
@@ -30,3 +31,11 @@ func (t *Transaction) PristineConfig() map[string]map[string]*json.RawMessage {
 }
 
 var SortPatchKeysByDepth = sortPatchKeysByDepth
+
+func MockTimeNow(f func() time.Time) (restore func()) {
+	old := timeNow
+	timeNow = f
+	return func() {
+		timeNow = old
+	}
+}
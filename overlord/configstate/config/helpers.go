@@ -154,6 +154,25 @@ func GetSnapConfig(st *state.State, snapName string) (*json.RawMessage, error) {
 	return snapcfg, nil
 }
 
+// ConfiguredSnaps returns the names of the snaps that have some
+// configuration, sorted.
+func ConfiguredSnaps(st *state.State) ([]string, error) {
+	var config map[string]*json.RawMessage
+	err := st.Get("config", &config)
+	if err == state.ErrNoState {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(config))
+	for name := range config {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
 // SetSnapConfig replaces the configuration of a given snap.
 func SetSnapConfig(st *state.State, snapName string, snapcfg *json.RawMessage) error {
 	var config map[string]*json.RawMessage
@@ -268,10 +287,15 @@ func DiscardRevisionConfig(st *state.State, snapName string, rev snap.Revision)
 	return nil
 }
 
-// DeleteSnapConfig removed configuration of given snap from the state.
+// DeleteSnapConfig removed configuration of given snap from the state,
+// along with its history.
 func DeleteSnapConfig(st *state.State, snapName string) error {
 	var config map[string]map[string]*json.RawMessage // snap => key => value
 
+	if err := deleteHistory(st, snapName); err != nil {
+		return err
+	}
+
 	err := st.Get("config", &config)
 	if err == state.ErrNoState {
 		return nil
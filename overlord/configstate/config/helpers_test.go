@@ -186,6 +186,25 @@ func (s *configHelpersSuite) TestSnapConfig(c *C) {
 	}
 }
 
+func (s *configHelpersSuite) TestConfiguredSnaps(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	names, err := config.ConfiguredSnaps(s.state)
+	c.Assert(err, IsNil)
+	c.Check(names, HasLen, 0)
+
+	for _, name := range []string{"snap2", "core", "snap1"} {
+		cfg := json.RawMessage(`{"foo":"bar"}`)
+		c.Assert(config.SetSnapConfig(s.state, name, &cfg), IsNil)
+	}
+	c.Assert(config.SetSnapConfig(s.state, "snap2", nil), IsNil)
+
+	names, err = config.ConfiguredSnaps(s.state)
+	c.Assert(err, IsNil)
+	c.Check(names, DeepEquals, []string{"core", "snap1"})
+}
+
 func (s *configHelpersSuite) TestPatchInvalidConfig(c *C) {
 	s.state.Lock()
 	defer s.state.Unlock()
@@ -0,0 +1,172 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/snapcore/snapd/overlord/state"
+)
+
+// MaxHistoryEntries is the number of configuration changes that are
+// remembered for every snap.
+var MaxHistoryEntries = 20
+
+// MaxHistorySize is the number of bytes of configuration that are
+// remembered for every snap. The oldest entries are dropped to stay within
+// it, but the latest one is always kept.
+var MaxHistorySize = 64 * 1024
+
+var timeNow = time.Now
+
+// HistoryEntry describes the configuration of a snap as it was after
+// a change of it was applied. The whole configuration is kept, so values
+// that were since changed or unset remain in the history until their
+// entries are dropped or the snap is removed.
+type HistoryEntry struct {
+	// Revision identifies the entry, it increases with every change.
+	Revision int `json:"revision"`
+	// Time is when the change was applied, it is zero for the
+	// configuration that predates the history.
+	Time time.Time `json:"time,omitempty"`
+	// Initiator is who requested the change, as recorded in the
+	// change applying it: the snapd account of an authenticated user
+	// (its username or email) or "uid:<uid>" for other local users.
+	// It is "snapctl" for snapctl invoked outside of hooks, "snapd"
+	// for changes snapd initiated on its own, and empty for the
+	// configuration that predates the history.
+	Initiator string `json:"initiator,omitempty"`
+	// ChangeID is the change that applied the configuration, if any.
+	ChangeID string `json:"change-id,omitempty"`
+	// Config is the whole configuration of the snap, nil if the snap
+	// had no configuration.
+	Config *json.RawMessage `json:"config,omitempty"`
+}
+
+// History returns the recorded configuration changes of the given snap,
+// oldest first.
+// The caller is responsible for locking the state.
+func History(st *state.State, snapName string) ([]*HistoryEntry, error) {
+	var history map[string][]*HistoryEntry // snap => entries
+	err := st.Get("config-history", &history)
+	if err == state.ErrNoState {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("internal error: cannot unmarshal configuration history: %v", err)
+	}
+	return history[snapName], nil
+}
+
+func isNilConfig(cfg *json.RawMessage) bool {
+	return cfg == nil || len(*cfg) == 0 || bytes.Equal(*cfg, []byte("null")) || bytes.Equal(*cfg, []byte("{}"))
+}
+
+// RecordHistory appends the current configuration of the given snap to
+// its history, unless it is the same as the previous configuration. The
+// configuration before the change is given so that it can be restored
+// even if the history is empty. Only the last MaxHistoryEntries entries,
+// up to MaxHistorySize bytes of configuration, are kept.
+// The caller is responsible for locking the state.
+func RecordHistory(st *state.State, snapName string, previous *json.RawMessage, initiator, changeID string) error {
+	var history map[string][]*HistoryEntry // snap => entries
+	err := st.Get("config-history", &history)
+	if err == state.ErrNoState {
+		history = make(map[string][]*HistoryEntry)
+	} else if err != nil {
+		return fmt.Errorf("internal error: cannot unmarshal configuration history: %v", err)
+	}
+
+	current, err := GetSnapConfig(st, snapName)
+	if err != nil {
+		return err
+	}
+
+	entries := history[snapName]
+	if len(entries) == 0 {
+		if sameConfig(previous, current) {
+			return nil
+		}
+		if !isNilConfig(previous) {
+			entries = append(entries, &HistoryEntry{Revision: 1, Config: previous})
+		}
+	} else if sameConfig(entries[len(entries)-1].Config, current) {
+		return nil
+	}
+
+	rev := 1
+	if len(entries) > 0 {
+		rev = entries[len(entries)-1].Revision + 1
+	}
+	if isNilConfig(current) {
+		current = nil
+	}
+	entries = append(entries, &HistoryEntry{
+		Revision:  rev,
+		Time:      timeNow(),
+		Initiator: initiator,
+		ChangeID:  changeID,
+		Config:    current,
+	})
+	if len(entries) > MaxHistoryEntries {
+		entries = entries[len(entries)-MaxHistoryEntries:]
+	}
+	size := 0
+	for _, entry := range entries {
+		if entry.Config != nil {
+			size += len(*entry.Config)
+		}
+	}
+	for len(entries) > 1 && size > MaxHistorySize {
+		if entries[0].Config != nil {
+			size -= len(*entries[0].Config)
+		}
+		entries = entries[1:]
+	}
+	history[snapName] = entries
+	st.Set("config-history", history)
+	return nil
+}
+
+func sameConfig(a, b *json.RawMessage) bool {
+	if isNilConfig(a) || isNilConfig(b) {
+		return isNilConfig(a) && isNilConfig(b)
+	}
+	return bytes.Equal(*a, *b)
+}
+
+// deleteHistory removes the configuration history of the given snap.
+func deleteHistory(st *state.State, snapName string) error {
+	var history map[string]*json.RawMessage // snap => entries
+	err := st.Get("config-history", &history)
+	if err == state.ErrNoState {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("internal error: cannot unmarshal configuration history: %v", err)
+	}
+	if _, ok := history[snapName]; ok {
+		delete(history, snapName)
+		st.Set("config-history", history)
+	}
+	return nil
+}
@@ -0,0 +1,180 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package config_test
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/overlord/configstate/config"
+	"github.com/snapcore/snapd/overlord/state"
+)
+
+type historySuite struct {
+	state *state.State
+	now   time.Time
+}
+
+var _ = Suite(&historySuite{})
+
+func (s *historySuite) SetUpTest(c *C) {
+	s.state = state.New(nil)
+	s.now = time.Date(2021, 6, 1, 10, 0, 0, 0, time.UTC)
+}
+
+func (s *historySuite) setAndRecord(c *C, key string, value interface{}, initiator, changeID string) {
+	previous, err := config.GetSnapConfig(s.state, "snap1")
+	c.Assert(err, IsNil)
+	tr := config.NewTransaction(s.state)
+	c.Assert(tr.Set("snap1", key, value), IsNil)
+	tr.Commit()
+	s.now = s.now.Add(time.Minute)
+	restore := config.MockTimeNow(func() time.Time { return s.now })
+	defer restore()
+	c.Assert(config.RecordHistory(s.state, "snap1", previous, initiator, changeID), IsNil)
+}
+
+func configOf(c *C, entry *config.HistoryEntry) map[string]interface{} {
+	if entry.Config == nil {
+		return nil
+	}
+	var cfg map[string]interface{}
+	c.Assert(json.Unmarshal(*entry.Config, &cfg), IsNil)
+	return cfg
+}
+
+func (s *historySuite) TestRecordHistory(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	history, err := config.History(s.state, "snap1")
+	c.Assert(err, IsNil)
+	c.Check(history, HasLen, 0)
+
+	s.setAndRecord(c, "foo", "a", "user1", "1")
+	s.setAndRecord(c, "foo", "b", "user2", "2")
+	// no change, nothing recorded
+	s.setAndRecord(c, "foo", "b", "user2", "3")
+	s.setAndRecord(c, "foo", nil, "snapd", "4")
+
+	history, err = config.History(s.state, "snap1")
+	c.Assert(err, IsNil)
+	c.Assert(history, HasLen, 3)
+	c.Check(history[0].Revision, Equals, 1)
+	c.Check(history[0].Initiator, Equals, "user1")
+	c.Check(history[0].ChangeID, Equals, "1")
+	c.Check(history[0].Time.Equal(time.Date(2021, 6, 1, 10, 1, 0, 0, time.UTC)), Equals, true)
+	c.Check(configOf(c, history[0]), DeepEquals, map[string]interface{}{"foo": "a"})
+	c.Check(history[1].Revision, Equals, 2)
+	c.Check(history[1].Initiator, Equals, "user2")
+	c.Check(configOf(c, history[1]), DeepEquals, map[string]interface{}{"foo": "b"})
+	c.Check(history[2].Revision, Equals, 3)
+	c.Check(history[2].ChangeID, Equals, "4")
+	c.Check(history[2].Config, IsNil)
+
+	// other snaps are not affected
+	history, err = config.History(s.state, "snap2")
+	c.Assert(err, IsNil)
+	c.Check(history, HasLen, 0)
+}
+
+func (s *historySuite) TestRecordHistoryKeepsPreviousConfig(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	// configuration from before the history was recorded
+	tr := config.NewTransaction(s.state)
+	c.Assert(tr.Set("snap1", "foo", "old"), IsNil)
+	tr.Commit()
+
+	s.setAndRecord(c, "foo", "new", "user1", "1")
+
+	history, err := config.History(s.state, "snap1")
+	c.Assert(err, IsNil)
+	c.Assert(history, HasLen, 2)
+	c.Check(history[0].Revision, Equals, 1)
+	c.Check(history[0].Time.IsZero(), Equals, true)
+	c.Check(history[0].Initiator, Equals, "")
+	c.Check(configOf(c, history[0]), DeepEquals, map[string]interface{}{"foo": "old"})
+	c.Check(history[1].Revision, Equals, 2)
+	c.Check(configOf(c, history[1]), DeepEquals, map[string]interface{}{"foo": "new"})
+}
+
+func (s *historySuite) TestRecordHistoryBounded(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	old := config.MaxHistoryEntries
+	config.MaxHistoryEntries = 3
+	defer func() { config.MaxHistoryEntries = old }()
+
+	for i := 1; i <= 5; i++ {
+		s.setAndRecord(c, "foo", i, "user1", "")
+	}
+
+	history, err := config.History(s.state, "snap1")
+	c.Assert(err, IsNil)
+	c.Assert(history, HasLen, 3)
+	c.Check(history[0].Revision, Equals, 3)
+	c.Check(history[2].Revision, Equals, 5)
+	c.Check(configOf(c, history[2]), DeepEquals, map[string]interface{}{"foo": 5.})
+}
+
+func (s *historySuite) TestRecordHistoryBoundedSize(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	old := config.MaxHistorySize
+	// room for two entries of {"foo":"xxxxxxxx"}
+	config.MaxHistorySize = 40
+	defer func() { config.MaxHistorySize = old }()
+
+	for _, v := range []string{"aaaaaaaa", "bbbbbbbb", "cccccccc"} {
+		s.setAndRecord(c, "foo", v, "user1", "")
+	}
+
+	history, err := config.History(s.state, "snap1")
+	c.Assert(err, IsNil)
+	c.Assert(history, HasLen, 2)
+	c.Check(history[0].Revision, Equals, 2)
+	c.Check(history[1].Revision, Equals, 3)
+
+	// the latest configuration is kept even if it is too big
+	s.setAndRecord(c, "foo", strings.Repeat("d", 100), "user1", "")
+	history, err = config.History(s.state, "snap1")
+	c.Assert(err, IsNil)
+	c.Assert(history, HasLen, 1)
+	c.Check(history[0].Revision, Equals, 4)
+}
+
+func (s *historySuite) TestDeleteSnapConfigDeletesHistory(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	s.setAndRecord(c, "foo", "a", "user1", "1")
+	c.Assert(config.DeleteSnapConfig(s.state, "snap1"), IsNil)
+
+	history, err := config.History(s.state, "snap1")
+	c.Assert(err, IsNil)
+	c.Check(history, HasLen, 0)
+}
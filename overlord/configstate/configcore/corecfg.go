@@ -53,6 +53,42 @@ func coreCfg(tr config.ConfGetter, key string) (result string, err error) {
 // The actual values are populated by `init()` functions in each module.
 var supportedConfigurations = make(map[string]bool, 32)
 
+// WithoutInternalOptions returns the given system configuration without
+// the options snapd keeps there for its own use, such as the seeding
+// state, which cannot be set.
+func WithoutInternalOptions(conf map[string]interface{}) map[string]interface{} {
+	return filterOptions("core", conf, true)
+}
+
+// InternalOptions returns only the options of the given system
+// configuration that snapd keeps there for its own use.
+func InternalOptions(conf map[string]interface{}) map[string]interface{} {
+	return filterOptions("core", conf, false)
+}
+
+func filterOptions(prefix string, conf map[string]interface{}, settable bool) map[string]interface{} {
+	filtered := make(map[string]interface{}, len(conf))
+	for name, value := range conf {
+		key := prefix + "." + name
+		if supportedConfigurations[key] || validCertOption(key) {
+			if settable {
+				filtered[name] = value
+			}
+			continue
+		}
+		if sub, ok := value.(map[string]interface{}); ok {
+			if sub = filterOptions(key, sub, settable); len(sub) > 0 {
+				filtered[name] = sub
+			}
+			continue
+		}
+		if !settable {
+			filtered[name] = value
+		}
+	}
+	return filtered
+}
+
 func validateBoolFlag(tr config.ConfGetter, flag string) error {
 	value, err := coreCfg(tr, flag)
 	if err != nil {
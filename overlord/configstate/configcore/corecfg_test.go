@@ -187,6 +187,36 @@ func (r *runCfgSuite) TestConfigureUnknownOption(c *C) {
 	c.Check(err, ErrorMatches, `cannot set "core.unknown.option": unsupported system option`)
 }
 
+func (r *runCfgSuite) TestInternalOptions(c *C) {
+	conf := map[string]interface{}{
+		"seed":  map[string]interface{}{"loaded": true},
+		"cloud": map[string]interface{}{"name": "aws"},
+		"system": map[string]interface{}{
+			"hostname": "foo",
+			"internal": "value",
+		},
+		"refresh": map[string]interface{}{"timer": "4:00-6:00"},
+		"store-certs": map[string]interface{}{
+			"my-cert": "-----BEGIN CERTIFICATE-----",
+		},
+	}
+	c.Check(configcore.WithoutInternalOptions(conf), DeepEquals, map[string]interface{}{
+		"system":  map[string]interface{}{"hostname": "foo"},
+		"refresh": map[string]interface{}{"timer": "4:00-6:00"},
+		"store-certs": map[string]interface{}{
+			"my-cert": "-----BEGIN CERTIFICATE-----",
+		},
+	})
+	c.Check(configcore.InternalOptions(conf), DeepEquals, map[string]interface{}{
+		"seed":   map[string]interface{}{"loaded": true},
+		"cloud":  map[string]interface{}{"name": "aws"},
+		"system": map[string]interface{}{"internal": "value"},
+	})
+	// the given configuration is left alone
+	c.Check(conf["seed"], NotNil)
+	c.Check(conf["system"], HasLen, 2)
+}
+
 type mockDev struct {
 	mode    string
 	classic bool
@@ -97,6 +97,12 @@ func init() {
 	// system.timezone
 	addFSOnlyHandler(validateTimezoneSettings, handleTimezoneConfiguration, coreOnly)
 
+	// system.hostname
+	addFSOnlyHandler(validateHostnameSettings, handleHostnameConfiguration, coreOnly)
+
+	// system.locale, system.keyboard
+	addFSOnlyHandler(validateLocaleSettings, handleLocaleConfiguration, coreOnly)
+
 	sysconfig.ApplyFilesystemOnlyDefaultsImpl = filesystemOnlyApply
 }
 
@@ -0,0 +1,197 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package configcore
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/snapcore/snapd/asserts"
+	"github.com/snapcore/snapd/boot"
+	"github.com/snapcore/snapd/osutil"
+	"github.com/snapcore/snapd/overlord/configstate/config"
+	"github.com/snapcore/snapd/overlord/devicestate"
+	"github.com/snapcore/snapd/overlord/snapstate"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/strutil"
+)
+
+const (
+	kernelCmdlineAppendOpt = "system.kernel.cmdline-append"
+	kernelCmdlineFullOpt   = "system.kernel.dangerous-cmdline-full"
+)
+
+func init() {
+	// add supported configuration of this module
+	supportedConfigurations["core."+kernelCmdlineAppendOpt] = true
+	supportedConfigurations["core."+kernelCmdlineFullOpt] = true
+}
+
+// allowedKernelArguments lists the kernel command line arguments that can be
+// appended through the system configuration, see
+// https://www.kernel.org/doc/html/latest/admin-guide/kernel-parameters.html
+var allowedKernelArguments = []string{
+	"console", "quiet", "splash", "loglevel", "panic", "mitigations",
+	"isolcpus", "nohz", "nohz_full", "rcu_nocbs", "irqaffinity",
+	"hugepages", "hugepagesz", "default_hugepagesz", "transparent_hugepage",
+	"intel_iommu", "amd_iommu", "iommu", "cma",
+	"net.ifnames", "usbcore.autosuspend",
+}
+
+// kernelArguments splits the given kernel command line and checks that
+// every argument is acceptable. Arguments that are meant for snapd are never
+// accepted.
+func kernelArguments(opt, cmdline string, allowed func(name string) bool) error {
+	args, err := osutil.KernelCommandLineSplit(cmdline)
+	if err != nil {
+		return fmt.Errorf("cannot set %q: %v", opt, err)
+	}
+	for _, arg := range args {
+		name := strings.SplitN(arg, "=", 2)[0]
+		if strings.HasPrefix(name, "snapd") && name != "snapd.debug" {
+			return fmt.Errorf("cannot set %q: kernel argument %q is reserved", opt, arg)
+		}
+		if allowed != nil && !allowed(name) {
+			return fmt.Errorf("cannot set %q: kernel argument %q is not allowed", opt, arg)
+		}
+	}
+	return nil
+}
+
+func validateKernelCmdlineSettings(tr config.Conf) error {
+	cmdlineAppend, err := coreCfg(tr, kernelCmdlineAppendOpt)
+	if err != nil {
+		return err
+	}
+	cmdlineFull, err := coreCfg(tr, kernelCmdlineFullOpt)
+	if err != nil {
+		return err
+	}
+	if cmdlineAppend == "" && cmdlineFull == "" {
+		return nil
+	}
+
+	if cmdlineAppend != "" {
+		allowed := func(name string) bool {
+			return strutil.ListContains(allowedKernelArguments, name)
+		}
+		if err := kernelArguments(kernelCmdlineAppendOpt, cmdlineAppend, allowed); err != nil {
+			return err
+		}
+	}
+	if cmdlineFull != "" {
+		if err := kernelArguments(kernelCmdlineFullOpt, cmdlineFull, nil); err != nil {
+			return err
+		}
+	}
+
+	opt := kernelCmdlineAppendOpt
+	if cmdlineAppend == "" {
+		opt = kernelCmdlineFullOpt
+	}
+
+	seeded, err := alreadySeeded(tr)
+	if err != nil {
+		return err
+	}
+	if !seeded {
+		// the kernel command line is not updated while seeding, so the
+		// options cannot come from the gadget defaults
+		return fmt.Errorf("cannot set %q: not supported in gadget defaults", opt)
+	}
+
+	st := tr.State()
+	st.Lock()
+	deviceCtx, err := snapstate.DeviceCtx(st, nil, nil)
+	st.Unlock()
+	if err != nil {
+		return err
+	}
+	grade := deviceCtx.Model().Grade()
+	if grade == asserts.ModelGradeUnset {
+		// the command line of UC16/UC18 systems is not managed by snapd
+		return fmt.Errorf("cannot set %q: only supported on Ubuntu Core 20 and later", opt)
+	}
+	if cmdlineFull != "" && grade != asserts.ModelDangerous {
+		return fmt.Errorf("cannot set %q: only allowed on models of grade dangerous", kernelCmdlineFullOpt)
+	}
+
+	managed, err := boot.CommandLineIsManaged()
+	if err != nil {
+		return err
+	}
+	if !managed {
+		return fmt.Errorf("cannot set %q: the bootloader does not support setting the kernel command line", opt)
+	}
+	return nil
+}
+
+// taskConf is implemented by the configuration given to Run when the
+// configure hook of the core snap runs from a task.
+type taskConf interface {
+	Task() *state.Task
+}
+
+func handleKernelCmdlineConfiguration(tr config.Conf, opts *fsOnlyContext) error {
+	var syscmdline, pristine boot.SystemCommandLine
+	for _, opt := range []struct {
+		name     string
+		value    *string
+		pristine *string
+	}{
+		{kernelCmdlineAppendOpt, &syscmdline.Append, &pristine.Append},
+		{kernelCmdlineFullOpt, &syscmdline.Full, &pristine.Full},
+	} {
+		if err := tr.GetPristine("core", opt.name, opt.pristine); err != nil && !config.IsNoOption(err) {
+			return err
+		}
+		if err := tr.Get("core", opt.name, opt.value); err != nil && !config.IsNoOption(err) {
+			return err
+		}
+	}
+	if syscmdline == pristine {
+		return nil
+	}
+
+	var hookTask *state.Task
+	if tc, ok := tr.(taskConf); ok {
+		hookTask = tc.Task()
+	}
+
+	st := tr.State()
+	st.Lock()
+	defer st.Unlock()
+
+	var chg *state.Change
+	if hookTask != nil {
+		chg = hookTask.Change()
+	}
+	if chg == nil {
+		return fmt.Errorf("internal error: cannot update the kernel command line outside of a change")
+	}
+
+	// the kernel command line is updated once the configuration is
+	// committed, that is after the configure hook is done; the previous
+	// arguments are restored if the change is undone
+	update := devicestate.UpdateSystemCommandLineTask(st, &pristine)
+	update.WaitFor(hookTask)
+	chg.AddTask(update)
+	return nil
+}
@@ -0,0 +1,274 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package configcore_test
+
+import (
+	"os"
+	"path/filepath"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/asserts"
+	"github.com/snapcore/snapd/asserts/assertstest"
+	"github.com/snapcore/snapd/boot"
+	"github.com/snapcore/snapd/bootloader"
+	"github.com/snapcore/snapd/bootloader/bootloadertest"
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/overlord/configstate/configcore"
+	"github.com/snapcore/snapd/overlord/snapstate/snapstatetest"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/sysconfig"
+)
+
+// taskMockConf is a mockConf of a configure hook running from a task
+type taskMockConf struct {
+	*mockConf
+	task *state.Task
+}
+
+func (cfg *taskMockConf) Task() *state.Task {
+	return cfg.task
+}
+
+type kernelCmdlineSuite struct {
+	configcoreSuite
+
+	chg      *state.Change
+	hookTask *state.Task
+}
+
+var _ = Suite(&kernelCmdlineSuite{})
+
+func mockModelWithGrade(grade string) *asserts.Model {
+	return assertstest.FakeAssertion(map[string]interface{}{
+		"type":         "model",
+		"authority-id": "canonical",
+		"series":       "16",
+		"brand-id":     "canonical",
+		"model":        "pc",
+		"architecture": "amd64",
+		"base":         "core20",
+		"grade":        grade,
+		"snaps": []interface{}{
+			map[string]interface{}{
+				"name": "pc-kernel",
+				"id":   "pckernelidididididididididididid",
+				"type": "kernel",
+			},
+			map[string]interface{}{
+				"name": "pc",
+				"id":   "pcididididididididididididididid",
+				"type": "gadget",
+			},
+		},
+	}).(*asserts.Model)
+}
+
+func (s *kernelCmdlineSuite) SetUpTest(c *C) {
+	s.configcoreSuite.SetUpTest(c)
+
+	err := os.MkdirAll(filepath.Join(dirs.GlobalRootDir, "/etc/"), 0755)
+	c.Assert(err, IsNil)
+
+	s.state.Lock()
+	s.state.Set("seeded", true)
+	s.chg = s.state.NewChange("configure-core", "...")
+	s.hookTask = s.state.NewTask("run-hook", "...")
+	s.chg.AddTask(s.hookTask)
+	s.state.Unlock()
+
+	s.AddCleanup(snapstatetest.MockDeviceModel(mockModelWithGrade("signed")))
+
+	bootloader.Force(bootloadertest.Mock("mock", c.MkDir()).WithTrustedAssets())
+	s.AddCleanup(func() { bootloader.Force(nil) })
+}
+
+func (s *kernelCmdlineSuite) run(dev sysconfig.Device, conf, changes map[string]interface{}) error {
+	return configcore.Run(dev, &taskMockConf{
+		mockConf: &mockConf{
+			state:   s.state,
+			conf:    conf,
+			changes: changes,
+		},
+		task: s.hookTask,
+	})
+}
+
+// checkUpdateTask checks that a task updating the kernel command line was
+// queued after the hook, keeping the given previous arguments.
+func (s *kernelCmdlineSuite) checkUpdateTask(c *C, previous boot.SystemCommandLine) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	tasks := s.chg.Tasks()
+	c.Assert(tasks, HasLen, 2)
+	update := tasks[1]
+	c.Check(update.Kind(), Equals, "update-system-cmdline")
+	c.Check(update.WaitTasks(), DeepEquals, []*state.Task{s.hookTask})
+	var saved boot.SystemCommandLine
+	c.Assert(update.Get("previous-system-cmdline", &saved), IsNil)
+	c.Check(saved, Equals, previous)
+}
+
+func (s *kernelCmdlineSuite) checkNoUpdateTask(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+	c.Check(s.chg.Tasks(), HasLen, 1)
+}
+
+func (s *kernelCmdlineSuite) TestConfigureCmdlineAppendHappy(c *C) {
+	err := s.run(coreDev, nil, map[string]interface{}{
+		"system.kernel.cmdline-append": `isolcpus=1,2 hugepages=16 console=ttyS0,115200 quiet`,
+	})
+	c.Assert(err, IsNil)
+	s.checkUpdateTask(c, boot.SystemCommandLine{})
+}
+
+func (s *kernelCmdlineSuite) TestConfigureCmdlineUnset(c *C) {
+	err := s.run(coreDev, map[string]interface{}{
+		"system.kernel.cmdline-append": "quiet",
+	}, map[string]interface{}{
+		"system.kernel.cmdline-append": "",
+	})
+	c.Assert(err, IsNil)
+	s.checkUpdateTask(c, boot.SystemCommandLine{Append: "quiet"})
+}
+
+func (s *kernelCmdlineSuite) TestConfigureCmdlineUnchanged(c *C) {
+	err := s.run(coreDev, map[string]interface{}{
+		"system.kernel.cmdline-append": "quiet",
+	}, map[string]interface{}{
+		"system.kernel.cmdline-append": "quiet",
+	})
+	c.Assert(err, IsNil)
+	s.checkNoUpdateTask(c)
+}
+
+func (s *kernelCmdlineSuite) TestConfigureCmdlineClassic(c *C) {
+	err := s.run(classicDev, nil, map[string]interface{}{
+		"system.kernel.cmdline-append": "quiet",
+	})
+	c.Assert(err, IsNil)
+	s.checkNoUpdateTask(c)
+}
+
+func (s *kernelCmdlineSuite) TestConfigureCmdlineNoTask(c *C) {
+	err := configcore.Run(coreDev, &mockConf{
+		state: s.state,
+		changes: map[string]interface{}{
+			"system.kernel.cmdline-append": "quiet",
+		},
+	})
+	c.Assert(err, ErrorMatches, "internal error: cannot update the kernel command line outside of a change")
+	s.checkNoUpdateTask(c)
+}
+
+func (s *kernelCmdlineSuite) TestConfigureCmdlineAppendInvalid(c *C) {
+	for _, tc := range []struct {
+		cmdline string
+		errMsg  string
+	}{
+		{`root=/dev/sda1`, `cannot set "system.kernel.cmdline-append": kernel argument "root=/dev/sda1" is not allowed`},
+		{`quiet init=/bin/sh`, `cannot set "system.kernel.cmdline-append": kernel argument "init=/bin/sh" is not allowed`},
+		{`snapd_recovery_mode=install`, `cannot set "system.kernel.cmdline-append": kernel argument "snapd_recovery_mode=install" is reserved`},
+		{`console="ttyS0`, `cannot set "system.kernel.cmdline-append": unbalanced quoting`},
+	} {
+		err := s.run(coreDev, nil, map[string]interface{}{
+			"system.kernel.cmdline-append": tc.cmdline,
+		})
+		c.Check(err, ErrorMatches, tc.errMsg, Commentf(tc.cmdline))
+	}
+	s.checkNoUpdateTask(c)
+}
+
+func (s *kernelCmdlineSuite) TestConfigureCmdlineFullDangerous(c *C) {
+	restore := snapstatetest.MockDeviceModel(mockModelWithGrade("dangerous"))
+	defer restore()
+
+	err := s.run(coreDev, nil, map[string]interface{}{
+		"system.kernel.dangerous-cmdline-full": "console=ttyS0 snapd_recovery_mode=run",
+	})
+	c.Assert(err, ErrorMatches, `cannot set "system.kernel.dangerous-cmdline-full": kernel argument "snapd_recovery_mode=run" is reserved`)
+	s.checkNoUpdateTask(c)
+
+	// any arguments but those of snapd are accepted
+	err = s.run(coreDev, nil, map[string]interface{}{
+		"system.kernel.dangerous-cmdline-full": "console=ttyS0 root=/dev/sda1 foo=bar",
+		"system.kernel.cmdline-append":         "quiet",
+	})
+	c.Assert(err, IsNil)
+	s.checkUpdateTask(c, boot.SystemCommandLine{})
+}
+
+func (s *kernelCmdlineSuite) TestConfigureCmdlineFullNotDangerous(c *C) {
+	err := s.run(coreDev, nil, map[string]interface{}{
+		"system.kernel.dangerous-cmdline-full": "console=ttyS0",
+	})
+	c.Assert(err, ErrorMatches, `cannot set "system.kernel.dangerous-cmdline-full": only allowed on models of grade dangerous`)
+	s.checkNoUpdateTask(c)
+}
+
+func (s *kernelCmdlineSuite) TestConfigureCmdlineNotUC20(c *C) {
+	restore := snapstatetest.MockDeviceModel(assertstest.FakeAssertion(map[string]interface{}{
+		"type":         "model",
+		"authority-id": "canonical",
+		"series":       "16",
+		"brand-id":     "canonical",
+		"model":        "pc",
+		"architecture": "amd64",
+		"base":         "core18",
+		"kernel":       "pc-kernel",
+		"gadget":       "pc",
+	}).(*asserts.Model))
+	defer restore()
+
+	for _, opt := range []string{"system.kernel.cmdline-append", "system.kernel.dangerous-cmdline-full"} {
+		err := s.run(coreDev, nil, map[string]interface{}{
+			opt: "quiet",
+		})
+		c.Check(err, ErrorMatches, `cannot set "`+opt+`": only supported on Ubuntu Core 20 and later`)
+	}
+	s.checkNoUpdateTask(c)
+}
+
+func (s *kernelCmdlineSuite) TestConfigureCmdlineNotSeeded(c *C) {
+	s.state.Lock()
+	s.state.Set("seeded", false)
+	s.state.Unlock()
+
+	// the options cannot be set from the gadget defaults
+	for _, opt := range []string{"system.kernel.cmdline-append", "system.kernel.dangerous-cmdline-full"} {
+		err := s.run(coreDev, nil, map[string]interface{}{
+			opt: "quiet",
+		})
+		c.Check(err, ErrorMatches, `cannot set "`+opt+`": not supported in gadget defaults`)
+	}
+	s.checkNoUpdateTask(c)
+}
+
+func (s *kernelCmdlineSuite) TestConfigureCmdlineBootloaderNotManaged(c *C) {
+	bootloader.Force(bootloadertest.Mock("mock", c.MkDir()))
+
+	err := s.run(coreDev, nil, map[string]interface{}{
+		"system.kernel.cmdline-append": "quiet",
+	})
+	c.Check(err, ErrorMatches, `cannot set "system.kernel.cmdline-append": the bootloader does not support setting the kernel command line`)
+	s.checkNoUpdateTask(c)
+}
@@ -0,0 +1,268 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package configcore
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/osutil"
+	"github.com/snapcore/snapd/overlord/configstate/config"
+	"github.com/snapcore/snapd/sysconfig"
+)
+
+func init() {
+	// add supported configuration of this module
+	supportedConfigurations["core.system.locale"] = true
+	supportedConfigurations["core.system.keyboard"] = true
+}
+
+// locales are named language[_territory][.codeset][@modifier], or C
+// and POSIX
+var validLocale = regexp.MustCompile(`^(C|POSIX|[a-z]{2,3}(_[A-Z]{2})?)(\.[a-zA-Z0-9-]+)?(@[a-zA-Z0-9]+)?$`).MatchString
+
+// the keyboard is given as an XKB layout with an optional variant, as
+// in "de" or "de:nodeadkeys"
+var validKeyboard = regexp.MustCompile(`^[a-z][a-z0-9_-]*(:[a-zA-Z0-9_-]+)?$`).MatchString
+
+// defaultKeyboardModel is the generic keyboard model that fits most
+// keyboards
+const defaultKeyboardModel = "pc105"
+
+func validateLocaleSettings(tr config.ConfGetter) error {
+	locale, err := coreCfg(tr, "system.locale")
+	if err != nil {
+		return err
+	}
+	if locale != "" && !validLocale(locale) {
+		return fmt.Errorf("cannot set locale %q: name not valid", locale)
+	}
+
+	keyboard, err := coreCfg(tr, "system.keyboard")
+	if err != nil {
+		return err
+	}
+	if keyboard != "" && !validKeyboard(keyboard) {
+		return fmt.Errorf("cannot set keyboard %q: layout not valid", keyboard)
+	}
+
+	return nil
+}
+
+func handleLocaleConfiguration(_ sysconfig.Device, tr config.ConfGetter, opts *fsOnlyContext) error {
+	locale, err := coreCfg(tr, "system.locale")
+	if err != nil {
+		return err
+	}
+	keyboard, err := coreCfg(tr, "system.keyboard")
+	if err != nil {
+		return err
+	}
+
+	rootDir := dirs.GlobalRootDir
+	if opts != nil {
+		rootDir = opts.RootDir
+	}
+
+	if locale != "" {
+		if err := checkLocaleAvailable(rootDir, locale); err != nil {
+			return err
+		}
+		if err := setLocale(locale, opts); err != nil {
+			return err
+		}
+	}
+	if keyboard != "" {
+		layout, variant := splitKeyboard(keyboard)
+		if err := checkKeyboardAvailable(rootDir, layout, variant); err != nil {
+			return fmt.Errorf("cannot set keyboard %q: %v", keyboard, err)
+		}
+		if err := setKeyboard(layout, variant, opts); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func splitKeyboard(keyboard string) (layout, variant string) {
+	if i := strings.IndexByte(keyboard, ':'); i >= 0 {
+		return keyboard[:i], keyboard[i+1:]
+	}
+	return keyboard, ""
+}
+
+// normalizeCodeset returns the locale with its codeset spelled the way
+// the compiled locales are named, as in "en_US.utf8" for "en_US.UTF-8"
+func normalizeCodeset(locale string) string {
+	i := strings.IndexByte(locale, '.')
+	if i < 0 {
+		return locale
+	}
+	codeset, modifier := locale[i+1:], ""
+	if j := strings.IndexByte(codeset, '@'); j >= 0 {
+		codeset, modifier = codeset[:j], codeset[j:]
+	}
+	codeset = strings.ToLower(strings.Replace(codeset, "-", "", -1))
+	return locale[:i+1] + codeset + modifier
+}
+
+// checkLocaleAvailable checks that the locale is either compiled under
+// /usr/lib/locale or one of the supported locales that can be generated.
+func checkLocaleAvailable(rootDir, locale string) error {
+	if locale == "C" || locale == "POSIX" {
+		return nil
+	}
+	for _, name := range []string{locale, normalizeCodeset(locale)} {
+		if osutil.IsDirectory(filepath.Join(rootDir, "/usr/lib/locale", name)) {
+			return nil
+		}
+	}
+
+	f, err := os.Open(filepath.Join(rootDir, "/usr/share/i18n/SUPPORTED"))
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("cannot read supported locales: %v", err)
+	}
+	if err == nil {
+		defer f.Close()
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			fields := strings.Fields(scanner.Text())
+			if len(fields) > 0 && fields[0] == locale {
+				return nil
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			return fmt.Errorf("cannot read supported locales: %v", err)
+		}
+	}
+
+	return fmt.Errorf("cannot set locale %q: locale not available", locale)
+}
+
+// checkKeyboardAvailable checks that the layout and variant are listed
+// in the XKB rules, which is where localectl looks them up too.
+func checkKeyboardAvailable(rootDir, layout, variant string) error {
+	f, err := os.Open(filepath.Join(rootDir, "/usr/share/X11/xkb/rules/base.lst"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return fmt.Errorf("layout not available")
+		}
+		return fmt.Errorf("cannot read keyboard layouts: %v", err)
+	}
+	defer f.Close()
+
+	// the file has sections started by "! <name>", the layouts are
+	// listed as "<layout> <description>" and the variants as
+	// "<variant> <layout>: <description>"
+	foundLayout, foundVariant := false, variant == ""
+	section := ""
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		if fields[0] == "!" {
+			if len(fields) > 1 {
+				section = fields[1]
+			}
+			continue
+		}
+		switch section {
+		case "layout":
+			if fields[0] == layout {
+				foundLayout = true
+			}
+		case "variant":
+			if fields[0] == variant && len(fields) > 1 && fields[1] == layout+":" {
+				foundVariant = true
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("cannot read keyboard layouts: %v", err)
+	}
+
+	if !foundLayout {
+		return fmt.Errorf("layout not available")
+	}
+	if !foundVariant {
+		return fmt.Errorf("variant not available")
+	}
+	return nil
+}
+
+func setLocale(locale string, opts *fsOnlyContext) error {
+	// runtime system
+	if opts == nil {
+		output, err := exec.Command("localectl", "set-locale", "LANG="+locale).CombinedOutput()
+		if err != nil {
+			return fmt.Errorf("cannot set locale: %v", osutil.OutputErr(output, err))
+		}
+		return nil
+	}
+
+	// Like /etc/hostname, on the Ubuntu Core images /etc/default/locale
+	// is a symlink to /etc/writable/locale, set the file in
+	// /etc/writable for this to work.
+	localePath := filepath.Join(opts.RootDir, "/etc/writable/locale")
+	if err := os.MkdirAll(filepath.Dir(localePath), 0755); err != nil {
+		return err
+	}
+	if err := osutil.AtomicWriteFile(localePath, []byte(fmt.Sprintf("LANG=%q\n", locale)), 0644, 0); err != nil {
+		return fmt.Errorf("cannot write locale: %v", err)
+	}
+	return nil
+}
+
+func setKeyboard(layout, variant string, opts *fsOnlyContext) error {
+	// runtime system
+	if opts == nil {
+		// the console keymap is converted from the X11 one
+		args := []string{"set-x11-keymap", layout, defaultKeyboardModel}
+		if variant != "" {
+			args = append(args, variant)
+		}
+		output, err := exec.Command("localectl", args...).CombinedOutput()
+		if err != nil {
+			return fmt.Errorf("cannot set keyboard: %v", osutil.OutputErr(output, err))
+		}
+		return nil
+	}
+
+	// as for the locale, /etc/default/keyboard is a symlink to
+	// /etc/writable/keyboard on the Ubuntu Core images
+	keyboardPath := filepath.Join(opts.RootDir, "/etc/writable/keyboard")
+	if err := os.MkdirAll(filepath.Dir(keyboardPath), 0755); err != nil {
+		return err
+	}
+	content := fmt.Sprintf("XKBMODEL=%q\nXKBLAYOUT=%q\nXKBVARIANT=%q\n", defaultKeyboardModel, layout, variant)
+	if err := osutil.AtomicWriteFile(keyboardPath, []byte(content), 0644, 0); err != nil {
+		return fmt.Errorf("cannot write keyboard: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,254 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package configcore_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/overlord/configstate/configcore"
+	"github.com/snapcore/snapd/testutil"
+)
+
+type localeSuite struct {
+	configcoreSuite
+
+	mockedLocalectl *testutil.MockCmd
+}
+
+var _ = Suite(&localeSuite{})
+
+func (s *localeSuite) SetUpTest(c *C) {
+	s.configcoreSuite.SetUpTest(c)
+
+	err := os.MkdirAll(filepath.Join(dirs.GlobalRootDir, "/etc/"), 0755)
+	c.Assert(err, IsNil)
+
+	s.mockedLocalectl = testutil.MockCommand(c, "localectl", "")
+	s.AddCleanup(s.mockedLocalectl.Restore)
+
+	mockLocaleData(c, dirs.GlobalRootDir)
+}
+
+const mockXkbBaseLst = `! model
+  pc105           Generic 105-key PC
+
+! layout
+  us              English (US)
+  de              German
+  fr              French
+
+! variant
+  nodeadkeys      de: German (no dead keys)
+  intl            us: English (US, intl., with dead keys)
+`
+
+func mockLocaleData(c *C, rootDir string) {
+	for _, locale := range []string{"C.utf8", "en_US.utf8", "de_DE.utf8", "fr_FR.utf8"} {
+		c.Assert(os.MkdirAll(filepath.Join(rootDir, "/usr/lib/locale", locale), 0755), IsNil)
+	}
+	supported := "de_DE ISO-8859-1\nast_ES.UTF-8 UTF-8\nca_ES@valencia ISO-8859-15\nsr_RS.UTF-8@latin UTF-8\n"
+	c.Assert(os.MkdirAll(filepath.Join(rootDir, "/usr/share/i18n"), 0755), IsNil)
+	c.Assert(ioutil.WriteFile(filepath.Join(rootDir, "/usr/share/i18n/SUPPORTED"), []byte(supported), 0644), IsNil)
+	c.Assert(os.MkdirAll(filepath.Join(rootDir, "/usr/share/X11/xkb/rules"), 0755), IsNil)
+	c.Assert(ioutil.WriteFile(filepath.Join(rootDir, "/usr/share/X11/xkb/rules/base.lst"), []byte(mockXkbBaseLst), 0644), IsNil)
+}
+
+func (s *localeSuite) TestConfigureLocaleInvalid(c *C) {
+	invalidLocales := []string{
+		"english", "EN_us", "en_US.", "en_US.UTF-8@", "en-US", "c", "$(reboot)",
+	}
+
+	for _, locale := range invalidLocales {
+		err := configcore.Run(coreDev, &mockConf{
+			state: s.state,
+			conf: map[string]interface{}{
+				"system.locale": locale,
+			},
+		})
+		c.Check(err, ErrorMatches, `cannot set locale .*: name not valid`, Commentf(locale))
+	}
+	c.Check(s.mockedLocalectl.Calls(), HasLen, 0)
+}
+
+func (s *localeSuite) TestConfigureLocaleIntegration(c *C) {
+	validLocales := []string{
+		"C", "POSIX", "C.UTF-8", "en_US.UTF-8", "de_DE", "ast_ES.UTF-8", "ca_ES@valencia", "sr_RS.UTF-8@latin",
+	}
+
+	for _, locale := range validLocales {
+		err := configcore.Run(coreDev, &mockConf{
+			state: s.state,
+			conf: map[string]interface{}{
+				"system.locale": locale,
+			},
+		})
+		c.Assert(err, IsNil)
+		c.Check(s.mockedLocalectl.Calls(), DeepEquals, [][]string{
+			{"localectl", "set-locale", "LANG=" + locale},
+		})
+		s.mockedLocalectl.ForgetCalls()
+	}
+}
+
+func (s *localeSuite) TestConfigureLocaleNotAvailable(c *C) {
+	for _, locale := range []string{"ja_JP.UTF-8", "de_DE.ISO-8859-15", "sr_RS.UTF-8"} {
+		err := configcore.Run(coreDev, &mockConf{
+			state: s.state,
+			conf: map[string]interface{}{
+				"system.locale": locale,
+			},
+		})
+		c.Check(err, ErrorMatches, `cannot set locale .*: locale not available`, Commentf(locale))
+	}
+	c.Check(s.mockedLocalectl.Calls(), HasLen, 0)
+}
+
+func (s *localeSuite) TestConfigureKeyboardInvalid(c *C) {
+	invalidKeyboards := []string{
+		"US", "de:", ":nodeadkeys", "de:no:dead", "de nodeadkeys", "$(reboot)",
+	}
+
+	for _, keyboard := range invalidKeyboards {
+		err := configcore.Run(coreDev, &mockConf{
+			state: s.state,
+			conf: map[string]interface{}{
+				"system.keyboard": keyboard,
+			},
+		})
+		c.Check(err, ErrorMatches, `cannot set keyboard .*: layout not valid`, Commentf(keyboard))
+	}
+	c.Check(s.mockedLocalectl.Calls(), HasLen, 0)
+}
+
+func (s *localeSuite) TestConfigureKeyboardIntegration(c *C) {
+	for _, tc := range []struct {
+		keyboard string
+		args     []string
+	}{
+		{"us", []string{"localectl", "set-x11-keymap", "us", "pc105"}},
+		{"de:nodeadkeys", []string{"localectl", "set-x11-keymap", "de", "pc105", "nodeadkeys"}},
+	} {
+		err := configcore.Run(coreDev, &mockConf{
+			state: s.state,
+			conf: map[string]interface{}{
+				"system.keyboard": tc.keyboard,
+			},
+		})
+		c.Assert(err, IsNil)
+		c.Check(s.mockedLocalectl.Calls(), DeepEquals, [][]string{tc.args})
+		s.mockedLocalectl.ForgetCalls()
+	}
+}
+
+func (s *localeSuite) TestConfigureKeyboardNotAvailable(c *C) {
+	for _, tc := range []struct {
+		keyboard string
+		err      string
+	}{
+		{"jp", `cannot set keyboard "jp": layout not available`},
+		{"de:intl", `cannot set keyboard "de:intl": variant not available`},
+		{"us:dvorak", `cannot set keyboard "us:dvorak": variant not available`},
+	} {
+		err := configcore.Run(coreDev, &mockConf{
+			state: s.state,
+			conf: map[string]interface{}{
+				"system.keyboard": tc.keyboard,
+			},
+		})
+		c.Check(err, ErrorMatches, tc.err)
+	}
+
+	// without the XKB rules no layout is known
+	c.Assert(os.Remove(filepath.Join(dirs.GlobalRootDir, "/usr/share/X11/xkb/rules/base.lst")), IsNil)
+	err := configcore.Run(coreDev, &mockConf{
+		state: s.state,
+		conf: map[string]interface{}{
+			"system.keyboard": "us",
+		},
+	})
+	c.Check(err, ErrorMatches, `cannot set keyboard "us": layout not available`)
+	c.Check(s.mockedLocalectl.Calls(), HasLen, 0)
+}
+
+func (s *localeSuite) TestConfigureLocaleAndKeyboard(c *C) {
+	err := configcore.Run(coreDev, &mockConf{
+		state: s.state,
+		conf: map[string]interface{}{
+			"system.locale":   "fr_FR.UTF-8",
+			"system.keyboard": "fr",
+		},
+	})
+	c.Assert(err, IsNil)
+	c.Check(s.mockedLocalectl.Calls(), DeepEquals, [][]string{
+		{"localectl", "set-locale", "LANG=fr_FR.UTF-8"},
+		{"localectl", "set-x11-keymap", "fr", "pc105"},
+	})
+}
+
+func (s *localeSuite) TestConfigureLocaleError(c *C) {
+	mockedLocalectl := testutil.MockCommand(c, "localectl", "echo failed; exit 1")
+	defer mockedLocalectl.Restore()
+
+	err := configcore.Run(coreDev, &mockConf{
+		state: s.state,
+		conf: map[string]interface{}{
+			"system.locale": "en_US.UTF-8",
+		},
+	})
+	c.Check(err, ErrorMatches, `cannot set locale: failed`)
+
+	err = configcore.Run(coreDev, &mockConf{
+		state: s.state,
+		conf: map[string]interface{}{
+			"system.keyboard": "us",
+		},
+	})
+	c.Check(err, ErrorMatches, `cannot set keyboard: failed`)
+}
+
+func (s *localeSuite) TestFilesystemOnlyApply(c *C) {
+	conf := configcore.PlainCoreConfig(map[string]interface{}{
+		"system.locale":   "de_DE.UTF-8",
+		"system.keyboard": "de:nodeadkeys",
+	})
+	tmpDir := c.MkDir()
+	mockLocaleData(c, tmpDir)
+	c.Assert(configcore.FilesystemOnlyApply(coreDev, tmpDir, conf), IsNil)
+
+	c.Check(filepath.Join(tmpDir, "/etc/writable/locale"), testutil.FileEquals, "LANG=\"de_DE.UTF-8\"\n")
+	c.Check(filepath.Join(tmpDir, "/etc/writable/keyboard"), testutil.FileEquals, "XKBMODEL=\"pc105\"\nXKBLAYOUT=\"de\"\nXKBVARIANT=\"nodeadkeys\"\n")
+	c.Check(s.mockedLocalectl.Calls(), HasLen, 0)
+}
+
+func (s *localeSuite) TestFilesystemOnlyApplyChecksImage(c *C) {
+	// the locales of the image are checked, not the ones of the host
+	conf := configcore.PlainCoreConfig(map[string]interface{}{
+		"system.locale": "de_DE.UTF-8",
+	})
+	tmpDir := c.MkDir()
+	err := configcore.FilesystemOnlyApply(coreDev, tmpDir, conf)
+	c.Assert(err, ErrorMatches, `cannot set locale "de_DE.UTF-8": locale not available`)
+	c.Check(filepath.Join(tmpDir, "/etc/writable/locale"), testutil.FileAbsent)
+}
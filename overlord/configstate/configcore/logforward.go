@@ -0,0 +1,55 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+// +build !nomanagers
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package configcore
+
+import (
+	"strings"
+
+	"github.com/snapcore/snapd/overlord/configstate/config"
+	"github.com/snapcore/snapd/overlord/logforwardstate"
+)
+
+func init() {
+	// add supported configuration of this module
+	supportedConfigurations["core.logs.forward.target"] = true
+	supportedConfigurations["core.logs.forward.snaps"] = true
+	supportedConfigurations["core.logs.forward.rate-limit"] = true
+	supportedConfigurations["core.logs.forward.buffer-size"] = true
+}
+
+// validateLogForwardSettings validates the options of the forwarding of
+// the logs of snap services, which is run by the log forwarding manager.
+func validateLogForwardSettings(tr config.Conf) error {
+	_, err := logforwardstate.ConfigFromCore(tr)
+	return err
+}
+
+// handleLogForwardConfiguration has the log forwarding manager pick up
+// changed options right away rather than on its next periodic ensure.
+func handleLogForwardConfiguration(tr config.Conf, opts *fsOnlyContext) error {
+	for _, k := range tr.Changes() {
+		if strings.HasPrefix(k, "core.logs.forward.") {
+			tr.State().EnsureBefore(0)
+			break
+		}
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package configcore_test
+
+import (
+	"time"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/overlord/configstate/configcore"
+	"github.com/snapcore/snapd/overlord/state"
+)
+
+type logForwardSuite struct {
+	configcoreSuite
+}
+
+var _ = Suite(&logForwardSuite{})
+
+func (s *logForwardSuite) TestConfigureLogForwardHappy(c *C) {
+	for _, conf := range []map[string]interface{}{
+		{"logs.forward.target": "syslog+tcp://logs.example.com:6514"},
+		{"logs.forward.target": "syslog://192.168.1.2"},
+		{"logs.forward.target": "http://localhost:8080/logs", "logs.forward.snaps": "foo,bar_instance"},
+		{"logs.forward.target": "https://logs.example.com/in", "logs.forward.rate-limit": "0", "logs.forward.buffer-size": "50MB"},
+		// only the target enables forwarding
+		{"logs.forward.rate-limit": "10"},
+	} {
+		err := configcore.Run(classicDev, &mockConf{
+			state: s.state,
+			conf:  conf,
+		})
+		c.Check(err, IsNil, Commentf("%v", conf))
+	}
+}
+
+func (s *logForwardSuite) TestConfigureLogForwardInvalid(c *C) {
+	for _, t := range []struct {
+		conf map[string]interface{}
+		err  string
+	}{
+		{map[string]interface{}{"logs.forward.target": "ftp://logs.example.com"}, `logs.forward.target is invalid: unsupported scheme "ftp", .*`},
+		{map[string]interface{}{"logs.forward.target": "syslog+tcp:///"}, `logs.forward.target is invalid: missing host in "syslog\+tcp:///"`},
+		{map[string]interface{}{"logs.forward.target": "syslog://host/path"}, `logs.forward.target is invalid: unexpected path, query or user in syslog target "syslog://host/path"`},
+		{map[string]interface{}{"logs.forward.target": "http://host", "logs.forward.snaps": "foo,Bar"}, `logs.forward.snaps is invalid: invalid snap name: "Bar"`},
+		{map[string]interface{}{"logs.forward.target": "http://host", "logs.forward.rate-limit": "-1"}, `logs.forward.rate-limit must be a number of entries per second, or 0 for no limit, not "-1"`},
+		{map[string]interface{}{"logs.forward.target": "http://host", "logs.forward.buffer-size": "10"}, `logs.forward.buffer-size is invalid: cannot parse "10": need a number with a unit as input`},
+	} {
+		err := configcore.Run(classicDev, &mockConf{
+			state: s.state,
+			conf:  t.conf,
+		})
+		c.Check(err, ErrorMatches, t.err, Commentf("%v", t.conf))
+	}
+}
+
+type ensureWitnessBackend struct {
+	ensureBefore []time.Duration
+}
+
+func (b *ensureWitnessBackend) Checkpoint([]byte) error            { return nil }
+func (b *ensureWitnessBackend) RequestRestart(t state.RestartType) {}
+func (b *ensureWitnessBackend) EnsureBefore(d time.Duration) {
+	b.ensureBefore = append(b.ensureBefore, d)
+}
+
+func (s *logForwardSuite) TestConfigureLogForwardEnsures(c *C) {
+	backend := &ensureWitnessBackend{}
+	st := state.New(backend)
+
+	// other options leave the manager alone
+	err := configcore.Run(classicDev, &mockConf{
+		state:   st,
+		changes: map[string]interface{}{"refresh.timer": "0:00-24:00/4"},
+	})
+	c.Assert(err, IsNil)
+	c.Check(backend.ensureBefore, HasLen, 0)
+
+	err = configcore.Run(classicDev, &mockConf{
+		state:   st,
+		conf:    map[string]interface{}{"logs.forward.target": "http://localhost:8080/logs"},
+		changes: map[string]interface{}{"logs.forward.target": "http://localhost:8080/logs"},
+	})
+	c.Assert(err, IsNil)
+	c.Check(backend.ensureBefore, DeepEquals, []time.Duration{0})
+}
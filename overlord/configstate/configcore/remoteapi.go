@@ -0,0 +1,98 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+// +build !nomanagers
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package configcore
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/osutil"
+	"github.com/snapcore/snapd/overlord/configstate/config"
+	"github.com/snapcore/snapd/strutil"
+)
+
+func init() {
+	// add supported configuration of this module
+	supportedConfigurations["core.remote-api.listen-address"] = true
+	supportedConfigurations["core.remote-api.allowed-fingerprints"] = true
+}
+
+// validateRemoteAPISettings validates the options of the remote
+// management listener, which is set up by the daemon on startup.
+func validateRemoteAPISettings(tr config.Conf) error {
+	address, err := coreCfg(tr, "remote-api.listen-address")
+	if err != nil {
+		return err
+	}
+	if address != "" {
+		_, port, err := net.SplitHostPort(address)
+		if err != nil {
+			return fmt.Errorf("remote-api.listen-address must be of the form host:port: %v", err)
+		}
+		if n, err := strconv.ParseUint(port, 10, 16); err != nil || n == 0 {
+			return fmt.Errorf("remote-api.listen-address has invalid port %q", port)
+		}
+	}
+
+	_, err = remoteAPIAllowedFingerprints(tr)
+	return err
+}
+
+func remoteAPIAllowedFingerprints(tr config.Conf) ([]string, error) {
+	allowed, err := coreCfg(tr, "remote-api.allowed-fingerprints")
+	if err != nil {
+		return nil, err
+	}
+	var fingerprints []string
+	for _, fp := range strutil.CommaSeparatedList(allowed) {
+		norm, err := strutil.NormalizeCertificateFingerprint(fp)
+		if err != nil {
+			return nil, fmt.Errorf("remote-api.allowed-fingerprints contains invalid SHA256 fingerprint %q", fp)
+		}
+		fingerprints = append(fingerprints, norm)
+	}
+	return fingerprints, nil
+}
+
+// handleRemoteAPIConfiguration exports the allowed client certificate
+// fingerprints to a file, which the daemon checks on every connection
+// to the remote management listener.
+func handleRemoteAPIConfiguration(tr config.Conf, opts *fsOnlyContext) error {
+	fingerprints, err := remoteAPIAllowedFingerprints(tr)
+	if err != nil {
+		return err
+	}
+	if len(fingerprints) == 0 {
+		if err := os.Remove(dirs.SnapdRemoteAPIClientsFile); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	if err := os.MkdirAll(dirs.SnapdRemoteAPIDir, 0700); err != nil {
+		return err
+	}
+	content := strings.Join(fingerprints, "\n") + "\n"
+	return osutil.AtomicWriteFile(dirs.SnapdRemoteAPIClientsFile, []byte(content), 0600, 0)
+}
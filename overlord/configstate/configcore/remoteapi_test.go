@@ -0,0 +1,89 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package configcore_test
+
+import (
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/overlord/configstate/configcore"
+	"github.com/snapcore/snapd/testutil"
+)
+
+type remoteAPISuite struct {
+	configcoreSuite
+}
+
+var _ = Suite(&remoteAPISuite{})
+
+const testFingerprint = "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"
+
+func (s *remoteAPISuite) TestConfigureRemoteAPIHappy(c *C) {
+	err := configcore.Run(classicDev, &mockConf{
+		state: s.state,
+		conf: map[string]interface{}{
+			"remote-api.listen-address":       "0.0.0.0:8443",
+			"remote-api.allowed-fingerprints": testFingerprint + ",60:30:3A:E2:2B:99:88:61:BC:E3:B2:8F:33:EE:C1:BE:75:8A:21:3C:86:C9:3C:07:6D:BE:9F:55:8C:11:C7:52",
+		},
+	})
+	c.Assert(err, IsNil)
+	// the normalized fingerprints are exported for the daemon
+	c.Check(dirs.SnapdRemoteAPIClientsFile, testutil.FileEquals, testFingerprint+"\n60303ae22b998861bce3b28f33eec1be758a213c86c93c076dbe9f558c11c752\n")
+
+	err = configcore.Run(classicDev, &mockConf{
+		state: s.state,
+		conf: map[string]interface{}{
+			"remote-api.allowed-fingerprints": "",
+		},
+	})
+	c.Assert(err, IsNil)
+	c.Check(dirs.SnapdRemoteAPIClientsFile, testutil.FileAbsent)
+}
+
+func (s *remoteAPISuite) TestConfigureRemoteAPIInvalidAddress(c *C) {
+	for _, t := range []struct {
+		address, err string
+	}{
+		{"8443", `remote-api.listen-address must be of the form host:port: .*`},
+		{"[::1]:https", `remote-api.listen-address has invalid port "https"`},
+		{":0", `remote-api.listen-address has invalid port "0"`},
+		{"localhost:99999", `remote-api.listen-address has invalid port "99999"`},
+	} {
+		err := configcore.Run(classicDev, &mockConf{
+			state: s.state,
+			conf: map[string]interface{}{
+				"remote-api.listen-address": t.address,
+			},
+		})
+		c.Check(err, ErrorMatches, t.err, Commentf(t.address))
+	}
+}
+
+func (s *remoteAPISuite) TestConfigureRemoteAPIInvalidFingerprint(c *C) {
+	for _, fp := range []string{"abcd", "not-hex", testFingerprint + "00"} {
+		err := configcore.Run(classicDev, &mockConf{
+			state: s.state,
+			conf: map[string]interface{}{
+				"remote-api.allowed-fingerprints": testFingerprint + "," + fp,
+			},
+		})
+		c.Check(err, ErrorMatches, `remote-api.allowed-fingerprints contains invalid SHA256 fingerprint ".*"`)
+	}
+}
@@ -52,10 +52,20 @@ func init() {
 	// users.create.automatic
 	addWithStateHandler(validateUsersSettings, handleUserSettings, &flags{earlyConfigFilter: earlyUsersSettingsFilter})
 
+	// remote-api.{listen-address,allowed-fingerprints}
+	addWithStateHandler(validateRemoteAPISettings, handleRemoteAPIConfiguration, nil)
+
+	// logs.forward.{target,snaps,rate-limit,buffer-size}
+	addWithStateHandler(validateLogForwardSettings, handleLogForwardConfiguration, nil)
+
+	// system.kernel.{cmdline-append,dangerous-cmdline-full}
+	addWithStateHandler(validateKernelCmdlineSettings, handleKernelCmdlineConfiguration, coreOnly)
+
 	validateOnly := &flags{validatedOnlyStateConfig: true}
 	addWithStateHandler(validateRefreshSchedule, nil, validateOnly)
 	addWithStateHandler(validateRefreshRateLimit, nil, validateOnly)
 	addWithStateHandler(validateAutomaticSnapshotsExpiration, nil, validateOnly)
+	addWithStateHandler(validateSecuritySettings, nil, validateOnly)
 }
 
 type withStateHandler struct {
@@ -0,0 +1,49 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+// +build !nomanagers
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package configcore
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/snapcore/snapd/overlord/configstate/config"
+)
+
+func init() {
+	// add supported configuration of this module
+	supportedConfigurations["core.security.setup-concurrency"] = true
+}
+
+// validateSecuritySettings validates the number of snaps whose security
+// profiles are set up in parallel, which is read by the interface manager
+// whenever it sets up profiles.
+func validateSecuritySettings(tr config.Conf) error {
+	concurrencyStr, err := coreCfg(tr, "security.setup-concurrency")
+	if err != nil {
+		return err
+	}
+	if concurrencyStr != "" {
+		if n, err := strconv.ParseUint(concurrencyStr, 10, 16); err != nil || n == 0 {
+			return fmt.Errorf("security.setup-concurrency must be a positive number, not %q", concurrencyStr)
+		}
+	}
+	return nil
+}
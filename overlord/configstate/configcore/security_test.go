@@ -0,0 +1,54 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package configcore_test
+
+import (
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/overlord/configstate/configcore"
+)
+
+type securitySuite struct {
+	configcoreSuite
+}
+
+var _ = Suite(&securitySuite{})
+
+func (s *securitySuite) TestConfigureSetupConcurrencyHappy(c *C) {
+	err := configcore.Run(classicDev, &mockConf{
+		state: s.state,
+		conf: map[string]interface{}{
+			"security.setup-concurrency": "4",
+		},
+	})
+	c.Assert(err, IsNil)
+}
+
+func (s *securitySuite) TestConfigureSetupConcurrencyInvalid(c *C) {
+	for _, value := range []string{"0", "-1", "many", "1.5"} {
+		err := configcore.Run(classicDev, &mockConf{
+			state: s.state,
+			conf: map[string]interface{}{
+				"security.setup-concurrency": value,
+			},
+		})
+		c.Check(err, ErrorMatches, `security.setup-concurrency must be a positive number, not ".*"`)
+	}
+}
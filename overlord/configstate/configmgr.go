@@ -40,6 +40,19 @@ func MockConfigcoreRun(f func(sysconfig.Device, config.Conf) error) (restore fun
 	}
 }
 
+// taskTransaction is the configuration given to configcore when running the
+// configure hook of the core snap from a task, so that follow-up tasks can
+// be added after it.
+type taskTransaction struct {
+	*config.Transaction
+	task *state.Task
+}
+
+// Task returns the task running the configure hook.
+func (tr *taskTransaction) Task() *state.Task {
+	return tr.task
+}
+
 func Init(st *state.State, hookManager *hookstate.HookManager) error {
 	delayedCrossMgrInit()
 
@@ -58,7 +71,11 @@ func Init(st *state.State, hookManager *hookstate.HookManager) error {
 			if err != nil {
 				return nil, nil, err
 			}
-			return dev, ContextTransaction(ctx), nil
+			tr := ContextTransaction(ctx)
+			if task == nil {
+				return dev, tr, nil
+			}
+			return dev, &taskTransaction{Transaction: tr, task: task}, nil
 		}()
 		if err != nil {
 			return err
@@ -22,12 +22,16 @@
 package configstate
 
 import (
+	"bytes"
 	"fmt"
 	"os"
+	"reflect"
+	"sort"
 	"time"
 
 	"github.com/snapcore/snapd/gadget"
 	"github.com/snapcore/snapd/i18n"
+	"github.com/snapcore/snapd/jsonutil"
 	"github.com/snapcore/snapd/overlord/configstate/config"
 	"github.com/snapcore/snapd/overlord/configstate/configcore"
 	"github.com/snapcore/snapd/overlord/devicestate"
@@ -35,6 +39,7 @@ import (
 	"github.com/snapcore/snapd/overlord/snapstate"
 	"github.com/snapcore/snapd/overlord/state"
 	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/snap/configschema"
 	"github.com/snapcore/snapd/sysconfig"
 )
 
@@ -92,18 +97,236 @@ func canConfigure(st *state.State, snapName string) error {
 
 // ConfigureInstalled returns a taskset to apply the given
 // configuration patch for an installed snap. It returns
-// snap.NotInstalledError if the snap is not installed.
+// snap.NotInstalledError if the snap is not installed and a
+// *configschema.ValidationError if the resulting configuration does not
+// conform to the configuration schema of the snap.
 func ConfigureInstalled(st *state.State, snapName string, patch map[string]interface{}, flags int) (*state.TaskSet, error) {
 	if err := canConfigure(st, snapName); err != nil {
 		return nil, err
 	}
 
+	if err := ValidatePatch(config.NewTransaction(st), snapName, patch); err != nil {
+		return nil, err
+	}
+
 	taskset := Configure(st, snapName, patch, flags)
 	return taskset, nil
 }
 
+// RevertConfig returns a taskset to restore the configuration of an
+// installed snap as recorded in the given revision of its configuration
+// history, or in the revision before the current one if rev is 0. The
+// configuration is restored through the configure hook like any other
+// change.
+func RevertConfig(st *state.State, snapName string, rev int) (*state.TaskSet, error) {
+	if err := canConfigure(st, snapName); err != nil {
+		return nil, err
+	}
+
+	history, err := config.History(st, snapName)
+	if err != nil {
+		return nil, err
+	}
+	var target *config.HistoryEntry
+	if rev == 0 {
+		if len(history) < 2 {
+			return nil, fmt.Errorf("snap %q has no previous configuration", RemapSnapToResponse(snapName))
+		}
+		target = history[len(history)-2]
+	} else {
+		for _, entry := range history {
+			if entry.Revision == rev {
+				target = entry
+				break
+			}
+		}
+		if target == nil {
+			return nil, fmt.Errorf("snap %q has no configuration revision %d", RemapSnapToResponse(snapName), rev)
+		}
+	}
+
+	var targetCfg map[string]interface{}
+	if target.Config != nil {
+		if err := jsonutil.DecodeWithNumber(bytes.NewReader(*target.Config), &targetCfg); err != nil {
+			return nil, fmt.Errorf("internal error: cannot unmarshal configuration: %v", err)
+		}
+	}
+	current, err := currentConfig(st, snapName)
+	if err != nil {
+		return nil, err
+	}
+	patch := replacementPatch(snapName, current, targetCfg)
+	if len(patch) == 0 {
+		return nil, fmt.Errorf("configuration of snap %q is already the one of revision %d", RemapSnapToResponse(snapName), target.Revision)
+	}
+
+	return ConfigureInstalled(st, snapName, patch, 0)
+}
+
+// ReplaceConfig returns a taskset that replaces the whole configuration
+// of the given snap with the given one, running its configure hook once.
+// It returns a nil taskset if the configuration is already the given one.
+// When the change is undone, the configure hook runs again to restore the
+// previous configuration, so that the configuration of several snaps can
+// be replaced as a whole.
+func ReplaceConfig(st *state.State, snapName string, cfg map[string]interface{}) (*state.TaskSet, error) {
+	if err := canConfigure(st, snapName); err != nil {
+		return nil, err
+	}
+
+	current, err := currentConfig(st, snapName)
+	if err != nil {
+		return nil, err
+	}
+	patch := replacementPatch(snapName, current, cfg)
+	if len(patch) == 0 {
+		return nil, nil
+	}
+	if err := ValidatePatch(config.NewTransaction(st), snapName, patch); err != nil {
+		return nil, err
+	}
+
+	// the patch restoring the options the replacement changes
+	undoPatch := make(map[string]interface{}, len(patch))
+	for key := range patch {
+		undoPatch[key] = current[key]
+	}
+	return configure(st, snapName, patch, undoPatch, 0), nil
+}
+
+// currentConfig returns the whole stored configuration of the snap.
+func currentConfig(st *state.State, snapName string) (map[string]interface{}, error) {
+	raw, err := config.GetSnapConfig(st, snapName)
+	if err != nil {
+		return nil, err
+	}
+	var current map[string]interface{}
+	if raw != nil {
+		if err := jsonutil.DecodeWithNumber(bytes.NewReader(*raw), &current); err != nil {
+			return nil, fmt.Errorf("internal error: cannot unmarshal configuration: %v", err)
+		}
+	}
+	return current, nil
+}
+
+// replacementPatch returns the patch of top-level options that turns the
+// current configuration of the snap into the target one.
+func replacementPatch(snapName string, current, target map[string]interface{}) map[string]interface{} {
+	if snapName == "core" {
+		// the options snapd keeps for its own use cannot be set, nor
+		// are they exported, so they are carried over
+		target = withOptions(target, configcore.InternalOptions(current))
+	}
+
+	patch := make(map[string]interface{})
+	for key, value := range target {
+		if cur, ok := current[key]; !ok || !reflect.DeepEqual(cur, value) {
+			patch[key] = value
+		}
+	}
+	for key := range current {
+		if _, ok := target[key]; !ok {
+			patch[key] = nil
+		}
+	}
+	return patch
+}
+
+// withOptions returns the given configuration completed with the given
+// options, the ones already in it take precedence.
+func withOptions(conf, options map[string]interface{}) map[string]interface{} {
+	merged := make(map[string]interface{}, len(conf)+len(options))
+	for name, value := range conf {
+		merged[name] = value
+	}
+	for name, value := range options {
+		cur, ok := merged[name]
+		if !ok {
+			merged[name] = value
+			continue
+		}
+		curMap, ok1 := cur.(map[string]interface{})
+		valueMap, ok2 := value.(map[string]interface{})
+		if ok1 && ok2 {
+			merged[name] = withOptions(curMap, valueMap)
+		}
+	}
+	return merged
+}
+
+// ConfigSchema returns the configuration schema shipped by the current
+// revision of the given snap, or nil if the snap does not declare one or
+// is not installed.
+func ConfigSchema(st *state.State, snapName string) (*configschema.Schema, error) {
+	// the "core" snap/pseudonym is configured internally and has no schema
+	if snapName == "core" {
+		return nil, nil
+	}
+	info, err := snapstate.CurrentInfo(st, snapName)
+	if _, ok := err.(*snap.NotInstalledError); ok {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return configschema.ReadSchema(info.MountDir())
+}
+
+// ValidatePatch checks the configuration of the snap resulting from applying
+// the given patch on top of its configuration as seen through cfg against
+// the configuration schema of the snap, if it declares one. It returns a
+// *configschema.ValidationError if the result does not conform to it.
+//
+// The state associated with cfg must be locked by the caller.
+func ValidatePatch(cfg config.Conf, snapName string, patch map[string]interface{}) error {
+	schema, err := ConfigSchema(cfg.State(), snapName)
+	if err != nil || schema == nil {
+		return err
+	}
+	var conf map[string]interface{}
+	if err := cfg.Get(snapName, "", &conf); err != nil && !config.IsNoOption(err) {
+		return err
+	}
+	return schema.ValidatePatch(conf, patch)
+}
+
+// applySchemaDefaults sets the options of the snap that are unset but have
+// a default in its configuration schema to that default.
+func applySchemaDefaults(cfg config.Conf, snapName string) error {
+	schema, err := ConfigSchema(cfg.State(), snapName)
+	if err != nil || schema == nil {
+		return err
+	}
+	var conf map[string]interface{}
+	if err := cfg.Get(snapName, "", &conf); err != nil && !config.IsNoOption(err) {
+		return err
+	}
+	withDefaults, _ := schema.WithDefaults(conf).(map[string]interface{})
+	names := make([]string, 0, len(withDefaults))
+	for name := range withDefaults {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if reflect.DeepEqual(withDefaults[name], conf[name]) {
+			continue
+		}
+		if err := cfg.Set(snapName, name, withDefaults[name]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // Configure returns a taskset to apply the given configuration patch.
 func Configure(st *state.State, snapName string, patch map[string]interface{}, flags int) *state.TaskSet {
+	return configure(st, snapName, patch, nil, flags)
+}
+
+// configure returns a taskset to apply the given configuration patch, and
+// to apply undoPatch through the configure hook again when undone if it is
+// not nil.
+func configure(st *state.State, snapName string, patch, undoPatch map[string]interface{}, flags int) *state.TaskSet {
 	summary := fmt.Sprintf(i18n.G("Run configure hook of %q snap"), snapName)
 	// regular configuration hook
 	hooksup := &hookstate.HookSetup{
@@ -126,7 +349,13 @@ func Configure(st *state.State, snapName string, patch map[string]interface{}, f
 		summary = fmt.Sprintf(i18n.G("Run configure hook of %q snap if present"), snapName)
 	}
 
-	task := hookstate.HookTask(st, summary, hooksup, contextData)
+	var undoHooksup *hookstate.HookSetup
+	if undoPatch != nil {
+		contextData["undo-patch"] = undoPatch
+		undoHooksup = hooksup
+	}
+
+	task := hookstate.HookTaskWithUndo(st, summary, hooksup, undoHooksup, contextData)
 	return state.NewTaskSet(task)
 }
 
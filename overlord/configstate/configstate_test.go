@@ -20,7 +20,11 @@
 package configstate_test
 
 import (
+	"encoding/json"
 	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"time"
 
 	. "gopkg.in/check.v1"
@@ -36,6 +40,7 @@ import (
 	"github.com/snapcore/snapd/overlord/snapstate/snapstatetest"
 	"github.com/snapcore/snapd/overlord/state"
 	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/snap/configschema"
 	"github.com/snapcore/snapd/sysconfig"
 	"github.com/snapcore/snapd/testutil"
 )
@@ -177,6 +182,205 @@ func (s *tasksetsSuite) TestConfigureInstalledConflict(c *C) {
 	c.Check(err, ErrorMatches, `snap "test-snap" has "other-change" change in progress`)
 }
 
+func (s *tasksetsSuite) TestConfigureInstalledSchema(c *C) {
+	dirs.SetRootDir(c.MkDir())
+	defer dirs.SetRootDir("")
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	snapstate.Set(s.state, "test-snap", &snapstate.SnapState{
+		Sequence: []*snap.SideInfo{
+			{RealName: "test-snap", Revision: snap.R(1)},
+		},
+		Current:  snap.R(1),
+		Active:   true,
+		SnapType: "app",
+	})
+
+	// no schema, anything goes
+	schema, err := configstate.ConfigSchema(s.state, "test-snap")
+	c.Assert(err, IsNil)
+	c.Check(schema, IsNil)
+	_, err = configstate.ConfigureInstalled(s.state, "test-snap", map[string]interface{}{"port": "abc"}, 0)
+	c.Check(err, IsNil)
+
+	metaDir := filepath.Join(dirs.SnapMountDir, "test-snap", "1", "meta")
+	c.Assert(os.MkdirAll(metaDir, 0755), IsNil)
+	c.Assert(ioutil.WriteFile(filepath.Join(metaDir, "config-schema.yaml"), []byte(`
+properties:
+  port:
+    type: integer
+`), 0644), IsNil)
+
+	schema, err = configstate.ConfigSchema(s.state, "test-snap")
+	c.Assert(err, IsNil)
+	c.Check(schema.Lookup("port").Type, Equals, "integer")
+
+	ts, err := configstate.ConfigureInstalled(s.state, "test-snap", map[string]interface{}{"port": json.Number("80")}, 0)
+	c.Assert(err, IsNil)
+	c.Check(ts.Tasks(), HasLen, 1)
+
+	_, err = configstate.ConfigureInstalled(s.state, "test-snap", map[string]interface{}{"port": "abc"}, 0)
+	c.Check(err, ErrorMatches, `invalid value for option "port": expected integer, got string "abc"`)
+	c.Check(err, FitsTypeOf, &configschema.ValidationError{})
+
+	// the resulting configuration is checked, not only the patch
+	tr := config.NewTransaction(s.state)
+	tr.Set("test-snap", "port", "abc")
+	tr.Commit()
+	_, err = configstate.ConfigureInstalled(s.state, "test-snap", map[string]interface{}{"other": "value"}, 0)
+	c.Check(err, ErrorMatches, `invalid value for option "port": expected integer, got string "abc"`)
+	_, err = configstate.ConfigureInstalled(s.state, "test-snap", map[string]interface{}{"port": nil}, 0)
+	c.Check(err, IsNil)
+
+	// the system has no schema
+	schema, err = configstate.ConfigSchema(s.state, "core")
+	c.Assert(err, IsNil)
+	c.Check(schema, IsNil)
+
+	// nor have snaps that are not installed
+	schema, err = configstate.ConfigSchema(s.state, "other-snap")
+	c.Assert(err, IsNil)
+	c.Check(schema, IsNil)
+}
+
+func (s *tasksetsSuite) TestRevertConfig(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+	snapstate.Set(s.state, "test-snap", &snapstate.SnapState{
+		Sequence: []*snap.SideInfo{
+			{RealName: "test-snap", Revision: snap.R(1)},
+		},
+		Current:  snap.R(1),
+		Active:   true,
+		SnapType: "app",
+	})
+
+	_, err := configstate.RevertConfig(s.state, "test-snap", 0)
+	c.Check(err, ErrorMatches, `snap "test-snap" has no previous configuration`)
+
+	for _, cfg := range []map[string]interface{}{
+		{"foo": "a", "bar": map[string]interface{}{"baz": 1}},
+		{"foo": "b", "bar": map[string]interface{}{"baz": 1}, "new": true},
+	} {
+		previous, err := config.GetSnapConfig(s.state, "test-snap")
+		c.Assert(err, IsNil)
+		tr := config.NewTransaction(s.state)
+		c.Assert(config.Patch(tr, "test-snap", cfg), IsNil)
+		tr.Commit()
+		c.Assert(config.RecordHistory(s.state, "test-snap", previous, "user", ""), IsNil)
+	}
+
+	for _, rev := range []int{0, 1} {
+		ts, err := configstate.RevertConfig(s.state, "test-snap", rev)
+		c.Assert(err, IsNil)
+		c.Assert(ts.Tasks(), HasLen, 1)
+		task := ts.Tasks()[0]
+		c.Check(task.Kind(), Equals, "run-hook")
+		var hookContext map[string]interface{}
+		c.Assert(task.Get("hook-context", &hookContext), IsNil)
+		// only the changed options are part of the patch
+		c.Check(hookContext["patch"], DeepEquals, map[string]interface{}{"foo": "a", "new": nil})
+	}
+
+	_, err = configstate.RevertConfig(s.state, "test-snap", 2)
+	c.Check(err, ErrorMatches, `configuration of snap "test-snap" is already the one of revision 2`)
+
+	_, err = configstate.RevertConfig(s.state, "test-snap", 3)
+	c.Check(err, ErrorMatches, `snap "test-snap" has no configuration revision 3`)
+
+	_, err = configstate.RevertConfig(s.state, "other-snap", 1)
+	c.Check(err, ErrorMatches, `snap "other-snap" is not installed`)
+}
+
+func (s *tasksetsSuite) TestReplaceConfig(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+	snapstate.Set(s.state, "test-snap", &snapstate.SnapState{
+		Sequence: []*snap.SideInfo{
+			{RealName: "test-snap", Revision: snap.R(1)},
+		},
+		Current:  snap.R(1),
+		Active:   true,
+		SnapType: "app",
+	})
+
+	tr := config.NewTransaction(s.state)
+	c.Assert(config.Patch(tr, "test-snap", map[string]interface{}{
+		"foo": "a",
+		"bar": map[string]interface{}{"baz": 1},
+		"old": true,
+	}), IsNil)
+	tr.Commit()
+
+	ts, err := configstate.ReplaceConfig(s.state, "test-snap", map[string]interface{}{
+		"foo": "b",
+		"bar": map[string]interface{}{"baz": json.Number("1")},
+		"new": map[string]interface{}{"a": "b"},
+	})
+	c.Assert(err, IsNil)
+	c.Assert(ts.Tasks(), HasLen, 1)
+	task := ts.Tasks()[0]
+	c.Check(task.Kind(), Equals, "run-hook")
+	var hookContext map[string]interface{}
+	c.Assert(task.Get("hook-context", &hookContext), IsNil)
+	// only the changed options are part of the patch
+	c.Check(hookContext["patch"], DeepEquals, map[string]interface{}{
+		"foo": "b",
+		"old": nil,
+		"new": map[string]interface{}{"a": "b"},
+	})
+	// the hook runs again on undo to restore them
+	c.Check(hookContext["undo-patch"], DeepEquals, map[string]interface{}{
+		"foo": "a",
+		"old": true,
+		"new": nil,
+	})
+	var hooksup, undoHooksup hookstate.HookSetup
+	c.Assert(task.Get("hook-setup", &hooksup), IsNil)
+	c.Assert(task.Get("undo-hook-setup", &undoHooksup), IsNil)
+	c.Check(undoHooksup, DeepEquals, hooksup)
+	c.Check(undoHooksup.Hook, Equals, "configure")
+
+	// nothing to do if the configuration is the same
+	ts, err = configstate.ReplaceConfig(s.state, "test-snap", map[string]interface{}{
+		"foo": "a",
+		"bar": map[string]interface{}{"baz": json.Number("1")},
+		"old": true,
+	})
+	c.Assert(err, IsNil)
+	c.Check(ts, IsNil)
+
+	_, err = configstate.ReplaceConfig(s.state, "other-snap", map[string]interface{}{"foo": "a"})
+	c.Check(err, ErrorMatches, `snap "other-snap" is not installed`)
+}
+
+func (s *tasksetsSuite) TestReplaceConfigSystemKeepsInternalOptions(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	tr := config.NewTransaction(s.state)
+	c.Assert(config.Patch(tr, "core", map[string]interface{}{
+		"seed.loaded":     true,
+		"system.hostname": "foo",
+		"refresh.timer":   "4:00-6:00",
+	}), IsNil)
+	tr.Commit()
+
+	ts, err := configstate.ReplaceConfig(s.state, "core", map[string]interface{}{
+		"system": map[string]interface{}{"hostname": "bar"},
+	})
+	c.Assert(err, IsNil)
+	c.Assert(ts.Tasks(), HasLen, 1)
+	var hookContext map[string]interface{}
+	c.Assert(ts.Tasks()[0].Get("hook-context", &hookContext), IsNil)
+	// the seeding state is left alone
+	c.Check(hookContext["patch"], DeepEquals, map[string]interface{}{
+		"system":  map[string]interface{}{"hostname": "bar"},
+		"refresh": nil,
+	})
+}
+
 func (s *tasksetsSuite) TestConfigureNotInstalled(c *C) {
 	patch := map[string]interface{}{"foo": "bar"}
 	s.state.Lock()
@@ -20,6 +20,7 @@
 package configstate_test
 
 import (
+	"encoding/json"
 	"io/ioutil"
 	"path/filepath"
 	"testing"
@@ -114,6 +115,123 @@ func (s *configureHandlerSuite) TestBeforeInitializesTransaction(c *C) {
 	c.Check(value, Equals, "bar")
 }
 
+func (s *configureHandlerSuite) TestBeforeUndoAppliesUndoPatch(c *C) {
+	s.context.Lock()
+	s.context.Set("patch", map[string]interface{}{"foo": "bar"})
+	s.context.Set("undo-patch", map[string]interface{}{"foo": "baz"})
+	task, _ := s.context.Task()
+	task.SetStatus(state.UndoingStatus)
+	s.context.Unlock()
+
+	c.Check(s.handler.Before(), IsNil)
+
+	s.context.Lock()
+	tr := configstate.ContextTransaction(s.context)
+	s.context.Unlock()
+
+	var value string
+	c.Check(tr.Get("test-snap", "foo", &value), IsNil)
+	c.Check(value, Equals, "baz")
+}
+
+func (s *configureHandlerSuite) TestBeforeAppliesSchemaDefaults(c *C) {
+	info := snaptest.MockSnap(c, "name: test-snap\nversion: 1\n", &snap.SideInfo{
+		RealName: "test-snap",
+		Revision: snap.R(1),
+	})
+	err := ioutil.WriteFile(filepath.Join(info.MountDir(), "meta", "config-schema.yaml"), []byte(`
+properties:
+  port:
+    type: integer
+    default: 8080
+  proxy:
+    type: object
+    properties:
+      url:
+        type: string
+      timeout:
+        type: integer
+        default: 30
+`), 0644)
+	c.Assert(err, IsNil)
+
+	s.context.Lock()
+	snapstate.Set(s.state, "test-snap", &snapstate.SnapState{
+		Active:   true,
+		Sequence: []*snap.SideInfo{{RealName: "test-snap", Revision: snap.R(1)}},
+		Current:  snap.R(1),
+		SnapType: "app",
+	})
+	s.context.Set("patch", map[string]interface{}{
+		"proxy.url": "http://proxy",
+	})
+	s.context.Unlock()
+
+	c.Check(s.handler.Before(), IsNil)
+
+	s.context.Lock()
+	tr := configstate.ContextTransaction(s.context)
+	s.context.Unlock()
+
+	var value interface{}
+	c.Check(tr.Get("test-snap", "", &value), IsNil)
+	c.Check(value, DeepEquals, map[string]interface{}{
+		"port":  json.Number("8080"),
+		"proxy": map[string]interface{}{"url": "http://proxy", "timeout": json.Number("30")},
+	})
+	// only the options that were missing are changed
+	c.Check(tr.Changes(), DeepEquals, []string{"test-snap.port", "test-snap.proxy.timeout", "test-snap.proxy.url"})
+}
+
+func (s *configureHandlerSuite) TestDoneRecordsHistory(c *C) {
+	s.state.Lock()
+	chg := s.state.NewChange("configure-snap", "...")
+	task, _ := s.context.Task()
+	chg.AddTask(task)
+	chg.Set("initiator", "user1")
+	s.state.Unlock()
+
+	s.context.Lock()
+	s.context.Set("patch", map[string]interface{}{"foo": "bar"})
+	s.context.Unlock()
+
+	c.Check(s.handler.Before(), IsNil)
+	c.Check(s.handler.Done(), IsNil)
+
+	s.context.Lock()
+	c.Check(s.context.Done(), IsNil)
+	s.context.Unlock()
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	history, err := config.History(s.state, "test-snap")
+	c.Assert(err, IsNil)
+	c.Assert(history, HasLen, 1)
+	c.Check(history[0].Revision, Equals, 1)
+	c.Check(history[0].Initiator, Equals, "user1")
+	c.Check(history[0].ChangeID, Equals, chg.ID())
+	c.Check(string(*history[0].Config), Equals, `{"foo":"bar"}`)
+}
+
+func (s *configureHandlerSuite) TestDoneRecordsHistoryEphemeral(c *C) {
+	context, err := hookstate.NewContext(nil, s.state, &hookstate.HookSetup{Snap: "test-snap"}, nil, "")
+	c.Assert(err, IsNil)
+
+	context.Lock()
+	tr := configstate.ContextTransaction(context)
+	c.Assert(tr.Set("test-snap", "foo", "bar"), IsNil)
+	c.Check(context.Done(), IsNil)
+	context.Unlock()
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	history, err := config.History(s.state, "test-snap")
+	c.Assert(err, IsNil)
+	c.Assert(history, HasLen, 1)
+	c.Check(history[0].Initiator, Equals, "snapctl")
+	c.Check(history[0].ChangeID, Equals, "")
+}
+
 func makeModel(override map[string]interface{}) *asserts.Model {
 	model := map[string]interface{}{
 		"type":         "model",
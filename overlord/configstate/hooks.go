@@ -50,8 +50,17 @@ func ContextTransaction(context *hookstate.Context) *config.Transaction {
 	tr = config.NewTransaction(context.State())
 
 	context.OnDone(func() error {
+		instanceName := context.InstanceName()
+		previous, err := config.GetSnapConfig(context.State(), instanceName)
+		if err != nil {
+			return err
+		}
 		tr.Commit()
-		if context.InstanceName() == "core" {
+		initiator, changeID := contextInitiator(context)
+		if err := config.RecordHistory(context.State(), instanceName, previous, initiator, changeID); err != nil {
+			return err
+		}
+		if instanceName == "core" {
 			// make sure the Ensure logic can process
 			// system configuration changes as soon as possible
 			context.State().EnsureBefore(0)
@@ -63,6 +72,25 @@ func ContextTransaction(context *hookstate.Context) *config.Transaction {
 	return tr
 }
 
+// contextInitiator returns who requested the configuration change applied
+// through the given context, and the change applying it if any. Changes
+// created on behalf of a user carry an "initiator"; snapctl invoked outside
+// of hooks, and changes snapd initiated on its own, are reported as such.
+func contextInitiator(context *hookstate.Context) (initiator, changeID string) {
+	task, ok := context.Task()
+	if !ok {
+		return "snapctl", ""
+	}
+	chg := task.Change()
+	if chg == nil {
+		return "snapd", ""
+	}
+	if err := chg.Get("initiator", &initiator); err != nil || initiator == "" {
+		initiator = "snapd"
+	}
+	return initiator, chg.ID()
+}
+
 func newConfigureHandler(context *hookstate.Context) hookstate.Handler {
 	return &configureHandler{context: context}
 }
@@ -109,7 +137,13 @@ func (h *configureHandler) Before() error {
 			}
 		}
 	} else {
-		if err := h.context.Get("patch", &patch); err != nil && err != state.ErrNoState {
+		key := "patch"
+		if task, ok := h.context.Task(); ok && task.Status() == state.UndoingStatus {
+			// the hook runs again to restore the previous
+			// configuration
+			key = "undo-patch"
+		}
+		if err := h.context.Get(key, &patch); err != nil && err != state.ErrNoState {
 			return err
 		}
 	}
@@ -118,7 +152,8 @@ func (h *configureHandler) Before() error {
 		return err
 	}
 
-	return nil
+	// the hook gets to see the defaults of the options left unset
+	return applySchemaDefaults(tr, instanceName)
 }
 
 // Done is called by the HookManager after the configure hook has exited
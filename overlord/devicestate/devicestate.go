@@ -800,3 +800,32 @@ func CreateRecoverySystem(st *state.State, label string) (*state.Change, error)
 	chg.AddAll(ts)
 	return chg, nil
 }
+
+// systemCommandLine returns the kernel command line arguments of the run
+// system that are set through the system configuration, nil if there are
+// none.
+func systemCommandLine(st *state.State) (*boot.SystemCommandLine, error) {
+	tr := config.NewTransaction(st)
+	var syscmdline boot.SystemCommandLine
+	if err := tr.Get("core", "system.kernel.cmdline-append", &syscmdline.Append); err != nil && !config.IsNoOption(err) {
+		return nil, err
+	}
+	if err := tr.Get("core", "system.kernel.dangerous-cmdline-full", &syscmdline.Full); err != nil && !config.IsNoOption(err) {
+		return nil, err
+	}
+	if syscmdline == (boot.SystemCommandLine{}) {
+		return nil, nil
+	}
+	return &syscmdline, nil
+}
+
+// UpdateSystemCommandLineTask returns a task that updates the kernel command
+// line of the run system with the arguments set through the system
+// configuration, resealing the encryption keys and updating the bootloader
+// as needed. The previously set arguments are restored on undo.
+// The caller is responsible for locking the state.
+func UpdateSystemCommandLineTask(st *state.State, previous *boot.SystemCommandLine) *state.Task {
+	t := st.NewTask("update-system-cmdline", i18n.G("Update kernel command line from system configuration"))
+	t.Set("previous-system-cmdline", previous)
+	return t
+}
@@ -38,6 +38,7 @@ import (
 	"github.com/snapcore/snapd/gadget"
 	"github.com/snapcore/snapd/osutil"
 	"github.com/snapcore/snapd/overlord/auth"
+	"github.com/snapcore/snapd/overlord/configstate/config"
 	"github.com/snapcore/snapd/overlord/devicestate"
 	"github.com/snapcore/snapd/overlord/devicestate/devicestatetest"
 	"github.com/snapcore/snapd/overlord/snapstate"
@@ -1609,3 +1610,218 @@ func (s *deviceMgrGadgetSuite) TestUpdateGadgetCommandlineWithFullArgs(c *C) {
 		"snapd_full_cmdline_args":  "full args",
 	})
 }
+
+func (s *deviceMgrGadgetSuite) TestUpdateGadgetCommandlineWithSystemOption(c *C) {
+	bootloader.Force(s.managedbl)
+	s.state.Lock()
+	s.setupUC20ModelWithGadget(c, "pc")
+	s.mockModeenvForMode(c, "run")
+	devicestate.SetBootOkRan(s.mgr, true)
+	s.state.Set("seeded", true)
+
+	tr := config.NewTransaction(s.state)
+	c.Assert(tr.Set("core", "system.kernel.cmdline-append", "isolcpus=1"), IsNil)
+	tr.Commit()
+
+	// mimic system state
+	m, err := boot.ReadModeenv("")
+	c.Assert(err, IsNil)
+	m.CurrentKernelCommandLines = []string{
+		"snapd_recovery_mode=run console=ttyS0 console=tty1 panic=-1 args from old gadget isolcpus=1",
+	}
+	c.Assert(m.Write(), IsNil)
+	err = s.managedbl.SetBootVars(map[string]string{
+		"snapd_extra_cmdline_args": "args from old gadget isolcpus=1",
+	})
+	c.Assert(err, IsNil)
+	s.managedbl.SetBootVarsCalls = 0
+
+	s.state.Unlock()
+
+	const update = true
+	s.testGadgetCommandlineUpdateRun(c,
+		[][]string{
+			{"meta/gadget.yaml", gadgetYaml},
+			{"cmdline.extra", "args from old gadget"},
+		},
+		[][]string{
+			{"meta/gadget.yaml", gadgetYaml},
+			{"cmdline.extra", "args from updated gadget"},
+		},
+		"", "Updated kernel command line", update)
+
+	m, err = boot.ReadModeenv("")
+	c.Assert(err, IsNil)
+	c.Check([]string(m.CurrentKernelCommandLines), DeepEquals, []string{
+		"snapd_recovery_mode=run console=ttyS0 console=tty1 panic=-1 args from old gadget isolcpus=1",
+		// arguments from the system configuration are kept
+		"snapd_recovery_mode=run console=ttyS0 console=tty1 panic=-1 args from updated gadget isolcpus=1",
+	})
+	vars, err := s.managedbl.GetBootVars("snapd_extra_cmdline_args", "snapd_full_cmdline_args")
+	c.Assert(err, IsNil)
+	c.Assert(vars, DeepEquals, map[string]string{
+		"snapd_extra_cmdline_args": "args from updated gadget isolcpus=1",
+		"snapd_full_cmdline_args":  "",
+	})
+}
+
+func (s *deviceMgrGadgetSuite) mockSystemCommandLineState(c *C) {
+	si := &snap.SideInfo{
+		RealName: "pc",
+		Revision: snap.R(33),
+		SnapID:   "foo-id",
+	}
+	snapstate.Set(s.state, "pc", &snapstate.SnapState{
+		SnapType: "gadget",
+		Sequence: []*snap.SideInfo{si},
+		Current:  si.Revision,
+		Active:   true,
+	})
+	snaptest.MockSnapWithFiles(c, pcGadgetSnapYaml, si, [][]string{
+		{"meta/gadget.yaml", gadgetYaml},
+		{"cmdline.extra", "args from gadget"},
+	})
+
+	m, err := boot.ReadModeenv("")
+	c.Assert(err, IsNil)
+	m.CurrentKernelCommandLines = []string{
+		"snapd_recovery_mode=run console=ttyS0 console=tty1 panic=-1 args from gadget",
+	}
+	c.Assert(m.Write(), IsNil)
+	err = s.managedbl.SetBootVars(map[string]string{
+		"snapd_extra_cmdline_args": "args from gadget",
+	})
+	c.Assert(err, IsNil)
+	s.managedbl.SetBootVarsCalls = 0
+
+	// the configuration was changed by the configure hook
+	tr := config.NewTransaction(s.state)
+	c.Assert(tr.Set("core", "system.kernel.cmdline-append", "isolcpus=1"), IsNil)
+	tr.Commit()
+}
+
+func (s *deviceMgrGadgetSuite) TestUpdateSystemCommandLineUndo(c *C) {
+	restore := release.MockOnClassic(false)
+	defer restore()
+
+	bootloader.Force(s.managedbl)
+	s.state.Lock()
+	s.setupUC20ModelWithGadget(c, "pc")
+	s.mockModeenvForMode(c, "run")
+	devicestate.SetBootOkRan(s.mgr, true)
+	s.state.Set("seeded", true)
+	s.mockSystemCommandLineState(c)
+
+	tsk := devicestate.UpdateSystemCommandLineTask(s.state, &boot.SystemCommandLine{})
+	terr := s.state.NewTask("error-trigger", "provoking total undo")
+	terr.WaitFor(tsk)
+	chg := s.state.NewChange("dummy", "...")
+	chg.AddTask(tsk)
+	chg.AddTask(terr)
+	s.state.Unlock()
+
+	restartCount := 0
+	s.restartObserve = func() {
+		// we want to observe restarts and mangle modeenv like
+		// devicemanager boot handling would do
+		restartCount++
+		m, err := boot.ReadModeenv("")
+		c.Assert(err, IsNil)
+		switch restartCount {
+		case 1:
+			c.Check([]string(m.CurrentKernelCommandLines), DeepEquals, []string{
+				"snapd_recovery_mode=run console=ttyS0 console=tty1 panic=-1 args from gadget",
+				"snapd_recovery_mode=run console=ttyS0 console=tty1 panic=-1 args from gadget isolcpus=1",
+			})
+			m.CurrentKernelCommandLines = []string{"snapd_recovery_mode=run console=ttyS0 console=tty1 panic=-1 args from gadget isolcpus=1"}
+		case 2:
+			c.Check([]string(m.CurrentKernelCommandLines), DeepEquals, []string{
+				"snapd_recovery_mode=run console=ttyS0 console=tty1 panic=-1 args from gadget isolcpus=1",
+				"snapd_recovery_mode=run console=ttyS0 console=tty1 panic=-1 args from gadget",
+			})
+			m.CurrentKernelCommandLines = []string{"snapd_recovery_mode=run console=ttyS0 console=tty1 panic=-1 args from gadget"}
+		default:
+			c.Fatalf("unexpected restart %v", restartCount)
+		}
+		c.Assert(m.Write(), IsNil)
+	}
+
+	s.settle(c)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	c.Assert(chg.IsReady(), Equals, true)
+	c.Check(chg.Err(), ErrorMatches, "(?s)cannot perform the following tasks.*total undo.*")
+	c.Check(tsk.Status(), Equals, state.UndoneStatus)
+	log := tsk.Log()
+	c.Assert(log, HasLen, 2)
+	c.Check(log[0], Matches, ".* Updated kernel command line")
+	c.Check(log[1], Matches, ".* Reverted kernel command line change")
+	// update was applied and then undone
+	c.Check(s.restartRequests, DeepEquals, []state.RestartType{state.RestartSystem, state.RestartSystem})
+	c.Check(restartCount, Equals, 2)
+	vars, err := s.managedbl.GetBootVars("snapd_extra_cmdline_args", "snapd_full_cmdline_args")
+	c.Assert(err, IsNil)
+	// the arguments set before are restored
+	c.Assert(vars, DeepEquals, map[string]string{
+		"snapd_extra_cmdline_args": "args from gadget",
+		"snapd_full_cmdline_args":  "",
+	})
+	c.Check(s.managedbl.SetBootVarsCalls, Equals, 2)
+}
+
+func (s *deviceMgrGadgetSuite) TestUpdateSystemCommandLineNotSeeded(c *C) {
+	restore := release.MockOnClassic(false)
+	defer restore()
+
+	bootloader.Force(s.managedbl)
+	s.state.Lock()
+	s.setupUC20ModelWithGadget(c, "pc")
+	s.mockModeenvForMode(c, "run")
+	devicestate.SetBootOkRan(s.mgr, true)
+	s.seeding()
+	s.mockSystemCommandLineState(c)
+
+	tsk := devicestate.UpdateSystemCommandLineTask(s.state, &boot.SystemCommandLine{})
+	chg := s.state.NewChange("dummy", "...")
+	chg.AddTask(tsk)
+	s.state.Unlock()
+
+	s.settle(c)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	c.Assert(chg.IsReady(), Equals, true)
+	c.Check(chg.Err(), IsNil)
+	c.Check(tsk.Status(), Equals, state.DoneStatus)
+	// nothing is done during seeding
+	c.Check(tsk.Log(), HasLen, 0)
+	c.Check(s.restartRequests, HasLen, 0)
+	c.Check(s.managedbl.SetBootVarsCalls, Equals, 0)
+}
+
+func (s *deviceMgrGadgetSuite) TestUpdateSystemCommandLineNonUC20(c *C) {
+	restore := release.MockOnClassic(false)
+	defer restore()
+
+	s.state.Lock()
+	s.setupModelWithGadget(c, "pc")
+	s.state.Set("seeded", true)
+
+	tsk := devicestate.UpdateSystemCommandLineTask(s.state, &boot.SystemCommandLine{})
+	chg := s.state.NewChange("dummy", "...")
+	chg.AddTask(tsk)
+	s.state.Unlock()
+
+	s.settle(c)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	c.Assert(chg.IsReady(), Equals, true)
+	c.Check(chg.Err(), ErrorMatches, "(?s).*internal error: cannot update the kernel command line on a system without a model grade.*")
+	c.Check(tsk.Status(), Equals, state.ErrorStatus)
+	c.Check(s.restartRequests, HasLen, 0)
+}
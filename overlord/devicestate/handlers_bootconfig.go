@@ -71,8 +71,13 @@ func (m *DeviceManager) doUpdateManagedBootConfig(t *state.Task, _ *tomb.Tomb) e
 		return fmt.Errorf("internal error: no current gadget")
 	}
 
+	syscmdline, err := systemCommandLine(st)
+	if err != nil {
+		return err
+	}
+
 	// TODO:UC20 update recovery boot config
-	updated, err := boot.UpdateManagedBootConfigs(devCtx, currentData.RootDir)
+	updated, err := boot.UpdateManagedBootConfigs(devCtx, currentData.RootDir, syscmdline)
 	if err != nil {
 		return fmt.Errorf("cannot update boot config assets: %v", err)
 	}
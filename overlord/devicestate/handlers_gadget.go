@@ -240,7 +240,11 @@ func (m *DeviceManager) updateGadgetCommandLine(t *state.Task, st *state.State,
 		}
 		gadgetData = currentGadgetData
 	}
-	updated, err = boot.UpdateCommandLineForGadgetComponent(devCtx, gadgetData.RootDir)
+	syscmdline, err := systemCommandLine(st)
+	if err != nil {
+		return false, err
+	}
+	updated, err = boot.UpdateCommandLineForGadgetComponent(devCtx, gadgetData.RootDir, syscmdline)
 	if err != nil {
 		return false, fmt.Errorf("cannot update kernel command line from gadget: %v", err)
 	}
@@ -324,3 +328,116 @@ func (m *DeviceManager) undoUpdateGadgetCommandLine(t *state.Task, _ *tomb.Tomb)
 	st.RequestRestart(state.RestartSystem)
 	return nil
 }
+
+func (m *DeviceManager) updateSystemCommandLine(t *state.Task, st *state.State, isUndo bool) (updated bool, err error) {
+	devCtx, err := DeviceCtx(st, t, nil)
+	if err != nil {
+		return false, err
+	}
+	if devCtx.Model().Grade() == asserts.ModelGradeUnset {
+		// pre UC20 system, the options cannot be set there
+		return false, fmt.Errorf("internal error: cannot update the kernel command line on a system without a model grade")
+	}
+	gadgetData, err := currentGadgetInfo(st, devCtx)
+	if err != nil {
+		return false, fmt.Errorf("cannot obtain current gadget data: %v", err)
+	}
+	if gadgetData == nil {
+		return false, fmt.Errorf("internal error: no current gadget")
+	}
+	syscmdline := &boot.SystemCommandLine{}
+	if !isUndo {
+		// when updating, the arguments come from the system configuration
+		current, err := systemCommandLine(st)
+		if err != nil {
+			return false, err
+		}
+		if current != nil {
+			syscmdline = current
+		}
+	} else {
+		// but when undoing, the arguments set before are restored
+		if err := t.Get("previous-system-cmdline", syscmdline); err != nil && err != state.ErrNoState {
+			return false, err
+		}
+	}
+	updated, err = boot.UpdateCommandLineForSystemOption(devCtx, gadgetData.RootDir, syscmdline)
+	if err != nil {
+		return false, fmt.Errorf("cannot update kernel command line from system configuration: %v", err)
+	}
+	return updated, nil
+}
+
+func (m *DeviceManager) doUpdateSystemCommandLine(t *state.Task, _ *tomb.Tomb) error {
+	if release.OnClassic {
+		return fmt.Errorf("internal error: cannot run update system kernel command line task on a classic system")
+	}
+
+	st := t.State()
+	st.Lock()
+	defer st.Unlock()
+
+	var seeded bool
+	err := st.Get("seeded", &seeded)
+	if err != nil && err != state.ErrNoState {
+		return err
+	}
+	if !seeded {
+		// do nothing during first boot & seeding
+		return nil
+	}
+
+	const isUndo = false
+	updated, err := m.updateSystemCommandLine(t, st, isUndo)
+	if err != nil {
+		return err
+	}
+	if !updated {
+		logger.Debugf("no kernel command line update from system configuration")
+		return nil
+	}
+	t.Logf("Updated kernel command line")
+
+	t.SetStatus(state.DoneStatus)
+
+	// kernel command line was updated, request a reboot to make it effective
+	st.RequestRestart(state.RestartSystem)
+	return nil
+}
+
+func (m *DeviceManager) undoUpdateSystemCommandLine(t *state.Task, _ *tomb.Tomb) error {
+	if release.OnClassic {
+		return fmt.Errorf("internal error: cannot run update system kernel command line task on a classic system")
+	}
+
+	st := t.State()
+	st.Lock()
+	defer st.Unlock()
+
+	var seeded bool
+	err := st.Get("seeded", &seeded)
+	if err != nil && err != state.ErrNoState {
+		return err
+	}
+	if !seeded {
+		// do nothing during first boot & seeding
+		return nil
+	}
+
+	const isUndo = true
+	updated, err := m.updateSystemCommandLine(t, st, isUndo)
+	if err != nil {
+		return err
+	}
+	if !updated {
+		logger.Debugf("no kernel command line update to undo")
+		return nil
+	}
+	t.Logf("Reverted kernel command line change")
+
+	t.SetStatus(state.UndoneStatus)
+
+	// kernel command line was updated, request a reboot to make it effective
+	st.RequestRestart(state.RestartSystem)
+	return nil
+}
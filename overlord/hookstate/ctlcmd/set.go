@@ -96,15 +96,16 @@ func (s *setCommand) Execute(args []string) error {
 }
 
 func (s *setCommand) setConfigSetting(context *hookstate.Context) error {
-	context.Lock()
-	tr := configstate.ContextTransaction(context)
-	context.Unlock()
+	instanceName := context.InstanceName()
 
+	var keys []string
+	patch := make(map[string]interface{}, len(s.Positional.ConfValues))
 	for _, patchValue := range s.Positional.ConfValues {
 		parts := strings.SplitN(patchValue, "=", 2)
 		if len(parts) == 1 && strings.HasSuffix(patchValue, "!") {
 			key := strings.TrimSuffix(patchValue, "!")
-			tr.Set(s.context().InstanceName(), key, nil)
+			keys = append(keys, key)
+			patch[key] = nil
 			continue
 		}
 		if len(parts) != 2 {
@@ -117,7 +118,22 @@ func (s *setCommand) setConfigSetting(context *hookstate.Context) error {
 			value = parts[1]
 		}
 
-		tr.Set(s.context().InstanceName(), key, value)
+		keys = append(keys, key)
+		patch[key] = value
+	}
+
+	context.Lock()
+	tr := configstate.ContextTransaction(context)
+	err := configstate.ValidatePatch(tr, instanceName, patch)
+	context.Unlock()
+	if err != nil {
+		return err
+	}
+
+	// apply the values in the order they were given, unsetting an
+	// option and then setting some of its sub-keys is valid
+	for _, key := range keys {
+		tr.Set(instanceName, key, patch[key])
 	}
 
 	return nil
@@ -21,15 +21,20 @@ package ctlcmd_test
 
 import (
 	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"strings"
 
 	. "gopkg.in/check.v1"
 
+	"github.com/snapcore/snapd/dirs"
 	"github.com/snapcore/snapd/interfaces"
 	"github.com/snapcore/snapd/overlord/configstate/config"
 	"github.com/snapcore/snapd/overlord/hookstate"
 	"github.com/snapcore/snapd/overlord/hookstate/ctlcmd"
 	"github.com/snapcore/snapd/overlord/hookstate/hooktest"
+	"github.com/snapcore/snapd/overlord/snapstate"
 	"github.com/snapcore/snapd/overlord/state"
 	"github.com/snapcore/snapd/snap"
 )
@@ -231,6 +236,50 @@ func (s *setSuite) TestCommandSavesDeltasOnly(c *C) {
 	c.Check(value, Equals, "test-value3")
 }
 
+func (s *setSuite) TestCommandSchema(c *C) {
+	dirs.SetRootDir(c.MkDir())
+	defer dirs.SetRootDir("")
+
+	s.mockContext.State().Lock()
+	snapstate.Set(s.mockContext.State(), "test-snap", &snapstate.SnapState{
+		Sequence: []*snap.SideInfo{{RealName: "test-snap", Revision: snap.R(1)}},
+		Current:  snap.R(1),
+		Active:   true,
+		SnapType: "app",
+	})
+	s.mockContext.State().Unlock()
+
+	metaDir := filepath.Join(dirs.SnapMountDir, "test-snap", "1", "meta")
+	c.Assert(os.MkdirAll(metaDir, 0755), IsNil)
+	c.Assert(ioutil.WriteFile(filepath.Join(metaDir, "config-schema.yaml"), []byte(`
+properties:
+  port:
+    type: integer
+  mode:
+    type: string
+    enum: [fast, safe]
+`), 0644), IsNil)
+
+	_, _, err := ctlcmd.Run(s.mockContext, []string{"set", "port=80", "mode=slow"}, 0)
+	c.Check(err, ErrorMatches, `invalid value for option "mode": "slow" is not one of "fast", "safe"`)
+
+	_, _, err = ctlcmd.Run(s.mockContext, []string{"set", "port=80", "mode=safe", "other!"}, 0)
+	c.Check(err, IsNil)
+
+	// Notify the context that we're done. This should save the config.
+	s.mockContext.Lock()
+	defer s.mockContext.Unlock()
+	c.Check(s.mockContext.Done(), IsNil)
+
+	// Only the valid values made it into the configuration.
+	var value interface{}
+	tr := config.NewTransaction(s.mockContext.State())
+	c.Check(tr.Get("test-snap", "port", &value), IsNil)
+	c.Check(value, Equals, json.Number("80"))
+	c.Check(tr.Get("test-snap", "mode", &value), IsNil)
+	c.Check(value, Equals, "safe")
+}
+
 func (s *setSuite) TestCommandWithoutContext(c *C) {
 	_, _, err := ctlcmd.Run(nil, []string{"set", "foo=bar"}, 0)
 	c.Check(err, ErrorMatches, ".*cannot set without a context.*")
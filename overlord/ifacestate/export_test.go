@@ -57,6 +57,7 @@ var (
 	AllocHotplugSeq              = allocHotplugSeq
 	AddHotplugSeqWaitTask        = addHotplugSeqWaitTask
 	AddHotplugSlot               = addHotplugSlot
+	ConnectionEventInitiator     = connectionEventInitiator
 
 	BatchConnectTasks                = batchConnectTasks
 	FirstTaskAfterBootWhenPreseeding = firstTaskAfterBootWhenPreseeding
@@ -72,6 +73,10 @@ func NewDisconnectOptsWithByHotplugSet() disconnectOpts {
 	return disconnectOpts{ByHotplug: true}
 }
 
+func NewDisconnectOptsWithAutoDisconnectSet() disconnectOpts {
+	return disconnectOpts{AutoDisconnect: true}
+}
+
 func NewConnectOptsWithDelayProfilesSet() connectOpts {
 	return connectOpts{AutoConnect: true, ByGadget: false, DelayedSetupProfiles: true}
 }
@@ -82,6 +87,12 @@ func MockRemoveStaleConnections(f func(st *state.State) error) (restore func())
 	return func() { removeStaleConnections = old }
 }
 
+func MockMaxConnectionHistory(n int) (restore func()) {
+	old := maxConnectionHistory
+	maxConnectionHistory = n
+	return func() { maxConnectionHistory = old }
+}
+
 func MockContentLinkRetryTimeout(d time.Duration) (restore func()) {
 	old := contentLinkRetryTimeout
 	contentLinkRetryTimeout = d
@@ -164,6 +175,15 @@ func MockWriteSystemKey(fn func() error) func() {
 	return func() { writeSystemKey = old }
 }
 
+// MockNumCPU mocks the function returning the number of CPUs.
+func MockNumCPU(fn func() int) func() {
+	old := numCPU
+	numCPU = fn
+	return func() { numCPU = old }
+}
+
+var SecuritySetupConcurrency = securitySetupConcurrency
+
 func (m *InterfaceManager) TransitionConnectionsCoreMigration(st *state.State, oldName, newName string) error {
 	return m.transitionConnectionsCoreMigration(st, oldName, newName)
 }
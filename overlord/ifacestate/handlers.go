@@ -522,6 +522,9 @@ func (m *InterfaceManager) doConnect(task *state.Task, _ *tomb.Tomb) error {
 		HotplugKey:       slot.HotplugKey,
 	}
 	setConns(st, conns)
+	if err := recordConnectionEvent(task, "connect", connRef, conn.Interface(), false); err != nil {
+		return err
+	}
 
 	// the dynamic attributes might have been updated by the interface's BeforeConnectPlug/Slot code,
 	// so we need to update the task for connect-plug- and connect-slot- hooks to see new values.
@@ -585,7 +588,7 @@ func (m *InterfaceManager) doDisconnect(task *state.Task, _ *tomb.Tomb) error {
 		if forget && (notConnected || noPlugOrSlot) {
 			delete(conns, cref.ID())
 			setConns(st, conns)
-			return nil
+			return recordConnectionEvent(task, "forget", &cref, conn.Interface, false)
 		}
 		return fmt.Errorf("snapd changed, please retry the operation: %v", err)
 	}
@@ -633,7 +636,7 @@ func (m *InterfaceManager) doDisconnect(task *state.Task, _ *tomb.Tomb) error {
 	}
 	setConns(st, conns)
 
-	return nil
+	return recordConnectionEvent(task, "disconnect", &cref, conn.Interface, false)
 }
 
 func (m *InterfaceManager) undoDisconnect(task *state.Task, _ *tomb.Tomb) error {
@@ -713,7 +716,7 @@ func (m *InterfaceManager) undoDisconnect(task *state.Task, _ *tomb.Tomb) error
 	conns[connRef.ID()] = &oldconn
 	setConns(st, conns)
 
-	return nil
+	return recordConnectionEvent(task, "connect", connRef, oldconn.Interface, true)
 }
 
 func (m *InterfaceManager) undoConnect(task *state.Task, _ *tomb.Tomb) error {
@@ -734,6 +737,11 @@ func (m *InterfaceManager) undoConnect(task *state.Task, _ *tomb.Tomb) error {
 		return err
 	}
 
+	var iface string
+	if conn, ok := conns[connRef.ID()]; ok {
+		iface = conn.Interface
+	}
+
 	var old connState
 	err = task.Get("old-conn", &old)
 	if err != nil && err != state.ErrNoState {
@@ -749,6 +757,9 @@ func (m *InterfaceManager) undoConnect(task *state.Task, _ *tomb.Tomb) error {
 	if err := m.repo.Disconnect(connRef.PlugRef.Snap, connRef.PlugRef.Name, connRef.SlotRef.Snap, connRef.SlotRef.Name); err != nil {
 		return err
 	}
+	if err := recordConnectionEvent(task, "disconnect", &connRef, iface, true); err != nil {
+		return err
+	}
 
 	var delayedSetupProfiles bool
 	if err := task.Get("delayed-setup-profiles", &delayedSetupProfiles); err != nil && err != state.ErrNoState {
@@ -1255,6 +1266,37 @@ func (m *InterfaceManager) doAutoConnect(task *state.Task, _ *tomb.Tomb) error {
 	return nil
 }
 
+// doDeferredConnect resolves the plug and slot of a connection requested
+// before the snaps were installed or changed by the same change, and
+// creates the tasks for connecting them.
+func (m *InterfaceManager) doDeferredConnect(task *state.Task, _ *tomb.Tomb) error {
+	st := task.State()
+	st.Lock()
+	defer st.Unlock()
+
+	plugRef, slotRef, err := getPlugAndSlotRefs(task)
+	if err != nil {
+		return err
+	}
+	connRef, err := m.repo.ResolveConnect(plugRef.Snap, plugRef.Name, slotRef.Snap, slotRef.Name)
+	if err != nil {
+		return err
+	}
+	ts, err := connect(st, connRef.PlugRef.Snap, connRef.PlugRef.Name, connRef.SlotRef.Snap, connRef.SlotRef.Name, connectOpts{})
+	if _, ok := err.(*ErrAlreadyConnected); ok {
+		task.Logf("%s:%s is already connected to %s:%s", connRef.PlugRef.Snap, connRef.PlugRef.Name, connRef.SlotRef.Snap, connRef.SlotRef.Name)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	snapstate.InjectTasks(task, ts)
+	st.EnsureBefore(0)
+
+	task.SetStatus(state.DoneStatus)
+	return nil
+}
+
 // doAutoDisconnect creates tasks for disconnecting all interfaces of a snap and running its interface hooks.
 func (m *InterfaceManager) doAutoDisconnect(task *state.Task, _ *tomb.Tomb) error {
 	st := task.State()
@@ -1479,7 +1521,7 @@ func (m *InterfaceManager) doHotplugConnect(task *state.Task, _ *tomb.Tomb) erro
 	connectTs := state.NewTaskSet()
 	for _, conn := range recreate {
 		wasAutoconnected := conns[conn.ID()].Auto
-		ts, err := connect(st, conn.PlugRef.Snap, conn.PlugRef.Name, conn.SlotRef.Snap, conn.SlotRef.Name, connectOpts{AutoConnect: wasAutoconnected})
+		ts, err := connect(st, conn.PlugRef.Snap, conn.PlugRef.Name, conn.SlotRef.Snap, conn.SlotRef.Name, connectOpts{AutoConnect: wasAutoconnected, ByHotplug: true})
 		if err != nil {
 			return fmt.Errorf("internal error: connect of %q failed: %s", conn, err)
 		}
@@ -1487,7 +1529,7 @@ func (m *InterfaceManager) doHotplugConnect(task *state.Task, _ *tomb.Tomb) erro
 	}
 	// Create connect tasks and interface hooks for new auto-connections
 	for _, conn := range newconns {
-		ts, err := connect(st, conn.PlugRef.Snap, conn.PlugRef.Name, conn.SlotRef.Snap, conn.SlotRef.Name, connectOpts{AutoConnect: true})
+		ts, err := connect(st, conn.PlugRef.Snap, conn.PlugRef.Name, conn.SlotRef.Snap, conn.SlotRef.Name, connectOpts{AutoConnect: true, ByHotplug: true})
 		if err != nil {
 			return fmt.Errorf("internal error: auto-connect of %q failed: %s", conn, err)
 		}
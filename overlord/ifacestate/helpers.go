@@ -24,6 +24,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"runtime"
 	"sort"
 	"strings"
 
@@ -37,6 +38,7 @@ import (
 	"github.com/snapcore/snapd/jsonutil"
 	"github.com/snapcore/snapd/logger"
 	"github.com/snapcore/snapd/overlord/assertstate"
+	"github.com/snapcore/snapd/overlord/configstate/config"
 	"github.com/snapcore/snapd/overlord/snapstate"
 	"github.com/snapcore/snapd/overlord/state"
 	"github.com/snapcore/snapd/snap"
@@ -111,6 +113,23 @@ func profilesNeedRegenerationImpl() bool {
 var profilesNeedRegeneration = profilesNeedRegenerationImpl
 var writeSystemKey = interfaces.WriteSystemKey
 
+var numCPU = runtime.NumCPU
+
+// securitySetupConcurrency returns the number of snaps whose security
+// profiles are set up in parallel by each backend. It can be configured
+// with core.security.setup-concurrency and defaults to the number of CPUs.
+// State must be locked by the caller.
+func securitySetupConcurrency(st *state.State) int {
+	var concurrency int
+	if err := config.NewTransaction(st).Get("core", "security.setup-concurrency", &concurrency); err != nil && !config.IsNoOption(err) {
+		logger.Noticef("cannot get security setup concurrency: %v", err)
+	}
+	if concurrency < 1 {
+		return numCPU()
+	}
+	return concurrency
+}
+
 // regenerateAllSecurityProfiles will regenerate all security profiles.
 func (m *InterfaceManager) regenerateAllSecurityProfiles(tm timings.Measurer) error {
 	// Get all the security backends
@@ -147,12 +166,15 @@ func (m *InterfaceManager) regenerateAllSecurityProfiles(tm timings.Measurer) er
 		return confinementOptions(snapst.Flags)
 	}
 
-	// For each backend:
+	concurrency := securitySetupConcurrency(m.state)
+
+	// For each backend, the profiles of all snaps are set up before
+	// moving on to the next one:
 	for _, backend := range securityBackends {
 		if backend.Name() == "" {
 			continue // Test backends have no name, skip them to simplify testing.
 		}
-		if errors := interfaces.SetupMany(m.repo, backend, snaps, confinementOpts, tm); len(errors) > 0 {
+		if errors := interfaces.SetupMany(m.repo, backend, snaps, confinementOpts, concurrency, tm); len(errors) > 0 {
 			logger.Noticef("cannot regenerate %s profiles", backend.Name())
 			for _, err := range errors {
 				logger.Noticef(err.Error())
@@ -374,6 +396,7 @@ func (m *InterfaceManager) setupSecurityByBackend(task *state.Task, snaps []*sna
 	}
 
 	st := task.State()
+	concurrency := securitySetupConcurrency(st)
 	st.Unlock()
 	defer st.Lock()
 
@@ -382,7 +405,7 @@ func (m *InterfaceManager) setupSecurityByBackend(task *state.Task, snaps []*sna
 	for _, backend := range m.repo.Backends() {
 		errs := interfaces.SetupMany(m.repo, backend, snaps, func(snapName string) interfaces.ConfinementOptions {
 			return confOpts[snapName]
-		}, tm)
+		}, concurrency, tm)
 		if len(errs) > 0 {
 			// SetupMany processes all profiles and returns all encountered errors; report just the first one
 			return errs[0]
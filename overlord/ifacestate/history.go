@@ -0,0 +1,116 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package ifacestate
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/snapcore/snapd/interfaces"
+	"github.com/snapcore/snapd/overlord/state"
+)
+
+// maxConnectionHistory is the maximum number of connection events kept
+// in the state, older events are dropped first.
+var maxConnectionHistory = 1000
+
+// ConnectionEvent records a plug and slot being connected or
+// disconnected.
+type ConnectionEvent struct {
+	Time time.Time `json:"time"`
+	// Action is either "connect", "disconnect" or "forget".
+	Action    string             `json:"action"`
+	Plug      interfaces.PlugRef `json:"plug"`
+	Slot      interfaces.SlotRef `json:"slot"`
+	Interface string             `json:"interface"`
+	// Initiator is the username or email of the authenticated user, or
+	// "uid:<uid>" of the local user, that requested the change, or one
+	// of "auto-connect", "gadget", "hotplug" and "snapd".
+	Initiator string `json:"initiator"`
+	// Reason is the summary of the change that caused the event.
+	Reason string `json:"reason,omitempty"`
+}
+
+// ConnectionHistory returns the recorded connection events, oldest
+// first.
+func ConnectionHistory(st *state.State) ([]*ConnectionEvent, error) {
+	var history []*ConnectionEvent
+	if err := st.Get("conns-history", &history); err != nil && err != state.ErrNoState {
+		return nil, fmt.Errorf("cannot obtain connection history: %v", err)
+	}
+	return history, nil
+}
+
+// connectionEventInitiator tells who caused the connect or disconnect
+// task to run from its flags and from the initiator of its change, set
+// when a user requested it.
+func connectionEventInitiator(task *state.Task) string {
+	for _, flag := range []struct {
+		name      string
+		initiator string
+	}{
+		{"by-gadget", "gadget"},
+		{"by-hotplug", "hotplug"},
+		{"auto", "auto-connect"},
+		{"auto-disconnect", "snapd"},
+	} {
+		var set bool
+		if err := task.Get(flag.name, &set); err == nil && set {
+			return flag.initiator
+		}
+	}
+	if chg := task.Change(); chg != nil {
+		var initiator string
+		if err := chg.Get("initiator", &initiator); err == nil && initiator != "" {
+			return initiator
+		}
+	}
+	return "snapd"
+}
+
+// recordConnectionEvent appends the event of the task connecting or
+// disconnecting the given plug and slot to the connection history.
+func recordConnectionEvent(task *state.Task, action string, connRef *interfaces.ConnRef, iface string, undo bool) error {
+	st := task.State()
+	history, err := ConnectionHistory(st)
+	if err != nil {
+		return err
+	}
+	event := &ConnectionEvent{
+		Time:      time.Now().UTC(),
+		Action:    action,
+		Plug:      connRef.PlugRef,
+		Slot:      connRef.SlotRef,
+		Interface: iface,
+		Initiator: connectionEventInitiator(task),
+	}
+	if chg := task.Change(); chg != nil {
+		event.Reason = chg.Summary()
+	}
+	if undo {
+		event.Reason = fmt.Sprintf("undo of %q", event.Reason)
+	}
+	history = append(history, event)
+	if len(history) > maxConnectionHistory {
+		history = history[len(history)-maxConnectionHistory:]
+	}
+	st.Set("conns-history", history)
+	return nil
+}
@@ -0,0 +1,182 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package ifacestate_test
+
+import (
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/interfaces"
+	"github.com/snapcore/snapd/interfaces/ifacetest"
+	"github.com/snapcore/snapd/overlord/ifacestate"
+	"github.com/snapcore/snapd/overlord/state"
+)
+
+var (
+	historyPlug = interfaces.PlugRef{Snap: "consumer", Name: "plug"}
+	historySlot = interfaces.SlotRef{Snap: "producer", Name: "slot"}
+)
+
+func (s *interfaceManagerSuite) runConnectChange(c *C, summary string, undo bool, setup func(chg *state.Change)) *state.Change {
+	s.state.Lock()
+	chg := s.state.NewChange("connect-snap", summary)
+	ts, err := ifacestate.Connect(s.state, "consumer", "plug", "producer", "slot")
+	c.Assert(err, IsNil)
+	chg.AddAll(ts)
+	if undo {
+		terr := s.state.NewTask("error-trigger", "provoking total undo")
+		terr.WaitAll(ts)
+		chg.AddTask(terr)
+	}
+	if setup != nil {
+		setup(chg)
+	}
+	s.state.Unlock()
+
+	s.settle(c)
+	return chg
+}
+
+func (s *interfaceManagerSuite) TestConnectionHistory(c *C) {
+	s.MockModel(c, nil)
+	s.mockIfaces(c, &ifacetest.TestInterface{InterfaceName: "test"})
+	s.mockSnap(c, consumerYaml)
+	s.mockSnap(c, producerYaml)
+	mgr := s.manager(c)
+
+	chg := s.runConnectChange(c, "Connect consumer:plug to producer:slot", false, func(chg *state.Change) {
+		chg.Set("initiator", "uid:1000")
+	})
+
+	s.state.Lock()
+	c.Assert(chg.Err(), IsNil)
+	conn, err := mgr.Repository().Connection(&interfaces.ConnRef{PlugRef: historyPlug, SlotRef: historySlot})
+	c.Assert(err, IsNil)
+	chg = s.state.NewChange("remove-snap", `Remove "consumer" snap`)
+	ts, err := ifacestate.DisconnectPriv(s.state, conn, ifacestate.NewDisconnectOptsWithAutoDisconnectSet())
+	c.Assert(err, IsNil)
+	chg.AddAll(ts)
+	s.state.Unlock()
+
+	s.settle(c)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	c.Assert(chg.Err(), IsNil)
+
+	history, err := ifacestate.ConnectionHistory(s.state)
+	c.Assert(err, IsNil)
+	c.Assert(history, HasLen, 2)
+	c.Check(history[0].Time.IsZero(), Equals, false)
+	c.Check(history[0].Time.After(history[1].Time), Equals, false)
+	c.Check(*history[0], DeepEquals, ifacestate.ConnectionEvent{
+		Time:      history[0].Time,
+		Action:    "connect",
+		Plug:      historyPlug,
+		Slot:      historySlot,
+		Interface: "test",
+		Initiator: "uid:1000",
+		Reason:    "Connect consumer:plug to producer:slot",
+	})
+	c.Check(*history[1], DeepEquals, ifacestate.ConnectionEvent{
+		Time:      history[1].Time,
+		Action:    "disconnect",
+		Plug:      historyPlug,
+		Slot:      historySlot,
+		Interface: "test",
+		Initiator: "snapd",
+		Reason:    `Remove "consumer" snap`,
+	})
+}
+
+func (s *interfaceManagerSuite) TestConnectionHistoryUndo(c *C) {
+	s.MockModel(c, nil)
+	s.mockIfaces(c, &ifacetest.TestInterface{InterfaceName: "test"})
+	s.mockSnap(c, consumerYaml)
+	s.mockSnap(c, producerYaml)
+	_ = s.manager(c)
+
+	chg := s.runConnectChange(c, "Connect consumer:plug to producer:slot", true, nil)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	c.Assert(chg.Status(), Equals, state.ErrorStatus)
+
+	history, err := ifacestate.ConnectionHistory(s.state)
+	c.Assert(err, IsNil)
+	c.Assert(history, HasLen, 2)
+	c.Check(history[0].Action, Equals, "connect")
+	c.Check(history[0].Reason, Equals, "Connect consumer:plug to producer:slot")
+	c.Check(history[1].Action, Equals, "disconnect")
+	c.Check(history[1].Interface, Equals, "test")
+	c.Check(history[1].Initiator, Equals, "snapd")
+	c.Check(history[1].Reason, Equals, `undo of "Connect consumer:plug to producer:slot"`)
+}
+
+func (s *interfaceManagerSuite) TestConnectionHistoryIsBounded(c *C) {
+	restore := ifacestate.MockMaxConnectionHistory(3)
+	defer restore()
+
+	s.MockModel(c, nil)
+	s.mockIfaces(c, &ifacetest.TestInterface{InterfaceName: "test"})
+	s.mockSnap(c, consumerYaml)
+	s.mockSnap(c, producerYaml)
+	_ = s.manager(c)
+
+	// every undone connection adds two events
+	for _, summary := range []string{"first", "second"} {
+		s.runConnectChange(c, summary, true, nil)
+	}
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	history, err := ifacestate.ConnectionHistory(s.state)
+	c.Assert(err, IsNil)
+	c.Assert(history, HasLen, 3)
+	c.Check(history[0].Reason, Equals, `undo of "first"`)
+	c.Check(history[1].Reason, Equals, "second")
+	c.Check(history[2].Reason, Equals, `undo of "second"`)
+}
+
+func (s *interfaceManagerSuite) TestConnectionEventInitiator(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	chg := s.state.NewChange("connect-snap", "...")
+	for _, t := range []struct {
+		flag      string
+		initiator string
+	}{
+		{"by-gadget", "gadget"},
+		{"by-hotplug", "hotplug"},
+		{"auto", "auto-connect"},
+		{"auto-disconnect", "snapd"},
+	} {
+		task := s.state.NewTask("connect", "...")
+		task.Set(t.flag, true)
+		chg.AddTask(task)
+		c.Check(ifacestate.ConnectionEventInitiator(task), Equals, t.initiator, Commentf("%s", t.flag))
+	}
+
+	task := s.state.NewTask("connect", "...")
+	c.Check(ifacestate.ConnectionEventInitiator(task), Equals, "snapd")
+	chg.AddTask(task)
+	chg.Set("initiator", "uid:0")
+	c.Check(ifacestate.ConnectionEventInitiator(task), Equals, "uid:0")
+}
@@ -102,6 +102,7 @@ func Manager(s *state.State, hookManager *hookstate.HookManager, runner *state.T
 	addHandler("discard-conns", m.doDiscardConns, m.undoDiscardConns)
 	addHandler("auto-connect", m.doAutoConnect, m.undoAutoConnect)
 	addHandler("auto-disconnect", m.doAutoDisconnect, nil)
+	addHandler("deferred-connect", m.doDeferredConnect, nil)
 	addHandler("hotplug-add-slot", m.doHotplugAddSlot, nil)
 	addHandler("hotplug-connect", m.doHotplugConnect, nil)
 	addHandler("hotplug-update-slot", m.doHotplugUpdateSlot, nil)
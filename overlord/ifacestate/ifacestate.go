@@ -82,6 +82,7 @@ func findSymmetricAutoconnectTask(st *state.State, plugSnap, slotSnap string, in
 type connectOpts struct {
 	ByGadget    bool
 	AutoConnect bool
+	ByHotplug   bool
 
 	DelayedSetupProfiles bool
 }
@@ -96,6 +97,18 @@ func Connect(st *state.State, plugSnap, plugName, slotSnap, slotName string) (*s
 	return connect(st, plugSnap, plugName, slotSnap, slotName, connectOpts{})
 }
 
+// DeferredConnect returns a set of tasks for connecting an interface of
+// snaps that are installed or changed by earlier tasks of the same
+// change. The plug and slot are resolved, and the connect tasks are
+// created, only once those tasks are done.
+func DeferredConnect(st *state.State, plugSnap, plugName, slotSnap, slotName string) *state.TaskSet {
+	summary := fmt.Sprintf(i18n.G("Prepare connection of %s:%s to %s:%s"), plugSnap, plugName, slotSnap, slotName)
+	task := st.NewTask("deferred-connect", summary)
+	task.Set("plug", interfaces.PlugRef{Snap: plugSnap, Name: plugName})
+	task.Set("slot", interfaces.SlotRef{Snap: slotSnap, Name: slotName})
+	return state.NewTaskSet(task)
+}
+
 func connect(st *state.State, plugSnap, plugName, slotSnap, slotName string, flags connectOpts) (*state.TaskSet, error) {
 	// TODO: Store the intent-to-connect in the state so that we automatically
 	// try to reconnect on reboot (reconnection can fail or can connect with
@@ -205,6 +218,9 @@ func connect(st *state.State, plugSnap, plugName, slotSnap, slotName string, fla
 	if flags.ByGadget {
 		connectInterface.Set("by-gadget", true)
 	}
+	if flags.ByHotplug {
+		connectInterface.Set("by-hotplug", true)
+	}
 	if flags.DelayedSetupProfiles {
 		connectInterface.Set("delayed-setup-profiles", true)
 	}
@@ -531,6 +547,7 @@ func delayedCrossMgrInit() {
 		// hook into conflict checks mechanisms
 		snapstate.AddAffectedSnapsByKind("connect", connectDisconnectAffectedSnaps)
 		snapstate.AddAffectedSnapsByKind("disconnect", connectDisconnectAffectedSnaps)
+		snapstate.AddAffectedSnapsByKind("deferred-connect", connectDisconnectAffectedSnaps)
 	})
 }
 
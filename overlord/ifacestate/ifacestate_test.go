@@ -43,6 +43,7 @@ import (
 	"github.com/snapcore/snapd/osutil"
 	"github.com/snapcore/snapd/overlord"
 	"github.com/snapcore/snapd/overlord/assertstate"
+	"github.com/snapcore/snapd/overlord/configstate/config"
 	"github.com/snapcore/snapd/overlord/hookstate"
 	"github.com/snapcore/snapd/overlord/ifacestate"
 	"github.com/snapcore/snapd/overlord/ifacestate/ifacerepo"
@@ -3482,6 +3483,23 @@ func (s *interfaceManagerSuite) TestSetupSecurityByBackendInvalidNumberOfSnaps(c
 	c.Check(err, ErrorMatches, `internal error: setupSecurityByBackend received an unexpected number of snaps.*`)
 }
 
+func (s *interfaceManagerSuite) TestSecuritySetupConcurrency(c *C) {
+	restore := ifacestate.MockNumCPU(func() int { return 3 })
+	defer restore()
+
+	st := s.state
+	st.Lock()
+	defer st.Unlock()
+
+	// defaults to the number of CPUs
+	c.Check(ifacestate.SecuritySetupConcurrency(st), Equals, 3)
+
+	tr := config.NewTransaction(st)
+	tr.Set("core", "security.setup-concurrency", 8)
+	tr.Commit()
+	c.Check(ifacestate.SecuritySetupConcurrency(st), Equals, 8)
+}
+
 // setup-profiles uses the new snap.Info when setting up security for the new
 // snap when it had prior connections and DisconnectSnap() returns it as a part
 // of the affected set.
@@ -3990,6 +4008,79 @@ func (s *interfaceManagerSuite) TestConnectSetsUpSecurity(c *C) {
 	c.Check(s.secBackend.SetupCalls[1].Options, Equals, interfaces.ConfinementOptions{})
 }
 
+func (s *interfaceManagerSuite) TestDeferredConnect(c *C) {
+	s.MockModel(c, nil)
+
+	s.mockIfaces(c, &ifacetest.TestInterface{InterfaceName: "test"}, &ifacetest.TestInterface{InterfaceName: "test2"})
+	s.mockSnap(c, consumerYaml)
+	s.mockSnap(c, producerYaml)
+	_ = s.manager(c)
+
+	s.state.Lock()
+	// the slot is resolved when the task runs
+	ts := ifacestate.DeferredConnect(s.state, "consumer", "plug", "producer", "")
+	c.Assert(ts.Tasks(), HasLen, 1)
+	task := ts.Tasks()[0]
+	c.Check(task.Kind(), Equals, "deferred-connect")
+	c.Check(task.Summary(), Equals, "Prepare connection of consumer:plug to producer:")
+	change := s.state.NewChange("batch", "")
+	change.AddAll(ts)
+	s.state.Unlock()
+
+	s.settle(c)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	c.Assert(change.Err(), IsNil)
+	c.Check(change.Status(), Equals, state.DoneStatus)
+	var connect *state.Task
+	for _, t := range change.Tasks() {
+		if t.Kind() == "connect" {
+			connect = t
+		}
+	}
+	c.Assert(connect, NotNil)
+	c.Check(connect.WaitTasks(), testutil.Contains, task)
+
+	conns, err := s.manager(c).Repository().Connections("consumer")
+	c.Assert(err, IsNil)
+	c.Check(conns, DeepEquals, []*interfaces.ConnRef{{
+		PlugRef: interfaces.PlugRef{Snap: "consumer", Name: "plug"},
+		SlotRef: interfaces.SlotRef{Snap: "producer", Name: "slot"},
+	}})
+}
+
+func (s *interfaceManagerSuite) TestDeferredConnectAlreadyConnected(c *C) {
+	s.MockModel(c, nil)
+
+	s.mockIfaces(c, &ifacetest.TestInterface{InterfaceName: "test"}, &ifacetest.TestInterface{InterfaceName: "test2"})
+	s.mockSnap(c, consumerYaml)
+	s.mockSnap(c, producerYaml)
+
+	s.state.Lock()
+	s.state.Set("conns", map[string]interface{}{
+		"consumer:plug producer:slot": map[string]interface{}{"interface": "test"},
+	})
+	s.state.Unlock()
+	_ = s.manager(c)
+
+	s.state.Lock()
+	change := s.state.NewChange("batch", "")
+	change.AddAll(ifacestate.DeferredConnect(s.state, "consumer", "plug", "producer", "slot"))
+	s.state.Unlock()
+
+	s.settle(c)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	c.Assert(change.Err(), IsNil)
+	c.Check(change.Status(), Equals, state.DoneStatus)
+	c.Check(change.Tasks(), HasLen, 1)
+	c.Check(strings.Join(change.Tasks()[0].Log(), ""), Matches, `.*consumer:plug is already connected to producer:slot`)
+}
+
 func (s *interfaceManagerSuite) TestConnectSetsHotplugKeyFromTheSlot(c *C) {
 	s.MockModel(c, nil)
 
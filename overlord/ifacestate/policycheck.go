@@ -0,0 +1,196 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package ifacestate
+
+import (
+	"fmt"
+
+	"github.com/snapcore/snapd/asserts"
+	"github.com/snapcore/snapd/interfaces"
+	"github.com/snapcore/snapd/interfaces/policy"
+	"github.com/snapcore/snapd/overlord/assertstate"
+	"github.com/snapcore/snapd/overlord/snapstate"
+	"github.com/snapcore/snapd/snap"
+)
+
+// PolicyCheck is the outcome of evaluating one of the policy checks
+// governing a connection.
+type PolicyCheck struct {
+	// Kind is one of "plug-installation", "slot-installation",
+	// "connection" and "auto-connection".
+	Kind    string
+	Allowed bool
+	// Rule describes the declaration rule that decided the check, it
+	// is empty if no rule applies.
+	Rule string
+	// Explanation tells which alternative of the rule matched and on
+	// which constraints, or why none of them did.
+	Explanation string
+	// Error explains why the check failed.
+	Error string
+}
+
+func newPolicyCheck(kind string, explanation *policy.Explanation, err error) *PolicyCheck {
+	check := &PolicyCheck{
+		Kind:    kind,
+		Allowed: err == nil,
+	}
+	if explanation != nil {
+		check.Rule = explanation.Rule
+		check.Explanation = explanation.String()
+	}
+	if err != nil {
+		check.Error = err.Error()
+	}
+	return check
+}
+
+// CheckPolicy evaluates the installation, connection and
+// auto-connection policies for the given plug and slot, reporting for
+// each the rule that decided it.
+//
+// The plug and slot snaps are taken from snaps when present there,
+// matched by instance name, which allows to check snaps that are not
+// installed, or otherwise are the installed ones.
+//
+// The snap declarations of the plug and slot snaps are taken from
+// snapDecls when present there, matched by snap name, or otherwise from
+// the assertion database. Snaps without snap-id and without a given
+// declaration are checked against the base declaration only.
+func (m *InterfaceManager) CheckPolicy(plugRef *interfaces.PlugRef, slotRef *interfaces.SlotRef, snaps []*snap.Info, snapDecls []*asserts.SnapDeclaration) ([]*PolicyCheck, error) {
+	st := m.state
+	st.Lock()
+	defer st.Unlock()
+
+	snapInfo := func(instanceName string) (*snap.Info, error) {
+		var info *snap.Info
+		for _, given := range snaps {
+			if given.InstanceName() == instanceName {
+				info = given
+				break
+			}
+		}
+		current, err := snapstate.CurrentInfo(st, instanceName)
+		if err != nil && info == nil {
+			return nil, err
+		}
+		if info == nil {
+			info = current
+		} else if info.SnapID == "" && current != nil {
+			// look up the stored declaration of the installed snap
+			info.SnapID = current.SnapID
+		}
+		if err := addImplicitSlots(st, info); err != nil {
+			return nil, err
+		}
+		return info, nil
+	}
+	plugSnap, err := snapInfo(plugRef.Snap)
+	if err != nil {
+		return nil, err
+	}
+	plugInfo := plugSnap.Plugs[plugRef.Name]
+	if plugInfo == nil {
+		return nil, fmt.Errorf("snap %q has no plug named %q", plugRef.Snap, plugRef.Name)
+	}
+	slotSnap, err := snapInfo(slotRef.Snap)
+	if err != nil {
+		return nil, err
+	}
+	slotInfo := slotSnap.Slots[slotRef.Name]
+	if slotInfo == nil {
+		return nil, fmt.Errorf("snap %q has no slot named %q", slotRef.Snap, slotRef.Name)
+	}
+	if plugInfo.Interface != slotInfo.Interface {
+		return nil, fmt.Errorf("cannot check mismatched plug interface %q and slot interface %q", plugInfo.Interface, slotInfo.Interface)
+	}
+
+	deviceCtx, err := snapstate.DeviceCtx(st, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	modelAs := deviceCtx.Model()
+	var storeAs *asserts.Store
+	if modelAs.Store() != "" {
+		storeAs, err = assertstate.Store(st, modelAs.Store())
+		if err != nil && !asserts.IsNotFound(err) {
+			return nil, err
+		}
+	}
+	baseDecl, err := assertstate.BaseDeclaration(st)
+	if err != nil {
+		return nil, fmt.Errorf("internal error: cannot find base declaration: %v", err)
+	}
+
+	snapDeclaration := func(info *snap.Info) (*asserts.SnapDeclaration, error) {
+		for _, decl := range snapDecls {
+			if decl.SnapName() == info.SnapName() {
+				return decl, nil
+			}
+		}
+		if info.SnapID == "" {
+			return nil, nil
+		}
+		decl, err := assertstate.SnapDeclaration(st, info.SnapID)
+		if err != nil {
+			return nil, fmt.Errorf("cannot find snap declaration for %q: %v", info.InstanceName(), err)
+		}
+		return decl, nil
+	}
+	plugDecl, err := snapDeclaration(plugInfo.Snap)
+	if err != nil {
+		return nil, err
+	}
+	slotDecl, err := snapDeclaration(slotInfo.Snap)
+	if err != nil {
+		return nil, err
+	}
+
+	plugIc := policy.InstallCandidate{
+		Snap:            plugInfo.Snap,
+		SnapDeclaration: plugDecl,
+		BaseDeclaration: baseDecl,
+		Model:           modelAs,
+		Store:           storeAs,
+	}
+	slotIc := policy.InstallCandidate{
+		Snap:            slotInfo.Snap,
+		SnapDeclaration: slotDecl,
+		BaseDeclaration: baseDecl,
+		Model:           modelAs,
+		Store:           storeAs,
+	}
+	connc := policy.ConnectCandidate{
+		Plug:                interfaces.NewConnectedPlug(plugInfo, nil, nil),
+		PlugSnapDeclaration: plugDecl,
+		Slot:                interfaces.NewConnectedSlot(slotInfo, nil, nil),
+		SlotSnapDeclaration: slotDecl,
+		BaseDeclaration:     baseDecl,
+		Model:               modelAs,
+		Store:               storeAs,
+	}
+	_, autoConnectErr := connc.CheckAutoConnect()
+	return []*PolicyCheck{
+		newPolicyCheck("plug-installation", plugIc.ExplainPlug(plugInfo), plugIc.CheckPlug(plugInfo)),
+		newPolicyCheck("slot-installation", slotIc.ExplainSlot(slotInfo), slotIc.CheckSlot(slotInfo)),
+		newPolicyCheck("connection", connc.ExplainConnection(), connc.Check()),
+		newPolicyCheck("auto-connection", connc.ExplainAutoConnection(), autoConnectErr),
+	}, nil
+}
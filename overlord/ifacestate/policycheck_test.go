@@ -0,0 +1,186 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package ifacestate_test
+
+import (
+	"time"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/asserts"
+	"github.com/snapcore/snapd/asserts/assertstest"
+	"github.com/snapcore/snapd/interfaces"
+	"github.com/snapcore/snapd/interfaces/ifacetest"
+	"github.com/snapcore/snapd/overlord/ifacestate"
+	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/snap/snaptest"
+)
+
+func (s *interfaceManagerSuite) mockPolicyCheckSnaps(c *C) {
+	restore := assertstest.MockBuiltinBaseDeclaration([]byte(`
+type: base-declaration
+authority-id: canonical
+series: 16
+slots:
+  test:
+    allow-connection: false
+`))
+	s.AddCleanup(restore)
+	s.mockIfaces(c, &ifacetest.TestInterface{InterfaceName: "test"})
+	s.MockModel(c, nil)
+
+	s.MockSnapDecl(c, "producer", "one-publisher", nil)
+	s.mockSnap(c, producerYaml)
+	s.MockSnapDecl(c, "consumer", "one-publisher", map[string]interface{}{
+		"format": "1",
+		"plugs": map[string]interface{}{
+			"test": map[string]interface{}{
+				"allow-connection":      "true",
+				"allow-auto-connection": "false",
+			},
+		},
+	})
+	s.mockSnap(c, consumerYaml)
+}
+
+func (s *interfaceManagerSuite) TestCheckPolicy(c *C) {
+	s.mockPolicyCheckSnaps(c)
+	mgr := s.manager(c)
+
+	checks, err := mgr.CheckPolicy(&interfaces.PlugRef{Snap: "consumer", Name: "plug"}, &interfaces.SlotRef{Snap: "producer", Name: "slot"}, nil, nil)
+	c.Assert(err, IsNil)
+	c.Check(checks, DeepEquals, []*ifacestate.PolicyCheck{{
+		Kind:        "plug-installation",
+		Allowed:     true,
+		Rule:        `plug rule of interface "test" for "consumer" snap`,
+		Explanation: `alternative 1 of allow-installation in plug rule of interface "test" for "consumer" snap matched unconditionally`,
+	}, {
+		Kind:        "slot-installation",
+		Allowed:     true,
+		Rule:        `slot rule of interface "test" in the base declaration`,
+		Explanation: `alternative 1 of allow-installation in slot rule of interface "test" in the base declaration matched unconditionally`,
+	}, {
+		Kind:        "connection",
+		Allowed:     true,
+		Rule:        `plug rule of interface "test" for "consumer" snap`,
+		Explanation: `alternative 1 of allow-connection in plug rule of interface "test" for "consumer" snap matched unconditionally`,
+	}, {
+		Kind:        "auto-connection",
+		Rule:        `plug rule of interface "test" for "consumer" snap`,
+		Explanation: `no alternative of allow-auto-connection in plug rule of interface "test" for "consumer" snap matched: alternative 1: not allowed`,
+		Error:       `auto-connection not allowed by plug rule of interface "test" for "consumer" snap`,
+	}})
+}
+
+func (s *interfaceManagerSuite) TestCheckPolicyGivenSnapDeclaration(c *C) {
+	s.mockPolicyCheckSnaps(c)
+	mgr := s.manager(c)
+
+	// a declaration without rules replaces the one of the consumer
+	// snap in the assertion database
+	a, err := s.storeSigning.Sign(asserts.SnapDeclarationType, map[string]interface{}{
+		"series":       "16",
+		"snap-name":    "consumer",
+		"publisher-id": "one-publisher",
+		"snap-id":      "consumeridididididididididididid",
+		"revision":     "1",
+		"timestamp":    time.Now().Format(time.RFC3339),
+	}, nil, "")
+	c.Assert(err, IsNil)
+	decl := a.(*asserts.SnapDeclaration)
+
+	checks, err := mgr.CheckPolicy(&interfaces.PlugRef{Snap: "consumer", Name: "plug"}, &interfaces.SlotRef{Snap: "producer", Name: "slot"}, nil, []*asserts.SnapDeclaration{decl})
+	c.Assert(err, IsNil)
+	c.Check(checks, DeepEquals, []*ifacestate.PolicyCheck{{
+		Kind:    "plug-installation",
+		Allowed: true,
+	}, {
+		Kind:        "slot-installation",
+		Allowed:     true,
+		Rule:        `slot rule of interface "test" in the base declaration`,
+		Explanation: `alternative 1 of allow-installation in slot rule of interface "test" in the base declaration matched unconditionally`,
+	}, {
+		Kind:        "connection",
+		Rule:        `slot rule of interface "test" in the base declaration`,
+		Explanation: `no alternative of allow-connection in slot rule of interface "test" in the base declaration matched: alternative 1: not allowed`,
+		Error:       `connection not allowed by slot rule of interface "test"`,
+	}, {
+		Kind:        "auto-connection",
+		Allowed:     true,
+		Rule:        `slot rule of interface "test" in the base declaration`,
+		Explanation: `alternative 1 of allow-auto-connection in slot rule of interface "test" in the base declaration matched unconditionally`,
+	}})
+}
+
+func (s *interfaceManagerSuite) TestCheckPolicyGivenSnap(c *C) {
+	s.mockPolicyCheckSnaps(c)
+	mgr := s.manager(c)
+
+	// a snap which is not installed, with a declaration allowing its
+	// plug to connect to slots of the producer snap only
+	newConsumer := snaptest.MockInfo(c, `name: new-consumer
+version: 1
+plugs:
+  plug:
+    interface: test
+`, nil)
+	a, err := s.storeSigning.Sign(asserts.SnapDeclarationType, map[string]interface{}{
+		"series":       "16",
+		"snap-name":    "new-consumer",
+		"publisher-id": "one-publisher",
+		"snap-id":      "newconsumerididididididididididi",
+		"format":       "1",
+		"plugs": map[string]interface{}{
+			"test": map[string]interface{}{
+				"allow-connection": map[string]interface{}{
+					"slot-snap-id": []interface{}{"produceridididididididididididid"},
+				},
+			},
+		},
+		"revision":  "1",
+		"timestamp": time.Now().Format(time.RFC3339),
+	}, nil, "")
+	c.Assert(err, IsNil)
+	decl := a.(*asserts.SnapDeclaration)
+
+	checks, err := mgr.CheckPolicy(&interfaces.PlugRef{Snap: "new-consumer", Name: "plug"}, &interfaces.SlotRef{Snap: "producer", Name: "slot"}, []*snap.Info{newConsumer}, []*asserts.SnapDeclaration{decl})
+	c.Assert(err, IsNil)
+	c.Assert(checks, HasLen, 4)
+	c.Check(checks[2], DeepEquals, &ifacestate.PolicyCheck{
+		Kind:        "connection",
+		Allowed:     true,
+		Rule:        `plug rule of interface "test" for "new-consumer" snap`,
+		Explanation: `alternative 1 of allow-connection in plug rule of interface "test" for "new-consumer" snap matched on slot-snap-id`,
+	})
+
+	// without the snap the plug snap is looked up among the installed ones
+	_, err = mgr.CheckPolicy(&interfaces.PlugRef{Snap: "new-consumer", Name: "plug"}, &interfaces.SlotRef{Snap: "producer", Name: "slot"}, nil, []*asserts.SnapDeclaration{decl})
+	c.Check(err, ErrorMatches, `snap "new-consumer" is not installed`)
+}
+
+func (s *interfaceManagerSuite) TestCheckPolicyErrors(c *C) {
+	s.mockPolicyCheckSnaps(c)
+	mgr := s.manager(c)
+
+	_, err := mgr.CheckPolicy(&interfaces.PlugRef{Snap: "consumer", Name: "missing"}, &interfaces.SlotRef{Snap: "producer", Name: "slot"}, nil, nil)
+	c.Check(err, ErrorMatches, `snap "consumer" has no plug named "missing"`)
+	_, err = mgr.CheckPolicy(&interfaces.PlugRef{Snap: "consumer", Name: "plug"}, &interfaces.SlotRef{Snap: "producer", Name: "missing"}, nil, nil)
+	c.Check(err, ErrorMatches, `snap "producer" has no slot named "missing"`)
+}
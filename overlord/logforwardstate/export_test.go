@@ -0,0 +1,97 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package logforwardstate
+
+import (
+	"io"
+	"time"
+
+	"github.com/snapcore/snapd/systemd"
+)
+
+type (
+	Spool       = spool
+	RateLimiter = rateLimiter
+)
+
+var (
+	OpenSpool       = openSpool
+	EntryFromLog    = entryFromLog
+	NewSender       = newSender
+	SnapAppFromUnit = snapAppFromUnit
+)
+
+func (s *spool) Add(entries []*Entry, cursor string) (int, error) { return s.add(entries, cursor) }
+func (s *spool) Cursor() (string, error)                          { return s.cursor() }
+func (s *spool) Oldest() (string, []*Entry, error)                { return s.oldest() }
+func (s *spool) Remove(name string) error                         { return s.remove(name) }
+
+func NewRateLimiter(rate int) *RateLimiter { return &rateLimiter{rate: rate} }
+
+func (l *rateLimiter) Wait(n int, stop <-chan struct{}) bool { return l.wait(n, stop) }
+
+type Sender = sender
+
+func Send(s sender, entries []*Entry) error { return s.send(entries) }
+func Close(s sender) error                  { return s.close() }
+
+func MockLogReader(f func(units []string, n int, follow bool, filter *systemd.LogFilter) (io.ReadCloser, error)) (restore func()) {
+	old := logReader
+	logReader = f
+	return func() {
+		logReader = old
+	}
+}
+
+func MockTimeNow(f func() time.Time) (restore func()) {
+	old := timeNow
+	timeNow = f
+	return func() {
+		timeNow = old
+	}
+}
+
+func MockTimeAfter(f func(time.Duration) <-chan time.Time) (restore func()) {
+	old := timeAfter
+	timeAfter = f
+	return func() {
+		timeAfter = old
+	}
+}
+
+func MockIntervals(flush, minRetry, maxRetry time.Duration) (restore func()) {
+	oldFlush, oldMinRetry, oldMaxRetry := flushInterval, minRetryInterval, maxRetryInterval
+	flushInterval, minRetryInterval, maxRetryInterval = flush, minRetry, maxRetry
+	return func() {
+		flushInterval, minRetryInterval, maxRetryInterval = oldFlush, oldMinRetry, oldMaxRetry
+	}
+}
+
+func MockOsHostname(f func() (string, error)) (restore func()) {
+	old := osHostname
+	osHostname = f
+	return func() {
+		osHostname = old
+	}
+}
+
+func (m *LogForwardManager) Running() bool {
+	return m.forwarder != nil && m.forwarder.alive()
+}
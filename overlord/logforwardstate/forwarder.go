@@ -0,0 +1,441 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package logforwardstate
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/tomb.v2"
+
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/logger"
+	"github.com/snapcore/snapd/progress"
+	"github.com/snapcore/snapd/systemd"
+)
+
+var (
+	logReader = func(units []string, n int, follow bool, filter *systemd.LogFilter) (io.ReadCloser, error) {
+		return systemd.New(systemd.SystemMode, progress.Null).LogReader(units, n, follow, filter)
+	}
+
+	timeNow   = time.Now
+	timeAfter = time.After
+
+	// flushInterval is how often at most the journal cursor of the
+	// entries sent is recorded.
+	flushInterval = time.Second
+	// the interval between attempts to send entries to an unavailable
+	// target doubles from minRetryInterval up to maxRetryInterval.
+	minRetryInterval = time.Second
+	maxRetryInterval = 5 * time.Minute
+)
+
+const (
+	// maxBatch is the maximum number of entries in a segment of the
+	// disk buffer and in a single send.
+	maxBatch = 100
+	// maxQueued is the number of entries read from the journal which
+	// are kept in memory, reading the journal waits beyond that.
+	maxQueued = 10 * maxBatch
+	// defaultPriority is the priority of entries without one, info.
+	defaultPriority = 6
+)
+
+// queued is an entry read from the journal, with its cursor.
+type queued struct {
+	entry  *Entry
+	cursor string
+}
+
+// forwarder follows the journal of the services of the selected snaps and
+// sends their entries to the target. Entries are kept in memory, and only
+// written to the disk buffer while the target is unavailable.
+type forwarder struct {
+	config *Config
+	sender sender
+
+	tomb tomb.Tomb
+	// queue passes the entries read from the journal on to be sent.
+	queue chan queued
+
+	// The spool and the fields below are only used by the goroutine
+	// sending the entries.
+	spool *spool
+	// cursor is the journal cursor of the last entry sent, if it is not
+	// recorded in the spool yet, and cursorSaved is when it last was.
+	cursor      string
+	cursorSaved time.Time
+	// failing is set while the target is unavailable.
+	failing bool
+}
+
+func startForwarder(config *Config) (*forwarder, error) {
+	sp, err := openSpool(dirs.SnapdLogForwardDir, config.BufferSize)
+	if err != nil {
+		return nil, err
+	}
+	cursor, err := sp.cursor()
+	if err != nil {
+		return nil, err
+	}
+	snd, err := newSender(config.Target)
+	if err != nil {
+		return nil, err
+	}
+	f := &forwarder{
+		config:      config,
+		spool:       sp,
+		sender:      snd,
+		queue:       make(chan queued, maxQueued),
+		cursorSaved: timeNow(),
+	}
+	f.tomb.Go(func() error { return f.follow(cursor) })
+	f.tomb.Go(f.forward)
+	return f, nil
+}
+
+func (f *forwarder) alive() bool {
+	return f.tomb.Alive()
+}
+
+// stop stops the forwarding, returning the error that made it fail if
+// any.
+func (f *forwarder) stop() error {
+	f.tomb.Kill(nil)
+	err := f.tomb.Wait()
+	f.sender.close()
+	return err
+}
+
+func (f *forwarder) units() []string {
+	if len(f.config.Snaps) == 0 {
+		return []string{"snap.*"}
+	}
+	units := make([]string, len(f.config.Snaps))
+	for i, name := range f.config.Snaps {
+		units[i] = fmt.Sprintf("snap.%s.*", name)
+	}
+	return units
+}
+
+// follow reads the journal, starting after the entry with the given
+// cursor or with new entries if there is none.
+func (f *forwarder) follow(cursor string) error {
+	n := 0
+	if cursor != "" {
+		n = -1
+	}
+	reader, err := logReader(f.units(), n, true, &systemd.LogFilter{AfterCursor: cursor})
+	if err != nil {
+		return fmt.Errorf("cannot read journal: %v", err)
+	}
+	defer reader.Close()
+
+	readErr := make(chan error, 1)
+	go func() {
+		dec := json.NewDecoder(reader)
+		for {
+			var log systemd.Log
+			if err := dec.Decode(&log); err != nil {
+				readErr <- err
+				return
+			}
+			select {
+			case f.queue <- queued{entry: entryFromLog(log), cursor: log.Cursor()}:
+			case <-f.tomb.Dying():
+				return
+			}
+		}
+	}()
+
+	select {
+	case err := <-readErr:
+		if err == io.EOF {
+			return fmt.Errorf("journal reader exited unexpectedly")
+		}
+		return fmt.Errorf("cannot read journal: %v", err)
+	case <-f.tomb.Dying():
+		return nil
+	}
+}
+
+// forward sends the entries read from the journal to the target, after
+// those left in the spool, respecting the rate limit and retrying while
+// the target is unavailable.
+func (f *forwarder) forward() error {
+	limiter := &rateLimiter{rate: f.config.RateLimit}
+	batchSize := maxBatch
+	if limiter.rate > 0 && limiter.rate < batchSize {
+		batchSize = limiter.rate
+	}
+	for {
+		name, entries, err := f.spool.oldest()
+		if err != nil {
+			if name == "" {
+				return err
+			}
+			logger.Noticef("%v, dropping it", err)
+			if err := f.spool.remove(name); err != nil {
+				return err
+			}
+			continue
+		}
+		if name != "" {
+			sent, err := f.sendSpooled(limiter, batchSize, entries)
+			if err != nil || !sent {
+				return err
+			}
+			if err := f.spool.remove(name); err != nil {
+				return err
+			}
+			continue
+		}
+
+		batch, err := f.nextBatch(batchSize)
+		if err != nil || batch == nil {
+			return err
+		}
+		if !limiter.wait(len(batch), f.tomb.Dying()) {
+			// the entries are read again from the journal
+			return f.saveCursor()
+		}
+		entries = make([]*Entry, len(batch))
+		for i, q := range batch {
+			entries[i] = q.entry
+		}
+		if err := f.sender.send(entries); err != nil {
+			f.sendFailed(err)
+			// the entries are sent from the spool from now on
+			if err := f.spoolQueued(batch); err != nil {
+				return err
+			}
+			continue
+		}
+		f.failing = false
+		f.cursor = batch[len(batch)-1].cursor
+		if timeNow().Sub(f.cursorSaved) >= flushInterval {
+			if err := f.saveCursor(); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// nextBatch waits for entries read from the journal and returns up to n of
+// them. It returns nil once the forwarding is stopped.
+func (f *forwarder) nextBatch(n int) ([]queued, error) {
+	var flush <-chan time.Time
+	for {
+		if f.cursor != "" && flush == nil {
+			flush = timeAfter(flushInterval)
+		}
+		select {
+		case q := <-f.queue:
+			return f.takeQueued(q, n), nil
+		case <-flush:
+			if err := f.saveCursor(); err != nil {
+				return nil, err
+			}
+			flush = nil
+		case <-f.tomb.Dying():
+			return nil, f.saveCursor()
+		}
+	}
+}
+
+// takeQueued returns the given entry followed by those already queued, up
+// to n entries.
+func (f *forwarder) takeQueued(first queued, n int) []queued {
+	batch := []queued{first}
+	for len(batch) < n {
+		select {
+		case q := <-f.queue:
+			batch = append(batch, q)
+		default:
+			return batch
+		}
+	}
+	return batch
+}
+
+// sendSpooled sends entries of the spool, retrying while the target is
+// unavailable. It returns false if the forwarding was stopped meanwhile.
+func (f *forwarder) sendSpooled(limiter *rateLimiter, batchSize int, entries []*Entry) (bool, error) {
+	retryInterval := minRetryInterval
+	for len(entries) > 0 {
+		batch := entries
+		if len(batch) > batchSize {
+			batch = batch[:batchSize]
+		}
+		if !limiter.wait(len(batch), f.tomb.Dying()) {
+			return false, nil
+		}
+		if err := f.sender.send(batch); err != nil {
+			f.sendFailed(err)
+			if retry, err := f.spoolFor(retryInterval); err != nil || !retry {
+				return false, err
+			}
+			retryInterval *= 2
+			if retryInterval > maxRetryInterval {
+				retryInterval = maxRetryInterval
+			}
+			continue
+		}
+		f.failing = false
+		retryInterval = minRetryInterval
+		entries = entries[len(batch):]
+	}
+	return true, nil
+}
+
+func (f *forwarder) sendFailed(err error) {
+	if !f.failing {
+		logger.Noticef("cannot forward logs, will retry: %v", err)
+	}
+	f.failing = true
+}
+
+// spoolFor waits for the given duration, adding the entries read from the
+// journal meanwhile to the spool. It returns false if the forwarding was
+// stopped.
+func (f *forwarder) spoolFor(d time.Duration) (bool, error) {
+	timeout := timeAfter(d)
+	for {
+		select {
+		case q := <-f.queue:
+			if err := f.spoolQueued(f.takeQueued(q, maxBatch)); err != nil {
+				return false, err
+			}
+		case <-timeout:
+			return true, nil
+		case <-f.tomb.Dying():
+			return false, nil
+		}
+	}
+}
+
+// spoolQueued adds entries read from the journal to the spool, together
+// with the cursor of the last one.
+func (f *forwarder) spoolQueued(batch []queued) error {
+	entries := make([]*Entry, len(batch))
+	for i, q := range batch {
+		entries[i] = q.entry
+	}
+	dropped, err := f.spool.add(entries, batch[len(batch)-1].cursor)
+	if err != nil {
+		return fmt.Errorf("cannot buffer logs: %v", err)
+	}
+	if dropped > 0 {
+		logger.Noticef("log forwarding buffer is full, dropped %d entries", dropped)
+	}
+	f.cursor = ""
+	f.cursorSaved = timeNow()
+	return nil
+}
+
+// saveCursor records the cursor of the last entry sent, so that forwarding
+// resumes after it. Entries sent after the last time it was recorded are
+// sent again if snapd stops abruptly.
+func (f *forwarder) saveCursor() error {
+	if f.cursor == "" {
+		return nil
+	}
+	if _, err := f.spool.add(nil, f.cursor); err != nil {
+		return fmt.Errorf("cannot record log forwarding position: %v", err)
+	}
+	f.cursor = ""
+	f.cursorSaved = timeNow()
+	return nil
+}
+
+// rateLimiter spaces out sends so that on average at most rate entries
+// are sent per second, no limit applies if rate is 0.
+type rateLimiter struct {
+	rate int
+	next time.Time
+}
+
+// wait blocks until n entries can be sent, it returns false if stop was
+// closed meanwhile.
+func (l *rateLimiter) wait(n int, stop <-chan struct{}) bool {
+	if l.rate <= 0 {
+		return true
+	}
+	now := timeNow()
+	if l.next.Before(now) {
+		l.next = now
+	}
+	delay := l.next.Sub(now)
+	l.next = l.next.Add(time.Duration(n) * time.Second / time.Duration(l.rate))
+	if delay <= 0 {
+		return true
+	}
+	select {
+	case <-timeAfter(delay):
+		return true
+	case <-stop:
+		return false
+	}
+}
+
+// entryFromLog converts a journal entry of a snap service.
+func entryFromLog(log systemd.Log) *Entry {
+	fields := log.Fields()
+	t, _ := log.Time()
+	e := &Entry{
+		Timestamp: t,
+		Priority:  defaultPriority,
+		SID:       log.SID(),
+		PID:       log.PID(),
+		Message:   log.Message(),
+	}
+	if prio, err := strconv.Atoi(fields["PRIORITY"]); err == nil && prio >= 0 && prio <= 7 {
+		e.Priority = prio
+	}
+	// messages of systemd itself about the unit have it in UNIT
+	unit := fields["_SYSTEMD_UNIT"]
+	if !strings.HasPrefix(unit, "snap.") {
+		unit = fields["UNIT"]
+	}
+	e.Snap, e.App = snapAppFromUnit(unit)
+	return e
+}
+
+// snapAppFromUnit returns the snap and app of a unit named as
+// snap.<snap>.<app>.<type>.
+func snapAppFromUnit(unit string) (snapName, app string) {
+	if !strings.HasPrefix(unit, "snap.") {
+		return "", ""
+	}
+	unit = strings.TrimPrefix(unit, "snap.")
+	if idx := strings.LastIndexByte(unit, '.'); idx > 0 {
+		unit = unit[:idx]
+	}
+	parts := strings.SplitN(unit, ".", 2)
+	if len(parts) != 2 {
+		return "", ""
+	}
+	return parts[0], parts[1]
+}
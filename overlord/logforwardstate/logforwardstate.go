@@ -0,0 +1,174 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+// Package logforwardstate implements the manager forwarding the logs of
+// snap services to a remote syslog server or HTTP collector.
+package logforwardstate
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+
+	"github.com/snapcore/snapd/logger"
+	"github.com/snapcore/snapd/overlord/configstate/config"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/strutil"
+)
+
+const (
+	defaultRateLimit  = 100
+	defaultBufferSize = 10 * 1000 * 1000
+)
+
+// Config is the log forwarding configuration.
+type Config struct {
+	Target *Target
+	// Snaps are the snaps whose service logs are forwarded, all snaps
+	// if empty.
+	Snaps []string
+	// RateLimit is the maximum number of entries forwarded per
+	// second, 0 means no limit.
+	RateLimit int
+	// BufferSize is the maximum size in bytes of the disk buffer of
+	// entries which could not be forwarded yet, the oldest entries are
+	// dropped when it is exceeded.
+	BufferSize int64
+}
+
+func coreOption(tr config.ConfGetter, key string) (string, error) {
+	var v interface{} = ""
+	if err := tr.Get("core", key, &v); err != nil && !config.IsNoOption(err) {
+		return "", err
+	}
+	return fmt.Sprintf("%v", v), nil
+}
+
+// ConfigFromCore returns the log forwarding configuration given by the
+// core logs.forward.* options, or nil if no target is set.
+func ConfigFromCore(tr config.ConfGetter) (*Config, error) {
+	targetStr, err := coreOption(tr, "logs.forward.target")
+	if err != nil {
+		return nil, err
+	}
+	if targetStr == "" {
+		return nil, nil
+	}
+	conf := &Config{
+		RateLimit:  defaultRateLimit,
+		BufferSize: defaultBufferSize,
+	}
+	conf.Target, err = ParseTarget(targetStr)
+	if err != nil {
+		return nil, fmt.Errorf("logs.forward.target is invalid: %v", err)
+	}
+
+	snaps, err := coreOption(tr, "logs.forward.snaps")
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range strutil.CommaSeparatedList(snaps) {
+		if err := snap.ValidateInstanceName(name); err != nil {
+			return nil, fmt.Errorf("logs.forward.snaps is invalid: %v", err)
+		}
+		conf.Snaps = append(conf.Snaps, name)
+	}
+
+	rateLimit, err := coreOption(tr, "logs.forward.rate-limit")
+	if err != nil {
+		return nil, err
+	}
+	if rateLimit != "" {
+		n, err := strconv.ParseUint(rateLimit, 10, 31)
+		if err != nil {
+			return nil, fmt.Errorf("logs.forward.rate-limit must be a number of entries per second, or 0 for no limit, not %q", rateLimit)
+		}
+		conf.RateLimit = int(n)
+	}
+
+	bufferSize, err := coreOption(tr, "logs.forward.buffer-size")
+	if err != nil {
+		return nil, err
+	}
+	if bufferSize != "" {
+		conf.BufferSize, err = strutil.ParseByteSize(bufferSize)
+		if err != nil {
+			return nil, fmt.Errorf("logs.forward.buffer-size is invalid: %v", err)
+		}
+	}
+
+	return conf, nil
+}
+
+// LogForwardManager runs the forwarding of the logs of snap services as
+// configured by the core logs.forward.* options.
+type LogForwardManager struct {
+	state *state.State
+
+	config    *Config
+	forwarder *forwarder
+}
+
+// Manager returns a new LogForwardManager.
+func Manager(st *state.State) *LogForwardManager {
+	return &LogForwardManager{state: st}
+}
+
+// Ensure is part of the overlord.StateManager interface. It starts,
+// restarts or stops the forwarding whenever its configuration changed,
+// and restarts it if it failed.
+func (m *LogForwardManager) Ensure() error {
+	m.state.Lock()
+	conf, err := ConfigFromCore(config.NewTransaction(m.state))
+	m.state.Unlock()
+	if err != nil {
+		return err
+	}
+
+	if m.forwarder != nil {
+		if m.forwarder.alive() && reflect.DeepEqual(conf, m.config) {
+			return nil
+		}
+		if err := m.forwarder.stop(); err != nil {
+			logger.Noticef("log forwarding stopped: %v", err)
+		}
+		m.forwarder = nil
+	}
+	m.config = conf
+	if conf == nil {
+		return nil
+	}
+	m.forwarder, err = startForwarder(conf)
+	if err != nil {
+		return fmt.Errorf("cannot start log forwarding: %v", err)
+	}
+	return nil
+}
+
+// Stop is part of the overlord.StateStopper interface.
+func (m *LogForwardManager) Stop() {
+	if m.forwarder == nil {
+		return
+	}
+	if err := m.forwarder.stop(); err != nil {
+		logger.Noticef("log forwarding stopped: %v", err)
+	}
+	m.forwarder = nil
+}
@@ -0,0 +1,390 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package logforwardstate_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/overlord/configstate/config"
+	"github.com/snapcore/snapd/overlord/logforwardstate"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/systemd"
+	"github.com/snapcore/snapd/testutil"
+)
+
+func Test(t *testing.T) { TestingT(t) }
+
+type journalCall struct {
+	units  []string
+	n      int
+	follow bool
+	filter systemd.LogFilter
+}
+
+type logForwardSuite struct {
+	testutil.BaseTest
+
+	state *state.State
+	srv   *httptest.Server
+
+	mu       sync.Mutex
+	calls    []journalCall
+	journals []*io.PipeWriter
+	statuses []int
+	received []*logforwardstate.Entry
+}
+
+var _ = Suite(&logForwardSuite{})
+
+func (s *logForwardSuite) SetUpTest(c *C) {
+	s.BaseTest.SetUpTest(c)
+
+	dirs.SetRootDir(c.MkDir())
+	s.AddCleanup(func() { dirs.SetRootDir("") })
+	s.AddCleanup(logforwardstate.MockIntervals(10*time.Millisecond, 10*time.Millisecond, 50*time.Millisecond))
+
+	s.state = state.New(nil)
+	s.calls = nil
+	s.journals = nil
+	s.statuses = nil
+	s.received = nil
+
+	s.AddCleanup(logforwardstate.MockLogReader(func(units []string, n int, follow bool, filter *systemd.LogFilter) (io.ReadCloser, error) {
+		r, w := io.Pipe()
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.calls = append(s.calls, journalCall{units: units, n: n, follow: follow, filter: *filter})
+		s.journals = append(s.journals, w)
+		return r, nil
+	}))
+
+	s.srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if len(s.statuses) > 0 {
+			status := s.statuses[0]
+			s.statuses = s.statuses[1:]
+			if status != 200 {
+				w.WriteHeader(status)
+				return
+			}
+		}
+		var entries []*logforwardstate.Entry
+		c.Check(json.NewDecoder(r.Body).Decode(&entries), IsNil)
+		s.received = append(s.received, entries...)
+	}))
+	s.AddCleanup(s.srv.Close)
+}
+
+func (s *logForwardSuite) setConfig(c *C, conf map[string]interface{}) {
+	s.state.Lock()
+	defer s.state.Unlock()
+	tr := config.NewTransaction(s.state)
+	for k, v := range conf {
+		c.Assert(tr.Set("core", k, v), IsNil)
+	}
+	tr.Commit()
+}
+
+// waitFor polls for a condition, checking it with the lock held.
+func (s *logForwardSuite) waitFor(c *C, what string, cond func() bool) {
+	for i := 0; i < 1000; i++ {
+		s.mu.Lock()
+		ok := cond()
+		s.mu.Unlock()
+		if ok {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	c.Fatalf("timeout waiting for %s", what)
+}
+
+// spooled returns the segments of entries buffered on disk.
+func (s *logForwardSuite) spooled(c *C) []string {
+	segs, err := filepath.Glob(filepath.Join(dirs.SnapdLogForwardDir, "*.json"))
+	c.Assert(err, IsNil)
+	return segs
+}
+
+func (s *logForwardSuite) writeJournal(c *C, idx int, cursor, unit, message string) {
+	s.mu.Lock()
+	w := s.journals[idx]
+	s.mu.Unlock()
+	_, err := fmt.Fprintf(w, `{"__CURSOR": %q, "__REALTIME_TIMESTAMP": "1614592800000000", "_SYSTEMD_UNIT": %q, "SYSLOG_IDENTIFIER": "foo.svc", "_PID": "42", "PRIORITY": "3", "MESSAGE": %q}`+"\n", cursor, unit, message)
+	c.Assert(err, IsNil)
+}
+
+func (s *logForwardSuite) TestConfigFromCore(c *C) {
+	s.state.Lock()
+	conf, err := logforwardstate.ConfigFromCore(config.NewTransaction(s.state))
+	s.state.Unlock()
+	c.Assert(err, IsNil)
+	c.Check(conf, IsNil)
+
+	s.setConfig(c, map[string]interface{}{"logs.forward.target": "syslog+tcp://logs.example.com"})
+	s.state.Lock()
+	conf, err = logforwardstate.ConfigFromCore(config.NewTransaction(s.state))
+	s.state.Unlock()
+	c.Assert(err, IsNil)
+	c.Check(conf, DeepEquals, &logforwardstate.Config{
+		Target:     &logforwardstate.Target{Kind: "syslog", Network: "tcp", Address: "logs.example.com:514"},
+		RateLimit:  100,
+		BufferSize: 10 * 1000 * 1000,
+	})
+
+	s.setConfig(c, map[string]interface{}{
+		"logs.forward.snaps":       "foo, bar",
+		"logs.forward.rate-limit":  0,
+		"logs.forward.buffer-size": "1MB",
+	})
+	s.state.Lock()
+	conf, err = logforwardstate.ConfigFromCore(config.NewTransaction(s.state))
+	s.state.Unlock()
+	c.Assert(err, IsNil)
+	c.Check(conf.Snaps, DeepEquals, []string{"foo", "bar"})
+	c.Check(conf.RateLimit, Equals, 0)
+	c.Check(conf.BufferSize, Equals, int64(1000*1000))
+}
+
+func (s *logForwardSuite) TestEnsureDisabled(c *C) {
+	mgr := logforwardstate.Manager(s.state)
+	c.Assert(mgr.Ensure(), IsNil)
+	c.Check(mgr.Running(), Equals, false)
+	c.Check(s.calls, HasLen, 0)
+	mgr.Stop()
+}
+
+func (s *logForwardSuite) TestForward(c *C) {
+	s.setConfig(c, map[string]interface{}{
+		"logs.forward.target": s.srv.URL,
+		"logs.forward.snaps":  "foo",
+	})
+	mgr := logforwardstate.Manager(s.state)
+	defer mgr.Stop()
+	c.Assert(mgr.Ensure(), IsNil)
+	c.Check(mgr.Running(), Equals, true)
+
+	s.waitFor(c, "journal reader", func() bool { return len(s.calls) == 1 })
+	// without cursor only new entries are read
+	c.Check(s.calls[0], DeepEquals, journalCall{units: []string{"snap.foo.*"}, n: 0, follow: true})
+
+	s.writeJournal(c, 0, "c1", "snap.foo.svc.service", "hello")
+	s.writeJournal(c, 0, "c2", "snap.foo.svc.service", "bye")
+	s.waitFor(c, "entries", func() bool { return len(s.received) == 2 })
+	c.Check(s.received[0], DeepEquals, &logforwardstate.Entry{
+		Timestamp: time.Date(2021, 3, 1, 10, 0, 0, 0, time.UTC),
+		Snap:      "foo",
+		App:       "svc",
+		Priority:  3,
+		SID:       "foo.svc",
+		PID:       "42",
+		Message:   "hello",
+	})
+	c.Check(s.received[1].Message, Equals, "bye")
+
+	// entries which could be sent are never written to disk
+	c.Check(s.spooled(c), HasLen, 0)
+
+	// nothing changed
+	c.Assert(mgr.Ensure(), IsNil)
+	c.Check(s.calls, HasLen, 1)
+
+	// forwarding resumes after the last entry read
+	mgr.Stop()
+	c.Check(mgr.Running(), Equals, false)
+	c.Assert(mgr.Ensure(), IsNil)
+	s.waitFor(c, "journal reader", func() bool { return len(s.calls) == 2 })
+	c.Check(s.calls[1], DeepEquals, journalCall{
+		units:  []string{"snap.foo.*"},
+		n:      -1,
+		follow: true,
+		filter: systemd.LogFilter{AfterCursor: "c2"},
+	})
+}
+
+func (s *logForwardSuite) TestForwardRetries(c *C) {
+	s.statuses = []int{500, 503}
+	s.setConfig(c, map[string]interface{}{"logs.forward.target": s.srv.URL})
+	mgr := logforwardstate.Manager(s.state)
+	defer mgr.Stop()
+	c.Assert(mgr.Ensure(), IsNil)
+
+	s.waitFor(c, "journal reader", func() bool { return len(s.calls) == 1 })
+	c.Check(s.calls[0].units, DeepEquals, []string{"snap.*"})
+	s.writeJournal(c, 0, "c1", "snap.foo.svc.service", "hello")
+
+	s.waitFor(c, "entries", func() bool { return len(s.received) == 1 })
+	c.Check(s.statuses, HasLen, 0)
+	c.Check(s.received[0].Message, Equals, "hello")
+}
+
+func (s *logForwardSuite) TestForwardBuffersWhileUnavailable(c *C) {
+	s.setConfig(c, map[string]interface{}{"logs.forward.target": s.srv.URL})
+	s.mu.Lock()
+	s.statuses = []int{500, 500, 500, 500, 500, 500, 500, 500, 500, 500}
+	s.mu.Unlock()
+
+	mgr := logforwardstate.Manager(s.state)
+	defer mgr.Stop()
+	c.Assert(mgr.Ensure(), IsNil)
+	s.waitFor(c, "journal reader", func() bool { return len(s.calls) == 1 })
+	s.writeJournal(c, 0, "c1", "snap.foo.svc.service", "one")
+	// the entry which cannot be sent is kept on disk
+	s.waitFor(c, "buffered entries", func() bool { return len(s.spooled(c)) == 1 })
+	s.writeJournal(c, 0, "c2", "snap.bar.daemon.service", "two")
+
+	// everything is eventually delivered in order
+	s.waitFor(c, "entries", func() bool { return len(s.received) == 2 })
+	c.Check(s.received[0].Message, Equals, "one")
+	c.Check(s.received[1].Message, Equals, "two")
+	c.Check(s.received[1].Snap, Equals, "bar")
+	s.waitFor(c, "buffer emptied", func() bool { return len(s.spooled(c)) == 0 })
+	c.Check(filepath.Join(dirs.SnapdLogForwardDir, "cursor"), testutil.FileEquals, "c2")
+}
+
+func (s *logForwardSuite) TestEnsureRestartsOnChangesAndFailures(c *C) {
+	s.setConfig(c, map[string]interface{}{"logs.forward.target": s.srv.URL})
+	mgr := logforwardstate.Manager(s.state)
+	defer mgr.Stop()
+	c.Assert(mgr.Ensure(), IsNil)
+	s.waitFor(c, "journal reader", func() bool { return len(s.calls) == 1 })
+
+	// a configuration change restarts forwarding
+	s.setConfig(c, map[string]interface{}{"logs.forward.snaps": "foo,bar"})
+	c.Assert(mgr.Ensure(), IsNil)
+	s.waitFor(c, "journal reader", func() bool { return len(s.calls) == 2 })
+	c.Check(s.calls[1].units, DeepEquals, []string{"snap.foo.*", "snap.bar.*"})
+
+	// and so does the journal reader going away
+	s.mu.Lock()
+	s.journals[1].Close()
+	s.mu.Unlock()
+	for i := 0; i < 1000 && mgr.Running(); i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	c.Assert(mgr.Running(), Equals, false)
+	c.Assert(mgr.Ensure(), IsNil)
+	c.Check(mgr.Running(), Equals, true)
+	s.waitFor(c, "journal reader", func() bool { return len(s.calls) == 3 })
+
+	// unsetting the target stops forwarding
+	s.setConfig(c, map[string]interface{}{"logs.forward.target": ""})
+	c.Assert(mgr.Ensure(), IsNil)
+	c.Check(mgr.Running(), Equals, false)
+}
+
+func (s *logForwardSuite) TestRateLimiter(c *C) {
+	now := time.Date(2021, 3, 1, 10, 0, 0, 0, time.UTC)
+	s.AddCleanup(logforwardstate.MockTimeNow(func() time.Time { return now }))
+	var delays []time.Duration
+	s.AddCleanup(logforwardstate.MockTimeAfter(func(d time.Duration) <-chan time.Time {
+		delays = append(delays, d)
+		now = now.Add(d)
+		ch := make(chan time.Time, 1)
+		ch <- now
+		return ch
+	}))
+
+	stop := make(chan struct{})
+	limiter := logforwardstate.NewRateLimiter(10)
+	// the first batch goes out immediately
+	c.Check(limiter.Wait(10, stop), Equals, true)
+	c.Check(delays, HasLen, 0)
+	// the next ones are spaced out
+	c.Check(limiter.Wait(5, stop), Equals, true)
+	c.Check(limiter.Wait(5, stop), Equals, true)
+	c.Check(delays, DeepEquals, []time.Duration{time.Second, 500 * time.Millisecond})
+	// time passing makes room again
+	now = now.Add(time.Minute)
+	c.Check(limiter.Wait(10, stop), Equals, true)
+	c.Check(delays, HasLen, 2)
+
+	// no limit
+	c.Check(logforwardstate.NewRateLimiter(0).Wait(1000, stop), Equals, true)
+	c.Check(delays, HasLen, 2)
+}
+
+func (s *logForwardSuite) TestRateLimiterStopped(c *C) {
+	s.AddCleanup(logforwardstate.MockTimeAfter(func(d time.Duration) <-chan time.Time {
+		return nil
+	}))
+	stop := make(chan struct{})
+	close(stop)
+	limiter := logforwardstate.NewRateLimiter(1)
+	c.Check(limiter.Wait(1, stop), Equals, true)
+	c.Check(limiter.Wait(1, stop), Equals, false)
+}
+
+func (s *logForwardSuite) TestEntryFromLog(c *C) {
+	raw := func(v string) *json.RawMessage {
+		msg := json.RawMessage(v)
+		return &msg
+	}
+	// messages of systemd about a unit
+	e := logforwardstate.EntryFromLog(systemd.Log{
+		"__REALTIME_TIMESTAMP": raw(`"1614592800000000"`),
+		"_SYSTEMD_UNIT":        raw(`"init.scope"`),
+		"UNIT":                 raw(`"snap.foo.svc.service"`),
+		"SYSLOG_IDENTIFIER":    raw(`"systemd"`),
+		"_PID":                 raw(`"1"`),
+		"MESSAGE":              raw(`"Started Service for snap application foo.svc."`),
+	})
+	c.Check(e, DeepEquals, &logforwardstate.Entry{
+		Timestamp: time.Date(2021, 3, 1, 10, 0, 0, 0, time.UTC),
+		Snap:      "foo",
+		App:       "svc",
+		Priority:  6,
+		SID:       "systemd",
+		PID:       "1",
+		Message:   "Started Service for snap application foo.svc.",
+	})
+
+	e = logforwardstate.EntryFromLog(systemd.Log{"PRIORITY": raw(`"9"`)})
+	c.Check(e.Priority, Equals, 6)
+	c.Check(e.Snap, Equals, "")
+}
+
+func (s *logForwardSuite) TestSnapAppFromUnit(c *C) {
+	for _, t := range []struct {
+		unit, snap, app string
+	}{
+		{"snap.foo.svc.service", "foo", "svc"},
+		{"snap.foo_bar.svc.timer", "foo_bar", "svc"},
+		{"snap.foo.svc", "", ""},
+		{"snap-foo-1.mount", "", ""},
+		{"", "", ""},
+	} {
+		snapName, app := logforwardstate.SnapAppFromUnit(t.unit)
+		c.Check(snapName, Equals, t.snap, Commentf(t.unit))
+		c.Check(app, Equals, t.app, Commentf(t.unit))
+	}
+}
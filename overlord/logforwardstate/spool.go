@@ -0,0 +1,174 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package logforwardstate
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/snapcore/snapd/osutil"
+)
+
+const spoolCursorFile = "cursor"
+
+// spool is the disk buffer of the entries which could not be forwarded
+// yet. Entries are kept in segment files, named after a sequence number and
+// the number of entries, together with the journal cursor of the last entry
+// sent or added, so that forwarding resumes where it stopped across
+// restarts. It is only used by the goroutine sending the entries.
+type spool struct {
+	dir     string
+	maxSize int64
+
+	next uint64
+}
+
+type segment struct {
+	name  string
+	seq   uint64
+	count int
+	size  int64
+}
+
+func openSpool(dir string, maxSize int64) (*spool, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("cannot create log forwarding buffer directory: %v", err)
+	}
+	s := &spool{dir: dir, maxSize: maxSize}
+	segs, err := s.segments()
+	if err != nil {
+		return nil, err
+	}
+	if len(segs) > 0 {
+		s.next = segs[len(segs)-1].seq + 1
+	}
+	return s, nil
+}
+
+// segments returns the segments in the spool, oldest first.
+func (s *spool) segments() ([]segment, error) {
+	matches, err := filepath.Glob(filepath.Join(s.dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	segs := make([]segment, 0, len(matches))
+	for _, path := range matches {
+		var seg segment
+		name := filepath.Base(path)
+		if _, err := fmt.Sscanf(name, "%016d-%d.json", &seg.seq, &seg.count); err != nil {
+			continue
+		}
+		fi, err := os.Stat(path)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, err
+		}
+		seg.name = name
+		seg.size = fi.Size()
+		segs = append(segs, seg)
+	}
+	sort.Slice(segs, func(i, j int) bool { return segs[i].seq < segs[j].seq })
+	return segs, nil
+}
+
+// cursor returns the journal cursor of the last entry added to the spool,
+// or "" if there is none.
+func (s *spool) cursor() (string, error) {
+	data, err := ioutil.ReadFile(filepath.Join(s.dir, spoolCursorFile))
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	return string(data), err
+}
+
+// add writes entries to a new segment, if any, and records the given
+// cursor. When the spool grows past its maximum size the oldest segments are
+// dropped, the number of entries dropped is returned.
+func (s *spool) add(entries []*Entry, cursor string) (dropped int, err error) {
+	if len(entries) > 0 {
+		data, err := json.Marshal(entries)
+		if err != nil {
+			return 0, err
+		}
+		name := fmt.Sprintf("%016d-%d.json", s.next, len(entries))
+		if err := osutil.AtomicWriteFile(filepath.Join(s.dir, name), data, 0600, 0); err != nil {
+			return 0, err
+		}
+		s.next++
+	}
+	if cursor != "" {
+		if err := osutil.AtomicWriteFile(filepath.Join(s.dir, spoolCursorFile), []byte(cursor), 0600, 0); err != nil {
+			return 0, err
+		}
+	}
+
+	segs, err := s.segments()
+	if err != nil {
+		return 0, err
+	}
+	var total int64
+	for _, seg := range segs {
+		total += seg.size
+	}
+	// the newest segment is always kept
+	for len(segs) > 1 && total > s.maxSize {
+		if err := os.Remove(filepath.Join(s.dir, segs[0].name)); err != nil && !os.IsNotExist(err) {
+			return dropped, err
+		}
+		total -= segs[0].size
+		dropped += segs[0].count
+		segs = segs[1:]
+	}
+	return dropped, nil
+}
+
+// oldest returns the name and the entries of the oldest segment, the name
+// is empty if the spool is empty.
+func (s *spool) oldest() (string, []*Entry, error) {
+	segs, err := s.segments()
+	if err != nil || len(segs) == 0 {
+		return "", nil, err
+	}
+	name := segs[0].name
+	data, err := ioutil.ReadFile(filepath.Join(s.dir, name))
+	if err != nil {
+		return "", nil, err
+	}
+	var entries []*Entry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return name, nil, fmt.Errorf("cannot decode log forwarding buffer %q: %v", name, err)
+	}
+	return name, entries, nil
+}
+
+// remove removes a segment once forwarded, it might have been dropped
+// already.
+func (s *spool) remove(name string) error {
+	if err := os.Remove(filepath.Join(s.dir, name)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
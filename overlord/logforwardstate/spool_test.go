@@ -0,0 +1,157 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package logforwardstate_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/osutil"
+	"github.com/snapcore/snapd/overlord/logforwardstate"
+)
+
+type spoolSuite struct {
+	dir string
+}
+
+var _ = Suite(&spoolSuite{})
+
+func (s *spoolSuite) SetUpTest(c *C) {
+	s.dir = filepath.Join(c.MkDir(), "spool")
+}
+
+func (s *spoolSuite) TestAddOldestRemove(c *C) {
+	sp, err := logforwardstate.OpenSpool(s.dir, 1000*1000)
+	c.Assert(err, IsNil)
+	c.Check(osutil.IsDirectory(s.dir), Equals, true)
+
+	name, entries, err := sp.Oldest()
+	c.Assert(err, IsNil)
+	c.Check(name, Equals, "")
+	c.Check(entries, IsNil)
+	cursor, err := sp.Cursor()
+	c.Assert(err, IsNil)
+	c.Check(cursor, Equals, "")
+
+	dropped, err := sp.Add(testEntries[:1], "cursor-1")
+	c.Assert(err, IsNil)
+	c.Check(dropped, Equals, 0)
+	dropped, err = sp.Add(testEntries[1:], "cursor-2")
+	c.Assert(err, IsNil)
+	c.Check(dropped, Equals, 0)
+	// only the cursor moves when no entry is added
+	_, err = sp.Add(nil, "cursor-3")
+	c.Assert(err, IsNil)
+
+	cursor, err = sp.Cursor()
+	c.Assert(err, IsNil)
+	c.Check(cursor, Equals, "cursor-3")
+
+	name, entries, err = sp.Oldest()
+	c.Assert(err, IsNil)
+	c.Check(name, Equals, "0000000000000000-1.json")
+	c.Check(entries, DeepEquals, testEntries[:1])
+	c.Assert(sp.Remove(name), IsNil)
+	// removing twice is fine
+	c.Assert(sp.Remove(name), IsNil)
+
+	name, entries, err = sp.Oldest()
+	c.Assert(err, IsNil)
+	c.Check(name, Equals, "0000000000000001-1.json")
+	c.Check(entries, DeepEquals, testEntries[1:])
+	c.Assert(sp.Remove(name), IsNil)
+
+	name, _, err = sp.Oldest()
+	c.Assert(err, IsNil)
+	c.Check(name, Equals, "")
+}
+
+func (s *spoolSuite) TestReopenContinuesSequence(c *C) {
+	sp, err := logforwardstate.OpenSpool(s.dir, 1000*1000)
+	c.Assert(err, IsNil)
+	_, err = sp.Add(testEntries, "cursor-1")
+	c.Assert(err, IsNil)
+
+	sp, err = logforwardstate.OpenSpool(s.dir, 1000*1000)
+	c.Assert(err, IsNil)
+	cursor, err := sp.Cursor()
+	c.Assert(err, IsNil)
+	c.Check(cursor, Equals, "cursor-1")
+	_, err = sp.Add(testEntries[:1], "cursor-2")
+	c.Assert(err, IsNil)
+
+	matches, err := filepath.Glob(filepath.Join(s.dir, "*.json"))
+	c.Assert(err, IsNil)
+	c.Check(matches, DeepEquals, []string{
+		filepath.Join(s.dir, "0000000000000000-2.json"),
+		filepath.Join(s.dir, "0000000000000001-1.json"),
+	})
+}
+
+func (s *spoolSuite) TestDropsOldestWhenFull(c *C) {
+	data, err := json.Marshal(testEntries)
+	c.Assert(err, IsNil)
+	// room for a bit more than three segments of two entries
+	sp, err := logforwardstate.OpenSpool(s.dir, int64(len(data)*7/2))
+	c.Assert(err, IsNil)
+
+	for i := 0; i < 3; i++ {
+		dropped, err := sp.Add(testEntries, "")
+		c.Assert(err, IsNil)
+		c.Check(dropped, Equals, 0)
+	}
+	dropped, err := sp.Add(testEntries, "")
+	c.Assert(err, IsNil)
+	c.Check(dropped, Equals, 2)
+
+	name, _, err := sp.Oldest()
+	c.Assert(err, IsNil)
+	c.Check(name, Equals, "0000000000000001-2.json")
+}
+
+func (s *spoolSuite) TestKeepsNewestSegment(c *C) {
+	sp, err := logforwardstate.OpenSpool(s.dir, 10)
+	c.Assert(err, IsNil)
+
+	dropped, err := sp.Add(testEntries, "")
+	c.Assert(err, IsNil)
+	c.Check(dropped, Equals, 0)
+	dropped, err = sp.Add(testEntries[:1], "")
+	c.Assert(err, IsNil)
+	c.Check(dropped, Equals, 2)
+
+	name, entries, err := sp.Oldest()
+	c.Assert(err, IsNil)
+	c.Check(name, Equals, "0000000000000001-1.json")
+	c.Check(entries, HasLen, 1)
+}
+
+func (s *spoolSuite) TestOldestCorrupted(c *C) {
+	sp, err := logforwardstate.OpenSpool(s.dir, 1000*1000)
+	c.Assert(err, IsNil)
+	c.Assert(ioutil.WriteFile(filepath.Join(s.dir, "0000000000000000-1.json"), []byte("{"), 0600), IsNil)
+
+	name, _, err := sp.Oldest()
+	c.Check(name, Equals, "0000000000000000-1.json")
+	c.Check(err, ErrorMatches, `cannot decode log forwarding buffer "0000000000000000-1.json": .*`)
+}
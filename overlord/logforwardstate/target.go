@@ -0,0 +1,224 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package logforwardstate
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+const defaultSyslogPort = "514"
+
+// Target is where logs are forwarded to.
+type Target struct {
+	// Kind is either "syslog" or "http".
+	Kind string
+	// Network is the network used for syslog, "tcp" or "udp".
+	Network string
+	// Address is the host:port of the syslog server, or the URL the
+	// logs are posted to.
+	Address string
+}
+
+// ParseTarget parses a log forwarding target, one of:
+//
+//   syslog+udp://host[:port] or syslog://host[:port]
+//   syslog+tcp://host[:port]
+//   http://host[:port]/path or https://host[:port]/path
+//
+// Syslog servers are sent RFC5424 messages, on port 514 by default. HTTP
+// collectors are sent batches of entries as JSON arrays with POST.
+func ParseTarget(s string) (*Target, error) {
+	u, err := url.Parse(s)
+	if err != nil {
+		return nil, err
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("missing host in %q", s)
+	}
+	switch u.Scheme {
+	case "syslog", "syslog+udp", "syslog+tcp":
+		if u.Path != "" || u.RawQuery != "" || u.User != nil {
+			return nil, fmt.Errorf("unexpected path, query or user in syslog target %q", s)
+		}
+		network := "udp"
+		if u.Scheme == "syslog+tcp" {
+			network = "tcp"
+		}
+		addr := u.Host
+		if u.Port() == "" {
+			addr = net.JoinHostPort(u.Hostname(), defaultSyslogPort)
+		}
+		return &Target{Kind: "syslog", Network: network, Address: addr}, nil
+	case "http", "https":
+		return &Target{Kind: "http", Address: u.String()}, nil
+	default:
+		return nil, fmt.Errorf("unsupported scheme %q, expected syslog, syslog+udp, syslog+tcp, http or https", u.Scheme)
+	}
+}
+
+// Entry is a forwarded log entry.
+type Entry struct {
+	Timestamp time.Time `json:"timestamp"`
+	// Snap and App identify the service which logged the entry.
+	Snap string `json:"snap"`
+	App  string `json:"app"`
+	// Priority is the syslog severity, from 0 (emerg) to 7 (debug).
+	Priority int    `json:"priority"`
+	SID      string `json:"sid"`
+	PID      string `json:"pid"`
+	Message  string `json:"message"`
+}
+
+// sender delivers entries to a target.
+type sender interface {
+	send(entries []*Entry) error
+	close() error
+}
+
+var (
+	netDialTimeout = net.DialTimeout
+	osHostname     = os.Hostname
+)
+
+const sendTimeout = 30 * time.Second
+
+func newSender(target *Target) (sender, error) {
+	switch target.Kind {
+	case "syslog":
+		hostname, err := osHostname()
+		if err != nil || hostname == "" {
+			hostname = "-"
+		}
+		return &syslogSender{target: target, hostname: hostname}, nil
+	case "http":
+		return &httpSender{
+			url:    target.Address,
+			client: &http.Client{Timeout: sendTimeout},
+		}, nil
+	}
+	return nil, fmt.Errorf("internal error: unknown log forwarding target kind %q", target.Kind)
+}
+
+// syslogSender sends RFC5424 messages, with octet counting framing over
+// TCP as described in RFC6587 and as one datagram each over UDP.
+type syslogSender struct {
+	target   *Target
+	hostname string
+	conn     net.Conn
+}
+
+// syslogFacility is the user-level messages facility.
+const syslogFacility = 1
+
+func (s *syslogSender) format(e *Entry) []byte {
+	appName := syslogField(e.SID, 48)
+	procID := syslogField(e.PID, 128)
+	// version 1, no message id and no structured data
+	return []byte(fmt.Sprintf("<%d>1 %s %s %s %s - - %s",
+		syslogFacility*8+e.Priority, e.Timestamp.UTC().Format(time.RFC3339Nano),
+		syslogField(s.hostname, 255), appName, procID, e.Message))
+}
+
+// syslogField turns a value into a syslog header field, made of at most
+// maxLen printable ASCII characters, or "-" when empty.
+func syslogField(value string, maxLen int) string {
+	field := strings.Map(func(r rune) rune {
+		if r < 33 || r > 126 {
+			return '_'
+		}
+		return r
+	}, value)
+	if len(field) > maxLen {
+		field = field[:maxLen]
+	}
+	if field == "" {
+		return "-"
+	}
+	return field
+}
+
+func (s *syslogSender) send(entries []*Entry) error {
+	if s.conn == nil {
+		conn, err := netDialTimeout(s.target.Network, s.target.Address, sendTimeout)
+		if err != nil {
+			return err
+		}
+		s.conn = conn
+	}
+	s.conn.SetWriteDeadline(time.Now().Add(sendTimeout))
+	for _, e := range entries {
+		msg := s.format(e)
+		if s.target.Network == "tcp" {
+			msg = append([]byte(fmt.Sprintf("%d ", len(msg))), msg...)
+		}
+		if _, err := s.conn.Write(msg); err != nil {
+			// reconnect on the next attempt
+			s.close()
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *syslogSender) close() error {
+	if s.conn == nil {
+		return nil
+	}
+	err := s.conn.Close()
+	s.conn = nil
+	return err
+}
+
+// httpSender posts entries as a JSON array.
+type httpSender struct {
+	url    string
+	client *http.Client
+}
+
+func (s *httpSender) send(entries []*Entry) error {
+	body, err := json.Marshal(entries)
+	if err != nil {
+		return err
+	}
+	rsp, err := s.client.Post(s.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer rsp.Body.Close()
+	io.Copy(ioutil.Discard, rsp.Body)
+	if rsp.StatusCode < 200 || rsp.StatusCode > 299 {
+		return fmt.Errorf("cannot post logs to %s: got unexpected status %s", s.url, rsp.Status)
+	}
+	return nil
+}
+
+func (s *httpSender) close() error {
+	return nil
+}
@@ -0,0 +1,191 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package logforwardstate_test
+
+import (
+	"bufio"
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/overlord/logforwardstate"
+	"github.com/snapcore/snapd/testutil"
+)
+
+type targetSuite struct {
+	testutil.BaseTest
+}
+
+var _ = Suite(&targetSuite{})
+
+func (s *targetSuite) SetUpTest(c *C) {
+	s.BaseTest.SetUpTest(c)
+	s.AddCleanup(logforwardstate.MockOsHostname(func() (string, error) {
+		return "my-host", nil
+	}))
+}
+
+func (s *targetSuite) TestParseTarget(c *C) {
+	for _, t := range []struct {
+		in     string
+		target logforwardstate.Target
+	}{
+		{"syslog://logs.example.com", logforwardstate.Target{Kind: "syslog", Network: "udp", Address: "logs.example.com:514"}},
+		{"syslog+udp://10.0.0.1:5514", logforwardstate.Target{Kind: "syslog", Network: "udp", Address: "10.0.0.1:5514"}},
+		{"syslog+tcp://[::1]", logforwardstate.Target{Kind: "syslog", Network: "tcp", Address: "[::1]:514"}},
+		{"http://localhost:8080/logs?key=1", logforwardstate.Target{Kind: "http", Address: "http://localhost:8080/logs?key=1"}},
+		{"https://logs.example.com", logforwardstate.Target{Kind: "http", Address: "https://logs.example.com"}},
+	} {
+		target, err := logforwardstate.ParseTarget(t.in)
+		c.Assert(err, IsNil, Commentf(t.in))
+		c.Check(*target, DeepEquals, t.target, Commentf(t.in))
+	}
+}
+
+func (s *targetSuite) TestParseTargetErrors(c *C) {
+	for _, t := range []struct {
+		in  string
+		err string
+	}{
+		{"logs.example.com", `missing host in "logs.example.com"`},
+		{"tcp://logs.example.com", `unsupported scheme "tcp", expected syslog, syslog\+udp, syslog\+tcp, http or https`},
+		{"syslog+tcp://user@logs.example.com", `unexpected path, query or user in syslog target .*`},
+		{"syslog://logs.example.com?a=b", `unexpected path, query or user in syslog target .*`},
+		{"http://%zz", `.*invalid URL escape.*`},
+	} {
+		_, err := logforwardstate.ParseTarget(t.in)
+		c.Check(err, ErrorMatches, t.err, Commentf(t.in))
+	}
+}
+
+var testEntries = []*logforwardstate.Entry{{
+	Timestamp: time.Date(2021, 3, 1, 10, 0, 0, 5000, time.UTC),
+	Snap:      "foo",
+	App:       "svc",
+	Priority:  3,
+	SID:       "foo.svc",
+	PID:       "42",
+	Message:   "something failed",
+}, {
+	Timestamp: time.Date(2021, 3, 1, 10, 0, 1, 0, time.UTC),
+	Snap:      "foo",
+	App:       "svc",
+	Priority:  6,
+	SID:       "my app",
+	Message:   "hello",
+}}
+
+func (s *targetSuite) TestSyslogTCP(c *C) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	c.Assert(err, IsNil)
+	defer l.Close()
+
+	received := make(chan []byte, 1)
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		data, _ := ioutil.ReadAll(conn)
+		received <- data
+	}()
+
+	snd, err := logforwardstate.NewSender(&logforwardstate.Target{Kind: "syslog", Network: "tcp", Address: l.Addr().String()})
+	c.Assert(err, IsNil)
+	c.Assert(logforwardstate.Send(snd, testEntries), IsNil)
+	c.Assert(logforwardstate.Close(snd), IsNil)
+
+	msg1 := "<11>1 2021-03-01T10:00:00.000005Z my-host foo.svc 42 - - something failed"
+	msg2 := "<14>1 2021-03-01T10:00:01Z my-host my_app - - - hello"
+	select {
+	case data := <-received:
+		c.Check(string(data), Equals, "73 "+msg1+"53 "+msg2)
+		c.Check(len(msg1), Equals, 73)
+		c.Check(len(msg2), Equals, 53)
+	case <-time.After(10 * time.Second):
+		c.Fatal("no message received")
+	}
+}
+
+func (s *targetSuite) TestSyslogUDP(c *C) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	c.Assert(err, IsNil)
+	defer conn.Close()
+
+	snd, err := logforwardstate.NewSender(&logforwardstate.Target{Kind: "syslog", Network: "udp", Address: conn.LocalAddr().String()})
+	c.Assert(err, IsNil)
+	defer logforwardstate.Close(snd)
+	c.Assert(logforwardstate.Send(snd, testEntries), IsNil)
+
+	conn.SetReadDeadline(time.Now().Add(10 * time.Second))
+	buf := make([]byte, 1024)
+	var msgs []string
+	for i := 0; i < 2; i++ {
+		n, _, err := conn.ReadFrom(buf)
+		c.Assert(err, IsNil)
+		msgs = append(msgs, string(buf[:n]))
+	}
+	c.Check(msgs, DeepEquals, []string{
+		"<11>1 2021-03-01T10:00:00.000005Z my-host foo.svc 42 - - something failed",
+		"<14>1 2021-03-01T10:00:01Z my-host my_app - - - hello",
+	})
+}
+
+func (s *targetSuite) TestSyslogUnavailable(c *C) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	c.Assert(err, IsNil)
+	addr := l.Addr().String()
+	l.Close()
+
+	snd, err := logforwardstate.NewSender(&logforwardstate.Target{Kind: "syslog", Network: "tcp", Address: addr})
+	c.Assert(err, IsNil)
+	err = logforwardstate.Send(snd, testEntries)
+	c.Check(err, ErrorMatches, ".*connection refused")
+}
+
+func (s *targetSuite) TestHTTP(c *C) {
+	var received [][]*logforwardstate.Entry
+	status := 200
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c.Check(r.Method, Equals, "POST")
+		c.Check(r.URL.Path, Equals, "/logs")
+		c.Check(r.Header.Get("Content-Type"), Equals, "application/json")
+		var entries []*logforwardstate.Entry
+		c.Check(json.NewDecoder(bufio.NewReader(r.Body)).Decode(&entries), IsNil)
+		received = append(received, entries)
+		w.WriteHeader(status)
+	}))
+	defer srv.Close()
+
+	snd, err := logforwardstate.NewSender(&logforwardstate.Target{Kind: "http", Address: srv.URL + "/logs"})
+	c.Assert(err, IsNil)
+	c.Assert(logforwardstate.Send(snd, testEntries), IsNil)
+	c.Check(received, DeepEquals, [][]*logforwardstate.Entry{testEntries})
+
+	status = 503
+	err = logforwardstate.Send(snd, testEntries[:1])
+	c.Check(err, ErrorMatches, `cannot post logs to http://.*/logs: got unexpected status 503 Service Unavailable`)
+}
@@ -43,6 +43,7 @@ import (
 	"github.com/snapcore/snapd/overlord/healthstate"
 	"github.com/snapcore/snapd/overlord/hookstate"
 	"github.com/snapcore/snapd/overlord/ifacestate"
+	"github.com/snapcore/snapd/overlord/logforwardstate"
 	"github.com/snapcore/snapd/overlord/patch"
 	"github.com/snapcore/snapd/overlord/servicestate"
 	"github.com/snapcore/snapd/overlord/snapshotstate"
@@ -100,6 +101,7 @@ type Overlord struct {
 	deviceMgr  *devicestate.DeviceManager
 	cmdMgr     *cmdstate.CommandManager
 	shotMgr    *snapshotstate.SnapshotManager
+	logFwdMgr  *logforwardstate.LogForwardManager
 	// proxyConf mediates the http proxy config
 	proxyConf func(req *http.Request) (*url.URL, error)
 }
@@ -181,6 +183,10 @@ func New(restartBehavior RestartBehavior) (*Overlord, error) {
 
 	o.addManager(cmdstate.Manager(s, o.runner))
 	o.addManager(snapshotstate.Manager(s, o.runner))
+	// forwarding logs is of no use while preseeding an image
+	if !snapdenv.Preseeding() {
+		o.addManager(logforwardstate.Manager(s))
+	}
 
 	if err := configstateInit(s, hookMgr); err != nil {
 		return nil, err
@@ -220,6 +226,8 @@ func (o *Overlord) addManager(mgr StateManager) {
 		o.cmdMgr = x
 	case *snapshotstate.SnapshotManager:
 		o.shotMgr = x
+	case *logforwardstate.LogForwardManager:
+		o.logFwdMgr = x
 	}
 	o.stateEng.AddManager(mgr)
 }
@@ -631,6 +639,12 @@ func (o *Overlord) SnapshotManager() *snapshotstate.SnapshotManager {
 	return o.shotMgr
 }
 
+// LogForwardManager returns the manager responsible for forwarding the
+// logs of snap services, it is nil while preseeding.
+func (o *Overlord) LogForwardManager() *logforwardstate.LogForwardManager {
+	return o.logFwdMgr
+}
+
 // Mock creates an Overlord without any managers and with a backend
 // not using disk. Managers can be added with AddManager. For testing.
 func Mock() *Overlord {
@@ -114,6 +114,7 @@ func (ovs *overlordSuite) TestNew(c *C) {
 	c.Check(o.DeviceManager(), NotNil)
 	c.Check(o.CommandManager(), NotNil)
 	c.Check(o.SnapshotManager(), NotNil)
+	c.Check(o.LogForwardManager(), NotNil)
 	c.Check(configstateInitCalled, Equals, true)
 
 	o.InterfaceManager().DisableUDevMonitor()
@@ -1097,6 +1098,8 @@ func (ovs *overlordSuite) TestEnsureErrorWhenPreseeding(c *C) {
 	o, err := overlord.New(nil)
 	c.Assert(err, IsNil)
 	markSeeded(o)
+	// logs are not forwarded while preseeding
+	c.Check(o.LogForwardManager(), IsNil)
 
 	err = o.StartUp()
 	c.Assert(err, IsNil)
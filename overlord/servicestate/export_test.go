@@ -20,6 +20,8 @@
 package servicestate
 
 import (
+	"os/user"
+
 	tomb "gopkg.in/tomb.v2"
 
 	"github.com/snapcore/snapd/overlord/state"
@@ -46,3 +48,13 @@ func MockOsutilBootID(mockID string) (restore func()) {
 		osutilBootID = old
 	}
 }
+
+var SetServiceOverrideInState = setServiceOverrideInState
+
+func MockUserLookupId(f func(uid string) (*user.User, error)) (restore func()) {
+	old := userLookupId
+	userLookupId = f
+	return func() {
+		userLookupId = old
+	}
+}
@@ -152,7 +152,7 @@ func systemctlCallsForServiceRestart(name string) []expectedSystemctl {
 	svc := "snap." + name + ".svc1.service"
 	return []expectedSystemctl{
 		{
-			expArgs: []string{"show", "--property=Id,ActiveState,UnitFileState,Type", svc},
+			expArgs: []string{"show", "--property=Id,ActiveState,UnitFileState,Type,Result", svc},
 			output:  fmt.Sprintf("Id=%s\nActiveState=active\nUnitFileState=enabled\nType=simple\n", svc),
 		},
 		{expArgs: []string{"stop", svc}},
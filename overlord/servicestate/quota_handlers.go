@@ -386,6 +386,26 @@ func quotaUpdate(st *state.State, action QuotaControlAction, allGrps map[string]
 	return grp, allGrps, nil
 }
 
+// snapServicesEnsureOptions returns the options to use with
+// wrappers.EnsureSnapServices for the device.
+// TODO: this is still duplicated in a few places
+func snapServicesEnsureOptions(st *state.State) (*wrappers.EnsureSnapServicesOptions, error) {
+	ensureOpts := &wrappers.EnsureSnapServicesOptions{
+		Preseeding: snapdenv.Preseeding(),
+	}
+
+	// set RequireMountedSnapdSnap if we are on UC18+ only
+	deviceCtx, err := snapstate.DeviceCtx(st, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if !deviceCtx.Classic() && deviceCtx.Model().Base() != "" {
+		ensureOpts.RequireMountedSnapdSnap = true
+	}
+	return ensureOpts, nil
+}
+
 type ensureSnapServicesForGroupOptions struct {
 	// allGrps is the updated set of quota groups
 	allGrps map[string]*quota.Group
@@ -436,22 +456,11 @@ func ensureSnapServicesForGroup(st *state.State, t *state.Task, grp *quota.Group
 		snapSvcMap[info] = opts
 	}
 
-	// TODO: the following lines should maybe be EnsureOptionsForDevice() or
-	// something since it is duplicated a few places
-	ensureOpts := &wrappers.EnsureSnapServicesOptions{
-		Preseeding: snapdenv.Preseeding(),
-	}
-
-	// set RequireMountedSnapdSnap if we are on UC18+ only
-	deviceCtx, err := snapstate.DeviceCtx(st, nil, nil)
+	ensureOpts, err := snapServicesEnsureOptions(st)
 	if err != nil {
 		return nil, err
 	}
 
-	if !deviceCtx.Classic() && deviceCtx.Model().Base() != "" {
-		ensureOpts.RequireMountedSnapdSnap = true
-	}
-
 	grpsToStart := []*quota.Group{}
 	appsToRestartBySnap = map[*snap.Info][]*snap.AppInfo{}
 
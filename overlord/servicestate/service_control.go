@@ -24,6 +24,7 @@ import (
 
 	tomb "gopkg.in/tomb.v2"
 
+	"github.com/snapcore/snapd/client"
 	"github.com/snapcore/snapd/overlord/snapstate"
 	"github.com/snapcore/snapd/overlord/state"
 	"github.com/snapcore/snapd/snap"
@@ -54,6 +55,11 @@ type ServiceAction struct {
 	// inactive and not disabled services in snap-name, and also svc1 regardless
 	// of the state svc1 is in.
 	ExplicitServices []string `json:"explicit-services,omitempty"`
+	// Users, if set, restricts the action to the user daemons in the
+	// sessions of the selected users, given by Uids unless all the
+	// users are selected.
+	Users client.UserSelection `json:"users,omitempty"`
+	Uids  []int                `json:"uids,omitempty"`
 }
 
 func (m *ServiceManager) doServiceControl(t *state.Task, _ *tomb.Tomb) error {
@@ -101,6 +107,20 @@ func (m *ServiceManager) doServiceControl(t *state.Task, _ *tomb.Tomb) error {
 		}
 	}
 
+	if sc.Users != client.UserSelectionNone {
+		// only the user daemons run in the sessions of the users
+		userServices := make([]*snap.AppInfo, 0, len(services))
+		for _, app := range services {
+			if app.DaemonScope == snap.UserDaemon {
+				userServices = append(userServices, app)
+			}
+		}
+		services = userServices
+		if len(services) == 0 {
+			return nil
+		}
+	}
+
 	meter := snapstate.NewTaskProgressAdapterUnlocked(t)
 
 	var startupOrdered []*snap.AppInfo
@@ -117,6 +137,7 @@ func (m *ServiceManager) doServiceControl(t *state.Task, _ *tomb.Tomb) error {
 		disable := sc.ActionModifier == "disable"
 		flags := &wrappers.StopServicesFlags{
 			Disable: disable,
+			Uids:    sc.Uids,
 		}
 		st.Unlock()
 		err := wrappers.StopServices(services, flags, snap.StopReasonOther, meter, perfTimings)
@@ -141,6 +162,7 @@ func (m *ServiceManager) doServiceControl(t *state.Task, _ *tomb.Tomb) error {
 		enable := sc.ActionModifier == "enable"
 		flags := &wrappers.StartServicesFlags{
 			Enable: enable,
+			Uids:   sc.Uids,
 		}
 		st.Unlock()
 		err = wrappers.StartServices(startupOrdered, nil, flags, meter, perfTimings)
@@ -162,12 +184,20 @@ func (m *ServiceManager) doServiceControl(t *state.Task, _ *tomb.Tomb) error {
 			}
 		}
 	case "restart":
+		flags := &wrappers.RestartServicesFlags{
+			UserServices: sc.Users != client.UserSelectionNone,
+			Uids:         sc.Uids,
+		}
 		st.Unlock()
-		err := wrappers.RestartServices(startupOrdered, sc.ExplicitServices, nil, meter, perfTimings)
+		err := wrappers.RestartServices(startupOrdered, sc.ExplicitServices, flags, meter, perfTimings)
 		st.Lock()
 		return err
 	case "reload-or-restart":
-		flags := &wrappers.RestartServicesFlags{Reload: true}
+		flags := &wrappers.RestartServicesFlags{
+			Reload:       true,
+			UserServices: sc.Users != client.UserSelectionNone,
+			Uids:         sc.Uids,
+		}
 		st.Unlock()
 		err := wrappers.RestartServices(startupOrdered, sc.ExplicitServices, flags, meter, perfTimings)
 		st.Lock()
@@ -463,6 +463,38 @@ func (s *serviceControlSuite) TestWithExplicitServicesAndSnapName(c *C) {
 		[]string{"snap.test-snap.bar.service", "snap.test-snap.foo.service"})
 }
 
+func (s *serviceControlSuite) TestControlUsersInstruction(c *C) {
+	st := s.state
+	st.Lock()
+	defer st.Unlock()
+
+	info := s.mockTestSnap(c)
+
+	for _, tc := range []struct {
+		users client.UserSelection
+		uids  []int
+	}{
+		{client.UserSelectionSelf, []int{1000}},
+		{client.UserSelectionAll, nil},
+	} {
+		inst := &servicestate.Instruction{
+			Action: "restart",
+			Names:  []string{"test-snap.foo"},
+			Users:  tc.users,
+			Uids:   []int{1000},
+		}
+		tss, err := servicestate.Control(st, []*snap.AppInfo{info.Apps["foo"]}, inst, nil, nil)
+		c.Assert(err, IsNil)
+		c.Assert(tss, HasLen, 1)
+		tasks := tss[0].Tasks()
+		c.Assert(tasks, HasLen, 1)
+		var sa servicestate.ServiceAction
+		c.Assert(tasks[0].Get("service-action", &sa), IsNil)
+		c.Check(sa.Users, Equals, tc.users)
+		c.Check(sa.Uids, DeepEquals, tc.uids)
+	}
+}
+
 func (s *serviceControlSuite) TestNoServiceCommandError(c *C) {
 	st := s.state
 	st.Lock()
@@ -619,6 +651,34 @@ func (s *serviceControlSuite) TestStartAllServices(c *C) {
 	})
 }
 
+func (s *serviceControlSuite) TestStartServicesUsersSkipsSystemDaemons(c *C) {
+	st := s.state
+	st.Lock()
+	defer st.Unlock()
+
+	s.mockTestSnap(c)
+
+	chg := st.NewChange("service-control", "...")
+	t := st.NewTask("service-control", "...")
+	cmd := &servicestate.ServiceAction{
+		SnapName: "test-snap",
+		Action:   "start",
+		Users:    client.UserSelectionAll,
+	}
+	t.Set("service-action", cmd)
+	chg.AddTask(t)
+
+	st.Unlock()
+	defer s.se.Stop()
+	err := s.o.Settle(5 * time.Second)
+	st.Lock()
+	c.Assert(err, IsNil)
+
+	c.Assert(t.Status(), Equals, state.DoneStatus)
+	// test-snap only has system daemons
+	c.Check(s.sysctlArgs, HasLen, 0)
+}
+
 func (s *serviceControlSuite) TestStartListedServices(c *C) {
 	st := s.state
 	st.Lock()
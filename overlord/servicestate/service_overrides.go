@@ -0,0 +1,441 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package servicestate
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	tomb "gopkg.in/tomb.v2"
+
+	"github.com/snapcore/snapd/overlord/snapstate"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/progress"
+	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/snap/quota"
+	"github.com/snapcore/snapd/timeout"
+	"github.com/snapcore/snapd/wrappers"
+)
+
+// ServiceOverrideAction is the serialized representation of a change of the
+// settings overridden for a service that lives in a task.
+type ServiceOverrideAction struct {
+	SnapName string `json:"snap-name"`
+	AppName  string `json:"app-name"`
+
+	// Override is the new override for the service, nil if the
+	// overridden settings are reset.
+	Override *wrappers.ServiceOverride `json:"override,omitempty"`
+}
+
+// AllServiceOverrides returns the settings overridden for the services of
+// all snaps, by snap and app name.
+func AllServiceOverrides(st *state.State) (map[string]map[string]*wrappers.ServiceOverride, error) {
+	var all map[string]map[string]*wrappers.ServiceOverride
+	if err := st.Get("service-overrides", &all); err != nil && err != state.ErrNoState {
+		return nil, err
+	}
+	if all == nil {
+		all = make(map[string]map[string]*wrappers.ServiceOverride)
+	}
+	return all, nil
+}
+
+// ServiceOverrides returns the settings overridden for the services of the
+// given snap, by app name.
+func ServiceOverrides(st *state.State, instanceName string) (map[string]*wrappers.ServiceOverride, error) {
+	all, err := AllServiceOverrides(st)
+	if err != nil {
+		return nil, err
+	}
+	return all[instanceName], nil
+}
+
+func setServiceOverrideInState(st *state.State, instanceName, appName string, override *wrappers.ServiceOverride) error {
+	all, err := AllServiceOverrides(st)
+	if err != nil {
+		return err
+	}
+	if override == nil {
+		delete(all[instanceName], appName)
+		if len(all[instanceName]) == 0 {
+			delete(all, instanceName)
+		}
+	} else {
+		if all[instanceName] == nil {
+			all[instanceName] = make(map[string]*wrappers.ServiceOverride)
+		}
+		all[instanceName][appName] = override
+	}
+	st.Set("service-overrides", all)
+	return nil
+}
+
+// RemoveServiceOverrides forgets the settings overridden for the services of
+// the given snap, usually because the snap is being removed from the
+// system. The service units themselves are expected to be gone already.
+func RemoveServiceOverrides(st *state.State, instanceName string) error {
+	all, err := AllServiceOverrides(st)
+	if err != nil {
+		return err
+	}
+	if _, ok := all[instanceName]; !ok {
+		return nil
+	}
+	delete(all, instanceName)
+	st.Set("service-overrides", all)
+	return nil
+}
+
+var validEnvName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func overrideEnvName(key string) (name string, err error) {
+	name = strings.TrimPrefix(key, "environment.")
+	if !validEnvName.MatchString(name) {
+		return "", fmt.Errorf("invalid environment variable name %q", name)
+	}
+	return name, nil
+}
+
+// applyServiceOverrideSettings updates an override with settings given as
+// key and value, the keys being restart, restart-delay, start-timeout,
+// stop-timeout, watchdog-timeout and environment.<NAME>.
+func applyServiceOverrideSettings(override *wrappers.ServiceOverride, settings map[string]string) error {
+	for _, key := range sortedKeys(settings) {
+		value := settings[key]
+		var dur *timeout.Timeout
+		switch key {
+		case "restart":
+			cond, ok := snap.RestartMap[value]
+			if !ok {
+				return fmt.Errorf("invalid restart condition %q", value)
+			}
+			override.Restart = cond
+			continue
+		case "restart-delay":
+			dur = &override.RestartDelay
+		case "start-timeout":
+			dur = &override.StartTimeout
+		case "stop-timeout":
+			dur = &override.StopTimeout
+		case "watchdog-timeout":
+			dur = &override.WatchdogTimeout
+		default:
+			if !strings.HasPrefix(key, "environment.") {
+				return fmt.Errorf("cannot override unsupported setting %q", key)
+			}
+			name, err := overrideEnvName(key)
+			if err != nil {
+				return err
+			}
+			if override.Environment == nil {
+				override.Environment = make(map[string]string)
+			}
+			override.Environment[name] = value
+			continue
+		}
+		d, err := time.ParseDuration(value)
+		if err != nil || d <= 0 {
+			return fmt.Errorf("invalid %s %q, expected a positive duration", key, value)
+		}
+		*dur = timeout.Timeout(d)
+	}
+	return nil
+}
+
+// ServiceOverrideSettings returns the settings of an override as key and
+// value, as accepted by SetServiceOverride.
+func ServiceOverrideSettings(override *wrappers.ServiceOverride) map[string]string {
+	settings := make(map[string]string)
+	if override.Restart != "" {
+		settings["restart"] = string(override.Restart)
+	}
+	for key, dur := range map[string]timeout.Timeout{
+		"restart-delay":    override.RestartDelay,
+		"start-timeout":    override.StartTimeout,
+		"stop-timeout":     override.StopTimeout,
+		"watchdog-timeout": override.WatchdogTimeout,
+	} {
+		if dur != 0 {
+			settings[key] = dur.String()
+		}
+	}
+	for name, value := range override.Environment {
+		settings["environment."+name] = value
+	}
+	return settings
+}
+
+// resetServiceOverrideSettings drops the given settings from an override,
+// environment drops all the environment variables.
+func resetServiceOverrideSettings(override *wrappers.ServiceOverride, keys []string) error {
+	for _, key := range keys {
+		switch key {
+		case "restart":
+			override.Restart = ""
+		case "restart-delay":
+			override.RestartDelay = 0
+		case "start-timeout":
+			override.StartTimeout = 0
+		case "stop-timeout":
+			override.StopTimeout = 0
+		case "watchdog-timeout":
+			override.WatchdogTimeout = 0
+		case "environment":
+			override.Environment = nil
+		default:
+			if !strings.HasPrefix(key, "environment.") {
+				return fmt.Errorf("cannot reset unsupported setting %q", key)
+			}
+			name, err := overrideEnvName(key)
+			if err != nil {
+				return err
+			}
+			delete(override.Environment, name)
+			if len(override.Environment) == 0 {
+				override.Environment = nil
+			}
+		}
+	}
+	return nil
+}
+
+func isEmptyServiceOverride(override *wrappers.ServiceOverride) bool {
+	return override.Restart == "" && override.RestartDelay == 0 &&
+		override.StartTimeout == 0 && override.StopTimeout == 0 &&
+		override.WatchdogTimeout == 0 && len(override.Environment) == 0
+}
+
+func copyServiceOverride(override *wrappers.ServiceOverride) *wrappers.ServiceOverride {
+	if override == nil {
+		return &wrappers.ServiceOverride{}
+	}
+	cpy := *override
+	if override.Environment != nil {
+		cpy.Environment = make(map[string]string, len(override.Environment))
+		for k, v := range override.Environment {
+			cpy.Environment[k] = v
+		}
+	}
+	return &cpy
+}
+
+func serviceOverrideTaskSet(st *state.State, instanceName, appName string, override *wrappers.ServiceOverride, summary string) (*state.TaskSet, error) {
+	if err := snapstate.CheckChangeConflictMany(st, []string{instanceName}, ""); err != nil {
+		return nil, err
+	}
+	if override != nil && isEmptyServiceOverride(override) {
+		override = nil
+	}
+	action := ServiceOverrideAction{
+		SnapName: instanceName,
+		AppName:  appName,
+		Override: override,
+	}
+	task := st.NewTask("service-override", summary)
+	task.Set("service-override-action", action)
+	return state.NewTaskSet(task), nil
+}
+
+// SetServiceOverride returns a task set overriding the given settings of a
+// service, on top of the ones overridden already. Settings are given as key
+// and value, see the snap set-service-override command for the supported
+// ones.
+func SetServiceOverride(st *state.State, instanceName, appName string, settings map[string]string) (*state.TaskSet, error) {
+	if len(settings) == 0 {
+		return nil, fmt.Errorf("no settings to override")
+	}
+	info, err := snapstate.CurrentInfo(st, instanceName)
+	if err != nil {
+		return nil, err
+	}
+	app := info.Apps[appName]
+	if app == nil || !app.IsService() {
+		return nil, fmt.Errorf("snap %q has no service %q", instanceName, appName)
+	}
+
+	overrides, err := ServiceOverrides(st, instanceName)
+	if err != nil {
+		return nil, err
+	}
+	override := copyServiceOverride(overrides[appName])
+	if err := applyServiceOverrideSettings(override, settings); err != nil {
+		return nil, err
+	}
+
+	summary := fmt.Sprintf("Override settings of service %q", app.Snap.InstanceName()+"."+app.Name)
+	return serviceOverrideTaskSet(st, instanceName, appName, override, summary)
+}
+
+// ResetServiceOverride returns a task set resetting the given overridden
+// settings of a service, or all of them if keys is empty.
+func ResetServiceOverride(st *state.State, instanceName, appName string, keys []string) (*state.TaskSet, error) {
+	if _, err := snapstate.CurrentInfo(st, instanceName); err != nil {
+		return nil, err
+	}
+	overrides, err := ServiceOverrides(st, instanceName)
+	if err != nil {
+		return nil, err
+	}
+	if overrides[appName] == nil {
+		return nil, fmt.Errorf("no settings overridden for service %q", instanceName+"."+appName)
+	}
+
+	var override *wrappers.ServiceOverride
+	if len(keys) > 0 {
+		override = copyServiceOverride(overrides[appName])
+		if err := resetServiceOverrideSettings(override, keys); err != nil {
+			return nil, err
+		}
+	}
+
+	summary := fmt.Sprintf("Reset overridden settings of service %q", instanceName+"."+appName)
+	return serviceOverrideTaskSet(st, instanceName, appName, override, summary)
+}
+
+func serviceOverrideAffectedSnaps(t *state.Task) ([]string, error) {
+	var action ServiceOverrideAction
+	if err := t.Get("service-override-action", &action); err != nil {
+		return nil, fmt.Errorf("internal error: cannot obtain service override action from task: %s", t.Summary())
+	}
+	return []string{action.SnapName}, nil
+}
+
+func (m *ServiceManager) doServiceOverride(t *state.Task, _ *tomb.Tomb) error {
+	st := t.State()
+	st.Lock()
+	defer st.Unlock()
+
+	perfTimings := state.TimingsForTask(t)
+	defer perfTimings.Save(st)
+
+	var action ServiceOverrideAction
+	if err := t.Get("service-override-action", &action); err != nil {
+		return fmt.Errorf("internal error: cannot get service-override-action: %v", err)
+	}
+
+	// see doQuotaControl about why the update is remembered
+	updated, appsToRestartBySnap, err := quotaStateAlreadyUpdated(t)
+	if err != nil {
+		return err
+	}
+	if !updated {
+		overrides, err := ServiceOverrides(st, action.SnapName)
+		if err != nil {
+			return err
+		}
+		// keep the previous override for undo
+		t.Set("old-service-override", overrides[action.AppName])
+
+		if err := setServiceOverrideInState(st, action.SnapName, action.AppName, action.Override); err != nil {
+			return err
+		}
+		appsToRestartBySnap, err = ensureSnapServicesForOverride(st, t, action.SnapName)
+		if err != nil {
+			return err
+		}
+		if err := rememberQuotaStateUpdated(t, appsToRestartBySnap); err != nil {
+			return err
+		}
+	}
+
+	return restartSnapServices(st, t, appsToRestartBySnap, perfTimings)
+}
+
+func (m *ServiceManager) undoServiceOverride(t *state.Task, _ *tomb.Tomb) error {
+	st := t.State()
+	st.Lock()
+	defer st.Unlock()
+
+	perfTimings := state.TimingsForTask(t)
+	defer perfTimings.Save(st)
+
+	var action ServiceOverrideAction
+	if err := t.Get("service-override-action", &action); err != nil {
+		return fmt.Errorf("internal error: cannot get service-override-action: %v", err)
+	}
+	var old *wrappers.ServiceOverride
+	if err := t.Get("old-service-override", &old); err != nil && err != state.ErrNoState {
+		return err
+	}
+
+	if err := setServiceOverrideInState(st, action.SnapName, action.AppName, old); err != nil {
+		return err
+	}
+	appsToRestartBySnap, err := ensureSnapServicesForOverride(st, t, action.SnapName)
+	if err != nil {
+		return err
+	}
+	return restartSnapServices(st, t, appsToRestartBySnap, perfTimings)
+}
+
+// ensureSnapServicesForOverride updates the units of the services of the
+// given snap after a change of their overridden settings, and returns the
+// services which need restarting for it to apply.
+func ensureSnapServicesForOverride(st *state.State, t *state.Task, instanceName string) (appsToRestartBySnap map[*snap.Info][]*snap.AppInfo, err error) {
+	info, err := snapstate.CurrentInfo(st, instanceName)
+	if err != nil {
+		return nil, err
+	}
+	opts, err := SnapServiceOptions(st, instanceName, nil)
+	if err != nil {
+		return nil, err
+	}
+	ensureOpts, err := snapServicesEnsureOptions(st)
+	if err != nil {
+		return nil, err
+	}
+
+	var meterLocked progress.Meter = progress.Null
+	if t != nil {
+		meterLocked = snapstate.NewTaskProgressAdapterLocked(t)
+	}
+
+	appsToRestartBySnap = map[*snap.Info][]*snap.AppInfo{}
+	seen := make(map[string]bool)
+	collectModifiedUnits := func(app *snap.AppInfo, _ *quota.Group, unitType, name, old, new string) {
+		if (unitType == "service" || unitType == "override") && !seen[app.Name] {
+			seen[app.Name] = true
+			appsToRestartBySnap[app.Snap] = append(appsToRestartBySnap[app.Snap], app)
+		}
+	}
+	snapSvcMap := map[*snap.Info]*wrappers.SnapServiceOptions{info: opts}
+	if err := wrappers.EnsureSnapServices(snapSvcMap, ensureOpts, collectModifiedUnits, meterLocked); err != nil {
+		return nil, err
+	}
+
+	if ensureOpts.Preseeding {
+		// nothing to restart
+		return nil, nil
+	}
+	return appsToRestartBySnap, nil
+}
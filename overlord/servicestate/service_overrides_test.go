@@ -0,0 +1,341 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package servicestate_test
+
+import (
+	"errors"
+	"path/filepath"
+	"time"
+
+	. "gopkg.in/check.v1"
+	tomb "gopkg.in/tomb.v2"
+
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/overlord/servicestate"
+	"github.com/snapcore/snapd/overlord/snapstate"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/snap/snaptest"
+	"github.com/snapcore/snapd/testutil"
+	"github.com/snapcore/snapd/timeout"
+	"github.com/snapcore/snapd/wrappers"
+)
+
+type serviceOverridesSuite struct {
+	baseServiceMgrTestSuite
+}
+
+var _ = Suite(&serviceOverridesSuite{})
+
+func (s *serviceOverridesSuite) SetUpTest(c *C) {
+	s.baseServiceMgrTestSuite.SetUpTest(c)
+
+	// we don't need the EnsureSnapServices ensure loop to run by default
+	servicestate.MockEnsuredSnapServices(s.mgr, true)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	snapstate.Set(s.state, "test-snap", s.testSnapState)
+	snaptest.MockSnapCurrent(c, testYaml, s.testSnapSideInfo)
+}
+
+var overrideFile = func() string {
+	return filepath.Join(dirs.SnapServicesDir, "snap.test-snap.svc1.service.d", "snapd-override.conf")
+}
+
+func (s *serviceOverridesSuite) runChange(c *C, ts *state.TaskSet) *state.Change {
+	chg := s.state.NewChange("service-override", "...")
+	chg.AddAll(ts)
+
+	s.state.Unlock()
+	defer s.state.Lock()
+	err := s.o.Settle(5 * time.Second)
+	c.Assert(err, IsNil)
+	return chg
+}
+
+func (s *serviceOverridesSuite) TestSetResetServiceOverrideHappy(c *C) {
+	r := s.mockSystemctlCalls(c, join(
+		// set
+		[]expectedSystemctl{{expArgs: []string{"daemon-reload"}}},
+		systemctlCallsForServiceRestart("test-snap"),
+		// set more
+		[]expectedSystemctl{{expArgs: []string{"daemon-reload"}}},
+		systemctlCallsForServiceRestart("test-snap"),
+		// reset one
+		[]expectedSystemctl{{expArgs: []string{"daemon-reload"}}},
+		systemctlCallsForServiceRestart("test-snap"),
+		// reset all
+		[]expectedSystemctl{{expArgs: []string{"daemon-reload"}}},
+		systemctlCallsForServiceRestart("test-snap"),
+	))
+	defer r()
+
+	st := s.state
+	st.Lock()
+	defer st.Unlock()
+
+	ts, err := servicestate.SetServiceOverride(st, "test-snap", "svc1", map[string]string{
+		"restart":       "always",
+		"restart-delay": "10s",
+	})
+	c.Assert(err, IsNil)
+	c.Assert(ts.Tasks(), HasLen, 1)
+	t := ts.Tasks()[0]
+	c.Check(t.Kind(), Equals, "service-override")
+	c.Check(t.Summary(), Equals, `Override settings of service "test-snap.svc1"`)
+	var action servicestate.ServiceOverrideAction
+	c.Assert(t.Get("service-override-action", &action), IsNil)
+	c.Check(action, DeepEquals, servicestate.ServiceOverrideAction{
+		SnapName: "test-snap",
+		AppName:  "svc1",
+		Override: &wrappers.ServiceOverride{
+			Restart:      snap.RestartAlways,
+			RestartDelay: timeout.Timeout(10 * time.Second),
+		},
+	})
+
+	chg := s.runChange(c, ts)
+	c.Assert(chg.Err(), IsNil)
+
+	overrides, err := servicestate.ServiceOverrides(st, "test-snap")
+	c.Assert(err, IsNil)
+	c.Check(overrides, DeepEquals, map[string]*wrappers.ServiceOverride{
+		"svc1": {
+			Restart:      snap.RestartAlways,
+			RestartDelay: timeout.Timeout(10 * time.Second),
+		},
+	})
+	c.Check(overrideFile(), testutil.FileEquals, `[Service]
+# Auto-generated, DO NOT EDIT
+Restart=always
+RestartSec=10
+`)
+
+	// settings are added to the ones already overridden
+	ts, err = servicestate.SetServiceOverride(st, "test-snap", "svc1", map[string]string{
+		"restart-delay":   "1m",
+		"environment.FOO": "bar",
+	})
+	c.Assert(err, IsNil)
+	chg = s.runChange(c, ts)
+	c.Assert(chg.Err(), IsNil)
+	c.Check(overrideFile(), testutil.FileEquals, `[Service]
+# Auto-generated, DO NOT EDIT
+Restart=always
+RestartSec=60
+Environment="FOO=bar"
+`)
+
+	ts, err = servicestate.ResetServiceOverride(st, "test-snap", "svc1", []string{"restart", "environment.FOO"})
+	c.Assert(err, IsNil)
+	c.Check(ts.Tasks()[0].Summary(), Equals, `Reset overridden settings of service "test-snap.svc1"`)
+	chg = s.runChange(c, ts)
+	c.Assert(chg.Err(), IsNil)
+	overrides, err = servicestate.ServiceOverrides(st, "test-snap")
+	c.Assert(err, IsNil)
+	c.Check(overrides, DeepEquals, map[string]*wrappers.ServiceOverride{
+		"svc1": {RestartDelay: timeout.Timeout(time.Minute)},
+	})
+
+	ts, err = servicestate.ResetServiceOverride(st, "test-snap", "svc1", nil)
+	c.Assert(err, IsNil)
+	chg = s.runChange(c, ts)
+	c.Assert(chg.Err(), IsNil)
+	all, err := servicestate.AllServiceOverrides(st)
+	c.Assert(err, IsNil)
+	c.Check(all, HasLen, 0)
+	c.Check(overrideFile(), testutil.FileAbsent)
+}
+
+func (s *serviceOverridesSuite) TestResetLastSettingRemovesOverride(c *C) {
+	st := s.state
+	st.Lock()
+	defer st.Unlock()
+
+	err := servicestate.SetServiceOverrideInState(st, "test-snap", "svc1", &wrappers.ServiceOverride{Restart: snap.RestartAlways})
+	c.Assert(err, IsNil)
+
+	ts, err := servicestate.ResetServiceOverride(st, "test-snap", "svc1", []string{"restart"})
+	c.Assert(err, IsNil)
+	var action servicestate.ServiceOverrideAction
+	c.Assert(ts.Tasks()[0].Get("service-override-action", &action), IsNil)
+	c.Check(action.Override, IsNil)
+}
+
+func (s *serviceOverridesSuite) TestSetServiceOverrideErrors(c *C) {
+	st := s.state
+	st.Lock()
+	defer st.Unlock()
+
+	for _, t := range []struct {
+		snap, app string
+		settings  map[string]string
+		err       string
+	}{
+		{"test-snap", "svc1", nil, `no settings to override`},
+		{"other-snap", "svc1", map[string]string{"restart": "always"}, `snap "other-snap" is not installed`},
+		{"test-snap", "svc2", map[string]string{"restart": "always"}, `snap "test-snap" has no service "svc2"`},
+		{"test-snap", "svc1", map[string]string{"restart": "sometimes"}, `invalid restart condition "sometimes"`},
+		{"test-snap", "svc1", map[string]string{"start-timeout": "forever"}, `invalid start-timeout "forever", expected a positive duration`},
+		{"test-snap", "svc1", map[string]string{"stop-timeout": "-1s"}, `invalid stop-timeout "-1s", expected a positive duration`},
+		{"test-snap", "svc1", map[string]string{"environment.1FOO": "bar"}, `invalid environment variable name "1FOO"`},
+		{"test-snap", "svc1", map[string]string{"environment.": "bar"}, `invalid environment variable name ""`},
+		{"test-snap", "svc1", map[string]string{"user": "root"}, `cannot override unsupported setting "user"`},
+	} {
+		_, err := servicestate.SetServiceOverride(st, t.snap, t.app, t.settings)
+		c.Check(err, ErrorMatches, t.err, Commentf("%v", t.settings))
+	}
+}
+
+func (s *serviceOverridesSuite) TestResetServiceOverrideErrors(c *C) {
+	st := s.state
+	st.Lock()
+	defer st.Unlock()
+
+	_, err := servicestate.ResetServiceOverride(st, "other-snap", "svc1", nil)
+	c.Check(err, ErrorMatches, `snap "other-snap" is not installed`)
+	_, err = servicestate.ResetServiceOverride(st, "test-snap", "svc1", nil)
+	c.Check(err, ErrorMatches, `no settings overridden for service "test-snap.svc1"`)
+
+	err = servicestate.SetServiceOverrideInState(st, "test-snap", "svc1", &wrappers.ServiceOverride{Restart: snap.RestartAlways})
+	c.Assert(err, IsNil)
+	_, err = servicestate.ResetServiceOverride(st, "test-snap", "svc1", []string{"user"})
+	c.Check(err, ErrorMatches, `cannot reset unsupported setting "user"`)
+	_, err = servicestate.ResetServiceOverride(st, "test-snap", "svc1", []string{"environment.FOO-BAR"})
+	c.Check(err, ErrorMatches, `invalid environment variable name "FOO-BAR"`)
+}
+
+func (s *serviceOverridesSuite) TestSetServiceOverrideConflict(c *C) {
+	st := s.state
+	st.Lock()
+	defer st.Unlock()
+
+	ts, err := servicestate.SetServiceOverride(st, "test-snap", "svc1", map[string]string{"restart": "always"})
+	c.Assert(err, IsNil)
+	chg := st.NewChange("service-override", "...")
+	chg.AddAll(ts)
+
+	_, err = servicestate.SetServiceOverride(st, "test-snap", "svc1", map[string]string{"restart": "no"})
+	c.Check(err, ErrorMatches, `snap "test-snap" has "service-override" change in progress`)
+
+	// and other changes of the snap conflict with it too
+	_, err = snapstate.Disable(st, "test-snap")
+	c.Check(err, ErrorMatches, `snap "test-snap" has "service-override" change in progress`)
+}
+
+func (s *serviceOverridesSuite) TestSetServiceOverrideUndo(c *C) {
+	r := s.mockSystemctlCalls(c, join(
+		// do
+		[]expectedSystemctl{{expArgs: []string{"daemon-reload"}}},
+		systemctlCallsForServiceRestart("test-snap"),
+		// undo
+		[]expectedSystemctl{{expArgs: []string{"daemon-reload"}}},
+		systemctlCallsForServiceRestart("test-snap"),
+	))
+	defer r()
+
+	st := s.state
+	st.Lock()
+	defer st.Unlock()
+
+	// the unit exists already
+	info, err := snapstate.CurrentInfo(st, "test-snap")
+	c.Assert(err, IsNil)
+	c.Assert(wrappers.AddSnapServices(info, &wrappers.AddSnapServicesOptions{Preseeding: true}, nil), IsNil)
+
+	s.o.TaskRunner().AddHandler("error-trigger", func(*state.Task, *tomb.Tomb) error {
+		return errors.New("boom")
+	}, nil)
+
+	ts, err := servicestate.SetServiceOverride(st, "test-snap", "svc1", map[string]string{"watchdog-timeout": "30s"})
+	c.Assert(err, IsNil)
+	errTask := st.NewTask("error-trigger", "...")
+	errTask.WaitAll(ts)
+	ts.AddTask(errTask)
+
+	chg := s.runChange(c, ts)
+	c.Assert(chg.Err(), ErrorMatches, `(?s).*boom.*`)
+	c.Check(ts.Tasks()[0].Status(), Equals, state.UndoneStatus)
+
+	all, err := servicestate.AllServiceOverrides(st)
+	c.Assert(err, IsNil)
+	c.Check(all, HasLen, 0)
+	c.Check(overrideFile(), testutil.FileAbsent)
+}
+
+func (s *serviceOverridesSuite) TestRemoveServiceOverrides(c *C) {
+	st := s.state
+	st.Lock()
+	defer st.Unlock()
+
+	// nothing to do
+	c.Assert(servicestate.RemoveServiceOverrides(st, "test-snap"), IsNil)
+
+	override := &wrappers.ServiceOverride{Restart: snap.RestartAlways}
+	c.Assert(servicestate.SetServiceOverrideInState(st, "test-snap", "svc1", override), IsNil)
+	c.Assert(servicestate.SetServiceOverrideInState(st, "other-snap", "svc", override), IsNil)
+
+	c.Assert(servicestate.RemoveServiceOverrides(st, "test-snap"), IsNil)
+	all, err := servicestate.AllServiceOverrides(st)
+	c.Assert(err, IsNil)
+	c.Check(all, DeepEquals, map[string]map[string]*wrappers.ServiceOverride{
+		"other-snap": {"svc": override},
+	})
+}
+
+func (s *serviceOverridesSuite) TestSnapServiceOptionsServiceOverrides(c *C) {
+	st := s.state
+	st.Lock()
+	defer st.Unlock()
+
+	override := &wrappers.ServiceOverride{
+		Environment: map[string]string{"FOO": "bar"},
+	}
+	c.Assert(servicestate.SetServiceOverrideInState(st, "test-snap", "svc1", override), IsNil)
+
+	opts, err := servicestate.SnapServiceOptions(st, "test-snap", nil)
+	c.Assert(err, IsNil)
+	c.Check(opts, DeepEquals, &wrappers.SnapServiceOptions{
+		ServiceOverrides: map[string]*wrappers.ServiceOverride{"svc1": override},
+	})
+}
+
+func (s *serviceOverridesSuite) TestServiceOverrideSettings(c *C) {
+	override := &wrappers.ServiceOverride{
+		Restart:         snap.RestartNever,
+		RestartDelay:    timeout.Timeout(10 * time.Second),
+		StartTimeout:    timeout.Timeout(time.Minute),
+		StopTimeout:     timeout.Timeout(90 * time.Second),
+		WatchdogTimeout: timeout.Timeout(500 * time.Millisecond),
+		Environment:     map[string]string{"FOO": "bar", "EMPTY": ""},
+	}
+	settings := servicestate.ServiceOverrideSettings(override)
+	c.Check(settings, DeepEquals, map[string]string{
+		"restart":           "never",
+		"restart-delay":     "10s",
+		"start-timeout":     "1m0s",
+		"stop-timeout":      "1m30s",
+		"watchdog-timeout":  "500ms",
+		"environment.FOO":   "bar",
+		"environment.EMPTY": "",
+	})
+	c.Check(servicestate.ServiceOverrideSettings(&wrappers.ServiceOverride{}), HasLen, 0)
+}
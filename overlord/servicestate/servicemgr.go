@@ -60,7 +60,10 @@ func Manager(st *state.State, runner *state.TaskRunner) *ServiceManager {
 	// TODO: undo handler
 	runner.AddHandler("quota-control", m.doQuotaControl, nil)
 
+	runner.AddHandler("service-override", m.doServiceOverride, m.undoServiceOverride)
+
 	snapstate.AddAffectedSnapsByKind("quota-control", quotaControlAffectedSnaps)
+	snapstate.AddAffectedSnapsByKind("service-override", serviceOverrideAffectedSnaps)
 
 	return m
 }
@@ -201,6 +204,7 @@ func delayedCrossMgrInit() {
 	snapstate.AddAffectedSnapsByAttr("service-action", serviceControlAffectedSnaps)
 	snapstate.SnapServiceOptions = SnapServiceOptions
 	snapstate.EnsureSnapAbsentFromQuotaGroup = EnsureSnapAbsentFromQuota
+	snapstate.RemoveServiceOverrides = RemoveServiceOverrides
 }
 
 func serviceControlAffectedSnaps(t *state.Task) ([]string, error) {
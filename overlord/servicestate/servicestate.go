@@ -20,13 +20,17 @@
 package servicestate
 
 import (
+	"context"
 	"fmt"
+	"os/user"
 	"path/filepath"
 	"sort"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/snapcore/snapd/client"
+	"github.com/snapcore/snapd/logger"
 	"github.com/snapcore/snapd/overlord/cmdstate"
 	"github.com/snapcore/snapd/overlord/configstate/config"
 	"github.com/snapcore/snapd/overlord/hookstate"
@@ -36,12 +40,20 @@ import (
 	"github.com/snapcore/snapd/snap/quota"
 	"github.com/snapcore/snapd/strutil"
 	"github.com/snapcore/snapd/systemd"
+	"github.com/snapcore/snapd/timeout"
+	userclient "github.com/snapcore/snapd/usersession/client"
 	"github.com/snapcore/snapd/wrappers"
 )
 
 type Instruction struct {
 	Action string   `json:"action"`
 	Names  []string `json:"names"`
+	// Users, if set, restricts the action to the user daemons in the
+	// sessions of the selected users.
+	Users client.UserSelection `json:"users,omitempty"`
+	// Uids are the users selected by client.UserSelectionSelf, as
+	// found out from the credentials of the caller.
+	Uids []int `json:"-"`
 	client.StartOptions
 	client.StopOptions
 	client.RestartOptions
@@ -97,7 +109,10 @@ func serviceControlTs(st *state.State, appInfos []*snap.AppInfo, inst *Instructi
 			return nil, err
 		}
 
-		cmd := &ServiceAction{SnapName: snapName}
+		cmd := &ServiceAction{SnapName: snapName, Users: inst.Users}
+		if inst.Users == client.UserSelectionSelf {
+			cmd.Uids = inst.Uids
+		}
 		switch {
 		case inst.Action == "start":
 			cmd.Action = "start"
@@ -298,17 +313,22 @@ func (sd *StatusDecorator) DecorateWithStatus(appInfo *client.AppInfo, snapApp *
 	if len(sts) != len(serviceNames) {
 		return fmt.Errorf("cannot get status of services of app %q: expected %d results, got %d", appInfo.Name, len(serviceNames), len(sts))
 	}
+	var result string
 	for _, st := range sts {
 		switch filepath.Ext(st.UnitName) {
 		case ".service":
 			appInfo.Enabled = st.Enabled
 			appInfo.Active = st.Active
+			result = st.Result
 		case ".timer":
 			appInfo.Activators = append(appInfo.Activators, client.AppActivator{
-				Name:    snapApp.Name,
-				Enabled: st.Enabled,
-				Active:  st.Active,
-				Type:    "timer",
+				Name:     snapApp.Name,
+				Enabled:  st.Enabled,
+				Active:   st.Active,
+				Type:     "timer",
+				Schedule: snapApp.Timer.Timer,
+				NextRun:  st.NextElapse,
+				LastRun:  st.LastTrigger,
 			})
 		case ".socket":
 			appInfo.Activators = append(appInfo.Activators, client.AppActivator{
@@ -316,9 +336,17 @@ func (sd *StatusDecorator) DecorateWithStatus(appInfo *client.AppInfo, snapApp *
 				Enabled: st.Enabled,
 				Active:  st.Active,
 				Type:    "socket",
+				Listen:  st.Listen,
 			})
 		}
 	}
+	// the result of the service is only meaningful for a timer if the
+	// timer already elapsed
+	for i := range appInfo.Activators {
+		if act := &appInfo.Activators[i]; act.Type == "timer" && !act.LastRun.IsZero() {
+			act.LastResult = result
+		}
+	}
 	// Decorate with D-Bus names that activate this service
 	for _, slot := range snapApp.ActivatesOn {
 		var busName string
@@ -380,5 +408,104 @@ func SnapServiceOptions(st *state.State, instanceName string, quotaGroups map[st
 		}
 	}
 
+	// and for settings overridden by the administrator
+	opts.ServiceOverrides, err = ServiceOverrides(st, instanceName)
+	if err != nil {
+		return nil, err
+	}
+
 	return opts, nil
 }
+
+var userLookupId = user.LookupId
+
+// UserStatusDecorator supports decorating client.AppInfos of user daemons
+// with their status in the sessions of the users.
+type UserStatusDecorator struct {
+	uids     []int
+	services []string
+
+	fetched  bool
+	statuses map[int][]*userclient.ServiceUnitStatus
+}
+
+// NewUserStatusDecorator returns a new UserStatusDecorator that asks the
+// session agents of the given users, or of all the active user sessions if
+// uids is empty, about the user daemons among the given apps. The agents
+// are asked once, when the first app is decorated.
+func NewUserStatusDecorator(uids []int, apps []*snap.AppInfo) *UserStatusDecorator {
+	var services []string
+	for _, app := range apps {
+		if app.Snap.IsActive() && app.IsService() && app.DaemonScope == snap.UserDaemon {
+			services = append(services, app.ServiceName())
+		}
+	}
+	return &UserStatusDecorator{uids: uids, services: services}
+}
+
+func (sd *UserStatusDecorator) fetchStatuses() {
+	if sd.fetched {
+		return
+	}
+	sd.fetched = true
+	if len(sd.services) == 0 {
+		return
+	}
+
+	var cli *userclient.Client
+	if len(sd.uids) == 0 {
+		cli = userclient.New()
+	} else {
+		cli = userclient.NewForUids(sd.uids...)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(timeout.DefaultTimeout))
+	defer cancel()
+	sts, err := cli.ServiceStatus(ctx, sd.services)
+	if err != nil {
+		logger.Noticef("cannot get status of user services in all sessions: %v", err)
+	}
+	sd.statuses = sts
+}
+
+// DecorateWithStatus adds the status of the user daemon in each of the
+// user sessions to the given client.AppInfo associated with the given
+// snap.AppInfo. If the snap is inactive or the app is not a user daemon it
+// does nothing. Sessions whose agents cannot be reached are left out.
+func (sd *UserStatusDecorator) DecorateWithStatus(appInfo *client.AppInfo, snapApp *snap.AppInfo) error {
+	if appInfo.Snap != snapApp.Snap.InstanceName() || appInfo.Name != snapApp.Name {
+		return fmt.Errorf("internal error: misassociated app info %v and client app info %s.%s", snapApp, appInfo.Snap, appInfo.Name)
+	}
+	if !snapApp.Snap.IsActive() || !snapApp.IsService() || snapApp.DaemonScope != snap.UserDaemon {
+		// nothing to do
+		return nil
+	}
+	serviceName := snapApp.ServiceName()
+	if !strutil.ListContains(sd.services, serviceName) {
+		return fmt.Errorf("internal error: user service %q was not among the apps the decorator was created for", serviceName)
+	}
+	sd.fetchStatuses()
+
+	uids := make([]int, 0, len(sd.statuses))
+	for uid := range sd.statuses {
+		uids = append(uids, uid)
+	}
+	sort.Ints(uids)
+	for _, uid := range uids {
+		for _, st := range sd.statuses[uid] {
+			if st.Id != serviceName {
+				continue
+			}
+			userStatus := client.AppUserStatus{
+				Uid:     uid,
+				Enabled: st.Enabled,
+				Active:  st.Active,
+			}
+			if u, err := userLookupId(strconv.Itoa(uid)); err == nil {
+				userStatus.User = u.Username
+			}
+			appInfo.UserStatus = append(appInfo.UserStatus, userStatus)
+		}
+	}
+
+	return nil
+}
@@ -22,9 +22,15 @@ package servicestate_test
 import (
 	"bytes"
 	"fmt"
+	"net"
+	"net/http"
 	"os"
+	"os/user"
 	"path/filepath"
+	"sort"
 	"strings"
+	"sync"
+	"time"
 
 	. "gopkg.in/check.v1"
 
@@ -71,16 +77,30 @@ func (s *statusDecoratorSuite) TestDecorateWithStatus(c *C) {
 				activeState = "inactive"
 				unitState = "disabled"
 			}
-			if strings.HasSuffix(unit, ".timer") || strings.HasSuffix(unit, ".socket") {
+			switch {
+			case strings.HasSuffix(unit, ".timer"):
+				lastTrigger, nextElapse := "Mon 2021-03-01 10:00:00 UTC", "Tue 2021-03-02 10:00:00 UTC"
+				if disabled {
+					lastTrigger, nextElapse = "n/a", ""
+				}
 				return []byte(fmt.Sprintf(`Id=%s
 ActiveState=%s
 UnitFileState=%s
+NextElapseUSecRealtime=%s
+LastTriggerUSec=%s
+`, args[2], activeState, unitState, nextElapse, lastTrigger)), nil
+			case strings.HasSuffix(unit, ".socket"):
+				return []byte(fmt.Sprintf(`Id=%s
+ActiveState=%s
+UnitFileState=%s
+Listen=/var/snap/foo/common/a.socket (Stream)
 `, args[2], activeState, unitState)), nil
-			} else {
+			default:
 				return []byte(fmt.Sprintf(`Id=%s
 Type=simple
 ActiveState=%s
 UnitFileState=%s
+Result=exit-code
 `, args[2], activeState, unitState)), nil
 			}
 		case "--user":
@@ -152,9 +172,13 @@ UnitFileState=%s
 		c.Assert(err, IsNil)
 		c.Check(app.Active, Equals, enabled)
 		c.Check(app.Enabled, Equals, enabled)
-		c.Check(app.Activators, DeepEquals, []client.AppActivator{
-			{Name: "svc", Type: "timer", Active: enabled, Enabled: enabled},
-		})
+		timer := client.AppActivator{Name: "svc", Type: "timer", Active: enabled, Enabled: enabled, Schedule: "10:00"}
+		if enabled {
+			timer.LastRun = time.Date(2021, 3, 1, 10, 0, 0, 0, time.UTC)
+			timer.NextRun = time.Date(2021, 3, 2, 10, 0, 0, 0, time.UTC)
+			timer.LastResult = "exit-code"
+		}
+		c.Check(app.Activators, DeepEquals, []client.AppActivator{timer})
 
 		// service with socket
 		app = &client.AppInfo{
@@ -181,7 +205,7 @@ UnitFileState=%s
 		c.Check(app.Active, Equals, enabled)
 		c.Check(app.Enabled, Equals, enabled)
 		c.Check(app.Activators, DeepEquals, []client.AppActivator{
-			{Name: "socket1", Type: "socket", Active: enabled, Enabled: enabled},
+			{Name: "socket1", Type: "socket", Active: enabled, Enabled: enabled, Listen: []string{"/var/snap/foo/common/a.socket"}},
 		})
 
 		// service with D-Bus activation
@@ -257,12 +281,109 @@ UnitFileState=%s
 		c.Check(app.Enabled, Equals, enabled)
 		c.Check(app.Activators, DeepEquals, []client.AppActivator{
 			{Name: "socket1", Type: "socket", Active: false, Enabled: enabled},
-			{Name: "svc", Type: "timer", Active: false, Enabled: enabled},
+			{Name: "svc", Type: "timer", Active: false, Enabled: enabled, Schedule: "10:00"},
 			{Name: "org.example.Svc", Type: "dbus", Active: true, Enabled: true},
 		})
 	}
 }
 
+func (s *statusDecoratorSuite) TestUserStatusDecorator(c *C) {
+	dirs.SetRootDir(c.MkDir())
+	defer dirs.SetRootDir("")
+	snp := &snap.Info{
+		SideInfo: snap.SideInfo{
+			RealName: "foo",
+			Revision: snap.R(1),
+		},
+	}
+	err := os.MkdirAll(snp.MountDir(), 0755)
+	c.Assert(err, IsNil)
+	err = os.Symlink(snp.Revision.String(), filepath.Join(filepath.Dir(snp.MountDir()), "current"))
+	c.Assert(err, IsNil)
+
+	restore := servicestate.MockUserLookupId(func(uid string) (*user.User, error) {
+		if uid == "1000" {
+			return &user.User{Uid: uid, Username: "jane"}, nil
+		}
+		return nil, user.UnknownUserIdError(42)
+	})
+	defer restore()
+
+	// fake session agents for two users
+	var mu sync.Mutex
+	var requests []string
+	server := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c.Check(r.URL.Path, Equals, "/v1/service-status")
+		c.Check(r.URL.Query().Get("services"), Equals, "snap.foo.svc.service,snap.foo.other.service")
+		mu.Lock()
+		requests = append(requests, r.Host)
+		mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(200)
+		active := r.Host == "1000"
+		fmt.Fprintf(w, `{"type": "sync", "result": [{"daemon": "simple", "id": "snap.foo.svc.service", "enabled": true, "active": %v}, {"daemon": "simple", "id": "snap.foo.other.service", "enabled": false, "active": false}]}`, active)
+	})}
+	defer server.Close()
+	for _, uid := range []int{1000, 42} {
+		sock := fmt.Sprintf("%s/%d/snapd-session-agent.socket", dirs.XdgRuntimeDirBase, uid)
+		c.Assert(os.MkdirAll(filepath.Dir(sock), 0755), IsNil)
+		l, err := net.Listen("unix", sock)
+		c.Assert(err, IsNil)
+		go server.Serve(l)
+	}
+
+	snapApp := &snap.AppInfo{
+		Snap:        snp,
+		Name:        "svc",
+		Daemon:      "simple",
+		DaemonScope: snap.UserDaemon,
+	}
+	otherApp := &snap.AppInfo{
+		Snap:        snp,
+		Name:        "other",
+		Daemon:      "simple",
+		DaemonScope: snap.UserDaemon,
+	}
+	apps := []*snap.AppInfo{snapApp, otherApp}
+	app := &client.AppInfo{Snap: "foo", Name: "svc", Daemon: "simple"}
+	other := &client.AppInfo{Snap: "foo", Name: "other", Daemon: "simple"}
+	sd := servicestate.NewUserStatusDecorator(nil, apps)
+	c.Assert(sd.DecorateWithStatus(app, snapApp), IsNil)
+	c.Assert(sd.DecorateWithStatus(other, otherApp), IsNil)
+	c.Check(app.UserStatus, DeepEquals, []client.AppUserStatus{
+		{Uid: 42, Enabled: true},
+		{Uid: 1000, User: "jane", Enabled: true, Active: true},
+	})
+	c.Check(other.UserStatus, DeepEquals, []client.AppUserStatus{
+		{Uid: 42},
+		{Uid: 1000, User: "jane"},
+	})
+	// each agent was asked only once about all the services
+	sort.Strings(requests)
+	c.Check(requests, DeepEquals, []string{"1000", "42"})
+
+	// only the selected users are asked
+	requests = nil
+	app = &client.AppInfo{Snap: "foo", Name: "svc", Daemon: "simple"}
+	sd = servicestate.NewUserStatusDecorator([]int{1000}, apps)
+	c.Assert(sd.DecorateWithStatus(app, snapApp), IsNil)
+	c.Check(app.UserStatus, DeepEquals, []client.AppUserStatus{
+		{Uid: 1000, User: "jane", Enabled: true, Active: true},
+	})
+	c.Check(requests, DeepEquals, []string{"1000"})
+
+	// apps the decorator was not created for are refused
+	app = &client.AppInfo{Snap: "foo", Name: "svc", Daemon: "simple"}
+	sd = servicestate.NewUserStatusDecorator(nil, []*snap.AppInfo{otherApp})
+	c.Check(sd.DecorateWithStatus(app, snapApp), ErrorMatches, `internal error: user service "snap.foo.svc.service" was not among the apps the decorator was created for`)
+
+	// system daemons are left alone
+	snapApp.DaemonScope = snap.SystemDaemon
+	app = &client.AppInfo{Snap: "foo", Name: "svc", Daemon: "simple"}
+	c.Assert(sd.DecorateWithStatus(app, snapApp), IsNil)
+	c.Check(app.UserStatus, IsNil)
+}
+
 type snapServiceOptionsSuite struct {
 	testutil.BaseTest
 	state *state.State
@@ -194,9 +194,11 @@ func (b Backend) generateWrappers(s *snap.Info, linkCtx LinkContext) error {
 
 	vitalityRank := 0
 	var quotaGrp *quota.Group
+	var overrides map[string]*wrappers.ServiceOverride
 	if linkCtx.ServiceOptions != nil {
 		vitalityRank = linkCtx.ServiceOptions.VitalityRank
 		quotaGrp = linkCtx.ServiceOptions.QuotaGroup
+		overrides = linkCtx.ServiceOptions.ServiceOverrides
 	}
 	// add the daemons from the snap.yaml
 	opts := &wrappers.AddSnapServicesOptions{
@@ -204,6 +206,7 @@ func (b Backend) generateWrappers(s *snap.Info, linkCtx LinkContext) error {
 		Preseeding:              b.preseed,
 		RequireMountedSnapdSnap: linkCtx.RequireMountedSnapdSnap,
 		QuotaGroup:              quotaGrp,
+		ServiceOverrides:        overrides,
 	}
 	// TODO: switch to EnsureSnapServices
 	if err = wrappers.AddSnapServices(s, opts, progress.Null); err != nil {
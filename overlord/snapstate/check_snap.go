@@ -34,6 +34,7 @@ import (
 	"github.com/snapcore/snapd/release"
 	seccomp_compiler "github.com/snapcore/snapd/sandbox/seccomp"
 	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/snap/configschema"
 	"github.com/snapcore/snapd/snapdtool"
 	"github.com/snapcore/snapd/strutil"
 )
@@ -231,6 +232,11 @@ var openSnapFile = backend.OpenSnapFile
 
 func validateContainer(c snap.Container, s *snap.Info, logf func(format string, v ...interface{})) error {
 	err := snap.ValidateContainer(c, s, logf)
+	if err == nil {
+		// a snap with an invalid configuration schema could not be
+		// configured
+		_, err = configschema.ReadSchemaFromSnapFile(c)
+	}
 	if err == nil {
 		return nil
 	}
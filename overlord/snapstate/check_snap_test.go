@@ -275,6 +275,29 @@ version: 1.0`
 	c.Check(checkCbCalled, Equals, true)
 }
 
+func (s *checkSnapSuite) TestCheckSnapConfigSchema(c *C) {
+	const yaml = `name: foo
+version: 1.0`
+
+	info, err := snap.InfoFromSnapYaml([]byte(yaml))
+	c.Assert(err, IsNil)
+
+	schema := "properties: {port: {type: integer}}"
+	var openSnapFile = func(path string, si *snap.SideInfo) (*snap.Info, snap.Container, error) {
+		return info, snaptest.MockContainer(c, [][]string{{"meta/config-schema.yaml", schema}}), nil
+	}
+	restore := snapstate.MockOpenSnapFile(openSnapFile)
+	defer restore()
+
+	err = snapstate.CheckSnap(s.st, "snap-path", "foo", nil, nil, snapstate.Flags{}, nil)
+	c.Check(err, IsNil)
+
+	// a snap that could not be configured is not installed
+	schema = "properties: {port: {type: integer, default: http}}"
+	err = snapstate.CheckSnap(s.st, "snap-path", "foo", nil, nil, snapstate.Flags{}, nil)
+	c.Check(err, ErrorMatches, `invalid configuration schema: .*; contact developer`)
+}
+
 func (s *checkSnapSuite) TestCheckSnapCheckCallbackFail(c *C) {
 	const yaml = `name: foo
 version: 1.0`
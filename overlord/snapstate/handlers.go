@@ -63,6 +63,11 @@ var EnsureSnapAbsentFromQuotaGroup = func(st *state.State, snap string) error {
 	panic("internal error: snapstate.EnsureSnapAbsentFromQuotaGroup is unset")
 }
 
+// RemoveServiceOverrides is a hook set by servicestate.
+var RemoveServiceOverrides = func(st *state.State, snap string) error {
+	panic("internal error: snapstate.RemoveServiceOverrides is unset")
+}
+
 var SecurityProfilesRemoveLate = func(snapName string, rev snap.Revision, typ snap.Type) error {
 	panic("internal error: snapstate.SecurityProfilesRemoveLate is unset")
 }
@@ -2298,6 +2303,11 @@ func (m *SnapManager) doDiscardSnap(t *state.Task, _ *tomb.Tomb) error {
 		if err := EnsureSnapAbsentFromQuotaGroup(st, snapsup.InstanceName()); err != nil {
 			return err
 		}
+
+		// and forget the settings overridden for its services
+		if err := RemoveServiceOverrides(st, snapsup.InstanceName()); err != nil {
+			return err
+		}
 	}
 	if err = config.DiscardRevisionConfig(st, snapsup.InstanceName(), snapsup.Revision()); err != nil {
 		return err
@@ -49,6 +49,11 @@ func (s *discardSnapSuite) SetUpTest(c *C) {
 	s.AddCleanup(func() {
 		snapstate.EnsureSnapAbsentFromQuotaGroup = oldSnapStateEnsureSnapAbsentFromQuotaGroup
 	})
+	oldSnapStateRemoveServiceOverrides := snapstate.RemoveServiceOverrides
+	snapstate.RemoveServiceOverrides = servicestate.RemoveServiceOverrides
+	s.AddCleanup(func() {
+		snapstate.RemoveServiceOverrides = oldSnapStateRemoveServiceOverrides
+	})
 
 	s.AddCleanup(snapstatetest.MockDeviceModel(DefaultModel()))
 }
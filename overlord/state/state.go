@@ -408,6 +408,31 @@ func (s *State) NewTask(kind, summary string) *Task {
 	return t
 }
 
+// DiscardTasks removes from the state tasks that were created but
+// never linked to a change, for example because setting up the rest of
+// an operation failed. Discarding tasks linked to a change panics.
+func (s *State) DiscardTasks(tasks []*Task) {
+	s.writing()
+	for _, t := range tasks {
+		if t.Change() != nil {
+			panic(fmt.Sprintf("internal error: cannot discard task %q of change %q", t.ID(), t.Change().ID()))
+		}
+	}
+	for _, t := range tasks {
+		for _, wt := range s.tasksIn(t.waitTasks) {
+			if wt != nil {
+				wt.haltTasks = removeOnce(wt.haltTasks, t.id)
+			}
+		}
+		for _, ht := range s.tasksIn(t.haltTasks) {
+			if ht != nil {
+				ht.waitTasks = removeOnce(ht.waitTasks, t.id)
+			}
+		}
+		delete(s.tasks, t.id)
+	}
+}
+
 // Tasks returns all tasks currently known to the state and linked to changes.
 func (s *State) Tasks() []*Task {
 	s.reading()
@@ -693,6 +693,7 @@ func (ss *stateSuite) TestMethodEntrance(c *C) {
 		func() { st.NewTask("download", "...") },
 		func() { st.UnmarshalJSON(nil) },
 		func() { st.NewLane() },
+		func() { st.DiscardTasks(nil) },
 		func() { st.Warnf("hello") },
 		func() { st.OkayWarnings(time.Time{}) },
 		func() { st.UnshowAllWarnings() },
@@ -731,6 +732,29 @@ func (ss *stateSuite) TestMethodEntrance(c *C) {
 	}
 }
 
+func (ss *stateSuite) TestDiscardTasks(c *C) {
+	st := state.New(nil)
+	st.Lock()
+	defer st.Unlock()
+
+	chg := st.NewChange("install", "...")
+	t1 := st.NewTask("download", "...")
+	chg.AddTask(t1)
+
+	t2 := st.NewTask("check", "...")
+	t2.WaitFor(t1)
+	t3 := st.NewTask("link", "...")
+	t3.WaitFor(t2)
+	c.Check(st.TaskCount(), Equals, 3)
+
+	st.DiscardTasks([]*state.Task{t2, t3})
+	c.Check(st.TaskCount(), Equals, 1)
+	c.Check(t1.HaltTasks(), HasLen, 0)
+
+	c.Check(func() { st.DiscardTasks([]*state.Task{t1}) }, PanicMatches, `internal error: cannot discard task "1" of change "1"`)
+	c.Check(st.TaskCount(), Equals, 1)
+}
+
 func (ss *stateSuite) TestPrune(c *C) {
 	st := state.New(&fakeStateBackend{})
 	st.Lock()
@@ -400,6 +400,15 @@ func addOnce(set []string, s string) []string {
 	return append(set, s)
 }
 
+func removeOnce(set []string, s string) []string {
+	for i, cur := range set {
+		if s == cur {
+			return append(set[:i:i], set[i+1:]...)
+		}
+	}
+	return set
+}
+
 // WaitFor registers another task as a requirement for t to make progress.
 func (t *Task) WaitFor(another *Task) {
 	t.state.writing()
@@ -67,6 +67,25 @@ func securityTagFromCgroupPath(path string) naming.SecurityTag {
 	return nil
 }
 
+// trackingCgroupsRoot returns the directory under which the cgroups used
+// for tracking the processes of snaps are found.
+func trackingCgroupsRoot() (string, error) {
+	ver, err := Version()
+	if err != nil {
+		return "", err
+	}
+	if ver == V2 {
+		// In v2 mode scan all of /sys/fs/cgroup as there is no specialization
+		// anymore (each directory represents a hierarchy with equal
+		// capabilities and old split into controllers is gone).
+		return filepath.Join(rootPath, cgroupMountPoint), nil
+	}
+	// In v1 mode scan just /sys/fs/cgroup/systemd as that is sufficient
+	// for finding snap-specific cgroup names. Systemd uses this for
+	// tracking and scopes and services are represented there.
+	return filepath.Join(rootPath, cgroupMountPoint, "systemd"), nil
+}
+
 // PidsOfSnap returns the association of security tags to PIDs.
 //
 // NOTE: This function returns a reliable result only if the refresh-app-awareness
@@ -133,22 +152,10 @@ func PidsOfSnap(snapInstanceName string) (map[string][]int, error) {
 		return filepath.SkipDir
 	}
 
-	var cgroupPathToScan string
-	ver, err := Version()
+	cgroupPathToScan, err := trackingCgroupsRoot()
 	if err != nil {
 		return nil, err
 	}
-	if ver == V2 {
-		// In v2 mode scan all of /sys/fs/cgroup as there is no specialization
-		// anymore (each directory represents a hierarchy with equal
-		// capabilities and old split into controllers is gone).
-		cgroupPathToScan = filepath.Join(rootPath, cgroupMountPoint)
-	} else {
-		// In v1 mode scan just /sys/fs/cgroup/systemd as that is sufficient
-		// for finding snap-specific cgroup names. Systemd uses this for
-		// tracking and scopes and services are represented there.
-		cgroupPathToScan = filepath.Join(rootPath, cgroupMountPoint, "systemd")
-	}
 	// NOTE: Walk is internally performed in lexical order so the output is
 	// deterministic and we don't need to sort the returned aggregated PIDs.
 	if err := filepath.Walk(cgroupPathToScan, walkFunc); err != nil {
@@ -0,0 +1,371 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package cgroup
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/snapcore/snapd/strutil"
+)
+
+// Resources whose accounting may not be available.
+const (
+	UsageCPUTime = "cpu-time"
+	UsageMemory  = "memory"
+	UsageIO      = "io"
+)
+
+// Usage describes the resources used by the processes of a group.
+type Usage struct {
+	// CPUTime is the total CPU time consumed by the processes.
+	CPUTime time.Duration
+	// Memory is the memory currently used, in bytes.
+	Memory uint64
+	// Processes is the number of processes currently in the group.
+	Processes int
+	// IORead and IOWrite are the number of bytes read from and written
+	// to block devices.
+	IORead  uint64
+	IOWrite uint64
+	// Unavailable lists the resources, one of UsageCPUTime, UsageMemory
+	// or UsageIO, which are not accounted for because the corresponding
+	// controller is not enabled for the group. Their usage is zero.
+	Unavailable []string
+}
+
+// Add accumulates the given usage into u.
+func (u *Usage) Add(other *Usage) {
+	u.CPUTime += other.CPUTime
+	u.Memory += other.Memory
+	u.Processes += other.Processes
+	u.IORead += other.IORead
+	u.IOWrite += other.IOWrite
+	for _, resource := range other.Unavailable {
+		u.unavailable(resource)
+	}
+}
+
+func (u *Usage) unavailable(resource string) {
+	if strutil.ListContains(u.Unavailable, resource) {
+		return
+	}
+	u.Unavailable = append(u.Unavailable, resource)
+	sort.Strings(u.Unavailable)
+}
+
+// UsageOfSnap returns the resource usage of the processes of the given snap,
+// grouped by security tag.
+//
+// The usage is read from the cgroups used for tracking the snap processes,
+// the same ones that are considered by PidsOfSnap, and so shares its
+// limitations. The usage of a tracking cgroup includes that of the cgroups
+// nested in it.
+func UsageOfSnap(snapInstanceName string) (map[string]*Usage, error) {
+	return usageOfSnaps(func(instanceName string) bool {
+		return instanceName == snapInstanceName
+	})
+}
+
+// UsageOfAllSnaps returns the resource usage of the processes of all snaps,
+// grouped by security tag, with a single scan of the tracking cgroups.
+func UsageOfAllSnaps() (map[string]*Usage, error) {
+	return usageOfSnaps(func(string) bool { return true })
+}
+
+func usageOfSnaps(match func(instanceName string) bool) (map[string]*Usage, error) {
+	ver, err := Version()
+	if err != nil {
+		return nil, err
+	}
+	root, err := trackingCgroupsRoot()
+	if err != nil {
+		return nil, err
+	}
+
+	usageByTag := make(map[string]*Usage)
+	walkFunc := func(path string, fileInfo os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !fileInfo.IsDir() {
+			return nil
+		}
+		parsedTag := securityTagFromCgroupPath(path)
+		if parsedTag == nil || !match(parsedTag.InstanceName()) {
+			return nil
+		}
+		var usage *Usage
+		if ver == V2 {
+			usage, err = usageOfGroupV2(path)
+		} else {
+			// other controllers use the same layout as the
+			// systemd hierarchy
+			var rel string
+			rel, err = filepath.Rel(root, path)
+			if err == nil {
+				usage, err = usageOfGroupV1(path, rel)
+			}
+		}
+		if err != nil {
+			return err
+		}
+		tag := parsedTag.String()
+		if usageByTag[tag] == nil {
+			usageByTag[tag] = &Usage{}
+		}
+		usageByTag[tag].Add(usage)
+		// Nested groups are accounted for by the group.
+		return filepath.SkipDir
+	}
+	if err := filepath.Walk(root, walkFunc); err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return usageByTag, nil
+}
+
+// processesInTree returns the number of processes in the group at the
+// given path and all the groups nested in it, the controllers only account
+// for the processes of a group itself in cgroup.procs.
+func processesInTree(path string) (int, error) {
+	count := 0
+	err := filepath.Walk(path, func(path string, fileInfo os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !fileInfo.IsDir() {
+			return nil
+		}
+		pids, err := pidsInFile(filepath.Join(path, "cgroup.procs"))
+		if err != nil {
+			return err
+		}
+		count += len(pids)
+		return nil
+	})
+	return count, err
+}
+
+func usageOfGroupV2(path string) (*Usage, error) {
+	// The statistics of the cpu, memory and io controllers all include
+	// those of nested groups.
+	var usage Usage
+	var err error
+	if usage.Processes, err = processesInTree(path); err != nil {
+		return nil, err
+	}
+
+	cpuStat, err := readKeyedValues(filepath.Join(path, "cpu.stat"))
+	if err != nil {
+		return nil, err
+	}
+	if cpuStat == nil {
+		usage.unavailable(UsageCPUTime)
+	}
+	usage.CPUTime = time.Duration(cpuStat["usage_usec"]) * time.Microsecond
+
+	memory, ok, err := readUintFile(filepath.Join(path, "memory.current"))
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		usage.unavailable(UsageMemory)
+	}
+	usage.Memory = memory
+
+	// Each line of io.stat describes one device, as in:
+	// 8:0 rbytes=1024 wbytes=512 rios=2 wios=1 dbytes=0 dios=0
+	ok, err = scanLines(filepath.Join(path, "io.stat"), func(fields []string) error {
+		for _, field := range fields[1:] {
+			kv := strings.SplitN(field, "=", 2)
+			if len(kv) != 2 {
+				continue
+			}
+			var dst *uint64
+			switch kv[0] {
+			case "rbytes":
+				dst = &usage.IORead
+			case "wbytes":
+				dst = &usage.IOWrite
+			default:
+				continue
+			}
+			n, err := strconv.ParseUint(kv[1], 10, 64)
+			if err != nil {
+				return fmt.Errorf("cannot parse io.stat field %q: %v", field, err)
+			}
+			*dst += n
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		usage.unavailable(UsageIO)
+	}
+	return &usage, nil
+}
+
+func usageOfGroupV1(path, rel string) (*Usage, error) {
+	// The statistics of the cpuacct and memory controllers include those
+	// of nested groups, the blkio controller has a separate file for the
+	// hierarchical statistics.
+	var usage Usage
+	var err error
+	if usage.Processes, err = processesInTree(path); err != nil {
+		return nil, err
+	}
+
+	// A controller which is not mounted, or in which systemd did not
+	// create the group because accounting is not enabled for the unit,
+	// has no files for the group.
+	controllerPath := func(controller, name string) string {
+		return filepath.Join(rootPath, cgroupMountPoint, controller, rel, name)
+	}
+
+	cpuTime, ok, err := readUintFile(controllerPath("cpuacct", "cpuacct.usage"))
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		usage.unavailable(UsageCPUTime)
+	}
+	usage.CPUTime = time.Duration(cpuTime)
+
+	memory, ok, err := readUintFile(controllerPath("memory", "memory.usage_in_bytes"))
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		usage.unavailable(UsageMemory)
+	}
+	usage.Memory = memory
+
+	// Each line describes one operation on a device, as in:
+	// 8:0 Read 1024
+	// and there is a final summary line:
+	// Total 1536
+	scanBlkio := func(fields []string) error {
+		if len(fields) != 3 {
+			return nil
+		}
+		var dst *uint64
+		switch fields[1] {
+		case "Read":
+			dst = &usage.IORead
+		case "Write":
+			dst = &usage.IOWrite
+		default:
+			return nil
+		}
+		n, err := strconv.ParseUint(fields[2], 10, 64)
+		if err != nil {
+			return fmt.Errorf("cannot parse blkio entry %q: %v", strings.Join(fields, " "), err)
+		}
+		*dst += n
+		return nil
+	}
+	ok, err = scanLines(controllerPath("blkio", "blkio.throttle.io_service_bytes_recursive"), scanBlkio)
+	if err == nil && !ok {
+		// older kernels only have the statistics of the group itself
+		ok, err = scanLines(controllerPath("blkio", "blkio.throttle.io_service_bytes"), scanBlkio)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		usage.unavailable(UsageIO)
+	}
+	return &usage, nil
+}
+
+// readUintFile reads a file with a single unsigned value, it returns false
+// if the file does not exist.
+func readUintFile(fname string) (uint64, bool, error) {
+	data, err := ioutil.ReadFile(fname)
+	if os.IsNotExist(err) {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, err
+	}
+	text := strings.TrimSpace(string(data))
+	n, err := strconv.ParseUint(text, 10, 64)
+	if err != nil {
+		return 0, false, fmt.Errorf("cannot parse %s: %v", fname, err)
+	}
+	return n, true, nil
+}
+
+// readKeyedValues reads a file with a key and value per line, a missing
+// file reads as nil.
+func readKeyedValues(fname string) (map[string]uint64, error) {
+	values := make(map[string]uint64)
+	ok, err := scanLines(fname, func(fields []string) error {
+		if len(fields) != 2 {
+			return nil
+		}
+		n, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			return fmt.Errorf("cannot parse %s: %v", fname, err)
+		}
+		values[fields[0]] = n
+		return nil
+	})
+	if err != nil || !ok {
+		return nil, err
+	}
+	return values, nil
+}
+
+// scanLines calls fn with the whitespace separated fields of each non-empty
+// line of a file, it returns false if the file does not exist.
+func scanLines(fname string, fn func(fields []string) error) (bool, error) {
+	file, err := os.Open(fname)
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		if err := fn(fields); err != nil {
+			return true, err
+		}
+	}
+	return true, scanner.Err()
+}
@@ -0,0 +1,211 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package cgroup_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/sandbox/cgroup"
+	"github.com/snapcore/snapd/testutil"
+)
+
+type usageSuite struct {
+	testutil.BaseTest
+	rootDir string
+}
+
+var _ = Suite(&usageSuite{})
+
+func (s *usageSuite) SetUpTest(c *C) {
+	s.BaseTest.SetUpTest(c)
+
+	s.rootDir = c.MkDir()
+	dirs.SetRootDir(s.rootDir)
+	s.AddCleanup(func() { dirs.SetRootDir("/") })
+}
+
+func (s *usageSuite) writeFiles(c *C, dir string, files map[string]string) {
+	path := filepath.Join(s.rootDir, "/sys/fs/cgroup", dir)
+	c.Assert(os.MkdirAll(path, 0755), IsNil)
+	for name, content := range files {
+		c.Assert(ioutil.WriteFile(filepath.Join(path, name), []byte(content), 0644), IsNil)
+	}
+}
+
+func (s *usageSuite) TestUsageOfSnapEmpty(c *C) {
+	for _, ver := range []int{cgroup.V2, cgroup.V1} {
+		restore := cgroup.MockVersion(ver, nil)
+		defer restore()
+
+		usage, err := cgroup.UsageOfSnap("pkg")
+		c.Assert(err, IsNil)
+		c.Check(usage, HasLen, 0)
+	}
+}
+
+func (s *usageSuite) TestUsageOfSnapV2(c *C) {
+	restore := cgroup.MockVersion(cgroup.V2, nil)
+	defer restore()
+
+	s.writeFiles(c, "system.slice/snap.pkg.daemon.service", map[string]string{
+		"cgroup.procs":   "1\n2\n",
+		"cpu.stat":       "usage_usec 1500000\nuser_usec 1000000\nsystem_usec 500000\n",
+		"memory.current": "4096\n",
+		"io.stat":        "8:0 rbytes=1024 wbytes=512 rios=2 wios=1 dbytes=0 dios=0\n8:16 rbytes=1 wbytes=2 rios=1 wios=1 dbytes=0 dios=0\n",
+	})
+	// the processes of nested groups are counted in their parent, whose
+	// other statistics already include them
+	s.writeFiles(c, "system.slice/snap.pkg.daemon.service/nested", map[string]string{
+		"cgroup.procs":   "3\n",
+		"memory.current": "1024\n",
+	})
+	s.writeFiles(c, "system.slice/snap.pkg.daemon.service/nested/deeper", map[string]string{
+		"cgroup.procs": "7\n8\n",
+	})
+	s.writeFiles(c, "user.slice/user-1000.slice/user@1000.service/snap.pkg.app.1234-5678.scope", map[string]string{
+		"cgroup.procs": "4\n",
+		"cpu.stat":     "usage_usec 10\n",
+	})
+	s.writeFiles(c, "user.slice/user-1000.slice/user@1000.service/snap.pkg.app.abcd-efgh.scope", map[string]string{
+		"cgroup.procs": "5\n",
+		"cpu.stat":     "usage_usec 20\n",
+	})
+	// other snaps are not considered
+	s.writeFiles(c, "system.slice/snap.other.daemon.service", map[string]string{
+		"cgroup.procs":   "6\n",
+		"memory.current": "1\n",
+	})
+
+	usage, err := cgroup.UsageOfSnap("pkg")
+	c.Assert(err, IsNil)
+	c.Check(usage, DeepEquals, map[string]*cgroup.Usage{
+		"snap.pkg.daemon": {
+			CPUTime:   1500 * time.Millisecond,
+			Memory:    4096,
+			Processes: 5,
+			IORead:    1025,
+			IOWrite:   514,
+		},
+		// without the memory and io controllers
+		"snap.pkg.app": {
+			CPUTime:     30 * time.Microsecond,
+			Processes:   2,
+			Unavailable: []string{"io", "memory"},
+		},
+	})
+
+	usage, err = cgroup.UsageOfAllSnaps()
+	c.Assert(err, IsNil)
+	c.Check(usage, HasLen, 3)
+	c.Check(usage["snap.other.daemon"], DeepEquals, &cgroup.Usage{
+		Memory:      1,
+		Processes:   1,
+		Unavailable: []string{"cpu-time", "io"},
+	})
+}
+
+func (s *usageSuite) TestUsageOfSnapV1(c *C) {
+	restore := cgroup.MockVersion(cgroup.V1, nil)
+	defer restore()
+
+	s.writeFiles(c, "systemd/system.slice/snap.pkg.daemon.service", map[string]string{
+		"cgroup.procs": "1\n2\n",
+	})
+	s.writeFiles(c, "cpuacct/system.slice/snap.pkg.daemon.service", map[string]string{
+		"cpuacct.usage": "2000000000\n",
+	})
+	s.writeFiles(c, "memory/system.slice/snap.pkg.daemon.service", map[string]string{
+		"memory.usage_in_bytes": "8192\n",
+	})
+	s.writeFiles(c, "blkio/system.slice/snap.pkg.daemon.service", map[string]string{
+		"blkio.throttle.io_service_bytes": "8:0 Read 100\n8:0 Write 200\n8:0 Sync 300\n8:0 Total 300\nTotal 300\n",
+	})
+	// the hierarchical statistics are preferred
+	s.writeFiles(c, "systemd/system.slice/snap.pkg.other.service", map[string]string{
+		"cgroup.procs": "4\n",
+	})
+	s.writeFiles(c, "systemd/system.slice/snap.pkg.other.service/nested", map[string]string{
+		"cgroup.procs": "5\n",
+	})
+	s.writeFiles(c, "cpuacct/system.slice/snap.pkg.other.service", map[string]string{
+		"cpuacct.usage": "1000000000\n",
+	})
+	s.writeFiles(c, "memory/system.slice/snap.pkg.other.service", map[string]string{
+		"memory.usage_in_bytes": "4096\n",
+	})
+	s.writeFiles(c, "blkio/system.slice/snap.pkg.other.service", map[string]string{
+		"blkio.throttle.io_service_bytes":           "8:0 Read 1\n8:0 Write 2\n",
+		"blkio.throttle.io_service_bytes_recursive": "8:0 Read 10\n8:0 Write 20\n",
+	})
+	// without accounting in other controllers
+	s.writeFiles(c, "systemd/user.slice/snap.pkg.hook.configure.1234.scope", map[string]string{
+		"cgroup.procs": "3\n",
+	})
+
+	usage, err := cgroup.UsageOfSnap("pkg")
+	c.Assert(err, IsNil)
+	c.Check(usage, DeepEquals, map[string]*cgroup.Usage{
+		"snap.pkg.daemon": {
+			CPUTime:   2 * time.Second,
+			Memory:    8192,
+			Processes: 2,
+			IORead:    100,
+			IOWrite:   200,
+		},
+		"snap.pkg.other": {
+			CPUTime:   time.Second,
+			Memory:    4096,
+			Processes: 2,
+			IORead:    10,
+			IOWrite:   20,
+		},
+		"snap.pkg.hook.configure": {
+			Processes:   1,
+			Unavailable: []string{"cpu-time", "io", "memory"},
+		},
+	})
+}
+
+func (s *usageSuite) TestUsageOfSnapBadData(c *C) {
+	restore := cgroup.MockVersion(cgroup.V2, nil)
+	defer restore()
+
+	s.writeFiles(c, "system.slice/snap.pkg.daemon.service", map[string]string{
+		"memory.current": "lots\n",
+	})
+	_, err := cgroup.UsageOfSnap("pkg")
+	c.Assert(err, ErrorMatches, `cannot parse .*/memory.current: .*`)
+}
+
+func (s *usageSuite) TestUsageAdd(c *C) {
+	u := &cgroup.Usage{CPUTime: time.Second, Memory: 1, Processes: 1, IORead: 1, IOWrite: 1}
+	u.Add(&cgroup.Usage{CPUTime: time.Second, Memory: 2, Processes: 3, IORead: 4, IOWrite: 5})
+	c.Check(u, DeepEquals, &cgroup.Usage{CPUTime: 2 * time.Second, Memory: 3, Processes: 4, IORead: 5, IOWrite: 6})
+
+	u.Add(&cgroup.Usage{Unavailable: []string{"memory", "cpu-time"}})
+	u.Add(&cgroup.Usage{Unavailable: []string{"memory"}})
+	c.Check(u.Unavailable, DeepEquals, []string{"cpu-time", "memory"})
+}
@@ -0,0 +1,229 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+// Package denials parses the AppArmor and seccomp denials recorded by
+// the kernel audit subsystem.
+package denials
+
+import (
+	"bufio"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/snapcore/snapd/osutil"
+)
+
+const (
+	// KindAppArmor is the kind of denials reported by AppArmor.
+	KindAppArmor = "apparmor"
+	// KindSeccomp is the kind of denials reported by seccomp.
+	KindSeccomp = "seccomp"
+)
+
+const (
+	auditTypeAppArmor = "1400"
+	auditTypeSeccomp  = "1326"
+)
+
+// Denial describes a single access denied by the sandbox.
+type Denial struct {
+	Time time.Time
+	Kind string
+	// Label is the AppArmor profile or the seccomp security tag of the
+	// denied process.
+	Label string
+	PID   int
+	Comm  string
+
+	// AppArmor specific fields.
+	Operation     string
+	Class         string
+	Path          string
+	RequestedMask string
+	DeniedMask    string
+	Capability    string
+
+	// Seccomp specific fields.
+	Syscall int
+	Arch    string
+	// SyscallName is the name of the syscall, once resolved by the
+	// seccomp compiler for the architecture.
+	SyscallName string
+}
+
+// parseAuditFields splits an audit message into its key=value fields.
+// Values may be double quoted, and AppArmor hex encodes the values of
+// some fields (e.g. names with spaces) instead of quoting them.
+func parseAuditFields(msg string) map[string]string {
+	fields := make(map[string]string)
+	for len(msg) > 0 {
+		msg = strings.TrimLeft(msg, " ")
+		eq := strings.IndexAny(msg, "= ")
+		if eq < 0 {
+			break
+		}
+		if msg[eq] == ' ' {
+			// not a key=value token
+			msg = msg[eq:]
+			continue
+		}
+		key := msg[:eq]
+		msg = msg[eq+1:]
+		var value string
+		if strings.HasPrefix(msg, `"`) {
+			end := strings.IndexByte(msg[1:], '"')
+			if end < 0 {
+				value, msg = msg[1:], ""
+			} else {
+				value, msg = msg[1:end+1], msg[end+2:]
+			}
+		} else {
+			end := strings.IndexByte(msg, ' ')
+			if end < 0 {
+				value, msg = msg, ""
+			} else {
+				value, msg = msg[:end], msg[end:]
+			}
+			switch key {
+			case "name", "comm", "profile", "exe":
+				if decoded, err := hex.DecodeString(value); err == nil && len(value) > 0 {
+					value = string(decoded)
+				}
+			}
+		}
+		fields[key] = value
+	}
+	return fields
+}
+
+// ParseAuditMessage parses an audit message as found in the kernel log
+// or the audit journal and returns the denial it describes, or nil if
+// the message does not describe an AppArmor or seccomp denial. The
+// type of the audit record is either given or extracted from the
+// message.
+func ParseAuditMessage(auditType, msg string) *Denial {
+	fields := parseAuditFields(msg)
+	if auditType == "" {
+		auditType = fields["type"]
+	}
+	var d Denial
+	switch {
+	case fields["apparmor"] == "DENIED":
+		d.Kind = KindAppArmor
+		d.Label = fields["profile"]
+		d.Operation = fields["operation"]
+		d.Class = fields["class"]
+		d.Path = fields["name"]
+		d.RequestedMask = fields["requested_mask"]
+		d.DeniedMask = fields["denied_mask"]
+		d.Capability = fields["capname"]
+	case auditType == auditTypeSeccomp:
+		d.Kind = KindSeccomp
+		d.Label = fields["subj"]
+		d.Arch = fields["arch"]
+		syscall, err := strconv.Atoi(fields["syscall"])
+		if err != nil {
+			return nil
+		}
+		d.Syscall = syscall
+	default:
+		return nil
+	}
+	if d.Label == "" {
+		return nil
+	}
+	// the label may carry the mode of the profile, e.g. "(enforce)"
+	if idx := strings.LastIndex(d.Label, " ("); idx > 0 && strings.HasSuffix(d.Label, ")") {
+		d.Label = d.Label[:idx]
+	}
+	d.Comm = fields["comm"]
+	d.PID, _ = strconv.Atoi(fields["pid"])
+	return &d
+}
+
+type journalEntry struct {
+	Message   string `json:"MESSAGE"`
+	AuditType string `json:"_AUDIT_TYPE"`
+	Timestamp string `json:"__REALTIME_TIMESTAMP"`
+}
+
+// ParseJournal reads journal entries in the JSON format of journalctl
+// and returns the last n denials found in them for which keep returns
+// true, oldest first. A negative n returns all of them and a nil keep
+// keeps all the denials.
+func ParseJournal(r io.Reader, n int, keep func(*Denial) bool) ([]*Denial, error) {
+	var found []*Denial
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 1024*1024)
+	for scanner.Scan() {
+		var entry journalEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			// MESSAGE is an array of bytes when not valid utf-8
+			continue
+		}
+		d := ParseAuditMessage(entry.AuditType, entry.Message)
+		if d == nil || (keep != nil && !keep(d)) {
+			continue
+		}
+		if usec, err := strconv.ParseInt(entry.Timestamp, 10, 64); err == nil {
+			d.Time = time.Unix(usec/1e6, (usec%1e6)*1e3).UTC()
+		}
+		found = append(found, d)
+		if n >= 0 && len(found) > n {
+			found = found[1:]
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("cannot read journal: %v", err)
+	}
+	return found, nil
+}
+
+// journalctl returns the kernel and audit messages of the current boot.
+var journalctl = func() (io.ReadCloser, error) {
+	return osutil.StreamCommand("journalctl", "-o", "json", "--no-pager", "-b",
+		"_TRANSPORT=kernel", "+", "_TRANSPORT=audit")
+}
+
+// MockJournalctl replaces the function reading the kernel and audit
+// messages from the journal.
+func MockJournalctl(f func() (io.ReadCloser, error)) (restore func()) {
+	old := journalctl
+	journalctl = f
+	return func() {
+		journalctl = old
+	}
+}
+
+// FromJournal returns the last n sandbox denials recorded in the
+// journal since the system booted for which keep returns true, see
+// ParseJournal.
+func FromJournal(n int, keep func(*Denial) bool) ([]*Denial, error) {
+	stream, err := journalctl()
+	if err != nil {
+		return nil, fmt.Errorf("cannot get journal: %v", err)
+	}
+	defer stream.Close()
+	return ParseJournal(stream, n, keep)
+}
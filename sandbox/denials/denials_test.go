@@ -0,0 +1,202 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package denials_test
+
+import (
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/sandbox/denials"
+)
+
+func Test(t *testing.T) { TestingT(t) }
+
+type denialsSuite struct{}
+
+var _ = Suite(&denialsSuite{})
+
+const appArmorFileDenial = `audit: type=1400 audit(1612345678.123:456): apparmor="DENIED" operation="open" profile="snap.foo.app" name="/sys/class/net/eth0/statistics/rx_bytes" pid=1234 comm="foo" requested_mask="r" denied_mask="r" fsuid=1000 ouid=0`
+
+const seccompDenial = `audit: type=1326 audit(1612345678.123:457): auid=1000 uid=1000 gid=1000 ses=2 subj=snap.foo.app pid=1234 comm="foo" exe="/snap/foo/1/bin/foo" sig=0 arch=c000003e syscall=165 compat=0 ip=0x7f0 code=0x50000`
+
+func (s *denialsSuite) TestParseAppArmorFileDenial(c *C) {
+	d := denials.ParseAuditMessage("", appArmorFileDenial)
+	c.Assert(d, NotNil)
+	c.Check(d, DeepEquals, &denials.Denial{
+		Kind:          denials.KindAppArmor,
+		Label:         "snap.foo.app",
+		PID:           1234,
+		Comm:          "foo",
+		Operation:     "open",
+		Path:          "/sys/class/net/eth0/statistics/rx_bytes",
+		RequestedMask: "r",
+		DeniedMask:    "r",
+	})
+}
+
+func (s *denialsSuite) TestParseAppArmorCapabilityDenial(c *C) {
+	d := denials.ParseAuditMessage("1400", `apparmor="DENIED" operation="capable" profile="snap.foo.hook.configure" pid=42 comm="ip" capability=12  capname="net_admin"`)
+	c.Assert(d, NotNil)
+	c.Check(d.Label, Equals, "snap.foo.hook.configure")
+	c.Check(d.Capability, Equals, "net_admin")
+	c.Check(d.PID, Equals, 42)
+}
+
+func (s *denialsSuite) TestParseAppArmorHexName(c *C) {
+	// "/tmp/a b" is hex encoded because of the space
+	d := denials.ParseAuditMessage("", `apparmor="DENIED" operation="mknod" profile="snap.foo.app (enforce)" name=2F746D702F612062 pid=1 comm="foo" requested_mask="c" denied_mask="c"`)
+	c.Assert(d, NotNil)
+	c.Check(d.Path, Equals, "/tmp/a b")
+	c.Check(d.Label, Equals, "snap.foo.app")
+}
+
+func (s *denialsSuite) TestParseSeccompDenial(c *C) {
+	d := denials.ParseAuditMessage("", seccompDenial)
+	c.Assert(d, NotNil)
+	c.Check(d, DeepEquals, &denials.Denial{
+		Kind:    denials.KindSeccomp,
+		Label:   "snap.foo.app",
+		PID:     1234,
+		Comm:    "foo",
+		Syscall: 165,
+		Arch:    "c000003e",
+	})
+
+	// the audit transport gives the type separately
+	msg := strings.TrimPrefix(seccompDenial, "audit: type=1326 ")
+	d = denials.ParseAuditMessage("1326", msg)
+	c.Assert(d, NotNil)
+	c.Check(d.Syscall, Equals, 165)
+}
+
+func (s *denialsSuite) TestParseNotADenial(c *C) {
+	for _, msg := range []string{
+		"",
+		"usb 1-1: new high-speed USB device number 2",
+		`audit: type=1400 audit(1612345678.123:456): apparmor="STATUS" operation="profile_load" profile="unconfined" name="snap.foo.app" pid=1`,
+		`audit: type=1400 audit(1612345678.123:456): apparmor="DENIED" operation="open" name="/etc/shadow" pid=1`,
+		`audit: type=1326 audit(1612345678.123:457): subj=snap.foo.app pid=1234 syscall=foo`,
+	} {
+		c.Check(denials.ParseAuditMessage("", msg), IsNil, Commentf("%q", msg))
+	}
+}
+
+func (s *denialsSuite) TestFromJournal(c *C) {
+	journal := `{"MESSAGE":"usb 1-1: new device","__REALTIME_TIMESTAMP":"1612345677000000"}
+{"MESSAGE":"audit: type=1400 audit(1612345678.123:456): apparmor=\"DENIED\" operation=\"open\" profile=\"snap.foo.app\" name=\"/etc/foo\" pid=1 comm=\"foo\" requested_mask=\"r\" denied_mask=\"r\"","__REALTIME_TIMESTAMP":"1612345678123000"}
+{"MESSAGE":[1,2,3]}
+{"MESSAGE":"auid=1000 uid=1000 subj=snap.bar.app pid=2 comm=\"bar\" arch=c000003e syscall=165","_AUDIT_TYPE":"1326","__REALTIME_TIMESTAMP":"1612345679000000"}
+`
+	restore := denials.MockJournalctl(func() (io.ReadCloser, error) {
+		return ioutil.NopCloser(strings.NewReader(journal)), nil
+	})
+	defer restore()
+
+	found, err := denials.FromJournal(-1, nil)
+	c.Assert(err, IsNil)
+	c.Assert(found, HasLen, 2)
+	c.Check(found[0].Label, Equals, "snap.foo.app")
+	c.Check(found[0].Time.Equal(time.Unix(1612345678, 123000000)), Equals, true)
+	c.Check(found[1].Label, Equals, "snap.bar.app")
+	c.Check(found[1].Kind, Equals, denials.KindSeccomp)
+
+	found, err = denials.FromJournal(1, nil)
+	c.Assert(err, IsNil)
+	c.Assert(found, HasLen, 1)
+	c.Check(found[0].Label, Equals, "snap.bar.app")
+
+	// the last denials kept are returned
+	found, err = denials.FromJournal(1, func(d *denials.Denial) bool {
+		return d.Label == "snap.foo.app"
+	})
+	c.Assert(err, IsNil)
+	c.Assert(found, HasLen, 1)
+	c.Check(found[0].Label, Equals, "snap.foo.app")
+}
+
+func (s *denialsSuite) TestAppArmorSnippetAllows(c *C) {
+	const snippet = `# Description: some access
+/sys/class/net/[^/]*/statistics/* r,
+owner @{HOME}/.config/foo/** rwk,
+@{PROC}/@{pid}/{stat,status} r,
+/dev/ttyUSB[0-9]* rw,
+deny /etc/shadow r,
+/var/snap/@{SNAP_NAME}/** rw, # comment
+capability net_admin sys_admin,
+network inet,
+`
+	vars := map[string]string{"SNAP_NAME": "foo"}
+	for _, t := range []struct {
+		d       denials.Denial
+		allowed bool
+	}{
+		{denials.Denial{Path: "/sys/class/net/eth0/statistics/rx_bytes", DeniedMask: "r"}, true},
+		{denials.Denial{Path: "/sys/class/net/eth0/statistics/rx_bytes", DeniedMask: "w"}, false},
+		{denials.Denial{Path: "/home/user/.config/foo/a/b", DeniedMask: "wc"}, true},
+		{denials.Denial{Path: "/root/.config/foo/a", RequestedMask: "k"}, true},
+		{denials.Denial{Path: "/home/user/.config/bar", DeniedMask: "r"}, false},
+		{denials.Denial{Path: "/proc/42/status", DeniedMask: "r"}, true},
+		{denials.Denial{Path: "/proc/42/maps", DeniedMask: "r"}, false},
+		{denials.Denial{Path: "/dev/ttyUSB12", DeniedMask: "a"}, true},
+		{denials.Denial{Path: "/etc/shadow", DeniedMask: "r"}, false},
+		{denials.Denial{Path: "/var/snap/foo/common/x", DeniedMask: "w"}, true},
+		{denials.Denial{Path: "/var/snap/bar/common/x", DeniedMask: "w"}, false},
+		{denials.Denial{Capability: "net_admin"}, true},
+		{denials.Denial{Capability: "sys_module"}, false},
+	} {
+		t.d.Kind = denials.KindAppArmor
+		c.Check(denials.AppArmorSnippetAllows(snippet, &t.d, vars), Equals, t.allowed, Commentf("%+v", t.d))
+	}
+
+	// seccomp denials are never granted by AppArmor rules
+	c.Check(denials.AppArmorSnippetAllows("capability,\n/** rw,\n", &denials.Denial{Kind: denials.KindSeccomp, Syscall: 1}, nil), Equals, false)
+}
+
+func (s *denialsSuite) TestSeccompSnippetAllows(c *C) {
+	const snippet = `# Description: some syscalls
+mount
+umount2
+# socket AF_NETLINK - NETLINK_ROUTE
+socket AF_NETLINK - NETLINK_KOBJECT_UEVENT
+~ptrace
+`
+	for _, t := range []struct {
+		name    string
+		allowed bool
+	}{
+		{"mount", true},
+		{"umount2", true},
+		{"umount", false},
+		{"socket", true},
+		{"ptrace", false},
+		{"", false},
+	} {
+		d := &denials.Denial{Kind: denials.KindSeccomp, SyscallName: t.name}
+		c.Check(denials.SeccompSnippetAllows(snippet, d), Equals, t.allowed, Commentf("%q", t.name))
+	}
+
+	// AppArmor denials are never granted by seccomp rules
+	c.Check(denials.SeccompSnippetAllows("mount\n", &denials.Denial{Kind: denials.KindAppArmor, SyscallName: "mount"}), Equals, false)
+}
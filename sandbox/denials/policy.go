@@ -0,0 +1,210 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package denials
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// builtinVariables are the AppArmor variables commonly used by the
+// snippets of the interfaces, expressed as regular expressions.
+var builtinVariables = map[string]string{
+	"PROC":      "/proc",
+	"HOME":      "(/home/[^/]+|/root)",
+	"pid":       "[0-9]+",
+	"pids":      "[0-9]+",
+	"tid":       "[0-9]+",
+	"multiarch": "[^/]+",
+}
+
+// aareToRegexp converts an AppArmor regular expression to a Go regular
+// expression matching the same paths. Variables are looked up in vars
+// first and then in the builtin variables; unknown variables match a
+// single path component.
+func aareToRegexp(aare string, vars map[string]string) (*regexp.Regexp, error) {
+	var re strings.Builder
+	re.WriteString("^")
+	alternations := 0
+	for i := 0; i < len(aare); i++ {
+		c := aare[i]
+		switch {
+		case c == '*' && i+1 < len(aare) && aare[i+1] == '*':
+			re.WriteString(".*")
+			i++
+		case c == '*':
+			re.WriteString("[^/]*")
+		case c == '?':
+			re.WriteString("[^/]")
+		case c == '{':
+			alternations++
+			re.WriteString("(")
+		case c == '}' && alternations > 0:
+			alternations--
+			re.WriteString(")")
+		case c == ',' && alternations > 0:
+			re.WriteString("|")
+		case c == '[':
+			end := strings.IndexByte(aare[i:], ']')
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated character class in %q", aare)
+			}
+			re.WriteString(aare[i : i+end+1])
+			i += end
+		case c == '@' && i+1 < len(aare) && aare[i+1] == '{':
+			end := strings.IndexByte(aare[i:], '}')
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated variable in %q", aare)
+			}
+			name := aare[i+2 : i+end]
+			if value, ok := vars[name]; ok {
+				re.WriteString(regexp.QuoteMeta(value))
+			} else if value, ok := builtinVariables[name]; ok {
+				re.WriteString(value)
+			} else {
+				re.WriteString("[^/]*")
+			}
+			i += end
+		case c == '\\' && i+1 < len(aare):
+			re.WriteString(regexp.QuoteMeta(aare[i+1 : i+2]))
+			i++
+		default:
+			re.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	if alternations != 0 {
+		return nil, fmt.Errorf("unbalanced alternation in %q", aare)
+	}
+	re.WriteString("$")
+	return regexp.Compile(re.String())
+}
+
+// permsAllow returns whether the permissions of a file rule grant all
+// of the given AppArmor access mask.
+func permsAllow(perms, mask string) bool {
+	if mask == "" {
+		return false
+	}
+	for _, m := range mask {
+		var ok bool
+		switch m {
+		case 'r', 'k', 'l', 'm':
+			ok = strings.ContainsRune(perms, m)
+		case 'w', 'c', 'd':
+			ok = strings.ContainsRune(perms, 'w')
+		case 'a':
+			ok = strings.ContainsAny(perms, "aw")
+		case 'x':
+			ok = strings.ContainsRune(perms, 'x')
+		}
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func isPerms(token string) bool {
+	return token != "" && strings.Trim(token, "rwaklmixuUpPcC") == ""
+}
+
+// AppArmorSnippetAllows returns whether the rules of an AppArmor
+// snippet grant the access described by the given denial. Only file
+// and capability denials are considered; the variables used in the
+// rules are resolved with vars, e.g. SNAP_NAME.
+func AppArmorSnippetAllows(snippet string, d *Denial, vars map[string]string) bool {
+	if d.Kind != KindAppArmor {
+		return false
+	}
+	mask := d.DeniedMask
+	if mask == "" {
+		mask = d.RequestedMask
+	}
+	for _, line := range strings.Split(snippet, "\n") {
+		if idx := strings.Index(line, "#"); idx >= 0 {
+			line = line[:idx]
+		}
+		line = strings.TrimSuffix(strings.TrimSpace(line), ",")
+		tokens := strings.Fields(line)
+		for len(tokens) > 0 && (tokens[0] == "owner" || tokens[0] == "audit" || tokens[0] == "allow" || tokens[0] == "file") {
+			tokens = tokens[1:]
+		}
+		if len(tokens) == 0 || tokens[0] == "deny" {
+			continue
+		}
+		if tokens[0] == "capability" {
+			if d.Capability == "" {
+				continue
+			}
+			if len(tokens) == 1 {
+				return true
+			}
+			for _, capability := range tokens[1:] {
+				if capability == d.Capability {
+					return true
+				}
+			}
+			continue
+		}
+		if d.Path == "" || len(tokens) < 2 {
+			continue
+		}
+		path, perms := tokens[0], tokens[1]
+		if isPerms(path) {
+			path, perms = perms, path
+		}
+		path = strings.Trim(path, `"`)
+		if !(strings.HasPrefix(path, "/") || strings.HasPrefix(path, "@{")) || !isPerms(perms) {
+			continue
+		}
+		if !permsAllow(perms, mask) {
+			continue
+		}
+		re, err := aareToRegexp(path, vars)
+		if err != nil {
+			continue
+		}
+		if re.MatchString(d.Path) {
+			return true
+		}
+	}
+	return false
+}
+
+// SeccompSnippetAllows returns whether the rules of a seccomp snippet
+// allow the syscall of the given denial. The name of the syscall must
+// have been resolved; rules filtering the arguments of the syscall are
+// assumed to allow it.
+func SeccompSnippetAllows(snippet string, d *Denial) bool {
+	if d.Kind != KindSeccomp || d.SyscallName == "" {
+		return false
+	}
+	for _, line := range strings.Split(snippet, "\n") {
+		if idx := strings.Index(line, "#"); idx >= 0 {
+			line = line[:idx]
+		}
+		tokens := strings.Fields(line)
+		if len(tokens) > 0 && tokens[0] == d.SyscallName {
+			return true
+		}
+	}
+	return false
+}
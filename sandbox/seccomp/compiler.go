@@ -89,6 +89,42 @@ func (c *Compiler) VersionInfo() (VersionInfo, error) {
 	return VersionInfo(raw), nil
 }
 
+// SyscallNames returns the names of the given syscalls, as known to the
+// compiler, for the architecture of a seccomp audit record in its hex
+// form, e.g. c000003e for amd64. Syscalls unknown to the compiler are
+// left out.
+func (c *Compiler) SyscallNames(auditArch string, syscalls []int) (map[int]string, error) {
+	args := make([]string, 0, len(syscalls)+2)
+	args = append(args, "syscall-names", auditArch)
+	for _, nr := range syscalls {
+		args = append(args, strconv.Itoa(nr))
+	}
+	cmd := exec.Command(c.snapSeccomp, args...)
+	output, err := cmd.Output()
+	if err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok && len(exitErr.Stderr) > 0 {
+			output = exitErr.Stderr
+		}
+		return nil, osutil.OutputErr(output, err)
+	}
+	names := make(map[int]string, len(syscalls))
+	for _, line := range strings.Split(string(bytes.TrimSpace(output)), "\n") {
+		if line == "" {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("invalid syscall name output: %q", line)
+		}
+		nr, err := strconv.Atoi(fields[0])
+		if err != nil {
+			return nil, fmt.Errorf("invalid syscall name output: %q", line)
+		}
+		names[nr] = fields[1]
+	}
+	return names, nil
+}
+
 var compilerVersionInfoImpl = func(lookupTool func(name string) (string, error)) (VersionInfo, error) {
 	c, err := NewCompiler(lookupTool)
 	if err != nil {
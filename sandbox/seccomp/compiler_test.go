@@ -297,3 +297,37 @@ exit 1`))
 	_, err := seccomp.CompilerVersionInfo(fromCmd(c, cmd))
 	c.Assert(err, ErrorMatches, "this goes to stderr")
 }
+
+func (s *compilerSuite) TestSyscallNames(c *C) {
+	cmd := testutil.MockCommand(c, "snap-seccomp", `printf "2 open\n257 openat\n"`)
+	defer cmd.Restore()
+	compiler, err := seccomp.NewCompiler(fromCmd(c, cmd))
+	c.Assert(err, IsNil)
+
+	names, err := compiler.SyscallNames("c000003e", []int{2, 257, 9999})
+	c.Assert(err, IsNil)
+	c.Check(names, DeepEquals, map[int]string{2: "open", 257: "openat"})
+	c.Check(cmd.Calls(), DeepEquals, [][]string{
+		{"snap-seccomp", "syscall-names", "c000003e", "2", "257", "9999"},
+	})
+}
+
+func (s *compilerSuite) TestSyscallNamesError(c *C) {
+	cmd := testutil.MockCommand(c, "snap-seccomp", `echo "error: unsupported audit architecture \"1234\"" >&2; exit 1`)
+	defer cmd.Restore()
+	compiler, err := seccomp.NewCompiler(fromCmd(c, cmd))
+	c.Assert(err, IsNil)
+
+	_, err = compiler.SyscallNames("1234", []int{2})
+	c.Check(err, ErrorMatches, `error: unsupported audit architecture "1234"`)
+}
+
+func (s *compilerSuite) TestSyscallNamesInvalidOutput(c *C) {
+	cmd := testutil.MockCommand(c, "snap-seccomp", `echo "open"`)
+	defer cmd.Restore()
+	compiler, err := seccomp.NewCompiler(fromCmd(c, cmd))
+	c.Assert(err, IsNil)
+
+	_, err = compiler.SyscallNames("c000003e", []int{2})
+	c.Check(err, ErrorMatches, `invalid syscall name output: "open"`)
+}
@@ -0,0 +1,492 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+// Package configschema implements the schema that snaps can ship in
+// meta/config-schema.yaml to describe and validate their configuration.
+//
+// The schema follows a subset of JSON Schema: each option is described by
+// its type, description, default value and optionally the values it can
+// take (enum, minimum, maximum and pattern). Options holding documents are
+// described with properties and arrays with items. As YAML is a superset
+// of JSON, the schema can be written in either.
+package configschema
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/snapcore/snapd/metautil"
+	"github.com/snapcore/snapd/snap"
+)
+
+// The types an option can have.
+const (
+	TypeString  = "string"
+	TypeInteger = "integer"
+	TypeNumber  = "number"
+	TypeBoolean = "boolean"
+	TypeObject  = "object"
+	TypeArray   = "array"
+)
+
+var validTypes = map[string]bool{
+	"":          true,
+	TypeString:  true,
+	TypeInteger: true,
+	TypeNumber:  true,
+	TypeBoolean: true,
+	TypeObject:  true,
+	TypeArray:   true,
+}
+
+// validName matches the valid names of options, as accepted by snap set.
+var validName = regexp.MustCompile("^(?:[a-z0-9]+-?)*[a-z](?:-?[a-z0-9])*$")
+
+// Schema describes a configuration option, or the whole configuration of
+// a snap at the top level. An empty Type accepts values of any type.
+type Schema struct {
+	Type        string        `yaml:"type,omitempty" json:"type,omitempty"`
+	Description string        `yaml:"description,omitempty" json:"description,omitempty"`
+	Default     interface{}   `yaml:"default,omitempty" json:"default,omitempty"`
+	Enum        []interface{} `yaml:"enum,omitempty" json:"enum,omitempty"`
+	Minimum     *float64      `yaml:"minimum,omitempty" json:"minimum,omitempty"`
+	Maximum     *float64      `yaml:"maximum,omitempty" json:"maximum,omitempty"`
+	Pattern     string        `yaml:"pattern,omitempty" json:"pattern,omitempty"`
+
+	Properties map[string]*Schema `yaml:"properties,omitempty" json:"properties,omitempty"`
+	// AdditionalProperties tells whether options not listed in
+	// Properties can be set; they can unless it is false.
+	AdditionalProperties *bool   `yaml:"additionalProperties,omitempty" json:"additionalProperties,omitempty"`
+	Items                *Schema `yaml:"items,omitempty" json:"items,omitempty"`
+
+	pattern *regexp.Regexp
+}
+
+// ValidationError is returned when a configuration value does not match
+// the schema.
+type ValidationError struct {
+	Key string
+	Msg string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("invalid value for option %q: %s", e.Key, e.Msg)
+}
+
+// Parse parses and checks the given configuration schema.
+func Parse(data []byte) (*Schema, error) {
+	var schema Schema
+	if err := yaml.Unmarshal(data, &schema); err != nil {
+		return nil, fmt.Errorf("cannot parse configuration schema: %v", err)
+	}
+	if schema.Type != "" && schema.Type != TypeObject {
+		return nil, fmt.Errorf("invalid configuration schema: top level type must be %q, not %q", TypeObject, schema.Type)
+	}
+	schema.Type = TypeObject
+	if err := schema.check(""); err != nil {
+		return nil, fmt.Errorf("invalid configuration schema: %v", err)
+	}
+	return &schema, nil
+}
+
+// ReadSchema reads the configuration schema from meta/config-schema.yaml
+// in the snap root directory. It returns a nil schema if the snap does
+// not ship one.
+func ReadSchema(snapRootDir string) (*Schema, error) {
+	return readSchema(ioutil.ReadFile, filepath.Join(snapRootDir, schemaPath))
+}
+
+// ReadSchemaFromSnapFile reads the configuration schema from
+// meta/config-schema.yaml in the given snap container. It returns a nil
+// schema if the snap does not ship one.
+func ReadSchemaFromSnapFile(snapf snap.Container) (*Schema, error) {
+	return readSchema(snapf.ReadFile, schemaPath)
+}
+
+const schemaPath = "meta/config-schema.yaml"
+
+func readSchema(readFile func(string) ([]byte, error), p string) (*Schema, error) {
+	content, err := readFile(p)
+	// meta/config-schema.yaml is optional
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cannot read configuration schema: %v", err)
+	}
+	return Parse(content)
+}
+
+func describe(key string) string {
+	if key == "" {
+		return "top level"
+	}
+	return fmt.Sprintf("option %q", key)
+}
+
+func join(key, name string) string {
+	if key == "" {
+		return name
+	}
+	return key + "." + name
+}
+
+// check checks the schema of the option with the given key and normalizes
+// the values in it.
+func (s *Schema) check(key string) error {
+	if !validTypes[s.Type] {
+		return fmt.Errorf("%s has unknown type %q", describe(key), s.Type)
+	}
+	if len(s.Properties) > 0 && s.Type != TypeObject {
+		return fmt.Errorf("%s has properties but is not of type %q", describe(key), TypeObject)
+	}
+	if s.AdditionalProperties != nil && s.Type != TypeObject {
+		return fmt.Errorf("%s has additionalProperties but is not of type %q", describe(key), TypeObject)
+	}
+	if s.Items != nil && s.Type != TypeArray {
+		return fmt.Errorf("%s has items but is not of type %q", describe(key), TypeArray)
+	}
+	if (s.Minimum != nil || s.Maximum != nil) && s.Type != TypeInteger && s.Type != TypeNumber {
+		return fmt.Errorf("%s has a minimum or maximum but is not of type %q or %q", describe(key), TypeInteger, TypeNumber)
+	}
+	if s.Minimum != nil && s.Maximum != nil && *s.Minimum > *s.Maximum {
+		return fmt.Errorf("%s has a minimum greater than its maximum", describe(key))
+	}
+	if s.Pattern != "" {
+		if s.Type != TypeString {
+			return fmt.Errorf("%s has a pattern but is not of type %q", describe(key), TypeString)
+		}
+		pattern, err := regexp.Compile("^(?:" + s.Pattern + ")$")
+		if err != nil {
+			return fmt.Errorf("%s has an invalid pattern: %v", describe(key), err)
+		}
+		s.pattern = pattern
+	}
+
+	for name, prop := range s.Properties {
+		if !validName.MatchString(name) {
+			return fmt.Errorf("invalid option name: %q", join(key, name))
+		}
+		if prop == nil {
+			return fmt.Errorf("option %q has no schema", join(key, name))
+		}
+		if err := prop.check(join(key, name)); err != nil {
+			return err
+		}
+	}
+	if s.Items != nil {
+		if err := s.Items.check(key + "[]"); err != nil {
+			return err
+		}
+	}
+
+	// enum values and defaults are checked against the schema
+	for i, v := range s.Enum {
+		nv, err := metautil.NormalizeValue(v)
+		if err != nil {
+			return fmt.Errorf("%s has an invalid enum value: %v", describe(key), err)
+		}
+		if err := s.validate(key, nv, true); err != nil {
+			return fmt.Errorf("%s has an invalid enum value: %v", describe(key), err.(*ValidationError).Msg)
+		}
+		s.Enum[i] = nv
+	}
+	if s.Default != nil {
+		nv, err := metautil.NormalizeValue(s.Default)
+		if err != nil {
+			return fmt.Errorf("%s has an invalid default: %v", describe(key), err)
+		}
+		if err := s.validate(key, nv, false); err != nil {
+			return fmt.Errorf("%s has an invalid default: %v", describe(key), err.(*ValidationError).Msg)
+		}
+		s.Default = nv
+	}
+	return nil
+}
+
+// Lookup returns the schema of the option with the given dotted key, or
+// nil if the schema does not describe it.
+func (s *Schema) Lookup(key string) *Schema {
+	if key == "" {
+		return s
+	}
+	cur := s
+	for _, name := range strings.Split(key, ".") {
+		cur = cur.Properties[name]
+		if cur == nil {
+			return nil
+		}
+	}
+	return cur
+}
+
+// ValidatePatch checks the configuration resulting from applying the given
+// patch on top of the given configuration against the schema. The patch is
+// indexed by dotted keys as given to snap set, and its nil values unset
+// options. The given configuration is left untouched.
+func (s *Schema) ValidatePatch(conf, patch map[string]interface{}) error {
+	keys := make([]string, 0, len(patch))
+	for key := range patch {
+		keys = append(keys, key)
+	}
+	// apply the shallower keys first, like snap set does
+	sort.Slice(keys, func(i, j int) bool {
+		di, dj := strings.Count(keys[i], "."), strings.Count(keys[j], ".")
+		if di != dj {
+			return di < dj
+		}
+		return keys[i] < keys[j]
+	})
+
+	for _, key := range keys {
+		cur := s
+		var path string
+		for _, name := range strings.Split(key, ".") {
+			if cur.Type != "" && cur.Type != TypeObject {
+				return &ValidationError{Key: path, Msg: fmt.Sprintf("expected %s, cannot set %q in it", cur.Type, name)}
+			}
+			path = join(path, name)
+			cur = cur.Properties[name]
+			if cur == nil {
+				// nothing more is known about this option
+				break
+			}
+		}
+	}
+
+	merged := copyObject(conf)
+	for _, key := range keys {
+		names := strings.Split(key, ".")
+		obj := merged
+		for _, name := range names[:len(names)-1] {
+			sub, _ := obj[name].(map[string]interface{})
+			sub = copyObject(sub)
+			obj[name] = sub
+			obj = sub
+		}
+		last := names[len(names)-1]
+		if patch[key] == nil {
+			delete(obj, last)
+		} else {
+			obj[last] = patch[key]
+		}
+	}
+	return s.validate("", merged, false)
+}
+
+func copyObject(obj map[string]interface{}) map[string]interface{} {
+	cp := make(map[string]interface{}, len(obj))
+	for name, value := range obj {
+		cp[name] = value
+	}
+	return cp
+}
+
+// Validate checks the given value against the schema of the option with
+// the given key.
+func (s *Schema) Validate(key string, value interface{}) error {
+	return s.validate(key, value, false)
+}
+
+func (s *Schema) validate(key string, value interface{}, skipEnum bool) error {
+	fail := func(format string, v ...interface{}) error {
+		return &ValidationError{Key: key, Msg: fmt.Sprintf(format, v...)}
+	}
+
+	switch s.Type {
+	case TypeString:
+		str, ok := value.(string)
+		if !ok {
+			return fail("expected string, got %s", typeOf(value))
+		}
+		if s.pattern != nil && !s.pattern.MatchString(str) {
+			return fail("%q does not match %q", str, s.Pattern)
+		}
+	case TypeBoolean:
+		if _, ok := value.(bool); !ok {
+			return fail("expected boolean, got %s", typeOf(value))
+		}
+	case TypeInteger, TypeNumber:
+		n, ok := asNumber(value)
+		if !ok {
+			return fail("expected %s, got %s", s.Type, typeOf(value))
+		}
+		if s.Type == TypeInteger && n != math.Trunc(n) {
+			return fail("expected integer, got %v", n)
+		}
+		if s.Minimum != nil && n < *s.Minimum {
+			return fail("%v is less than the minimum %v", n, *s.Minimum)
+		}
+		if s.Maximum != nil && n > *s.Maximum {
+			return fail("%v is greater than the maximum %v", n, *s.Maximum)
+		}
+	case TypeObject:
+		obj, ok := value.(map[string]interface{})
+		if !ok {
+			return fail("expected object, got %s", typeOf(value))
+		}
+		names := make([]string, 0, len(obj))
+		for name := range obj {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if obj[name] == nil {
+				continue
+			}
+			prop := s.Properties[name]
+			if prop == nil {
+				if s.AdditionalProperties != nil && !*s.AdditionalProperties {
+					return &ValidationError{Key: join(key, name), Msg: "unknown option"}
+				}
+				continue
+			}
+			if err := prop.validate(join(key, name), obj[name], skipEnum); err != nil {
+				return err
+			}
+		}
+	case TypeArray:
+		arr, ok := value.([]interface{})
+		if !ok {
+			return fail("expected array, got %s", typeOf(value))
+		}
+		if s.Items != nil {
+			for i, item := range arr {
+				if err := s.Items.validate(fmt.Sprintf("%s[%d]", key, i), item, skipEnum); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	if len(s.Enum) > 0 && !skipEnum {
+		for _, allowed := range s.Enum {
+			if equalValues(value, allowed) {
+				return nil
+			}
+		}
+		return fail("%s is not one of %s", formatValue(value), formatValues(s.Enum))
+	}
+	return nil
+}
+
+// WithDefaults returns the given value of the option completed with the
+// defaults of the schema: if value is nil the default is returned, and
+// the missing options of documents are filled in with theirs.
+func (s *Schema) WithDefaults(value interface{}) interface{} {
+	if value == nil && s.Default != nil {
+		return s.Default
+	}
+	if len(s.Properties) == 0 {
+		return value
+	}
+	var obj map[string]interface{}
+	switch v := value.(type) {
+	case nil:
+	case map[string]interface{}:
+		obj = make(map[string]interface{}, len(v))
+		for name, item := range v {
+			obj[name] = item
+		}
+	default:
+		return value
+	}
+	for name, prop := range s.Properties {
+		withDefaults := prop.WithDefaults(obj[name])
+		if withDefaults == nil {
+			continue
+		}
+		if obj == nil {
+			obj = make(map[string]interface{})
+		}
+		obj[name] = withDefaults
+	}
+	if obj == nil {
+		return value
+	}
+	return obj
+}
+
+func asNumber(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case json.Number:
+		f, err := v.Float64()
+		return f, err == nil
+	case float64:
+		return v, true
+	case float32:
+		return float64(v), true
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	}
+	return 0, false
+}
+
+func equalValues(a, b interface{}) bool {
+	if na, ok := asNumber(a); ok {
+		nb, ok := asNumber(b)
+		return ok && na == nb
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+func typeOf(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return "string " + strconv.Quote(v)
+	case bool:
+		return "boolean"
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		return "array"
+	}
+	if _, ok := asNumber(value); ok {
+		return "number " + formatValue(value)
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+func formatValue(value interface{}) string {
+	if str, ok := value.(string); ok {
+		return strconv.Quote(str)
+	}
+	return fmt.Sprintf("%v", value)
+}
+
+func formatValues(values []interface{}) string {
+	strs := make([]string, len(values))
+	for i, v := range values {
+		strs[i] = formatValue(v)
+	}
+	return strings.Join(strs, ", ")
+}
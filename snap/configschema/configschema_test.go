@@ -0,0 +1,267 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package configschema_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/snap/configschema"
+	"github.com/snapcore/snapd/snap/snapdir"
+)
+
+func Test(t *testing.T) { TestingT(t) }
+
+type schemaSuite struct{}
+
+var _ = Suite(&schemaSuite{})
+
+const mockSchema = `
+type: object
+additionalProperties: false
+properties:
+  port:
+    type: integer
+    description: The port to listen on
+    default: 8080
+    minimum: 1
+    maximum: 65535
+  mode:
+    type: string
+    enum: [fast, safe]
+    default: safe
+  name:
+    type: string
+    pattern: "[a-z]+"
+  debug:
+    type: boolean
+  ratio:
+    type: number
+  servers:
+    type: array
+    items:
+      type: string
+  proxy:
+    type: object
+    properties:
+      url:
+        type: string
+        description: The URL of the proxy
+      timeout:
+        type: integer
+        default: 30
+  extra: {}
+`
+
+func (s *schemaSuite) TestParse(c *C) {
+	schema, err := configschema.Parse([]byte(mockSchema))
+	c.Assert(err, IsNil)
+	c.Check(schema.Type, Equals, "object")
+	c.Check(schema.Properties, HasLen, 8)
+
+	port := schema.Lookup("port")
+	c.Assert(port, NotNil)
+	c.Check(port.Type, Equals, "integer")
+	c.Check(port.Description, Equals, "The port to listen on")
+	c.Check(port.Default, Equals, int64(8080))
+	c.Check(schema.Lookup("mode").Enum, DeepEquals, []interface{}{"fast", "safe"})
+	c.Check(schema.Lookup("proxy.url").Description, Equals, "The URL of the proxy")
+	c.Check(schema.Lookup("proxy.missing"), IsNil)
+	c.Check(schema.Lookup(""), Equals, schema)
+
+	// JSON works as well
+	schema, err = configschema.Parse([]byte(`{"properties": {"port": {"type": "integer", "default": 80}}}`))
+	c.Assert(err, IsNil)
+	c.Check(schema.Type, Equals, "object")
+	c.Check(schema.Lookup("port").Default, Equals, int64(80))
+}
+
+func (s *schemaSuite) TestParseErrors(c *C) {
+	for _, tc := range []struct {
+		schema string
+		err    string
+	}{
+		{`type: string`, `invalid configuration schema: top level type must be "object", not "string"`},
+		{`[`, `cannot parse configuration schema: .*`},
+		{`properties: {port: {type: int}}`, `invalid configuration schema: option "port" has unknown type "int"`},
+		{`properties: {Port: {type: string}}`, `invalid configuration schema: invalid option name: "Port"`},
+		{`properties: {port: }`, `invalid configuration schema: option "port" has no schema`},
+		{`properties: {port: {type: string, properties: {a: {}}}}`, `invalid configuration schema: option "port" has properties but is not of type "object"`},
+		{`properties: {port: {type: string, items: {}}}`, `invalid configuration schema: option "port" has items but is not of type "array"`},
+		{`properties: {port: {type: string, minimum: 1}}`, `invalid configuration schema: option "port" has a minimum or maximum but is not of type "integer" or "number"`},
+		{`properties: {port: {type: integer, minimum: 10, maximum: 1}}`, `invalid configuration schema: option "port" has a minimum greater than its maximum`},
+		{`properties: {port: {type: integer, pattern: "x"}}`, `invalid configuration schema: option "port" has a pattern but is not of type "string"`},
+		{`properties: {name: {type: string, pattern: "("}}`, `invalid configuration schema: option "name" has an invalid pattern: .*`},
+		{`properties: {port: {type: integer, default: "80"}}`, `invalid configuration schema: option "port" has an invalid default: expected integer, got string "80"`},
+		{`properties: {mode: {type: string, enum: [a, b], default: c}}`, `invalid configuration schema: option "mode" has an invalid default: "c" is not one of "a", "b"`},
+		{`properties: {mode: {type: string, enum: [a, 1]}}`, `invalid configuration schema: option "mode" has an invalid enum value: expected string, got number 1`},
+		{`properties: {a: {type: object, properties: {b: {type: array, items: {type: foo}}}}}`, `invalid configuration schema: option "a.b\[\]" has unknown type "foo"`},
+	} {
+		_, err := configschema.Parse([]byte(tc.schema))
+		c.Check(err, ErrorMatches, tc.err, Commentf(tc.schema))
+	}
+}
+
+func (s *schemaSuite) TestReadSchema(c *C) {
+	dir := c.MkDir()
+
+	// the schema is optional
+	schema, err := configschema.ReadSchema(dir)
+	c.Assert(err, IsNil)
+	c.Check(schema, IsNil)
+
+	c.Assert(os.MkdirAll(filepath.Join(dir, "meta"), 0755), IsNil)
+	c.Assert(ioutil.WriteFile(filepath.Join(dir, "meta", "config-schema.yaml"), []byte(mockSchema), 0644), IsNil)
+	schema, err = configschema.ReadSchema(dir)
+	c.Assert(err, IsNil)
+	c.Check(schema.Lookup("port").Type, Equals, "integer")
+
+	c.Assert(ioutil.WriteFile(filepath.Join(dir, "meta", "config-schema.yaml"), []byte("type: array"), 0644), IsNil)
+	_, err = configschema.ReadSchema(dir)
+	c.Check(err, ErrorMatches, `invalid configuration schema: top level type must be "object", not "array"`)
+}
+
+func (s *schemaSuite) TestReadSchemaFromSnapFile(c *C) {
+	dir := c.MkDir()
+	snapf := snapdir.New(dir)
+
+	// the schema is optional
+	schema, err := configschema.ReadSchemaFromSnapFile(snapf)
+	c.Assert(err, IsNil)
+	c.Check(schema, IsNil)
+
+	c.Assert(os.MkdirAll(filepath.Join(dir, "meta"), 0755), IsNil)
+	c.Assert(ioutil.WriteFile(filepath.Join(dir, "meta", "config-schema.yaml"), []byte(mockSchema), 0644), IsNil)
+	schema, err = configschema.ReadSchemaFromSnapFile(snapf)
+	c.Assert(err, IsNil)
+	c.Check(schema.Lookup("port").Type, Equals, "integer")
+
+	c.Assert(ioutil.WriteFile(filepath.Join(dir, "meta", "config-schema.yaml"), []byte("properties: {port: {type: float}}"), 0644), IsNil)
+	_, err = configschema.ReadSchemaFromSnapFile(snapf)
+	c.Check(err, ErrorMatches, `invalid configuration schema: option "port" has unknown type "float"`)
+}
+
+func (s *schemaSuite) TestValidatePatch(c *C) {
+	schema, err := configschema.Parse([]byte(mockSchema))
+	c.Assert(err, IsNil)
+
+	for _, patch := range []map[string]interface{}{
+		{"port": json.Number("443"), "mode": "fast", "name": "abc", "debug": true},
+		{"port": 443, "ratio": json.Number("0.5"), "servers": []interface{}{"a", "b"}},
+		{"proxy": map[string]interface{}{"url": "http://proxy", "timeout": json.Number("10")}},
+		{"proxy.url": "http://proxy", "proxy.other": json.Number("1")},
+		{"extra": json.Number("1")},
+		{"extra.anything": []interface{}{true}},
+		// unsetting is always fine
+		{"port": nil, "proxy": nil, "proxy.url": nil},
+	} {
+		c.Check(schema.ValidatePatch(nil, patch), IsNil, Commentf("%v", patch))
+	}
+
+	for _, tc := range []struct {
+		patch map[string]interface{}
+		err   string
+	}{
+		{map[string]interface{}{"port": "abc"}, `invalid value for option "port": expected integer, got string "abc"`},
+		{map[string]interface{}{"port": json.Number("1.5")}, `invalid value for option "port": expected integer, got 1.5`},
+		{map[string]interface{}{"port": json.Number("0")}, `invalid value for option "port": 0 is less than the minimum 1`},
+		{map[string]interface{}{"port": json.Number("65536")}, `invalid value for option "port": 65536 is greater than the maximum 65535`},
+		{map[string]interface{}{"mode": "slow"}, `invalid value for option "mode": "slow" is not one of "fast", "safe"`},
+		{map[string]interface{}{"name": "ABC"}, `invalid value for option "name": "ABC" does not match "\[a-z\]\+"`},
+		{map[string]interface{}{"debug": "true"}, `invalid value for option "debug": expected boolean, got string "true"`},
+		{map[string]interface{}{"ratio": false}, `invalid value for option "ratio": expected number, got boolean`},
+		{map[string]interface{}{"servers": "a"}, `invalid value for option "servers": expected array, got string "a"`},
+		{map[string]interface{}{"servers": []interface{}{"a", json.Number("1")}}, `invalid value for option "servers\[1\]": expected string, got number 1`},
+		{map[string]interface{}{"proxy": "http://proxy"}, `invalid value for option "proxy": expected object, got string "http://proxy"`},
+		{map[string]interface{}{"proxy": map[string]interface{}{"timeout": "1"}}, `invalid value for option "proxy.timeout": expected integer, got string "1"`},
+		{map[string]interface{}{"proxy.timeout": true}, `invalid value for option "proxy.timeout": expected integer, got boolean`},
+		{map[string]interface{}{"port.number": json.Number("1")}, `invalid value for option "port": expected integer, cannot set "number" in it`},
+		{map[string]interface{}{"unknown": json.Number("1")}, `invalid value for option "unknown": unknown option`},
+	} {
+		err := schema.ValidatePatch(nil, tc.patch)
+		c.Check(err, ErrorMatches, tc.err, Commentf("%v", tc.patch))
+		c.Check(err, FitsTypeOf, &configschema.ValidationError{})
+	}
+}
+
+func (s *schemaSuite) TestValidatePatchMerged(c *C) {
+	schema, err := configschema.Parse([]byte(mockSchema))
+	c.Assert(err, IsNil)
+
+	conf := map[string]interface{}{
+		"port":  json.Number("80"),
+		"proxy": map[string]interface{}{"url": "http://proxy"},
+	}
+	c.Check(schema.ValidatePatch(conf, map[string]interface{}{"proxy.timeout": json.Number("10")}), IsNil)
+	// the configuration is left untouched
+	c.Check(conf, DeepEquals, map[string]interface{}{
+		"port":  json.Number("80"),
+		"proxy": map[string]interface{}{"url": "http://proxy"},
+	})
+
+	// the options already set are checked as well
+	conf = map[string]interface{}{
+		"port":    json.Number("80"),
+		"unknown": "value",
+		"proxy":   map[string]interface{}{"timeout": "1"},
+	}
+	err = schema.ValidatePatch(conf, map[string]interface{}{"mode": "fast"})
+	c.Check(err, ErrorMatches, `invalid value for option "proxy.timeout": expected integer, got string "1"`)
+	err = schema.ValidatePatch(conf, map[string]interface{}{"proxy.timeout": json.Number("1")})
+	c.Check(err, ErrorMatches, `invalid value for option "unknown": unknown option`)
+	// unless the patch fixes or unsets them
+	c.Check(schema.ValidatePatch(conf, map[string]interface{}{"unknown": nil, "proxy": nil}), IsNil)
+	c.Check(schema.ValidatePatch(conf, map[string]interface{}{"unknown": nil, "proxy": nil, "proxy.url": "http://proxy"}), IsNil)
+	err = schema.ValidatePatch(conf, map[string]interface{}{"unknown": nil, "proxy": nil, "proxy.timeout": "2"})
+	c.Check(err, ErrorMatches, `invalid value for option "proxy.timeout": expected integer, got string "2"`)
+}
+
+func (s *schemaSuite) TestWithDefaults(c *C) {
+	schema, err := configschema.Parse([]byte(mockSchema))
+	c.Assert(err, IsNil)
+
+	c.Check(schema.WithDefaults(nil), DeepEquals, map[string]interface{}{
+		"port":  int64(8080),
+		"mode":  "safe",
+		"proxy": map[string]interface{}{"timeout": int64(30)},
+	})
+	cfg := map[string]interface{}{
+		"port":  json.Number("443"),
+		"other": "value",
+		"proxy": map[string]interface{}{"url": "http://proxy"},
+	}
+	c.Check(schema.WithDefaults(cfg), DeepEquals, map[string]interface{}{
+		"port":  json.Number("443"),
+		"mode":  "safe",
+		"other": "value",
+		"proxy": map[string]interface{}{"url": "http://proxy", "timeout": int64(30)},
+	})
+	// the given value is left alone
+	c.Check(cfg["mode"], IsNil)
+
+	c.Check(schema.Lookup("port").WithDefaults(nil), Equals, int64(8080))
+	c.Check(schema.Lookup("port").WithDefaults(json.Number("1")), Equals, json.Number("1"))
+	c.Check(schema.Lookup("proxy.url").WithDefaults(nil), IsNil)
+}
@@ -30,6 +30,7 @@ import (
 	"github.com/snapcore/snapd/kernel"
 	"github.com/snapcore/snapd/logger"
 	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/snap/configschema"
 	"github.com/snapcore/snapd/snap/snapdir"
 	"github.com/snapcore/snapd/snap/squashfs"
 )
@@ -142,6 +143,9 @@ func loadAndValidate(sourceDir string) (*snap.Info, error) {
 			return nil, err
 		}
 	}
+	if _, err := configschema.ReadSchema(sourceDir); err != nil {
+		return nil, err
+	}
 
 	return info, nil
 }
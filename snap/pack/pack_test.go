@@ -270,6 +270,41 @@ integration:
 
 }
 
+func (s *packSuite) TestPackConfigSchemaValidate(c *C) {
+	sourceDir := makeExampleSnapSourceDir(c, "{name: hello, version: 0}")
+
+	err := ioutil.WriteFile(filepath.Join(sourceDir, "meta/config-schema.yaml"), []byte(`
+properties:
+  port:
+    type: integer
+    default: http
+`), 0644)
+	c.Assert(err, IsNil)
+
+	outputDir := filepath.Join(c.MkDir(), "output")
+	absSnapFile := filepath.Join(c.MkDir(), "foo.snap")
+
+	_, err = pack.Snap(sourceDir, &pack.Options{
+		TargetDir: outputDir,
+		SnapName:  absSnapFile,
+	})
+	c.Assert(err, ErrorMatches, `invalid configuration schema: option "port" has an invalid default: expected integer, got string "http"`)
+
+	err = ioutil.WriteFile(filepath.Join(sourceDir, "meta/config-schema.yaml"), []byte(`
+properties:
+  port:
+    type: integer
+    default: 80
+`), 0644)
+	c.Assert(err, IsNil)
+
+	_, err = pack.Snap(sourceDir, &pack.Options{
+		TargetDir: outputDir,
+		SnapName:  absSnapFile,
+	})
+	c.Assert(err, IsNil)
+}
+
 func (s *packSuite) TestPackGadgetValidate(c *C) {
 	sourceDir := makeExampleSnapSourceDir(c, `name: funky-gadget
 version: 1.0.1
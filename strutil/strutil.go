@@ -20,6 +20,8 @@
 package strutil
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"sort"
 	"strconv"
@@ -246,3 +248,14 @@ func ElliptLeft(str string, n int) string {
 
 	return "…" + string(rstr[len(rstr)-n+1:])
 }
+
+// NormalizeCertificateFingerprint returns the canonical lower case hex
+// form of a SHA256 certificate fingerprint, accepting also the colon
+// separated form used by openssl.
+func NormalizeCertificateFingerprint(fp string) (string, error) {
+	norm := strings.ToLower(strings.Replace(fp, ":", "", -1))
+	if b, err := hex.DecodeString(norm); err != nil || len(b) != sha256.Size {
+		return "", fmt.Errorf("invalid SHA256 certificate fingerprint %q", fp)
+	}
+	return norm, nil
+}
@@ -22,6 +22,7 @@ package strutil_test
 import (
 	"math"
 	"sort"
+	"strings"
 	"testing"
 
 	"gopkg.in/check.v1"
@@ -249,3 +250,21 @@ func (strutilSuite) TestSortedListsUniqueMerge(c *check.C) {
 		c.Check(res, check.DeepEquals, t.res)
 	}
 }
+
+func (strutilSuite) TestNormalizeCertificateFingerprint(c *check.C) {
+	const fp = "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"
+	for _, in := range []string{
+		fp,
+		strings.ToUpper(fp),
+		"9F:86:D0:81:88:4C:7D:65:9A:2F:EA:A0:C5:5A:D0:15:A3:BF:4F:1B:2B:0B:82:2C:D1:5D:6C:15:B0:F0:0A:08",
+	} {
+		norm, err := strutil.NormalizeCertificateFingerprint(in)
+		c.Assert(err, check.IsNil)
+		c.Check(norm, check.Equals, fp)
+	}
+
+	for _, in := range []string{"", "9f86", "not-hex", fp + "00"} {
+		_, err := strutil.NormalizeCertificateFingerprint(in)
+		c.Check(err, check.ErrorMatches, `invalid SHA256 certificate fingerprint ".*"`)
+	}
+}
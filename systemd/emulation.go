@@ -111,7 +111,7 @@ func (s *emulation) IsActive(service string) (bool, error) {
 	return false, errNotImplemented
 }
 
-func (s *emulation) LogReader(services []string, n int, follow bool) (io.ReadCloser, error) {
+func (s *emulation) LogReader(services []string, n int, follow bool, filter *LogFilter) (io.ReadCloser, error) {
 	return nil, errNotImplemented
 }
 
@@ -43,6 +43,7 @@ import (
 	"github.com/snapcore/snapd/osutil"
 	"github.com/snapcore/snapd/osutil/squashfs"
 	"github.com/snapcore/snapd/sandbox/selinux"
+	"github.com/snapcore/snapd/strutil"
 )
 
 var (
@@ -137,9 +138,14 @@ func Available() error {
 
 // Version returns systemd version.
 func Version() (int, error) {
+	ver, _, err := versionAndFeatures()
+	return ver, err
+}
+
+func versionAndFeatures() (int, []string, error) {
 	out, err := systemctlCmd("--version")
 	if err != nil {
-		return 0, err
+		return 0, nil, err
 	}
 
 	// systemd version outpus is two lines - actual version and a list
@@ -154,11 +160,11 @@ func Version() (int, error) {
 	var verstr string
 	for i := 0; i < 2; i++ {
 		if !r.Scan() {
-			return 0, fmt.Errorf("cannot read systemd version: %v", r.Err())
+			return 0, nil, fmt.Errorf("cannot read systemd version: %v", r.Err())
 		}
 		s := r.Text()
 		if i == 0 && s != "systemd" {
-			return 0, fmt.Errorf("cannot parse systemd version: expected \"systemd\", got %q", s)
+			return 0, nil, fmt.Errorf("cannot parse systemd version: expected \"systemd\", got %q", s)
 		}
 		if i == 1 {
 			verstr = strings.TrimSpace(s)
@@ -167,19 +173,97 @@ func Version() (int, error) {
 
 	ver, err := strconv.Atoi(verstr)
 	if err != nil {
-		return 0, fmt.Errorf("cannot convert systemd version to number: %s", verstr)
+		return 0, nil, fmt.Errorf("cannot convert systemd version to number: %s", verstr)
+	}
+
+	var features []string
+	if lines := strings.SplitN(string(out), "\n", 3); len(lines) > 1 {
+		features = strings.Fields(lines[1])
+	}
+	return ver, features, nil
+}
+
+// EnsureJournalGrep returns an error if journalctl cannot filter the
+// messages of the entries with --grep, which was added in systemd 237 and
+// needs it to be built with PCRE2.
+func EnsureJournalGrep() error {
+	ver, features, err := versionAndFeatures()
+	if err != nil {
+		return err
+	}
+	if ver < 237 {
+		return fmt.Errorf("systemd version %d is too old (expected at least 237)", ver)
+	}
+	if !strutil.ListContains(features, "+PCRE2") {
+		return fmt.Errorf("journalctl is built without PCRE2 support")
 	}
-	return ver, nil
+	return nil
 }
 
 var osutilStreamCommand = osutil.StreamCommand
 
+// LogFilter restricts the journal entries returned by LogReader.
+type LogFilter struct {
+	// Since and Until, if not zero, bound the time of the entries.
+	Since time.Time
+	Until time.Time
+	// Priority, if not empty, is the lowest priority of the entries,
+	// as a syslog level name or number.
+	Priority string
+	// Boot, if not empty, restricts the entries to a single boot, as
+	// accepted by journalctl --boot, e.g. "0" for the current boot or
+	// "-1" for the previous one.
+	Boot string
+	// Grep, if not empty, is a PCRE2 regular expression the messages of
+	// the entries must match. As with journalctl, a pattern without
+	// uppercase letters matches case-insensitively. It is not supported
+	// by all systems, see EnsureJournalGrep.
+	Grep string
+	// AfterCursor, if not empty, is the journal cursor of the entry
+	// after which to start.
+	AfterCursor string
+}
+
+func (f *LogFilter) args() []string {
+	if f == nil {
+		return nil
+	}
+	var args []string
+	if !f.Since.IsZero() {
+		args = append(args, "--since", journalTimestamp(f.Since))
+	}
+	if !f.Until.IsZero() {
+		args = append(args, "--until", journalTimestamp(f.Until))
+	}
+	if f.Priority != "" {
+		args = append(args, "--priority", f.Priority)
+	}
+	if f.Boot != "" {
+		args = append(args, "--boot", f.Boot)
+	}
+	if f.Grep != "" {
+		args = append(args, "--grep", f.Grep)
+	}
+	if f.AfterCursor != "" {
+		args = append(args, "--after-cursor", f.AfterCursor)
+	}
+	return args
+}
+
+// journalTimestamp formats the given time as seconds since the epoch, with
+// the microsecond precision of the journal.
+func journalTimestamp(t time.Time) string {
+	usec := t.UnixNano() / int64(time.Microsecond)
+	return fmt.Sprintf("@%d.%06d", usec/1e6, usec%1e6)
+}
+
 // jctl calls journalctl to get the JSON logs of the given services.
-var jctl = func(svcs []string, n int, follow bool) (io.ReadCloser, error) {
+var jctl = func(svcs []string, n int, follow bool, filter *LogFilter) (io.ReadCloser, error) {
+	filterArgs := filter.args()
 	// args will need two entries per service, plus a fixed number (give or take
-	// one) for the initial options.
-	args := make([]string, 0, 2*len(svcs)+6)        // the fixed number is 6
-	args = append(args, "-o", "json", "--no-pager") //   3...
+	// one) for the initial options, plus the filter ones.
+	args := make([]string, 0, 2*len(svcs)+6+len(filterArgs)) // the fixed number is 6
+	args = append(args, "-o", "json", "--no-pager")          //   3...
 	if n < 0 {
 		args = append(args, "--no-tail") // < 2
 	} else {
@@ -188,6 +272,7 @@ var jctl = func(svcs []string, n int, follow bool) (io.ReadCloser, error) {
 	if follow {
 		args = append(args, "-f") // ... + 1 == 6
 	}
+	args = append(args, filterArgs...)
 
 	for i := range svcs {
 		args = append(args, "-u", svcs[i]) // this is why 2×
@@ -196,7 +281,7 @@ var jctl = func(svcs []string, n int, follow bool) (io.ReadCloser, error) {
 	return osutilStreamCommand("journalctl", args...)
 }
 
-func MockJournalctl(f func(svcs []string, n int, follow bool) (io.ReadCloser, error)) func() {
+func MockJournalctl(f func(svcs []string, n int, follow bool, filter *LogFilter) (io.ReadCloser, error)) func() {
 	oldJctl := jctl
 	jctl = f
 	return func() {
@@ -249,8 +334,9 @@ type Systemd interface {
 	IsEnabled(service string) (bool, error)
 	// IsActive checks whether the given service is Active
 	IsActive(service string) (bool, error)
-	// LogReader returns a reader for the given services' log.
-	LogReader(services []string, n int, follow bool) (io.ReadCloser, error)
+	// LogReader returns a reader for the given services' log, with
+	// the entries restricted by filter if not nil.
+	LogReader(services []string, n int, follow bool, filter *LogFilter) (io.ReadCloser, error)
 	// AddMountUnitFile adds/enables/starts a mount unit.
 	AddMountUnitFile(name, revision, what, where, fstype string) (string, error)
 	// RemoveMountUnitFile unmounts/stops/disables/removes a mount unit.
@@ -456,8 +542,8 @@ func (s *systemd) StartNoBlock(serviceNames ...string) error {
 	return err
 }
 
-func (*systemd) LogReader(serviceNames []string, n int, follow bool) (io.ReadCloser, error) {
-	return jctl(serviceNames, n, follow)
+func (*systemd) LogReader(serviceNames []string, n int, follow bool, filter *LogFilter) (io.ReadCloser, error) {
+	return jctl(serviceNames, n, follow, filter)
 }
 
 var statusregex = regexp.MustCompile(`(?m)^(?:(.+?)=(.*)|(.*))?$`)
@@ -469,17 +555,44 @@ type UnitStatus struct {
 	Active   bool
 	// Installed is false if the queried unit doesn't exist.
 	Installed bool
+	// Result is the result of the last run of a service, as in
+	// "success", "exit-code" or "timeout".
+	Result string
+	// NextElapse and LastTrigger are the next and last times a timer
+	// elapses, they are zero if unknown.
+	NextElapse  time.Time
+	LastTrigger time.Time
+	// Listen are the addresses a socket listens on.
+	Listen []string
 }
 
 var baseProperties = []string{"Id", "ActiveState", "UnitFileState"}
-var extendedProperties = []string{"Id", "ActiveState", "UnitFileState", "Type"}
+var extendedProperties = []string{"Id", "ActiveState", "UnitFileState", "Type", "Result"}
+var timerProperties = []string{"Id", "ActiveState", "UnitFileState", "NextElapseUSecRealtime", "LastTriggerUSec"}
+var socketProperties = []string{"Id", "ActiveState", "UnitFileState", "Listen"}
+
+// unitProperties are the properties that must be reported for each unit
+// type, others might be missing with older versions of systemd.
 var unitProperties = map[string][]string{
 	".timer":  baseProperties,
 	".socket": baseProperties,
 	// in service units, Type is the daemon type
-	".service": extendedProperties,
+	".service": {"Id", "ActiveState", "UnitFileState", "Type"},
 	// in mount units, Type is the fs type
-	".mount": extendedProperties,
+	".mount": {"Id", "ActiveState", "UnitFileState", "Type"},
+}
+
+// parseTimestamp parses a timestamp as formatted by systemctl, an empty
+// one or "n/a" is the zero time.
+func parseTimestamp(timeStr string) (time.Time, error) {
+	if timeStr == "" || timeStr == "n/a" {
+		return time.Time{}, nil
+	}
+	t, err := time.Parse("Mon 2006-01-02 15:04:05 MST", timeStr)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("internal error: systemctl time output (%s) is malformed", timeStr)
+	}
+	return t, nil
 }
 
 func (s *systemd) getUnitStatus(properties []string, unitNames []string) ([]*UnitStatus, error) {
@@ -533,8 +646,12 @@ func (s *systemd) getUnitStatus(properties []string, unitNames []string) ([]*Uni
 		k := string(bs[1])
 		v := string(bs[2])
 
-		if v == "" && k != "UnitFileState" && k != "Type" {
-			return nil, fmt.Errorf("cannot get unit status: empty field %q in ‘systemctl show’ output", k)
+		switch k {
+		case "UnitFileState", "Type", "Result", "NextElapseUSecRealtime", "LastTriggerUSec", "Listen":
+		default:
+			if v == "" {
+				return nil, fmt.Errorf("cannot get unit status: empty field %q in ‘systemctl show’ output", k)
+			}
 		}
 
 		switch k {
@@ -549,6 +666,29 @@ func (s *systemd) getUnitStatus(properties []string, unitNames []string) ([]*Uni
 			// "static" means it can't be disabled
 			cur.Enabled = v == "enabled" || v == "static"
 			cur.Installed = v != ""
+		case "Result":
+			cur.Result = v
+		case "NextElapseUSecRealtime":
+			t, err := parseTimestamp(v)
+			if err != nil {
+				return nil, err
+			}
+			cur.NextElapse = t
+		case "LastTriggerUSec":
+			t, err := parseTimestamp(v)
+			if err != nil {
+				return nil, err
+			}
+			cur.LastTrigger = t
+		case "Listen":
+			// one line per address, as in "/run/foo.sock (Stream)"
+			if idx := strings.LastIndex(v, " ("); idx > 0 {
+				v = v[:idx]
+			}
+			if v != "" {
+				cur.Listen = append(cur.Listen, v)
+			}
+			continue
 		default:
 			return nil, fmt.Errorf("cannot get unit status: unexpected field %q in ‘systemctl show’ output", k)
 		}
@@ -669,16 +809,7 @@ func (s *systemd) InactiveEnterTimestamp(unit string) (time.Time, error) {
 		return time.Time{}, err
 	}
 
-	if timeStr == "" {
-		return time.Time{}, nil
-	}
-
-	// finally parse the time string
-	inactiveEnterTime, err := time.Parse("Mon 2006-01-02 15:04:05 MST", timeStr)
-	if err != nil {
-		return time.Time{}, fmt.Errorf("internal error: systemctl time output (%s) is malformed", timeStr)
-	}
-	return inactiveEnterTime, nil
+	return parseTimestamp(timeStr)
 }
 
 func (s *systemd) Status(unitNames ...string) ([]*UnitStatus, error) {
@@ -687,13 +818,17 @@ func (s *systemd) Status(unitNames ...string) ([]*UnitStatus, error) {
 	}
 	unitToStatus := make(map[string]*UnitStatus, len(unitNames))
 
-	var limitedUnits []string
+	var timerUnits []string
+	var socketUnits []string
 	var extendedUnits []string
 
 	for _, name := range unitNames {
-		if strings.HasSuffix(name, ".timer") || strings.HasSuffix(name, ".socket") {
-			limitedUnits = append(limitedUnits, name)
-		} else {
+		switch {
+		case strings.HasSuffix(name, ".timer"):
+			timerUnits = append(timerUnits, name)
+		case strings.HasSuffix(name, ".socket"):
+			socketUnits = append(socketUnits, name)
+		default:
 			extendedUnits = append(extendedUnits, name)
 		}
 	}
@@ -703,7 +838,8 @@ func (s *systemd) Status(unitNames ...string) ([]*UnitStatus, error) {
 		properties []string
 	}{
 		{units: extendedUnits, properties: extendedProperties},
-		{units: limitedUnits, properties: baseProperties},
+		{units: timerUnits, properties: timerProperties},
+		{units: socketUnits, properties: socketProperties},
 	} {
 		if len(set.units) == 0 {
 			continue
@@ -1038,6 +1174,34 @@ func (l Log) PID() string {
 	return "-"
 }
 
+// Cursor is the journal cursor of the Log, if any; otherwise, "".
+func (l Log) Cursor() string {
+	cursor, err := l.parseLogRawMessageString("__CURSOR", func([]string) (string, error) {
+		return "", fmt.Errorf("multiple cursors not supported")
+	})
+	if err != nil {
+		return ""
+	}
+	return cursor
+}
+
+// Fields returns all the fields of the Log as strings. The values of fields
+// appearing multiple times are joined with newlines, fields whose values
+// cannot be decoded are left out.
+func (l Log) Fields() map[string]string {
+	fields := make(map[string]string, len(l))
+	for key := range l {
+		value, err := l.parseLogRawMessageString(key, func(stringSlice []string) (string, error) {
+			return strings.Join(stringSlice, "\n"), nil
+		})
+		if err != nil {
+			continue
+		}
+		fields[key] = value
+	}
+	return fields
+}
+
 // MountUnitPath returns the path of a {,auto}mount unit
 func MountUnitPath(baseDir string) string {
 	escapedPath := EscapeUnitNamePath(baseDir)
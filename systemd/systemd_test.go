@@ -66,6 +66,7 @@ type SystemdTestSuite struct {
 	jouts    [][]byte
 	jerrs    []error
 	jfollows []bool
+	jfilters []*LogFilter
 
 	rep *testreporter
 
@@ -98,6 +99,7 @@ func (s *SystemdTestSuite) SetUpTest(c *C) {
 	s.jouts = nil
 	s.jerrs = nil
 	s.jfollows = nil
+	s.jfilters = nil
 
 	s.rep = new(testreporter)
 
@@ -122,13 +124,14 @@ func (s *SystemdTestSuite) myRun(args ...string) (out []byte, err error) {
 	return out, err
 }
 
-func (s *SystemdTestSuite) myJctl(svcs []string, n int, follow bool) (io.ReadCloser, error) {
+func (s *SystemdTestSuite) myJctl(svcs []string, n int, follow bool, filter *LogFilter) (io.ReadCloser, error) {
 	var err error
 	var out []byte
 
 	s.jns = append(s.jns, strconv.Itoa(n))
 	s.jsvcs = append(s.jsvcs, svcs)
 	s.jfollows = append(s.jfollows, follow)
+	s.jfilters = append(s.jfilters, filter)
 
 	if s.j < len(s.jouts) {
 		out = s.jouts[s.j]
@@ -195,11 +198,13 @@ Type=simple
 Id=foo.service
 ActiveState=active
 UnitFileState=enabled
+Result=success
 
 Type=simple
 Id=bar.service
 ActiveState=reloading
 UnitFileState=static
+Result=exit-code
 
 Type=potato
 Id=baz.service
@@ -210,19 +215,31 @@ Type=
 Id=missing.service
 ActiveState=inactive
 UnitFileState=
+Result=
 `[1:]),
 		[]byte(`
 Id=some.timer
 ActiveState=active
 UnitFileState=enabled
+NextElapseUSecRealtime=Tue 2021-03-02 00:00:00 UTC
+LastTriggerUSec=Mon 2021-03-01 00:00:01 UTC
 
+Id=other.timer
+ActiveState=active
+UnitFileState=enabled
+NextElapseUSecRealtime=
+LastTriggerUSec=n/a
+`[1:]),
+		[]byte(`
 Id=other.socket
 ActiveState=active
 UnitFileState=disabled
+Listen=/run/snap.foo/sock (Stream)
+Listen=[::]:8080 (Stream)
 `[1:]),
 	}
 	s.errors = []error{nil}
-	out, err := New(SystemMode, s.rep).Status("foo.service", "bar.service", "baz.service", "missing.service", "some.timer", "other.socket")
+	out, err := New(SystemMode, s.rep).Status("foo.service", "bar.service", "baz.service", "missing.service", "some.timer", "other.timer", "other.socket")
 	c.Assert(err, IsNil)
 	c.Check(out, DeepEquals, []*UnitStatus{
 		{
@@ -231,12 +248,14 @@ UnitFileState=disabled
 			Active:    true,
 			Enabled:   true,
 			Installed: true,
+			Result:    "success",
 		}, {
 			Daemon:    "simple",
 			UnitName:  "bar.service",
 			Active:    true,
 			Enabled:   true,
 			Installed: true,
+			Result:    "exit-code",
 		}, {
 			Daemon:    "potato",
 			UnitName:  "baz.service",
@@ -250,7 +269,14 @@ UnitFileState=disabled
 			Enabled:   false,
 			Installed: false,
 		}, {
-			UnitName:  "some.timer",
+			UnitName:    "some.timer",
+			Active:      true,
+			Enabled:     true,
+			Installed:   true,
+			NextElapse:  time.Date(2021, 3, 2, 0, 0, 0, 0, time.UTC),
+			LastTrigger: time.Date(2021, 3, 1, 0, 0, 1, 0, time.UTC),
+		}, {
+			UnitName:  "other.timer",
 			Active:    true,
 			Enabled:   true,
 			Installed: true,
@@ -259,12 +285,14 @@ UnitFileState=disabled
 			Active:    true,
 			Enabled:   false,
 			Installed: true,
+			Listen:    []string{"/run/snap.foo/sock", "[::]:8080"},
 		},
 	})
 	c.Check(s.rep.msgs, IsNil)
 	c.Assert(s.argses, DeepEquals, [][]string{
-		{"show", "--property=Id,ActiveState,UnitFileState,Type", "foo.service", "bar.service", "baz.service", "missing.service"},
-		{"show", "--property=Id,ActiveState,UnitFileState", "some.timer", "other.socket"},
+		{"show", "--property=Id,ActiveState,UnitFileState,Type,Result", "foo.service", "bar.service", "baz.service", "missing.service"},
+		{"show", "--property=Id,ActiveState,UnitFileState,NextElapseUSecRealtime,LastTriggerUSec", "some.timer", "other.timer"},
+		{"show", "--property=Id,ActiveState,UnitFileState,Listen", "other.socket"},
 	})
 }
 
@@ -522,7 +550,7 @@ func (s *SystemdTestSuite) TestIsTimeout(c *C) {
 func (s *SystemdTestSuite) TestLogErrJctl(c *C) {
 	s.jerrs = []error{&Timeout{}}
 
-	reader, err := New(SystemMode, s.rep).LogReader([]string{"foo"}, 24, false)
+	reader, err := New(SystemMode, s.rep).LogReader([]string{"foo"}, 24, false, nil)
 	c.Check(err, NotNil)
 	c.Check(reader, IsNil)
 	c.Check(s.jns, DeepEquals, []string{"24"})
@@ -537,7 +565,7 @@ func (s *SystemdTestSuite) TestLogs(c *C) {
 `
 	s.jouts = [][]byte{[]byte(expected)}
 
-	reader, err := New(SystemMode, s.rep).LogReader([]string{"foo"}, 24, false)
+	reader, err := New(SystemMode, s.rep).LogReader([]string{"foo"}, 24, false, nil)
 	c.Check(err, IsNil)
 	logs, err := ioutil.ReadAll(reader)
 	c.Assert(err, IsNil)
@@ -548,6 +576,15 @@ func (s *SystemdTestSuite) TestLogs(c *C) {
 	c.Check(s.j, Equals, 1)
 }
 
+func (s *SystemdTestSuite) TestLogReaderFilter(c *C) {
+	s.jouts = [][]byte{nil}
+
+	filter := &LogFilter{Priority: "err"}
+	_, err := New(SystemMode, s.rep).LogReader([]string{"foo"}, 24, false, filter)
+	c.Assert(err, IsNil)
+	c.Check(s.jfilters, DeepEquals, []*LogFilter{filter})
+}
+
 // mustJSONMarshal panic's if the value cannot be marshaled
 func mustJSONMarshal(v interface{}) *json.RawMessage {
 	b, err := json.Marshal(v)
@@ -710,6 +747,30 @@ func (s *SystemdTestSuite) TestLogPID(c *C) {
 	}.PID(), Equals, "42")
 }
 
+func (s *SystemdTestSuite) TestLogCursor(c *C) {
+	c.Check(Log{}.Cursor(), Equals, "")
+	c.Check(Log{"__CURSOR": mustJSONMarshal("s=abc;i=1")}.Cursor(), Equals, "s=abc;i=1")
+	c.Check(Log{"__CURSOR": mustJSONMarshal([]string{"a", "b"})}.Cursor(), Equals, "")
+}
+
+func (s *SystemdTestSuite) TestLogFields(c *C) {
+	c.Check(Log{}.Fields(), DeepEquals, map[string]string{})
+	c.Check(Log{
+		"MESSAGE":           mustJSONMarshal("hello"),
+		"_PID":              mustJSONMarshal("42"),
+		"SYSLOG_IDENTIFIER": mustJSONMarshal([]string{"abc", "def"}),
+		"_COMM":             mustJSONMarshal([]int{115, 110, 97, 112}),
+		// values which cannot be decoded are left out
+		"BROKEN":    mustJSONMarshal(42),
+		"TRUNCATED": nil,
+	}.Fields(), DeepEquals, map[string]string{
+		"MESSAGE":           "hello",
+		"_PID":              "42",
+		"SYSLOG_IDENTIFIER": "abc\ndef",
+		"_COMM":             "snap",
+	})
+}
+
 func (s *SystemdTestSuite) TestTime(c *C) {
 	t, err := Log{}.Time()
 	c.Check(t.IsZero(), Equals, true)
@@ -944,15 +1005,65 @@ func (s *SystemdTestSuite) TestJctl(c *C) {
 		return nil, nil
 	})
 
-	_, err = Jctl([]string{"foo", "bar"}, 10, false)
+	_, err = Jctl([]string{"foo", "bar"}, 10, false, nil)
 	c.Assert(err, IsNil)
 	c.Check(args, DeepEquals, []string{"-o", "json", "--no-pager", "-n", "10", "-u", "foo", "-u", "bar"})
-	_, err = Jctl([]string{"foo", "bar", "baz"}, 99, true)
+	_, err = Jctl([]string{"foo", "bar", "baz"}, 99, true, nil)
 	c.Assert(err, IsNil)
 	c.Check(args, DeepEquals, []string{"-o", "json", "--no-pager", "-n", "99", "-f", "-u", "foo", "-u", "bar", "-u", "baz"})
-	_, err = Jctl([]string{"foo", "bar"}, -1, false)
+	_, err = Jctl([]string{"foo", "bar"}, -1, false, nil)
 	c.Assert(err, IsNil)
 	c.Check(args, DeepEquals, []string{"-o", "json", "--no-pager", "--no-tail", "-u", "foo", "-u", "bar"})
+	_, err = Jctl([]string{"foo"}, 10, true, &LogFilter{})
+	c.Assert(err, IsNil)
+	c.Check(args, DeepEquals, []string{"-o", "json", "--no-pager", "-n", "10", "-f", "-u", "foo"})
+}
+
+func (s *SystemdTestSuite) TestJctlFilter(c *C) {
+	var args []string
+	restore := MockOsutilStreamCommand(func(name string, myargs ...string) (io.ReadCloser, error) {
+		c.Check(cap(myargs) <= len(myargs)+2, Equals, true, Commentf("cap:%d, len:%d", cap(myargs), len(myargs)))
+		args = myargs
+		return nil, nil
+	})
+	defer restore()
+
+	filter := &LogFilter{
+		Since:    time.Date(2021, 3, 1, 10, 0, 0, 123456789, time.UTC),
+		Until:    time.Date(2021, 3, 2, 10, 0, 0, 0, time.UTC),
+		Priority: "warning",
+		Boot:     "-1",
+		Grep:     "segfault|crash",
+	}
+	_, err := Jctl([]string{"foo", "bar"}, -1, false, filter)
+	c.Assert(err, IsNil)
+	c.Check(args, DeepEquals, []string{
+		"-o", "json", "--no-pager", "--no-tail",
+		"--since", "@1614592800.123456", "--until", "@1614679200.000000",
+		"--priority", "warning", "--boot", "-1", "--grep", "segfault|crash",
+		"-u", "foo", "-u", "bar",
+	})
+
+	_, err = Jctl([]string{"snap.*"}, -1, true, &LogFilter{AfterCursor: "s=abc;i=1"})
+	c.Assert(err, IsNil)
+	c.Check(args, DeepEquals, []string{
+		"-o", "json", "--no-pager", "--no-tail", "-f",
+		"--after-cursor", "s=abc;i=1", "-u", "snap.*",
+	})
+}
+
+func (s *SystemdTestSuite) TestEnsureJournalGrep(c *C) {
+	s.outs = [][]byte{
+		[]byte("systemd 245 (245.4-4ubuntu3)\n+PAM +AUDIT -PCRE2 +IDN2\n"),
+		[]byte("systemd 245 (245.4-4ubuntu3)\n+PAM +AUDIT +PCRE2 +IDN2\n"),
+		[]byte("systemd 229\n+PAM +AUDIT\n"),
+		[]byte("foo 223\n+PAM\n"),
+	}
+
+	c.Check(EnsureJournalGrep(), ErrorMatches, `journalctl is built without PCRE2 support`)
+	c.Check(EnsureJournalGrep(), IsNil)
+	c.Check(EnsureJournalGrep(), ErrorMatches, `systemd version 229 is too old \(expected at least 237\)`)
+	c.Check(EnsureJournalGrep(), ErrorMatches, `cannot parse systemd version: .*`)
 }
 
 func (s *SystemdTestSuite) TestIsActiveUnderRoot(c *C) {
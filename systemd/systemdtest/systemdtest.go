@@ -39,7 +39,7 @@ type ServiceState struct {
 func HandleMockAllUnitsActiveOutput(cmd []string, states map[string]ServiceState) []byte {
 	osutil.MustBeTestBinary("mocking systemctl output can only be done from tests")
 	if cmd[0] != "show" ||
-		cmd[1] != "--property=Id,ActiveState,UnitFileState,Type" {
+		cmd[1] != "--property=Id,ActiveState,UnitFileState,Type,Result" {
 		return nil
 	}
 	var output []byte
@@ -34,6 +34,7 @@ import (
 	"github.com/snapcore/snapd/desktop/notification"
 	"github.com/snapcore/snapd/dirs"
 	"github.com/snapcore/snapd/i18n"
+	"github.com/snapcore/snapd/strutil"
 	"github.com/snapcore/snapd/systemd"
 	"github.com/snapcore/snapd/timeout"
 )
@@ -42,6 +43,7 @@ var restApi = []*Command{
 	rootCmd,
 	sessionInfoCmd,
 	serviceControlCmd,
+	serviceStatusCmd,
 	pendingRefreshNotificationCmd,
 }
 
@@ -61,6 +63,11 @@ var (
 		POST: postServiceControl,
 	}
 
+	serviceStatusCmd = &Command{
+		Path: "/v1/service-status",
+		GET:  serviceStatus,
+	}
+
 	pendingRefreshNotificationCmd = &Command{
 		Path: "/v1/notifications/pending-refresh",
 		POST: postPendingRefreshNotification,
@@ -77,6 +84,8 @@ func sessionInfo(c *Command, r *http.Request) Response {
 type serviceInstruction struct {
 	Action   string   `json:"action"`
 	Services []string `json:"services"`
+	// ExplicitServices are restarted even if they are not active.
+	ExplicitServices []string `json:"explicit-services,omitempty"`
 }
 
 var (
@@ -157,6 +166,62 @@ func serviceStop(inst *serviceInstruction, sysd systemd.Systemd) Response {
 	})
 }
 
+func serviceRestart(inst *serviceInstruction, sysd systemd.Systemd) Response {
+	return restartServices(inst, sysd, false)
+}
+
+func serviceReloadOrRestart(inst *serviceInstruction, sysd systemd.Systemd) Response {
+	return restartServices(inst, sysd, true)
+}
+
+// restartServices restarts, or reloads, the services which are active and
+// those explicitly asked for regardless of their state.
+func restartServices(inst *serviceInstruction, sysd systemd.Systemd, reload bool) Response {
+	// Refuse to restart non-snap services
+	for _, service := range inst.Services {
+		if !strings.HasPrefix(service, "snap.") {
+			return InternalError("cannot restart non-snap service %v", service)
+		}
+	}
+	if len(inst.Services) == 0 {
+		return SyncResponse(nil)
+	}
+
+	sts, err := sysd.Status(inst.Services...)
+	if err != nil {
+		return InternalError("cannot get status of services: %v", err)
+	}
+	restartErrors := make(map[string]string)
+	for _, st := range sts {
+		if !st.Active && !strutil.ListContains(inst.ExplicitServices, st.UnitName) {
+			continue
+		}
+		var err error
+		if reload {
+			err = sysd.ReloadOrRestart(st.UnitName)
+		} else {
+			err = sysd.Restart(st.UnitName, stopTimeout)
+		}
+		if err != nil {
+			restartErrors[st.UnitName] = err.Error()
+		}
+	}
+	if len(restartErrors) == 0 {
+		return SyncResponse(nil)
+	}
+	return SyncResponse(&resp{
+		Type:   ResponseTypeError,
+		Status: 500,
+		Result: &errorResult{
+			Message: "some user services failed to restart",
+			Kind:    errorKindServiceControl,
+			Value: map[string]interface{}{
+				"restart-errors": restartErrors,
+			},
+		},
+	})
+}
+
 func serviceDaemonReload(inst *serviceInstruction, sysd systemd.Systemd) Response {
 	if len(inst.Services) != 0 {
 		return InternalError("daemon-reload should not be called with any services")
@@ -168,9 +233,11 @@ func serviceDaemonReload(inst *serviceInstruction, sysd systemd.Systemd) Respons
 }
 
 var serviceInstructionDispTable = map[string]func(*serviceInstruction, systemd.Systemd) Response{
-	"start":         serviceStart,
-	"stop":          serviceStop,
-	"daemon-reload": serviceDaemonReload,
+	"start":             serviceStart,
+	"stop":              serviceStop,
+	"restart":           serviceRestart,
+	"reload-or-restart": serviceReloadOrRestart,
+	"daemon-reload":     serviceDaemonReload,
 }
 
 var systemdLock sync.Mutex
@@ -211,6 +278,44 @@ func postServiceControl(c *Command, r *http.Request) Response {
 	return impl(&inst, sysd)
 }
 
+// serviceUnitStatus is the status of a user service unit.
+type serviceUnitStatus struct {
+	Daemon  string `json:"daemon"`
+	Id      string `json:"id"`
+	Enabled bool   `json:"enabled"`
+	Active  bool   `json:"active"`
+}
+
+func serviceStatus(c *Command, r *http.Request) Response {
+	services := strutil.CommaSeparatedList(r.URL.Query().Get("services"))
+	if len(services) == 0 {
+		return BadRequest("cannot get status of services without a list of services")
+	}
+	for _, service := range services {
+		if !strings.HasPrefix(service, "snap.") {
+			return BadRequest("cannot get status of non-snap service %v", service)
+		}
+	}
+
+	systemdLock.Lock()
+	defer systemdLock.Unlock()
+	sysd := systemd.New(systemd.UserMode, dummyReporter{})
+	sts, err := sysd.Status(services...)
+	if err != nil {
+		return InternalError("cannot get status of services: %v", err)
+	}
+	result := make([]serviceUnitStatus, len(sts))
+	for i, st := range sts {
+		result[i] = serviceUnitStatus{
+			Daemon:  st.Daemon,
+			Id:      st.UnitName,
+			Enabled: st.Enabled,
+			Active:  st.Active,
+		}
+	}
+	return SyncResponse(result)
+}
+
 func postPendingRefreshNotification(c *Command, r *http.Request) Response {
 	contentType := r.Header.Get("Content-Type")
 	mediaType, params, err := mime.ParseMediaType(contentType)
@@ -27,6 +27,7 @@ import (
 	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/godbus/dbus"
@@ -35,6 +36,7 @@ import (
 	"github.com/snapcore/snapd/desktop/notification"
 	"github.com/snapcore/snapd/desktop/notification/notificationtest"
 	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/strutil"
 	"github.com/snapcore/snapd/systemd"
 	"github.com/snapcore/snapd/testutil"
 	"github.com/snapcore/snapd/usersession/agent"
@@ -362,6 +364,165 @@ func (s *restSuite) TestServicesStopReportsTimeout(c *C) {
 	})
 }
 
+func (s *restSuite) mockUserServicesStatus(activeServices ...string) {
+	restore := systemd.MockSystemctl(func(cmd ...string) ([]byte, error) {
+		s.sysdLog = append(s.sysdLog, cmd)
+		if cmd[1] == "show" && strings.HasPrefix(cmd[2], "--property=Id,") {
+			var out []string
+			for _, unit := range cmd[3:] {
+				activeState := "inactive"
+				if strutil.ListContains(activeServices, unit) {
+					activeState = "active"
+				}
+				out = append(out, fmt.Sprintf("Type=simple\nId=%s\nActiveState=%s\nUnitFileState=enabled\n", unit, activeState))
+			}
+			return []byte(strings.Join(out, "\n")), nil
+		}
+		return []byte("ActiveState=inactive\n"), nil
+	})
+	s.AddCleanup(restore)
+}
+
+func (s *restSuite) TestServicesRestart(c *C) {
+	s.mockUserServicesStatus("snap.foo.service")
+
+	req := httptest.NewRequest("POST", "/v1/service-control", bytes.NewBufferString(`{"action":"restart","services":["snap.foo.service", "snap.bar.service", "snap.baz.service"],"explicit-services":["snap.baz.service"]}`))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	agent.ServiceControlCmd.POST(agent.ServiceControlCmd, req).ServeHTTP(rec, req)
+	c.Check(rec.Code, Equals, 200)
+
+	var rsp resp
+	c.Assert(json.Unmarshal(rec.Body.Bytes(), &rsp), IsNil)
+	c.Check(rsp.Type, Equals, agent.ResponseTypeSync)
+	c.Check(rsp.Result, Equals, nil)
+
+	// the inactive snap.bar.service is not restarted
+	c.Check(s.sysdLog, DeepEquals, [][]string{
+		{"--user", "show", "--property=Id,ActiveState,UnitFileState,Type,Result", "snap.foo.service", "snap.bar.service", "snap.baz.service"},
+		{"--user", "stop", "snap.foo.service"},
+		{"--user", "show", "--property=ActiveState", "snap.foo.service"},
+		{"--user", "start", "snap.foo.service"},
+		{"--user", "stop", "snap.baz.service"},
+		{"--user", "show", "--property=ActiveState", "snap.baz.service"},
+		{"--user", "start", "snap.baz.service"},
+	})
+}
+
+func (s *restSuite) TestServicesReloadOrRestart(c *C) {
+	s.mockUserServicesStatus("snap.foo.service", "snap.bar.service")
+
+	req := httptest.NewRequest("POST", "/v1/service-control", bytes.NewBufferString(`{"action":"reload-or-restart","services":["snap.foo.service", "snap.bar.service"]}`))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	agent.ServiceControlCmd.POST(agent.ServiceControlCmd, req).ServeHTTP(rec, req)
+	c.Check(rec.Code, Equals, 200)
+
+	c.Check(s.sysdLog, DeepEquals, [][]string{
+		{"--user", "show", "--property=Id,ActiveState,UnitFileState,Type,Result", "snap.foo.service", "snap.bar.service"},
+		{"--user", "reload-or-restart", "snap.foo.service"},
+		{"--user", "reload-or-restart", "snap.bar.service"},
+	})
+}
+
+func (s *restSuite) TestServicesRestartReportsFailures(c *C) {
+	restore := systemd.MockSystemctl(func(cmd ...string) ([]byte, error) {
+		s.sysdLog = append(s.sysdLog, cmd)
+		switch cmd[1] {
+		case "show":
+			return []byte("Type=simple\nId=snap.foo.service\nActiveState=active\nUnitFileState=enabled\n"), nil
+		case "reload-or-restart":
+			return nil, fmt.Errorf("restart failure")
+		}
+		return nil, fmt.Errorf("unexpected command")
+	})
+	defer restore()
+
+	req := httptest.NewRequest("POST", "/v1/service-control", bytes.NewBufferString(`{"action":"reload-or-restart","services":["snap.foo.service"]}`))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	agent.ServiceControlCmd.POST(agent.ServiceControlCmd, req).ServeHTTP(rec, req)
+	c.Check(rec.Code, Equals, 500)
+
+	var rsp resp
+	c.Assert(json.Unmarshal(rec.Body.Bytes(), &rsp), IsNil)
+	c.Check(rsp.Type, Equals, agent.ResponseTypeError)
+	c.Check(rsp.Result, DeepEquals, map[string]interface{}{
+		"message": "some user services failed to restart",
+		"kind":    "service-control",
+		"value": map[string]interface{}{
+			"restart-errors": map[string]interface{}{
+				"snap.foo.service": "restart failure",
+			},
+		},
+	})
+}
+
+func (s *restSuite) TestServicesRestartNonSnap(c *C) {
+	req := httptest.NewRequest("POST", "/v1/service-control", bytes.NewBufferString(`{"action":"restart","services":["snap.foo.service", "not-snap.bar.service"]}`))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	agent.ServiceControlCmd.POST(agent.ServiceControlCmd, req).ServeHTTP(rec, req)
+	c.Check(rec.Code, Equals, 500)
+
+	var rsp resp
+	c.Assert(json.Unmarshal(rec.Body.Bytes(), &rsp), IsNil)
+	c.Check(rsp.Result, DeepEquals, map[string]interface{}{
+		"message": "cannot restart non-snap service not-snap.bar.service",
+	})
+	c.Check(s.sysdLog, HasLen, 0)
+}
+
+func (s *restSuite) TestServiceStatus(c *C) {
+	// the agent.ServiceStatus end point only supports GET requests
+	c.Check(agent.ServiceStatusCmd.PUT, IsNil)
+	c.Check(agent.ServiceStatusCmd.POST, IsNil)
+	c.Check(agent.ServiceStatusCmd.DELETE, IsNil)
+	c.Assert(agent.ServiceStatusCmd.GET, NotNil)
+	c.Check(agent.ServiceStatusCmd.Path, Equals, "/v1/service-status")
+
+	s.mockUserServicesStatus("snap.foo.service")
+
+	req := httptest.NewRequest("GET", "/v1/service-status?services=snap.foo.service,snap.bar.service", nil)
+	rec := httptest.NewRecorder()
+	agent.ServiceStatusCmd.GET(agent.ServiceStatusCmd, req).ServeHTTP(rec, req)
+	c.Check(rec.Code, Equals, 200)
+
+	var rsp resp
+	c.Assert(json.Unmarshal(rec.Body.Bytes(), &rsp), IsNil)
+	c.Check(rsp.Type, Equals, agent.ResponseTypeSync)
+	c.Check(rsp.Result, DeepEquals, []interface{}{
+		map[string]interface{}{"daemon": "simple", "id": "snap.foo.service", "enabled": true, "active": true},
+		map[string]interface{}{"daemon": "simple", "id": "snap.bar.service", "enabled": true, "active": false},
+	})
+	c.Check(s.sysdLog, DeepEquals, [][]string{
+		{"--user", "show", "--property=Id,ActiveState,UnitFileState,Type,Result", "snap.foo.service", "snap.bar.service"},
+	})
+}
+
+func (s *restSuite) TestServiceStatusErrors(c *C) {
+	for _, t := range []struct {
+		query   string
+		message string
+	}{
+		{"", "cannot get status of services without a list of services"},
+		{"?services=snap.foo.service,foo.service", "cannot get status of non-snap service foo.service"},
+	} {
+		req := httptest.NewRequest("GET", "/v1/service-status"+t.query, nil)
+		rec := httptest.NewRecorder()
+		agent.ServiceStatusCmd.GET(agent.ServiceStatusCmd, req).ServeHTTP(rec, req)
+		c.Check(rec.Code, Equals, 400)
+
+		var rsp resp
+		c.Assert(json.Unmarshal(rec.Body.Bytes(), &rsp), IsNil)
+		c.Check(rsp.Type, Equals, agent.ResponseTypeError)
+		c.Check(rsp.Result, DeepEquals, map[string]interface{}{
+			"message": t.message,
+		})
+	}
+	c.Check(s.sysdLog, HasLen, 0)
+}
+
 func (s *restSuite) TestPostPendingRefreshNotificationMalformedContentType(c *C) {
 	req := httptest.NewRequest("POST", "/v1/notifications/pending-refresh", bytes.NewBufferString(""))
 	req.Header.Set("Content-Type", "text/plain/joke")
@@ -31,6 +31,7 @@ import (
 	"net/url"
 	"path/filepath"
 	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -52,6 +53,7 @@ func dialSessionAgent(network, address string) (net.Conn, error) {
 
 type Client struct {
 	doer *http.Client
+	uids map[int]bool
 }
 
 func New() *Client {
@@ -61,6 +63,17 @@ func New() *Client {
 	}
 }
 
+// NewForUids creates a Client that only talks to the session agents
+// of the given users.
+func NewForUids(uids ...int) *Client {
+	client := New()
+	client.uids = make(map[int]bool, len(uids))
+	for _, uid := range uids {
+		client.uids[uid] = true
+	}
+	return client
+}
+
 type Error struct {
 	Kind    string      `json:"kind"`
 	Value   interface{} `json:"value"`
@@ -116,6 +129,9 @@ func (client *Client) doMany(ctx context.Context, method, urlpath string, query
 				// (i.e. /run/user/NNNN).
 				return
 			}
+			if client.uids != nil && !client.uids[uid] {
+				return
+			}
 			response := response{uid: uid}
 			defer func() {
 				mu.Lock()
@@ -226,47 +242,109 @@ func decodeServiceErrors(uid int, errorValue map[string]interface{}, kind string
 	return failures, err
 }
 
-func (client *Client) serviceControlCall(ctx context.Context, action string, services []string) (startFailures, stopFailures []ServiceFailure, err error) {
+func (client *Client) serviceControlCall(ctx context.Context, inst map[string]interface{}, kinds ...string) (failures map[string][]ServiceFailure, err error) {
 	headers := map[string]string{"Content-Type": "application/json"}
-	reqBody, err := json.Marshal(map[string]interface{}{
-		"action":   action,
-		"services": services,
-	})
+	reqBody, err := json.Marshal(inst)
 	if err != nil {
-		return nil, nil, err
+		return nil, err
 	}
 	responses, err := client.doMany(ctx, "POST", "/v1/service-control", nil, headers, reqBody)
 	if err != nil {
-		return nil, nil, err
+		return nil, err
 	}
+	failures = make(map[string][]ServiceFailure, len(kinds))
 	for _, resp := range responses {
 		if agentErr, ok := resp.err.(*Error); ok && agentErr.Kind == "service-control" {
 			if errorValue, ok := agentErr.Value.(map[string]interface{}); ok {
-				failures, _ := decodeServiceErrors(resp.uid, errorValue, "start-errors")
-				startFailures = append(startFailures, failures...)
-				failures, _ = decodeServiceErrors(resp.uid, errorValue, "stop-errors")
-				stopFailures = append(stopFailures, failures...)
+				for _, kind := range kinds {
+					kindFailures, _ := decodeServiceErrors(resp.uid, errorValue, kind)
+					failures[kind] = append(failures[kind], kindFailures...)
+				}
 			}
 		}
 		if resp.err != nil && err == nil {
 			err = resp.err
 		}
 	}
-	return startFailures, stopFailures, err
+	return failures, err
 }
 
 func (client *Client) ServicesDaemonReload(ctx context.Context) error {
-	_, _, err := client.serviceControlCall(ctx, "daemon-reload", nil)
+	_, err := client.serviceControlCall(ctx, map[string]interface{}{
+		"action": "daemon-reload",
+	})
 	return err
 }
 
 func (client *Client) ServicesStart(ctx context.Context, services []string) (startFailures, stopFailures []ServiceFailure, err error) {
-	return client.serviceControlCall(ctx, "start", services)
+	failures, err := client.serviceControlCall(ctx, map[string]interface{}{
+		"action":   "start",
+		"services": services,
+	}, "start-errors", "stop-errors")
+	return failures["start-errors"], failures["stop-errors"], err
 }
 
 func (client *Client) ServicesStop(ctx context.Context, services []string) (stopFailures []ServiceFailure, err error) {
-	_, stopFailures, err = client.serviceControlCall(ctx, "stop", services)
-	return stopFailures, err
+	failures, err := client.serviceControlCall(ctx, map[string]interface{}{
+		"action":   "stop",
+		"services": services,
+	}, "stop-errors")
+	return failures["stop-errors"], err
+}
+
+// ServicesRestart restarts the given services in the user sessions.
+// Services that are not running are only restarted if they are listed
+// in explicit. If reload is set, services are reloaded instead when
+// they support it.
+func (client *Client) ServicesRestart(ctx context.Context, services, explicit []string, reload bool) (restartFailures []ServiceFailure, err error) {
+	action := "restart"
+	if reload {
+		action = "reload-or-restart"
+	}
+	failures, err := client.serviceControlCall(ctx, map[string]interface{}{
+		"action":            action,
+		"services":          services,
+		"explicit-services": explicit,
+	}, "restart-errors")
+	return failures["restart-errors"], err
+}
+
+// ServiceUnitStatus is the status of a service unit in a user session.
+type ServiceUnitStatus struct {
+	Daemon  string `json:"daemon"`
+	Id      string `json:"id"`
+	Enabled bool   `json:"enabled"`
+	Active  bool   `json:"active"`
+}
+
+// ServiceStatus returns the status of the given services for each of
+// the user sessions, indexed by uid. Sessions that could not be queried
+// are left out of the result and the first such error is returned.
+func (client *Client) ServiceStatus(ctx context.Context, services []string) (status map[int][]*ServiceUnitStatus, err error) {
+	query := url.Values{"services": []string{strings.Join(services, ",")}}
+	responses, err := client.doMany(ctx, "GET", "/v1/service-status", query, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	status = make(map[int][]*ServiceUnitStatus)
+	for _, resp := range responses {
+		if resp.err != nil {
+			if err == nil {
+				err = resp.err
+			}
+			continue
+		}
+		var sts []*ServiceUnitStatus
+		if decodeErr := json.Unmarshal(resp.Result, &sts); decodeErr != nil {
+			if err == nil {
+				err = decodeErr
+			}
+			continue
+		}
+		status[resp.uid] = sts
+	}
+	return status, err
 }
 
 // PendingSnapRefreshInfo holds information about pending snap refresh provided to userd.
@@ -21,11 +21,13 @@ package client_test
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"net"
 	"net/http"
 	"os"
 	"path/filepath"
+	"sync"
 	"testing"
 	"time"
 
@@ -447,6 +449,128 @@ func (s *clientSuite) TestServicesStopFailure(c *C) {
 	})
 }
 
+func (s *clientSuite) TestServicesRestart(c *C) {
+	var mu sync.Mutex
+	var hosts []string
+	s.handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c.Check(r.URL.Path, Equals, "/v1/service-control")
+		var inst map[string]interface{}
+		c.Assert(json.NewDecoder(r.Body).Decode(&inst), IsNil)
+		c.Check(inst, DeepEquals, map[string]interface{}{
+			"action":            "reload-or-restart",
+			"services":          []interface{}{"service1.service", "service2.service"},
+			"explicit-services": []interface{}{"service2.service"},
+		})
+		mu.Lock()
+		hosts = append(hosts, r.Host)
+		mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(200)
+		w.Write([]byte(`{"type": "sync", "result": null}`))
+	})
+	failures, err := s.cli.ServicesRestart(context.Background(), []string{"service1.service", "service2.service"}, []string{"service2.service"}, true)
+	c.Assert(err, IsNil)
+	c.Check(failures, HasLen, 0)
+	c.Check(hosts, HasLen, 2)
+}
+
+func (s *clientSuite) TestServicesRestartFailure(c *C) {
+	s.handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.Host != "42" {
+			w.WriteHeader(200)
+			w.Write([]byte(`{"type": "sync","result": null}`))
+			return
+		}
+		w.WriteHeader(500)
+		w.Write([]byte(`{
+  "type": "error",
+  "result": {
+    "kind": "service-control",
+    "message": "some user services failed to restart",
+    "value": {
+      "restart-errors": {
+        "service2.service": "failed to restart"
+      }
+    }
+  }
+}`))
+	})
+	failures, err := s.cli.ServicesRestart(context.Background(), []string{"service1.service", "service2.service"}, nil, false)
+	c.Assert(err, ErrorMatches, "some user services failed to restart")
+	c.Check(failures, DeepEquals, []client.ServiceFailure{
+		{
+			Uid:     42,
+			Service: "service2.service",
+			Error:   "failed to restart",
+		},
+	})
+}
+
+func (s *clientSuite) TestServiceStatus(c *C) {
+	s.handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c.Check(r.URL.Path, Equals, "/v1/service-status")
+		c.Check(r.URL.Query().Get("services"), Equals, "snap.foo.svc.service,snap.foo.other.service")
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(200)
+		active := r.Host == "1000"
+		w.Write([]byte(fmt.Sprintf(`{
+  "type": "sync",
+  "result": [
+    {"daemon": "simple", "id": "snap.foo.svc.service", "enabled": true, "active": %v},
+    {"daemon": "simple", "id": "snap.foo.other.service", "enabled": false, "active": false}
+  ]
+}`, active)))
+	})
+	status, err := s.cli.ServiceStatus(context.Background(), []string{"snap.foo.svc.service", "snap.foo.other.service"})
+	c.Assert(err, IsNil)
+	c.Check(status, DeepEquals, map[int][]*client.ServiceUnitStatus{
+		1000: {
+			{Daemon: "simple", Id: "snap.foo.svc.service", Enabled: true, Active: true},
+			{Daemon: "simple", Id: "snap.foo.other.service"},
+		},
+		42: {
+			{Daemon: "simple", Id: "snap.foo.svc.service", Enabled: true},
+			{Daemon: "simple", Id: "snap.foo.other.service"},
+		},
+	})
+}
+
+func (s *clientSuite) TestServiceStatusOneAgentFailure(c *C) {
+	s.handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.Host == "42" {
+			w.WriteHeader(500)
+			w.Write([]byte(`{"type": "error", "result": {"message": "cannot get status"}}`))
+			return
+		}
+		w.WriteHeader(200)
+		w.Write([]byte(`{"type": "sync", "result": [{"daemon": "simple", "id": "snap.foo.svc.service", "enabled": true, "active": true}]}`))
+	})
+	status, err := s.cli.ServiceStatus(context.Background(), []string{"snap.foo.svc.service"})
+	c.Assert(err, ErrorMatches, "cannot get status")
+	c.Check(status, DeepEquals, map[int][]*client.ServiceUnitStatus{
+		1000: {{Daemon: "simple", Id: "snap.foo.svc.service", Enabled: true, Active: true}},
+	})
+}
+
+func (s *clientSuite) TestNewForUids(c *C) {
+	var mu sync.Mutex
+	var hosts []string
+	s.handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		hosts = append(hosts, r.Host)
+		mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(200)
+		w.Write([]byte(`{"type": "sync", "result": null}`))
+	})
+	cli := client.NewForUids(42)
+	_, _, err := cli.ServicesStart(context.Background(), []string{"service1.service"})
+	c.Assert(err, IsNil)
+	c.Check(hosts, DeepEquals, []string{"42"})
+}
+
 func (s *clientSuite) TestPendingRefreshNotification(c *C) {
 	s.handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		c.Assert(r.URL.Path, Equals, "/v1/notifications/pending-refresh")
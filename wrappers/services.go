@@ -125,7 +125,26 @@ func startUserServices(cli *client.Client, inter interacter, services ...string)
 	return err
 }
 
-func stopService(sysd systemd.Systemd, app *snap.AppInfo, inter interacter) error {
+// userServicesClient returns a client for the session agents of the given
+// users, or for all the active user sessions if uids is empty.
+func userServicesClient(uids []int) *client.Client {
+	if len(uids) == 0 {
+		return client.New()
+	}
+	return client.NewForUids(uids...)
+}
+
+func restartUserServices(cli *client.Client, inter interacter, services, explicit []string, reload bool) error {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(timeout.DefaultTimeout))
+	defer cancel()
+	failures, err := cli.ServicesRestart(ctx, services, explicit, reload)
+	for _, f := range failures {
+		inter.Notify(fmt.Sprintf("Could not restart service %q for uid %d: %s", f.Service, f.Uid, f.Error))
+	}
+	return err
+}
+
+func stopService(sysd systemd.Systemd, cli *client.Client, app *snap.AppInfo, inter interacter) error {
 	serviceName := app.ServiceName()
 	tout := serviceStopTimeout(app)
 
@@ -163,7 +182,6 @@ func stopService(sysd systemd.Systemd, app *snap.AppInfo, inter interacter) erro
 
 	case snap.UserDaemon:
 		extraServices = append(extraServices, serviceName)
-		cli := client.New()
 		return stopUserServices(cli, inter, extraServices...)
 	}
 
@@ -231,6 +249,9 @@ func enableServices(apps []*snap.AppInfo, inter interacter) (disable func(), err
 // StartServicesFlags carries extra flags for StartServices.
 type StartServicesFlags struct {
 	Enable bool
+	// Uids restricts the user services to the sessions of the given
+	// users. All the active user sessions are used if it is empty.
+	Uids []int
 }
 
 // StartServices starts service units for the applications from the snap which
@@ -243,7 +264,7 @@ func StartServices(apps []*snap.AppInfo, disabledSvcs []string, flags *StartServ
 
 	systemSysd := systemd.New(systemd.SystemMode, inter)
 	userSysd := systemd.New(systemd.GlobalUserMode, inter)
-	cli := client.New()
+	cli := userServicesClient(flags.Uids)
 
 	var disableEnabledServices func()
 
@@ -311,7 +332,7 @@ func StartServices(apps []*snap.AppInfo, disabledSvcs []string, flags *StartServ
 				return
 			}
 
-			if e := stopService(sysd, app, inter); e != nil {
+			if e := stopService(sysd, cli, app, inter); e != nil {
 				inter.Notify(fmt.Sprintf("While trying to stop previously started service %q: %v", app.ServiceName(), e))
 			}
 			for _, socket := range app.Sockets {
@@ -459,11 +480,37 @@ type SnapServiceOptions struct {
 
 	// QuotaGroup is the quota group for all services in the specified snap.
 	QuotaGroup *quota.Group
+
+	// ServiceOverrides are the settings overridden by the administrator for
+	// services of the specified snap, by app name.
+	ServiceOverrides map[string]*ServiceOverride
+}
+
+// ServiceOverride holds service settings set by the administrator, which
+// take precedence over the ones from snap.yaml. They are rendered as a
+// systemd drop-in for the service unit, unset settings are left as they
+// are in the unit.
+type ServiceOverride struct {
+	Restart         snap.RestartCondition `json:"restart,omitempty"`
+	RestartDelay    timeout.Timeout       `json:"restart-delay,omitempty"`
+	StartTimeout    timeout.Timeout       `json:"start-timeout,omitempty"`
+	StopTimeout     timeout.Timeout       `json:"stop-timeout,omitempty"`
+	WatchdogTimeout timeout.Timeout       `json:"watchdog-timeout,omitempty"`
+	// Environment holds extra environment variables for the service.
+	Environment map[string]string `json:"environment,omitempty"`
+}
+
+// ServiceOverrideFile returns the path of the systemd drop-in holding the
+// settings overridden by the administrator for the given service.
+func ServiceOverrideFile(app *snap.AppInfo) string {
+	return filepath.Join(app.ServiceFile()+".d", "snapd-override.conf")
 }
 
 // ObserveChangeCallback can be invoked by EnsureSnapServices to observe
 // the previous content of a unit and the new on a change.
-// unitType can be "service", "socket", "timer". name is empty for a timer.
+// unitType can be "service", "socket", "timer" or "override", the drop-in
+// with the settings overridden by the administrator, new is empty when it is
+// removed. name is empty for a timer.
 type ObserveChangeCallback func(app *snap.AppInfo, grp *quota.Group, unitType string, name, old, new string)
 
 // EnsureSnapServicesOptions is the set of options applying to the
@@ -535,7 +582,11 @@ func EnsureSnapServices(snaps map[*snap.Info]*SnapServiceOptions, opts *EnsureSn
 					inter.Notify(fmt.Sprintf("while trying to remove %s due to previous failure: %v", file, e))
 				}
 			} else {
-				// rollback the file to the previous state
+				// rollback the file to the previous state, the
+				// directory of a removed drop-in might be gone
+				if e := os.MkdirAll(filepath.Dir(file), 0755); e != nil {
+					inter.Notify(fmt.Sprintf("while trying to rollback %s due to previous failure: %v", file, e))
+				}
 				if e := osutil.EnsureFileState(file, state); e != nil {
 					inter.Notify(fmt.Sprintf("while trying to rollback %s due to previous failure: %v", file, e))
 				}
@@ -582,6 +633,40 @@ func EnsureSnapServices(snaps map[*snap.Info]*SnapServiceOptions, opts *EnsureSn
 		return nil
 	}
 
+	handleFileRemoval := func(app *snap.AppInfo, unitType string, name, path string) error {
+		st, err := os.Stat(path)
+		if os.IsNotExist(err) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		content, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		// keep the old state to rollback to
+		old := &osutil.MemoryFileState{Content: content, Mode: st.Mode()}
+		if err := os.Remove(path); err != nil {
+			return err
+		}
+		// the directory of a drop-in is only removed if empty
+		os.Remove(filepath.Dir(path))
+
+		if observeChange != nil {
+			observeChange(app, nil, unitType, name, string(old.Content), "")
+		}
+		modifiedUnitsPreviousState[path] = old
+
+		switch app.DaemonScope {
+		case snap.SystemDaemon:
+			modifiedSystem = true
+		case snap.UserDaemon:
+			modifiedUser = true
+		}
+		return nil
+	}
+
 	neededQuotaGrps := &quota.QuotaGroupSet{}
 
 	for s, snapSvcOpts := range snaps {
@@ -598,6 +683,7 @@ func EnsureSnapServices(snaps map[*snap.Info]*SnapServiceOptions, opts *EnsureSn
 			// VitalityRank
 			genServiceOpts.VitalityRank = snapSvcOpts.VitalityRank
 			genServiceOpts.QuotaGroup = snapSvcOpts.QuotaGroup
+			genServiceOpts.ServiceOverrides = snapSvcOpts.ServiceOverrides
 
 			if snapSvcOpts.QuotaGroup != nil {
 				if err := neededQuotaGrps.AddAllNecessaryGroups(snapSvcOpts.QuotaGroup); err != nil {
@@ -627,6 +713,20 @@ func EnsureSnapServices(snaps map[*snap.Info]*SnapServiceOptions, opts *EnsureSn
 				return err
 			}
 
+			// the drop-in with the settings overridden by the
+			// administrator, if any
+			overridePath := ServiceOverrideFile(app)
+			if override := genServiceOpts.ServiceOverrides[app.Name]; override != nil {
+				content := generateServiceOverrideFile(override)
+				if err := handleFileModification(app, "override", app.Name, overridePath, content); err != nil {
+					return err
+				}
+			} else {
+				if err := handleFileRemoval(app, "override", app.Name, overridePath); err != nil {
+					return err
+				}
+			}
+
 			// Generate systemd .socket files if needed
 			socketFiles, err := generateSnapSocketFiles(app)
 			if err != nil {
@@ -718,6 +818,10 @@ type AddSnapServicesOptions struct {
 	// QuotaGroup is the quota group for all services in the specified snap.
 	QuotaGroup *quota.Group
 
+	// ServiceOverrides are the settings overridden by the administrator for
+	// services of the specified snap, by app name.
+	ServiceOverrides map[string]*ServiceOverride
+
 	// RequireMountedSnapdSnap is whether the generated units should depend on
 	// the snapd snap being mounted, this is specific to systems like UC18 and
 	// UC20 which have the snapd snap and need to have units generated
@@ -740,6 +844,7 @@ func AddSnapServices(s *snap.Info, opts *AddSnapServicesOptions, inter interacte
 		// set the per-snap service options
 		m[s].VitalityRank = opts.VitalityRank
 		m[s].QuotaGroup = opts.QuotaGroup
+		m[s].ServiceOverrides = opts.ServiceOverrides
 
 		// copy the globally applicable opts from AddSnapServicesOptions to
 		// EnsureSnapServicesOptions, since those options override the per-snap opts
@@ -754,6 +859,9 @@ func AddSnapServices(s *snap.Info, opts *AddSnapServicesOptions, inter interacte
 // StopServicesFlags carries extra flags for StopServices.
 type StopServicesFlags struct {
 	Disable bool
+	// Uids restricts the user services to the sessions of the given
+	// users. All the active user sessions are used if it is empty.
+	Uids []int
 }
 
 // StopServices stops and optionally disables service units for the applications
@@ -763,6 +871,7 @@ func StopServices(apps []*snap.AppInfo, flags *StopServicesFlags, reason snap.Se
 	if flags == nil {
 		flags = &StopServicesFlags{}
 	}
+	cli := userServicesClient(flags.Uids)
 
 	if reason != snap.StopReasonOther {
 		logger.Debugf("StopServices called for %q, reason: %v", apps, reason)
@@ -788,7 +897,7 @@ func StopServices(apps []*snap.AppInfo, flags *StopServicesFlags, reason snap.Se
 
 		var err error
 		timings.Run(tm, "stop-service", fmt.Sprintf("stop service %q", app.ServiceName()), func(nested timings.Measurer) {
-			err = stopService(sysd, app, inter)
+			err = stopService(sysd, cli, app, inter)
 			if err == nil && flags.Disable {
 				err = sysd.Disable(app.ServiceName())
 			}
@@ -925,6 +1034,13 @@ func RemoveSnapServices(s *snap.Info, inter interacter) error {
 			logger.Noticef("Failed to remove service file for %q: %v", serviceName, err)
 		}
 
+		overridePath := ServiceOverrideFile(app)
+		if err := os.Remove(overridePath); err != nil && !os.IsNotExist(err) {
+			logger.Noticef("Failed to remove service override file for %q: %v", serviceName, err)
+		}
+		// the directory is only removed if there are no other drop-ins
+		os.Remove(filepath.Dir(overridePath))
+
 	}
 
 	// only reload if we actually had services
@@ -1205,6 +1321,44 @@ WantedBy={{.ServicesTarget}}
 	return templateOut.Bytes(), nil
 }
 
+// generateServiceOverrideFile generates the drop-in for a service with the
+// settings overridden by the administrator.
+func generateServiceOverrideFile(override *ServiceOverride) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("[Service]\n# Auto-generated, DO NOT EDIT\n")
+	if override.Restart != "" {
+		fmt.Fprintf(&buf, "Restart=%s\n", override.Restart)
+	}
+	if override.RestartDelay != 0 {
+		fmt.Fprintf(&buf, "RestartSec=%v\n", override.RestartDelay.Seconds())
+	}
+	if override.StartTimeout != 0 {
+		fmt.Fprintf(&buf, "TimeoutStartSec=%v\n", override.StartTimeout.Seconds())
+	}
+	if override.StopTimeout != 0 {
+		fmt.Fprintf(&buf, "TimeoutStopSec=%v\n", override.StopTimeout.Seconds())
+	}
+	if override.WatchdogTimeout != 0 {
+		fmt.Fprintf(&buf, "WatchdogSec=%v\n", override.WatchdogTimeout.Seconds())
+	}
+	names := make([]string, 0, len(override.Environment))
+	for name := range override.Environment {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(&buf, "Environment=%s\n", quoteEnvironment(name+"="+override.Environment[name]))
+	}
+	return buf.Bytes()
+}
+
+// quoteEnvironment quotes an assignment for the systemd Environment=
+// directive, escaping quotes, backslashes and specifiers.
+func quoteEnvironment(assignment string) string {
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "%", "%%", "\n", `\n`)
+	return `"` + r.Replace(assignment) + `"`
+}
+
 func genServiceSocketFile(appInfo *snap.AppInfo, socketName string) []byte {
 	socketTemplate := `[Unit]
 # Auto-generated, DO NOT EDIT
@@ -1575,6 +1729,13 @@ func generateOnCalendarSchedules(schedule []*timeutil.Schedule) []string {
 
 type RestartServicesFlags struct {
 	Reload bool
+	// UserServices has the user services restarted in the user sessions
+	// by the session agents, instead of being treated like the system
+	// services.
+	UserServices bool
+	// Uids restricts the user services to the sessions of the given
+	// users. All the active user sessions are used if it is empty.
+	Uids []int
 }
 
 // Restart or reload active services in `svcs`.
@@ -1588,16 +1749,47 @@ type RestartServicesFlags struct {
 func RestartServices(svcs []*snap.AppInfo, explicitServices []string,
 	flags *RestartServicesFlags, inter interacter, tm timings.Measurer) error {
 	sysd := systemd.New(systemd.SystemMode, inter)
+	if flags == nil {
+		flags = &RestartServicesFlags{}
+	}
 
 	unitNames := make([]string, 0, len(svcs))
+	var userUnitNames []string
 	for _, srv := range svcs {
 		// they're *supposed* to be all services, but checking doesn't hurt
 		if !srv.IsService() {
 			continue
 		}
+		// if asked, user services are restarted by the session agents,
+		// which know about the state of the services in each session
+		if flags.UserServices && srv.DaemonScope == snap.UserDaemon {
+			userUnitNames = append(userUnitNames, srv.ServiceName())
+			continue
+		}
 		unitNames = append(unitNames, srv.ServiceName())
 	}
 
+	if len(userUnitNames) != 0 {
+		var explicitUserServices []string
+		for _, name := range userUnitNames {
+			if strutil.ListContains(explicitServices, name) {
+				explicitUserServices = append(explicitUserServices, name)
+			}
+		}
+		var err error
+		timings.Run(tm, "restart-user-services", "restart user services", func(nested timings.Measurer) {
+			cli := userServicesClient(flags.Uids)
+			err = restartUserServices(cli, inter, userUnitNames, explicitUserServices, flags.Reload)
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(unitNames) == 0 {
+		return nil
+	}
+
 	unitStatuses, err := sysd.Status(unitNames...)
 	if err != nil {
 		return err
@@ -1614,7 +1806,7 @@ func RestartServices(svcs []*snap.AppInfo, explicitServices []string,
 
 		var err error
 		timings.Run(tm, "restart-service", fmt.Sprintf("restart service %s", unit.UnitName), func(nested timings.Measurer) {
-			if flags != nil && flags.Reload {
+			if flags.Reload {
 				err = sysd.ReloadOrRestart(unit.UnitName)
 			} else {
 				// note: stop followed by start, not just 'restart'
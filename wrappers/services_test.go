@@ -45,6 +45,7 @@ import (
 	"github.com/snapcore/snapd/systemd"
 	"github.com/snapcore/snapd/systemd/systemdtest"
 	"github.com/snapcore/snapd/testutil"
+	"github.com/snapcore/snapd/timeout"
 	"github.com/snapcore/snapd/timings"
 	"github.com/snapcore/snapd/usersession/agent"
 	"github.com/snapcore/snapd/wrappers"
@@ -1294,6 +1295,180 @@ WantedBy=multi-user.target
 	))
 }
 
+func (s *servicesTestSuite) TestEnsureSnapServicesWithServiceOverrides(c *C) {
+	// map unit -> new
+	seen := make(map[string]bool)
+	cb := func(app *snap.AppInfo, grp *quota.Group, unitType, name string, old, new string) {
+		seen[fmt.Sprintf("%s:%s:%s:%s", app.Snap.InstanceName(), app.Name, unitType, name)] = old == ""
+	}
+
+	info := snaptest.MockSnap(c, packageHello, &snap.SideInfo{Revision: snap.R(12)})
+	svcFile := filepath.Join(s.tempdir, "/etc/systemd/system/snap.hello-snap.svc1.service")
+	overrideFile := filepath.Join(s.tempdir, "/etc/systemd/system/snap.hello-snap.svc1.service.d/snapd-override.conf")
+	c.Check(wrappers.ServiceOverrideFile(info.Apps["svc1"]), Equals, overrideFile)
+
+	m := map[*snap.Info]*wrappers.SnapServiceOptions{
+		info: {ServiceOverrides: map[string]*wrappers.ServiceOverride{
+			"svc1": {
+				Restart:         snap.RestartAlways,
+				RestartDelay:    timeout.Timeout(10 * time.Second),
+				StartTimeout:    timeout.Timeout(90 * time.Second),
+				StopTimeout:     timeout.Timeout(1500 * time.Millisecond),
+				WatchdogTimeout: timeout.Timeout(time.Minute),
+				Environment: map[string]string{
+					"FOO":  "bar",
+					"BAZ":  `"quoted" 100%`,
+					"PATH": `C:\dos`,
+				},
+			},
+		}},
+	}
+
+	err := wrappers.EnsureSnapServices(m, nil, cb, progress.Null)
+	c.Assert(err, IsNil)
+	c.Check(s.sysdLog, DeepEquals, [][]string{
+		{"daemon-reload"},
+	})
+	c.Check(seen, DeepEquals, map[string]bool{
+		"hello-snap:svc1:service:svc1":  true,
+		"hello-snap:svc1:override:svc1": true,
+	})
+	// the unit itself is unchanged
+	c.Check(svcFile, testutil.FileContains, "\nRestart=on-failure\n")
+	c.Check(overrideFile, testutil.FileEquals, `[Service]
+# Auto-generated, DO NOT EDIT
+Restart=always
+RestartSec=10
+TimeoutStartSec=90
+TimeoutStopSec=1.5
+WatchdogSec=60
+Environment="BAZ=\"quoted\" 100%%"
+Environment="FOO=bar"
+Environment="PATH=C:\\dos"
+`)
+
+	// never is rendered as systemd expects it
+	m[info].ServiceOverrides["svc1"] = &wrappers.ServiceOverride{Restart: snap.RestartNever}
+	seen = make(map[string]bool)
+	s.sysdLog = nil
+	err = wrappers.EnsureSnapServices(m, nil, cb, progress.Null)
+	c.Assert(err, IsNil)
+	c.Check(s.sysdLog, DeepEquals, [][]string{
+		{"daemon-reload"},
+	})
+	c.Check(seen, DeepEquals, map[string]bool{
+		"hello-snap:svc1:override:svc1": false,
+	})
+	c.Check(overrideFile, testutil.FileEquals, `[Service]
+# Auto-generated, DO NOT EDIT
+Restart=no
+`)
+}
+
+func (s *servicesTestSuite) TestEnsureSnapServicesRemovesServiceOverride(c *C) {
+	// map unit -> new content
+	seen := make(map[string]string)
+	cb := func(app *snap.AppInfo, grp *quota.Group, unitType, name string, old, new string) {
+		seen[fmt.Sprintf("%s:%s:%s:%s", app.Snap.InstanceName(), app.Name, unitType, name)] = new
+	}
+
+	info := snaptest.MockSnap(c, packageHello, &snap.SideInfo{Revision: snap.R(12)})
+	m := map[*snap.Info]*wrappers.SnapServiceOptions{
+		info: {ServiceOverrides: map[string]*wrappers.ServiceOverride{
+			"svc1": {Restart: snap.RestartAlways},
+		}},
+	}
+	err := wrappers.EnsureSnapServices(m, nil, nil, progress.Null)
+	c.Assert(err, IsNil)
+	overrideFile := wrappers.ServiceOverrideFile(info.Apps["svc1"])
+	c.Assert(overrideFile, testutil.FilePresent)
+
+	s.sysdLog = nil
+	err = wrappers.EnsureSnapServices(map[*snap.Info]*wrappers.SnapServiceOptions{info: nil}, nil, cb, progress.Null)
+	c.Assert(err, IsNil)
+	c.Check(s.sysdLog, DeepEquals, [][]string{
+		{"daemon-reload"},
+	})
+	c.Check(seen, DeepEquals, map[string]string{
+		"hello-snap:svc1:override:svc1": "",
+	})
+	c.Check(overrideFile, testutil.FileAbsent)
+	c.Check(filepath.Dir(overrideFile), testutil.FileAbsent)
+
+	// nothing to do anymore
+	s.sysdLog = nil
+	err = wrappers.EnsureSnapServices(map[*snap.Info]*wrappers.SnapServiceOptions{info: nil}, nil, nil, progress.Null)
+	c.Assert(err, IsNil)
+	c.Check(s.sysdLog, HasLen, 0)
+}
+
+func (s *servicesTestSuite) TestEnsureSnapServicesKeepsOtherDropIns(c *C) {
+	info := snaptest.MockSnap(c, packageHello, &snap.SideInfo{Revision: snap.R(12)})
+	m := map[*snap.Info]*wrappers.SnapServiceOptions{
+		info: {ServiceOverrides: map[string]*wrappers.ServiceOverride{
+			"svc1": {Restart: snap.RestartAlways},
+		}},
+	}
+	err := wrappers.EnsureSnapServices(m, nil, nil, progress.Null)
+	c.Assert(err, IsNil)
+	overrideFile := wrappers.ServiceOverrideFile(info.Apps["svc1"])
+	otherDropIn := filepath.Join(filepath.Dir(overrideFile), "local.conf")
+	c.Assert(ioutil.WriteFile(otherDropIn, []byte("[Service]\n"), 0644), IsNil)
+
+	err = wrappers.EnsureSnapServices(map[*snap.Info]*wrappers.SnapServiceOptions{info: nil}, nil, nil, progress.Null)
+	c.Assert(err, IsNil)
+	c.Check(overrideFile, testutil.FileAbsent)
+	c.Check(otherDropIn, testutil.FilePresent)
+}
+
+func (s *servicesTestSuite) TestEnsureSnapServicesRollsbackServiceOverrideRemoval(c *C) {
+	info := snaptest.MockSnap(c, packageHello, &snap.SideInfo{Revision: snap.R(12)})
+	m := map[*snap.Info]*wrappers.SnapServiceOptions{
+		info: {ServiceOverrides: map[string]*wrappers.ServiceOverride{
+			"svc1": {Restart: snap.RestartAlways},
+		}},
+	}
+	err := wrappers.EnsureSnapServices(m, nil, nil, progress.Null)
+	c.Assert(err, IsNil)
+	overrideFile := wrappers.ServiceOverrideFile(info.Apps["svc1"])
+
+	r := systemd.MockSystemctl(func(cmd ...string) ([]byte, error) {
+		s.sysdLog = append(s.sysdLog, cmd)
+		if cmd[0] == "daemon-reload" && len(s.sysdLog) == 1 {
+			return nil, fmt.Errorf("oops")
+		}
+		return nil, nil
+	})
+	defer r()
+
+	s.sysdLog = nil
+	err = wrappers.EnsureSnapServices(map[*snap.Info]*wrappers.SnapServiceOptions{info: nil}, nil, nil, progress.Null)
+	c.Assert(err, ErrorMatches, "oops")
+	c.Check(s.sysdLog, DeepEquals, [][]string{
+		{"daemon-reload"},
+		{"daemon-reload"},
+	})
+	c.Check(overrideFile, testutil.FileEquals, "[Service]\n# Auto-generated, DO NOT EDIT\nRestart=always\n")
+}
+
+func (s *servicesTestSuite) TestRemoveSnapServicesRemovesServiceOverride(c *C) {
+	info := snaptest.MockSnap(c, packageHello, &snap.SideInfo{Revision: snap.R(12)})
+	opts := &wrappers.AddSnapServicesOptions{
+		ServiceOverrides: map[string]*wrappers.ServiceOverride{
+			"svc1": {Environment: map[string]string{"FOO": "bar"}},
+		},
+	}
+	err := wrappers.AddSnapServices(info, opts, progress.Null)
+	c.Assert(err, IsNil)
+	overrideFile := wrappers.ServiceOverrideFile(info.Apps["svc1"])
+	c.Check(overrideFile, testutil.FileContains, "Environment=\"FOO=bar\"\n")
+
+	err = wrappers.RemoveSnapServices(info, progress.Null)
+	c.Assert(err, IsNil)
+	c.Check(overrideFile, testutil.FileAbsent)
+	c.Check(filepath.Dir(overrideFile), testutil.FileAbsent)
+}
+
 func (s *servicesTestSuite) TestEnsureSnapServicesRollsback(c *C) {
 	info := snaptest.MockSnap(c, packageHello, &snap.SideInfo{Revision: snap.R(12)})
 
@@ -2175,6 +2350,79 @@ func (s *servicesTestSuite) TestStartServicesUserDaemons(c *C) {
 	})
 }
 
+func (s *servicesTestSuite) TestStartServicesUserDaemonsOtherUsers(c *C) {
+	info := snaptest.MockSnap(c, packageHello+`
+ svc1:
+  daemon: simple
+  daemon-scope: user
+`, &snap.SideInfo{Revision: snap.R(12)})
+
+	// no session agent is running for that user
+	flags := &wrappers.StartServicesFlags{Uids: []int{os.Getuid() + 1}}
+	err := wrappers.StartServices(info.Services(), nil, flags, &progress.Null, s.perfTimings)
+	c.Assert(err, IsNil)
+	c.Check(s.sysdLog, HasLen, 0)
+
+	flags = &wrappers.StartServicesFlags{Uids: []int{os.Getuid()}}
+	err = wrappers.StartServices(info.Services(), nil, flags, &progress.Null, s.perfTimings)
+	c.Assert(err, IsNil)
+	c.Check(s.sysdLog, DeepEquals, [][]string{
+		{"--user", "start", "snap.hello-snap.svc1.service"},
+	})
+}
+
+func (s *servicesTestSuite) TestRestartServicesUserDaemons(c *C) {
+	info := snaptest.MockSnap(c, packageHello+`
+ svc2:
+  daemon: simple
+  daemon-scope: user
+ svc3:
+  daemon: simple
+  daemon-scope: user
+`, &snap.SideInfo{Revision: snap.R(12)})
+	sysSvc := "snap.hello-snap.svc1.service"
+	userSvc2 := "snap.hello-snap.svc2.service"
+	userSvc3 := "snap.hello-snap.svc3.service"
+
+	s.systemctlRestorer()
+	s.systemctlRestorer = systemd.MockSystemctl(func(cmd ...string) ([]byte, error) {
+		s.sysdLog = append(s.sysdLog, cmd)
+		if cmd[0] == "--user" {
+			cmd = cmd[1:]
+		}
+		states := map[string]systemdtest.ServiceState{
+			sysSvc:   {ActiveState: "active", UnitFileState: "enabled"},
+			userSvc2: {ActiveState: "active", UnitFileState: "enabled"},
+			userSvc3: {ActiveState: "inactive", UnitFileState: "enabled"},
+		}
+		if out := systemdtest.HandleMockAllUnitsActiveOutput(cmd, states); out != nil {
+			return out, nil
+		}
+		return []byte("ActiveState=inactive\n"), nil
+	})
+
+	services := info.Services()
+	sort.Sort(snap.AppInfoBySnapApp(services))
+	flags := &wrappers.RestartServicesFlags{Reload: true, UserServices: true}
+	c.Assert(wrappers.RestartServices(services, []string{userSvc3}, flags, progress.Null, s.perfTimings), IsNil)
+	c.Check(s.sysdLog, DeepEquals, [][]string{
+		{"--user", "show", "--property=Id,ActiveState,UnitFileState,Type,Result", userSvc2, userSvc3},
+		{"--user", "reload-or-restart", userSvc2},
+		{"--user", "reload-or-restart", userSvc3},
+		{"show", "--property=Id,ActiveState,UnitFileState,Type,Result", sysSvc},
+		{"reload-or-restart", sysSvc},
+	})
+
+	// unless asked, the session agents are left alone
+	s.sysdLog = nil
+	flags = &wrappers.RestartServicesFlags{Reload: true}
+	c.Assert(wrappers.RestartServices(services, []string{userSvc3}, flags, progress.Null, s.perfTimings), IsNil)
+	c.Check(s.sysdLog, Not(HasLen), 0)
+	for _, cmd := range s.sysdLog {
+		c.Check(cmd[0], Not(Equals), "--user")
+	}
+}
+
 func (s *servicesTestSuite) TestStartServicesEnabledConditional(c *C) {
 	info := snaptest.MockSnap(c, packageHello, &snap.SideInfo{Revision: snap.R(12)})
 	svcFile := filepath.Join(s.tempdir, "/etc/systemd/system/snap.hello-snap.svc1.service")
@@ -3353,7 +3601,7 @@ apps:
 	c.Assert(wrappers.RestartServices(info.Services(), nil, flags, progress.Null, s.perfTimings), IsNil)
 	c.Assert(err, IsNil)
 	c.Check(s.sysdLog, DeepEquals, [][]string{
-		{"show", "--property=Id,ActiveState,UnitFileState,Type", srvFile},
+		{"show", "--property=Id,ActiveState,UnitFileState,Type,Result", srvFile},
 		{"reload-or-restart", srvFile},
 	})
 
@@ -3361,7 +3609,7 @@ apps:
 	flags.Reload = false
 	c.Assert(wrappers.RestartServices(info.Services(), nil, flags, progress.Null, s.perfTimings), IsNil)
 	c.Check(s.sysdLog, DeepEquals, [][]string{
-		{"show", "--property=Id,ActiveState,UnitFileState,Type", srvFile},
+		{"show", "--property=Id,ActiveState,UnitFileState,Type,Result", srvFile},
 		{"stop", srvFile},
 		{"show", "--property=ActiveState", srvFile},
 		{"start", srvFile},
@@ -3370,7 +3618,7 @@ apps:
 	s.sysdLog = nil
 	c.Assert(wrappers.RestartServices(info.Services(), nil, nil, progress.Null, s.perfTimings), IsNil)
 	c.Check(s.sysdLog, DeepEquals, [][]string{
-		{"show", "--property=Id,ActiveState,UnitFileState,Type", srvFile},
+		{"show", "--property=Id,ActiveState,UnitFileState,Type,Result", srvFile},
 		{"stop", srvFile},
 		{"show", "--property=ActiveState", srvFile},
 		{"start", srvFile},
@@ -3424,7 +3672,7 @@ apps:
 	sort.Sort(snap.AppInfoBySnapApp(services))
 	c.Assert(wrappers.RestartServices(services, nil, nil, progress.Null, s.perfTimings), IsNil)
 	c.Check(s.sysdLog, DeepEquals, [][]string{
-		{"show", "--property=Id,ActiveState,UnitFileState,Type",
+		{"show", "--property=Id,ActiveState,UnitFileState,Type,Result",
 			srvFile1, srvFile2, srvFile3, srvFile4},
 		{"stop", srvFile1},
 		{"show", "--property=ActiveState", srvFile1},
@@ -3439,7 +3687,7 @@ apps:
 	s.sysdLog = nil
 	c.Assert(wrappers.RestartServices(services, []string{srvFile2}, nil, progress.Null, s.perfTimings), IsNil)
 	c.Check(s.sysdLog, DeepEquals, [][]string{
-		{"show", "--property=Id,ActiveState,UnitFileState,Type",
+		{"show", "--property=Id,ActiveState,UnitFileState,Type,Result",
 			srvFile1, srvFile2, srvFile3, srvFile4},
 		{"stop", srvFile1},
 		{"show", "--property=ActiveState", srvFile1},